@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/migrate"
+)
+
+func main() {
+	if err := rootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func rootCommand() *cobra.Command {
+	var files []string
+	var outFile, reportFile string
+	var strict bool
+	options := migrate.Options{}
+
+	rootCmd := &cobra.Command{
+		Use:   "eve-migrate -f FILENAME",
+		Short: "Eve-migrate: convert Antrea and Calico network policies into everoute SecurityPolicies",
+		Long: `Convert the Antrea ClusterNetworkPolicies and ClusterGroups, the Calico NetworkPolicies and
+GlobalNetworkPolicies into everoute SecurityPolicies. The constructs could not be converted
+exactly are listed in the compatibility report, review it before applying the policies.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data bytes.Buffer
+			for _, file := range files {
+				content, err := readFile(file)
+				if err != nil {
+					return err
+				}
+				data.Write(content)
+				data.WriteString("\n---\n")
+			}
+
+			result, err := migrate.Convert(data.Bytes(), options)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if outFile != "" {
+				f, err := os.Create(outFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			if err = migrate.EncodePolicies(out, result.Policies); err != nil {
+				return err
+			}
+
+			report := io.Writer(cmd.ErrOrStderr())
+			if reportFile != "" {
+				f, err := os.Create(reportFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				report = f
+			}
+			if _, err = fmt.Fprint(report, result.Report.String()); err != nil {
+				return err
+			}
+
+			if unsupported := result.Report.Count(migrate.SeverityUnsupported); strict && unsupported != 0 {
+				return fmt.Errorf("%d unsupported constructs", unsupported)
+			}
+			return nil
+		},
+	}
+
+	rootCmd.SilenceUsage = true
+	rootCmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "files of the policies to convert, - for stdin")
+	rootCmd.Flags().StringVarP(&outFile, "output", "o", "", "file the SecurityPolicies written to, stdout if empty")
+	rootCmd.Flags().StringVar(&reportFile, "report", "", "file the compatibility report written to, stderr if empty")
+	rootCmd.Flags().StringVarP(&options.Namespace, "namespace", "n", "default", "namespace of the policies converted from the cluster scoped policies")
+	rootCmd.Flags().StringVar(&options.Tier, "tier", constants.Tier2, "tier of the converted policies")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "exit with error if any construct not supported")
+	_ = rootCmd.MarkFlagRequired("filename")
+
+	return rootCmd
+}
+
+func readFile(file string) ([]byte, error) {
+	if file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
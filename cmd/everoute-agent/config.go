@@ -20,8 +20,10 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"strings"
+	"time"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/plugins/pkg/ip"
@@ -30,8 +32,22 @@ import (
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/everoute/everoute/pkg/agent/bfd"
+	"github.com/everoute/everoute/pkg/agent/cri"
 	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/denylog"
+	"github.com/everoute/everoute/pkg/agent/effectiverules"
+	"github.com/everoute/everoute/pkg/agent/flowexporter"
+	"github.com/everoute/everoute/pkg/agent/flowlog"
+	"github.com/everoute/everoute/pkg/agent/fqdn"
+	"github.com/everoute/everoute/pkg/agent/l7proxy"
+	"github.com/everoute/everoute/pkg/agent/mirror"
+	"github.com/everoute/everoute/pkg/agent/portscan"
+	"github.com/everoute/everoute/pkg/agent/rulehit"
+	"github.com/everoute/everoute/pkg/agent/uplink"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/monitor"
 	"github.com/everoute/everoute/pkg/utils"
 )
 
@@ -46,17 +62,261 @@ type Options struct {
 type CNIConf struct {
 	EnableProxy bool   `yaml:"enableProxy,omitempty"`
 	EncapMode   string `yaml:"encapMode,omitempty"`
+	// CRIEndpoint correlate the veths with the pods by the sandboxes of the container runtime,
+	// e.g. unix:///run/containerd/containerd.sock, the socket should be mounted into the agent
+	CRIEndpoint string `yaml:"criEndpoint,omitempty"`
+}
+
+type PortScanDetectionConf struct {
+	Enable          bool          `yaml:"enable,omitempty"`
+	Threshold       int           `yaml:"threshold,omitempty"`
+	Interval        time.Duration `yaml:"interval,omitempty"`
+	Quarantine      bool          `yaml:"quarantine,omitempty"`
+	QuarantineTTL   time.Duration `yaml:"quarantineTTL,omitempty"`
+	ManagementCIDRs []string      `yaml:"managementCIDRs,omitempty"`
+}
+
+type UplinkFailoverConf struct {
+	Enable           bool                      `yaml:"enable,omitempty"`
+	Groups           []UplinkFailoverGroupConf `yaml:"groups,omitempty"`
+	ProbeInterval    time.Duration             `yaml:"probeInterval,omitempty"`
+	ProbeTimeout     time.Duration             `yaml:"probeTimeout,omitempty"`
+	FailureThreshold int                       `yaml:"failureThreshold,omitempty"`
+	Preempt          bool                      `yaml:"preempt,omitempty"`
+}
+
+type UplinkFailoverGroupConf struct {
+	Bridge        string   `yaml:"bridge"`
+	Bond          string   `yaml:"bond,omitempty"`
+	Interfaces    []string `yaml:"interfaces"`
+	Gateway       string   `yaml:"gateway,omitempty"`
+	ProbeSourceIP string   `yaml:"probeSourceIP,omitempty"`
+}
+
+type BFDConf struct {
+	Enable     bool          `yaml:"enable,omitempty"`
+	Interfaces []string      `yaml:"interfaces,omitempty"`
+	MinRx      time.Duration `yaml:"minRx,omitempty"`
+	MinTx      time.Duration `yaml:"minTx,omitempty"`
+	Interval   time.Duration `yaml:"interval,omitempty"`
+}
+
+type MirrorConf struct {
+	Enable   bool                `yaml:"enable,omitempty"`
+	Interval time.Duration       `yaml:"interval,omitempty"`
+	SFlow    SFlowConf           `yaml:"sflow,omitempty"`
+	Mirrors  []MirrorSessionConf `yaml:"mirrors,omitempty"`
+}
+
+type SFlowConf struct {
+	Bridges  []string `yaml:"bridges,omitempty"`
+	Targets  []string `yaml:"targets,omitempty"`
+	Agent    string   `yaml:"agent,omitempty"`
+	Sampling int      `yaml:"sampling,omitempty"`
+	Polling  int      `yaml:"polling,omitempty"`
+	Header   int      `yaml:"header,omitempty"`
+}
+
+type MirrorSessionConf struct {
+	Name           string   `yaml:"name"`
+	Bridge         string   `yaml:"bridge"`
+	SelectAll      bool     `yaml:"selectAll,omitempty"`
+	SelectSrcPorts []string `yaml:"selectSrcPorts,omitempty"`
+	SelectDstPorts []string `yaml:"selectDstPorts,omitempty"`
+	SelectVLANs    []int    `yaml:"selectVLANs,omitempty"`
+	OutputPort     string   `yaml:"outputPort,omitempty"`
+	OutputVLAN     int      `yaml:"outputVLAN,omitempty"`
+}
+
+type RuleHitTrackingConf struct {
+	Enable         bool          `yaml:"enable,omitempty"`
+	Interval       time.Duration `yaml:"interval,omitempty"`
+	UnusedFor      time.Duration `yaml:"unusedFor,omitempty"`
+	AnnotateUnused bool          `yaml:"annotateUnused,omitempty"`
+	// ReportCounters report the packets and bytes of the rules in the agentinfo, they are
+	// summed in the status of the policies
+	ReportCounters bool `yaml:"reportCounters,omitempty"`
+	// CountersPath is the file the counters persisted in across the agent restarts, default
+	// /var/lib/everoute/rulecounters.json
+	CountersPath string `yaml:"countersPath,omitempty"`
+}
+
+type IPSourcesConf struct {
+	Priority    []string `yaml:"priority,omitempty"`
+	MergePolicy string   `yaml:"mergePolicy,omitempty"`
+}
+
+type AgentInfoSyncConf struct {
+	Debounce time.Duration `yaml:"debounce,omitempty"`
+	MaxDelay time.Duration `yaml:"maxDelay,omitempty"`
+}
+
+type OVSDBDatabaseConf struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address,omitempty"`
+}
+
+type EndpointClassifierConf struct {
+	Drivers      []string          `yaml:"drivers,omitempty"`
+	ExternalIDs  map[string]string `yaml:"externalIDs,omitempty"`
+	NamePatterns []string          `yaml:"namePatterns,omitempty"`
+}
+
+type FlowLogConf struct {
+	Enable    bool          `yaml:"enable,omitempty"`
+	Interval  time.Duration `yaml:"interval,omitempty"`
+	LabelKeys []string      `yaml:"labelKeys,omitempty"`
+	Path      string        `yaml:"path,omitempty"`
+	MaxSize   int64         `yaml:"maxSize,omitempty"`
+
+	Sink FlowLogSinkConf `yaml:"sink,omitempty"`
+}
+
+type ConnectionStatsConf struct {
+	Enable   bool          `yaml:"enable,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+type FlowExporterConf struct {
+	Enable              bool          `yaml:"enable,omitempty"`
+	Collector           string        `yaml:"collector,omitempty"`
+	Protocol            string        `yaml:"protocol,omitempty"`
+	Interval            time.Duration `yaml:"interval,omitempty"`
+	SampleRate          uint32        `yaml:"sampleRate,omitempty"`
+	ObservationDomainID uint32        `yaml:"observationDomainID,omitempty"`
+}
+
+type DenyLoggingConf struct {
+	Enable      bool    `yaml:"enable,omitempty"`
+	RuleRate    float64 `yaml:"ruleRate,omitempty"`
+	RuleBurst   int     `yaml:"ruleBurst,omitempty"`
+	Rate        float64 `yaml:"rate,omitempty"`
+	RecordEvent bool    `yaml:"recordEvent,omitempty"`
+}
+
+type EffectiveRulesConf struct {
+	Enable   bool          `yaml:"enable,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+	MaxRules int           `yaml:"maxRules,omitempty"`
+}
+
+type FQDNPolicyConf struct {
+	Enable bool          `yaml:"enable,omitempty"`
+	MinTTL time.Duration `yaml:"minTTL,omitempty"`
+	// Resolvers are the ips or cidrs of the dns servers the addresses learned from.
+	Resolvers []string `yaml:"resolvers,omitempty"`
+}
+
+type L7ProxyConf struct {
+	Enable  bool   `yaml:"enable,omitempty"`
+	Address string `yaml:"address,omitempty"`
+}
+
+type FlowLogSinkConf struct {
+	Type       string        `yaml:"type,omitempty"`
+	URL        string        `yaml:"url,omitempty"`
+	Username   string        `yaml:"username,omitempty"`
+	Password   string        `yaml:"password,omitempty"`
+	Database   string        `yaml:"database,omitempty"`
+	Table      string        `yaml:"table,omitempty"`
+	Index      string        `yaml:"index,omitempty"`
+	Retention  time.Duration `yaml:"retention,omitempty"`
+	BatchSize  int           `yaml:"batchSize,omitempty"`
+	MaxPending int           `yaml:"maxPending,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
 }
 
 type agentConfig struct {
 	DatapathConfig map[string]string `yaml:"datapathConfig"`
 
+	// VRFConfig map the managed vds to its vrf, the vds not in it belongs to the default vrf
+	VRFConfig map[string]string `yaml:"vrfConfig,omitempty"`
+
 	// InternalIPs allow the items all ingress and egress traffics
 	InternalIPs []string `yaml:"internalIPs,omitempty"`
 
 	// cni config
 	EnableCNI bool    `yaml:"enableCNI,omitempty"`
 	CNIConf   CNIConf `yaml:"CNIConf,omitempty"`
+
+	// AllowSpanningTreeBridge allow manage the bridges with stp or rstp enabled
+	AllowSpanningTreeBridge bool `yaml:"allowSpanningTreeBridge,omitempty"`
+
+	// OVNInterop coexist with OVN on the host, refuse to manage the bridges owned by OVN and
+	// ignore the logical ports bound by ovn-controller
+	OVNInterop bool `yaml:"ovnInterop,omitempty"`
+
+	// PortScanDetection detect SYN fan-out from local endpoints, and quarantine them if enabled
+	PortScanDetection PortScanDetectionConf `yaml:"portScanDetection,omitempty"`
+
+	// UplinkFailover monitor the uplinks and failover to the standby ones
+	UplinkFailover UplinkFailoverConf `yaml:"uplinkFailover,omitempty"`
+
+	// BFD enable bfd sessions on the uplinks and tunnels
+	BFD BFDConf `yaml:"bfd,omitempty"`
+
+	// Mirror configure the sflow sampling and the port mirrors on the bridges
+	Mirror MirrorConf `yaml:"mirror,omitempty"`
+
+	// PolicyCompileBudget warn when compile a policy takes longer than it, zero means no budget
+	PolicyCompileBudget time.Duration `yaml:"policyCompileBudget,omitempty"`
+
+	// MaxPolicyFlows the most policy rule flows the agent could realize, reported in the agentinfo, the controller
+	// refuses the policy plans pushing the agent over it, zero means unlimited
+	MaxPolicyFlows int32 `yaml:"maxPolicyFlows,omitempty"`
+
+	// ClusterInternalCIDRs the cidrs of the pods and vms in the cluster, part of the builtin policy peer
+	// ClusterInternal besides the ips of the endpoints, the cluster pod cidr is added in cni mode
+	ClusterInternalCIDRs []string `yaml:"clusterInternalCIDRs,omitempty"`
+
+	// IPAgingTime remove the learned ip of the interface if not learned again in it, default 30m
+	IPAgingTime time.Duration `yaml:"ipAgingTime,omitempty"`
+
+	// EnableDHCPSnooping learn the ip of the endpoint from the dhcp ack, the ip expires at the end
+	// of the lease instead of the aging time, not work in cni mode
+	EnableDHCPSnooping bool `yaml:"enableDHCPSnooping,omitempty"`
+
+	// IPSources the priority of the ip learning sources, and report the ips of the interface from all the
+	// sources (Union) or the highest only (HighestPriority), default Declared > DHCP > ARP and Union
+	IPSources IPSourcesConf `yaml:"ipSources,omitempty"`
+
+	// OVSDBDatabases monitor the databases in addition to Open_vSwitch and report them in the agentinfo,
+	// only hardware_vtep supported, the address is unix:<path> or tcp:<ip>:<port>, default the local ovsdb-server
+	OVSDBDatabases []OVSDBDatabaseConf `yaml:"ovsdbDatabases,omitempty"`
+
+	// EndpointClassifier classify the interfaces as the endpoints by the drivers, the external_ids or the
+	// name patterns, for SR-IOV, macvlan or DPDK vhost-user ports, default the drivers tun and veth
+	EndpointClassifier EndpointClassifierConf `yaml:"endpointClassifier,omitempty"`
+
+	// AgentInfoSync coalesce the agentinfo syncs on the bursts of the port changes, default 500ms debounce and 5s max delay
+	AgentInfoSync AgentInfoSyncConf `yaml:"agentInfoSync,omitempty"`
+
+	// RuleHitTracking record the last hit time of the policy rules, for unused rules cleanup
+	RuleHitTracking RuleHitTrackingConf `yaml:"ruleHitTracking,omitempty"`
+
+	// EnableNameCache maintain the names of the ip addresses in the cluster for the traces and the flow logs
+	EnableNameCache bool `yaml:"enableNameCache,omitempty"`
+
+	// FlowLog export the connections with the identity of the workloads as flow logs
+	FlowLog FlowLogConf `yaml:"flowLog,omitempty"`
+
+	// ConnectionStats poll the conntrack and report the connections and the counters of the endpoints in the agentinfo
+	ConnectionStats ConnectionStatsConf `yaml:"connectionStats,omitempty"`
+
+	// FlowExporter export the connections matched by the security rules to an IPFIX or NetFlow v9 collector
+	FlowExporter FlowExporterConf `yaml:"flowExporter,omitempty"`
+
+	// DenyLogging log the packets dropped by the deny rules, rate limited per rule and per agent
+	DenyLogging DenyLoggingConf `yaml:"denyLogging,omitempty"`
+
+	// EffectiveRules export the rules active in the datapath as the EffectiveRuleSet of the agent
+	EffectiveRules EffectiveRulesConf `yaml:"effectiveRules,omitempty"`
+
+	// FQDNPolicy allow the policy peers defined by the dns names, learn the addresses of the names from the dns responses
+	FQDNPolicy FQDNPolicyConf `yaml:"fqdnPolicy,omitempty"`
+
+	// L7Proxy enforce the l7 criteria of the policy rules by the proxy, it's experimental
+	L7Proxy L7ProxyConf `yaml:"l7Proxy,omitempty"`
 }
 
 func NewOptions() *Options {
@@ -85,6 +345,283 @@ func (o *Options) IsEnableOverlay() bool {
 	return o.Config.CNIConf.EncapMode == constants.EncapModeGeneve
 }
 
+// getCRIClient returns the client of the container runtime, nil if not configured.
+func (o *Options) getCRIClient() cri.Client {
+	if !o.Config.EnableCNI || o.Config.CNIConf.CRIEndpoint == "" {
+		return nil
+	}
+	return cri.NewClient(o.Config.CNIConf.CRIEndpoint)
+}
+
+func (o *Options) IsEnablePortScanDetection() bool {
+	return o.Config.PortScanDetection.Enable
+}
+
+func (o *Options) getPortScanConfig() portscan.Config {
+	conf := o.Config.PortScanDetection
+	return portscan.Config{
+		Threshold:       conf.Threshold,
+		Interval:        conf.Interval,
+		Quarantine:      conf.Quarantine,
+		QuarantineTTL:   conf.QuarantineTTL,
+		ManagementCIDRs: conf.ManagementCIDRs,
+	}
+}
+
+func (o *Options) IsEnableUplinkFailover() bool {
+	return o.Config.UplinkFailover.Enable
+}
+
+func (o *Options) getUplinkFailoverConfig() uplink.Config {
+	conf := o.Config.UplinkFailover
+	config := uplink.Config{
+		ProbeInterval:    conf.ProbeInterval,
+		ProbeTimeout:     conf.ProbeTimeout,
+		FailureThreshold: conf.FailureThreshold,
+		Preempt:          conf.Preempt,
+	}
+	for _, group := range conf.Groups {
+		config.Groups = append(config.Groups, uplink.Group{
+			Bridge:        group.Bridge,
+			Bond:          group.Bond,
+			Interfaces:    group.Interfaces,
+			Gateway:       group.Gateway,
+			ProbeSourceIP: group.ProbeSourceIP,
+		})
+	}
+	return config
+}
+
+func (o *Options) IsEnableBFD() bool {
+	return o.Config.BFD.Enable
+}
+
+func (o *Options) getBFDConfig() bfd.Config {
+	conf := o.Config.BFD
+	return bfd.Config{
+		Interfaces: conf.Interfaces,
+		MinRx:      conf.MinRx,
+		MinTx:      conf.MinTx,
+		Interval:   conf.Interval,
+	}
+}
+
+func (o *Options) IsEnableMirror() bool {
+	return o.Config.Mirror.Enable
+}
+
+func (o *Options) getMirrorConfig() mirror.Config {
+	conf := o.Config.Mirror
+	mirrorConfig := mirror.Config{
+		Interval: conf.Interval,
+		SFlow: mirror.SFlowConfig{
+			Bridges:  conf.SFlow.Bridges,
+			Targets:  conf.SFlow.Targets,
+			Agent:    conf.SFlow.Agent,
+			Sampling: conf.SFlow.Sampling,
+			Polling:  conf.SFlow.Polling,
+			Header:   conf.SFlow.Header,
+		},
+	}
+	for _, session := range conf.Mirrors {
+		mirrorConfig.Mirrors = append(mirrorConfig.Mirrors, mirror.MirrorConfig{
+			Name:           session.Name,
+			Bridge:         session.Bridge,
+			SelectAll:      session.SelectAll,
+			SelectSrcPorts: session.SelectSrcPorts,
+			SelectDstPorts: session.SelectDstPorts,
+			SelectVLANs:    session.SelectVLANs,
+			OutputPort:     session.OutputPort,
+			OutputVLAN:     session.OutputVLAN,
+		})
+	}
+	return mirrorConfig
+}
+
+func (o *Options) IsEnableRuleHitTracking() bool {
+	return o.Config.RuleHitTracking.Enable
+}
+
+func (o *Options) IsReportRuleCounters() bool {
+	return o.IsEnableRuleHitTracking() && o.Config.RuleHitTracking.ReportCounters
+}
+
+func (o *Options) getRuleCountersPath() string {
+	if o.Config.RuleHitTracking.CountersPath == "" {
+		return rulehit.DefaultCountersPath
+	}
+	return o.Config.RuleHitTracking.CountersPath
+}
+
+func (o *Options) getRuleHitConfig() rulehit.Config {
+	conf := o.Config.RuleHitTracking
+	return rulehit.Config{
+		Interval:       conf.Interval,
+		UnusedFor:      conf.UnusedFor,
+		AnnotateUnused: conf.AnnotateUnused,
+	}
+}
+
+func (o *Options) IsEnableNameCache() bool {
+	return o.Config.EnableNameCache
+}
+
+func (o *Options) IsEnableFlowLog() bool {
+	return o.Config.FlowLog.Enable
+}
+
+func (o *Options) IsEnableConnectionStats() bool {
+	return o.Config.ConnectionStats.Enable
+}
+
+func (o *Options) IsEnableFlowExporter() bool {
+	return o.Config.FlowExporter.Enable
+}
+
+func (o *Options) getFlowExporterConfig() flowexporter.Config {
+	conf := o.Config.FlowExporter
+	return flowexporter.Config{
+		Collector:           conf.Collector,
+		Protocol:            flowexporter.Protocol(conf.Protocol),
+		Interval:            conf.Interval,
+		SampleRate:          conf.SampleRate,
+		ObservationDomainID: conf.ObservationDomainID,
+	}
+}
+
+func (o *Options) IsEnableDenyLogging() bool {
+	return o.Config.DenyLogging.Enable
+}
+
+func (o *Options) getDenyLogConfig() denylog.Config {
+	conf := o.Config.DenyLogging
+	return denylog.Config{
+		RuleRate:    conf.RuleRate,
+		RuleBurst:   conf.RuleBurst,
+		Rate:        conf.Rate,
+		RecordEvent: conf.RecordEvent,
+	}
+}
+
+func (o *Options) IsEnableEffectiveRules() bool {
+	return o.Config.EffectiveRules.Enable
+}
+
+func (o *Options) getEffectiveRulesConfig() effectiverules.Config {
+	conf := o.Config.EffectiveRules
+	return effectiverules.Config{
+		Interval: conf.Interval,
+		MaxRules: conf.MaxRules,
+	}
+}
+
+func (o *Options) IsEnableFQDNPolicy() bool {
+	return o.Config.FQDNPolicy.Enable
+}
+
+// getFQDNCache returns the cache of the addresses of the dns names, nil if fqdn policy disabled.
+func (o *Options) getFQDNCache() *fqdn.Cache {
+	if !o.IsEnableFQDNPolicy() {
+		return nil
+	}
+	minTTL := o.Config.FQDNPolicy.MinTTL
+	if minTTL == 0 {
+		minTTL = fqdn.DefaultMinTTL
+	}
+	return fqdn.NewCache(minTTL)
+}
+
+func (o *Options) IsEnableL7Proxy() bool {
+	return o.Config.L7Proxy.Enable
+}
+
+func (o *Options) getL7ProxyConfig() l7proxy.Config {
+	return l7proxy.Config{Address: o.Config.L7Proxy.Address}
+}
+
+// getL7ProxyAddr returns the address the rules with l7 criteria redirected to, nil if l7
+// proxy disabled.
+func (o *Options) getL7ProxyAddr() *net.TCPAddr {
+	if !o.IsEnableL7Proxy() {
+		return nil
+	}
+	addr, err := net.ResolveTCPAddr("tcp4", o.Config.L7Proxy.Address)
+	if err != nil || addr.IP == nil || addr.IP.IsUnspecified() {
+		klog.Fatalf("l7 proxy address %s must be an available ipv4 address with port", o.Config.L7Proxy.Address)
+	}
+	return addr
+}
+
+// getAgentFeatures returns the optional features enabled, which are reported in the agentinfo.
+func (o *Options) getAgentFeatures() []agentv1alpha1.AgentFeature {
+	var features []agentv1alpha1.AgentFeature
+	if o.IsEnableFlowLog() {
+		features = append(features, agentv1alpha1.FeatureFlowLog)
+	}
+	if o.IsEnableDenyLogging() {
+		features = append(features, agentv1alpha1.FeatureDenyLogging)
+	}
+	if o.IsEnablePortScanDetection() && o.Config.PortScanDetection.Quarantine {
+		features = append(features, agentv1alpha1.FeaturePortScanQuarantine)
+	}
+	return features
+}
+
+// getAgentCapacity returns the limits of the agent reported in the agentinfo, nil if not configured.
+func (o *Options) getAgentCapacity() *agentv1alpha1.AgentCapacity {
+	if o.Config.MaxPolicyFlows <= 0 {
+		return nil
+	}
+	return &agentv1alpha1.AgentCapacity{MaxPolicyFlows: o.Config.MaxPolicyFlows}
+}
+
+func (o *Options) getClusterInternalCIDRs(datapathManager *datapath.DpManager) []string {
+	cidrs := append([]string{}, o.Config.ClusterInternalCIDRs...)
+	if o.IsEnableCNI() && datapathManager.Info.ClusterPodCIDR != nil {
+		cidrs = append(cidrs, datapathManager.Info.ClusterPodCIDR.String())
+	}
+	return cidrs
+}
+
+func (o *Options) getFlowLogConfig() flowlog.Config {
+	conf := o.Config.FlowLog
+	return flowlog.Config{
+		Interval:  conf.Interval,
+		LabelKeys: conf.LabelKeys,
+		Path:      conf.Path,
+		MaxSize:   conf.MaxSize,
+		Sink: flowlog.SinkConfig{
+			Type:       flowlog.SinkType(conf.Sink.Type),
+			URL:        conf.Sink.URL,
+			Username:   conf.Sink.Username,
+			Password:   conf.Sink.Password,
+			Database:   conf.Sink.Database,
+			Table:      conf.Sink.Table,
+			Index:      conf.Sink.Index,
+			Retention:  conf.Sink.Retention,
+			BatchSize:  conf.Sink.BatchSize,
+			MaxPending: conf.Sink.MaxPending,
+			Timeout:    conf.Sink.Timeout,
+		},
+	}
+}
+
+func (o *Options) getEndpointClassifierConfig() monitor.ClassifierConfig {
+	conf := o.Config.EndpointClassifier
+	return monitor.ClassifierConfig{
+		Drivers:      conf.Drivers,
+		ExternalIDs:  conf.ExternalIDs,
+		NamePatterns: conf.NamePatterns,
+	}
+}
+
+func (o *Options) getIPSourceConfig() monitor.IPSourceConfig {
+	return monitor.IPSourceConfig{
+		Priority:    o.Config.IPSources.Priority,
+		MergePolicy: o.Config.IPSources.MergePolicy,
+	}
+}
+
 func (o *Options) complete() error {
 	agentConfig, err := getAgentConfig()
 	if err != nil {
@@ -98,9 +635,19 @@ func (o *Options) getDatapathConfig() *datapath.DpManagerConfig {
 	agentConfig := o.Config
 
 	dpConfig := &datapath.DpManagerConfig{
-		InternalIPs:      agentConfig.InternalIPs,
-		EnableIPLearning: true,
-		EnableCNI:        agentConfig.EnableCNI,
+		InternalIPs:        agentConfig.InternalIPs,
+		EnableIPLearning:   true,
+		EnableCNI:          agentConfig.EnableCNI,
+		AllowSpanningTree:  agentConfig.AllowSpanningTreeBridge,
+		OVNInterop:         agentConfig.OVNInterop,
+		EnableDHCPSnooping: agentConfig.EnableDHCPSnooping,
+		EnableDenyLogging:  agentConfig.DenyLogging.Enable,
+		EnableDNSSnooping:  agentConfig.FQDNPolicy.Enable,
+		DNSResolvers:       parseDNSResolvers(agentConfig.FQDNPolicy.Resolvers),
+	}
+
+	if dpConfig.EnableDNSSnooping && len(dpConfig.DNSResolvers) == 0 {
+		klog.Warningf("no dns resolvers configured, the fqdn peers resolve to nothing")
 	}
 
 	managedVDSMap := make(map[string]string)
@@ -109,9 +656,22 @@ func (o *Options) getDatapathConfig() *datapath.DpManagerConfig {
 	}
 	dpConfig.ManagedVDSMap = managedVDSMap
 
+	vrfMap := make(map[string]string)
+	for managedvds, vrf := range agentConfig.VRFConfig {
+		if _, ok := managedVDSMap[managedvds]; !ok {
+			klog.Warningf("ignore vrf %s of unmanaged vds %s", vrf, managedvds)
+			continue
+		}
+		if vrf != "" {
+			vrfMap[managedvds] = vrf
+		}
+	}
+	dpConfig.VRFMap = vrfMap
+
 	if dpConfig.EnableCNI {
 		// cni disable ip learning
 		dpConfig.EnableIPLearning = false
+		dpConfig.EnableDHCPSnooping = false
 
 		// cni config
 		cniConfig := &datapath.DpManagerCNIConfig{
@@ -124,6 +684,27 @@ func (o *Options) getDatapathConfig() *datapath.DpManagerConfig {
 	return dpConfig
 }
 
+// parseDNSResolvers parses the resolvers in ip or cidr format, the invalid ones are ignored.
+func parseDNSResolvers(resolvers []string) []*net.IPNet {
+	var ipNets []*net.IPNet
+	for _, resolver := range resolvers {
+		if ip := net.ParseIP(resolver); ip != nil {
+			if ip.To4() != nil {
+				ip = ip.To4()
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(resolver)
+		if err != nil {
+			klog.Errorf("ignore invalid dns resolver %s: %s", resolver, err)
+			continue
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets
+}
+
 func setAgentConf(datapathManager *datapath.DpManager, k8sReader client.Reader) {
 	var err error
 
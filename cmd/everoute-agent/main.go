@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -40,17 +41,35 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/everoute/everoute/pkg/agent/bfd"
+	"github.com/everoute/everoute/pkg/agent/connstats"
 	"github.com/everoute/everoute/pkg/agent/controller/overlay"
 	"github.com/everoute/everoute/pkg/agent/controller/policy"
 	ctrlProxy "github.com/everoute/everoute/pkg/agent/controller/proxy"
+	"github.com/everoute/everoute/pkg/agent/cri"
 	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/denylog"
+	"github.com/everoute/everoute/pkg/agent/effectiverules"
+	"github.com/everoute/everoute/pkg/agent/flowexporter"
+	"github.com/everoute/everoute/pkg/agent/flowlog"
+	"github.com/everoute/everoute/pkg/agent/fqdn"
+	"github.com/everoute/everoute/pkg/agent/handoff"
+	"github.com/everoute/everoute/pkg/agent/ifaceman"
+	"github.com/everoute/everoute/pkg/agent/l7proxy"
+	"github.com/everoute/everoute/pkg/agent/mirror"
+	"github.com/everoute/everoute/pkg/agent/namecache"
+	"github.com/everoute/everoute/pkg/agent/portscan"
 	"github.com/everoute/everoute/pkg/agent/proxy"
 	"github.com/everoute/everoute/pkg/agent/rpcserver"
+	"github.com/everoute/everoute/pkg/agent/rulehit"
+	"github.com/everoute/everoute/pkg/agent/startup"
+	"github.com/everoute/everoute/pkg/agent/uplink"
 	"github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
 	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
 	"github.com/everoute/everoute/pkg/constants"
 	evehealthz "github.com/everoute/everoute/pkg/healthz"
+	"github.com/everoute/everoute/pkg/metrics"
 	"github.com/everoute/everoute/pkg/monitor"
 	ersource "github.com/everoute/everoute/pkg/source"
 	"github.com/everoute/everoute/pkg/types"
@@ -66,6 +85,8 @@ func init() {
 }
 
 func main() {
+	startupTracker := startup.NewTracker()
+
 	// init opts
 	opts = NewOptions()
 
@@ -76,12 +97,15 @@ func main() {
 	defer klog.Flush()
 
 	// Init everoute datapathManager: init bridge chain config and default flow
-	stopChan := ctrl.SetupSignalHandler()
+	signalChan := ctrl.SetupSignalHandler()
+	// the manager and the datapath are stopped on signal, or after the state handed off
+	stopChan, stop := newStopChan(signalChan)
 	ofportIPMonitorChan := make(chan map[string]net.IP, 1024)
 	proxySyncChan := make(chan event.GenericEvent)
 	overlaySyncChan := make(chan event.GenericEvent)
 	config := ctrl.GetConfigOrDie()
 	config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(constants.ControllerRuntimeQPS, constants.ControllerRuntimeBurst)
+	config.AcceptContentTypes = constants.ControllerRuntimeAcceptContentTypes
 
 	// complete options
 	err := opts.complete()
@@ -91,27 +115,57 @@ func main() {
 
 	// TODO Update vds which is managed by everoute agent from datapathConfig.
 	datapathConfig := opts.getDatapathConfig()
+	previousAgent := receiveHandoff()
 	datapathManager := datapath.NewDatapathManager(datapathConfig, ofportIPMonitorChan)
+	if previousAgent != nil {
+		adoptHandoff(datapathManager, previousAgent.State)
+	}
 	datapathManager.InitializeDatapath(stopChan)
+	startupTracker.Done(startup.PhaseDatapath)
+	if previousAgent != nil {
+		// the previous agent keeps updating the datapath until this agent is ready to install
+		// flows, the manager binds the metrics port after the previous agent released it
+		takeOverHandoff(previousAgent)
+	}
+
+	// the rule counters are collected by the rule hit tracker, and reported by the agent monitor
+	var ruleCounters *rulehit.Counters
+	if opts.IsReportRuleCounters() {
+		ruleCounters = rulehit.NewCounters(opts.getRuleCountersPath())
+	}
 
 	var mgr manager.Manager
+	var ovsdbMonitor *monitor.OVSDBMonitor
 	if opts.IsEnableCNI() {
 		// in the cni scenario, cni initialization must precede ovsdb monitor initialization
 		mgr = initK8sCtrlManager(config, stopChan)
 		initCNI(datapathManager, mgr, proxySyncChan, overlaySyncChan)
-		startMonitor(datapathManager, config, ofportIPMonitorChan, stopChan)
+		ovsdbMonitor = startMonitor(datapathManager, config, ofportIPMonitorChan, ruleCounters, stopChan)
 	} else {
 		// In the virtualization scenario, k8sCtrl manager initializer reply on ovsdbmonitor initialization to connect to kube-apiserver
-		startMonitor(datapathManager, config, ofportIPMonitorChan, stopChan)
+		ovsdbMonitor = startMonitor(datapathManager, config, ofportIPMonitorChan, ruleCounters, stopChan)
 		mgr = initK8sCtrlManager(config, stopChan)
 	}
 
+	// install the local endpoints before the policies, otherwise the traffics of the
+	// endpoints are dropped until the policies of all the endpoints installed
+	if !ovsdbMonitor.WaitForInitialSync(stopChan) {
+		return
+	}
+	startupTracker.Done(startup.PhaseEndpoints)
+
 	// add health check handler
 	loadModuleHealthz := evehealthz.NewLoadModuleHealthz(constants.AlgNeedModules)
 	err = mgr.AddMetricsExtraHandler(constants.HealthCheckPath, healthz.CheckHandler{Checker: loadModuleHealthz.Check})
 	if err != nil {
 		klog.Fatalf("failed to add health check handler: %s", err)
 	}
+	// the exemplars of the realization latency are only served in the OpenMetrics format
+	if err = mgr.AddMetricsExtraHandler(metrics.OpenMetricsPath, metrics.OpenMetricsHandler()); err != nil {
+		klog.Fatalf("failed to add openmetrics handler: %s", err)
+	}
+
+	nameCache := setupNameCache(mgr)
 
 	proxyCache, err := startManager(mgr, datapathManager, stopChan, proxySyncChan, overlaySyncChan)
 	if err != nil {
@@ -120,12 +174,189 @@ func main() {
 
 	rpcServer := rpcserver.Initialize(datapathManager, mgr.GetClient(), opts.IsEnableCNI(), proxyCache)
 	go rpcServer.Run(stopChan)
+	if opts.IsEnableCNI() {
+		go collectVethGarbage(datapathManager, mgr.GetAPIReader(), stopChan)
+	}
 
 	if err := resourceUpdate(mgr, datapathManager, stopChan); err != nil {
 		klog.Fatalf("resource update failed when start everoute-agent, err: %v", err)
 	}
+	startupTracker.Done(startup.PhasePolicy)
+	datapathManager.MarkFlowsSynced()
+	handoffServer := serveHandoff(datapathManager, stopChan)
+
+	if opts.IsEnableRuleHitTracking() {
+		tracker := &rulehit.Tracker{
+			Client:   mgr.GetClient(),
+			Datapath: datapathManager,
+			Config:   opts.getRuleHitConfig(),
+			Counters: ruleCounters,
+		}
+		go tracker.Run(stopChan)
+	}
+
+	if opts.IsEnableFlowLog() {
+		exporter := &flowlog.Exporter{
+			Client:    mgr.GetClient(),
+			Datapath:  datapathManager,
+			Names:     nameCache,
+			Config:    opts.getFlowLogConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}
+		go exporter.Run(stopChan)
+	}
+
+	if opts.IsEnableFlowExporter() {
+		exporter := &flowexporter.Exporter{
+			Datapath: datapathManager,
+			Config:   opts.getFlowExporterConfig(),
+		}
+		go exporter.Run(stopChan)
+	}
+
+	if opts.IsEnableDenyLogging() {
+		logger := &denylog.Logger{
+			Recorder:  mgr.GetEventRecorderFor("everoute-agent"),
+			Datapath:  datapathManager,
+			Config:    opts.getDenyLogConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}
+		go logger.Run(stopChan)
+	}
+
+	if opts.IsEnableEffectiveRules() {
+		exporter := &effectiverules.Exporter{
+			Client:    mgr.GetClient(),
+			Datapath:  datapathManager,
+			Config:    opts.getEffectiveRulesConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}
+		go exporter.Run(stopChan)
+	}
+
+	if opts.IsEnableBFD() {
+		bfdManager := &bfd.Manager{
+			Recorder:  mgr.GetEventRecorderFor("everoute-agent"),
+			Config:    opts.getBFDConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}
+		go bfdManager.Run(stopChan)
+	}
+
+	if opts.IsEnableMirror() {
+		mirrorManager := &mirror.Manager{Config: opts.getMirrorConfig()}
+		go mirrorManager.Run(stopChan)
+	}
 
-	<-stopChan
+	if opts.IsEnableUplinkFailover() {
+		uplinkManager := &uplink.Manager{
+			Recorder:  mgr.GetEventRecorderFor("everoute-agent"),
+			Config:    opts.getUplinkFailoverConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}
+		go uplinkManager.Run(stopChan)
+	}
+
+	select {
+	case <-signalChan:
+	case <-handoffServer.HandedOff():
+		// the new agent owns the datapath from now on, stop updating the datapath and
+		// serving on the host network, wait for the pod deleted
+		klog.Infof("State handed off to the new agent, stop the manager and the datapath and wait for stop")
+		stop()
+		<-signalChan
+	}
+}
+
+// newStopChan returns the channel closed on signal or the stop function called.
+func newStopChan(signalChan <-chan struct{}) (<-chan struct{}, func()) {
+	stopChan := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() { stopOnce.Do(func() { close(stopChan) }) }
+	go func() {
+		<-signalChan
+		stop()
+	}()
+	return stopChan, stop
+}
+
+// receiveHandoff receives the state from the agent running on the node, which is
+// being replaced by this agent. It returns nil if there is nothing to hand off.
+func receiveHandoff() *handoff.Handoff {
+	previousAgent, err := handoff.ReceiveState(constants.HandoffSocketAddr, handoff.DefaultDialTimeout, handoff.DefaultIOTimeout)
+	if err != nil {
+		klog.Errorf("Failed to receive handoff state, start without handoff: %s", err)
+		return nil
+	}
+	return previousAgent
+}
+
+// takeOverHandoff stops the previous agent updating the datapath.
+func takeOverHandoff(previousAgent *handoff.Handoff) {
+	if err := previousAgent.TakeOver(); err != nil {
+		klog.Errorf("Failed to take over from the previous agent: %s", err)
+		return
+	}
+	klog.Infof("Take over the datapath from the previous agent")
+}
+
+// adoptHandoff adopts the flows and the learned ip addresses of the previous agent, so the
+// traffics are not interrupted until the flows of this agent installed.
+func adoptHandoff(datapathManager *datapath.DpManager, state *handoff.State) {
+	learnedIPAddrs := make(map[string]net.IP)
+	learnedIPv6Addrs := make(map[string]net.IP)
+	for _, ep := range state.Endpoints {
+		if ep.IPAddr != nil {
+			learnedIPAddrs[ep.InterfaceUUID] = ep.IPAddr
+		}
+		if ep.IPv6Addr != nil {
+			learnedIPv6Addrs[ep.InterfaceUUID] = ep.IPv6Addr
+		}
+	}
+	datapathManager.AdoptFlows(state.Rounds, learnedIPAddrs, learnedIPv6Addrs)
+	klog.Infof("Adopt flows of rounds %v and %d endpoints from the previous agent", state.Rounds, len(state.Endpoints))
+}
+
+func serveHandoff(datapathManager *datapath.DpManager, stopChan <-chan struct{}) *handoff.Server {
+	server := handoff.NewServer(constants.HandoffSocketAddr, func() *handoff.State {
+		state := &handoff.State{Rounds: datapathManager.GetRoundNums()}
+		for _, ep := range datapathManager.ListLocalEndpoints() {
+			state.Endpoints = append(state.Endpoints, handoff.EndpointState{
+				InterfaceUUID: ep.InterfaceUUID,
+				BridgeName:    ep.BridgeName,
+				PortNo:        ep.PortNo,
+				IPAddr:        ep.IPAddr,
+				IPv6Addr:      ep.IPv6Addr,
+			})
+		}
+		return state
+	})
+	go func() {
+		if err := server.Run(stopChan); err != nil {
+			klog.Errorf("Failed to run handoff server: %s", err)
+		}
+	}()
+	return server
+}
+
+// setupNameCache setup the cache of the ip address names, which are listed on the metrics
+// server for diagnostics. It returns nil if the name cache is disabled.
+func setupNameCache(mgr manager.Manager) *namecache.Cache {
+	if !opts.IsEnableNameCache() {
+		return nil
+	}
+
+	nameCache := namecache.New()
+	if err := (&namecache.Reconciler{
+		Client: mgr.GetClient(),
+		Cache:  nameCache,
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create name cache controller: %s", err)
+	}
+	if err := mgr.AddMetricsExtraHandler(constants.NameCachePath, nameCache.Handler()); err != nil {
+		klog.Fatalf("failed to add name cache handler: %s", err)
+	}
+	return nameCache
 }
 
 func initCNI(datapathManager *datapath.DpManager, mgr manager.Manager, proxySyncChan chan event.GenericEvent, overlaySyncChan chan event.GenericEvent) {
@@ -144,6 +375,135 @@ func initCNI(datapathManager *datapath.DpManager, mgr manager.Manager, proxySync
 
 	setAgentConf(datapathManager, mgr.GetAPIReader())
 	datapathManager.InitializeCNI()
+	recoverVeths(datapathManager, mgr.GetAPIReader())
+}
+
+const (
+	// vethRecoveryTimeout is the time the veth recovery retried, the agent restarts if timeout.
+	vethRecoveryTimeout = time.Minute
+	// vethGCInterval is the interval the orphan veths collected, the veth setup within the
+	// vethGCGracePeriod is skipped, the pod may not be listed yet.
+	vethGCInterval    = 5 * time.Minute
+	vethGCGracePeriod = 2 * time.Minute
+)
+
+// recoverVeths restores the ports and the external ids of the pods lost by the ovsdb, e.g.
+// after the host reboot, it must be done before the ovsdb monitor reports the local endpoints.
+func recoverVeths(datapathManager *datapath.DpManager, k8sReader client.Reader) {
+	ovsDriver := datapathManager.OvsdbDriverMap[datapathManager.Info.BridgeName][datapath.LOCAL_BRIDGE_KEYWORD]
+	ifaceManager := ifaceman.NewManager(ovsDriver, ifaceman.NewStore(constants.CNIVethStorePath))
+	criClient := opts.getCRIClient()
+
+	err := wait.PollImmediate(time.Second, vethRecoveryTimeout, func() (bool, error) {
+		var expected func(record *ifaceman.Record) bool
+		var err error
+		if criClient != nil {
+			expected, err = criSandboxVeth(criClient)
+		} else {
+			expected, err = endpointVeth(k8sReader)
+		}
+		if err != nil {
+			klog.Errorf("failed to list the pods for veth recovery: %s", err)
+			return false, nil
+		}
+
+		// the veth of the pod deleted when the agent down is torn down
+		if err = ifaceManager.Recover(expected); err != nil {
+			klog.Errorf("failed to recover veths: %s", err)
+		}
+		return err == nil, nil
+	})
+	if err != nil {
+		klog.Fatalf("veth recovery not done in %s: %s", vethRecoveryTimeout, err)
+	}
+}
+
+// collectVethGarbage tears down the veths of the pods gone periodically, the ovs ports and the
+// endpoints in the agentinfo leak if the CNI DEL missed.
+func collectVethGarbage(datapathManager *datapath.DpManager, k8sReader client.Reader, stopChan <-chan struct{}) {
+	ovsDriver := datapathManager.OvsdbDriverMap[datapathManager.Info.BridgeName][datapath.LOCAL_BRIDGE_KEYWORD]
+	ifaceManager := ifaceman.NewManager(ovsDriver, ifaceman.NewStore(constants.CNIVethStorePath))
+	criClient := opts.getCRIClient()
+
+	wait.Until(func() {
+		var alive func(record *ifaceman.Record) bool
+		var err error
+		if criClient != nil {
+			alive, err = criSandboxVeth(criClient)
+		} else {
+			alive, err = nodePodVeth(k8sReader, datapathManager.Info.NodeName)
+		}
+		if err != nil {
+			klog.Errorf("failed to list the pods for veth garbage collection: %s", err)
+			return
+		}
+
+		collected, err := ifaceManager.CollectGarbage(alive, vethGCGracePeriod)
+		if err != nil {
+			klog.Errorf("failed to collect orphan veths: %s", err)
+		}
+		if collected != 0 {
+			klog.Infof("collected %d orphan veths", collected)
+		}
+	}, vethGCInterval, stopChan)
+}
+
+// criSandboxVeth returns whether the veth belongs to a ready sandbox of the container runtime,
+// the pod-uuid of the veth is completed by the sandbox.
+func criSandboxVeth(criClient cri.Client) (func(record *ifaceman.Record) bool, error) {
+	index, err := cri.NewIndex(criClient)
+	if err != nil {
+		return nil, err
+	}
+	return func(record *ifaceman.Record) bool {
+		sandbox, ok := index.Sandbox(record.GetContainerID())
+		if !ok || !sandbox.Ready {
+			return false
+		}
+		if record.ExternalIDs == nil {
+			record.ExternalIDs = make(map[string]string)
+		}
+		record.ExternalIDs[constants.PodEndpointExternalIDName] = sandbox.PodUUID()
+		return true
+	}, nil
+}
+
+// endpointVeth returns whether the endpoint of the pod of the veth exists.
+func endpointVeth(k8sReader client.Reader) (func(record *ifaceman.Record) bool, error) {
+	endpoints := v1alpha1.EndpointList{}
+	if err := k8sReader.List(context.Background(), &endpoints); err != nil {
+		return nil, err
+	}
+	pods := make(map[string]bool, len(endpoints.Items))
+	for _, endpoint := range endpoints.Items {
+		if endpoint.Spec.Reference.ExternalIDName == constants.PodEndpointExternalIDName {
+			pods[endpoint.Spec.Reference.ExternalIDValue] = true
+		}
+	}
+	return func(record *ifaceman.Record) bool {
+		return pods[record.ExternalIDs[constants.PodEndpointExternalIDName]]
+	}, nil
+}
+
+// nodePodVeth returns whether the pod of the veth is running on the node.
+func nodePodVeth(k8sReader client.Reader, nodeName string) (func(record *ifaceman.Record) bool, error) {
+	pods := corev1.PodList{}
+	if err := k8sReader.List(context.Background(), &pods, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return nil, err
+	}
+	podMap := make(map[string]*corev1.Pod, len(pods.Items))
+	for index := range pods.Items {
+		pod := &pods.Items[index]
+		podMap[utils.EncodeNamespacedName(coretypes.NamespacedName{Namespace: pod.Namespace, Name: "pod-" + pod.Name})] = pod
+	}
+	return func(record *ifaceman.Record) bool {
+		pod, ok := podMap[record.ExternalIDs[constants.PodEndpointExternalIDName]]
+		if !ok || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return false
+		}
+		// the veth setup before the pod created belongs to the former pod with the same name
+		return record.CreatedAt.IsZero() || !record.CreatedAt.Before(pod.CreationTimestamp.Time)
+	}, nil
 }
 
 func initK8sCtrlManager(config *rest.Config, stopChan <-chan struct{}) manager.Manager {
@@ -153,7 +513,8 @@ func initK8sCtrlManager(config *rest.Config, stopChan <-chan struct{}) manager.M
 	// create eventBroadcaster before manager to avoid goroutine leakage: kubernetes-sigs/controller-runtime#637
 	eventBroadcaster := record.NewBroadcaster()
 
-	// loop initialize manager until success or stop
+	// loop initialize manager until success or stop, e.g. the metrics port not released
+	// by the previous agent yet
 	err = wait.PollImmediateUntil(time.Second, func() (bool, error) {
 		mgr, err = ctrl.NewManager(config, ctrl.Options{
 			Scheme:             clientsetscheme.Scheme,
@@ -171,11 +532,22 @@ func initK8sCtrlManager(config *rest.Config, stopChan <-chan struct{}) manager.M
 	return mgr
 }
 
-func startMonitor(datapathManager *datapath.DpManager, config *rest.Config, ofportIPMonitorChan chan map[string]net.IP, stopChan <-chan struct{}) {
+func startMonitor(datapathManager *datapath.DpManager, config *rest.Config, ofportIPMonitorChan chan map[string]net.IP,
+	ruleCounters *rulehit.Counters, stopChan <-chan struct{}) *monitor.OVSDBMonitor {
 	ovsdbMonitor, err := monitor.NewOVSDBMonitor()
 	if err != nil {
 		klog.Fatalf("unable to create ovsdb monitor: %s", err.Error())
 	}
+	for _, database := range opts.Config.OVSDBDatabases {
+		ovsdbMonitor.ExtraDatabases = append(ovsdbMonitor.ExtraDatabases, monitor.OVSDBDatabase{
+			Name:    database.Name,
+			Address: database.Address,
+		})
+	}
+	ovsdbMonitor.EndpointClassifier, err = monitor.NewEndpointClassifier(opts.getEndpointClassifierConfig())
+	if err != nil {
+		klog.Fatalf("unable to create endpoint classifier: %s", err)
+	}
 	ovsdbMonitor.RegisterOvsdbEventHandler(monitor.OvsdbEventHandlerFuncs{
 		LocalEndpointAddFunc: func(endpoint *datapath.Endpoint) {
 			err := datapathManager.AddLocalEndpoint(endpoint)
@@ -199,22 +571,78 @@ func startMonitor(datapathManager *datapath.DpManager, config *rest.Config, ofpo
 
 	clientset := clientset.NewForConfigOrDie(config)
 	agentmonitor := monitor.NewAgentMonitor(clientset, ovsdbMonitor, ofportIPMonitorChan)
+	if opts.Config.IPAgingTime != 0 {
+		agentmonitor.IPAgingTime = opts.Config.IPAgingTime
+	}
+	if opts.Config.AgentInfoSync.Debounce != 0 {
+		agentmonitor.SyncDebounce = opts.Config.AgentInfoSync.Debounce
+	}
+	if opts.Config.AgentInfoSync.MaxDelay != 0 {
+		agentmonitor.SyncMaxDelay = opts.Config.AgentInfoSync.MaxDelay
+	}
+	agentmonitor.OpenflowConnected = datapathManager.IsBridgesConnected
+	agentmonitor.FlowsSynced = datapathManager.IsFlowsSynced
+	agentmonitor.DHCPLeases = datapathManager.DHCPLeases()
+	agentmonitor.IPSourcePolicy, err = monitor.NewIPSourcePolicy(opts.getIPSourceConfig())
+	if err != nil {
+		klog.Fatalf("unable to create ip source policy: %s", err)
+	}
+	agentmonitor.ConntrackZones = datapathManager.GetCTZones
+	agentmonitor.VRFs = datapathManager.GetVRFs
+	agentmonitor.OVNInterop = opts.Config.OVNInterop
+	if opts.IsEnableConnectionStats() {
+		poller := &connstats.Poller{Interval: opts.Config.ConnectionStats.Interval}
+		agentmonitor.ConnectionStats = poller.Stats
+		go poller.Run(stopChan)
+	}
+	if ruleCounters != nil {
+		agentmonitor.PolicyRuleStats = ruleCounters.Stats
+	}
+	agentmonitor.Features = opts.getAgentFeatures()
+	agentmonitor.RuleFeatures = policy.SupportedRuleFeatures(opts.IsEnableFQDNPolicy(), opts.IsEnableL7Proxy())
+	agentmonitor.Capacity = opts.getAgentCapacity()
 
 	go ovsdbMonitor.Run(stopChan)
 	go agentmonitor.Run(stopChan)
+
+	return ovsdbMonitor
 }
 
 func startManager(mgr manager.Manager, datapathManager *datapath.DpManager, stopChan <-chan struct{}, proxySyncChan chan event.GenericEvent,
 	overlaySyncChan chan event.GenericEvent) (*ctrlProxy.Cache, error) {
 	var err error
 	// Policy controller: watch policy related resource and update
-	if err = (&policy.Reconciler{
+	policyReconciler := &policy.Reconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
 		DatapathManager: datapathManager,
-	}).SetupWithManager(mgr); err != nil {
+		CompileBudget:   opts.Config.PolicyCompileBudget,
+
+		ClusterInternalCIDRs: opts.getClusterInternalCIDRs(datapathManager),
+		FQDNCache:            opts.getFQDNCache(),
+		L7Proxy:              opts.getL7ProxyAddr(),
+	}
+	if err = policyReconciler.SetupWithManager(mgr); err != nil {
 		klog.Fatalf("unable to create policy controller: %s", err.Error())
 	}
+	if policyReconciler.FQDNCache != nil {
+		// recompute the policies with the fqdn peers when the addresses of the names changed
+		snooper := &fqdn.Snooper{
+			Cache:    policyReconciler.FQDNCache,
+			Datapath: datapathManager,
+			OnChange: policyReconciler.ProcessFQDNChanges,
+		}
+		go snooper.Run(stopChan)
+	}
+	if policyReconciler.L7Proxy != nil {
+		// the traffic matching the rules with l7 criteria are redirected to the proxy
+		l7Proxy := &l7proxy.Proxy{
+			Config:   opts.getL7ProxyConfig(),
+			Datapath: datapathManager,
+			Rules:    policyReconciler,
+		}
+		go l7Proxy.Run(stopChan)
+	}
 
 	if opts.IsEnableCNI() {
 		if err = proxy.SetupRouteAndIPtables(mgr, datapathManager, stopChan); err != nil {
@@ -235,6 +663,17 @@ func startManager(mgr manager.Manager, datapathManager *datapath.DpManager, stop
 		}
 	}
 
+	if opts.IsEnablePortScanDetection() {
+		if err = (&portscan.Detector{
+			Client:    mgr.GetClient(),
+			Recorder:  mgr.GetEventRecorderFor("everoute-agent"),
+			Config:    opts.getPortScanConfig(),
+			AgentName: utils.CurrentAgentName(),
+		}).SetupWithManager(mgr); err != nil {
+			klog.Fatalf("unable to create port scan detector: %s", err.Error())
+		}
+	}
+
 	var proxyCache *ctrlProxy.Cache
 	if opts.IsEnableProxy() {
 		proxyReconciler := &ctrlProxy.Reconciler{
@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/klog"
 
 	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/controller/iphistory"
+	"github.com/everoute/everoute/pkg/controller/notifier"
+	"github.com/everoute/everoute/pkg/controller/snmptrap"
+	"github.com/everoute/everoute/pkg/webhook/validates"
 )
 
 const configPath = "/var/lib/everoute/controllerconfig.yaml"
@@ -26,6 +31,37 @@ type Options struct {
 type controllerConfig struct {
 	EnableCNI bool    `yaml:"enableCNI,omitempty"`
 	CNIConf   CNIConf `yaml:"CNIConf,omitempty"`
+
+	// Notifier posts policy violations, quarantines and realization failures to external webhooks
+	Notifier NotifierConf `yaml:"notifier,omitempty"`
+
+	// SNMPTrap sends snmp traps on critical conditions, e.g. agent down, uplink down
+	SNMPTrap SNMPTrapConf `yaml:"snmpTrap,omitempty"`
+
+	// GroupMemberEnrichment populates the endpoint metadata in the group members,
+	// e.g. namespace, node, labels hash and endpoint type
+	GroupMemberEnrichment bool `yaml:"groupMemberEnrichment,omitempty"`
+
+	// GroupMembersCompaction is the number of the groupmemberspatches accumulated before
+	// compacted into the groupmembers, the agents receive only the patches in between, the
+	// groupmembers are rewritten on each change if zero
+	GroupMembersCompaction int32 `yaml:"groupMembersCompaction,omitempty"`
+
+	// PolicyDelegation restricts the policies to reference the peers in other namespaces,
+	// unless the namespaces delegated to the policy namespace
+	PolicyDelegation PolicyDelegationConf `yaml:"policyDelegation,omitempty"`
+
+	// PolicyViews serves the read only views of the policies as an aggregated api, the tenants
+	// could read the views of their namespaces without the cluster-wide read of the groups
+	PolicyViews PolicyViewsConf `yaml:"policyViews,omitempty"`
+
+	// IPHistory records the ips learned and unlearned by the endpoints on the local disk, the
+	// history of the ips are served by the policy views
+	IPHistory IPHistoryConf `yaml:"ipHistory,omitempty"`
+
+	// Exporter serves the policies, groups, endpoints and agents from the cache of the
+	// controller as a read only grpc stream, for the heavy consumers instead of the kube-apiserver
+	Exporter ExporterConf `yaml:"exporter,omitempty"`
 }
 
 type CNIConf struct {
@@ -33,6 +69,54 @@ type CNIConf struct {
 	EncapMode   string `yaml:"encapMode,omitempty"`
 }
 
+type NotifierConf struct {
+	Enable        bool                   `yaml:"enable,omitempty"`
+	Endpoints     []NotifierEndpointConf `yaml:"endpoints,omitempty"`
+	MaxRetries    int                    `yaml:"maxRetries,omitempty"`
+	RetryInterval time.Duration          `yaml:"retryInterval,omitempty"`
+	Timeout       time.Duration          `yaml:"timeout,omitempty"`
+}
+
+type NotifierEndpointConf struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret,omitempty"`
+}
+
+type SNMPTrapConf struct {
+	Enable           bool                 `yaml:"enable,omitempty"`
+	Targets          []SNMPTrapTargetConf `yaml:"targets,omitempty"`
+	EnterpriseOID    string               `yaml:"enterpriseOID,omitempty"`
+	Interval         time.Duration        `yaml:"interval,omitempty"`
+	AgentDownTimeout time.Duration        `yaml:"agentDownTimeout,omitempty"`
+}
+
+type SNMPTrapTargetConf struct {
+	Address   string `yaml:"address"`
+	Community string `yaml:"community,omitempty"`
+}
+
+type PolicyDelegationConf struct {
+	Enable           bool     `yaml:"enable,omitempty"`
+	PrivilegedUsers  []string `yaml:"privilegedUsers,omitempty"`
+	PrivilegedGroups []string `yaml:"privilegedGroups,omitempty"`
+}
+
+type PolicyViewsConf struct {
+	Enable bool `yaml:"enable,omitempty"`
+	Port   int  `yaml:"port,omitempty"`
+}
+
+type ExporterConf struct {
+	Enable bool `yaml:"enable,omitempty"`
+	Port   int  `yaml:"port,omitempty"`
+}
+
+type IPHistoryConf struct {
+	Enable  bool   `yaml:"enable,omitempty"`
+	Path    string `yaml:"path,omitempty"`
+	MaxSize int64  `yaml:"maxSize,omitempty"`
+}
+
 func NewOptions() *Options {
 	return &Options{
 		Config: &controllerConfig{},
@@ -59,6 +143,73 @@ func (o *Options) IsEnableOverlay() bool {
 	return o.Config.CNIConf.EncapMode == constants.EncapModeGeneve
 }
 
+func (o *Options) IsEnableNotifier() bool {
+	return o.Config.Notifier.Enable && len(o.Config.Notifier.Endpoints) != 0
+}
+
+func (o *Options) getNotifierConfig() notifier.Config {
+	conf := o.Config.Notifier
+	config := notifier.Config{
+		MaxRetries:    conf.MaxRetries,
+		RetryInterval: conf.RetryInterval,
+		Timeout:       conf.Timeout,
+	}
+	for _, endpoint := range conf.Endpoints {
+		config.Endpoints = append(config.Endpoints, notifier.Endpoint{URL: endpoint.URL, Secret: endpoint.Secret})
+	}
+	return config
+}
+
+func (o *Options) IsEnableSNMPTrap() bool {
+	return o.Config.SNMPTrap.Enable && len(o.Config.SNMPTrap.Targets) != 0
+}
+
+func (o *Options) getSNMPTrapConfig() snmptrap.Config {
+	conf := o.Config.SNMPTrap
+	config := snmptrap.Config{
+		EnterpriseOID:    conf.EnterpriseOID,
+		Interval:         conf.Interval,
+		AgentDownTimeout: conf.AgentDownTimeout,
+	}
+	for _, target := range conf.Targets {
+		config.Targets = append(config.Targets, snmptrap.Target{Address: target.Address, Community: target.Community})
+	}
+	return config
+}
+
+// getPolicyDelegation returns nil if the policy delegation disabled.
+func (o *Options) getPolicyDelegation() *validates.PolicyDelegation {
+	conf := o.Config.PolicyDelegation
+	if !conf.Enable {
+		return nil
+	}
+	return &validates.PolicyDelegation{
+		PrivilegedUsers:  conf.PrivilegedUsers,
+		PrivilegedGroups: conf.PrivilegedGroups,
+	}
+}
+
+func (o *Options) IsEnablePolicyViews() bool {
+	return o.Config.PolicyViews.Enable
+}
+
+func (o *Options) IsEnableExporter() bool {
+	return o.Config.Exporter.Enable
+}
+
+// getIPHistoryStore returns nil if the ip history disabled.
+func (o *Options) getIPHistoryStore() *iphistory.Store {
+	conf := o.Config.IPHistory
+	if !conf.Enable {
+		return nil
+	}
+	store := &iphistory.Store{Path: conf.Path, MaxSize: conf.MaxSize}
+	if store.Path == "" {
+		store.Path = iphistory.DefaultPath
+	}
+	return store
+}
+
 func (o *Options) complete() error {
 	config, err := getControllerConfig()
 	if err != nil {
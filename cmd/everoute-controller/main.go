@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"crypto/x509"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"net"
@@ -27,9 +28,11 @@ import (
 
 	"github.com/cenkalti/backoff"
 	admv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	certutil "k8s.io/client-go/util/cert"
@@ -39,13 +42,29 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/everoute/everoute/pkg/apiserver"
 	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
 	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/controller/bypass"
+	"github.com/everoute/everoute/pkg/controller/capacity"
+	"github.com/everoute/everoute/pkg/controller/clusterstatus"
 	"github.com/everoute/everoute/pkg/controller/common"
+	"github.com/everoute/everoute/pkg/controller/compliance"
 	endpointctrl "github.com/everoute/everoute/pkg/controller/endpoint"
 	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	"github.com/everoute/everoute/pkg/controller/iphistory"
 	"github.com/everoute/everoute/pkg/controller/k8s"
+	"github.com/everoute/everoute/pkg/controller/namespacedefault"
+	"github.com/everoute/everoute/pkg/controller/notifier"
 	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/controller/policyplan"
+	"github.com/everoute/everoute/pkg/controller/quarantine"
+	"github.com/everoute/everoute/pkg/controller/reachability"
+	"github.com/everoute/everoute/pkg/controller/snmptrap"
+	"github.com/everoute/everoute/pkg/controller/tier"
+	"github.com/everoute/everoute/pkg/controller/topology"
+	"github.com/everoute/everoute/pkg/crdschema"
+	"github.com/everoute/everoute/pkg/exporter"
 	"github.com/everoute/everoute/pkg/healthz"
 	"github.com/everoute/everoute/pkg/webhook"
 	towerplugin "github.com/everoute/everoute/plugin/tower/pkg/register"
@@ -58,6 +77,7 @@ func init() {
 	utilruntime.Must(corev1.AddToScheme(clientsetscheme.Scheme))
 	utilruntime.Must(admv1.AddToScheme(clientsetscheme.Scheme))
 	utilruntime.Must(networkingv1.AddToScheme(clientsetscheme.Scheme))
+	utilruntime.Must(authorizationv1.AddToScheme(clientsetscheme.Scheme))
 }
 
 func main() {
@@ -84,6 +104,7 @@ func main() {
 
 	config := ctrl.GetConfigOrDie()
 	config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(constants.ControllerRuntimeQPS, constants.ControllerRuntimeBurst)
+	config.AcceptContentTypes = constants.ControllerRuntimeAcceptContentTypes
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:                  clientsetscheme.Scheme,
 		MetricsBindAddress:      opts.metricsAddr,
@@ -106,6 +127,9 @@ func main() {
 		}).SetupWithManager(mgr); err != nil {
 			klog.Fatalf("unable to create webhook controller: %s", err.Error())
 		}
+		if opts.IsEnablePolicyViews() {
+			setAPIServiceCABundle(mgr.GetAPIReader())
+		}
 	}
 
 	// endpoint controller sync endpoint status from agentinfo.
@@ -118,8 +142,11 @@ func main() {
 
 	// group controller sync & manager group members.
 	if err = (&groupctrl.GroupReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("everoute-controller"),
+		EnrichMembers:  opts.Config.GroupMemberEnrichment,
+		CompactPatches: opts.Config.GroupMembersCompaction,
 	}).SetupWithManager(mgr); err != nil {
 		klog.Fatalf("unable to create group controller: %s", err.Error())
 	}
@@ -132,6 +159,146 @@ func main() {
 		klog.Fatalf("unable to create policy controller: %s", err.Error())
 	}
 
+	// quarantine controller enforce quarantines with tier0 policies.
+	if err = (&quarantine.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("everoute-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create quarantine controller: %s", err.Error())
+	}
+
+	// bypass controller bypass the enforcement of the endpoints for troubleshooting until expired.
+	if err = (&bypass.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("everoute-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create bypass controller: %s", err.Error())
+	}
+
+	// namespacedefault controller enforce the default deny of the namespaces selected by NamespaceDefaultPolicies.
+	if err = (&namespacedefault.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("everoute-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create namespacedefault controller: %s", err.Error())
+	}
+
+	// tier controller keep the default Tiers of the policies exist.
+	if err = (&tier.Reconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create tier controller: %s", err.Error())
+	}
+
+	// topology controller build the network topology of each agent from its agentinfo.
+	if err = (&topology.Reconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create topology controller: %s", err.Error())
+	}
+
+	// compliance controller evaluate the compliance checks of the ComplianceReports.
+	if err = (&compliance.Reconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create compliance controller: %s", err.Error())
+	}
+
+	// reachability controller analyze the reachability between the groups of the ReachabilityMatrices.
+	if err = (&reachability.Reconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create reachability controller: %s", err.Error())
+	}
+
+	// policyplan controller plan the changes of the PolicyPlans, and apply them once approved.
+	if err = (&policyplan.Reconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create policyplan controller: %s", err.Error())
+	}
+
+	// capacity controller warn on the agents the policy flows estimated over their capacity.
+	if err = (&capacity.Reconciler{
+		Client:   mgr.GetClient(),
+		Recorder: mgr.GetEventRecorderFor("everoute-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create capacity controller: %s", err.Error())
+	}
+
+	// clusterstatus controller summarize the health of everoute into the singleton ClusterStatus.
+	if err = (&clusterstatus.Reconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create clusterstatus controller: %s", err.Error())
+	}
+
+	if opts.IsEnableNotifier() {
+		// notifier controller forward security events to external webhooks.
+		if err = (&notifier.EventReconciler{
+			Client:   mgr.GetClient(),
+			Notifier: notifier.New(opts.getNotifierConfig()),
+		}).SetupWithManager(mgr); err != nil {
+			klog.Fatalf("unable to create notifier controller: %s", err.Error())
+		}
+		klog.Info("start notifier controller")
+	}
+
+	if opts.IsEnableSNMPTrap() {
+		// snmp trap exporter send traps on critical conditions to the NOC.
+		if err = mgr.Add(&snmptrap.Exporter{
+			Reader: mgr.GetClient(),
+			Config: opts.getSNMPTrapConfig(),
+		}); err != nil {
+			klog.Fatalf("unable to create snmp trap exporter: %s", err.Error())
+		}
+		klog.Info("start snmp trap exporter")
+	}
+
+	ipHistoryStore := opts.getIPHistoryStore()
+	if ipHistoryStore != nil {
+		// iphistory controller records the ips learned and unlearned by the endpoints.
+		if err = (&iphistory.Reconciler{
+			Client: mgr.GetClient(),
+			Store:  ipHistoryStore,
+		}).SetupWithManager(mgr); err != nil {
+			klog.Fatalf("unable to create iphistory controller: %s", err.Error())
+		}
+		klog.Infof("start iphistory controller, records in %s", ipHistoryStore.Path)
+	}
+
+	if opts.IsEnablePolicyViews() {
+		// views aggregated api serves the policy views authorized by the namespace RBAC, the
+		// ip owners looked up by the ip indexes and the ip histories if recorded.
+		if err = (&apiserver.Server{
+			Reader:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			CertDir:   opts.tlsCertDir,
+			Port:      opts.Config.PolicyViews.Port,
+			IPHistory: ipHistoryStore,
+		}).SetupWithManager(mgr); err != nil {
+			klog.Fatalf("unable to create views aggregated api server: %s", err.Error())
+		}
+		klog.Info("start views aggregated api server")
+	}
+
+	if opts.IsEnableExporter() {
+		// exporter streams the objects from the cache, the requesters authorized by their RBAC.
+		if err = (&exporter.Server{
+			Cache:   mgr.GetCache(),
+			Client:  mgr.GetClient(),
+			CertDir: opts.tlsCertDir,
+			Port:    opts.Config.Exporter.Port,
+		}).SetupWithManager(mgr); err != nil {
+			klog.Fatalf("unable to create exporter rpc server: %s", err.Error())
+		}
+		klog.Info("start exporter rpc server")
+	}
+
 	if opts.IsEnableCNI() {
 		// pod controller
 		if err = (&k8s.PodReconciler{
@@ -174,19 +341,35 @@ func main() {
 		}
 	}
 
+	// report the existing policies no longer compile before serving webhooks
+	if err = ctrlpolicy.CompileExistingPolicies(context.Background(), mgr.GetAPIReader(), mgr.GetClient().Status()); err != nil {
+		klog.Errorf("unable to compile existing policies: %s", err)
+	}
+
 	// register validate handle
 	if err = (&webhook.ValidateWebhook{
-		Scheme: mgr.GetScheme(),
+		Scheme:           mgr.GetScheme(),
+		PolicyDelegation: opts.getPolicyDelegation(),
 	}).SetupWithManager(mgr); err != nil {
 		klog.Fatalf("unable to create crd validate webhook %s", err.Error())
 	}
 
+	// register mutate handle, set the defaults of the objects before validate
+	if err = (&webhook.MutateWebhook{
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		klog.Fatalf("unable to create crd mutate webhook %s", err.Error())
+	}
+
 	// register tower plugin
 	err = towerplugin.AddToManager(&towerPluginOptions, mgr)
 	if err != nil {
 		klog.Fatalf("unable register tower plugin: %s", err.Error())
 	}
 
+	// publish the JSON schemas of the CRDs for the IaC tools and the IDEs
+	mgr.GetWebhookServer().Register(constants.CRDSchemaPath+"/", crdschema.Handler(constants.CRDSchemaPath))
+
 	// install /healthz handler
 	healthz.InstallHandler(mgr.GetWebhookServer(),
 		healthz.PingHealthz,
@@ -243,6 +426,40 @@ func setWebhookCert(k8sReader client.Reader) {
 	}
 }
 
+// setAPIServiceCABundle set the ca of the secret as the caBundle of the views APIService.
+func setAPIServiceCABundle(k8sReader client.Reader) {
+	ctx := context.Background()
+	k8sClient := k8sReader.(client.Client)
+
+	secret := &corev1.Secret{}
+	secretReq := types.NamespacedName{
+		Name:      constants.EverouteSecretName,
+		Namespace: constants.EverouteSecretNamespace,
+	}
+
+	if err := backoff.Retry(func() error {
+		if err := k8sClient.Get(ctx, secretReq, secret); err != nil {
+			return err
+		}
+		apiService := &unstructured.Unstructured{}
+		apiService.SetAPIVersion("apiregistration.k8s.io/v1")
+		apiService.SetKind("APIService")
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: constants.EverouteViewsAPIServiceName}, apiService); err != nil {
+			return err
+		}
+		caBundle := base64.StdEncoding.EncodeToString(secret.Data["ca.crt"])
+		if current, _, _ := unstructured.NestedString(apiService.Object, "spec", "caBundle"); current == caBundle {
+			return nil
+		}
+		if err := unstructured.SetNestedField(apiService.Object, caBundle, "spec", "caBundle"); err != nil {
+			return err
+		}
+		return k8sClient.Update(ctx, apiService)
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 10)); err != nil {
+		klog.Fatalf("fail to update apiservice %s after 10 tries. err: %s", constants.EverouteViewsAPIServiceName, err)
+	}
+}
+
 func genSecret(secretReq types.NamespacedName) *corev1.Secret {
 	data := make(map[string][]byte)
 
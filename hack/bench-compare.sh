@@ -0,0 +1,40 @@
+#!/bin/bash -eu
+# Copyright 2021 The Everoute Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# compare the policy compile benchmarks with the baseline, exit with error when the
+# time/op of any benchmark regresses more than the threshold percent.
+# usage: bench-compare.sh <baseline> <current> [threshold]
+
+baseline=$1
+current=$2
+threshold=${3:-20}
+
+if [ ! -f "${baseline}" ]; then
+  echo "baseline ${baseline} not found, create it with make bench-baseline on the reference machine"
+  exit 1
+fi
+
+which benchstat >/dev/null || go install golang.org/x/perf/cmd/benchstat@v0.0.0-20220411212318-84e58bfe0a7e
+benchstat -delta-test utest "${baseline}" "${current}" | tee /dev/stderr | awk -v threshold="${threshold}" '
+  /^name/ { timeop = ($0 ~ /time\/op/) }
+  timeop && $0 ~ /[+-][0-9.]+%/ {
+    for (i = 1; i <= NF; i++) {
+      if ($i ~ /^\+[0-9.]+%$/ && substr($i, 2, length($i) - 2) + 0 > threshold) {
+        print "regression: " $1 " " $i; failed = 1
+      }
+    }
+  }
+  END { exit failed }
+'
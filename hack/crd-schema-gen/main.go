@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// crd-schema-gen generates the JSON schemas of the everoute CRDs, with an index of them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/crdschema"
+)
+
+func main() {
+	var crdDir, outDir string
+	flag.StringVar(&crdDir, "crd-dir", "deploy/chart/templates/crds", "The directory of the CRD manifests.")
+	flag.StringVar(&outDir, "out-dir", "pkg/crdschema/schemas", "The directory the schemas written to, removed before written.")
+	flag.Parse()
+
+	schemas, err := crdschema.LoadCRDDir(crdDir)
+	if err != nil {
+		klog.Fatalf("unable load crds from %s: %s", crdDir, err)
+	}
+
+	if err = os.RemoveAll(outDir); err != nil {
+		klog.Fatalf("unable remove %s: %s", outDir, err)
+	}
+	for _, schema := range schemas {
+		file := filepath.Join(outDir, filepath.FromSlash(schema.Path))
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			klog.Fatalf("unable create %s: %s", filepath.Dir(file), err)
+		}
+		if err = ioutil.WriteFile(file, schema.Content, 0644); err != nil {
+			klog.Fatalf("unable write %s: %s", file, err)
+		}
+	}
+
+	index, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		klog.Fatalf("unable encode index: %s", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(outDir, crdschema.IndexFile), append(index, '\n'), 0644); err != nil {
+		klog.Fatalf("unable write index: %s", err)
+	}
+}
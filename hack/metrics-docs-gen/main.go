@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// metrics-docs-gen generates the documentation of the everoute metrics. The metrics are
+// defined when the packages initialized, the packages defining metrics must be imported.
+package main
+
+import (
+	"flag"
+	"os"
+
+	"k8s.io/klog"
+
+	_ "github.com/everoute/everoute/pkg/agent/controller/policy"
+	_ "github.com/everoute/everoute/pkg/agent/startup"
+	_ "github.com/everoute/everoute/pkg/agent/uplink"
+	"github.com/everoute/everoute/pkg/metrics"
+	_ "github.com/everoute/everoute/pkg/monitor"
+)
+
+func main() {
+	var outFile string
+	flag.StringVar(&outFile, "out-file", "docs/content/en/docs/reference/metrics.md", "The file the documentation written to.")
+	flag.Parse()
+
+	file, err := os.Create(outFile)
+	if err != nil {
+		klog.Fatalf("unable create %s: %s", outFile, err)
+	}
+	defer file.Close()
+
+	if err = metrics.WriteMarkdown(file, metrics.Definitions()); err != nil {
+		klog.Fatalf("unable write %s: %s", outFile, err)
+	}
+}
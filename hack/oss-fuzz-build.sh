@@ -0,0 +1,20 @@
+#!/bin/bash -eu
+# Copyright 2021 The Everoute Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# build script of everoute fuzz targets in oss-fuzz, the targets are go-fuzz style
+# functions guarded by the gofuzz build tag.
+
+compile_go_fuzzer github.com/everoute/everoute/pkg/monitor FuzzFetchPort fuzz_monitor_fetch_port gofuzz
+compile_go_fuzzer github.com/everoute/everoute/pkg/monitor FuzzInterfaceRow fuzz_monitor_interface_row gofuzz
@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// pipeline-docs-gen generates the documentation of the openflow table layout of the bridges.
+package main
+
+import (
+	"flag"
+	"os"
+
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+func main() {
+	var outFile string
+	flag.StringVar(&outFile, "out-file", "docs/content/en/docs/reference/pipeline.md", "The file the documentation written to.")
+	flag.Parse()
+
+	file, err := os.Create(outFile)
+	if err != nil {
+		klog.Fatalf("unable create %s: %s", outFile, err)
+	}
+	defer file.Close()
+
+	if err = datapath.WritePipelineMarkdown(file); err != nil {
+		klog.Fatalf("unable write %s: %s", outFile, err)
+	}
+}
@@ -0,0 +1,356 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package appctl wraps the ovs-appctl commands for datapath diagnostics and fdb
+// control, and parses their outputs into structured results.
+package appctl
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	CommandDumpFlows = "dpctl/dump-flows"
+	CommandFDBShow   = "fdb/show"
+	CommandFDBFlush  = "fdb/flush"
+	CommandFDBDel    = "fdb/del"
+	CommandCoverage  = "coverage/show"
+	CommandTrace     = "ofproto/trace"
+	// CommandBondSetActive is renamed to bond/set-active-member since ovs 2.16,
+	// the old name is still accepted.
+	CommandBondSetActive = "bond/set-active-slave"
+)
+
+// allowedCommands are the commands could be run by the wrapper, all of them
+// are read only except fdb/flush, fdb/del and bond/set-active-slave.
+var allowedCommands = map[string]bool{
+	CommandDumpFlows: true,
+	CommandFDBShow:   true,
+	CommandFDBFlush:  true,
+	CommandFDBDel:    true,
+	CommandCoverage:  true,
+	CommandTrace:     true,
+
+	CommandBondSetActive: true,
+}
+
+// DatapathFlow is a flow in the kernel datapath
+type DatapathFlow struct {
+	Match   string `json:"match"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	Used    string `json:"used"`
+	Flags   string `json:"flags,omitempty"`
+	Actions string `json:"actions"`
+}
+
+// FDBEntry is a learned mac in the bridge forwarding database
+type FDBEntry struct {
+	Port string `json:"port"`
+	VLAN int    `json:"vlan"`
+	MAC  string `json:"mac"`
+	// Age is the seconds since the mac last seen, or "static" for static entries
+	Age string `json:"age"`
+}
+
+// CoverageCounter is an ovs internal event counter
+type CoverageCounter struct {
+	Name string `json:"name"`
+	// average rates per second over last 5 seconds, last minute and last hour
+	Rate5s     float64 `json:"rate5s"`
+	RateMinute float64 `json:"rateMinute"`
+	RateHour   float64 `json:"rateHour"`
+	Total      uint64  `json:"total"`
+}
+
+// TraceStep is an openflow table the traced packet went through
+type TraceStep struct {
+	Bridge   string `json:"bridge"`
+	Table    uint8  `json:"table"`
+	Match    string `json:"match,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Cookie   uint64 `json:"cookie,omitempty"`
+	// NoMatch means the packet matched no flow in the table
+	NoMatch bool     `json:"noMatch,omitempty"`
+	Actions []string `json:"actions,omitempty"`
+}
+
+// TraceResult is the pipeline the traced packet went through, across the patch ports
+// and the recirculations of conntrack.
+type TraceResult struct {
+	Flow  string      `json:"flow"`
+	Steps []TraceStep `json:"steps"`
+	// DatapathActions are the actions of the last recirculation, drop if the packet dropped
+	DatapathActions string `json:"datapathActions"`
+}
+
+// Dropped returns whether the traced packet dropped at the end of the pipeline.
+func (r *TraceResult) Dropped() bool {
+	return r.DatapathActions == "" || r.DatapathActions == "drop"
+}
+
+var (
+	// traceBridgeRegexp match the bridge the trace entered, e.g. bridge("ovsbr0-policy")
+	traceBridgeRegexp = regexp.MustCompile(`^bridge\("([^"]+)"\)$`)
+	// traceTableRegexp match the table the trace went through, e.g.
+	// "10. ip,nw_src=10.0.0.1, priority 200, cookie 0x4000000000001" or " 0. No match."
+	traceTableRegexp = regexp.MustCompile(`^(\d+)\. (.*)$`)
+)
+
+// coverageRegexp match the counters in coverage/show, e.g.
+// "bridge_reconfigure   0.0/sec   0.000/sec   0.0003/sec   total: 5"
+var coverageRegexp = regexp.MustCompile(`^(\S+)\s+([\d.]+)/sec\s+([\d.]+)/sec\s+([\d.]+)/sec\s+total: (\d+)`)
+
+// Run the ovs-appctl command with args, only the allowed commands could be run.
+// The args passed to ovs-appctl directly without shell.
+func Run(command string, args ...string) (string, error) {
+	if !allowedCommands[command] {
+		return "", fmt.Errorf("command %s not allowed", command)
+	}
+	out, err := exec.Command("ovs-appctl", append([]string{command}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ovs-appctl %s: %s: %s", command, err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// DumpDatapathFlows return the flows in the kernel datapath.
+func DumpDatapathFlows() ([]DatapathFlow, error) {
+	out, err := Run(CommandDumpFlows)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDatapathFlows(out)
+}
+
+// ShowFDB return the forwarding database of the bridge.
+func ShowFDB(bridge string) ([]FDBEntry, error) {
+	out, err := Run(CommandFDBShow, bridge)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFDB(out)
+}
+
+// FlushFDB remove the learned macs on the port of the bridge, remove all the macs
+// of the bridge if the port is empty. The port is the ofport number or LOCAL, as
+// shown by ShowFDB. Return the number of entries removed.
+func FlushFDB(bridge, port string) (int, error) {
+	entries, err := ShowFDB(bridge)
+	if err != nil {
+		return 0, err
+	}
+
+	if port == "" {
+		if _, err = Run(CommandFDBFlush, bridge); err != nil {
+			return 0, err
+		}
+		return len(entries), nil
+	}
+
+	var flushed int
+	for _, entry := range entries {
+		if entry.Port != port || entry.Age == "static" {
+			continue
+		}
+		if _, err = Run(CommandFDBDel, bridge, strconv.Itoa(entry.VLAN), entry.MAC); err != nil {
+			return flushed, err
+		}
+		flushed++
+	}
+	return flushed, nil
+}
+
+// ShowCoverage return the ovs internal event counters which have been hit.
+func ShowCoverage() ([]CoverageCounter, error) {
+	out, err := Run(CommandCoverage)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCoverage(out), nil
+}
+
+// Trace the packet described by the flow in the bridge, by ofproto/trace, e.g.
+// flow "tcp,in_port=vnet0,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_dst=80".
+func Trace(bridge, flow string) (*TraceResult, error) {
+	out, err := Run(CommandTrace, bridge, flow)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrace(out), nil
+}
+
+// ParseDatapathFlows parse the output of dpctl/dump-flows, e.g.
+// "recirc_id(0),in_port(2),eth_type(0x0800),ipv4(frag=no), packets:3, bytes:294, used:0.532s, actions:1"
+func ParseDatapathFlows(output string) ([]DatapathFlow, error) {
+	var flows []DatapathFlow
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		index := strings.Index(line, " packets:")
+		if index == -1 {
+			return nil, fmt.Errorf("unexpected datapath flow %s", line)
+		}
+		flow := DatapathFlow{Match: strings.TrimSuffix(line[:index], ",")}
+
+		stats := line[index+1:]
+		if actionsIndex := strings.Index(stats, "actions:"); actionsIndex != -1 {
+			flow.Actions = stats[actionsIndex+len("actions:"):]
+			stats = stats[:actionsIndex]
+		}
+		for _, field := range strings.Split(stats, ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "packets":
+				flow.Packets, _ = strconv.ParseUint(kv[1], 10, 64)
+			case "bytes":
+				flow.Bytes, _ = strconv.ParseUint(kv[1], 10, 64)
+			case "used":
+				flow.Used = kv[1]
+			case "flags":
+				flow.Flags = kv[1]
+			}
+		}
+		flows = append(flows, flow)
+	}
+
+	return flows, nil
+}
+
+// ParseFDB parse the output of fdb/show, e.g.
+// " port  VLAN  MAC                Age"
+// "    1     0  52:54:00:5e:2a:0c    3"
+func ParseFDB(output string) ([]FDBEntry, error) {
+	var entries []FDBEntry
+
+	for i, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) == 0 {
+			// skip the header line
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected fdb entry %s", line)
+		}
+		vlan, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected fdb entry %s: %s", line, err)
+		}
+		entries = append(entries, FDBEntry{Port: fields[0], VLAN: vlan, MAC: fields[2], Age: fields[3]})
+	}
+
+	return entries, nil
+}
+
+// ParseCoverage parse the output of coverage/show, the lines other than counters are ignored.
+func ParseCoverage(output string) []CoverageCounter {
+	var counters []CoverageCounter
+
+	for _, line := range strings.Split(output, "\n") {
+		match := coverageRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		counter := CoverageCounter{Name: match[1]}
+		counter.Rate5s, _ = strconv.ParseFloat(match[2], 64)
+		counter.RateMinute, _ = strconv.ParseFloat(match[3], 64)
+		counter.RateHour, _ = strconv.ParseFloat(match[4], 64)
+		counter.Total, _ = strconv.ParseUint(match[5], 10, 64)
+		counters = append(counters, counter)
+	}
+
+	return counters
+}
+
+// ParseTrace parse the output of ofproto/trace, the tables the packet went through are
+// parsed as steps with the following lines as the actions, e.g.
+// "bridge("ovsbr0")"
+// "---------------"
+// " 0. in_port=1, priority 100, cookie 0x1"
+// "    goto_table:10"
+func ParseTrace(output string) *TraceResult {
+	result := &TraceResult{}
+	var bridge string
+	var step *TraceStep
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.Trim(line, "-=") == "":
+			step = nil
+		case strings.HasPrefix(line, "Flow: "):
+			if result.Flow == "" {
+				result.Flow = strings.TrimPrefix(line, "Flow: ")
+			}
+			step = nil
+		case strings.HasPrefix(line, "Datapath actions: "):
+			result.DatapathActions = strings.TrimPrefix(line, "Datapath actions: ")
+			step = nil
+		case strings.HasPrefix(line, "Final flow: ") || strings.HasPrefix(line, "Megaflow: "):
+			step = nil
+		case traceBridgeRegexp.MatchString(line):
+			bridge = traceBridgeRegexp.FindStringSubmatch(line)[1]
+			step = nil
+		case traceTableRegexp.MatchString(line):
+			match := traceTableRegexp.FindStringSubmatch(line)
+			table, err := strconv.ParseUint(match[1], 10, 8)
+			if err != nil {
+				step = nil
+				continue
+			}
+			result.Steps = append(result.Steps, parseTraceStep(bridge, uint8(table), match[2]))
+			step = &result.Steps[len(result.Steps)-1]
+		case step != nil:
+			step.Actions = append(step.Actions, line)
+		}
+	}
+
+	return result
+}
+
+// parseTraceStep parse the flow matched in the table, e.g. "ip,nw_src=10.0.0.1, priority 200, cookie 0x1"
+func parseTraceStep(bridge string, table uint8, flow string) TraceStep {
+	step := TraceStep{Bridge: bridge, Table: table}
+	if strings.HasPrefix(flow, "No match") {
+		step.NoMatch = true
+		return step
+	}
+
+	var match []string
+	for _, field := range strings.Split(flow, ", ") {
+		switch {
+		case strings.HasPrefix(field, "priority "):
+			step.Priority, _ = strconv.Atoi(strings.TrimPrefix(field, "priority "))
+		case strings.HasPrefix(field, "cookie "):
+			step.Cookie, _ = strconv.ParseUint(strings.TrimPrefix(field, "cookie 0x"), 16, 64)
+		default:
+			match = append(match, field)
+		}
+	}
+	step.Match = strings.Join(match, ", ")
+	return step
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appctl
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRunNotAllowed(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := Run("exit")
+	Expect(err).Should(HaveOccurred())
+	_, err = Run("dpctl/del-flows")
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestParseDatapathFlows(t *testing.T) {
+	RegisterTestingT(t)
+
+	flows, err := ParseDatapathFlows(`recirc_id(0),in_port(2),eth(src=52:54:00:5e:2a:0c,dst=ff:ff:ff:ff:ff:ff),eth_type(0x0806), packets:3, bytes:126, used:0.532s, actions:1,3
+recirc_id(0),in_port(3),eth_type(0x0800),ipv4(frag=no), packets:10, bytes:980, used:never, flags:S., actions:ct(zone=65520),recirc(0x1)
+`)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(flows).Should(Equal([]DatapathFlow{
+		{
+			Match:   "recirc_id(0),in_port(2),eth(src=52:54:00:5e:2a:0c,dst=ff:ff:ff:ff:ff:ff),eth_type(0x0806)",
+			Packets: 3,
+			Bytes:   126,
+			Used:    "0.532s",
+			Actions: "1,3",
+		},
+		{
+			Match:   "recirc_id(0),in_port(3),eth_type(0x0800),ipv4(frag=no)",
+			Packets: 10,
+			Bytes:   980,
+			Used:    "never",
+			Flags:   "S.",
+			Actions: "ct(zone=65520),recirc(0x1)",
+		},
+	}))
+
+	_, err = ParseDatapathFlows("ovs-vswitchd: no datapaths exist")
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestParseFDB(t *testing.T) {
+	RegisterTestingT(t)
+
+	entries, err := ParseFDB(` port  VLAN  MAC                Age
+    1     0  52:54:00:5e:2a:0c    3
+LOCAL   100  52:54:00:5e:2a:0d  static
+`)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(entries).Should(Equal([]FDBEntry{
+		{Port: "1", VLAN: 0, MAC: "52:54:00:5e:2a:0c", Age: "3"},
+		{Port: "LOCAL", VLAN: 100, MAC: "52:54:00:5e:2a:0d", Age: "static"},
+	}))
+
+	_, err = ParseFDB(" port  VLAN  MAC                Age\n    1     x  52:54:00:5e:2a:0c    3\n")
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestParseCoverage(t *testing.T) {
+	RegisterTestingT(t)
+
+	counters := ParseCoverage(`Event coverage, avg rate over last: 5 seconds, last minute, last hour,  hash=e1a3ad8c:
+bridge_reconfigure         0.0/sec     0.000/sec        0.0003/sec   total: 5
+xlate_actions              1.2/sec     0.850/sec        0.6500/sec   total: 2401
+109 events never hit
+`)
+	Expect(counters).Should(Equal([]CoverageCounter{
+		{Name: "bridge_reconfigure", Rate5s: 0, RateMinute: 0, RateHour: 0.0003, Total: 5},
+		{Name: "xlate_actions", Rate5s: 1.2, RateMinute: 0.85, RateHour: 0.65, Total: 2401},
+	}))
+}
+
+func TestParseTrace(t *testing.T) {
+	RegisterTestingT(t)
+
+	result := ParseTrace(`Flow: tcp,in_port=3,vlan_tci=0x0000,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_src=0,tp_dst=80
+
+bridge("ovsbr0")
+----------------
+ 0. in_port=3, priority 200, cookie 0x1
+    output:1
+
+bridge("ovsbr0-policy")
+-----------------------
+ 0. priority 0
+    goto_table:10
+10. ip,nw_dst=10.0.0.2, priority 300, cookie 0x4000000000003
+    ct(table=20,zone=65520)
+    drop
+     -> A clone of the packet is forked to recirculate. The forked pipeline will be resumed at table 20.
+
+Final flow: unchanged
+Megaflow: recirc_id=0,eth,ip,in_port=3,nw_dst=10.0.0.2,nw_frag=no
+Datapath actions: ct(zone=65520),recirc(0x1)
+
+===============================================================================
+recirc(0x1) - resume conntrack with default ct_state=trk|new (use --ct-next to customize)
+===============================================================================
+
+Flow: recirc_id=0x1,ct_state=new|trk,tcp,in_port=3,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_src=0,tp_dst=80
+
+bridge("ovsbr0-policy")
+-----------------------
+    thaw
+        Resuming from table 20
+20. No match.
+    drop
+
+Final flow: unchanged
+Megaflow: recirc_id=0x1,ct_state=+new+trk,eth,tcp,in_port=3,nw_frag=no
+Datapath actions: drop
+`)
+	Expect(result.Flow).Should(Equal("tcp,in_port=3,vlan_tci=0x0000,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_src=0,tp_dst=80"))
+	Expect(result.DatapathActions).Should(Equal("drop"))
+	Expect(result.Dropped()).Should(BeTrue())
+	Expect(result.Steps).Should(Equal([]TraceStep{
+		{Bridge: "ovsbr0", Table: 0, Match: "in_port=3", Priority: 200, Cookie: 0x1, Actions: []string{"output:1"}},
+		{Bridge: "ovsbr0-policy", Table: 0, Priority: 0, Actions: []string{"goto_table:10"}},
+		{Bridge: "ovsbr0-policy", Table: 10, Match: "ip,nw_dst=10.0.0.2", Priority: 300, Cookie: 0x4000000000003, Actions: []string{
+			"ct(table=20,zone=65520)",
+			"drop",
+			"-> A clone of the packet is forked to recirculate. The forked pipeline will be resumed at table 20.",
+		}},
+		{Bridge: "ovsbr0-policy", Table: 20, NoMatch: true, Actions: []string{"drop"}},
+	}))
+
+	Expect(ParseTrace("Flow: ip\nDatapath actions: 2\n").Dropped()).Should(BeFalse())
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bfd enables bfd sessions on the ovs interfaces, e.g. the uplinks and
+// the tunnels with fixed remote ip, and reports the session state changes.
+package bfd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+const (
+	// SessionDownEventReason is the reason of the event recorded on the agentinfo when a bfd session down
+	SessionDownEventReason = "BFDSessionDown"
+	// SessionUpEventReason is the reason of the event recorded on the agentinfo when a bfd session recovers
+	SessionUpEventReason = "BFDSessionUp"
+
+	StateUp        = "up"
+	StateAdminDown = "admin_down"
+
+	DefaultMinRx    = time.Second
+	DefaultMinTx    = 100 * time.Millisecond
+	DefaultInterval = 5 * time.Second
+)
+
+// Config defines the bfd sessions on the interfaces
+type Config struct {
+	// Interfaces enable bfd on. The flow based tunnels have no fixed remote,
+	// bfd could not be enabled on them.
+	Interfaces []string
+	// MinRx is the fastest rate the bfd control messages expected to receive.
+	MinRx time.Duration
+	// MinTx is the fastest rate the bfd control messages sent.
+	MinTx time.Duration
+	// Interval is the period of bfd config reconcile and session state check.
+	Interval time.Duration
+}
+
+// Manager keep bfd enabled on the interfaces, the config reapplied periodically
+// in case of the interfaces recreated, and record events on the agentinfo when
+// the session state changes.
+type Manager struct {
+	Recorder record.EventRecorder
+	Config   Config
+
+	// AgentName is the name of current agent, the events are recorded on its agentinfo
+	AgentName string
+
+	// enable and getStatus replaced in testing
+	enable    func(iface string, minRx, minTx time.Duration) error
+	getStatus func(iface string) (*agentv1alpha1.BFDStatus, error)
+
+	// states are the last known session states of the interfaces
+	states map[string]string
+}
+
+func (m *Manager) Run(stopChan <-chan struct{}) {
+	m.complete()
+
+	klog.Infof("start bfd manager on interfaces %v", m.Config.Interfaces)
+	defer klog.Infof("shutting down bfd manager")
+
+	wait.Until(m.syncOnce, m.Config.Interval, stopChan)
+}
+
+func (m *Manager) complete() {
+	if m.Config.MinRx <= 0 {
+		m.Config.MinRx = DefaultMinRx
+	}
+	if m.Config.MinTx <= 0 {
+		m.Config.MinTx = DefaultMinTx
+	}
+	if m.Config.Interval <= 0 {
+		m.Config.Interval = DefaultInterval
+	}
+	if m.enable == nil {
+		m.enable = Enable
+	}
+	if m.getStatus == nil {
+		m.getStatus = GetStatus
+	}
+	if m.states == nil {
+		m.states = make(map[string]string)
+	}
+}
+
+func (m *Manager) syncOnce() {
+	for _, iface := range m.Config.Interfaces {
+		if err := m.enable(iface, m.Config.MinRx, m.Config.MinTx); err != nil {
+			klog.Errorf("unable enable bfd on interface %s: %s", iface, err)
+			continue
+		}
+
+		status, err := m.getStatus(iface)
+		if err != nil {
+			klog.Errorf("unable get bfd status of interface %s: %s", iface, err)
+			continue
+		}
+		if status == nil {
+			// session not established yet
+			continue
+		}
+
+		lastState, known := m.states[iface]
+		m.states[iface] = status.State
+		if lastState == status.State {
+			continue
+		}
+		switch {
+		case status.State == StateUp && known:
+			klog.Infof("bfd session on interface %s up", iface)
+			m.recordEvent(corev1.EventTypeNormal, SessionUpEventReason, "bfd session on interface %s up", iface)
+		case status.State != StateUp && status.State != StateAdminDown && (!known || lastState == StateUp):
+			klog.Warningf("bfd session on interface %s %s: %s", iface, status.State, status.Diagnostic)
+			m.recordEvent(corev1.EventTypeWarning, SessionDownEventReason,
+				"bfd session on interface %s %s, remote state %s: %s", iface, status.State, status.RemoteState, status.Diagnostic)
+		}
+	}
+}
+
+func (m *Manager) recordEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if m.Recorder == nil {
+		return
+	}
+	agentInfo := &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: m.AgentName}}
+	m.Recorder.Eventf(agentInfo, eventType, reason, messageFmt, args...)
+}
+
+// Enable bfd on the interface with the intervals, the intervals are in milliseconds in ovsdb.
+func Enable(iface string, minRx, minTx time.Duration) error {
+	return vsctl("set", "Interface", iface, "bfd:enable=true",
+		fmt.Sprintf("bfd:min_rx=%d", minRx.Milliseconds()),
+		fmt.Sprintf("bfd:min_tx=%d", minTx.Milliseconds()),
+	)
+}
+
+// GetStatus return the bfd session of the interface, nil if bfd not enabled.
+func GetStatus(iface string) (*agentv1alpha1.BFDStatus, error) {
+	out, err := exec.Command("ovs-vsctl", "get", "Interface", iface, "bfd_status").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-vsctl get bfd_status of %s: %s: %s", iface, err, strings.TrimSpace(string(out)))
+	}
+	return ParseStatus(ParseMap(string(out))), nil
+}
+
+// ParseStatus convert the bfd_status column into BFDStatus, nil if no state in it.
+func ParseStatus(status map[string]string) *agentv1alpha1.BFDStatus {
+	if status["state"] == "" {
+		return nil
+	}
+	return &agentv1alpha1.BFDStatus{
+		State:       status["state"],
+		Forwarding:  status["forwarding"] == "true",
+		RemoteState: status["remote_state"],
+		Diagnostic:  status["diagnostic"],
+	}
+}
+
+// ParseMap parse the map column printed by ovs-vsctl, e.g.
+// {diagnostic="No Diagnostic", forwarding="true", state=up}
+func ParseMap(output string) map[string]string {
+	result := make(map[string]string)
+	output = strings.TrimSpace(output)
+	output = strings.TrimSuffix(strings.TrimPrefix(output, "{"), "}")
+
+	var key, token strings.Builder
+	var inQuote, escaped bool
+	current := &key
+	flush := func() {
+		if key.Len() != 0 {
+			result[strings.TrimSpace(key.String())] = token.String()
+		}
+		key.Reset()
+		token.Reset()
+		current = &key
+	}
+
+	for _, c := range output {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && inQuote:
+			escaped = true
+		case c == '"':
+			inQuote = !inQuote
+		case c == '=' && !inQuote && current == &key:
+			current = &token
+		case c == ',' && !inQuote:
+			flush()
+		case c == ' ' && !inQuote:
+		default:
+			current.WriteRune(c)
+		}
+	}
+	flush()
+
+	return result
+}
+
+func vsctl(args ...string) error {
+	out, err := exec.Command("ovs-vsctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ovs-vsctl %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
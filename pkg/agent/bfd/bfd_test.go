@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bfd
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+func TestParseMap(t *testing.T) {
+	RegisterTestingT(t)
+
+	status := ParseMap(`{diagnostic="Control Detection Time Expired", flap_count="3", forwarding="false", remote_diagnostic="No Diagnostic", remote_state=down, state=down}` + "\n")
+	Expect(status).Should(HaveLen(6))
+	Expect(status).Should(HaveKeyWithValue("diagnostic", "Control Detection Time Expired"))
+	Expect(status).Should(HaveKeyWithValue("state", "down"))
+	Expect(ParseStatus(status)).Should(Equal(&agentv1alpha1.BFDStatus{
+		State:       "down",
+		RemoteState: "down",
+		Diagnostic:  "Control Detection Time Expired",
+	}))
+
+	Expect(ParseMap(`{key="a\"b,c"}`)).Should(Equal(map[string]string{"key": `a"b,c`}))
+	Expect(ParseMap("{}\n")).Should(BeEmpty())
+	Expect(ParseStatus(ParseMap("{}"))).Should(BeNil())
+}
+
+func TestSyncOnce(t *testing.T) {
+	RegisterTestingT(t)
+
+	var enabled []string
+	states := map[string]string{"ens192": "up", "tun0": "init"}
+	recorder := record.NewFakeRecorder(10)
+	m := &Manager{
+		Recorder:  recorder,
+		Config:    Config{Interfaces: []string{"ens192", "tun0"}},
+		AgentName: "agent01",
+		enable: func(iface string, minRx, minTx time.Duration) error {
+			enabled = append(enabled, iface)
+			Expect(minRx).Should(Equal(DefaultMinRx))
+			Expect(minTx).Should(Equal(DefaultMinTx))
+			return nil
+		},
+		getStatus: func(iface string) (*agentv1alpha1.BFDStatus, error) {
+			return &agentv1alpha1.BFDStatus{State: states[iface]}, nil
+		},
+	}
+	m.complete()
+
+	m.syncOnce()
+	Expect(enabled).Should(Equal([]string{"ens192", "tun0"}))
+	Expect(recorder.Events).Should(Receive(ContainSubstring("BFDSessionDown bfd session on interface tun0 init")))
+	Expect(recorder.Events).ShouldNot(Receive())
+
+	states["ens192"] = "down"
+	states["tun0"] = "down"
+	m.syncOnce()
+	Expect(recorder.Events).Should(Receive(ContainSubstring("BFDSessionDown bfd session on interface ens192 down")))
+	Expect(recorder.Events).ShouldNot(Receive())
+
+	states["ens192"] = "up"
+	m.syncOnce()
+	Expect(recorder.Events).Should(Receive(ContainSubstring("BFDSessionUp bfd session on interface ens192 up")))
+}
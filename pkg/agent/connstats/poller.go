@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connstats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+const DefaultInterval = 30 * time.Second
+
+// Poller polls the conntrack table periodically, and summarizes the connections of each ip.
+// Only the connections in the policy conntrack zones are counted, they are the connections
+// of the endpoints, and each connection is tracked once in the zone.
+type Poller struct {
+	// Interval is the interval of polling the conntrack table.
+	Interval time.Duration
+
+	// listFlows used for list conntrack flows, replaced in testing
+	listFlows func() ([]*netlink.ConntrackFlow, error)
+
+	lock  sync.RWMutex
+	stats map[types.IPAddress]agentv1alpha1.ConnectionStats
+}
+
+func (p *Poller) Run(stopChan <-chan struct{}) {
+	if p.Interval <= 0 {
+		p.Interval = DefaultInterval
+	}
+	if p.listFlows == nil {
+		p.listFlows = listConntrackFlows
+	}
+
+	klog.Infof("start polling connection stats every %s", p.Interval)
+	wait.Until(p.poll, p.Interval, stopChan)
+}
+
+// Stats returns the connection stats of the ips polled last time.
+func (p *Poller) Stats() map[types.IPAddress]agentv1alpha1.ConnectionStats {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	// the stats are replaced instead of modified on poll, no need to copy
+	return p.stats
+}
+
+func (p *Poller) poll() {
+	flows, err := p.listFlows()
+	if err != nil {
+		klog.Errorf("unable list conntrack flows: %s", err)
+		return
+	}
+	stats := summarize(flows)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stats = stats
+}
+
+// summarize counts the connections to both the source and the destination of the original
+// direction, the connections between the endpoints are counted on both of them.
+func summarize(flows []*netlink.ConntrackFlow) map[types.IPAddress]agentv1alpha1.ConnectionStats {
+	stats := make(map[types.IPAddress]agentv1alpha1.ConnectionStats)
+	for _, flow := range flows {
+		if flow == nil || !datapath.IsPolicyCTZone(flow.Zone) {
+			continue
+		}
+		packets := int64(flow.Forward.Packets + flow.Reverse.Packets)
+		bytes := int64(flow.Forward.Bytes + flow.Reverse.Bytes)
+
+		for _, ip := range []types.IPAddress{types.IPAddress(flow.Forward.SrcIP.String()), types.IPAddress(flow.Forward.DstIP.String())} {
+			stat := stats[ip]
+			stat.ActiveConnections++
+			stat.Packets += packets
+			stat.Bytes += bytes
+			stats[ip] = stat
+		}
+	}
+	return stats
+}
+
+func listConntrackFlows() ([]*netlink.ConntrackFlow, error) {
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET)
+	if err != nil {
+		return nil, err
+	}
+	ipv6Flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	return append(flows, ipv6Flows...), nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connstats
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func newFlow(zone uint16, srcIP, dstIP string, packets, bytes uint64) *netlink.ConntrackFlow {
+	return &netlink.ConntrackFlow{
+		Zone:    zone,
+		Forward: netlink.IpTuple{SrcIP: net.ParseIP(srcIP), DstIP: net.ParseIP(dstIP), Packets: packets, Bytes: bytes},
+		Reverse: netlink.IpTuple{SrcIP: net.ParseIP(dstIP), DstIP: net.ParseIP(srcIP), Packets: packets, Bytes: bytes},
+	}
+}
+
+func TestPoll(t *testing.T) {
+	RegisterTestingT(t)
+
+	flows := []*netlink.ConntrackFlow{
+		newFlow(datapath.CTZoneForPolicy, "10.0.0.1", "10.0.0.2", 1, 100),
+		newFlow(datapath.CTZoneForPolicy, "10.0.0.1", "10.0.0.3", 2, 200),
+		newFlow(datapath.CTZoneForPolicy, "fe80::1", "fe80::2", 3, 300),
+		// the connections of the nat bridge are tracked in another zone
+		newFlow(datapath.CTZoneForPktFromLocal, "10.0.0.1", "10.0.0.2", 1, 100),
+		nil,
+	}
+	p := &Poller{listFlows: func() ([]*netlink.ConntrackFlow, error) { return flows, nil }}
+	Expect(p.Stats()).Should(BeEmpty())
+
+	p.poll()
+	Expect(p.Stats()).Should(Equal(map[types.IPAddress]agentv1alpha1.ConnectionStats{
+		"10.0.0.1": {ActiveConnections: 2, Packets: 6, Bytes: 600},
+		"10.0.0.2": {ActiveConnections: 1, Packets: 2, Bytes: 200},
+		"10.0.0.3": {ActiveConnections: 1, Packets: 4, Bytes: 400},
+		"fe80::1":  {ActiveConnections: 1, Packets: 6, Bytes: 600},
+		"fe80::2":  {ActiveConnections: 1, Packets: 6, Bytes: 600},
+	}))
+
+	// keep the stats polled last time on error
+	p.listFlows = func() ([]*netlink.ConntrackFlow, error) { return nil, fmt.Errorf("some error") }
+	p.poll()
+	Expect(p.Stats()).Should(HaveLen(5))
+}
@@ -18,6 +18,7 @@ package cache
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -36,12 +37,14 @@ type RuleDirection string
 type PolicyType string
 
 const (
-	RuleTypeGlobalDefaultRule RuleType = "GlobalDefaultRule"
-	RuleTypeDefaultRule       RuleType = "DefaultRule"
-	RuleTypeNormalRule        RuleType = "NormalRule"
+	RuleTypeGlobalDefaultRule   RuleType = "GlobalDefaultRule"
+	RuleTypeGlobalExceptionRule RuleType = "GlobalExceptionRule"
+	RuleTypeDefaultRule         RuleType = "DefaultRule"
+	RuleTypeNormalRule          RuleType = "NormalRule"
 
-	RuleActionAllow RuleAction = "Allow"
-	RuleActionDrop  RuleAction = "Drop"
+	RuleActionAllow    RuleAction = "Allow"
+	RuleActionDrop     RuleAction = "Drop"
+	RuleActionRedirect RuleAction = "Redirect"
 
 	RuleDirectionIn  RuleDirection = "Ingress"
 	RuleDirectionOut RuleDirection = "Egress"
@@ -60,6 +63,7 @@ type PolicyRule struct {
 	Direction       RuleDirection `json:"direction"`
 	RuleType        RuleType      `json:"ruleType"`
 	Tier            string        `json:"tier,omitempty"`
+	TierPriority    int32         `json:"tierPriority,omitempty"`
 	EnforcementMode string        `json:"enforcementMode,omitempty"`
 	SrcIPAddr       string        `json:"srcIPAddr,omitempty"`
 	DstIPAddr       string        `json:"dstIPAddr,omitempty"`
@@ -68,6 +72,15 @@ type PolicyRule struct {
 	DstPort         uint16        `json:"dstPort,omitempty"`
 	SrcPortMask     uint16        `json:"srcPortMask,omitempty"`
 	DstPortMask     uint16        `json:"dstPortMask,omitempty"`
+	ICMPType        *uint8        `json:"icmpType,omitempty"`
+	ICMPCode        *uint8        `json:"icmpCode,omitempty"`
+
+	// redirect target when action is Redirect
+	RedirectIPAddr string `json:"redirectIPAddr,omitempty"`
+	RedirectPort   uint16 `json:"redirectPort,omitempty"`
+
+	// VRF the rule scoped in, the rule only installed on the vds of the vrf
+	VRF string `json:"vrf,omitempty"`
 }
 
 type DeepCopyBase interface {
@@ -107,7 +120,9 @@ type CompleteRule struct {
 	// RuleID is a unique identifier of rule, it's always set to policyNamespace/policyName/policyType/ruleName.
 	RuleID string
 
+	// TierPriority is the priority of the Tier if the rule belongs to one, zero for the builtin tiers.
 	Tier            string
+	TierPriority    int32
 	EnforcementMode string
 	Action          RuleAction
 	Direction       RuleDirection
@@ -135,6 +150,18 @@ type CompleteRule struct {
 
 	// Ports is a list of srcport and dstport with protocol. This filed must not empty.
 	Ports []RulePort
+
+	// RedirectIPAddr and RedirectPort is the target of the matching traffic, only
+	// works when Action is Redirect. RedirectPort zero means keep the original port.
+	RedirectIPAddr string
+	RedirectPort   uint16
+
+	// L7 is the application layer criteria of the rule, the matching traffic is redirected
+	// to the l7 proxy, which checks the criteria. It's immutable once the rule created.
+	L7 *L7Match
+
+	// VRF is the vrf of the policy, the ip addresses of the rule are in the vrf.
+	VRF string
 }
 
 type RulePort struct {
@@ -152,10 +179,40 @@ type RulePort struct {
 	// DstPortName is a destination port name, the mapped port depends on each endpoint.
 	DstPortName string
 
+	// ICMPType is the type of icmp or icmpv6 message, nil matches all types.
+	ICMPType *uint8
+	// ICMPCode is the code of icmp or icmpv6 message, nil matches all codes.
+	ICMPCode *uint8
+
 	// Protocol should set "" if want match all protocol.
 	Protocol securityv1alpha1.Protocol
 }
 
+// L7Match is the l7 criteria of the rule with the http paths compiled.
+type L7Match struct {
+	securityv1alpha1.L7Match
+	// Paths are the compiled http paths of the criteria, which match the whole path.
+	Paths map[string]*regexp.Regexp
+}
+
+// NewL7Match compiles the http paths of the l7 criteria, the path failed to compile matches
+// nothing.
+func NewL7Match(l7 *securityv1alpha1.L7Match) *L7Match {
+	match := &L7Match{L7Match: *l7.DeepCopy(), Paths: make(map[string]*regexp.Regexp)}
+	for _, http := range match.HTTP {
+		if http.Path == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + http.Path + ")$")
+		if err != nil {
+			klog.Errorf("failed to compile http path %s of l7 criteria: %s", http.Path, err)
+			continue
+		}
+		match.Paths[http.Path] = re
+	}
+	return match
+}
+
 func (rule *CompleteRule) Clone() *CompleteRule {
 	if rule == nil {
 		return nil
@@ -166,6 +223,7 @@ func (rule *CompleteRule) Clone() *CompleteRule {
 	return &CompleteRule{
 		RuleID:            rule.RuleID,
 		Tier:              rule.Tier,
+		TierPriority:      rule.TierPriority,
 		EnforcementMode:   rule.EnforcementMode,
 		Action:            rule.Action,
 		Direction:         rule.Direction,
@@ -176,6 +234,10 @@ func (rule *CompleteRule) Clone() *CompleteRule {
 		SrcIPBlocks:       DeepCopyMap(rule.SrcIPBlocks).(map[string]*IPBlockItem),
 		DstIPBlocks:       DeepCopyMap(rule.DstIPBlocks).(map[string]*IPBlockItem),
 		Ports:             append([]RulePort{}, rule.Ports...),
+		RedirectIPAddr:    rule.RedirectIPAddr,
+		RedirectPort:      rule.RedirectPort,
+		L7:                rule.L7,
+		VRF:               rule.VRF,
 	}
 }
 
@@ -197,6 +259,10 @@ func (rule *CompleteRule) generateRuleList(srcIPBlocks, dstIPBlocks map[string]*
 				continue
 			}
 			for _, port := range ports {
+				// icmp only matches ipv4 traffic and icmpv6 only matches ipv6 traffic
+				if !ICMPFamilyMatches(port.Protocol, srcIP) || !ICMPFamilyMatches(port.Protocol, dstIP) {
+					continue
+				}
 				dstPorts := []RulePort{port}
 				if port.DstPortName != "" {
 					if dstIPBlock == nil {
@@ -247,6 +313,20 @@ func (rule *CompleteRule) hasLocalRule(ipBlock *IPBlockItem) bool {
 	return false
 }
 
+// ICMPFamilyMatches returns false if the ip address is not in the ip family of the icmp protocol.
+func ICMPFamilyMatches(protocol securityv1alpha1.Protocol, ipAddr string) bool {
+	if ipAddr == "" {
+		return true
+	}
+	switch protocol {
+	case securityv1alpha1.ProtocolICMP:
+		return !strings.Contains(ipAddr, ":")
+	case securityv1alpha1.ProtocolICMPv6:
+		return strings.Contains(ipAddr, ":")
+	}
+	return true
+}
+
 func (rule *CompleteRule) generateRule(srcIPBlock, dstIPBlock string, direction RuleDirection, port RulePort) PolicyRule {
 	var ruleType = RuleTypeNormalRule
 	if rule.DefaultPolicyRule {
@@ -257,6 +337,7 @@ func (rule *CompleteRule) generateRule(srcIPBlock, dstIPBlock string, direction
 		Direction:       direction,
 		RuleType:        ruleType,
 		Tier:            rule.Tier,
+		TierPriority:    rule.TierPriority,
 		EnforcementMode: rule.EnforcementMode,
 		SrcIPAddr:       srcIPBlock,
 		DstIPAddr:       dstIPBlock,
@@ -265,7 +346,14 @@ func (rule *CompleteRule) generateRule(srcIPBlock, dstIPBlock string, direction
 		DstPort:         port.DstPort,
 		SrcPortMask:     port.SrcPortMask,
 		DstPortMask:     port.DstPortMask,
+		ICMPType:        port.ICMPType,
+		ICMPCode:        port.ICMPCode,
 		Action:          rule.Action,
+		VRF:             rule.VRF,
+	}
+	if rule.Action == RuleActionRedirect {
+		policyRule.RedirectIPAddr = rule.RedirectIPAddr
+		policyRule.RedirectPort = rule.RedirectPort
 	}
 
 	// todo: it is not appropriate to calculate the flowkey here
@@ -426,6 +514,8 @@ func GenerateFlowKey(rule PolicyRule) string {
 	// We consider PolicyRule with the same spec but different action as the same flow.
 	// Some we remove the action to generate FlowKey here.
 	rule.Action = ""
+	rule.RedirectIPAddr = ""
+	rule.RedirectPort = 0
 	return HashName(32, rule)
 }
 
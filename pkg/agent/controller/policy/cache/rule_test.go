@@ -247,3 +247,76 @@ func TestAppendIPBlockPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestRedirectRule(t *testing.T) {
+	rule := &CompleteRule{
+		RuleID:         "ns/policy/normal/egress.honeypot",
+		Action:         RuleActionRedirect,
+		Direction:      RuleDirectionOut,
+		SrcIPBlocks:    map[string]*IPBlockItem{"10.0.0.1/32": nil},
+		DstIPBlocks:    map[string]*IPBlockItem{"": nil},
+		Ports:          []RulePort{{DstPort: 22, Protocol: securityv1alpha1.ProtocolTCP}},
+		RedirectIPAddr: "10.0.0.100",
+		RedirectPort:   2222,
+	}
+
+	rules := rule.Clone().ListRules()
+	if len(rules) != 1 {
+		t.Fatalf("expect 1 rule, got %d", len(rules))
+	}
+	if rules[0].RedirectIPAddr != "10.0.0.100" || rules[0].RedirectPort != 2222 {
+		t.Errorf("unexpect redirect target %s:%d", rules[0].RedirectIPAddr, rules[0].RedirectPort)
+	}
+
+	// redirect target is a part of action, should not change the flow key
+	allowRule := rules[0]
+	allowRule.Action = RuleActionAllow
+	allowRule.RedirectIPAddr, allowRule.RedirectPort = "", 0
+	if GenerateFlowKey(rules[0]) != GenerateFlowKey(allowRule) {
+		t.Errorf("redirect rule should have the same flow key with allow rule")
+	}
+
+	rule.Action = RuleActionAllow
+	if rules = rule.ListRules(); rules[0].RedirectIPAddr != "" || rules[0].RedirectPort != 0 {
+		t.Errorf("allow rule should not have redirect target")
+	}
+}
+
+func TestICMPRule(t *testing.T) {
+	echoRequest := uint8(8)
+	rule := &CompleteRule{
+		RuleID:      "ns/policy/normal/ingress.ping",
+		Action:      RuleActionAllow,
+		Direction:   RuleDirectionIn,
+		SrcIPBlocks: map[string]*IPBlockItem{"10.0.0.1/32": nil, "fe80::1/128": nil},
+		DstIPBlocks: map[string]*IPBlockItem{"": nil},
+		Ports: []RulePort{
+			{ICMPType: &echoRequest, Protocol: securityv1alpha1.ProtocolICMP},
+			{Protocol: securityv1alpha1.ProtocolICMPv6},
+		},
+	}
+
+	rules := rule.ListRules()
+	if len(rules) != 2 {
+		t.Fatalf("expect 2 rules, got %+v", rules)
+	}
+	for _, item := range rules {
+		switch item.SrcIPAddr {
+		case "10.0.0.1/32":
+			if item.IPProtocol != "ICMP" || item.ICMPType == nil || *item.ICMPType != echoRequest || item.ICMPCode != nil {
+				t.Errorf("unexpect icmp rule %+v", item)
+			}
+		case "fe80::1/128":
+			if item.IPProtocol != "ICMPv6" || item.ICMPType != nil {
+				t.Errorf("unexpect icmpv6 rule %+v", item)
+			}
+		}
+	}
+
+	// icmp type is a part of match fields, should change the flow key
+	anyType := rules[0]
+	anyType.ICMPType = nil
+	if GenerateFlowKey(rules[0]) == GenerateFlowKey(anyType) {
+		t.Errorf("rules with different icmp type should have different flow key")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// The benchmarks of group resolution and rule compilation, run them with make bench,
+// and compare with the baseline by make bench-compare to find the regressions.
+
+var benchEndpointScales = []int{1000, 10000, 100000}
+
+// benchLocalEndpoints is the number of the endpoints the policy applied to on the
+// current agent, the compiled rules are about benchLocalEndpoints * scale.
+const benchLocalEndpoints = 4
+
+// BenchmarkGroupResolution resolves the members of a group selects half of the endpoints.
+func BenchmarkGroupResolution(b *testing.B) {
+	for _, scale := range benchEndpointScales {
+		scale := scale
+		b.Run(benchScaleName(scale), func(b *testing.B) {
+			ctx := context.Background()
+			scheme := newCompileScheme()
+			objects := []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bench"}}}
+			for i := 0; i < scale; i++ {
+				role := "client"
+				if i%2 == 0 {
+					role = "server"
+				}
+				objects = append(objects, newFuzzEndpoint("bench", fmt.Sprintf("ep%d", i), benchEndpointIP(i), map[string]string{"role": role}))
+			}
+			c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+			peer := securityv1alpha1.SecurityPolicyPeer{EndpointSelector: benchSelector("client")}
+			group := ctrlpolicy.PeerAsEndpointGroup("bench", "", peer)
+			if err := c.Create(ctx, group); err != nil {
+				b.Fatalf("create group: %s", err)
+			}
+			groupReconciler := &groupctrl.GroupReconciler{Client: c, Scheme: scheme}
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: group.Name}}
+			// the first reconcile add finalizer, the second one create groupmembers
+			for i := 0; i < 2; i++ {
+				if _, err := groupReconciler.Reconcile(req); err != nil {
+					b.Fatalf("reconcile group: %s", err)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := groupReconciler.Reconcile(req); err != nil {
+					b.Fatalf("reconcile group: %s", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRuleCompile compiles a policy applied to the local endpoints allows ingress
+// from all the endpoints on the other agents.
+func BenchmarkRuleCompile(b *testing.B) {
+	for _, scale := range benchEndpointScales {
+		scale := scale
+		b.Run(benchScaleName(scale), func(b *testing.B) {
+			policy := &securityv1alpha1.SecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "bench"},
+				Spec: securityv1alpha1.SecurityPolicySpec{
+					Tier:      constants.Tier2,
+					AppliedTo: []securityv1alpha1.ApplyToPeer{{EndpointSelector: benchSelector("server")}},
+					IngressRules: []securityv1alpha1.Rule{{
+						Name:  "bench",
+						From:  []securityv1alpha1.SecurityPolicyPeer{{EndpointSelector: benchSelector("client")}},
+						Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "80"}},
+					}},
+				},
+			}
+
+			r := &Reconciler{
+				ruleCache:  policycache.NewCompleteRuleCache(),
+				groupCache: policycache.NewGroupCache(),
+			}
+			appliedGroup := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, "", ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, policy.Spec.AppliedTo[0]))
+			r.groupCache.AddGroupMembership(newBenchGroupMembers(appliedGroup.Name, 0, benchLocalEndpoints, utils.CurrentAgentName()))
+			peerGroup := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, "", policy.Spec.IngressRules[0].From[0])
+			r.groupCache.AddGroupMembership(newBenchGroupMembers(peerGroup.Name, benchLocalEndpoints, scale, "remote-agent"))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rules, err := r.calculateExpectedPolicyRules(policy)
+				if err != nil {
+					b.Fatalf("compile policy: %s", err)
+				}
+				// the default drop rules of the applied endpoints are also compiled
+				if len(rules) < benchLocalEndpoints*scale {
+					b.Fatalf("expect at least %d rules, got %d", benchLocalEndpoints*scale, len(rules))
+				}
+			}
+		})
+	}
+}
+
+func newBenchGroupMembers(name string, offset, count int, agent string) *groupv1alpha1.GroupMembers {
+	members := &groupv1alpha1.GroupMembers{ObjectMeta: metav1.ObjectMeta{Name: name}, Revision: 1}
+	for i := offset; i < offset+count; i++ {
+		members.GroupMembers = append(members.GroupMembers, groupv1alpha1.GroupMember{
+			EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: fmt.Sprintf("ep%d", i)},
+			EndpointAgent:     []string{agent},
+			IPs:               []types.IPAddress{types.IPAddress(benchEndpointIP(i))},
+		})
+	}
+	return members
+}
+
+func benchSelector(role string) *labels.Selector {
+	return &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"role": role}}}
+}
+
+// benchEndpointIP returns unique ip in 10.0.0.0/8 for each endpoint.
+func benchEndpointIP(i int) string {
+	return fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+}
+
+func benchScaleName(scale int) string {
+	return fmt.Sprintf("%dk", scale/1000)
+}
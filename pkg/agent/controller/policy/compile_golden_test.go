@@ -0,0 +1,432 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/fqdn"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of policy compile tests")
+
+// localAgentPlaceholder in the scenarios is replaced with the current agent name,
+// the endpoints on other agents have no rules installed on the current agent.
+const localAgentPlaceholder = "$(LOCAL_AGENT)"
+
+// TestCompileGolden compiles each scenario in testdata/compile/*.yaml, and asserts
+// the normalized policy rules exactly the same as the golden file <scenario>.golden.
+// Run with -update to regenerate the golden files after intended changes.
+func TestCompileGolden(t *testing.T) {
+	scenarios, err := filepath.Glob(filepath.Join("testdata", "compile", "*.yaml"))
+	if err != nil {
+		t.Fatalf("list scenarios: %s", err)
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(strings.TrimSuffix(filepath.Base(scenario), ".yaml"), func(t *testing.T) {
+			RegisterTestingT(t)
+
+			objects, err := loadScenario(scenario)
+			Expect(err).ShouldNot(HaveOccurred())
+			rules, err := compileScenario(objects)
+			Expect(err).ShouldNot(HaveOccurred())
+			actual := normalizeRules(rules)
+
+			golden := strings.TrimSuffix(scenario, ".yaml") + ".golden"
+			if *updateGolden {
+				Expect(ioutil.WriteFile(golden, []byte(actual), 0644)).Should(Succeed())
+			}
+			expect, err := ioutil.ReadFile(golden)
+			Expect(err).ShouldNot(HaveOccurred(), "run with -update to create the golden file")
+			Expect(actual).Should(Equal(string(expect)))
+		})
+	}
+}
+
+// loadScenario decode the namespaces, endpoints and securitypolicies in the yaml file.
+func loadScenario(path string) ([]runtime.Object, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.ReplaceAll(content, []byte(localAgentPlaceholder), []byte(utils.CurrentAgentName()))
+
+	var objects []runtime.Object
+	decoder := serializer.NewCodecFactory(newCompileScheme()).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %s", path, err)
+		}
+		objects = append(objects, obj)
+	}
+}
+
+func newCompileScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = kubescheme.AddToScheme(scheme)
+	_ = clientsetscheme.AddToScheme(scheme)
+	return scheme
+}
+
+// compileScenario simulates the whole rule compilation: generate endpointgroups from
+// policies, calculate the groupmembers from endpoints and namespaces by the group
+// controller, then compile the policies into policy rules with the groupmembers.
+func compileScenario(objects []runtime.Object) ([]policycache.PolicyRule, error) {
+	ctx := context.Background()
+	scheme := newCompileScheme()
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	var policies []*securityv1alpha1.SecurityPolicy
+	groups := make(map[string]*groupv1alpha1.EndpointGroup)
+	for _, obj := range objects {
+		policy, ok := obj.(*securityv1alpha1.SecurityPolicy)
+		if !ok {
+			continue
+		}
+		policies = append(policies, policy)
+		for _, group := range policyEndpointGroups(policy) {
+			groups[group.Name] = group
+		}
+	}
+
+	groupReconciler := &groupctrl.GroupReconciler{Client: c, Scheme: scheme}
+	for name, group := range groups {
+		if err := c.Create(ctx, group); err != nil {
+			return nil, err
+		}
+		// the first reconcile add finalizer, the second one sync groupmembers
+		req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: name}}
+		for result := (ctrl.Result{Requeue: true}); result != (ctrl.Result{}); {
+			var err error
+			if result, err = groupReconciler.Reconcile(req); err != nil {
+				return nil, fmt.Errorf("reconcile group %s: %s", name, err)
+			}
+		}
+	}
+
+	r := &Reconciler{
+		Client:     c,
+		Scheme:     scheme,
+		ruleCache:  policycache.NewCompleteRuleCache(),
+		groupCache: policycache.NewGroupCache(),
+		FQDNCache:  newCompileFQDNCache(),
+		L7Proxy:    &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 15001},
+	}
+	groupMembersList := groupv1alpha1.GroupMembersList{}
+	if err := c.List(ctx, &groupMembersList); err != nil {
+		return nil, err
+	}
+	for i := range groupMembersList.Items {
+		r.groupCache.AddGroupMembership(&groupMembersList.Items[i])
+	}
+
+	var rules []policycache.PolicyRule
+	for _, policy := range policies {
+		policyRules, err := r.calculateExpectedPolicyRules(policy)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, policyRules...)
+	}
+	return rules, nil
+}
+
+// newCompileFQDNCache returns the addresses of the dns names the fqdn peers in the scenarios
+// resolved to, the names are resolved by the endpoint 10.0.0.2 unless otherwise specified.
+func newCompileFQDNCache() *fqdn.Cache {
+	cache := fqdn.NewCache(0)
+	now := time.Now()
+	cache.Add("10.0.0.2", "www.example.com", net.ParseIP("93.184.216.34"), time.Hour, now)
+	cache.Add("10.0.0.2", "api.example.com", net.ParseIP("93.184.216.35"), time.Hour, now)
+	cache.Add("10.0.0.2", "api.example.com", net.ParseIP("93.184.216.36"), time.Hour, now)
+	cache.Add("10.0.0.2", "registry.example.org", net.ParseIP("203.0.113.10"), time.Hour, now)
+	cache.Add("10.0.0.9", "www.example.com", net.ParseIP("93.184.216.99"), time.Hour, now)
+	return cache
+}
+
+// policyEndpointGroups return the endpointgroups generated by the controller for the policy.
+func policyEndpointGroups(policy *securityv1alpha1.SecurityPolicy) []*groupv1alpha1.EndpointGroup {
+	var groups []*groupv1alpha1.EndpointGroup
+	addPeer := func(peer securityv1alpha1.SecurityPolicyPeer) {
+		if group := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer); group != nil {
+			groups = append(groups, group)
+		}
+	}
+
+	for _, appliedTo := range policy.Spec.AppliedTo {
+		addPeer(ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, appliedTo))
+	}
+	for _, rule := range policy.Spec.IngressRules {
+		for _, peer := range rule.From {
+			addPeer(peer)
+		}
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		for _, peer := range rule.To {
+			addPeer(peer)
+		}
+		if len(rule.To) == 0 {
+			_, namedPorts := classifyEgressPorts(rule.Ports)
+			if len(namedPorts) != 0 {
+				groups = append(groups, ctrlpolicy.GetAllEpWithNamedPortGroup(policy.Spec.VRF))
+			}
+		}
+	}
+
+	return groups
+}
+
+// normalizeRules format the policy rules one per line in order. The flow key suffix
+// of the rule name is removed, it's the hash of the other fields.
+func normalizeRules(rules []policycache.PolicyRule) string {
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		var fields []string
+		fields = append(fields,
+			strings.TrimSuffix(rule.Name, "-"+policycache.GenerateFlowKey(rule)),
+			string(rule.Direction),
+			string(rule.RuleType),
+			string(rule.Action),
+			"tier="+rule.Tier,
+		)
+		if rule.EnforcementMode != "" {
+			fields = append(fields, "mode="+rule.EnforcementMode)
+		}
+		if rule.VRF != "" {
+			fields = append(fields, "vrf="+rule.VRF)
+		}
+		fields = append(fields, "src="+anyIfEmpty(rule.SrcIPAddr), "dst="+anyIfEmpty(rule.DstIPAddr))
+		if rule.IPProtocol != "" {
+			fields = append(fields, "proto="+rule.IPProtocol)
+		}
+		if rule.SrcPort != 0 {
+			fields = append(fields, fmt.Sprintf("sport=%d/%#04x", rule.SrcPort, rule.SrcPortMask))
+		}
+		if rule.DstPort != 0 {
+			fields = append(fields, fmt.Sprintf("dport=%d/%#04x", rule.DstPort, rule.DstPortMask))
+		}
+		if rule.Action == policycache.RuleActionRedirect {
+			fields = append(fields, fmt.Sprintf("redirect=%s:%d", rule.RedirectIPAddr, rule.RedirectPort))
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+
+	sort.Strings(lines)
+	var buf strings.Builder
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func anyIfEmpty(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
+
+// TestCompileSelectorFuzz compiles policies with random selectors against random
+// endpoints, and asserts the compiled sources exactly the endpoints selected by the
+// kubernetes label selector. The labels are single valued, so the semantics of the
+// extend selector must be the same as the kubernetes one.
+func TestCompileSelectorFuzz(t *testing.T) {
+	RegisterTestingT(t)
+
+	seed := time.Now().UnixNano()
+	t.Logf("fuzz selectors with seed %d", seed)
+	r := rand.New(rand.NewSource(seed))
+
+	for i := 0; i < 50; i++ {
+		objects, expectSources := randomSelectorScenario(r)
+		rules, err := compileScenario(objects)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		actualSources := sets.NewString()
+		for _, rule := range rules {
+			if rule.RuleType == policycache.RuleTypeNormalRule {
+				actualSources.Insert(rule.SrcIPAddr)
+			}
+		}
+		Expect(actualSources.List()).Should(Equal(expectSources.List()), "scenario %d with seed %d", i, seed)
+	}
+}
+
+var (
+	fuzzLabelKeys   = []string{"app", "tier", "env"}
+	fuzzLabelValues = []string{"a", "b", "c"}
+)
+
+// randomSelectorScenario return the objects of a policy allow ingress from a random
+// selector, and the ips of the endpoints should be selected.
+func randomSelectorScenario(r *rand.Rand) ([]runtime.Object, sets.String) {
+	var objects []runtime.Object
+	var namespaceLabels = make(map[string]map[string]string)
+	for _, namespace := range []string{"ns0", "ns1", "ns2"} {
+		namespaceLabels[namespace] = randomLabels(r)
+		objects = append(objects, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: namespaceLabels[namespace]},
+		})
+	}
+
+	// the policy applied to the target endpoint, which is never selected as source
+	objects = append(objects, newFuzzEndpoint("ns0", "target", "10.0.0.1", map[string]string{"role": "target"}))
+
+	peer := securityv1alpha1.SecurityPolicyPeer{EndpointSelector: labels.FromLabelSelector(randomSelector(r))}
+	if r.Intn(2) == 0 {
+		peer.NamespaceSelector = randomSelector(r)
+	}
+	endpointSelector, _ := metav1.LabelSelectorAsSelector(&peer.EndpointSelector.LabelSelector)
+
+	expectSources := sets.NewString()
+	for i := 0; i < 20; i++ {
+		namespace := fmt.Sprintf("ns%d", r.Intn(3))
+		ip := fmt.Sprintf("10.0.1.%d", i+1)
+		endpointLabels := randomLabels(r)
+		objects = append(objects, newFuzzEndpoint(namespace, fmt.Sprintf("ep%d", i), ip, endpointLabels))
+
+		namespaceMatched := namespace == "ns0"
+		if peer.NamespaceSelector != nil {
+			namespaceSelector, _ := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			namespaceMatched = namespaceSelector.Matches(k8slabels.Set(namespaceLabels[namespace]))
+		}
+		if namespaceMatched && endpointSelector.Matches(k8slabels.Set(endpointLabels)) {
+			expectSources.Insert(ip + "/32")
+		}
+	}
+
+	objects = append(objects, &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "fuzz", Namespace: "ns0"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier: constants.Tier2,
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{
+				EndpointSelector: &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"role": "target"}}},
+			}},
+			IngressRules: []securityv1alpha1.Rule{{Name: "fuzz", From: []securityv1alpha1.SecurityPolicyPeer{peer}}},
+		},
+	})
+	return objects, expectSources
+}
+
+func newFuzzEndpoint(namespace, name, ip string, endpointLabels map[string]string) *securityv1alpha1.Endpoint {
+	return &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: endpointLabels},
+		Spec: securityv1alpha1.EndpointSpec{
+			Reference: securityv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: namespace + "/" + name},
+		},
+		Status: securityv1alpha1.EndpointStatus{IPs: []types.IPAddress{types.IPAddress(ip)}},
+	}
+}
+
+func randomLabels(r *rand.Rand) map[string]string {
+	result := make(map[string]string)
+	for _, key := range fuzzLabelKeys {
+		if r.Intn(3) != 0 {
+			result[key] = fuzzLabelValues[r.Intn(len(fuzzLabelValues))]
+		}
+	}
+	return result
+}
+
+func randomSelector(r *rand.Rand) *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{}
+	for _, key := range fuzzLabelKeys {
+		switch r.Intn(6) {
+		case 0:
+			if selector.MatchLabels == nil {
+				selector.MatchLabels = make(map[string]string)
+			}
+			selector.MatchLabels[key] = fuzzLabelValues[r.Intn(len(fuzzLabelValues))]
+		case 1:
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key: key, Operator: metav1.LabelSelectorOpIn, Values: randomValues(r),
+			})
+		case 2:
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key: key, Operator: metav1.LabelSelectorOpNotIn, Values: randomValues(r),
+			})
+		case 3:
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key: key, Operator: metav1.LabelSelectorOpExists,
+			})
+		case 4:
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key: key, Operator: metav1.LabelSelectorOpDoesNotExist,
+			})
+		}
+	}
+	return selector
+}
+
+func randomValues(r *rand.Rand) []string {
+	values := []string{fuzzLabelValues[r.Intn(len(fuzzLabelValues))]}
+	if r.Intn(2) == 0 {
+		values = append(values, fuzzLabelValues[r.Intn(len(fuzzLabelValues))])
+	}
+	return values
+}
@@ -19,7 +19,9 @@ package policy
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -81,7 +83,11 @@ func (r *Reconciler) calculateExpectGlobalPolicyRules() ([]cache.PolicyRule, err
 	switch len(policyList.Items) {
 	case 1:
 		ruleList := newGlobalPolicyRulePair(policyList.Items[0])
-		return ruleList, nil
+		exceptionRules, err := newGlobalExceptionRules(policyList.Items[0])
+		if err != nil {
+			return []cache.PolicyRule{}, err
+		}
+		return append(ruleList, exceptionRules...), nil
 	case 0:
 		return []cache.PolicyRule{}, nil
 	default:
@@ -114,3 +120,81 @@ func newGlobalPolicyRulePair(policy securityv1alpha1.GlobalPolicy) []cache.Polic
 
 	return []cache.PolicyRule{ingressRule, egressRule}
 }
+
+// newGlobalExceptionRules returns the rules allow the infrastructure traffics of the exceptions,
+// the rules are always in work mode, so the infrastructure traffics are never dropped.
+func newGlobalExceptionRules(policy securityv1alpha1.GlobalPolicy) ([]cache.PolicyRule, error) {
+	var ruleList []cache.PolicyRule
+	var flowKeys = sets.NewString()
+
+	for index := range policy.Spec.Exceptions {
+		exception := &policy.Spec.Exceptions[index]
+		ports, err := FlattenPorts(exception.GetPorts())
+		if err != nil {
+			return nil, fmt.Errorf("exception %d: %s", index, err)
+		}
+
+		for _, cidr := range exception.CIDRs {
+			for _, port := range ports {
+				if !cache.ICMPFamilyMatches(port.Protocol, cidr) {
+					continue
+				}
+				for _, rule := range newGlobalExceptionRulesOfCIDR(exception.Type, cidr, port) {
+					// the exceptions may overlap, keep only one rule of the same flow
+					if flowKey := cache.GenerateFlowKey(rule); !flowKeys.Has(flowKey) {
+						flowKeys.Insert(flowKey)
+						ruleList = append(ruleList, rule)
+					}
+				}
+			}
+		}
+	}
+
+	return ruleList, nil
+}
+
+// newGlobalExceptionRulesOfCIDR returns the rules in the direction the exception type implies: the
+// endpoints visit the kube-apiserver and the dns in the CIDR, the node health checks come from the
+// CIDR, and the custom exceptions allow the traffics in both directions.
+func newGlobalExceptionRulesOfCIDR(exceptionType securityv1alpha1.GlobalPolicyExceptionType, cidr string, port cache.RulePort) []cache.PolicyRule {
+	switch exceptionType {
+	case securityv1alpha1.GlobalPolicyExceptionKubeAPIServer, securityv1alpha1.GlobalPolicyExceptionDNS:
+		return []cache.PolicyRule{
+			newGlobalExceptionRule(exceptionType, cache.RuleDirectionOut, "", cidr, port),
+		}
+	case securityv1alpha1.GlobalPolicyExceptionNodeHealthCheck:
+		return []cache.PolicyRule{
+			newGlobalExceptionRule(exceptionType, cache.RuleDirectionIn, cidr, "", port),
+		}
+	default:
+		var ruleList []cache.PolicyRule
+		for _, direction := range []cache.RuleDirection{cache.RuleDirectionIn, cache.RuleDirectionOut} {
+			ruleList = append(ruleList,
+				newGlobalExceptionRule(exceptionType, direction, cidr, "", port),
+				newGlobalExceptionRule(exceptionType, direction, "", cidr, port),
+			)
+		}
+		return ruleList
+	}
+}
+
+func newGlobalExceptionRule(exceptionType securityv1alpha1.GlobalPolicyExceptionType, direction cache.RuleDirection,
+	srcCidr, dstCidr string, port cache.RulePort) cache.PolicyRule {
+	rule := cache.PolicyRule{
+		Direction:       direction,
+		RuleType:        cache.RuleTypeGlobalExceptionRule,
+		Tier:            constants.Tier2,
+		SrcIPAddr:       srcCidr,
+		DstIPAddr:       dstCidr,
+		IPProtocol:      string(port.Protocol),
+		DstPort:         port.DstPort,
+		DstPortMask:     port.DstPortMask,
+		ICMPType:        port.ICMPType,
+		ICMPCode:        port.ICMPCode,
+		Action:          cache.RuleActionAllow,
+		EnforcementMode: string(securityv1alpha1.WorkMode),
+	}
+	rule.Name = fmt.Sprintf("/%s/%s/exception.%s/-%s", DefaultGlobalPolicyName, cache.GlobalPolicy,
+		strings.ToLower(string(exceptionType)), cache.GenerateFlowKey(rule))
+	return rule
+}
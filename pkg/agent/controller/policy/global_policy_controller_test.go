@@ -69,6 +69,36 @@ var _ = Describe("PolicyController", func() {
 				assertHasGlobalPolicyRule("GlobalDefaultRule", "Egress", "Drop", "", "")
 			})
 		})
+		When("update GlobalPolicy with exceptions", func() {
+			BeforeEach(func() {
+				updatePolicy := policy.DeepCopy()
+				updatePolicy.Spec.DefaultAction = securityv1alpha1.GlobalDefaultActionDrop
+				updatePolicy.Spec.Exceptions = []securityv1alpha1.GlobalPolicyException{
+					{Type: securityv1alpha1.GlobalPolicyExceptionKubeAPIServer, CIDRs: []string{"10.0.0.1/32"}},
+					{Type: securityv1alpha1.GlobalPolicyExceptionDNS, CIDRs: []string{"10.96.0.10/32"}},
+					{Type: securityv1alpha1.GlobalPolicyExceptionNodeHealthCheck, CIDRs: []string{"192.168.0.0/24"}},
+				}
+
+				By(fmt.Sprintf("update global policy %s with exceptions", updatePolicy.Name))
+				Expect(k8sClient.Update(ctx, updatePolicy)).Should(Succeed())
+			})
+
+			It("should flatten exceptions to rules", func() {
+				// one rule for each cidr and port, in the direction of the exception type
+				assertGlobalPolicyRulesNum(2 + 1 + 2 + 1)
+				assertHasGlobalPolicyRule("GlobalDefaultRule", "Ingress", "Drop", "", "")
+				assertHasGlobalPolicyRule("GlobalExceptionRule", "Egress", "Allow", "", "10.0.0.1/32")
+				assertHasGlobalPolicyRule("GlobalExceptionRule", "Egress", "Allow", "", "10.96.0.10/32")
+				assertHasGlobalPolicyRule("GlobalExceptionRule", "Ingress", "Allow", "192.168.0.0/24", "")
+			})
+
+			It("should not allow the reverse direction of exceptions", func() {
+				assertGlobalPolicyRulesNum(2 + 1 + 2 + 1)
+				assertNoGlobalPolicyRule("GlobalExceptionRule", "Ingress", "Allow", "10.0.0.1/32", "")
+				assertNoGlobalPolicyRule("GlobalExceptionRule", "Ingress", "Allow", "10.96.0.10/32", "")
+				assertNoGlobalPolicyRule("GlobalExceptionRule", "Egress", "Allow", "", "192.168.0.0/24")
+			})
+		})
 		When("delete GlobalPolicy", func() {
 			BeforeEach(func() {
 				By(fmt.Sprintf("delete global policy %s", policy.Name))
@@ -110,18 +140,25 @@ func assertGlobalPolicyRulesNum(numOfPolicyRules int) {
 
 func assertHasGlobalPolicyRule(ruleType, direction, action, srcCidr, dstCidr string) {
 	Eventually(func() bool {
-		policyRuleList := getGlobalRuleFromCache()
+		return hasGlobalPolicyRule(ruleType, direction, action, srcCidr, dstCidr)
+	}, timeout, interval).Should(BeTrue())
+}
 
-		for _, rule := range policyRuleList {
-			if constants.Tier2 == rule.Tier &&
-				ruleType == string(rule.RuleType) &&
-				direction == string(rule.Direction) &&
-				action == string(rule.Action) &&
-				srcCidr == rule.SrcIPAddr &&
-				dstCidr == rule.DstIPAddr {
-				return true
-			}
+// assertNoGlobalPolicyRule should be called after the rules synced, e.g. the number of rules asserted
+func assertNoGlobalPolicyRule(ruleType, direction, action, srcCidr, dstCidr string) {
+	Expect(hasGlobalPolicyRule(ruleType, direction, action, srcCidr, dstCidr)).Should(BeFalse())
+}
+
+func hasGlobalPolicyRule(ruleType, direction, action, srcCidr, dstCidr string) bool {
+	for _, rule := range getGlobalRuleFromCache() {
+		if constants.Tier2 == rule.Tier &&
+			ruleType == string(rule.RuleType) &&
+			direction == string(rule.Direction) &&
+			action == string(rule.Action) &&
+			srcCidr == rule.SrcIPAddr &&
+			dstCidr == rule.DstIPAddr {
+			return true
 		}
-		return false
-	}, timeout, interval).Should(BeTrue())
+	}
+	return false
 }
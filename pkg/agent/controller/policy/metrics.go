@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/everoute/everoute/pkg/metrics"
+)
+
+const (
+	// realizationTypePolicy is the realization of a policy update.
+	realizationTypePolicy = "policy"
+	// realizationTypeGroupPatch is the realization of a group members patch.
+	realizationTypeGroupPatch = "group_patch"
+)
+
+var (
+	policyCompileDuration = metrics.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "policy",
+		Name:      "compile_duration_seconds",
+		Help:      "Duration of compiling a policy into policy rules.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	policyRealizationDuration = metrics.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "policy",
+		Name:      "realization_duration_seconds",
+		Help:      "Duration from reconciling a policy or group patch to its flows realized in the datapath.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{metrics.LabelType})
+)
+
+func init() {
+	metrics.MustRegister(policyCompileDuration, policyRealizationDuration)
+}
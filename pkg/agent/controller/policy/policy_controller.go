@@ -19,6 +19,8 @@ package policy
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -41,11 +43,15 @@ import (
 
 	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
 	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/fqdn"
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
 	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	ererrors "github.com/everoute/everoute/pkg/errors"
+	"github.com/everoute/everoute/pkg/metrics"
 	"github.com/everoute/everoute/pkg/source"
+	"github.com/everoute/everoute/pkg/types"
 	"github.com/everoute/everoute/pkg/utils"
 	"github.com/everoute/everoute/plugin/tower/pkg/informer"
 )
@@ -69,6 +75,22 @@ type Reconciler struct {
 	groupCache *policycache.GroupCache
 
 	DatapathManager *datapath.DpManager
+
+	// CompileBudget is the expected max time of compiling a policy into rules, warn
+	// when it takes longer. Zero means no budget.
+	CompileBudget time.Duration
+
+	// ClusterInternalCIDRs are the cidrs of the pods and vms in the cluster, they are
+	// part of the builtin peer ClusterInternal besides the ips of the endpoints.
+	ClusterInternalCIDRs []string
+
+	// FQDNCache is the addresses of the dns names learned, the fqdn peers are resolved from it.
+	// The fqdn peers resolve to nothing if nil.
+	FQDNCache *fqdn.Cache
+
+	// L7Proxy is the address of the l7 proxy on the agent, the traffic matching the rules with
+	// l7 criteria are redirected to it. The rules with l7 criteria are ignored if nil.
+	L7Proxy *net.TCPAddr
 }
 
 func (r *Reconciler) ReconcilePolicy(req ctrl.Request) (ctrl.Result, error) {
@@ -108,6 +130,7 @@ func (r *Reconciler) ReconcilePatch(req ctrl.Request) (ctrl.Result, error) {
 	r.reconcilerLock.Lock()
 	defer r.reconcilerLock.Unlock()
 
+	start, traceID := time.Now(), metrics.NewTraceID()
 	completeRules, _ := r.ruleCache.ByIndex(policycache.GroupIndex, patch.GroupName)
 
 	for _, completeRule := range completeRules {
@@ -120,6 +143,13 @@ func (r *Reconciler) ReconcilePatch(req ctrl.Request) (ctrl.Result, error) {
 	}
 
 	r.groupCache.ApplyPatch(patch)
+	if vrf, ok := parseClusterInternalGroup(patch.GroupName); ok {
+		// the builtin peer ClusterExternal is the complement of the group, which
+		// could not be patched, recompute the policies reference it.
+		r.processClusterExternalPolicies(vrf)
+	}
+	r.processFQDNPolicies(patch.GroupName)
+	r.observeRealization(realizationTypeGroupPatch, groupName, start, traceID)
 
 	if r.groupCache.PatchLen(groupName) != 0 {
 		return ctrl.Result{RequeueAfter: time.Nanosecond}, nil
@@ -133,6 +163,17 @@ func (r *Reconciler) GetCompleteRuleLister() informer.Lister {
 	return r.ruleCache
 }
 
+// GetL7Match returns the l7 criteria of the complete rule, nil if the rule not found or the
+// rule without l7 criteria.
+func (r *Reconciler) GetL7Match(ruleID string) *policycache.L7Match {
+	obj, exists, _ := r.ruleCache.GetByKey(ruleID)
+	if !exists {
+		return nil
+	}
+	completeRule := obj.(*policycache.CompleteRule)
+	return completeRule.L7
+}
+
 // GetGlobalRuleLister return globalRule lister, used for debug or testing
 func (r *Reconciler) GetGlobalRuleLister() informer.Lister {
 	return r.globalRuleCache
@@ -170,6 +211,13 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	// the rule priorities of the policies change with the priority of their Tier
+	if err = policyController.Watch(&source.Kind{Type: &securityv1alpha1.Tier{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.tierPolicies),
+	}); err != nil {
+		return err
+	}
+
 	if patchController, err = controller.New("groupPatch-controller", mgr, controller.Options{
 		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
 		Reconciler:              reconcile.Func(r.ReconcilePatch),
@@ -214,6 +262,26 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return nil
 }
 
+// tierPolicies returns the requests of the policies belong to the Tier.
+func (r *Reconciler) tierPolicies(object handler.MapObject) []reconcile.Request {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(context.Background(), &policyList); err != nil {
+		klog.Errorf("unable to list policies: %s", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, policy := range policyList.Items {
+		if policy.Spec.Tier == object.Meta.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{
+				Namespace: policy.Namespace,
+				Name:      policy.Name,
+			}})
+		}
+	}
+	return requests
+}
+
 func (r *Reconciler) addPatch(e event.CreateEvent, q workqueue.RateLimitingInterface) {
 	if e.Object == nil {
 		klog.Errorf("receive create event with no object %v", e)
@@ -247,14 +315,19 @@ func (r *Reconciler) cleanPolicyDependents(policy k8stypes.NamespacedName) error
 
 func (r *Reconciler) processPolicyUpdate(policy *securityv1alpha1.SecurityPolicy) (ctrl.Result, error) {
 	var oldRuleList []policycache.PolicyRule
+	start, traceID := time.Now(), metrics.NewTraceID()
 
 	completeRules, _ := r.ruleCache.ByIndex(policycache.PolicyIndex, policy.Namespace+"/"+policy.Name)
 	for _, completeRule := range completeRules {
 		oldRuleList = append(oldRuleList, completeRule.(*policycache.CompleteRule).ListRules()...)
 	}
 
+	compileStart := time.Now()
 	newRuleList, err := r.calculateExpectedPolicyRules(policy)
-	if isGroupNotFound(err) {
+	compileDuration := time.Since(compileStart)
+	metrics.ObserveWithExemplar(policyCompileDuration, compileDuration.Seconds(), traceID)
+	r.checkCompileBudget(policy, compileDuration, len(newRuleList))
+	if ererrors.IsNotFound(err) {
 		// wait until groupmembers created
 		return ctrl.Result{RequeueAfter: time.Nanosecond}, nil
 	}
@@ -265,16 +338,33 @@ func (r *Reconciler) processPolicyUpdate(policy *securityv1alpha1.SecurityPolicy
 
 	// start a force full synchronization of policyrule
 	r.syncPolicyRulesUntilSuccess(oldRuleList, newRuleList)
+	r.observeRealization(realizationTypePolicy, policy.Namespace+"/"+policy.Name, start, traceID)
 
 	return ctrl.Result{}, nil
 }
 
+// observeRealization records the realization duration with the trace id as exemplar, the
+// trace id is logged, so the slow realizations on the dashboards could be found in the logs.
+func (r *Reconciler) observeRealization(realizationType, name string, start time.Time, traceID string) {
+	elapsed := time.Since(start)
+	metrics.ObserveWithExemplar(policyRealizationDuration.WithLabelValues(realizationType), elapsed.Seconds(), traceID)
+	klog.V(2).Infof("realize %s %s in %s, %s=%s", realizationType, name, elapsed, metrics.ExemplarTraceID, traceID)
+}
+
+func (r *Reconciler) checkCompileBudget(policy *securityv1alpha1.SecurityPolicy, elapsed time.Duration, ruleCount int) {
+	if r.CompileBudget <= 0 || elapsed <= r.CompileBudget {
+		return
+	}
+	klog.Warningf("compile policy %s/%s into %d rules takes %s, exceeds the compile budget %s",
+		policy.Namespace, policy.Name, ruleCount, elapsed, r.CompileBudget)
+}
+
 func (r *Reconciler) calculateExpectedPolicyRules(policy *securityv1alpha1.SecurityPolicy) ([]policycache.PolicyRule, error) {
 	var policyRuleList []policycache.PolicyRule
 
 	completeRules, err := r.completePolicy(policy)
 	if err != nil {
-		return policyRuleList, fmt.Errorf("flatten policy %s: %s", policy.Name, err)
+		return policyRuleList, fmt.Errorf("flatten policy %s: %w", policy.Name, err)
 	}
 
 	// todo: replace delete and add completeRules with update
@@ -304,16 +394,37 @@ func classifyEgressPorts(ports []securityv1alpha1.SecurityPolicyPort) ([]securit
 	return numberPorts, namedPorts
 }
 
+// getTierPriority returns the priority of the Tier named, zero for the builtin tiers. The Tier
+// is read from the informer cache of the Tier watch, which also enqueues the policies of the
+// Tier by tierPolicies when its priority changes.
+func (r *Reconciler) getTierPriority(name string) (int32, error) {
+	switch name {
+	case constants.Tier0, constants.Tier1, constants.Tier2, constants.TierECP:
+		return 0, nil
+	}
+
+	var tier securityv1alpha1.Tier
+	if err := r.Get(context.Background(), k8stypes.NamespacedName{Name: name}, &tier); err != nil {
+		return 0, fmt.Errorf("unable to fetch tier %s: %s", name, err)
+	}
+	return tier.Spec.Priority, nil
+}
+
 //nolint:dupl,funlen // todo: remove dupl codes
 func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]*policycache.CompleteRule, error) {
 	var completeRules []*policycache.CompleteRule
 	var ingressEnabled, egressEnabled = policy.IsEnable()
 
+	tierPriority, err := r.getTierPriority(policy.Spec.Tier)
+	if err != nil {
+		return nil, err
+	}
+
 	appliedToPeer := make([]securityv1alpha1.SecurityPolicyPeer, 0, len(policy.Spec.AppliedTo))
 	for _, appliedTo := range policy.Spec.AppliedTo {
 		appliedToPeer = append(appliedToPeer, ctrlpolicy.AppliedAsSecurityPeer(policy.GetNamespace(), appliedTo))
 	}
-	appliedGroups, appliedIPBlocks, err := r.getPeersGroupsAndIPBlocks(policy.GetNamespace(), appliedToPeer)
+	appliedGroups, appliedIPBlocks, err := r.getPeersGroupsAndIPBlocks(policy.GetNamespace(), policy.Spec.VRF, appliedToPeer)
 	if err != nil {
 		return nil, err
 	}
@@ -325,16 +436,24 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 
 	if ingressEnabled {
 		for _, rule := range policy.Spec.IngressRules {
+			if unsupported := r.unsupportedRuleFeatures(policy, "ingress", &rule); len(unsupported) != 0 {
+				klog.Warningf("ignore ingress rule %s of policy %s/%s requiring features %v not supported", rule.Name, policy.Namespace, policy.Name, unsupported)
+				continue
+			}
 			ingressRuleTmpl := &policycache.CompleteRule{
 				RuleID:          fmt.Sprintf("%s/%s/%s/%s.%s", policy.Namespace, policy.Name, policycache.NormalPolicy, "ingress", rule.Name),
 				Tier:            policy.Spec.Tier,
+				TierPriority:    tierPriority,
 				EnforcementMode: policy.Spec.SecurityPolicyEnforcementMode.String(),
 				Action:          policycache.RuleActionAllow,
 				Direction:       policycache.RuleDirectionIn,
 				SymmetricMode:   policy.Spec.SymmetricMode,
 				DstGroups:       policycache.DeepCopyMap(appliedGroups).(map[string]int32),
 				DstIPBlocks:     policycache.DeepCopyMap(appliedIPBlocks).(map[string]*policycache.IPBlockItem),
+				VRF:             policy.Spec.VRF,
 			}
+			setRuleAction(ingressRuleTmpl, &rule)
+			setRuleL7(ingressRuleTmpl, &rule, r.L7Proxy)
 
 			ingressRuleTmpl.Ports, err = FlattenPorts(rule.Ports)
 			if err != nil {
@@ -359,6 +478,7 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 			defaultIngressRule := &policycache.CompleteRule{
 				RuleID:            fmt.Sprintf("%s/%s/%s/%s.%s", policy.Namespace, policy.Name, policycache.NormalPolicy, "default", "ingress"),
 				Tier:              policy.Spec.Tier,
+				TierPriority:      tierPriority,
 				EnforcementMode:   policy.Spec.SecurityPolicyEnforcementMode.String(),
 				Action:            policycache.RuleActionDrop,
 				Direction:         policycache.RuleDirectionIn,
@@ -368,6 +488,7 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 				DstIPBlocks:       policycache.DeepCopyMap(appliedIPBlocks).(map[string]*policycache.IPBlockItem),
 				SrcIPBlocks:       map[string]*policycache.IPBlockItem{"": nil}, // matches all source IP
 				Ports:             []policycache.RulePort{{}},                   // has a port matches all ports
+				VRF:               policy.Spec.VRF,
 			}
 			completeRules = append(completeRules, defaultIngressRule)
 		}
@@ -375,16 +496,24 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 
 	if egressEnabled {
 		for _, rule := range policy.Spec.EgressRules {
+			if unsupported := r.unsupportedRuleFeatures(policy, "egress", &rule); len(unsupported) != 0 {
+				klog.Warningf("ignore egress rule %s of policy %s/%s requiring features %v not supported", rule.Name, policy.Namespace, policy.Name, unsupported)
+				continue
+			}
 			egressRuleTmpl := &policycache.CompleteRule{
 				RuleID:          fmt.Sprintf("%s/%s/%s/%s.%s", policy.Namespace, policy.Name, policycache.NormalPolicy, "egress", rule.Name),
 				Tier:            policy.Spec.Tier,
+				TierPriority:    tierPriority,
 				EnforcementMode: policy.Spec.SecurityPolicyEnforcementMode.String(),
 				Action:          policycache.RuleActionAllow,
 				Direction:       policycache.RuleDirectionOut,
 				SymmetricMode:   policy.Spec.SymmetricMode,
 				SrcGroups:       policycache.DeepCopyMap(appliedGroups).(map[string]int32),
 				SrcIPBlocks:     policycache.DeepCopyMap(appliedIPBlocks).(map[string]*policycache.IPBlockItem),
+				VRF:             policy.Spec.VRF,
 			}
+			setRuleAction(egressRuleTmpl, &rule)
+			setRuleL7(egressRuleTmpl, &rule, r.L7Proxy)
 
 			if len(rule.To) > 0 {
 				egressRule := egressRuleTmpl.Clone()
@@ -419,7 +548,7 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 					egressRuleCur := egressRuleTmpl.Clone()
 					egressRuleCur.RuleID = fmt.Sprintf("%s.%s", egressRuleTmpl.RuleID, "namedport")
 					// If "rule.To" is empty or missing, this rule matches all endpoints with named port
-					egressRuleCur.DstGroups, egressRuleCur.DstIPBlocks, err = r.getAllEpWithNamedPortGroupAndIPBlocks()
+					egressRuleCur.DstGroups, egressRuleCur.DstIPBlocks, err = r.getAllEpWithNamedPortGroupAndIPBlocks(policy.Spec.VRF)
 					if err != nil {
 						return nil, err
 					}
@@ -436,6 +565,7 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 			defaultEgressRule := &policycache.CompleteRule{
 				RuleID:            fmt.Sprintf("%s/%s/%s/%s.%s", policy.Namespace, policy.Name, policycache.NormalPolicy, "default", "egress"),
 				Tier:              policy.Spec.Tier,
+				TierPriority:      tierPriority,
 				EnforcementMode:   policy.Spec.SecurityPolicyEnforcementMode.String(),
 				Action:            policycache.RuleActionDrop,
 				Direction:         policycache.RuleDirectionOut,
@@ -445,6 +575,7 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 				SrcIPBlocks:       policycache.DeepCopyMap(appliedIPBlocks).(map[string]*policycache.IPBlockItem),
 				DstIPBlocks:       map[string]*policycache.IPBlockItem{"": nil}, // matches all destination IP
 				Ports:             []policycache.RulePort{{}},                   // has a port matches all ports
+				VRF:               policy.Spec.VRF,
 			}
 			completeRules = append(completeRules, defaultEgressRule)
 		}
@@ -455,13 +586,14 @@ func (r *Reconciler) completePolicy(policy *securityv1alpha1.SecurityPolicy) ([]
 
 func (r *Reconciler) getCompleteRulesByParseSymmetricMode(ruleTmpl *policycache.CompleteRule, policy *securityv1alpha1.SecurityPolicy,
 	policyType networkingv1.PolicyType, peers []securityv1alpha1.SecurityPolicyPeer) ([]*policycache.CompleteRule, error) {
-	var rules []*policycache.CompleteRule
+	peers, fqdnPeers := splitFQDNPeers(peers)
+	rules := r.getFQDNPeersRules(ruleTmpl, policy, policyType, fqdnPeers)
 	if len(peers) == 0 {
 		return rules, nil
 	}
 
 	if !policy.Spec.SymmetricMode {
-		groups, ipBlocks, err := r.getPeersGroupsAndIPBlocks(policy.Namespace, peers)
+		groups, ipBlocks, err := r.getPeersGroupsAndIPBlocks(policy.Namespace, policy.Spec.VRF, peers)
 		if err != nil {
 			return nil, err
 		}
@@ -478,7 +610,7 @@ func (r *Reconciler) getCompleteRulesByParseSymmetricMode(ruleTmpl *policycache.
 	}
 
 	for i, symmetricMode := range []bool{true, false} {
-		groups, ipBlocks, err := r.getPeersGroupsAndIPBlocks(policy.Namespace, peers, symmetricMode)
+		groups, ipBlocks, err := r.getPeersGroupsAndIPBlocks(policy.Namespace, policy.Spec.VRF, peers, symmetricMode)
 		if err != nil {
 			return nil, err
 		}
@@ -500,8 +632,109 @@ func (r *Reconciler) getCompleteRulesByParseSymmetricMode(ruleTmpl *policycache.
 	return rules, nil
 }
 
-// getPeersGroupsAndIPBlocks get ipBlocks from groups, return unique ipBlock list
-func (r *Reconciler) getPeersGroupsAndIPBlocks(namespace string,
+// getFQDNPeersRules returns the rules of the fqdn peers for each endpoint applied, the peers
+// resolve to the addresses learned from the dns responses to the endpoint only. The rules
+// never reference the applied groups, which could not be patched, the policy is recomputed
+// on the applied groups patched instead.
+func (r *Reconciler) getFQDNPeersRules(ruleTmpl *policycache.CompleteRule, policy *securityv1alpha1.SecurityPolicy,
+	policyType networkingv1.PolicyType, peers []securityv1alpha1.SecurityPolicyPeer) []*policycache.CompleteRule {
+	if len(peers) == 0 || r.FQDNCache == nil {
+		return nil
+	}
+
+	appliedIPBlocks := ruleTmpl.SrcIPBlocks
+	if policyType == networkingv1.PolicyTypeIngress {
+		appliedIPBlocks = ruleTmpl.DstIPBlocks
+	}
+	// the rules are split by the symmetric mode as the rules of the other peers
+	matchSymmetrics := [][]bool{nil}
+	if policy.Spec.SymmetricMode {
+		matchSymmetrics = [][]bool{{true}, {false}}
+	}
+
+	var rules []*policycache.CompleteRule
+	for _, client := range r.FQDNCache.Clients() {
+		clientIPBlocks, ok := appliedToClient(appliedIPBlocks, client)
+		if !ok {
+			continue
+		}
+		for i, matchSymmetric := range matchSymmetrics {
+			ipBlocks := r.getFQDNPeersIPBlocks(peers, client, matchSymmetric...)
+			if len(ipBlocks) == 0 {
+				continue
+			}
+			rule := ruleTmpl.Clone()
+			if policy.Spec.SymmetricMode {
+				rule.RuleID = fmt.Sprintf("%s.%d", rule.RuleID, i)
+				rule.SymmetricMode = matchSymmetric[0]
+			}
+			rule.RuleID = fmt.Sprintf("%s.fqdn-%s", rule.RuleID, client)
+			if policyType == networkingv1.PolicyTypeIngress {
+				rule.SrcIPBlocks = ipBlocks
+				rule.DstGroups = map[string]int32{}
+				rule.DstIPBlocks = policycache.DeepCopyMap(clientIPBlocks).(map[string]*policycache.IPBlockItem)
+			} else {
+				rule.SrcGroups = map[string]int32{}
+				rule.SrcIPBlocks = policycache.DeepCopyMap(clientIPBlocks).(map[string]*policycache.IPBlockItem)
+				rule.DstIPBlocks = ipBlocks
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// getFQDNPeersIPBlocks returns the addresses of the fqdn peers resolved by the client.
+func (r *Reconciler) getFQDNPeersIPBlocks(peers []securityv1alpha1.SecurityPolicyPeer, client string,
+	matchSymmetric ...bool) map[string]*policycache.IPBlockItem {
+	var ipBlocks = make(map[string]*policycache.IPBlockItem)
+	for _, peer := range peers {
+		if len(matchSymmetric) != 0 && peer.DisableSymmetric == matchSymmetric[0] {
+			// symmetricMode doesn't match, skip peer
+			continue
+		}
+		for _, ip := range r.lookupFQDN(peer.FQDN, client) {
+			if _, exist := ipBlocks[ip]; !exist {
+				ipBlocks[ip] = policycache.NewIPBlockItem()
+			}
+			ipBlocks[ip].StaticCount++
+		}
+	}
+	return ipBlocks
+}
+
+// appliedToClient returns the applied ipBlocks scoped to the client, false if the client is
+// not applied. The empty ipBlock is applied to all the clients.
+func appliedToClient(appliedIPBlocks map[string]*policycache.IPBlockItem, client string) (map[string]*policycache.IPBlockItem, bool) {
+	clientIP, clientCIDR := net.ParseIP(client), policycache.GetIPCidr(types.IPAddress(client))
+	for ipBlock, item := range appliedIPBlocks {
+		if ipBlock == "" {
+			return map[string]*policycache.IPBlockItem{clientCIDR: nil}, true
+		}
+		_, ipNet, err := net.ParseCIDR(ipBlock)
+		if err != nil || !ipNet.Contains(clientIP) {
+			continue
+		}
+		return map[string]*policycache.IPBlockItem{clientCIDR: item}, true
+	}
+	return nil, false
+}
+
+// splitFQDNPeers returns the peers other than the fqdn peers, and the fqdn peers.
+func splitFQDNPeers(peers []securityv1alpha1.SecurityPolicyPeer) ([]securityv1alpha1.SecurityPolicyPeer, []securityv1alpha1.SecurityPolicyPeer) {
+	var others, fqdnPeers []securityv1alpha1.SecurityPolicyPeer
+	for _, peer := range peers {
+		if peer.FQDN != "" {
+			fqdnPeers = append(fqdnPeers, peer)
+		} else {
+			others = append(others, peer)
+		}
+	}
+	return others, fqdnPeers
+}
+
+// getPeersGroupsAndIPBlocks get ipBlocks from groups in the vrf, return unique ipBlock list
+func (r *Reconciler) getPeersGroupsAndIPBlocks(namespace, vrf string,
 	peers []securityv1alpha1.SecurityPolicyPeer, matchSymmetric ...bool) (map[string]int32, map[string]*policycache.IPBlockItem, error) {
 	var groups = make(map[string]int32)
 	var ipBlocks = make(map[string]*policycache.IPBlockItem)
@@ -519,6 +752,42 @@ func (r *Reconciler) getPeersGroupsAndIPBlocks(namespace string,
 			continue
 		}
 		switch {
+		case peer.Builtin == securityv1alpha1.BuiltinPeerClusterInternal:
+			group := ctrlpolicy.GetClusterInternalGroup(vrf).GetName()
+			revision, ipAddrs, exist := r.groupCache.ListGroupIPBlocks(group)
+			if !exist {
+				return nil, nil, ererrors.NewNotFound("group %s members not found", group)
+			}
+			groups[group] = revision
+
+			for ip, ipBlock := range ipAddrs {
+				if _, exist = ipBlocks[ip]; !exist {
+					ipBlocks[ip] = policycache.NewIPBlockItem()
+				}
+				ipBlocks[ip].AgentRef.Insert(ipBlock.AgentRef.List()...)
+				ipBlocks[ip].Ports = policycache.AppendIPBlockPorts(ipBlocks[ip].Ports, ipBlock.Ports)
+			}
+			for _, cidr := range r.ClusterInternalCIDRs {
+				if _, exist = ipBlocks[cidr]; !exist {
+					ipBlocks[cidr] = policycache.NewIPBlockItem()
+				}
+				ipBlocks[cidr].StaticCount++
+			}
+		case peer.Builtin == securityv1alpha1.BuiltinPeerClusterExternal:
+			// the group is not referenced by the rule, the policy is recomputed on
+			// the group patched instead.
+			ipNets, err := r.getClusterExternalIPNets(vrf)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, ipNet := range ipNets {
+				if _, exist := ipBlocks[ipNet.String()]; !exist {
+					ipBlocks[ipNet.String()] = policycache.NewIPBlockItem()
+				}
+				ipBlocks[ipNet.String()].StaticCount++
+			}
+		case peer.FQDN != "":
+			// the fqdn peers resolve for each endpoint applied by getFQDNPeersRules
 		case peer.IPBlock != nil:
 			ipNets, err := utils.ParseIPBlock(peer.IPBlock)
 			if err != nil {
@@ -531,11 +800,11 @@ func (r *Reconciler) getPeersGroupsAndIPBlocks(namespace string,
 				}
 				ipBlocks[ipNet.String()].StaticCount++
 			}
-		case peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil:
-			group := ctrlpolicy.PeerAsEndpointGroup(namespace, peer).GetName()
+		case peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil || peer.ServiceAccount != "":
+			group := ctrlpolicy.PeerAsEndpointGroup(namespace, vrf, peer).GetName()
 			revision, ipAddrs, exist := r.groupCache.ListGroupIPBlocks(group)
 			if !exist {
-				return nil, nil, groupNotFound(fmt.Errorf("group %s members not found", group))
+				return nil, nil, ererrors.NewNotFound("group %s members not found", group)
 			}
 			groups[group] = revision
 
@@ -554,14 +823,232 @@ func (r *Reconciler) getPeersGroupsAndIPBlocks(namespace string,
 	return groups, ipBlocks, nil
 }
 
-func (r *Reconciler) getAllEpWithNamedPortGroupAndIPBlocks() (map[string]int32, map[string]*policycache.IPBlockItem, error) {
+// getClusterExternalIPNets returns the ipv4 cidrs out of the endpoints in the vrf and the
+// ClusterInternalCIDRs.
+func (r *Reconciler) getClusterExternalIPNets(vrf string) ([]*net.IPNet, error) {
+	group := ctrlpolicy.GetClusterInternalGroup(vrf).GetName()
+	_, ipAddrs, exist := r.groupCache.ListGroupIPBlocks(group)
+	if !exist {
+		return nil, ererrors.NewNotFound("group %s members not found", group)
+	}
+
+	var internalNets []*net.IPNet
+	for _, cidr := range r.ClusterInternalCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("unable parse cluster internal cidr %s: %s", cidr, err)
+		}
+		if ipNet.IP.To4() != nil {
+			internalNets = append(internalNets, ipNet)
+		}
+	}
+
+	var except = sets.NewString()
+	for _, ipNet := range internalNets {
+		except.Insert(ipNet.String())
+	}
+	for ipAddr := range ipAddrs {
+		ip, _, err := net.ParseCIDR(ipAddr)
+		if err != nil || ip.To4() == nil || containsIP(internalNets, ip) {
+			continue
+		}
+		except.Insert(ipAddr)
+	}
+
+	return utils.ParseIPBlock(&networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: except.List()})
+}
+
+// processClusterExternalPolicies recomputes the policies with the builtin peer ClusterExternal
+// in the vrf, the caller must hold the reconcilerLock.
+func (r *Reconciler) processClusterExternalPolicies(vrf string) {
+	var policyList securityv1alpha1.SecurityPolicyList
+	if err := r.List(context.Background(), &policyList); err != nil {
+		klog.Errorf("unable list policies to recompute ClusterExternal peers: %s", err)
+		return
+	}
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+		if policy.Spec.VRF != vrf || !hasClusterExternalPeer(policy) {
+			continue
+		}
+		if _, err := r.processPolicyUpdate(policy); err != nil {
+			klog.Errorf("failed to recompute policy %s/%s ClusterExternal peers: %s", policy.Namespace, policy.Name, err)
+		}
+	}
+}
+
+func hasClusterExternalPeer(policy *securityv1alpha1.SecurityPolicy) bool {
+	for _, rule := range policy.Spec.IngressRules {
+		for _, peer := range rule.From {
+			if peer.Builtin == securityv1alpha1.BuiltinPeerClusterExternal {
+				return true
+			}
+		}
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		for _, peer := range rule.To {
+			if peer.Builtin == securityv1alpha1.BuiltinPeerClusterExternal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unsupportedRuleFeatures returns the features required by the rule but not supported by the
+// agent. The features published in the policy status are included, which might be introduced
+// after the agent, so the rules are skipped instead of realized partially.
+func (r *Reconciler) unsupportedRuleFeatures(policy *securityv1alpha1.SecurityPolicy, direction string, rule *securityv1alpha1.Rule) []string {
+	required := sets.NewString()
+	for _, feature := range rule.RequiredFeatures() {
+		required.Insert(string(feature))
+	}
+	for _, requirement := range policy.Status.RuleFeatures {
+		if requirement.Rule != direction+"."+rule.Name {
+			continue
+		}
+		for _, feature := range requirement.Features {
+			required.Insert(string(feature))
+		}
+	}
+
+	supported := sets.NewString()
+	for _, feature := range SupportedRuleFeatures(r.FQDNCache != nil, r.L7Proxy != nil) {
+		supported.Insert(string(feature))
+	}
+	if direction != "egress" || policy.Spec.SymmetricMode {
+		// the l7 proxy dials the upstream from the agent of the client only
+		supported.Delete(string(securityv1alpha1.RuleFeatureL7))
+	}
+	return required.Difference(supported).List()
+}
+
+// lookupFQDN returns the addresses of the names matches the fqdn resolved by the client in
+// cidr format.
+func (r *Reconciler) lookupFQDN(fqdnName, client string) []string {
+	if r.FQDNCache == nil {
+		return nil
+	}
+	ips := r.FQDNCache.Lookup(fqdnName, client)
+	for i := range ips {
+		ips[i] += "/32"
+	}
+	return ips
+}
+
+// ProcessFQDNChanges recomputes the policies with the fqdn peers match the names, which
+// addresses added or removed.
+func (r *Reconciler) ProcessFQDNChanges(names []string) {
+	var policyList securityv1alpha1.SecurityPolicyList
+	if err := r.List(context.Background(), &policyList); err != nil {
+		klog.Errorf("unable list policies to recompute fqdn peers: %s", err)
+		return
+	}
+
+	r.reconcilerLock.Lock()
+	defer r.reconcilerLock.Unlock()
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+		if !hasFQDNPeerMatch(policy, names) {
+			continue
+		}
+		if _, err := r.processPolicyUpdate(policy); err != nil {
+			klog.Errorf("failed to recompute policy %s/%s fqdn peers: %s", policy.Namespace, policy.Name, err)
+		}
+	}
+}
+
+// processFQDNPolicies recomputes the policies with the fqdn peers applied to the group, the rules
+// of the fqdn peers are generated for each endpoint applied, which could not be patched.
+func (r *Reconciler) processFQDNPolicies(group string) {
+	if r.FQDNCache == nil {
+		return
+	}
+
+	var policyList securityv1alpha1.SecurityPolicyList
+	if err := r.List(context.Background(), &policyList); err != nil {
+		klog.Errorf("unable list policies to recompute fqdn peers: %s", err)
+		return
+	}
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+		if !appliedToGroup(policy, group) || !hasFQDNPeerMatch(policy, nil) {
+			continue
+		}
+		if _, err := r.processPolicyUpdate(policy); err != nil {
+			klog.Errorf("failed to recompute policy %s/%s fqdn peers: %s", policy.Namespace, policy.Name, err)
+		}
+	}
+}
+
+func appliedToGroup(policy *securityv1alpha1.SecurityPolicy, group string) bool {
+	for _, appliedTo := range policy.Spec.AppliedTo {
+		peer := ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, appliedTo)
+		if endpointGroup := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer); endpointGroup != nil && endpointGroup.Name == group {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFQDNPeerMatch returns true if any fqdn peer of the policy matches the names, or the policy
+// has any fqdn peer if the names are nil.
+func hasFQDNPeerMatch(policy *securityv1alpha1.SecurityPolicy, names []string) bool {
+	var peers []securityv1alpha1.SecurityPolicyPeer
+	for _, rule := range policy.Spec.IngressRules {
+		peers = append(peers, rule.From...)
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		peers = append(peers, rule.To...)
+	}
+
+	for _, peer := range peers {
+		if peer.FQDN == "" {
+			continue
+		}
+		if names == nil {
+			return true
+		}
+		for _, name := range names {
+			if fqdn.Match(peer.FQDN, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseClusterInternalGroup returns the vrf of the group if it is a ClusterInternalEndpoints group.
+func parseClusterInternalGroup(group string) (string, bool) {
+	if group == constants.ClusterInternalEndpoints {
+		return "", true
+	}
+	if vrf := strings.TrimPrefix(group, constants.ClusterInternalEndpoints+"-"); vrf != group {
+		return vrf, true
+	}
+	return "", false
+}
+
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) getAllEpWithNamedPortGroupAndIPBlocks(vrf string) (map[string]int32, map[string]*policycache.IPBlockItem, error) {
 	var groups = make(map[string]int32)
 	var ipBlocks = make(map[string]*policycache.IPBlockItem)
 
-	group := ctrlpolicy.GetAllEpWithNamedPortGroup().GetName()
+	group := ctrlpolicy.GetAllEpWithNamedPortGroup(vrf).GetName()
 	revision, ipAddrs, exist := r.groupCache.ListGroupIPBlocks(group)
 	if !exist {
-		return nil, nil, groupNotFound(fmt.Errorf("group %s members not found", group))
+		return nil, nil, ererrors.NewNotFound("group %s members not found", group)
 	}
 	groups[group] = revision
 
@@ -653,7 +1140,7 @@ func (r *Reconciler) addPolicyRuleToDatapath(ruleID string, rule *policycache.Po
 	// Process PolicyRule: convert it to everoutePolicyRule, filter illegal PolicyRule; install everoutePolicyRule flow
 	everoutePolicyRule := toEveroutePolicyRule(ruleID, rule)
 	ruleDirection := getRuleDirection(rule.Direction)
-	ruleTier := getRuleTier(rule.Tier)
+	ruleTier := getRuleTier(rule.Tier, rule.TierPriority)
 
 	return r.DatapathManager.AddEveroutePolicyRule(everoutePolicyRule, rule.Name, ruleDirection, ruleTier, rule.EnforcementMode)
 }
@@ -18,6 +18,7 @@ package policy
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"runtime/debug"
 	"strings"
@@ -40,9 +41,12 @@ func toEveroutePolicyRule(ruleID string, rule *policycache.PolicyRule) *datapath
 		rulePriority = constants.DefaultPolicyRulePriority
 	case policycache.RuleTypeGlobalDefaultRule:
 		rulePriority = constants.GlobalDefaultPolicyRulePriority
+	case policycache.RuleTypeGlobalExceptionRule:
+		rulePriority = constants.GlobalExceptionPolicyRulePriority
 	default:
 		rulePriority = constants.NormalPolicyRulePriority
 	}
+	rulePriority += tierPriorityOffset(rule.TierPriority)
 
 	everoutePolicyRule := &datapath.EveroutePolicyRule{
 		RuleID:      ruleID,
@@ -54,17 +58,61 @@ func toEveroutePolicyRule(ruleID string, rule *policycache.PolicyRule) *datapath
 		SrcPortMask: rule.SrcPortMask,
 		DstPort:     rule.DstPort,
 		DstPortMask: rule.DstPortMask,
+		ICMPType:    rule.ICMPType,
+		ICMPCode:    rule.ICMPCode,
 		Action:      ruleAction,
+		VRF:         rule.VRF,
+	}
+	if rule.Action == policycache.RuleActionRedirect {
+		everoutePolicyRule.RedirectIPAddr = rule.RedirectIPAddr
+		everoutePolicyRule.RedirectPort = rule.RedirectPort
 	}
 
 	return everoutePolicyRule
 }
 
+// setRuleAction set action and redirect target of the complete rule from policy rule
+func setRuleAction(completeRule *policycache.CompleteRule, rule *securityv1alpha1.Rule) {
+	if rule.Action != securityv1alpha1.RuleActionRedirect || rule.RedirectTo == nil {
+		return
+	}
+	completeRule.Action = policycache.RuleActionRedirect
+	completeRule.RedirectIPAddr = rule.RedirectTo.IP
+	completeRule.RedirectPort = uint16(rule.RedirectTo.Port)
+}
+
+// SupportedRuleFeatures returns the rule features supported by the agent, the baseline features
+// and the features enabled on the agent, the agent advertises them in the agentinfo.
+func SupportedRuleFeatures(fqdnEnabled, l7ProxyEnabled bool) []securityv1alpha1.RuleFeature {
+	features := append([]securityv1alpha1.RuleFeature(nil), securityv1alpha1.BaselineRuleFeatures...)
+	if fqdnEnabled {
+		features = append(features, securityv1alpha1.RuleFeatureFQDN)
+	}
+	if l7ProxyEnabled {
+		features = append(features, securityv1alpha1.RuleFeatureL7)
+	}
+	return features
+}
+
+// setRuleL7 redirects the matching traffic of the complete rule to the l7 proxy, which checks
+// the l7 criteria of the policy rule.
+func setRuleL7(completeRule *policycache.CompleteRule, rule *securityv1alpha1.Rule, proxy *net.TCPAddr) {
+	if rule.L7 == nil || proxy == nil {
+		return
+	}
+	completeRule.Action = policycache.RuleActionRedirect
+	completeRule.RedirectIPAddr = proxy.IP.String()
+	completeRule.RedirectPort = uint16(proxy.Port)
+	completeRule.L7 = policycache.NewL7Match(rule.L7)
+}
+
 func protocolToInt(ipProtocol string) uint8 {
 	var protoNo uint8
 	switch ipProtocol {
 	case "ICMP":
 		protoNo = 1
+	case "ICMPv6":
+		protoNo = 58
 	case "TCP":
 		protoNo = 6
 	case "UDP":
@@ -88,6 +136,8 @@ func getRuleAction(ruleAction policycache.RuleAction) string {
 		action = "allow"
 	case policycache.RuleActionDrop:
 		action = "deny"
+	case policycache.RuleActionRedirect:
+		action = "redirect"
 	default:
 		klog.Fatalf("unsupport ruleAction %s in policyrule.", ruleAction)
 		return action
@@ -108,7 +158,22 @@ func getRuleDirection(ruleDir policycache.RuleDirection) uint8 {
 	return direction
 }
 
-func getRuleTier(ruleTier string) uint8 {
+// tierPriorityOffset returns the offset of the rule priorities of the Tier, the Tier with higher
+// precedence has the higher offset. The rules of the builtin tiers have no offset.
+func tierPriorityOffset(tierPriority int32) int {
+	if tierPriority <= 0 {
+		return 0
+	}
+	return int(constants.MaxTierPriority+1-tierPriority) * constants.TierPriorityBand
+}
+
+// getRuleTier returns the datapath tier of the rule, the rules of the Tiers are installed in
+// the tier of tier2.
+func getRuleTier(ruleTier string, tierPriority int32) uint8 {
+	if tierPriority > 0 {
+		return datapath.POLICY_TIER3
+	}
+
 	var tier uint8
 	switch ruleTier {
 	case constants.Tier0:
@@ -220,9 +285,17 @@ func FlattenPorts(ports []securityv1alpha1.SecurityPolicyPort) ([]policycache.Ru
 	var rulePortList []policycache.RulePort
 	var portMapTCP [65536]bool
 	var portMapUDP [65536]bool
+	var allPortsProtocol = make(map[securityv1alpha1.Protocol]bool, 0)
 	var portlessProtocol = make(map[securityv1alpha1.Protocol]bool, 0)
+	var icmpPorts = make(map[string]policycache.RulePort, 0)
 
 	for _, port := range ports {
+		if port.ICMPType != nil {
+			icmpPort := toICMPRulePort(port)
+			icmpPorts[policycache.HashName(32, icmpPort)] = icmpPort
+			continue
+		}
+
 		if port.Protocol != securityv1alpha1.ProtocolTCP && port.Protocol != securityv1alpha1.ProtocolUDP {
 			// ignore port when Protocol neither TCP nor UDP
 			portlessProtocol[port.Protocol] = true
@@ -248,6 +321,14 @@ func FlattenPorts(ports []securityv1alpha1.SecurityPolicyPort) ([]policycache.Ru
 			if err != nil {
 				return nil, fmt.Errorf("portrange %s unavailable: %s", subPortRange, err)
 			}
+			// port 0 means all ports, the port range begin with 0 compiled from port 1
+			if end == 0 {
+				allPortsProtocol[port.Protocol] = true
+				continue
+			}
+			if begin == 0 {
+				begin = 1
+			}
 
 			if port.Protocol == securityv1alpha1.ProtocolTCP {
 				// If defined portNumber as type uint16 here, an infinite loop will occur when end is
@@ -265,8 +346,17 @@ func FlattenPorts(ports []securityv1alpha1.SecurityPolicyPort) ([]policycache.Ru
 			}
 		}
 	}
-	rulePortList = append(rulePortList, processFlattenPorts(portMapTCP, securityv1alpha1.ProtocolTCP)...)
-	rulePortList = append(rulePortList, processFlattenPorts(portMapUDP, securityv1alpha1.ProtocolUDP)...)
+	// all ports matched, the port ranges of the protocol are redundant
+	if allPortsProtocol[securityv1alpha1.ProtocolTCP] {
+		rulePortList = append(rulePortList, policycache.RulePort{Protocol: securityv1alpha1.ProtocolTCP})
+	} else {
+		rulePortList = append(rulePortList, processFlattenPorts(portMapTCP, securityv1alpha1.ProtocolTCP)...)
+	}
+	if allPortsProtocol[securityv1alpha1.ProtocolUDP] {
+		rulePortList = append(rulePortList, policycache.RulePort{Protocol: securityv1alpha1.ProtocolUDP})
+	} else {
+		rulePortList = append(rulePortList, processFlattenPorts(portMapUDP, securityv1alpha1.ProtocolUDP)...)
+	}
 
 	// add portless protocol to rulePortList
 	for protocol := range portlessProtocol {
@@ -275,9 +365,29 @@ func FlattenPorts(ports []securityv1alpha1.SecurityPolicyPort) ([]policycache.Ru
 		})
 	}
 
+	// add icmp with type and code to rulePortList, unless all icmp messages of the protocol matched
+	for _, icmpPort := range icmpPorts {
+		if !portlessProtocol[icmpPort.Protocol] {
+			rulePortList = append(rulePortList, icmpPort)
+		}
+	}
+
 	return rulePortList, nil
 }
 
+func toICMPRulePort(port securityv1alpha1.SecurityPolicyPort) policycache.RulePort {
+	icmpType := uint8(*port.ICMPType)
+	rulePort := policycache.RulePort{
+		Protocol: port.Protocol,
+		ICMPType: &icmpType,
+	}
+	if port.ICMPCode != nil {
+		icmpCode := uint8(*port.ICMPCode)
+		rulePort.ICMPCode = &icmpCode
+	}
+	return rulePort
+}
+
 type RuleCount struct {
 	rule  *policycache.PolicyRule
 	count int
@@ -293,13 +403,3 @@ func toRuleMap(ruleList []policycache.PolicyRule) map[string]*RuleCount {
 	}
 	return ruleMap
 }
-
-type (
-	// groupNotFound means policy needed group not found, needed retry.
-	groupNotFound error
-)
-
-func isGroupNotFound(err error) bool {
-	_, isType := err.(groupNotFound)
-	return isType
-}
@@ -433,6 +433,41 @@ var _ = Describe("PolicyController", func() {
 				})
 			})
 
+			When("update policy into a Tier", func() {
+				var updPolicy *securityv1alpha1.SecurityPolicy
+				var tier *securityv1alpha1.Tier
+
+				BeforeEach(func() {
+					tier = &securityv1alpha1.Tier{
+						ObjectMeta: metav1.ObjectMeta{Name: constants.TierSecurityOps},
+						Spec:       securityv1alpha1.TierSpec{Priority: 50},
+					}
+					Expect(k8sClient.Create(ctx, tier)).Should(Succeed())
+					updPolicy = policy.DeepCopy()
+					updPolicy.Spec.Tier = tier.Name
+
+					By(fmt.Sprintf("update policy %s with tier %s", policy.Name, tier.Name))
+					mustUpdatePolicy(ctx, updPolicy)
+				})
+				AfterEach(func() {
+					Expect(k8sClient.Delete(ctx, tier)).Should(Succeed())
+				})
+
+				It("should replace policy rules with the tier priority", func() {
+					assertCompleteRuleNum(4)
+					assertHasPolicyRule(updPolicy, "Ingress", "Allow", "192.168.2.1/32", 0, "192.168.1.1/32", 22, "TCP")
+					assertPolicyRulesTierPriority(updPolicy, 50)
+				})
+
+				It("should follow the tier priority updated", func() {
+					assertPolicyRulesTierPriority(updPolicy, 50)
+					Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: tier.Name}, tier)).Should(Succeed())
+					tier.Spec.Priority = 60
+					Expect(k8sClient.Update(ctx, tier)).Should(Succeed())
+					assertPolicyRulesTierPriority(updPolicy, 60)
+				})
+			})
+
 			When("remove all ingress ports", func() {
 				var updPolicy *securityv1alpha1.SecurityPolicy
 
@@ -1169,6 +1204,41 @@ func TestFlattenPorts(t *testing.T) {
 				{DstPort: 80, DstPortMask: 0xffff, Protocol: "TCP"},
 			},
 		},
+		"should unmarshal portRange into masked ports": {
+			portRange: newTestPort("TCP", "30000-32767", "number"),
+			expectRulePort: []cache.RulePort{
+				{DstPort: 30000, DstPortMask: 0xfff0, Protocol: "TCP"},
+				{DstPort: 30016, DstPortMask: 0xffc0, Protocol: "TCP"},
+				{DstPort: 30080, DstPortMask: 0xff80, Protocol: "TCP"},
+				{DstPort: 30208, DstPortMask: 0xfe00, Protocol: "TCP"},
+				{DstPort: 30720, DstPortMask: 0xf800, Protocol: "TCP"},
+			},
+		},
+		"should unmarshal portRange begin with zero": {
+			portRange: newTestPort("UDP", "0-3", "number"),
+			expectRulePort: []cache.RulePort{
+				{DstPort: 1, DstPortMask: 0xffff, Protocol: "UDP"},
+				{DstPort: 2, DstPortMask: 0xfffe, Protocol: "UDP"},
+			},
+		},
+		"should unmarshal all ports with portRange": {
+			portRange: newTestPort("TCP", "0,1-1024", "number"),
+			expectRulePort: []cache.RulePort{
+				{Protocol: "TCP"},
+			},
+		},
+		"should unmarshal icmp type": {
+			portRange: newTestICMPPort("ICMP", int32Pointer(8), nil),
+			expectRulePort: []cache.RulePort{
+				{ICMPType: uint8Pointer(8), Protocol: "ICMP"},
+			},
+		},
+		"should unmarshal icmpv6 type and code": {
+			portRange: newTestICMPPort("ICMPv6", int32Pointer(1), int32Pointer(0)),
+			expectRulePort: []cache.RulePort{
+				{ICMPType: uint8Pointer(1), ICMPCode: uint8Pointer(0), Protocol: "ICMPv6"},
+			},
+		},
 	}
 
 	for name, tc := range testCases {
@@ -1184,6 +1254,66 @@ func TestFlattenPorts(t *testing.T) {
 	}
 }
 
+func TestRuleFeatures(t *testing.T) {
+	testCases := map[string]struct {
+		rule           securityv1alpha1.Rule
+		fqdnEnabled    bool
+		expectRequired []securityv1alpha1.RuleFeature
+		expectSupport  bool
+	}{
+		"should support the rule without features": {
+			rule:          securityv1alpha1.Rule{Ports: []securityv1alpha1.SecurityPolicyPort{*newTestPort("TCP", "80", "number")}},
+			expectSupport: true,
+		},
+		"should support the rule with baseline features": {
+			rule: securityv1alpha1.Rule{
+				Action: securityv1alpha1.RuleActionRedirect,
+				Ports:  []securityv1alpha1.SecurityPolicyPort{*newTestICMPPort("ICMP", int32Pointer(8), nil)},
+				To:     []securityv1alpha1.SecurityPolicyPeer{{Builtin: securityv1alpha1.BuiltinPeerClusterExternal}},
+			},
+			expectRequired: []securityv1alpha1.RuleFeature{
+				securityv1alpha1.RuleFeatureRedirect, securityv1alpha1.RuleFeatureICMPTypeCode, securityv1alpha1.RuleFeatureBuiltinPeer,
+			},
+			expectSupport: true,
+		},
+		"should not support the fqdn peers when fqdn disabled": {
+			rule:           securityv1alpha1.Rule{To: []securityv1alpha1.SecurityPolicyPeer{{FQDN: "www.example.com"}}},
+			expectRequired: []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureFQDN},
+		},
+		"should support the fqdn peers when fqdn enabled": {
+			rule:           securityv1alpha1.Rule{To: []securityv1alpha1.SecurityPolicyPeer{{FQDN: "www.example.com"}}},
+			fqdnEnabled:    true,
+			expectRequired: []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureFQDN},
+			expectSupport:  true,
+		},
+		"should not support the l7 criteria when l7 proxy disabled": {
+			rule:           securityv1alpha1.Rule{L7: &securityv1alpha1.L7Match{SNI: []string{"www.example.com"}}},
+			fqdnEnabled:    true,
+			expectRequired: []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureL7},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			required := tc.rule.RequiredFeatures()
+			if !reflect.DeepEqual(required, tc.expectRequired) {
+				t.Fatalf("expect required features: %v, get required features: %v", tc.expectRequired, required)
+			}
+			supported := sets.NewString()
+			for _, feature := range policy.SupportedRuleFeatures(tc.fqdnEnabled, false) {
+				supported.Insert(string(feature))
+			}
+			support := true
+			for _, feature := range required {
+				support = support && supported.Has(string(feature))
+			}
+			if support != tc.expectSupport {
+				t.Fatalf("expect support: %t, get support: %t", tc.expectSupport, support)
+			}
+		})
+	}
+}
+
 func newTestPort(protocol, portRange, portType string) *securityv1alpha1.SecurityPolicyPort {
 	return &securityv1alpha1.SecurityPolicyPort{
 		Protocol:  securityv1alpha1.Protocol(protocol),
@@ -1192,6 +1322,22 @@ func newTestPort(protocol, portRange, portType string) *securityv1alpha1.Securit
 	}
 }
 
+func newTestICMPPort(protocol string, icmpType, icmpCode *int32) *securityv1alpha1.SecurityPolicyPort {
+	return &securityv1alpha1.SecurityPolicyPort{
+		Protocol: securityv1alpha1.Protocol(protocol),
+		ICMPType: icmpType,
+		ICMPCode: icmpCode,
+	}
+}
+
+func int32Pointer(i int32) *int32 {
+	return &i
+}
+
+func uint8Pointer(i uint8) *uint8 {
+	return &i
+}
+
 func newTestNamedPort(protocol, name string, port int32) securityv1alpha1.NamedPort {
 	return securityv1alpha1.NamedPort{
 		Protocol: securityv1alpha1.Protocol(protocol),
@@ -1441,6 +1587,18 @@ func assertNoPolicyRule(policy *securityv1alpha1.SecurityPolicy,
 	}, timeout, interval).Should(BeFalse())
 }
 
+func assertPolicyRulesTierPriority(policy *securityv1alpha1.SecurityPolicy, tierPriority int32) {
+	Eventually(func() bool {
+		var policyRuleList = getRuleByPolicy(policy)
+		for _, rule := range policyRuleList {
+			if rule.Tier != policy.Spec.Tier || rule.TierPriority != tierPriority {
+				return false
+			}
+		}
+		return len(policyRuleList) != 0
+	}, timeout, interval).Should(BeTrue())
+}
+
 func getRuleByPolicy(policy *securityv1alpha1.SecurityPolicy) []cache.PolicyRule {
 	var policyRuleList []cache.PolicyRule
 	completeRules, _ := ruleCacheLister.ByIndex(cache.PolicyIndex, policy.Namespace+"/"+policy.Name)
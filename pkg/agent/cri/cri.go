@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cri correlates the containers with the pods by the container runtime. The container
+// the cni invoked for is the sandbox of the pod, so the veths are correlated with the pods by
+// the sandboxes, without relying on the external_ids written when the veths added.
+package cri
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	coretypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+const sandboxReady = "SANDBOX_READY"
+
+// Sandbox is the pod sandbox in the container runtime.
+type Sandbox struct {
+	ID        string
+	Name      string
+	Namespace string
+	UID       string
+	Ready     bool
+	CreatedAt time.Time
+}
+
+// PodUUID returns the pod-uuid external id of the veth of the sandbox, it's also the reference
+// of the endpoint of the pod.
+func (s *Sandbox) PodUUID() string {
+	return utils.EncodeNamespacedName(coretypes.NamespacedName{Namespace: s.Namespace, Name: "pod-" + s.Name})
+}
+
+// Client lists the sandboxes of the container runtime.
+type Client interface {
+	ListSandboxes() ([]Sandbox, error)
+}
+
+// NewClient returns the client of the container runtime serving the cri at the endpoint, e.g.
+// unix:///run/containerd/containerd.sock. It requires crictl in the PATH.
+func NewClient(endpoint string) Client {
+	return &crictlClient{endpoint: endpoint}
+}
+
+type crictlClient struct {
+	endpoint string
+}
+
+func (c *crictlClient) ListSandboxes() ([]Sandbox, error) {
+	out, err := exec.Command("crictl", "--runtime-endpoint", c.endpoint, "pods", "-o", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("crictl pods: %s", err)
+	}
+	return ParseSandboxes(out)
+}
+
+type crictlPods struct {
+	Items []struct {
+		ID       string `json:"id"`
+		Metadata struct {
+			Name      string `json:"name"`
+			UID       string `json:"uid"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		State string `json:"state"`
+		// CreatedAt is the unix nanoseconds, the int64 is a string in the protobuf json
+		CreatedAt int64 `json:"createdAt,string"`
+	} `json:"items"`
+}
+
+// ParseSandboxes parse the output of "crictl pods -o json".
+func ParseSandboxes(output []byte) ([]Sandbox, error) {
+	var result crictlPods
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("unexpected crictl output: %s", err)
+	}
+
+	sandboxes := make([]Sandbox, 0, len(result.Items))
+	for _, item := range result.Items {
+		sandboxes = append(sandboxes, Sandbox{
+			ID:        item.ID,
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			UID:       item.Metadata.UID,
+			Ready:     item.State == sandboxReady,
+			CreatedAt: time.Unix(0, item.CreatedAt),
+		})
+	}
+	return sandboxes, nil
+}
+
+// Index looks up the sandboxes by the container ids.
+type Index struct {
+	sandboxes []Sandbox
+}
+
+// NewIndex lists the sandboxes from the client and index them.
+func NewIndex(client Client) (*Index, error) {
+	sandboxes, err := client.ListSandboxes()
+	if err != nil {
+		return nil, err
+	}
+	return &Index{sandboxes: sandboxes}, nil
+}
+
+// Sandbox returns the sandbox of the container id, the id could be truncated as the names of
+// the veths, e.g. the first 12 characters.
+func (i *Index) Sandbox(containerID string) (*Sandbox, bool) {
+	if containerID == "" {
+		return nil, false
+	}
+	for index := range i.sandboxes {
+		if strings.HasPrefix(i.sandboxes[index].ID, containerID) {
+			return &i.sandboxes[index], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cri
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	coretypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+const podsOutput = `{
+  "items": [
+    {
+      "id": "4dd2e4ba7e1d7b0f3e0c7e0c3a1c3c2a3b8e5f0d9a8b7c6d5e4f3a2b1c0d9e8f",
+      "metadata": {
+        "name": "web-0",
+        "uid": "2a6b1c4e-0f8e-4f39-9d5a-8a7d2f1e6c3b",
+        "namespace": "prod",
+        "attempt": 0
+      },
+      "state": "SANDBOX_READY",
+      "createdAt": "1690000000000000000",
+      "labels": {"app": "web"},
+      "annotations": {},
+      "runtimeHandler": ""
+    },
+    {
+      "id": "9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0",
+      "metadata": {
+        "name": "job-1",
+        "uid": "7c1d2e3f-4a5b-4c6d-8e9f-0a1b2c3d4e5f",
+        "namespace": "default",
+        "attempt": 1
+      },
+      "state": "SANDBOX_NOTREADY",
+      "createdAt": "1690000100000000000"
+    }
+  ]
+}`
+
+type fakeClient struct {
+	sandboxes []Sandbox
+}
+
+func (c *fakeClient) ListSandboxes() ([]Sandbox, error) {
+	return c.sandboxes, nil
+}
+
+func TestParseSandboxes(t *testing.T) {
+	RegisterTestingT(t)
+
+	sandboxes, err := ParseSandboxes([]byte(podsOutput))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(sandboxes).Should(HaveLen(2))
+	Expect(sandboxes[0]).Should(Equal(Sandbox{
+		ID:        "4dd2e4ba7e1d7b0f3e0c7e0c3a1c3c2a3b8e5f0d9a8b7c6d5e4f3a2b1c0d9e8f",
+		Name:      "web-0",
+		Namespace: "prod",
+		UID:       "2a6b1c4e-0f8e-4f39-9d5a-8a7d2f1e6c3b",
+		Ready:     true,
+		CreatedAt: time.Unix(1690000000, 0),
+	}))
+	Expect(sandboxes[1].Ready).Should(BeFalse())
+	Expect(sandboxes[0].PodUUID()).Should(Equal(utils.EncodeNamespacedName(coretypes.NamespacedName{Namespace: "prod", Name: "pod-web-0"})))
+
+	_, err = ParseSandboxes([]byte("FATA[0000] connect: no such file or directory"))
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestIndex(t *testing.T) {
+	RegisterTestingT(t)
+
+	index, err := NewIndex(&fakeClient{sandboxes: []Sandbox{
+		{ID: "4dd2e4ba7e1d7b0f", Name: "web-0", Namespace: "prod"},
+		{ID: "9f8e7d6c5b4a3928", Name: "job-1", Namespace: "default"},
+	}})
+	Expect(err).ShouldNot(HaveOccurred())
+
+	sandbox, ok := index.Sandbox("4dd2e4ba7e1d7b0f")
+	Expect(ok).Should(BeTrue())
+	Expect(sandbox.Name).Should(Equal("web-0"))
+
+	// the truncated container id in the veth name
+	sandbox, ok = index.Sandbox("9f8e7d6c5b4a")
+	Expect(ok).Should(BeTrue())
+	Expect(sandbox.Name).Should(Equal("job-1"))
+
+	_, ok = index.Sandbox("0123456789ab")
+	Expect(ok).Should(BeFalse())
+	_, ok = index.Sandbox("")
+	Expect(ok).Should(BeFalse())
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CTZonePolicyMin is the lowest conntrack zone allocated for the policy of the vds. The
+	// zones are allocated from CTZoneForPolicy downward, a single vds always gets CTZoneForPolicy.
+	CTZonePolicyMin uint16 = 65280
+
+	// datapathCTZone in the external_ids of the local bridge is the policy conntrack zone of the
+	// vds, the vds keeps its zone and the connections tracked in it across restarts.
+	datapathCTZone string = "datapathCTZone"
+)
+
+// IsPolicyCTZone returns whether the conntrack zone could be allocated for the policy of a vds.
+func IsPolicyCTZone(zone uint16) bool {
+	return zone >= CTZonePolicyMin && zone <= CTZoneForPolicy && !isReservedCTZone(zone)
+}
+
+func isReservedCTZone(zone uint16) bool {
+	return zone == CNI_CONNTRACK_ZONE || zone == CTZoneForPktFromLocal
+}
+
+// GetCTZones returns the policy conntrack zones of the managed vds, keyed by the ovs bridge name.
+func (datapathManager *DpManager) GetCTZones() map[string]uint16 {
+	datapathManager.DpManagerMutex.Lock()
+	defer datapathManager.DpManagerMutex.Unlock()
+
+	zones := make(map[string]uint16, len(datapathManager.ctZones))
+	for vdsID, zone := range datapathManager.ctZones {
+		zones[datapathManager.Config.ManagedVDSMap[vdsID]] = zone
+	}
+	return zones
+}
+
+// initCTZones allocates the policy conntrack zones of the vds before the bridges init, so the
+// connections of the vds with overlapping ip spaces are tracked separately.
+func (datapathManager *DpManager) initCTZones() error {
+	var vdsIDs []string
+	persisted := make(map[string]uint16)
+	for vdsID := range datapathManager.Config.ManagedVDSMap {
+		vdsIDs = append(vdsIDs, vdsID)
+
+		externalIds, err := datapathManager.OvsdbDriverMap[vdsID][LOCAL_BRIDGE_KEYWORD].GetExternalIds()
+		if err != nil {
+			return fmt.Errorf("failed to get ovsdb externalids of vds %s: %v", vdsID, err)
+		}
+		if value, ok := externalIds[datapathCTZone]; ok {
+			zone, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				log.Warnf("Ignore bad format of conntrack zone %s of vds %s: %v", value, vdsID, err)
+				continue
+			}
+			persisted[vdsID] = uint16(zone)
+		}
+	}
+
+	zones, err := allocateCTZones(vdsIDs, persisted)
+	if err != nil {
+		return err
+	}
+
+	for vdsID, zone := range zones {
+		if zone != persisted[vdsID] {
+			ovsdbDriver := datapathManager.OvsdbDriverMap[vdsID][LOCAL_BRIDGE_KEYWORD]
+			externalIds, err := ovsdbDriver.GetExternalIds()
+			if err != nil {
+				return fmt.Errorf("failed to get ovsdb externalids of vds %s: %v", vdsID, err)
+			}
+			externalIds[datapathCTZone] = fmt.Sprint(zone)
+			if err := ovsdbDriver.SetExternalIds(externalIds); err != nil {
+				return fmt.Errorf("failed to persistent conntrack zone of vds %s: %v", vdsID, err)
+			}
+		}
+		log.Infof("Allocate conntrack zone %d for the policy of vds %s", zone, vdsID)
+		datapathManager.BridgeChainMap[vdsID][POLICY_BRIDGE_KEYWORD].(*PolicyBridge).ctZone = zone
+	}
+
+	datapathManager.DpManagerMutex.Lock()
+	datapathManager.ctZones = zones
+	datapathManager.DpManagerMutex.Unlock()
+	return nil
+}
+
+// allocateCTZones returns the zones of the vds, the zone persisted is kept if valid and not taken
+// by another vds, the others are allocated the highest free zones in the order of the vds id.
+func allocateCTZones(vdsIDs []string, persisted map[string]uint16) (map[string]uint16, error) {
+	sort.Strings(vdsIDs)
+	zones := make(map[string]uint16, len(vdsIDs))
+	used := make(map[uint16]bool, len(vdsIDs))
+
+	for _, vdsID := range vdsIDs {
+		zone, ok := persisted[vdsID]
+		if ok && IsPolicyCTZone(zone) && !used[zone] {
+			zones[vdsID] = zone
+			used[zone] = true
+		}
+	}
+
+	next := CTZoneForPolicy
+	for _, vdsID := range vdsIDs {
+		if _, ok := zones[vdsID]; ok {
+			continue
+		}
+		for next >= CTZonePolicyMin && (used[next] || isReservedCTZone(next)) {
+			next--
+		}
+		if next < CTZonePolicyMin {
+			return nil, fmt.Errorf("no free conntrack zone for vds %s", vdsID)
+		}
+		zones[vdsID] = next
+		used[next] = true
+	}
+	return zones, nil
+}
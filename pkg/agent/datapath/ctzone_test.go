@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAllocateCTZones(t *testing.T) {
+	testCases := []struct {
+		name      string
+		vdsIDs    []string
+		persisted map[string]uint16
+		expect    map[string]uint16
+	}{
+		{
+			name:   "single vds keeps the legacy zone",
+			vdsIDs: []string{"vds1"},
+			expect: map[string]uint16{"vds1": CTZoneForPolicy},
+		},
+		{
+			name:   "allocate in the order of the vds id",
+			vdsIDs: []string{"vds2", "vds1"},
+			expect: map[string]uint16{"vds1": CTZoneForPolicy, "vds2": CTZoneForPolicy - 1},
+		},
+		{
+			name:      "keep the persisted zones",
+			vdsIDs:    []string{"vds1", "vds2"},
+			persisted: map[string]uint16{"vds2": CTZoneForPolicy},
+			expect:    map[string]uint16{"vds1": CTZoneForPolicy - 1, "vds2": CTZoneForPolicy},
+		},
+		{
+			name:      "reallocate the zone taken",
+			vdsIDs:    []string{"vds1", "vds2"},
+			persisted: map[string]uint16{"vds1": 65400, "vds2": 65400},
+			expect:    map[string]uint16{"vds1": 65400, "vds2": CTZoneForPolicy},
+		},
+		{
+			name:      "reallocate the zone out of range",
+			vdsIDs:    []string{"vds1"},
+			persisted: map[string]uint16{"vds1": CNI_CONNTRACK_ZONE},
+			expect:    map[string]uint16{"vds1": CTZoneForPolicy},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			zones, err := allocateCTZones(tc.vdsIDs, tc.persisted)
+			if err != nil {
+				t.Fatalf("unexpect error: %s", err)
+			}
+			if !reflect.DeepEqual(zones, tc.expect) {
+				t.Errorf("expect zones %v, got %v", tc.expect, zones)
+			}
+		})
+	}
+}
+
+func TestAllocateCTZonesSkipReserved(t *testing.T) {
+	var vdsIDs []string
+	for i := 0; i < int(CTZoneForPolicy-CTZonePolicyMin)-1; i++ {
+		vdsIDs = append(vdsIDs, fmt.Sprintf("vds%03d", i))
+	}
+	zones, err := allocateCTZones(vdsIDs, nil)
+	if err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	for vdsID, zone := range zones {
+		if !IsPolicyCTZone(zone) {
+			t.Errorf("vds %s allocated zone %d out of the policy zones", vdsID, zone)
+		}
+	}
+
+	if _, err := allocateCTZones(append(vdsIDs, "vds998", "vds999"), nil); err == nil {
+		t.Errorf("expect error when the zones exhausted")
+	}
+}
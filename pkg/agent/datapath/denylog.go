@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// DeniedPacket is the copy of a packet dropped by a deny rule, punted to the agent.
+type DeniedPacket struct {
+	Time   time.Time
+	Bridge string
+	// FlowID is the deny rule flow matched, the cookie of the packet in.
+	FlowID uint64
+	// Monitor is true if the deny rule is in monitor mode, the packet is not dropped.
+	Monitor  bool
+	Protocol uint8
+	SrcIP    net.IP
+	DstIP    net.IP
+	// SrcPort and DstPort are zero for the protocols other than tcp and udp.
+	SrcPort uint16
+	DstPort uint16
+}
+
+// DeniedPackets returns the packets dropped by the deny rules, and the packets matched the deny
+// rules in monitor mode, nothing is received if deny logging disabled.
+func (datapathManager *DpManager) DeniedPackets() <-chan DeniedPacket {
+	return datapathManager.deniedPacketChan
+}
+
+// puntDeniedPacket sends a copy of the packets matched the deny rule flow to of controller.
+func (p *PolicyBridge) puntDeniedPacket(ruleFlow *ofctrl.Flow) error {
+	return ruleFlow.SendToController(ruleFlow.NewControllerAction(p.OfSwitch.ControllerID, 0))
+}
+
+func (p *PolicyBridge) processDeniedPacket(pkt *ofctrl.PacketIn) {
+	denied, ok := parseDeniedPacket(pkt.Data)
+	if !ok {
+		return
+	}
+	denied.Time = time.Now()
+	denied.Bridge = p.name
+	denied.FlowID = pkt.Cookie
+	denied.Monitor = p.datapathManager.getRuleModeByFlowID(pkt.Cookie) == "monitor"
+
+	select {
+	case p.datapathManager.deniedPacketChan <- denied:
+	default: // Non-block when deniedPacketChan is full, the packets over the handling rate are sampled out
+	}
+}
+
+// parseDeniedPacket returns the 5-tuple of the ipv4 packet.
+func parseDeniedPacket(pkt protocol.Ethernet) (DeniedPacket, bool) {
+	ipv4, ok := pkt.Data.(*protocol.IPv4)
+	if !ok {
+		return DeniedPacket{}, false
+	}
+
+	denied := DeniedPacket{
+		Protocol: ipv4.Protocol,
+		SrcIP:    utils.IPCopy(ipv4.NWSrc),
+		DstIP:    utils.IPCopy(ipv4.NWDst),
+	}
+	switch l4 := ipv4.Data.(type) {
+	case *protocol.TCP:
+		denied.SrcPort, denied.DstPort = l4.PortSrc, l4.PortDst
+	case *protocol.UDP:
+		denied.SrcPort, denied.DstPort = l4.PortSrc, l4.PortDst
+	}
+	return denied, true
+}
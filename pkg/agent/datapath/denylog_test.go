@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/contiv/libOpenflow/protocol"
+)
+
+func TestParseDeniedPacket(t *testing.T) {
+	srcIP, dstIP := net.ParseIP("10.0.0.1").To4(), net.ParseIP("10.0.0.2").To4()
+	testCases := []struct {
+		name   string
+		pkt    protocol.Ethernet
+		expect *DeniedPacket
+	}{
+		{
+			name: "tcp packet",
+			pkt: protocol.Ethernet{Ethertype: PROTOCOL_IP, Data: &protocol.IPv4{
+				Protocol: PROTOCOL_TCP, NWSrc: srcIP, NWDst: dstIP,
+				Data: &protocol.TCP{PortSrc: 34567, PortDst: 443},
+			}},
+			expect: &DeniedPacket{Protocol: PROTOCOL_TCP, SrcIP: srcIP, DstIP: dstIP, SrcPort: 34567, DstPort: 443},
+		},
+		{
+			name: "icmp packet",
+			pkt: protocol.Ethernet{Ethertype: PROTOCOL_IP, Data: &protocol.IPv4{
+				Protocol: 1, NWSrc: srcIP, NWDst: dstIP,
+				Data: &protocol.ICMP{Type: 8},
+			}},
+			expect: &DeniedPacket{Protocol: 1, SrcIP: srcIP, DstIP: dstIP},
+		},
+		{
+			name: "arp packet",
+			pkt:  protocol.Ethernet{Ethertype: PROTOCOL_ARP, Data: &protocol.ARP{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			denied, ok := parseDeniedPacket(tc.pkt)
+			if tc.expect == nil {
+				if ok {
+					t.Errorf("expect not parsed, got %+v", denied)
+				}
+				return
+			}
+			if !ok || !reflect.DeepEqual(denied, *tc.expect) {
+				t.Errorf("expect %+v, got %+v", *tc.expect, denied)
+			}
+		})
+	}
+}
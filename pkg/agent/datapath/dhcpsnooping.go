@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// The dhcp message format, RFC 2131 and RFC 2132.
+const (
+	dhcpServerPort = 67
+	dhcpClientPort = 68
+
+	dhcpOpBootReply     = 2
+	dhcpEthernetLen     = 6
+	dhcpYourIPOffset    = 16
+	dhcpClientMacOffset = 28
+	dhcpMagicOffset     = 236
+	dhcpMagicCookie     = 0x63825363
+	dhcpOptionsOffset   = dhcpMagicOffset + 4
+
+	dhcpOptionPad         = 0
+	dhcpOptionLeaseTime   = 51
+	dhcpOptionMessageType = 53
+	dhcpOptionEnd         = 255
+	dhcpMessageAck        = 5
+)
+
+// DHCPLease is the ip allocated to the local endpoint, snooped from the dhcp ack.
+type DHCPLease struct {
+	InterfaceUUID string
+	IP            net.IP
+	// LeaseTime is zero if the lease is infinite or not announced.
+	LeaseTime time.Duration
+}
+
+// DHCPLeases returns the leases snooped from the dhcp acks, nothing is received if dhcp
+// snooping disabled.
+func (datapathManager *DpManager) DHCPLeases() <-chan DHCPLease {
+	return datapathManager.dhcpLeaseChan
+}
+
+// initDHCPSnoopingFlow duplicates the dhcp replies to the local endpoints, sends one to of
+// controller to ip learning, forwards other as the packets from upstream.
+func (l *LocalBridge) initDHCPSnoopingFlow(sw *ofctrl.OFSwitch) error {
+	dhcpSnoopingFlow, _ := l.vlanInputTable.NewFlow(ofctrl.FlowMatch{
+		Priority:   HIGH_MATCH_FLOW_PRIORITY,
+		InputPort:  l.datapathManager.BridgeChainPortMap[l.name][LocalToPolicySuffix],
+		Ethertype:  PROTOCOL_IP,
+		IpProto:    PROTOCOL_UDP,
+		UdpSrcPort: dhcpServerPort,
+		UdpDstPort: dhcpClientPort,
+	})
+	_ = dhcpSnoopingFlow.SendToController(dhcpSnoopingFlow.NewControllerAction(sw.ControllerID, 0))
+	if err := dhcpSnoopingFlow.Next(l.localEndpointL2ForwardingTable); err != nil {
+		return fmt.Errorf("failed to install dhcp snooping flow, error: %v", err)
+	}
+
+	return nil
+}
+
+func (l *LocalBridge) processDHCP(pkt protocol.Ethernet) {
+	ip, mac, leaseTime, ok := parseDHCPAck(pkt)
+	if !ok {
+		return
+	}
+	// the ack is sent to the client, find the endpoint by the client mac
+	endpoint, isExist := l.getEndpointByMac(mac.String())
+	if !isExist {
+		return
+	}
+
+	lease := DHCPLease{InterfaceUUID: endpoint.InterfaceUUID, IP: ip, LeaseTime: leaseTime}
+	select {
+	case l.datapathManager.dhcpLeaseChan <- lease:
+	default: // Non-block when dhcpLeaseChan is full, the ip would be learned from arp
+		log.Warnf("drop dhcp lease %+v, too many leases not handled", lease)
+	}
+}
+
+func (l *LocalBridge) getEndpointByMac(mac string) (*Endpoint, bool) {
+	for endpointObj := range l.datapathManager.localEndpointDB.IterBuffered() {
+		endpoint := endpointObj.Val.(*Endpoint)
+		if endpoint.BridgeName == l.name && endpoint.MacAddrStr == mac {
+			return endpoint, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseDHCPAck returns the ip allocated, the client mac and the lease time of the dhcp ack.
+// The packet is parsed here instead of protocol.DHCP, which panics on the truncated options.
+func parseDHCPAck(pkt protocol.Ethernet) (net.IP, net.HardwareAddr, time.Duration, bool) {
+	ipv4, ok := pkt.Data.(*protocol.IPv4)
+	if !ok {
+		return nil, nil, 0, false
+	}
+	udp, ok := ipv4.Data.(*protocol.UDP)
+	if !ok || udp.PortSrc != dhcpServerPort || udp.PortDst != dhcpClientPort {
+		return nil, nil, 0, false
+	}
+	msg := udp.Data
+	if len(msg) < dhcpOptionsOffset || msg[0] != dhcpOpBootReply || msg[2] != dhcpEthernetLen ||
+		binary.BigEndian.Uint32(msg[dhcpMagicOffset:]) != dhcpMagicCookie {
+		return nil, nil, 0, false
+	}
+
+	var isAck bool
+	var leaseTime time.Duration
+	for options := msg[dhcpOptionsOffset:]; len(options) > 0 && options[0] != dhcpOptionEnd; {
+		if options[0] == dhcpOptionPad {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return nil, nil, 0, false
+		}
+		value := options[2 : 2+options[1]]
+		switch options[0] {
+		case dhcpOptionMessageType:
+			isAck = len(value) == 1 && value[0] == dhcpMessageAck
+		case dhcpOptionLeaseTime:
+			// 0xffffffff means infinity
+			if len(value) == 4 && binary.BigEndian.Uint32(value) != 0xffffffff {
+				leaseTime = time.Duration(binary.BigEndian.Uint32(value)) * time.Second
+			}
+		}
+		options = options[2+len(value):]
+	}
+
+	// the ack of dhcp inform has no ip allocated
+	yourIP := net.IP(msg[dhcpYourIPOffset : dhcpYourIPOffset+net.IPv4len])
+	if !isAck || !yourIP.IsGlobalUnicast() {
+		return nil, nil, 0, false
+	}
+	clientMac := net.HardwareAddr(msg[dhcpClientMacOffset : dhcpClientMacOffset+dhcpEthernetLen])
+	return utils.IPCopy(yourIP), append(net.HardwareAddr(nil), clientMac...), leaseTime, true
+}
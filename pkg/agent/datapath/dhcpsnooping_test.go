@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+)
+
+func newDHCPPacket(op byte, yourIP net.IP, options ...byte) protocol.Ethernet {
+	msg := make([]byte, dhcpOptionsOffset, dhcpOptionsOffset+len(options))
+	msg[0], msg[2] = op, dhcpEthernetLen
+	copy(msg[dhcpYourIPOffset:], yourIP.To4())
+	copy(msg[dhcpClientMacOffset:], []byte{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee})
+	binary.BigEndian.PutUint32(msg[dhcpMagicOffset:], dhcpMagicCookie)
+	return protocol.Ethernet{
+		Ethertype: PROTOCOL_IP,
+		Data: &protocol.IPv4{
+			Protocol: PROTOCOL_UDP,
+			Data: &protocol.UDP{
+				PortSrc: dhcpServerPort,
+				PortDst: dhcpClientPort,
+				Data:    append(msg, options...),
+			},
+		},
+	}
+}
+
+func TestParseDHCPAck(t *testing.T) {
+	addr := net.ParseIP("10.0.0.10")
+	ack := []byte{dhcpOptionMessageType, 1, dhcpMessageAck}
+	testCases := []struct {
+		name      string
+		pkt       protocol.Ethernet
+		ip        string
+		leaseTime time.Duration
+	}{
+		{
+			name:      "ack with lease time",
+			pkt:       newDHCPPacket(dhcpOpBootReply, addr, append(ack, dhcpOptionPad, dhcpOptionLeaseTime, 4, 0, 0, 0x0e, 0x10, dhcpOptionEnd)...),
+			ip:        "10.0.0.10",
+			leaseTime: time.Hour,
+		},
+		{
+			name: "ack with infinite lease",
+			pkt:  newDHCPPacket(dhcpOpBootReply, addr, append(ack, dhcpOptionLeaseTime, 4, 0xff, 0xff, 0xff, 0xff)...),
+			ip:   "10.0.0.10",
+		},
+		{
+			name: "offer",
+			pkt:  newDHCPPacket(dhcpOpBootReply, addr, dhcpOptionMessageType, 1, 2),
+		},
+		{
+			name: "request",
+			pkt:  newDHCPPacket(1, addr, ack...),
+		},
+		{
+			name: "ack of inform",
+			pkt:  newDHCPPacket(dhcpOpBootReply, net.IPv4zero, ack...),
+		},
+		{
+			name: "truncated option",
+			pkt:  newDHCPPacket(dhcpOpBootReply, addr, append(ack, dhcpOptionLeaseTime, 4, 0)...),
+		},
+		{
+			name: "arp",
+			pkt:  protocol.Ethernet{Ethertype: PROTOCOL_ARP, Data: &protocol.ARP{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, mac, leaseTime, ok := parseDHCPAck(tc.pkt)
+			if ok != (tc.ip != "") {
+				t.Fatalf("expect learned %t, got %t", tc.ip != "", ok)
+			}
+			if ok && (ip.String() != tc.ip || mac.String() != "00:aa:bb:cc:dd:ee" || leaseTime != tc.leaseTime) {
+				t.Fatalf("expect %s %s, got %s %s %s", tc.ip, tc.leaseTime, ip, mac, leaseTime)
+			}
+		})
+	}
+}
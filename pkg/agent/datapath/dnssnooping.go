@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+const (
+	dnsServerPort = 53
+
+	// dnsQueryTimeout is the time a response to the query accepted.
+	dnsQueryTimeout = 10 * time.Second
+	// maxDNSQueries is the max number of the queries waiting for the responses.
+	maxDNSQueries = 4096
+)
+
+// DNSRecord is an address record in the dns response to the local endpoint.
+type DNSRecord struct {
+	// Client is the address of the local endpoint resolved the name.
+	Client net.IP
+	// Name is the lower-case dns name without the trailing dot.
+	Name string
+	IP   net.IP
+	TTL  time.Duration
+}
+
+// dnsQuery is the tuple and the id of a dns query from the local endpoint, the response is
+// accepted only if it matches a query outstanding.
+type dnsQuery struct {
+	client     string
+	clientPort uint16
+	server     string
+	id         uint16
+}
+
+// DNSRecords returns the address records snooped from the dns responses, nothing is
+// received if dns snooping disabled.
+func (datapathManager *DpManager) DNSRecords() <-chan []DNSRecord {
+	return datapathManager.dnsRecordChan
+}
+
+// isDNSResolver returns true if the ip is in the DNSResolvers.
+func (datapathManager *DpManager) isDNSResolver(ip string) bool {
+	addr := net.ParseIP(ip)
+	for _, resolver := range datapathManager.Config.DNSResolvers {
+		if resolver.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// initDNSSnoopingFlow sends a copy of the udp dns queries from the local endpoints to of
+// controller, and duplicates the udp dns responses to the local endpoints, sends one to of
+// controller to learn the addresses of the names, forwards other as the packets from upstream.
+// The response is forwarded without waiting for the policy recomputed, so the first packet
+// to an address newly learned might be dropped, the clients retransmit it.
+func (l *LocalBridge) initDNSSnoopingFlow(sw *ofctrl.OFSwitch) error {
+	dnsQuerySnoopingFlow, _ := l.fromLocalDNSQueryTable.NewFlow(ofctrl.FlowMatch{
+		Priority:   NORMAL_MATCH_FLOW_PRIORITY,
+		Ethertype:  PROTOCOL_IP,
+		IpProto:    PROTOCOL_UDP,
+		UdpDstPort: dnsServerPort,
+	})
+	_ = dnsQuerySnoopingFlow.SendToController(dnsQuerySnoopingFlow.NewControllerAction(sw.ControllerID, 0))
+	if err := dnsQuerySnoopingFlow.Next(ofctrl.NewEmptyElem()); err != nil {
+		return fmt.Errorf("failed to install dns query snooping flow, error: %v", err)
+	}
+
+	dnsSnoopingFlow, _ := l.vlanInputTable.NewFlow(ofctrl.FlowMatch{
+		Priority:   HIGH_MATCH_FLOW_PRIORITY,
+		InputPort:  l.datapathManager.BridgeChainPortMap[l.name][LocalToPolicySuffix],
+		Ethertype:  PROTOCOL_IP,
+		IpProto:    PROTOCOL_UDP,
+		UdpSrcPort: dnsServerPort,
+	})
+	_ = dnsSnoopingFlow.SendToController(dnsSnoopingFlow.NewControllerAction(sw.ControllerID, 0))
+	if err := dnsSnoopingFlow.Next(l.localEndpointL2ForwardingTable); err != nil {
+		return fmt.Errorf("failed to install dns snooping flow, error: %v", err)
+	}
+
+	return nil
+}
+
+// resubmitDNSQuery resubmits the packets from the local endpoints to FROM_LOCAL_DNS_QUERY_TABLE
+// for the dns queries snooping, nothing is done if dns snooping disabled.
+func (l *LocalBridge) resubmitDNSQuery(flow *ofctrl.Flow) error {
+	if !l.datapathManager.Config.EnableDNSSnooping {
+		return nil
+	}
+	var dnsQueryTableID uint8 = FROM_LOCAL_DNS_QUERY_TABLE
+	return flow.Resubmit(nil, &dnsQueryTableID)
+}
+
+func (l *LocalBridge) processDNSQuery(pkt protocol.Ethernet) {
+	query, ok := parseDNSQuery(pkt)
+	if !ok || !l.datapathManager.isDNSResolver(query.server) {
+		return
+	}
+	l.addDNSQuery(query, time.Now())
+}
+
+func (l *LocalBridge) processDNS(pkt protocol.Ethernet) {
+	query, records, ok := parseDNSResponse(pkt)
+	if !ok || !l.datapathManager.isDNSResolver(query.server) {
+		return
+	}
+	// the responses not asked for by the endpoint are ignored, they might be forged
+	if !l.takeDNSQuery(query, time.Now()) {
+		log.Debugf("ignore dns response %+v without query", query)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	select {
+	case l.datapathManager.dnsRecordChan <- records:
+	default: // Non-block when dnsRecordChan is full, the names would be learned from the next response
+		log.Warnf("drop %d dns records, too many records not handled", len(records))
+	}
+}
+
+func (l *LocalBridge) addDNSQuery(query dnsQuery, now time.Time) {
+	l.dnsQueryLock.Lock()
+	defer l.dnsQueryLock.Unlock()
+
+	if len(l.dnsQueries) >= maxDNSQueries {
+		for q, expire := range l.dnsQueries {
+			if !expire.After(now) {
+				delete(l.dnsQueries, q)
+			}
+		}
+	}
+	if len(l.dnsQueries) >= maxDNSQueries {
+		log.Warnf("drop dns query %+v, too many queries outstanding", query)
+		return
+	}
+	l.dnsQueries[query] = now.Add(dnsQueryTimeout)
+}
+
+// takeDNSQuery removes the query, returns true if the query is outstanding.
+func (l *LocalBridge) takeDNSQuery(query dnsQuery, now time.Time) bool {
+	l.dnsQueryLock.Lock()
+	defer l.dnsQueryLock.Unlock()
+
+	expire, ok := l.dnsQueries[query]
+	delete(l.dnsQueries, query)
+	return ok && expire.After(now)
+}
+
+// parseDNSQuery returns the tuple and the id of the udp dns query.
+func parseDNSQuery(pkt protocol.Ethernet) (dnsQuery, bool) {
+	ipv4, ok := pkt.Data.(*protocol.IPv4)
+	if !ok {
+		return dnsQuery{}, false
+	}
+	udp, ok := ipv4.Data.(*protocol.UDP)
+	if !ok || udp.PortDst != dnsServerPort {
+		return dnsQuery{}, false
+	}
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(udp.Data)
+	if err != nil || header.Response {
+		return dnsQuery{}, false
+	}
+	return dnsQuery{
+		client:     ipv4.NWSrc.String(),
+		clientPort: udp.PortSrc,
+		server:     ipv4.NWDst.String(),
+		id:         header.ID,
+	}, true
+}
+
+// parseDNSResponse returns the query the response answers and the ipv4 address records in the
+// answers, the records of the names in the cname chain are returned with the queried name as well.
+func parseDNSResponse(pkt protocol.Ethernet) (dnsQuery, []DNSRecord, bool) {
+	ipv4, ok := pkt.Data.(*protocol.IPv4)
+	if !ok {
+		return dnsQuery{}, nil, false
+	}
+	udp, ok := ipv4.Data.(*protocol.UDP)
+	if !ok || udp.PortSrc != dnsServerPort {
+		return dnsQuery{}, nil, false
+	}
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(udp.Data)
+	if err != nil || !header.Response || header.RCode != dnsmessage.RCodeSuccess {
+		return dnsQuery{}, nil, false
+	}
+	if err = parser.SkipAllQuestions(); err != nil {
+		return dnsQuery{}, nil, false
+	}
+
+	query := dnsQuery{
+		client:     ipv4.NWDst.String(),
+		clientPort: udp.PortDst,
+		server:     ipv4.NWSrc.String(),
+		id:         header.ID,
+	}
+	client := utils.IPCopy(ipv4.NWDst)
+
+	// aliases are the names each cname target aliased from
+	var aliases = make(map[string][]string)
+	var records []DNSRecord
+	for {
+		answer, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return dnsQuery{}, nil, false
+		}
+
+		name := dnsName(answer.Name)
+		ttl := time.Duration(answer.TTL) * time.Second
+		switch answer.Type {
+		case dnsmessage.TypeA:
+			resource, err := parser.AResource()
+			if err != nil {
+				return dnsQuery{}, nil, false
+			}
+			records = append(records, DNSRecord{Client: client, Name: name, IP: utils.IPCopy(resource.A[:]), TTL: ttl})
+		case dnsmessage.TypeCNAME:
+			resource, err := parser.CNAMEResource()
+			if err != nil {
+				return dnsQuery{}, nil, false
+			}
+			target := dnsName(resource.CNAME)
+			aliases[target] = append(aliases[target], name)
+		default:
+			if err = parser.SkipAnswer(); err != nil {
+				return dnsQuery{}, nil, false
+			}
+		}
+	}
+
+	// the addresses are the addresses of the aliases, the cnames are in order in the answers
+	for i := range records {
+		for _, alias := range resolveAliases(aliases, records[i].Name) {
+			records = append(records, DNSRecord{Client: client, Name: alias, IP: records[i].IP, TTL: records[i].TTL})
+		}
+	}
+	return query, records, true
+}
+
+// resolveAliases returns all the names aliased to the name directly or indirectly.
+func resolveAliases(aliases map[string][]string, name string) []string {
+	var names []string
+	var visited = map[string]bool{name: true}
+	for queue := aliases[name]; len(queue) != 0; queue = queue[1:] {
+		if visited[queue[0]] {
+			continue
+		}
+		visited[queue[0]] = true
+		names = append(names, queue[0])
+		queue = append(queue, aliases[queue[0]]...)
+	}
+	return names
+}
+
+func dnsName(name dnsmessage.Name) string {
+	return strings.ToLower(strings.TrimSuffix(name.String(), "."))
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/contiv/libOpenflow/protocol"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newDNSPacket(t *testing.T, header dnsmessage.Header, build func(*dnsmessage.Builder) error) protocol.Ethernet {
+	builder := dnsmessage.NewBuilder(nil, header)
+	if err := builder.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("www.example.com."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	if err := build(&builder); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcIP, dstIP, srcPort, dstPort := testResolver, testClient, uint16(dnsServerPort), uint16(40000)
+	if !header.Response {
+		srcIP, dstIP, srcPort, dstPort = dstIP, srcIP, dstPort, srcPort
+	}
+	return protocol.Ethernet{
+		Ethertype: PROTOCOL_IP,
+		Data: &protocol.IPv4{
+			Protocol: PROTOCOL_UDP,
+			NWSrc:    srcIP,
+			NWDst:    dstIP,
+			Data:     &protocol.UDP{PortSrc: srcPort, PortDst: dstPort, Data: msg},
+		},
+	}
+}
+
+var (
+	testResolver = net.ParseIP("10.96.0.10").To4()
+	testClient   = net.ParseIP("10.0.0.2").To4()
+)
+
+func resourceHeader(name string, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: ttl}
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	response := dnsmessage.Header{ID: 100, Response: true}
+	pkt := newDNSPacket(t, response, func(b *dnsmessage.Builder) error {
+		if err := b.CNAMEResource(resourceHeader("WWW.example.com.", 300), dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.cdn.net.")}); err != nil {
+			return err
+		}
+		if err := b.CNAMEResource(resourceHeader("web.cdn.net.", 300), dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("edge.cdn.net.")}); err != nil {
+			return err
+		}
+		if err := b.AResource(resourceHeader("edge.cdn.net.", 60), dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}); err != nil {
+			return err
+		}
+		return b.AAAAResource(resourceHeader("edge.cdn.net.", 60), dnsmessage.AAAAResource{})
+	})
+
+	query, records, ok := parseDNSResponse(pkt)
+	if !ok {
+		t.Fatalf("expect dns response parsed")
+	}
+	if expect := (dnsQuery{client: "10.0.0.2", clientPort: 40000, server: "10.96.0.10", id: 100}); query != expect {
+		t.Fatalf("expect response to query %+v, got %+v", expect, query)
+	}
+	var got []string
+	for _, record := range records {
+		if record.IP.String() != "10.0.0.1" || record.TTL != time.Minute || !record.Client.Equal(testClient) {
+			t.Fatalf("unexpect record %+v", record)
+		}
+		got = append(got, record.Name)
+	}
+	if expect := []string{"edge.cdn.net", "web.cdn.net", "www.example.com"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect records of %v, got %v", expect, got)
+	}
+
+	queryPkt := newDNSPacket(t, dnsmessage.Header{}, func(b *dnsmessage.Builder) error { return nil })
+	if _, _, ok = parseDNSResponse(queryPkt); ok {
+		t.Fatalf("expect dns query ignored")
+	}
+	nxdomain := newDNSPacket(t, dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeNameError}, func(b *dnsmessage.Builder) error { return nil })
+	if _, _, ok = parseDNSResponse(nxdomain); ok {
+		t.Fatalf("expect dns error response ignored")
+	}
+	truncated := newDNSPacket(t, response, func(b *dnsmessage.Builder) error {
+		return b.AResource(resourceHeader("www.example.com.", 60), dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+	})
+	udp := truncated.Data.(*protocol.IPv4).Data.(*protocol.UDP)
+	udp.Data = udp.Data[:len(udp.Data)-2]
+	if _, _, ok = parseDNSResponse(truncated); ok {
+		t.Fatalf("expect truncated dns response ignored")
+	}
+}
+
+func TestParseDNSQuery(t *testing.T) {
+	pkt := newDNSPacket(t, dnsmessage.Header{ID: 100}, func(b *dnsmessage.Builder) error { return nil })
+	query, ok := parseDNSQuery(pkt)
+	if !ok {
+		t.Fatalf("expect dns query parsed")
+	}
+	if expect := (dnsQuery{client: "10.0.0.2", clientPort: 40000, server: "10.96.0.10", id: 100}); query != expect {
+		t.Fatalf("expect query %+v, got %+v", expect, query)
+	}
+
+	response := newDNSPacket(t, dnsmessage.Header{ID: 100, Response: true}, func(b *dnsmessage.Builder) error { return nil })
+	if _, ok = parseDNSQuery(response); ok {
+		t.Fatalf("expect dns response ignored")
+	}
+}
+
+func TestProcessDNS(t *testing.T) {
+	_, resolvers, _ := net.ParseCIDR("10.96.0.0/24")
+	l := newLocalBridge("test", &DpManager{
+		Config:        &DpManagerConfig{EnableDNSSnooping: true, DNSResolvers: []*net.IPNet{resolvers}},
+		dnsRecordChan: make(chan []DNSRecord, 10),
+	})
+	newResponse := func(id uint16) protocol.Ethernet {
+		return newDNSPacket(t, dnsmessage.Header{ID: id, Response: true}, func(b *dnsmessage.Builder) error {
+			return b.AResource(resourceHeader("www.example.com.", 60), dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+		})
+	}
+	received := func() int {
+		var count int
+		for len(l.datapathManager.dnsRecordChan) != 0 {
+			count += len(<-l.datapathManager.dnsRecordChan)
+		}
+		return count
+	}
+
+	// the response without the query is ignored
+	l.processDNS(newResponse(100))
+	if count := received(); count != 0 {
+		t.Fatalf("expect response without query ignored, got %d records", count)
+	}
+
+	// the response matches the query is learned once
+	l.processDNSQuery(newDNSPacket(t, dnsmessage.Header{ID: 100}, func(b *dnsmessage.Builder) error { return nil }))
+	l.processDNS(newResponse(101))
+	l.processDNS(newResponse(100))
+	l.processDNS(newResponse(100))
+	if count := received(); count != 1 {
+		t.Fatalf("expect one record learned, got %d records", count)
+	}
+
+	// the queries to the servers not trusted are ignored
+	l.datapathManager.Config.DNSResolvers = nil
+	l.processDNSQuery(newDNSPacket(t, dnsmessage.Header{ID: 102}, func(b *dnsmessage.Builder) error { return nil }))
+	l.processDNS(newResponse(102))
+	if count := received(); count != 0 {
+		t.Fatalf("expect response from untrusted server ignored, got %d records", count)
+	}
+}
+
+func TestTakeDNSQuery(t *testing.T) {
+	l := newLocalBridge("test", &DpManager{})
+	query := dnsQuery{client: "10.0.0.2", clientPort: 40000, server: "10.96.0.10", id: 100}
+	now := time.Now()
+
+	l.addDNSQuery(query, now)
+	if l.takeDNSQuery(query, now.Add(dnsQueryTimeout)) {
+		t.Fatalf("expect query expired")
+	}
+	l.addDNSQuery(query, now)
+	if !l.takeDNSQuery(query, now.Add(time.Second)) {
+		t.Fatalf("expect query outstanding")
+	}
+	if l.takeDNSQuery(query, now.Add(time.Second)) {
+		t.Fatalf("expect query taken by the first response")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+const (
+	// PreviousRoundFlowsTimeout is the time to wait before deleting the flows of the previous round.
+	PreviousRoundFlowsTimeout = 15 * time.Second
+	// AdoptedRoundFlowsTimeout is the max time to keep the flows adopted from the previous agent,
+	// in case of the flows never synced.
+	AdoptedRoundFlowsTimeout = 5 * time.Minute
+)
+
+// AdoptFlows adopts the flows and the learned ip addresses of the previous agent, it must be called
+// before InitializeDatapath. The flows are adopted only when the round matches the previous round
+// in ovsdb, they are kept until MarkFlowsSynced called.
+func (datapathManager *DpManager) AdoptFlows(rounds map[string]uint64, learnedIPAddrs, learnedIPv6Addrs map[string]net.IP) {
+	datapathManager.handoffMutex.Lock()
+	defer datapathManager.handoffMutex.Unlock()
+
+	for vdsID, round := range rounds {
+		datapathManager.adoptedRounds[vdsID] = round
+	}
+	for interfaceUUID, ip := range learnedIPAddrs {
+		datapathManager.adoptedIPAddrs[interfaceUUID] = utils.IPCopy(ip)
+	}
+	for interfaceUUID, ip := range learnedIPv6Addrs {
+		datapathManager.adoptedIPv6Addrs[interfaceUUID] = utils.IPCopy(ip)
+	}
+}
+
+// MarkFlowsSynced marks all of the flows installed with the current round, the flows adopted from
+// the previous agent would be deleted.
+func (datapathManager *DpManager) MarkFlowsSynced() {
+	datapathManager.flowsSyncedOnce.Do(func() {
+		close(datapathManager.flowsSynced)
+	})
+}
+
+// IsFlowsSynced returns true if the flows have been synced since the agent start.
+func (datapathManager *DpManager) IsFlowsSynced() bool {
+	select {
+	case <-datapathManager.flowsSynced:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetRoundNums returns the round num of the installed flows of each vds.
+func (datapathManager *DpManager) GetRoundNums() map[string]uint64 {
+	datapathManager.handoffMutex.RLock()
+	defer datapathManager.handoffMutex.RUnlock()
+
+	rounds := make(map[string]uint64, len(datapathManager.roundNums))
+	for vdsID, round := range datapathManager.roundNums {
+		rounds[vdsID] = round
+	}
+	return rounds
+}
+
+// ListLocalEndpoints returns the copy of the local endpoints.
+func (datapathManager *DpManager) ListLocalEndpoints() []*Endpoint {
+	var endpoints []*Endpoint
+	for item := range datapathManager.localEndpointDB.IterBuffered() {
+		ep := item.Val.(*Endpoint)
+		ep.IPAddrMutex.RLock()
+		endpoints = append(endpoints, &Endpoint{
+			InterfaceUUID:        ep.InterfaceUUID,
+			InterfaceName:        ep.InterfaceName,
+			IPAddr:               utils.IPCopy(ep.IPAddr),
+			IPAddrLastUpdateTime: ep.IPAddrLastUpdateTime,
+			IPv6Addr:             utils.IPCopy(ep.IPv6Addr),
+			PortNo:               ep.PortNo,
+			MacAddrStr:           ep.MacAddrStr,
+			VlanID:               ep.VlanID,
+			Trunk:                ep.Trunk,
+			BridgeName:           ep.BridgeName,
+		})
+		ep.IPAddrMutex.RUnlock()
+	}
+	return endpoints
+}
+
+func (datapathManager *DpManager) setRoundNum(vdsID string, round uint64) {
+	datapathManager.handoffMutex.Lock()
+	defer datapathManager.handoffMutex.Unlock()
+	datapathManager.roundNums[vdsID] = round
+}
+
+// waitPreviousRoundFlowsExpired waits until the flows of the previous round could be deleted. The flows
+// adopted from the previous agent are kept until all of the flows synced, otherwise wait for a fixed time.
+func (datapathManager *DpManager) waitPreviousRoundFlowsExpired(vdsID string, previousRoundNum uint64) {
+	datapathManager.handoffMutex.RLock()
+	adoptedRound, adopted := datapathManager.adoptedRounds[vdsID]
+	datapathManager.handoffMutex.RUnlock()
+
+	if !adopted || adoptedRound != previousRoundNum {
+		if adopted {
+			log.Warnf("Skip adopt flows of vds %s, handoff round %d mismatch previous round %d", vdsID, adoptedRound, previousRoundNum)
+		}
+		time.Sleep(PreviousRoundFlowsTimeout)
+		return
+	}
+
+	select {
+	case <-datapathManager.flowsSynced:
+		log.Infof("All flows of vds %s synced, delete flows adopted from the previous agent", vdsID)
+	case <-time.After(AdoptedRoundFlowsTimeout):
+		log.Warnf("Flows of vds %s not synced in %s, delete flows adopted from the previous agent", vdsID, AdoptedRoundFlowsTimeout)
+	}
+}
+
+// restoreLearnedIPAddr restores the ip addresses of the endpoint learned by the previous agent, so the
+// traffics of the endpoint are allowed before its ip addresses learned again.
+func (datapathManager *DpManager) restoreLearnedIPAddr(endpoint *Endpoint) {
+	if !datapathManager.Config.EnableIPLearning {
+		return
+	}
+
+	datapathManager.handoffMutex.Lock()
+	ip, ok := datapathManager.adoptedIPAddrs[endpoint.InterfaceUUID]
+	ipv6, ipv6OK := datapathManager.adoptedIPv6Addrs[endpoint.InterfaceUUID]
+	delete(datapathManager.adoptedIPAddrs, endpoint.InterfaceUUID)
+	delete(datapathManager.adoptedIPv6Addrs, endpoint.InterfaceUUID)
+	datapathManager.handoffMutex.Unlock()
+
+	if ok && endpoint.IPAddr == nil {
+		endpoint.IPAddr = ip
+		endpoint.IPAddrLastUpdateTime = time.Now()
+	}
+	if ipv6OK && endpoint.IPv6Addr == nil {
+		endpoint.IPv6Addr = ipv6
+	}
+}
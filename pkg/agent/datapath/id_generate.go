@@ -1,12 +1,13 @@
 package datapath
 
 import (
-	"errors"
 	"sync"
 
 	"github.com/contiv/libOpenflow/openflow13"
 	"github.com/contiv/ofnet/ofctrl/cookie"
 	log "github.com/sirupsen/logrus"
+
+	ererrors "github.com/everoute/everoute/pkg/errors"
 )
 
 const InvalidGroupID uint32 = 0
@@ -40,7 +41,7 @@ func getLearnCookieID() (uint64, error) {
 	learnCookieID.ascendUint64()
 	if learnCookieID.idUint64 >= (uint64(1) << cookie.BitWidthFlowId) {
 		log.Error("No enough avalible cookie id")
-		return 0, errors.New("no enough avalible cookie id")
+		return 0, ererrors.NewFatal("no enough avalible cookie id")
 	}
 	return learnCookieID.idUint64, nil
 }
@@ -49,7 +50,7 @@ func getGroupID() (uint32, error) {
 	groupID.ascendUint32()
 	if groupID.idUint32 > openflow13.OFPG_MAX {
 		log.Error("No enough avalible group id")
-		return InvalidGroupID, errors.New("no enough avalible group id")
+		return InvalidGroupID, ererrors.NewFatal("no enough avalible group id")
 	}
 
 	return groupID.idUint32, nil
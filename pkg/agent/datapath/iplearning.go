@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/contiv/libOpenflow/openflow13"
+	"github.com/contiv/libOpenflow/protocol"
+	"github.com/contiv/ofnet/ofctrl"
+
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// The arp packets are learned since the ip learning was introduced, the ipv6 addresses are
+// learned from the neighbor discovery packets (RFC 4861) the same way.
+const (
+	icmpv6TypeNeighborSolicitation  = 135
+	icmpv6TypeNeighborAdvertisement = 136
+
+	ndOptionSourceLinkLayerAddress = 1
+	ndOptionTargetLinkLayerAddress = 2
+
+	// reserved and flags before the target address in the icmp body
+	ndTargetAddressOffset = 4
+	ndOptionsOffset       = ndTargetAddressOffset + net.IPv6len
+)
+
+// initFromLocalNDFlows duplicates the neighbor solicitations and advertisements from local,
+// sends one to of controller to ip learning, sends other to local to policy port.
+func (l *LocalBridge) initFromLocalNDFlows(sw *ofctrl.OFSwitch) error {
+	outputPort, _ := sw.OutputPort(l.datapathManager.BridgeChainPortMap[l.name][LocalToPolicySuffix])
+	for _, icmpType := range []uint8{icmpv6TypeNeighborSolicitation, icmpv6TypeNeighborAdvertisement} {
+		fromLocalNDFlow, _ := l.fromLocalRedirectTable.NewFlow(ofctrl.FlowMatch{
+			Priority:  HIGH_MATCH_FLOW_PRIORITY,
+			Ethertype: PROTOCOL_IPV6,
+			IpProto:   PROTOCOL_ICMPV6,
+			RawMatchField: []*openflow13.MatchField{{
+				Class:  openflow13.OXM_CLASS_OPENFLOW_BASIC,
+				Field:  openflow13.OXM_FIELD_ICMPV6_TYPE,
+				Length: 1,
+				Value:  &openflow13.IcmpTypeField{Type: icmpType},
+			}},
+		})
+		_ = fromLocalNDFlow.SendToController(fromLocalNDFlow.NewControllerAction(sw.ControllerID, 0))
+		if err := fromLocalNDFlow.Next(outputPort); err != nil {
+			return fmt.Errorf("failed to install from local nd type %d redirect flow, error: %v", icmpType, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *LocalBridge) processND(pkt protocol.Ethernet, inPort uint32) {
+	ip, mac, ok := parseNDAddress(pkt)
+	if !ok {
+		return
+	}
+	endpoint, isExist := l.getEndpointByPort(inPort)
+	if !isExist || endpoint.MacAddrStr != mac.String() {
+		return
+	}
+
+	endpoint.IPAddrMutex.Lock()
+	endpoint.IPv6Addr = ip
+	endpoint.IPAddrMutex.Unlock()
+
+	l.learnedIPAddressMapMutex.Lock()
+	defer l.learnedIPAddressMapMutex.Unlock()
+	ipReference, ok := l.learnedIPAddressMap[ip.String()]
+	if ok && ipReference.updateTimes <= 0 {
+		return
+	}
+
+	l.datapathManager.ofPortIPAddressUpdateChan <- map[string]net.IP{endpoint.InterfaceUUID: ip}
+	if !ok {
+		l.learnedIPAddressMap[ip.String()] = IPAddressReference{
+			lastUpdateTime: time.Now(),
+			updateTimes:    MaxIPAddressLearningFrenquency,
+		}
+	} else {
+		l.learnedIPAddressMap[ip.String()] = IPAddressReference{
+			lastUpdateTime: ipReference.lastUpdateTime,
+			updateTimes:    ipReference.updateTimes - 1,
+		}
+	}
+}
+
+// parseNDAddress returns the global unicast address and the mac announced by the neighbor
+// discovery packet. The advertisement announces its target address, the solicitation announces
+// its source address, except the duplicate address detection which is sent from the unspecified
+// address. The mac is the link layer address option if present, else the ethernet source.
+func parseNDAddress(pkt protocol.Ethernet) (net.IP, net.HardwareAddr, bool) {
+	ipv6, ok := pkt.Data.(*protocol.IPv6)
+	if !ok {
+		return nil, nil, false
+	}
+	icmp, ok := ipv6.Data.(*protocol.ICMP)
+	if !ok || len(icmp.Data) < ndOptionsOffset {
+		return nil, nil, false
+	}
+
+	var ip net.IP
+	var optionType uint8
+	switch icmp.Type {
+	case icmpv6TypeNeighborSolicitation:
+		ip, optionType = ipv6.NWSrc, ndOptionSourceLinkLayerAddress
+	case icmpv6TypeNeighborAdvertisement:
+		ip, optionType = net.IP(icmp.Data[ndTargetAddressOffset:ndOptionsOffset]), ndOptionTargetLinkLayerAddress
+	default:
+		return nil, nil, false
+	}
+	if !ip.IsGlobalUnicast() {
+		return nil, nil, false
+	}
+
+	mac := pkt.HWSrc
+	if lladdr := findNDOption(icmp.Data[ndOptionsOffset:], optionType); len(lladdr) >= 6 {
+		mac = net.HardwareAddr(lladdr[:6])
+	}
+	return utils.IPCopy(ip), append(net.HardwareAddr(nil), mac...), true
+}
+
+// findNDOption returns the value of the option, the length of an option is in units of 8 bytes
+// including the type and length fields.
+func findNDOption(options []byte, optionType uint8) []byte {
+	for len(options) >= 2 {
+		length := int(options[1]) * 8
+		if length == 0 || length > len(options) {
+			return nil
+		}
+		if options[0] == optionType {
+			return options[2:length]
+		}
+		options = options[length:]
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"testing"
+
+	"github.com/contiv/libOpenflow/protocol"
+)
+
+func newNDPacket(src net.IP, icmpType uint8, target net.IP, options ...byte) protocol.Ethernet {
+	body := make([]byte, ndOptionsOffset, ndOptionsOffset+len(options))
+	copy(body[ndTargetAddressOffset:], target.To16())
+	return protocol.Ethernet{
+		HWSrc:     net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		Ethertype: PROTOCOL_IPV6,
+		Data: &protocol.IPv6{
+			NextHeader: PROTOCOL_ICMPV6,
+			NWSrc:      src,
+			Data:       &protocol.ICMP{Type: icmpType, Data: append(body, options...)},
+		},
+	}
+}
+
+func TestParseNDAddress(t *testing.T) {
+	addr := net.ParseIP("fd00::10")
+	linkLayerOption := func(optionType byte) []byte {
+		return []byte{optionType, 1, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	}
+	testCases := []struct {
+		name string
+		pkt  protocol.Ethernet
+		ip   string
+		mac  string
+	}{
+		{
+			name: "solicitation with source link layer address",
+			pkt:  newNDPacket(addr, icmpv6TypeNeighborSolicitation, net.ParseIP("fd00::1"), linkLayerOption(ndOptionSourceLinkLayerAddress)...),
+			ip:   "fd00::10",
+			mac:  "00:aa:bb:cc:dd:ee",
+		},
+		{
+			name: "solicitation without option",
+			pkt:  newNDPacket(addr, icmpv6TypeNeighborSolicitation, net.ParseIP("fd00::1")),
+			ip:   "fd00::10",
+			mac:  "00:11:22:33:44:55",
+		},
+		{
+			name: "duplicate address detection",
+			pkt:  newNDPacket(net.IPv6unspecified, icmpv6TypeNeighborSolicitation, addr),
+		},
+		{
+			name: "advertisement with target link layer address",
+			pkt:  newNDPacket(net.ParseIP("fe80::1"), icmpv6TypeNeighborAdvertisement, addr, linkLayerOption(ndOptionTargetLinkLayerAddress)...),
+			ip:   "fd00::10",
+			mac:  "00:aa:bb:cc:dd:ee",
+		},
+		{
+			name: "advertisement ignores source link layer address",
+			pkt:  newNDPacket(net.ParseIP("fe80::1"), icmpv6TypeNeighborAdvertisement, addr, linkLayerOption(ndOptionSourceLinkLayerAddress)...),
+			ip:   "fd00::10",
+			mac:  "00:11:22:33:44:55",
+		},
+		{
+			name: "advertisement of link local address",
+			pkt:  newNDPacket(net.ParseIP("fe80::1"), icmpv6TypeNeighborAdvertisement, net.ParseIP("fe80::1")),
+		},
+		{
+			name: "invalid option length",
+			pkt:  newNDPacket(addr, icmpv6TypeNeighborSolicitation, net.ParseIP("fd00::1"), ndOptionSourceLinkLayerAddress, 0),
+			ip:   "fd00::10",
+			mac:  "00:11:22:33:44:55",
+		},
+		{
+			name: "router solicitation",
+			pkt:  newNDPacket(addr, 133, nil),
+		},
+		{
+			name: "arp",
+			pkt:  protocol.Ethernet{Ethertype: PROTOCOL_ARP, Data: &protocol.ARP{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, mac, ok := parseNDAddress(tc.pkt)
+			if ok != (tc.ip != "") {
+				t.Fatalf("expect learned %t, got %t", tc.ip != "", ok)
+			}
+			if ok && (ip.String() != tc.ip || mac.String() != tc.mac) {
+				t.Fatalf("expect %s %s, got %s %s", tc.ip, tc.mac, ip, mac)
+			}
+		})
+	}
+}
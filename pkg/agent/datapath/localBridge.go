@@ -40,6 +40,7 @@ const (
 	FROM_LOCAL_REDIRECT_TABLE          = 15
 	FROM_LOCAL_ARP_PASS_TABLE          = 20
 	FROM_LOCAL_ARP_TO_CONTROLLER_TABLE = 25
+	FROM_LOCAL_DNS_QUERY_TABLE         = 30
 	CNI_CT_COMMIT_TABLE                = 100
 	CNI_CT_REDIRECT_TABLE              = 105
 	FACK_MAC                           = "ee:ee:ee:ee:ee:ee"
@@ -68,6 +69,7 @@ type LocalBridge struct {
 	fromLocalRedirectTable         *ofctrl.Table // Table 15
 	fromLocalArpPassTable          *ofctrl.Table // Table 20
 	fromLocalArpSendToCtrlTable    *ofctrl.Table // Table 25
+	fromLocalDNSQueryTable         *ofctrl.Table // Table 30
 	cniConntrackCommitTable        *ofctrl.Table // Table 100
 	cniConntrackRedirectTable      *ofctrl.Table // Table 105
 
@@ -78,6 +80,9 @@ type LocalBridge struct {
 	localToLocalBUMFlow      map[uint32]*ofctrl.Flow
 	learnedIPAddressMapMutex sync.RWMutex
 	learnedIPAddressMap      map[string]IPAddressReference
+	// dnsQueries are the dns queries from the local endpoints waiting for the responses
+	dnsQueryLock sync.Mutex
+	dnsQueries   map[dnsQuery]time.Time
 }
 
 type IPAddressReference struct {
@@ -100,6 +105,7 @@ func newLocalBridge(brName string, datapathManager *DpManager) *LocalBridge {
 	localBridge.fromLocalVlanFilterFlow = make(map[uint32][]*ofctrl.Flow)
 	localBridge.localToLocalBUMFlow = make(map[uint32]*ofctrl.Flow)
 	localBridge.learnedIPAddressMap = make(map[string]IPAddressReference)
+	localBridge.dnsQueries = make(map[dnsQuery]time.Time)
 
 	return localBridge
 }
@@ -107,26 +113,60 @@ func newLocalBridge(brName string, datapathManager *DpManager) *LocalBridge {
 func (l *LocalBridge) PacketRcvd(sw *ofctrl.OFSwitch, pkt *ofctrl.PacketIn) {
 	switch pkt.Data.Ethertype {
 	case PROTOCOL_ARP:
-		if (pkt.Match.Type == openflow13.MatchType_OXM) &&
-			(pkt.Match.Fields[0].Class == openflow13.OXM_CLASS_OPENFLOW_BASIC) &&
-			(pkt.Match.Fields[0].Field == openflow13.OXM_FIELD_IN_PORT) {
-			// Get the input port number
-			switch t := pkt.Match.Fields[0].Value.(type) {
-			case *openflow13.InPortField:
-				var inPortFld openflow13.InPortField
-				inPortFld = *t
-				l.processArp(pkt.Data, inPortFld.InPort)
-			default:
-				log.Errorf("error inport filed")
-			}
+		if inPort, ok := packetInPort(pkt); ok {
+			l.processArp(pkt.Data, inPort)
 		}
 
-	case protocol.IPv4_MSG: // other type of packet that must processing by controller
-		log.Errorf("controller received non arp packet error.")
-		return
+	case PROTOCOL_IPV6:
+		if inPort, ok := packetInPort(pkt); ok {
+			l.processND(pkt.Data, inPort)
+		}
+
+	case protocol.IPv4_MSG:
+		switch {
+		case isUDPPacketTo(pkt.Data, dnsServerPort) && l.datapathManager.Config.EnableDNSSnooping:
+			l.processDNSQuery(pkt.Data)
+		case isUDPPacketFrom(pkt.Data, dnsServerPort) && l.datapathManager.Config.EnableDNSSnooping:
+			l.processDNS(pkt.Data)
+		case l.datapathManager.Config.EnableDHCPSnooping:
+			l.processDHCP(pkt.Data)
+		default:
+			log.Errorf("controller received non arp packet error.")
+		}
 	}
 }
 
+func isUDPPacketFrom(pkt protocol.Ethernet, srcPort uint16) bool {
+	if ipv4, ok := pkt.Data.(*protocol.IPv4); ok {
+		udp, ok := ipv4.Data.(*protocol.UDP)
+		return ok && udp.PortSrc == srcPort
+	}
+	return false
+}
+
+func isUDPPacketTo(pkt protocol.Ethernet, dstPort uint16) bool {
+	if ipv4, ok := pkt.Data.(*protocol.IPv4); ok {
+		udp, ok := ipv4.Data.(*protocol.UDP)
+		return ok && udp.PortDst == dstPort
+	}
+	return false
+}
+
+func packetInPort(pkt *ofctrl.PacketIn) (uint32, bool) {
+	if (pkt.Match.Type == openflow13.MatchType_OXM) &&
+		(pkt.Match.Fields[0].Class == openflow13.OXM_CLASS_OPENFLOW_BASIC) &&
+		(pkt.Match.Fields[0].Field == openflow13.OXM_FIELD_IN_PORT) {
+		// Get the input port number
+		switch t := pkt.Match.Fields[0].Value.(type) {
+		case *openflow13.InPortField:
+			return t.InPort, true
+		default:
+			log.Errorf("error inport filed")
+		}
+	}
+	return 0, false
+}
+
 func (l *LocalBridge) MultipartReply(sw *ofctrl.OFSwitch, rep *openflow13.MultipartReply) {
 }
 
@@ -228,7 +268,7 @@ func (l *LocalBridge) processLocalEndpointUpdate(arpIn protocol.ARP, inPort uint
 		return
 	}
 
-	l.notifyLocalEndpointUpdate(arpIn, inPort)
+	l.notifyLocalEndpointUpdate(arpIn, endpoint)
 	ipReference, ok := l.learnedIPAddressMap[arpIn.IPSrc.String()]
 	if !ok {
 		l.learnedIPAddressMap[arpIn.IPSrc.String()] = IPAddressReference{
@@ -254,9 +294,9 @@ func (l *LocalBridge) getEndpointByPort(inPort uint32) (*Endpoint, bool) {
 	return nil, false
 }
 
-func (l *LocalBridge) notifyLocalEndpointUpdate(arpIn protocol.ARP, ofPort uint32) {
+func (l *LocalBridge) notifyLocalEndpointUpdate(arpIn protocol.ARP, endpoint *Endpoint) {
 	updatedOfPortInfo := make(map[string]net.IP)
-	updatedOfPortInfo[fmt.Sprintf("%s-%d", l.name, ofPort)] = arpIn.IPSrc
+	updatedOfPortInfo[endpoint.InterfaceUUID] = arpIn.IPSrc
 	l.datapathManager.ofPortIPAddressUpdateChan <- updatedOfPortInfo
 }
 
@@ -270,6 +310,9 @@ func (l *LocalBridge) BridgeInit() {
 	l.localEndpointL2LearningTable, _ = sw.NewTable(L2_LEARNING_TABLE)
 	l.fromLocalRedirectTable, _ = sw.NewTable(FROM_LOCAL_REDIRECT_TABLE)
 	l.fromLocalArpPassTable, _ = sw.NewTable(FROM_LOCAL_ARP_PASS_TABLE)
+	if l.datapathManager.Config.EnableDNSSnooping {
+		l.fromLocalDNSQueryTable, _ = sw.NewTable(FROM_LOCAL_DNS_QUERY_TABLE)
+	}
 
 	if err := l.initVlanInputTable(sw); err != nil {
 		log.Fatalf("Failed to init local bridge vlanInput table, error: %v", err)
@@ -744,6 +787,16 @@ func (l *LocalBridge) initVlanInputTable(sw *ofctrl.OFSwitch) error {
 	if err := fromUpstreamFlow.Next(l.localEndpointL2ForwardingTable); err != nil {
 		return fmt.Errorf("failed to install from upstream flow, error: %v", err)
 	}
+	if l.datapathManager.Config.EnableIPLearning && l.datapathManager.Config.EnableDHCPSnooping {
+		if err := l.initDHCPSnoopingFlow(sw); err != nil {
+			return err
+		}
+	}
+	if l.datapathManager.Config.EnableDNSSnooping {
+		if err := l.initDNSSnoopingFlow(sw); err != nil {
+			return err
+		}
+	}
 
 	vlanInputTableDefaultFlow, _ := l.vlanInputTable.NewFlow(ofctrl.FlowMatch{
 		Priority: DEFAULT_FLOW_MISS_PRIORITY,
@@ -751,6 +804,9 @@ func (l *LocalBridge) initVlanInputTable(sw *ofctrl.OFSwitch) error {
 	if err := vlanInputTableDefaultFlow.Resubmit(nil, &l.localEndpointL2LearningTable.TableId); err != nil {
 		return fmt.Errorf("failed to setup vlan input table default flow resubmit to learning table action, error: %v", err)
 	}
+	if err := l.resubmitDNSQuery(vlanInputTableDefaultFlow); err != nil {
+		return fmt.Errorf("failed to setup vlan input table default flow resubmit to dns query table action, error: %v", err)
+	}
 	if err := vlanInputTableDefaultFlow.Resubmit(nil, &l.fromLocalRedirectTable.TableId); err != nil {
 		return fmt.Errorf("failed to setup vlan input table default flow resubmit to redirect table action, error: %v", err)
 	}
@@ -851,6 +907,11 @@ func (l *LocalBridge) initFromLocalRedirectTable(sw *ofctrl.OFSwitch) error {
 	if err := fromLocalArpFlow.Next(ofctrl.NewEmptyElem()); err != nil {
 		return fmt.Errorf("failed to install from local arp redirect flow, error: %v", err)
 	}
+	if l.datapathManager.Config.EnableIPLearning {
+		if err := l.initFromLocalNDFlows(sw); err != nil {
+			return err
+		}
+	}
 
 	// from local other protocol type, send to local to policy port
 	fromLocalOtherRedirectFlow, _ := l.fromLocalRedirectTable.NewFlow(ofctrl.FlowMatch{
@@ -978,6 +1039,9 @@ func (l *LocalBridge) addAccessPortEndpoint(endpoint *Endpoint) error {
 	if err := vlanInputTableFromLocalFlow.Resubmit(nil, &l.localEndpointL2LearningTable.TableId); err != nil {
 		return err
 	}
+	if err := l.resubmitDNSQuery(vlanInputTableFromLocalFlow); err != nil {
+		return err
+	}
 	if err := vlanInputTableFromLocalFlow.Resubmit(nil, &l.fromLocalRedirectTable.TableId); err != nil {
 		return err
 	}
@@ -1029,6 +1093,9 @@ func (l *LocalBridge) addTrunkPortEndpoint(endpoint *Endpoint) error {
 		if err := vlanInputTableFromLocalFlow.Resubmit(nil, &l.localEndpointL2LearningTable.TableId); err != nil {
 			return err
 		}
+		if err := l.resubmitDNSQuery(vlanInputTableFromLocalFlow); err != nil {
+			return err
+		}
 		if err := vlanInputTableFromLocalFlow.Resubmit(nil, &l.fromLocalRedirectTable.TableId); err != nil {
 			return err
 		}
@@ -1113,6 +1180,9 @@ func (l *LocalBridge) addTrunkPortEndpoint(endpoint *Endpoint) error {
 		if err := fromLocalVlanFilterFlow.Resubmit(nil, &l.localEndpointL2LearningTable.TableId); err != nil {
 			return err
 		}
+		if err := l.resubmitDNSQuery(fromLocalVlanFilterFlow); err != nil {
+			return err
+		}
 		if err := fromLocalVlanFilterFlow.Resubmit(nil, &l.fromLocalRedirectTable.TableId); err != nil {
 			return err
 		}
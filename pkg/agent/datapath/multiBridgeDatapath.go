@@ -45,6 +45,7 @@ import (
 	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
 	"github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
+	ererrors "github.com/everoute/everoute/pkg/errors"
 	"github.com/everoute/everoute/pkg/utils"
 )
 
@@ -90,10 +91,13 @@ const (
 
 //nolint
 const (
-	PROTOCOL_ARP = 0x0806
-	PROTOCOL_IP  = 0x0800
-	PROTOCOL_UDP = 0x11
-	PROTOCOL_TCP = 0x06
+	PROTOCOL_ARP    = 0x0806
+	PROTOCOL_IP     = 0x0800
+	PROTOCOL_IPV6   = 0x86dd
+	PROTOCOL_UDP    = 0x11
+	PROTOCOL_TCP    = 0x06
+	PROTOCOL_ICMP   = 0x01
+	PROTOCOL_ICMPV6 = 0x3a
 )
 
 //nolint
@@ -140,6 +144,12 @@ const (
 
 	MaxArpChanCache = 100
 
+	MaxDHCPLeaseChanSize = 100
+
+	MaxDNSRecordChanSize = 1000
+
+	MaxDeniedPacketChanSize = 1000
+
 	MaxCleanConntrackChanSize = 5000
 )
 
@@ -205,7 +215,7 @@ type DpManager struct {
 	BridgeChainPortMap map[string]map[string]uint32 // map vds to patch port to ofport-num map
 
 	localEndpointDB           cmap.ConcurrentMap     // list of local endpoint map
-	ofPortIPAddressUpdateChan chan map[string]net.IP // map bridgename-ofport to endpoint ips
+	ofPortIPAddressUpdateChan chan map[string]net.IP // map interface uuid to endpoint ips
 	Config                    *DpManagerConfig
 	Info                      *DpManagerInfo
 	Rules                     map[string]*EveroutePolicyRuleEntry // rules database
@@ -218,8 +228,24 @@ type DpManager struct {
 
 	ArpChan chan ArpInfo
 
+	dhcpLeaseChan chan DHCPLease // leases snooped from the dhcp acks
+
+	dnsRecordChan chan []DNSRecord // address records snooped from the dns responses
+
+	deniedPacketChan chan DeniedPacket // packets dropped by the deny rules
+
+	ctZones map[string]uint16 // map vds to policy conntrack zone
+
 	proxyReplayFunc   func()
 	overlayReplayFunc func()
+
+	handoffMutex     sync.RWMutex
+	roundNums        map[string]uint64 // map vds to round num of the installed flows
+	adoptedRounds    map[string]uint64 // map vds to round num of the flows adopted from the previous agent
+	adoptedIPAddrs   map[string]net.IP // map interface uuid to ip learned by the previous agent
+	adoptedIPv6Addrs map[string]net.IP // map interface uuid to ipv6 learned by the previous agent
+	flowsSynced      chan struct{}
+	flowsSyncedOnce  sync.Once
 }
 
 type DpManagerInfo struct {
@@ -249,6 +275,32 @@ type DpManagerConfig struct {
 	EnableIPLearning bool                // enable ip learning
 	EnableCNI        bool                // enable CNI in Everoute
 	CNIConfig        *DpManagerCNIConfig // config related CNI
+
+	// EnableDHCPSnooping learns the ips and their leases from the dhcp acks to the local
+	// endpoints, in addition to the arp learning, only effective with ip learning enabled.
+	EnableDHCPSnooping bool
+
+	// EnableDNSSnooping learns the addresses of the dns names from the udp dns responses to the
+	// local endpoints, the records are received from DNSRecords. Only the responses from the
+	// DNSResolvers matching a query of the endpoint are learned.
+	EnableDNSSnooping bool
+	DNSResolvers      []*net.IPNet
+
+	// EnableDenyLogging punts a copy of the packets dropped by the deny rules to the agent, the
+	// packets are received from DeniedPackets.
+	EnableDenyLogging bool
+
+	// AllowSpanningTree allow manage bridges with stp or rstp enabled. The bridge chain
+	// forward by openflow rules without spanning tree, blocked ports may cause loops.
+	AllowSpanningTree bool
+
+	// OVNInterop coexist with OVN on the host, refuse to manage the bridges owned by OVN.
+	// Everoute flows are only installed on its own bridges, never collide with OVN flows.
+	OVNInterop bool
+
+	// VRFMap map vds to the vrf it belongs to, the vds not in it belongs to the default vrf.
+	// The vds forwards and tracks the connections separately, so the vrfs could overlap.
+	VRFMap map[string]string
 }
 
 type DpManagerCNIConfig struct {
@@ -280,12 +332,20 @@ type EveroutePolicyRule struct {
 	SrcPortMask uint16
 	DstPort     uint16 // destination port
 	DstPortMask uint16
-	Action      string // rule action: 'allow' or 'deny'
+	ICMPType    *uint8 // icmp or icmpv6 type, nil matches all types
+	ICMPCode    *uint8 // icmp or icmpv6 code, nil matches all codes
+	Action      string // rule action: 'allow', 'deny' or 'redirect'
+
+	RedirectIPAddr string // redirect target ip when action is 'redirect'
+	RedirectPort   uint16 // redirect target port, zero means keep the original port
+
+	VRF string // vrf the rule scoped in, the rule only installed on the vds of the vrf
 }
 
 const (
-	EveroutePolicyAllow string = "allow"
-	EveroutePolicyDeny  string = "deny"
+	EveroutePolicyAllow    string = "allow"
+	EveroutePolicyDeny     string = "deny"
+	EveroutePolicyRedirect string = "redirect"
 )
 
 type FlowEntry struct {
@@ -344,8 +404,16 @@ func NewDatapathManager(datapathConfig *DpManagerConfig, ofPortIPAddressUpdateCh
 	datapathManager.flowReplayMutex = sync.RWMutex{}
 	datapathManager.cleanConntrackChan = make(chan EveroutePolicyRule, MaxCleanConntrackChanSize)
 	datapathManager.ArpChan = make(chan ArpInfo, MaxArpChanCache)
+	datapathManager.dhcpLeaseChan = make(chan DHCPLease, MaxDHCPLeaseChanSize)
+	datapathManager.dnsRecordChan = make(chan []DNSRecord, MaxDNSRecordChanSize)
+	datapathManager.deniedPacketChan = make(chan DeniedPacket, MaxDeniedPacketChanSize)
 	datapathManager.proxyReplayFunc = func() {}
 	datapathManager.overlayReplayFunc = func() {}
+	datapathManager.roundNums = make(map[string]uint64)
+	datapathManager.adoptedRounds = make(map[string]uint64)
+	datapathManager.adoptedIPAddrs = make(map[string]net.IP)
+	datapathManager.adoptedIPv6Addrs = make(map[string]net.IP)
+	datapathManager.flowsSynced = make(chan struct{})
 
 	var wg sync.WaitGroup
 	for vdsID, ovsbrname := range datapathConfig.ManagedVDSMap {
@@ -367,6 +435,34 @@ func (datapathManager *DpManager) InitializeDatapath(stopChan <-chan struct{}) {
 		datapathManager.WaitForBridgeConnected()
 	}
 
+	if !datapathManager.Config.AllowSpanningTree {
+		for vdsID, ovsbrName := range datapathManager.Config.ManagedVDSMap {
+			bridges, err := GetSpanningTreeBridges(ovsbrName)
+			if err != nil {
+				log.Fatalf("Failed to check spanning tree of vds %v: %v", vdsID, err)
+			}
+			if len(bridges) != 0 {
+				log.Fatalf("Refuse to manage vds %v, spanning tree enabled on bridges %v", vdsID, bridges)
+			}
+		}
+	}
+
+	if datapathManager.Config.OVNInterop {
+		for vdsID, ovsbrName := range datapathManager.Config.ManagedVDSMap {
+			bridges, err := GetOVNOwnedBridges(ovsbrName)
+			if err != nil {
+				log.Fatalf("Failed to check ovn bridges of vds %v: %v", vdsID, err)
+			}
+			if len(bridges) != 0 {
+				log.Fatalf("Refuse to manage vds %v, bridges %v owned by ovn", vdsID, bridges)
+			}
+		}
+	}
+
+	if err := datapathManager.initCTZones(); err != nil {
+		log.Fatalf("Failed to allocate conntrack zones: %v", err)
+	}
+
 	var wg sync.WaitGroup
 	for vdsID, ovsbrName := range datapathManager.Config.ManagedVDSMap {
 		wg.Add(1)
@@ -466,6 +562,37 @@ func (datapathManager *DpManager) GetRulesByFlowIDs(flowIDs ...uint64) []*v1alph
 	return ans
 }
 
+// GetRuleReferencesByFlowID returns the policy rules the flow installed for, in format of
+// policyNamespace/policyName/policyType/ruleName-flowKey.
+func (datapathManager *DpManager) GetRuleReferencesByFlowID(flowID uint64) []string {
+	datapathManager.flowReplayMutex.RLock()
+	defer datapathManager.flowReplayMutex.RUnlock()
+	if entry, ok := datapathManager.FlowIDToRules[flowID]; ok {
+		return entry.PolicyRuleReference.List()
+	}
+	return nil
+}
+
+func (datapathManager *DpManager) getRuleModeByFlowID(flowID uint64) string {
+	datapathManager.flowReplayMutex.RLock()
+	defer datapathManager.flowReplayMutex.RUnlock()
+	if entry, ok := datapathManager.FlowIDToRules[flowID]; ok {
+		return entry.Mode
+	}
+	return ""
+}
+
+// GetPolicyBridges returns the name of the policy bridges of all vds.
+func (datapathManager *DpManager) GetPolicyBridges() []string {
+	var out []string
+	for vdsID := range datapathManager.Config.ManagedVDSMap {
+		if br, ok := datapathManager.BridgeChainMap[vdsID][POLICY_BRIDGE_KEYWORD]; ok {
+			out = append(out, br.GetName())
+		}
+	}
+	return out
+}
+
 func (datapathManager *DpManager) GetRulesByRuleIDs(ruleIDs ...string) []*v1alpha1.RuleEntry {
 	datapathManager.flowReplayMutex.RLock()
 	defer datapathManager.flowReplayMutex.RUnlock()
@@ -656,6 +783,16 @@ func InitializeVDS(datapathManager *DpManager, vdsID string, ovsbrName string, s
 	if err != nil {
 		log.Fatalf("Failed to get Roundinfo from ovsdb: %v", err)
 	}
+	datapathManager.setRoundNum(vdsID, roundInfo.curRoundNum)
+
+	// The flows of the previous round can't work together with the flows of the current round when the
+	// pipeline changed, delete them before the bridge init instead of handing off.
+	pipelineChanged := datapathManager.isPipelineChanged(vdsID)
+	if pipelineChanged && roundInfo.previousRoundNum != 0 {
+		for brKeyword := range datapathManager.BridgeChainMap[vdsID] {
+			datapathManager.BridgeChainMap[vdsID][brKeyword].getOfSwitch().DeleteFlowByRoundInfo(roundInfo.previousRoundNum)
+		}
+	}
 
 	cookieAllocator := cookie.NewAllocator(roundInfo.curRoundNum)
 	for brKeyword := range datapathManager.BridgeChainMap[vdsID] {
@@ -665,6 +802,9 @@ func InitializeVDS(datapathManager *DpManager, vdsID string, ovsbrName string, s
 		datapathManager.BridgeChainMap[vdsID][brKeyword].getOfSwitch().CookieAllocator = cookieAllocator
 		// bridge init
 		datapathManager.BridgeChainMap[vdsID][brKeyword].BridgeInit()
+		if err := installPipelineVersionFlow(datapathManager.BridgeChainMap[vdsID][brKeyword].getOfSwitch()); err != nil {
+			log.Fatalf("Failed to register pipeline version of bridge %s: %v", brKeyword, err)
+		}
 	}
 
 	if datapathManager.Config.EnableIPLearning {
@@ -688,11 +828,12 @@ func InitializeVDS(datapathManager *DpManager, vdsID string, ovsbrName string, s
 
 	// Delete flow with previousRoundNum cookie, and then persistent curRoundNum to ovsdb. We need to wait for long
 	// enough to guarantee that all of the basic flow which we are still required updated with new roundInfo encoding to
-	// flow cookie fields. But the time required to update all of the basic flow with updated roundInfo is
-	// non-determined.
-	// TODO  Implement a deterministic mechanism to control outdated flow flush procedure
+	// flow cookie fields. When the flows handed off from the previous agent, they are deleted after all of the flows
+	// synced, otherwise the time required to update all of the basic flow with updated roundInfo is non-determined.
 	go func(vdsID string) {
-		time.Sleep(time.Second * 15)
+		if !pipelineChanged {
+			datapathManager.waitPreviousRoundFlowsExpired(vdsID, roundInfo.previousRoundNum)
+		}
 
 		for brKeyword := range datapathManager.BridgeChainMap[vdsID] {
 			datapathManager.BridgeChainMap[vdsID][brKeyword].getOfSwitch().DeleteFlowByRoundInfo(roundInfo.previousRoundNum)
@@ -723,6 +864,9 @@ func (datapathManager *DpManager) replayVDSFlow(vdsID, vdsName, bridgeKeyword st
 	datapathManager.BridgeChainMap[vdsID][bridgeKeyword].getOfSwitch().CookieAllocator = cookieAllocator
 	datapathManager.BridgeChainMap[vdsID][bridgeKeyword].BridgeInit()
 	datapathManager.BridgeChainMap[vdsID][bridgeKeyword].BridgeInitCNI()
+	if err := installPipelineVersionFlow(datapathManager.BridgeChainMap[vdsID][bridgeKeyword].getOfSwitch()); err != nil {
+		return fmt.Errorf("failed to register pipeline version: %v", err)
+	}
 
 	// replay local endpoint flow
 	if bridgeKeyword == LOCAL_BRIDGE_KEYWORD || bridgeKeyword == NAT_BRIDGE_KEYWORD ||
@@ -783,6 +927,9 @@ func (datapathManager *DpManager) ReplayVDSLocalEndpointFlow(vdsID string, keyWo
 
 func (datapathManager *DpManager) ReplayVDSMicroSegmentFlow(vdsID string) error {
 	for ruleID, erPolicyRuleEntry := range datapathManager.Rules {
+		if !datapathManager.ruleInVDS(erPolicyRuleEntry.EveroutePolicyRule, vdsID) {
+			continue
+		}
 		// Add new policy rule flow to datapath
 		flowEntry, err := datapathManager.BridgeChainMap[vdsID][POLICY_BRIDGE_KEYWORD].AddMicroSegmentRule(erPolicyRuleEntry.EveroutePolicyRule,
 			erPolicyRuleEntry.Direction, erPolicyRuleEntry.Tier, erPolicyRuleEntry.Mode)
@@ -871,6 +1018,7 @@ func (datapathManager *DpManager) AddLocalEndpoint(endpoint *Endpoint) error {
 			// ovsdb interface table.
 			// if it's failed to add endpoint flow, replayVDSFlow routine would rebuild local endpoint flow according to
 			// current localEndpointDB
+			datapathManager.restoreLearnedIPAddr(endpoint)
 			datapathManager.localEndpointDB.Set(endpoint.InterfaceUUID, endpoint)
 			for kword := range datapathManager.BridgeChainMap[vdsID] {
 				br := datapathManager.BridgeChainMap[vdsID][kword]
@@ -897,7 +1045,7 @@ func (datapathManager *DpManager) UpdateLocalEndpoint(newEndpoint, oldEndpoint *
 		if ovsbrname == newEndpoint.BridgeName {
 			oldEP, _ := datapathManager.localEndpointDB.Get(oldEndpoint.InterfaceUUID)
 			if oldEP == nil {
-				return fmt.Errorf("old local endpoint: %v not found", oldEP)
+				return ererrors.NewNotFound("old local endpoint: %v not found", oldEP)
 			}
 			ep := oldEP.(*Endpoint)
 			if datapathManager.Config.EnableIPLearning {
@@ -905,9 +1053,9 @@ func (datapathManager *DpManager) UpdateLocalEndpoint(newEndpoint, oldEndpoint *
 				newEndpoint.IPAddr = utils.IPCopy(ep.IPAddr)
 			}
 
-			// assume that ofport does not update, so doesn't need to remove old flow for local bridge overlay
+			// the flows of the local bridge overlay only need removing when the ofport reassigned
 			datapathManager.localEndpointDB.Remove(oldEndpoint.InterfaceUUID)
-			if !datapathManager.IsEnableOverlay() {
+			if !datapathManager.IsEnableOverlay() || oldEndpoint.PortNo != newEndpoint.PortNo {
 				err = datapathManager.BridgeChainMap[vdsID][LOCAL_BRIDGE_KEYWORD].RemoveLocalEndpoint(oldEndpoint)
 				if err != nil {
 					return fmt.Errorf("failed to remove old local endpoint %v from vds %v, bridge %v, error: %v", oldEndpoint.InterfaceUUID, vdsID, ovsbrname, err)
@@ -918,7 +1066,7 @@ func (datapathManager *DpManager) UpdateLocalEndpoint(newEndpoint, oldEndpoint *
 				break
 			}
 			if newEP, _ := datapathManager.localEndpointDB.Get(newEndpoint.InterfaceUUID); newEP != nil {
-				return fmt.Errorf("new local endpoint: %v already exits", newEP)
+				return ererrors.NewConflict("new local endpoint: %v already exits", newEP)
 			}
 			datapathManager.localEndpointDB.Set(newEndpoint.InterfaceUUID, newEndpoint)
 			for kword := range datapathManager.BridgeChainMap[vdsID] {
@@ -944,7 +1092,7 @@ func (datapathManager *DpManager) RemoveLocalEndpoint(endpoint *Endpoint) error
 	}
 	ep, _ := datapathManager.localEndpointDB.Get(endpoint.InterfaceUUID)
 	if ep == nil {
-		return fmt.Errorf("Endpoint with interface name: %v, ofport: %v wasnot found", endpoint.InterfaceName, endpoint.PortNo)
+		return ererrors.NewNotFound("Endpoint with interface name: %v, ofport: %v wasnot found", endpoint.InterfaceName, endpoint.PortNo)
 	}
 	cachedEP := ep.(*Endpoint)
 
@@ -990,6 +1138,9 @@ func (datapathManager *DpManager) AddEveroutePolicyRule(rule *EveroutePolicyRule
 	ruleFlowMap := make(map[string]*FlowEntry)
 	// Install policy rule flow to datapath
 	for vdsID, bridgeChain := range datapathManager.BridgeChainMap {
+		if !datapathManager.ruleInVDS(rule, vdsID) {
+			continue
+		}
 		flowEntry, err := bridgeChain[POLICY_BRIDGE_KEYWORD].AddMicroSegmentRule(rule, direction, tier, mode)
 		if err != nil {
 			log.Errorf("Failed to add microsegment rule to vdsID %v, bridge %s, error: %v", vdsID, bridgeChain[POLICY_BRIDGE_KEYWORD], err)
@@ -1045,14 +1196,14 @@ func (datapathManager *DpManager) RemoveEveroutePolicyRule(ruleID string, ruleNa
 		}
 	}
 
-	for vdsID := range datapathManager.BridgeChainMap {
-		err := ofctrl.DeleteFlow(pRule.RuleFlowMap[vdsID].Table, pRule.RuleFlowMap[vdsID].Priority, pRule.RuleFlowMap[vdsID].FlowID)
+	for _, flowEntry := range pRule.RuleFlowMap {
+		err := ofctrl.DeleteFlow(flowEntry.Table, flowEntry.Priority, flowEntry.FlowID)
 		if err != nil {
 			log.Errorf("Failed to delete flow for rule: %+v. Err: %v", ruleID, err)
 			return err
 		}
 		// remove flowID reference
-		delete(datapathManager.FlowIDToRules, pRule.RuleFlowMap[vdsID].FlowID)
+		delete(datapathManager.FlowIDToRules, flowEntry.FlowID)
 	}
 
 	datapathManager.cleanConntrackFlow(datapathManager.Rules[ruleID].EveroutePolicyRule)
@@ -1170,7 +1321,7 @@ func (datapathManager *DpManager) cleanConntrackWorker() {
 		if ruleList == nil {
 			return
 		}
-		matches, err := netlink.ConntrackDeleteFilter(netlink.ConntrackTable, unix.AF_INET, ruleList)
+		matches, err := netlink.ConntrackDeleteFilter(netlink.ConntrackTable, unix.AF_INET, datapathManager.conntrackFilter(ruleList))
 		if err != nil {
 			klog.Errorf("clear conntrack error, rules: %+v, err: %s", ruleList, err)
 			continue
@@ -1339,6 +1490,9 @@ func ParseIPAddrMaskString(ipAddr string) (*net.IP, *net.IP, error) {
 		}
 
 		ipMask := net.ParseIP(IP_BROADCAST_ADDR).Mask(ipNet.Mask)
+		if ipDav.To4() == nil {
+			ipMask = net.IP(ipNet.Mask)
+		}
 
 		return &ipDav, &ipMask, nil
 	}
@@ -1349,6 +1503,9 @@ func ParseIPAddrMaskString(ipAddr string) (*net.IP, *net.IP, error) {
 	}
 
 	ipMask := net.ParseIP(IP_BROADCAST_ADDR)
+	if ipDa.To4() == nil {
+		ipMask = net.IP(net.CIDRMask(8*net.IPv6len, 8*net.IPv6len))
+	}
 
 	return &ipDa, &ipMask, nil
 }
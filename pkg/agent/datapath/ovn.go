@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// OVNRemoteKey in the external_ids of the Open_vSwitch table is the southbound db of
+	// ovn-controller, the host runs OVN if set.
+	OVNRemoteKey = "ovn-remote"
+	// OVNBridgeKey in the external_ids of the Open_vSwitch table is the OVN integration
+	// bridge, OVNDefaultBridge if not set.
+	OVNBridgeKey     = "ovn-bridge"
+	OVNDefaultBridge = "br-int"
+	// OVNBridgeMappingsKey in the external_ids of the Open_vSwitch table maps the physical
+	// networks to the provider bridges, e.g. physnet1:br-ex,physnet2:br-vlan.
+	OVNBridgeMappingsKey = "ovn-bridge-mappings"
+
+	// OVNIfaceIDKey in the external_ids of the interface is the OVN logical port bound to it.
+	OVNIfaceIDKey = "iface-id"
+	// OVNInstalledKey in the external_ids of the interface is set by ovn-controller after
+	// the flows of the logical port installed.
+	OVNInstalledKey = "ovn-installed"
+)
+
+// ParseOVNBridges returns the bridges owned by OVN from the external_ids of the Open_vSwitch
+// table, the integration bridge and the provider bridges, none if OVN not configured.
+func ParseOVNBridges(externalIDs map[string]string) []string {
+	if externalIDs[OVNRemoteKey] == "" {
+		return nil
+	}
+
+	bridges := []string{OVNDefaultBridge}
+	if bridge := externalIDs[OVNBridgeKey]; bridge != "" {
+		bridges[0] = bridge
+	}
+	for _, mapping := range strings.Split(externalIDs[OVNBridgeMappingsKey], ",") {
+		index := strings.Index(mapping, ":")
+		if index < 0 {
+			continue
+		}
+		if bridge := strings.TrimSpace(mapping[index+1:]); bridge != "" {
+			bridges = append(bridges, bridge)
+		}
+	}
+	return bridges
+}
+
+// IsOVNInterface returns true if the interface is a logical port bound by ovn-controller,
+// the interface on the OVN integration bridge with iface-id, or marked ovn-installed.
+func IsOVNInterface(bridgeName string, externalIDs map[string]string, ovnBridges []string) bool {
+	if _, ok := externalIDs[OVNInstalledKey]; ok {
+		return true
+	}
+	_, ok := externalIDs[OVNIfaceIDKey]
+	return ok && len(ovnBridges) != 0 && bridgeName == ovnBridges[0]
+}
+
+// GetOVNBridges return the bridges owned by OVN on the host.
+func GetOVNBridges() ([]string, error) {
+	externalIDs := make(map[string]string)
+	for _, key := range []string{OVNRemoteKey, OVNBridgeKey, OVNBridgeMappingsKey} {
+		out, err := exec.Command("ovs-vsctl", "--if-exists", "get", "Open_vSwitch", ".", "external_ids:"+key).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ovn config %s: %s, error: %v", key, strings.TrimSpace(string(out)), err)
+		}
+		externalIDs[key] = strings.Trim(strings.TrimSpace(string(out)), `"`)
+	}
+	return ParseOVNBridges(externalIDs), nil
+}
+
+// GetOVNOwnedBridges return the bridges of the vds owned by OVN.
+func GetOVNOwnedBridges(ovsbrName string) ([]string, error) {
+	ovnBridges, err := GetOVNBridges()
+	if err != nil {
+		return nil, err
+	}
+
+	var bridges []string
+	for _, brName := range getVDSBridgeNames(ovsbrName) {
+		for _, ovnBridge := range ovnBridges {
+			if brName == ovnBridge {
+				bridges = append(bridges, brName)
+			}
+		}
+	}
+	return bridges, nil
+}
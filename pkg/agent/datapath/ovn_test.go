@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOVNBridges(t *testing.T) {
+	testCases := []struct {
+		externalIDs map[string]string
+		bridges     []string
+	}{
+		{
+			externalIDs: map[string]string{OVNBridgeKey: "br-ovn"},
+			bridges:     nil,
+		},
+		{
+			externalIDs: map[string]string{OVNRemoteKey: "tcp:10.0.0.1:6642"},
+			bridges:     []string{OVNDefaultBridge},
+		},
+		{
+			externalIDs: map[string]string{
+				OVNRemoteKey:         "tcp:10.0.0.1:6642",
+				OVNBridgeKey:         "br-ovn",
+				OVNBridgeMappingsKey: "physnet1:br-ex, physnet2:br-vlan,invalid",
+			},
+			bridges: []string{"br-ovn", "br-ex", "br-vlan"},
+		},
+	}
+
+	for _, tc := range testCases {
+		if bridges := ParseOVNBridges(tc.externalIDs); !reflect.DeepEqual(bridges, tc.bridges) {
+			t.Errorf("parse %v expect bridges %v, actual %v", tc.externalIDs, tc.bridges, bridges)
+		}
+	}
+}
+
+func TestIsOVNInterface(t *testing.T) {
+	ovnBridges := []string{"br-int", "br-ex"}
+	testCases := []struct {
+		bridgeName  string
+		externalIDs map[string]string
+		isOVN       bool
+	}{
+		{bridgeName: "br-int", externalIDs: map[string]string{OVNIfaceIDKey: "lsp1"}, isOVN: true},
+		{bridgeName: "br-ex", externalIDs: map[string]string{OVNIfaceIDKey: "lsp1"}, isOVN: false},
+		{bridgeName: "ovsbr0", externalIDs: map[string]string{OVNIfaceIDKey: "lsp1", OVNInstalledKey: "true"}, isOVN: true},
+		{bridgeName: "ovsbr0", externalIDs: map[string]string{OVNIfaceIDKey: "lsp1"}, isOVN: false},
+		{bridgeName: "br-int", externalIDs: nil, isOVN: false},
+	}
+
+	for _, tc := range testCases {
+		if isOVN := IsOVNInterface(tc.bridgeName, tc.externalIDs, ovnBridges); isOVN != tc.isOVN {
+			t.Errorf("interface on %s with %v expect ovn %v, actual %v", tc.bridgeName, tc.externalIDs, tc.isOVN, isOVN)
+		}
+	}
+	if IsOVNInterface("br-int", map[string]string{OVNIfaceIDKey: "lsp1"}, nil) {
+		t.Errorf("interface with iface-id expect not ovn without ovn configured")
+	}
+}
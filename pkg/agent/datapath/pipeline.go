@@ -0,0 +1,273 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/contiv/ofnet/ofctrl"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// PipelineVersion is the version of the table layout installed by this binary. It must be
+	// increased when the tables in PipelineLayout are added, removed or renumbered, when a
+	// flow is moved between the tables, or when the priorities of the flows are changed.
+	PipelineVersion uint64 = 3
+	// PipelineVersionTable is reserved for the pipeline version register flow, no packet
+	// is sent to the table.
+	PipelineVersionTable uint8 = 253
+
+	// legacyPipelineVersion is the version of the bridges without the register flow, they are
+	// installed by the agent before the pipeline versioned.
+	legacyPipelineVersion uint64 = 1
+)
+
+// PipelineTable is a table in the pipeline of a bridge.
+type PipelineTable struct {
+	ID          uint8
+	Name        string
+	Description string
+}
+
+// BridgePipeline is the table layout of a bridge in the bridge chain.
+type BridgePipeline struct {
+	// Bridge is the keyword of the bridge, e.g. policy.
+	Bridge string
+	// Mode is the mode the layout used in, vlan or overlay, empty for all the modes.
+	Mode   string
+	Tables []PipelineTable
+}
+
+// PipelineLayout is the table layout of the bridges, the tables are in the order of the id.
+var PipelineLayout = []BridgePipeline{
+	{
+		Bridge: LOCAL_BRIDGE_KEYWORD,
+		Mode:   "vlan",
+		Tables: []PipelineTable{
+			{VLAN_INPUT_TABLE, "VLAN_INPUT_TABLE", "Forwards the packets from the policy bridge to L2_FORWARDING_TABLE, resubmits others to L2_LEARNING_TABLE, FROM_LOCAL_DNS_QUERY_TABLE and FROM_LOCAL_REDIRECT_TABLE."},
+			{VLAN_FILTER_TABLE, "VLAN_FILTER_TABLE", "Filters the vlan of the trunk ports, drops the packets by default."},
+			{L2_FORWARDING_TABLE, "L2_FORWARDING_TABLE", "The mac addresses learned by L2_LEARNING_TABLE, the normal action by default."},
+			{L2_LEARNING_TABLE, "L2_LEARNING_TABLE", "Learns the source mac address of the packets into L2_FORWARDING_TABLE."},
+			{FROM_LOCAL_REDIRECT_TABLE, "FROM_LOCAL_REDIRECT_TABLE", "Redirects the packets from the local endpoints to the policy bridge, sends the arp, nd and dhcp packets to the controller."},
+			{FROM_LOCAL_ARP_PASS_TABLE, "FROM_LOCAL_ARP_PASS_TABLE", "Outputs the arp packets from the local endpoints to the policy bridge."},
+			{FROM_LOCAL_ARP_TO_CONTROLLER_TABLE, "FROM_LOCAL_ARP_TO_CONTROLLER_TABLE", "Sends the arp packets to the controller for the ip learning."},
+			{FROM_LOCAL_DNS_QUERY_TABLE, "FROM_LOCAL_DNS_QUERY_TABLE", "Sends the udp dns queries to the controller for the dns snooping."},
+			{CNI_CT_COMMIT_TABLE, "CNI_CT_COMMIT_TABLE", "Commits the connections of the cni traffic."},
+			{CNI_CT_REDIRECT_TABLE, "CNI_CT_REDIRECT_TABLE", "Redirects the reply of the cni connections to the gateway."},
+		},
+	},
+	{
+		Bridge: LOCAL_BRIDGE_KEYWORD,
+		Mode:   "overlay",
+		Tables: []PipelineTable{
+			{0, "LBOInputTable", "Dispatches the arp packets to LBOArpProxyTable, others to LBOInPortTable."},
+			{LBOArpProxyTable, "LBOArpProxyTable", "Replies the arp requests of the endpoints in the cluster."},
+			{LBOInPortTable, "LBOInPortTable", "Dispatches the packets by the input port."},
+			{LBOFromNatTable, "LBOFromNatTable", "Handles the packets from the nat bridge."},
+			{LBOFromPolicyTable, "LBOFromPolicyTable", "Handles the packets from the policy bridge."},
+			{LBOFromLocalTable, "LBOFromLocalTable", "Handles the packets from the local endpoints."},
+			{LBOForwardToLocalTable, "LBOForwardToLocalTable", "Sets the output port of the packets to the local endpoints."},
+			{LBOPaddingL2Table, "LBOPaddingL2Table", "Rewrites the mac addresses of the routed packets."},
+			{LBOOutputTable, "LBOOutputTable", "Outputs the packets to the port in the output port register."},
+		},
+	},
+	{
+		Bridge: POLICY_BRIDGE_KEYWORD,
+		Tables: []PipelineTable{
+			{INPUT_TABLE, "INPUT_TABLE", "Sends the ip packets to the conntrack."},
+			{CT_STATE_TABLE, "CT_STATE_TABLE", "Drops the invalid connections, forwards the established connections to SFC_POLICY_TABLE."},
+			{DIRECTION_SELECTION_TABLE, "DIRECTION_SELECTION_TABLE", "Selects the egress or ingress tiers by the input port."},
+			{EGRESS_TIER1_TABLE, "EGRESS_TIER1_TABLE", "The egress rules of tier1."},
+			{EGRESS_TIER2_MONITOR_TABLE, "EGRESS_TIER2_MONITOR_TABLE", "The egress rules of tier2 in monitor mode."},
+			{EGRESS_TIER2_TABLE, "EGRESS_TIER2_TABLE", "The egress rules of tier2."},
+			{EGRESS_TIER_ECP_TABLE, "EGRESS_TIER_ECP_TABLE", "The egress rules of tier ecp."},
+			{EGRESS_TIER3_MONITOR_TABLE, "EGRESS_TIER3_MONITOR_TABLE", "The egress rules of tier3 in monitor mode."},
+			{EGRESS_TIER3_TABLE, "EGRESS_TIER3_TABLE", "The egress rules of tier3."},
+			{INGRESS_TIER1_TABLE, "INGRESS_TIER1_TABLE", "The ingress rules of tier1."},
+			{INGRESS_TIER2_MONITOR_TABLE, "INGRESS_TIER2_MONITOR_TABLE", "The ingress rules of tier2 in monitor mode."},
+			{INGRESS_TIER2_TABLE, "INGRESS_TIER2_TABLE", "The ingress rules of tier2."},
+			{INGRESS_TIER_ECP_TABLE, "INGRESS_TIER_ECP_TABLE", "The ingress rules of tier ecp."},
+			{INGRESS_TIER3_MONITOR_TABLE, "INGRESS_TIER3_MONITOR_TABLE", "The ingress rules of tier3 in monitor mode."},
+			{INGRESS_TIER3_TABLE, "INGRESS_TIER3_TABLE", "The ingress rules of tier3."},
+			{CT_COMMIT_TABLE, "CT_COMMIT_TABLE", "Commits the connections allowed by the rules."},
+			{CT_DROP_TABLE, "CT_DROP_TABLE", "Drops the connections denied by the rules."},
+			{SFC_POLICY_TABLE, "SFC_POLICY_TABLE", "The service function chain rules."},
+			{POLICY_FORWARDING_TABLE, "POLICY_FORWARDING_TABLE", "Forwards the packets to the local or cls bridge."},
+		},
+	},
+	{
+		Bridge: CLS_BRIDGE_KEYWORD,
+		Mode:   "vlan",
+		Tables: []PipelineTable{
+			{CLSBRIDGE_LEARNING_TABLE_ID, "CLSBRIDGE_LEARNING_TABLE_ID", "Learns the source mac address of the packets from the uplink."},
+			{CLSBRIDGE_FORWARDING_TABLE_ID, "CLSBRIDGE_FORWARDING_TABLE_ID", "The mac addresses learned, floods the unknown unicast packets."},
+			{CLSBRIDGE_OUTPUT_TABLE_ID, "CLSBRIDGE_OUTPUT_TABLE_ID", "Outputs the packets to the policy or uplink bridge."},
+		},
+	},
+	{
+		Bridge: CLS_BRIDGE_KEYWORD,
+		Mode:   "overlay",
+		Tables: []PipelineTable{
+			{0, "DefaultTable", "Forwards the packets between the policy and the uplink bridge."},
+		},
+	},
+	{
+		Bridge: UPLINK_BRIDGE_KEYWORD,
+		Mode:   "vlan",
+		Tables: []PipelineTable{
+			{0, "DefaultTable", "The normal action."},
+		},
+	},
+	{
+		Bridge: UPLINK_BRIDGE_KEYWORD,
+		Mode:   "overlay",
+		Tables: []PipelineTable{
+			{0, "UBOInputTable", "Dispatches the arp packets to UBOArpProxyTable, others by the input port."},
+			{UBOArpProxyTable, "UBOArpProxyTable", "Replies the arp requests of the endpoints in the cluster."},
+			{UBOForwardToLocalTable, "UBOForwardToLocalTable", "Forwards the packets to the local endpoints."},
+			{UBOForwardToTunnelTable, "UBOForwardToTunnelTable", "Forwards the packets to the remote endpoints through the tunnel."},
+			{UBOForwardToGwTable, "UBOForwardToGwTable", "Forwards the packets to the gateway."},
+			{UBOSetRemoteIPTable, "UBOSetRemoteIPTable", "Sets the tunnel destination to the node of the remote endpoint."},
+			{UBOSetTunnelOutPortTable, "UBOSetTunnelOutPortTable", "Sets the output port to the tunnel port."},
+			{UBOPaddingL2Table, "UBOPaddingL2Table", "Rewrites the mac addresses of the routed packets."},
+			{UBOOutputTable, "UBOOutputTable", "Outputs the packets to the port in the output port register."},
+		},
+	},
+	{
+		Bridge: NAT_BRIDGE_KEYWORD,
+		Tables: []PipelineTable{
+			{NatBrInputTable, "NatBrInputTable", "Sends the ip packets to NatBrInPortTable."},
+			{NatBrInPortTable, "NatBrInPortTable", "Dispatches the packets by the input port."},
+			{NatBrCTZoneTable, "NatBrCTZoneTable", "Sets the conntrack zone of the packets."},
+			{NatBrCTStateTable, "NatBrCTStateTable", "Sends the new connections to the service load balance, the established to the conntrack nat."},
+			{NatBrSessionAffinityTable, "NatBrSessionAffinityTable", "Selects the backend learned for the services with session affinity."},
+			{NatBrServiceLBTable, "NatBrServiceLBTable", "Selects the backend of the services by the group."},
+			{NatBrSessionAffinityLearnTable, "NatBrSessionAffinityLearnTable", "Learns the backend selected into NatBrSessionAffinityTable."},
+			{NatBrDnatTable, "NatBrDnatTable", "Commits the connections with the destination nat to the backend."},
+			{NatBrL3ForwardTable, "NatBrL3ForwardTable", "Routes the packets, resubmits others to NatBrOutputTable."},
+			{NatBrOutputTable, "NatBrOutputTable", "Outputs the packets to the input port."},
+		},
+	},
+}
+
+// WritePipelineMarkdown writes the documentation of the pipeline layout in markdown.
+func WritePipelineMarkdown(w io.Writer) error {
+	lines := []string{
+		"---",
+		`title: "Pipeline"`,
+		`linkTitle: "Pipeline"`,
+		"---",
+		"",
+		"<!-- Code generated by hack/pipeline-docs-gen. DO NOT EDIT. -->",
+		"",
+		fmt.Sprintf("The version of the pipeline is `%d`. It is registered in the reserved table `%d` of each bridge,", PipelineVersion, PipelineVersionTable),
+		"the everoute agent deletes the flows installed by the previous agent before installing its own flows",
+		"if the version registered differs.",
+	}
+	for _, pipeline := range PipelineLayout {
+		title := pipeline.Bridge
+		if pipeline.Mode != "" {
+			title = fmt.Sprintf("%s (%s)", pipeline.Bridge, pipeline.Mode)
+		}
+		lines = append(lines,
+			"",
+			"## "+title,
+			"",
+			"| ID | Name | Description |",
+			"| -- | ---- | ----------- |",
+		)
+		for _, table := range pipeline.Tables {
+			lines = append(lines, fmt.Sprintf("| %d | `%s` | %s |", table.ID, table.Name, table.Description))
+		}
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// installPipelineVersionFlow registers PipelineVersion in the bridge, the version is matched
+// as the metadata of the flow.
+func installPipelineVersionFlow(sw *ofctrl.OFSwitch) error {
+	table := sw.GetTable(PipelineVersionTable)
+	if table == nil {
+		var err error
+		if table, err = sw.NewTable(PipelineVersionTable); err != nil {
+			return fmt.Errorf("failed to new pipeline version table, error: %v", err)
+		}
+	}
+
+	version := PipelineVersion
+	flow, _ := table.NewFlow(ofctrl.FlowMatch{
+		Priority: NORMAL_MATCH_FLOW_PRIORITY,
+		Metadata: &version,
+	})
+	if err := flow.Next(sw.DropAction()); err != nil {
+		return fmt.Errorf("failed to install pipeline version flow, error: %v", err)
+	}
+	return nil
+}
+
+// isPipelineChanged returns true if the pipeline version registered in any bridge of the vds
+// differs from PipelineVersion, the version unknown is treated as changed.
+func (datapathManager *DpManager) isPipelineChanged(vdsID string) bool {
+	for brKeyword, bridge := range datapathManager.BridgeChainMap[vdsID] {
+		version, err := getInstalledPipelineVersion(bridge.GetName())
+		if err != nil {
+			log.Warnf("Unable to get pipeline version of bridge %s, migrate to version %d: %v", bridge.GetName(), PipelineVersion, err)
+			return true
+		}
+		if version != PipelineVersion {
+			log.Warnf("Pipeline version %d of %s bridge %s mismatch, migrate to version %d", version, brKeyword, bridge.GetName(), PipelineVersion)
+			return true
+		}
+	}
+	return false
+}
+
+// getInstalledPipelineVersion returns the pipeline version registered in the bridge.
+func getInstalledPipelineVersion(bridge string) (uint64, error) {
+	cmd := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "dump-flows", bridge, fmt.Sprintf("table=%d", PipelineVersionTable))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("fail to dump flows of bridge %s: %v, stderr: %s", bridge, err, stderr.String())
+	}
+	return parsePipelineVersion(stdout.String())
+}
+
+var pipelineVersionRegexp = regexp.MustCompile(`metadata=0x([0-9a-f]+)`)
+
+// parsePipelineVersion parses the version from the flows dumped from PipelineVersionTable.
+func parsePipelineVersion(flows string) (uint64, error) {
+	matches := pipelineVersionRegexp.FindAllStringSubmatch(flows, -1)
+	switch len(matches) {
+	case 0:
+		return legacyPipelineVersion, nil
+	case 1:
+		return strconv.ParseUint(matches[0][1], 16, 64)
+	default:
+		return 0, fmt.Errorf("found %d pipeline version flows", len(matches))
+	}
+}
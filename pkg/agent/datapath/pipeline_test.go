@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPipelineLayout(t *testing.T) {
+	for _, pipeline := range PipelineLayout {
+		for i, table := range pipeline.Tables {
+			if table.ID == PipelineVersionTable {
+				t.Errorf("table %s of bridge %s uses the reserved table %d", table.Name, pipeline.Bridge, PipelineVersionTable)
+			}
+			if i > 0 && table.ID <= pipeline.Tables[i-1].ID {
+				t.Errorf("table %s of bridge %s %s not in the order of the id", table.Name, pipeline.Bridge, pipeline.Mode)
+			}
+		}
+	}
+}
+
+func TestParsePipelineVersion(t *testing.T) {
+	testCases := []struct {
+		name    string
+		flows   string
+		version uint64
+		expErr  bool
+	}{
+		{
+			name:    "no version flow",
+			flows:   "",
+			version: legacyPipelineVersion,
+		},
+		{
+			name:    "version flow",
+			flows:   " cookie=0x10000000, duration=3.2s, table=253, n_packets=0, n_bytes=0, priority=100,metadata=0x1a actions=drop\n",
+			version: 26,
+		},
+		{
+			name: "multiple version flows",
+			flows: " cookie=0x10000000, table=253, priority=100,metadata=0x1 actions=drop\n" +
+				" cookie=0x20000000, table=253, priority=100,metadata=0x2 actions=drop\n",
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, err := parsePipelineVersion(tc.flows)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("expect error %t, got %v", tc.expErr, err)
+			}
+			if !tc.expErr && version != tc.version {
+				t.Fatalf("expect version %d, got %d", tc.version, version)
+			}
+		})
+	}
+}
+
+func TestWritePipelineMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePipelineMarkdown(&buf); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	for _, expect := range []string{
+		"## policy\n",
+		"## nat\n",
+		"## uplink (overlay)\n",
+		"| 90 | `POLICY_FORWARDING_TABLE` | Forwards the packets to the local or cls bridge. |\n",
+	} {
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("expect %q in the markdown", expect)
+		}
+	}
+}
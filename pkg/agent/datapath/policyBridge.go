@@ -60,6 +60,9 @@ type PolicyBridge struct {
 	ctDropTable                    *ofctrl.Table
 	sfcPolicyTable                 *ofctrl.Table
 	policyForwardingTable          *ofctrl.Table
+
+	// ctZone is the conntrack zone allocated for the vds, CTZoneForPolicy if not allocated
+	ctZone uint16
 }
 
 func NewPolicyBridge(brName string, datapathManager *DpManager) *PolicyBridge {
@@ -69,7 +72,17 @@ func NewPolicyBridge(brName string, datapathManager *DpManager) *PolicyBridge {
 	return policyBridge
 }
 
+func (p *PolicyBridge) conntrackZone() uint16 {
+	if p.ctZone == 0 {
+		return CTZoneForPolicy
+	}
+	return p.ctZone
+}
+
 func (p *PolicyBridge) PacketRcvd(sw *ofctrl.OFSwitch, pkt *ofctrl.PacketIn) {
+	if p.datapathManager.Config.EnableDenyLogging && pkt.Data.Ethertype == PROTOCOL_IP {
+		p.processDeniedPacket(pkt)
+	}
 }
 
 func (p *PolicyBridge) MultipartReply(sw *ofctrl.OFSwitch, rep *openflow13.MultipartReply) {
@@ -140,9 +153,11 @@ func (p *PolicyBridge) initDirectionSelectionTable() error {
 
 func (p *PolicyBridge) initInputTable(sw *ofctrl.OFSwitch) error {
 	var ctStateTableID uint8 = CT_STATE_TABLE
-	var policyConntrackZone = CTZoneForPolicy
+	var policyConntrackZone = p.conntrackZone()
 	localBrName := strings.TrimSuffix(p.name, "-policy")
-	ctAction := ofctrl.NewConntrackAction(false, false, &ctStateTableID, &policyConntrackZone)
+	// nat restore the redirected connections, it takes no effect on others
+	natAction, _ := ofctrl.NewNatAction().ToOfAction()
+	ctAction := ofctrl.NewConntrackAction(false, false, &ctStateTableID, &policyConntrackZone, natAction)
 	inputIPRedirectFlow, _ := p.inputTable.NewFlow(ofctrl.FlowMatch{
 		Priority:  HIGH_MATCH_FLOW_PRIORITY,
 		Ethertype: PROTOCOL_IP,
@@ -184,7 +199,7 @@ func (p *PolicyBridge) initInputTable(sw *ofctrl.OFSwitch) error {
 }
 
 func (p *PolicyBridge) initCTFlow(sw *ofctrl.OFSwitch) error {
-	var policyConntrackZone = CTZoneForPolicy
+	var policyConntrackZone = p.conntrackZone()
 	// Table 1, ctState table, est state flow
 	// FIXME. should add ctEst flow and ctInv flow with same priority. With different, it have no side effect to flow intent.
 	ctEstState := openflow13.NewCTStates()
@@ -468,7 +483,7 @@ func (p *PolicyBridge) initALGFlow(sw *ofctrl.OFSwitch) error {
 	ctTrkState := openflow13.NewCTStates()
 	ctTrkState.SetNew()
 	ctTrkState.SetTrk()
-	var policyConntrackZone = CTZoneForPolicy
+	var policyConntrackZone = p.conntrackZone()
 	var ctDropTable uint8 = CT_DROP_TABLE
 	srcField, _ := openflow13.FindFieldHeaderByName("nxm_nx_xxreg0", false)
 	dstField, _ := openflow13.FindFieldHeaderByName("nxm_nx_ct_label", false)
@@ -639,8 +654,7 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 		}
 	}
 
-	// Install the rule in policy table
-	ruleFlow, err := policyTable.NewFlow(ofctrl.FlowMatch{
+	ruleMatch := ofctrl.FlowMatch{
 		Priority:       uint16(rule.Priority),
 		Ethertype:      PROTOCOL_IP,
 		IpDa:           ipDa,
@@ -656,7 +670,17 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 		UdpSrcPortMask: rule.SrcPortMask,
 		UdpDstPort:     rule.DstPort,
 		UdpDstPortMask: rule.DstPortMask,
-	})
+		RawMatchField:  icmpMatchFields(rule),
+	}
+	// icmpv6 only carried by ipv6, match the ip addresses as ipv6
+	if rule.IPProtocol == PROTOCOL_ICMPV6 {
+		ruleMatch.Ethertype = PROTOCOL_IPV6
+		ruleMatch.Ipv6Da, ruleMatch.Ipv6DaMask, ruleMatch.IpDa, ruleMatch.IpDaMask = ipDa, ipDaMask, nil, nil
+		ruleMatch.Ipv6Sa, ruleMatch.Ipv6SaMask, ruleMatch.IpSa, ruleMatch.IpSaMask = ipSa, ipSaMask, nil, nil
+	}
+
+	// Install the rule in policy table
+	ruleFlow, err := policyTable.NewFlow(ruleMatch)
 	if err != nil {
 		log.Errorf("Failed to add flow for rule {%v}. Err: %v", rule, err)
 		return nil, err
@@ -674,11 +698,18 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 		if err := ruleFlow.LoadField("nxm_nx_xxreg0", ruleFlow.FlowID&FLOW_SEQ_NUM_MASK, openflow13.NewNXRange(32, 59)); err != nil {
 			return nil, err
 		}
+		// the packets are not dropped in monitor mode, log the packets the rule would deny
+		if rule.Action == "deny" && p.datapathManager.Config.EnableDenyLogging {
+			if err := p.puntDeniedPacket(ruleFlow); err != nil {
+				return nil, err
+			}
+		}
 
 		if err := ruleFlow.Next(nextTable); err != nil {
 			return nil, err
 		}
 	case "work":
+		var next ofctrl.FgraphElem = nextTable
 		switch rule.Action {
 		case "allow":
 			if rule.Priority == GLOBAL_DEFAULT_POLICY_FLOW_PRIORITY {
@@ -693,6 +724,18 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 			if err := ruleFlow.LoadField("nxm_nx_xxreg0", 0x1, openflow13.NewNXRange(127, 127)); err != nil {
 				return nil, err
 			}
+			if p.datapathManager.Config.EnableDenyLogging {
+				if err := p.puntDeniedPacket(ruleFlow); err != nil {
+					return nil, err
+				}
+			}
+		case "redirect":
+			// commit the connection with dnat here, the original destination is kept
+			// as the origin tuple of the conntrack entry
+			if err := p.setRedirectConntrack(ruleFlow, rule); err != nil {
+				return nil, err
+			}
+			next = ofctrl.NewEmptyElem()
 		default:
 			return nil, fmt.Errorf("unknown action")
 		}
@@ -704,7 +747,7 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 			return nil, err
 		}
 
-		if err := ruleFlow.Next(nextTable); err != nil {
+		if err := ruleFlow.Next(next); err != nil {
 			return nil, err
 		}
 	}
@@ -716,6 +759,61 @@ func (p *PolicyBridge) AddMicroSegmentRule(rule *EveroutePolicyRule, direction u
 	}, nil
 }
 
+// icmpMatchFields returns the match fields of the icmp type and code of the rule. The fields are
+// matched as raw fields, because the icmp type and code zero are ignored by the FlowMatch.
+func icmpMatchFields(rule *EveroutePolicyRule) []*openflow13.MatchField {
+	var typeField, codeField uint8
+	switch rule.IPProtocol {
+	case PROTOCOL_ICMP:
+		typeField, codeField = openflow13.OXM_FIELD_ICMPV4_TYPE, openflow13.OXM_FIELD_ICMPV4_CODE
+	case PROTOCOL_ICMPV6:
+		typeField, codeField = openflow13.OXM_FIELD_ICMPV6_TYPE, openflow13.OXM_FIELD_ICMPV6_CODE
+	default:
+		return nil
+	}
+
+	var fields []*openflow13.MatchField
+	if rule.ICMPType != nil {
+		fields = append(fields, &openflow13.MatchField{
+			Class:  openflow13.OXM_CLASS_OPENFLOW_BASIC,
+			Field:  typeField,
+			Length: 1,
+			Value:  &openflow13.IcmpTypeField{Type: *rule.ICMPType},
+		})
+	}
+	if rule.ICMPCode != nil {
+		fields = append(fields, &openflow13.MatchField{
+			Class:  openflow13.OXM_CLASS_OPENFLOW_BASIC,
+			Field:  codeField,
+			Length: 1,
+			Value:  &openflow13.IcmpCodeField{Code: *rule.ICMPCode},
+		})
+	}
+	return fields
+}
+
+func (p *PolicyBridge) setRedirectConntrack(ruleFlow *ofctrl.Flow, rule *EveroutePolicyRule) error {
+	var ctDropTable uint8 = CT_DROP_TABLE
+	var policyConntrackZone = p.conntrackZone()
+
+	redirectIP := net.ParseIP(rule.RedirectIPAddr)
+	if redirectIP == nil || redirectIP.To4() == nil {
+		return fmt.Errorf("invalid redirect ip %s of rule %s", rule.RedirectIPAddr, rule.RuleID)
+	}
+	var portRange *ofctrl.PortRange
+	if rule.RedirectPort != 0 {
+		portRange = ofctrl.NewPortRange(rule.RedirectPort)
+	}
+	natAction, _ := ofctrl.NewDNatAction(ofctrl.NewIPRange(redirectIP.To4()), portRange).ToOfAction()
+
+	srcField, _ := openflow13.FindFieldHeaderByName("nxm_nx_xxreg0", false)
+	dstField, _ := openflow13.FindFieldHeaderByName("nxm_nx_ct_label", false)
+	moveAct := openflow13.NewNXActionRegMove(128, 0, 0, srcField, dstField)
+
+	ctCommitAction := ofctrl.NewConntrackAction(true, false, &ctDropTable, &policyConntrackZone, natAction, moveAct)
+	return ruleFlow.SetConntrack(ctCommitAction)
+}
+
 func (p *PolicyBridge) RemoveMicroSegmentRule(rule *EveroutePolicyRule) error {
 	return nil
 }
@@ -153,6 +153,31 @@ func ExcuteCommand(cmdStr, arg string) error {
 	return nil
 }
 
+// getVDSBridgeNames return the bridge chain of the vds.
+func getVDSBridgeNames(ovsbrName string) []string {
+	return []string{
+		ovsbrName,
+		fmt.Sprintf("%s-%s", ovsbrName, POLICY_BRIDGE_KEYWORD),
+		fmt.Sprintf("%s-%s", ovsbrName, CLS_BRIDGE_KEYWORD),
+		fmt.Sprintf("%s-%s", ovsbrName, UPLINK_BRIDGE_KEYWORD),
+	}
+}
+
+// GetSpanningTreeBridges return the bridges of the vds with stp or rstp enabled.
+func GetSpanningTreeBridges(ovsbrName string) ([]string, error) {
+	var bridges []string
+	for _, brName := range getVDSBridgeNames(ovsbrName) {
+		out, err := exec.Command("ovs-vsctl", "--if-exists", "get", "Bridge", brName, "stp_enable", "rstp_enable").CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bridge %s spanning tree config: %s, error: %v", brName, strings.TrimSpace(string(out)), err)
+		}
+		if strings.Contains(string(out), "true") {
+			bridges = append(bridges, brName)
+		}
+	}
+	return bridges, nil
+}
+
 func InitCNIDpMgrUT(stopCh <-chan struct{}, brName string, enableProxy bool, enableOverlay bool) (*DpManager, error) {
 	var err error
 	dpConfig := &DpManagerConfig{
@@ -21,6 +21,8 @@ import (
 	"net"
 	"reflect"
 	"testing"
+
+	"github.com/contiv/libOpenflow/openflow13"
 )
 
 func TestMatchIP(t *testing.T) {
@@ -65,6 +67,84 @@ func TestMatchIP(t *testing.T) {
 	}
 }
 
+func TestParseIPAddrMaskString(t *testing.T) {
+	testCases := []struct {
+		ipAddr     string
+		expectIP   string
+		expectMask string
+	}{
+		{
+			ipAddr:     "192.168.16.1/20",
+			expectIP:   "192.168.16.1",
+			expectMask: "255.255.240.0",
+		},
+		{
+			ipAddr:     "192.168.16.1",
+			expectIP:   "192.168.16.1",
+			expectMask: "255.255.255.255",
+		},
+		{
+			ipAddr:     "fe80::1/64",
+			expectIP:   "fe80::1",
+			expectMask: "ffff:ffff:ffff:ffff::",
+		},
+		{
+			ipAddr:     "fe80::1",
+			expectIP:   "fe80::1",
+			expectMask: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+		},
+	}
+
+	for index, tc := range testCases {
+		t.Run(fmt.Sprintf("tc%2d", index), func(t *testing.T) {
+			ip, mask, err := ParseIPAddrMaskString(tc.ipAddr)
+			if err != nil {
+				t.Fatalf("unexpect error: %s", err)
+			}
+			if ip.String() != tc.expectIP || mask.String() != tc.expectMask {
+				t.Fatalf("expect %s/%s, got %s/%s", tc.expectIP, tc.expectMask, ip, mask)
+			}
+		})
+	}
+}
+
+func TestICMPMatchFields(t *testing.T) {
+	echoReply, code := uint8(0), uint8(0)
+	testCases := []struct {
+		rule         *EveroutePolicyRule
+		expectFields []uint8
+	}{
+		{
+			rule:         &EveroutePolicyRule{IPProtocol: PROTOCOL_TCP, ICMPType: &echoReply},
+			expectFields: nil,
+		},
+		{
+			rule:         &EveroutePolicyRule{IPProtocol: PROTOCOL_ICMP},
+			expectFields: nil,
+		},
+		{
+			rule:         &EveroutePolicyRule{IPProtocol: PROTOCOL_ICMP, ICMPType: &echoReply},
+			expectFields: []uint8{openflow13.OXM_FIELD_ICMPV4_TYPE},
+		},
+		{
+			rule:         &EveroutePolicyRule{IPProtocol: PROTOCOL_ICMPV6, ICMPType: &echoReply, ICMPCode: &code},
+			expectFields: []uint8{openflow13.OXM_FIELD_ICMPV6_TYPE, openflow13.OXM_FIELD_ICMPV6_CODE},
+		},
+	}
+
+	for index, tc := range testCases {
+		t.Run(fmt.Sprintf("tc%2d", index), func(t *testing.T) {
+			var fields []uint8
+			for _, field := range icmpMatchFields(tc.rule) {
+				fields = append(fields, field.Field)
+			}
+			if !reflect.DeepEqual(fields, tc.expectFields) {
+				t.Fatalf("expect match fields %v, got %v", tc.expectFields, fields)
+			}
+		})
+	}
+}
+
 func TestMatchPort(t *testing.T) {
 	testCases := []struct {
 		portMask    uint16
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"github.com/vishvananda/netlink"
+
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// GetVRFs returns the vrfs of the managed vds not in the default vrf, keyed by the ovs bridge name.
+func (datapathManager *DpManager) GetVRFs() map[string]string {
+	vrfs := make(map[string]string, len(datapathManager.Config.VRFMap))
+	for vdsID, vrf := range datapathManager.Config.VRFMap {
+		vrfs[datapathManager.Config.ManagedVDSMap[vdsID]] = vrf
+	}
+	return vrfs
+}
+
+// ruleInVDS returns whether the rule should be installed on the vds. A rule is installed on the
+// vds of its vrf only, except the global rules which match the ips of all the vrfs.
+func (datapathManager *DpManager) ruleInVDS(rule *EveroutePolicyRule, vdsID string) bool {
+	if isGlobalRulePriority(rule.Priority) {
+		return true
+	}
+	return datapathManager.Config.VRFMap[vdsID] == rule.VRF
+}
+
+// isGlobalRulePriority returns true if it is the priority of the global default rules or the
+// global exception rules.
+func isGlobalRulePriority(priority int) bool {
+	return priority == constants.GlobalDefaultPolicyRulePriority ||
+		priority == constants.GlobalExceptionPolicyRulePriority
+}
+
+// conntrackFilter returns the filter matches the conntrack flows of the rules. When vrfs
+// configured, the rule only matches the flows in the policy conntrack zones of its vrf, the
+// connections of the other vrfs with overlapping ips are kept.
+func (datapathManager *DpManager) conntrackFilter(rules EveroutePolicyRuleList) netlink.CustomConntrackFilter {
+	if len(datapathManager.Config.VRFMap) == 0 {
+		return rules
+	}
+
+	datapathManager.DpManagerMutex.Lock()
+	defer datapathManager.DpManagerMutex.Unlock()
+
+	zones := make(map[string]map[uint16]bool)
+	for vdsID, zone := range datapathManager.ctZones {
+		vrf := datapathManager.Config.VRFMap[vdsID]
+		if zones[vrf] == nil {
+			zones[vrf] = make(map[uint16]bool)
+		}
+		zones[vrf][zone] = true
+	}
+	return &vrfConntrackFilter{rules: rules, zones: zones}
+}
+
+type vrfConntrackFilter struct {
+	rules EveroutePolicyRuleList
+	// zones are the policy conntrack zones of the vrfs
+	zones map[string]map[uint16]bool
+}
+
+func (f *vrfConntrackFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	for _, rule := range f.rules {
+		if !isGlobalRulePriority(rule.Priority) && IsPolicyCTZone(flow.Zone) && !f.zones[rule.VRF][flow.Zone] {
+			continue
+		}
+		if rule.MatchConntrackFlow(flow) {
+			return true
+		}
+	}
+	return false
+}
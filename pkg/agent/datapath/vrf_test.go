@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datapath
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func newVRFTestDpManager() *DpManager {
+	return &DpManager{
+		Config: &DpManagerConfig{
+			VRFMap: map[string]string{"vds2": "tenant-a", "vds3": "tenant-a"},
+		},
+		ctZones: map[string]uint16{"vds1": CTZoneForPolicy, "vds2": CTZoneForPolicy - 1, "vds3": CTZoneForPolicy - 2},
+	}
+}
+
+func TestRuleInVDS(t *testing.T) {
+	dm := newVRFTestDpManager()
+
+	testCases := []struct {
+		name   string
+		rule   *EveroutePolicyRule
+		expect map[string]bool
+	}{
+		{
+			name:   "rule of the default vrf",
+			rule:   &EveroutePolicyRule{Priority: constants.NormalPolicyRulePriority},
+			expect: map[string]bool{"vds1": true, "vds2": false, "vds3": false},
+		},
+		{
+			name:   "rule of the vrf",
+			rule:   &EveroutePolicyRule{Priority: constants.NormalPolicyRulePriority, VRF: "tenant-a"},
+			expect: map[string]bool{"vds1": false, "vds2": true, "vds3": true},
+		},
+		{
+			name:   "rule of the vrf without vds",
+			rule:   &EveroutePolicyRule{Priority: constants.NormalPolicyRulePriority, VRF: "tenant-b"},
+			expect: map[string]bool{"vds1": false, "vds2": false, "vds3": false},
+		},
+		{
+			name:   "global default rule",
+			rule:   &EveroutePolicyRule{Priority: constants.GlobalDefaultPolicyRulePriority},
+			expect: map[string]bool{"vds1": true, "vds2": true, "vds3": true},
+		},
+		{
+			name:   "global exception rule",
+			rule:   &EveroutePolicyRule{Priority: constants.GlobalExceptionPolicyRulePriority},
+			expect: map[string]bool{"vds1": true, "vds2": true, "vds3": true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for vdsID, expect := range tc.expect {
+				if dm.ruleInVDS(tc.rule, vdsID) != expect {
+					t.Errorf("expect rule in vds %s %t, got %t", vdsID, expect, !expect)
+				}
+			}
+		})
+	}
+}
+
+func TestVRFConntrackFilter(t *testing.T) {
+	newFlow := func(zone uint16) *netlink.ConntrackFlow {
+		flow := &netlink.ConntrackFlow{Zone: zone}
+		flow.Forward.Protocol = 6
+		flow.Forward.SrcIP = net.ParseIP("10.0.0.1")
+		flow.Forward.DstIP = net.ParseIP("10.0.0.2")
+		flow.Forward.DstPort = 80
+		return flow
+	}
+	rule := EveroutePolicyRule{
+		RuleID:    "rule1",
+		Priority:  constants.NormalPolicyRulePriority,
+		SrcIPAddr: "10.0.0.1/32",
+		VRF:       "tenant-a",
+	}
+
+	dm := newVRFTestDpManager()
+	filter := dm.conntrackFilter(EveroutePolicyRuleList{rule})
+	if !filter.MatchConntrackFlow(newFlow(CTZoneForPolicy - 1)) {
+		t.Errorf("expect match the flow in the zone of the vrf")
+	}
+	if !filter.MatchConntrackFlow(newFlow(CTZoneForPolicy - 2)) {
+		t.Errorf("expect match the flow in the zone of the vrf")
+	}
+	if filter.MatchConntrackFlow(newFlow(CTZoneForPolicy)) {
+		t.Errorf("expect not match the flow in the zone of the other vrf")
+	}
+	if !filter.MatchConntrackFlow(newFlow(CNI_CONNTRACK_ZONE)) {
+		t.Errorf("expect match the flow not in the policy zones")
+	}
+
+	rule.Priority = constants.GlobalDefaultPolicyRulePriority
+	filter = dm.conntrackFilter(EveroutePolicyRuleList{rule})
+	if !filter.MatchConntrackFlow(newFlow(CTZoneForPolicy)) {
+		t.Errorf("expect global default rule match the flows of all the vrfs")
+	}
+
+	dm.Config.VRFMap = nil
+	rule.Priority = constants.NormalPolicyRulePriority
+	if _, ok := dm.conntrackFilter(EveroutePolicyRuleList{rule}).(EveroutePolicyRuleList); !ok {
+		t.Errorf("expect match by the rules only without vrf")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package denylog logs the packets dropped by the deny rules. The datapath punts a copy of
+// the dropped packets to the agent, the packets are sampled by the rate limits of each rule
+// and of the agent, then logged as json lines and recorded as the events of the policies.
+// The packets matched the deny rules of the policies in monitor mode are logged the same
+// way as monitored, they are not dropped.
+package denylog
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+const (
+	// DeniedEventReason is the reason of the event recorded on the policy denied a packet
+	DeniedEventReason = "PacketDenied"
+	// MonitoredEventReason is the reason of the event recorded on the policy in monitor mode
+	// would deny a packet
+	MonitoredEventReason = "PacketWouldDeny"
+
+	DefaultRuleRate  = 1
+	DefaultRuleBurst = 5
+	DefaultRate      = 100
+
+	// ruleLimiterIdle is the duration the limiter of a rule kept without packets denied
+	ruleLimiterIdle = 10 * time.Minute
+)
+
+// Datapath is the datapath the denied packets punted from.
+type Datapath interface {
+	// DeniedPackets returns the packets dropped by the deny rules.
+	DeniedPackets() <-chan datapath.DeniedPacket
+	// GetRuleReferencesByFlowID returns the policy rules the flow installed for.
+	GetRuleReferencesByFlowID(flowID uint64) []string
+}
+
+type Config struct {
+	// RuleRate and RuleBurst limit the packets logged per second of each rule.
+	RuleRate  float64
+	RuleBurst int
+	// Rate limits the packets logged per second of all the rules on the agent.
+	Rate float64
+	// RecordEvent records the denied packets as the events of the SecurityPolicies,
+	// the packets of the global policy are only logged.
+	RecordEvent bool
+}
+
+// Record is the json line logged for a denied packet.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Agent      string    `json:"agent"`
+	Bridge     string    `json:"bridge"`
+	Policy     string    `json:"policy"`
+	PolicyType string    `json:"policyType"`
+	Rule       string    `json:"rule"`
+	Protocol   string    `json:"protocol"`
+	SrcIP      string    `json:"srcIP"`
+	DstIP      string    `json:"dstIP"`
+	SrcPort    uint16    `json:"srcPort,omitempty"`
+	DstPort    uint16    `json:"dstPort,omitempty"`
+	// Monitor is true if the rule is in monitor mode, the packet is not dropped.
+	Monitor bool `json:"monitor,omitempty"`
+	// Suppressed is the number of the packets of the rule not logged since the last record.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Logger logs the packets dropped by the deny rules of the local datapath.
+type Logger struct {
+	Recorder  record.EventRecorder
+	Datapath  Datapath
+	Config    Config
+	AgentName string
+
+	limiter      flowcontrol.RateLimiter
+	ruleLimiters map[string]*ruleLimiter
+
+	// logRecord writes the record, replaced in testing
+	logRecord func(record Record)
+}
+
+type ruleLimiter struct {
+	limiter    flowcontrol.RateLimiter
+	suppressed int
+	lastSeen   time.Time
+}
+
+func (l *Logger) Run(stopChan <-chan struct{}) {
+	l.complete()
+
+	klog.Infof("start deny logging with %v packets per second of each rule", l.Config.RuleRate)
+	defer klog.Infof("shutting down deny logging")
+
+	ticker := time.NewTicker(ruleLimiterIdle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case packet := <-l.Datapath.DeniedPackets():
+			l.handle(packet)
+		case now := <-ticker.C:
+			l.cleanIdleLimiters(now)
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+func (l *Logger) complete() {
+	if l.Config.RuleRate <= 0 {
+		l.Config.RuleRate = DefaultRuleRate
+	}
+	if l.Config.RuleBurst <= 0 {
+		l.Config.RuleBurst = DefaultRuleBurst
+	}
+	if l.Config.Rate <= 0 {
+		l.Config.Rate = DefaultRate
+	}
+	l.limiter = flowcontrol.NewTokenBucketRateLimiter(float32(l.Config.Rate), int(l.Config.Rate))
+	l.ruleLimiters = make(map[string]*ruleLimiter)
+	if l.logRecord == nil {
+		l.logRecord = logRecord
+	}
+}
+
+func (l *Logger) handle(packet datapath.DeniedPacket) {
+	for _, reference := range l.Datapath.GetRuleReferencesByFlowID(packet.FlowID) {
+		namespace, name, policyType, rule, ok := parseRuleReference(reference)
+		if !ok {
+			continue
+		}
+		policy := name
+		if namespace != "" {
+			policy = namespace + "/" + name
+		}
+
+		key := policy + "/" + rule
+		limiter, ok := l.ruleLimiters[key]
+		if !ok {
+			limiter = &ruleLimiter{limiter: flowcontrol.NewTokenBucketRateLimiter(float32(l.Config.RuleRate), l.Config.RuleBurst)}
+			l.ruleLimiters[key] = limiter
+		}
+		limiter.lastSeen = packet.Time
+		if !limiter.limiter.TryAccept() || !l.limiter.TryAccept() {
+			limiter.suppressed++
+			continue
+		}
+
+		record := Record{
+			Time:       packet.Time,
+			Agent:      l.AgentName,
+			Bridge:     packet.Bridge,
+			Policy:     policy,
+			PolicyType: policyType,
+			Rule:       rule,
+			Protocol:   protocolName(packet.Protocol),
+			SrcIP:      packet.SrcIP.String(),
+			DstIP:      packet.DstIP.String(),
+			SrcPort:    packet.SrcPort,
+			DstPort:    packet.DstPort,
+			Monitor:    packet.Monitor,
+			Suppressed: limiter.suppressed,
+		}
+		limiter.suppressed = 0
+		l.logRecord(record)
+
+		if l.Config.RecordEvent && policyType == string(policycache.NormalPolicy) {
+			l.recordEvent(namespace, name, record)
+		}
+	}
+}
+
+func (l *Logger) recordEvent(namespace, name string, record Record) {
+	policy := &securityv1alpha1.SecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if record.Monitor {
+		l.Recorder.Eventf(policy, corev1.EventTypeNormal, MonitoredEventReason,
+			"agent %s would deny %s packet from %s to %s by rule %s in monitor mode, %d similar packets suppressed",
+			record.Agent, record.Protocol, endpointString(record.SrcIP, record.SrcPort), endpointString(record.DstIP, record.DstPort),
+			record.Rule, record.Suppressed)
+		return
+	}
+	l.Recorder.Eventf(policy, corev1.EventTypeWarning, DeniedEventReason,
+		"agent %s denied %s packet from %s to %s by rule %s, %d similar packets suppressed",
+		record.Agent, record.Protocol, endpointString(record.SrcIP, record.SrcPort), endpointString(record.DstIP, record.DstPort),
+		record.Rule, record.Suppressed)
+}
+
+func (l *Logger) cleanIdleLimiters(now time.Time) {
+	for key, limiter := range l.ruleLimiters {
+		if now.Sub(limiter.lastSeen) > ruleLimiterIdle {
+			delete(l.ruleLimiters, key)
+		}
+	}
+}
+
+func logRecord(record Record) {
+	line, _ := json.Marshal(record)
+	if record.Monitor {
+		klog.Infof("monitored packet: %s", line)
+		return
+	}
+	klog.Infof("denied packet: %s", line)
+}
+
+// parseRuleReference parses the rule reference of the flow, which is in format of
+// policyNamespace/policyName/policyType/ruleName-flowKey, the namespace is empty for the
+// global policy.
+func parseRuleReference(reference string) (namespace, name, policyType, rule string, ok bool) {
+	keys := strings.SplitN(reference, "/", 4)
+	if len(keys) != 4 || keys[1] == "" {
+		return "", "", "", "", false
+	}
+	index := strings.LastIndex(keys[3], "-")
+	if index <= 0 {
+		return "", "", "", "", false
+	}
+	return keys[0], keys[1], keys[2], strings.TrimSuffix(keys[3][:index], "/"), true
+}
+
+func protocolName(protocol uint8) string {
+	switch protocol {
+	case unix.IPPROTO_TCP:
+		return "TCP"
+	case unix.IPPROTO_UDP:
+		return "UDP"
+	case unix.IPPROTO_ICMP:
+		return "ICMP"
+	case unix.IPPROTO_SCTP:
+		return "SCTP"
+	default:
+		return strconv.Itoa(int(protocol))
+	}
+}
+
+func endpointString(ip string, port uint16) string {
+	if port == 0 {
+		return ip
+	}
+	return ip + ":" + strconv.Itoa(int(port))
+}
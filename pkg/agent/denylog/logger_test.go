@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package denylog
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+type fakeDatapath map[uint64][]string
+
+func (d fakeDatapath) DeniedPackets() <-chan datapath.DeniedPacket {
+	return nil
+}
+
+func (d fakeDatapath) GetRuleReferencesByFlowID(flowID uint64) []string {
+	return d[flowID]
+}
+
+func TestParseRuleReference(t *testing.T) {
+	RegisterTestingT(t)
+
+	namespace, name, policyType, rule, ok := parseRuleReference("ns/policy/normal/ingress.deny-ssh-a1b2c3")
+	Expect(ok).Should(BeTrue())
+	Expect([]string{namespace, name, policyType, rule}).Should(Equal([]string{"ns", "policy", "normal", "ingress.deny-ssh"}))
+
+	namespace, name, policyType, rule, ok = parseRuleReference("/default/global/global.ingress/-a1b2c3")
+	Expect(ok).Should(BeTrue())
+	Expect([]string{namespace, name, policyType, rule}).Should(Equal([]string{"", "default", "global", "global.ingress"}))
+
+	_, _, _, _, ok = parseRuleReference("ns/policy/normal")
+	Expect(ok).Should(BeFalse())
+}
+
+func TestLoggerHandle(t *testing.T) {
+	RegisterTestingT(t)
+
+	var records []Record
+	recorder := record.NewFakeRecorder(10)
+	logger := &Logger{
+		Recorder: recorder,
+		Datapath: fakeDatapath{
+			1: {"ns/policy/normal/ingress.deny-ssh-a1b2c3"},
+			2: {"/default/global/global.ingress/-a1b2c3"},
+		},
+		Config:    Config{RuleRate: 1, RuleBurst: 2, RecordEvent: true},
+		AgentName: "agent1",
+		logRecord: func(record Record) { records = append(records, record) },
+	}
+	logger.complete()
+
+	now := time.Now()
+	packet := datapath.DeniedPacket{
+		Time: now, Bridge: "ovsbr0-policy", FlowID: 1, Protocol: 6,
+		SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), SrcPort: 34567, DstPort: 22,
+	}
+	for i := 0; i < 5; i++ {
+		logger.handle(packet)
+	}
+	Expect(records).Should(HaveLen(2))
+	Expect(records[0]).Should(Equal(Record{
+		Time: now, Agent: "agent1", Bridge: "ovsbr0-policy", Policy: "ns/policy", PolicyType: "normal",
+		Rule: "ingress.deny-ssh", Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 34567, DstPort: 22,
+	}))
+	Expect(logger.ruleLimiters["ns/policy/ingress.deny-ssh"].suppressed).Should(Equal(3))
+	Expect(recorder.Events).Should(HaveLen(2))
+	Expect(<-recorder.Events).Should(Equal("Warning PacketDenied agent agent1 denied TCP packet from 10.0.0.1:34567 to 10.0.0.2:22 by rule ingress.deny-ssh, 0 similar packets suppressed"))
+
+	// the global policy has its own limiter, and is not recorded as events
+	packet.FlowID = 2
+	logger.handle(packet)
+	Expect(records).Should(HaveLen(3))
+	Expect(records[2].Policy).Should(Equal("default"))
+	Expect(records[2].Rule).Should(Equal("global.ingress"))
+	Expect(recorder.Events).Should(HaveLen(1))
+
+	logger.cleanIdleLimiters(now.Add(ruleLimiterIdle + time.Second))
+	Expect(logger.ruleLimiters).Should(BeEmpty())
+}
+
+func TestLoggerHandleMonitor(t *testing.T) {
+	RegisterTestingT(t)
+
+	var records []Record
+	recorder := record.NewFakeRecorder(10)
+	logger := &Logger{
+		Recorder:  recorder,
+		Datapath:  fakeDatapath{1: {"ns/policy/normal/ingress.deny-ssh-a1b2c3"}},
+		Config:    Config{RuleRate: 1, RuleBurst: 2, RecordEvent: true},
+		AgentName: "agent1",
+		logRecord: func(record Record) { records = append(records, record) },
+	}
+	logger.complete()
+
+	logger.handle(datapath.DeniedPacket{
+		Time: time.Now(), Bridge: "ovsbr0-policy", FlowID: 1, Monitor: true, Protocol: 17,
+		SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"), SrcPort: 34567, DstPort: 53,
+	})
+	Expect(records).Should(HaveLen(1))
+	Expect(records[0].Monitor).Should(BeTrue())
+	Expect(<-recorder.Events).Should(Equal("Normal PacketWouldDeny agent agent1 would deny UDP packet from 10.0.0.1:34567 to 10.0.0.2:53 by rule ingress.deny-ssh in monitor mode, 0 similar packets suppressed"))
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package effectiverules exports the rules active in the datapath of the agent as the
+// EffectiveRuleSet named as the agent. The rules are collected periodically, and the
+// EffectiveRuleSet is only updated when the rules changed, for the compliance audits
+// which require the evidence of the deployed rules.
+package effectiverules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	rpcv1alpha1 "github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+const (
+	DefaultInterval = time.Minute
+	// DefaultMaxRules limits the size of the EffectiveRuleSet under the object size
+	// limit of the apiserver, a rule is about 300 bytes.
+	DefaultMaxRules = 3000
+)
+
+// Datapath is the datapath the rules exported from.
+type Datapath interface {
+	// GetAllRules returns the rules installed in the datapath.
+	GetAllRules() []*rpcv1alpha1.RuleEntry
+}
+
+type Config struct {
+	// Interval is the interval of collecting the rules.
+	Interval time.Duration
+	// MaxRules is the max number of the rules listed in the EffectiveRuleSet.
+	MaxRules int
+}
+
+// Exporter exports the rules active on the agent in the EffectiveRuleSet.
+type Exporter struct {
+	client.Client
+	Datapath  Datapath
+	Config    Config
+	AgentName string
+
+	// hash is the hash of the rules last exported
+	hash string
+}
+
+func (e *Exporter) Run(stopChan <-chan struct{}) {
+	if e.Config.Interval == 0 {
+		e.Config.Interval = DefaultInterval
+	}
+	if e.Config.MaxRules == 0 {
+		e.Config.MaxRules = DefaultMaxRules
+	}
+
+	klog.Infof("start exporting effective rules every %s", e.Config.Interval)
+	wait.Until(e.export, e.Config.Interval, stopChan)
+}
+
+func (e *Exporter) export() {
+	rules := EffectiveRules(e.Datapath.GetAllRules())
+	hash := HashRules(rules)
+	if hash == e.hash {
+		return
+	}
+
+	if err := e.sync(rules, hash, time.Now()); err != nil {
+		klog.Errorf("unable to export effective rules: %s", err)
+		return
+	}
+	e.hash = hash
+}
+
+func (e *Exporter) sync(rules []agentv1alpha1.EffectiveRule, hash string, now time.Time) error {
+	ctx := context.Background()
+	ruleSet := agentv1alpha1.EffectiveRuleSet{}
+	err := e.Get(ctx, client.ObjectKey{Name: e.AgentName}, &ruleSet)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err == nil && ruleSet.Hash == hash {
+		// the rules have been exported before the agent restarted
+		return nil
+	}
+
+	ruleSet.Name = e.AgentName
+	ruleSet.Hash = hash
+	ruleSet.RuleCount = int32(len(rules))
+	ruleSet.Truncated = len(rules) > e.Config.MaxRules
+	ruleSet.GeneratedTime = metav1.NewTime(now)
+	ruleSet.Rules = rules
+	if ruleSet.Truncated {
+		ruleSet.Rules = rules[:e.Config.MaxRules]
+	}
+
+	if apierrors.IsNotFound(err) {
+		return e.Create(ctx, &ruleSet)
+	}
+	return e.Update(ctx, &ruleSet)
+}
+
+// EffectiveRules returns the rules in the order they are evaluated in the datapath, the egress
+// rules first, then by the tier and the priority.
+func EffectiveRules(entries []*rpcv1alpha1.RuleEntry) []agentv1alpha1.EffectiveRule {
+	type orderedRule struct {
+		direction, tier uint32
+		rule            agentv1alpha1.EffectiveRule
+	}
+
+	ordered := make([]orderedRule, 0, len(entries))
+	for _, entry := range entries {
+		if entry.EveroutePolicyRule == nil {
+			continue
+		}
+		rule := entry.EveroutePolicyRule
+		effective := agentv1alpha1.EffectiveRule{
+			Name:        rule.RuleID,
+			Direction:   directionName(entry.Direction),
+			Tier:        tierName(entry.Tier),
+			Priority:    rule.Priority,
+			Action:      rule.Action,
+			Mode:        entry.Mode,
+			SrcIPAddr:   rule.SrcIPAddr,
+			DstIPAddr:   rule.DstIPAddr,
+			IPProtocol:  int32(rule.IPProtocol),
+			SrcPort:     int32(rule.SrcPort),
+			SrcPortMask: int32(rule.SrcPortMask),
+			DstPort:     int32(rule.DstPort),
+			DstPortMask: int32(rule.DstPortMask),
+		}
+		for _, ref := range entry.PolicyRuleReference {
+			effective.Policies = append(effective.Policies, strings.Join([]string{ref.NameSpace, ref.Name, ref.Type}, "/"))
+		}
+		sort.Strings(effective.Policies)
+		ordered = append(ordered, orderedRule{direction: entry.Direction, tier: entry.Tier, rule: effective})
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.direction != b.direction {
+			return a.direction < b.direction
+		}
+		if a.tier != b.tier {
+			return a.tier < b.tier
+		}
+		if a.rule.Priority != b.rule.Priority {
+			return a.rule.Priority > b.rule.Priority
+		}
+		return a.rule.Name < b.rule.Name
+	})
+
+	rules := make([]agentv1alpha1.EffectiveRule, 0, len(ordered))
+	for _, item := range ordered {
+		rules = append(rules, item.rule)
+	}
+	return rules
+}
+
+// HashRules returns the sha256 of the rules in hex.
+func HashRules(rules []agentv1alpha1.EffectiveRule) string {
+	raw, _ := json.Marshal(rules)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func directionName(direction uint32) string {
+	if direction == datapath.POLICY_DIRECTION_IN {
+		return string(policycache.RuleDirectionIn)
+	}
+	return string(policycache.RuleDirectionOut)
+}
+
+func tierName(tier uint32) string {
+	switch tier {
+	case datapath.POLICY_TIER1:
+		return constants.Tier0
+	case datapath.POLICY_TIER2:
+		return constants.Tier1
+	case datapath.POLICY_TIER_ECP:
+		return constants.TierECP
+	case datapath.POLICY_TIER3:
+		return constants.Tier2
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package effectiverules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	rpcv1alpha1 "github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+)
+
+type fakeDatapath []*rpcv1alpha1.RuleEntry
+
+func (d fakeDatapath) GetAllRules() []*rpcv1alpha1.RuleEntry {
+	return d
+}
+
+func newRuleEntry(name string, direction, tier uint32, priority int32, action string, policies ...*rpcv1alpha1.PolicyRuleReference) *rpcv1alpha1.RuleEntry {
+	return &rpcv1alpha1.RuleEntry{
+		EveroutePolicyRule:  &rpcv1alpha1.PolicyRule{RuleID: name, Priority: priority, Action: action, SrcIPAddr: "10.0.0.1/32"},
+		Direction:           direction,
+		Tier:                tier,
+		Mode:                "work",
+		PolicyRuleReference: policies,
+	}
+}
+
+func TestEffectiveRules(t *testing.T) {
+	RegisterTestingT(t)
+
+	web := &rpcv1alpha1.PolicyRuleReference{NameSpace: "default", Name: "web", Type: "normal"}
+	global := &rpcv1alpha1.PolicyRuleReference{Name: "global", Type: "global"}
+	rules := EffectiveRules([]*rpcv1alpha1.RuleEntry{
+		newRuleEntry("default/web/normal/ingress.http-a", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER2, 100, "allow", web),
+		newRuleEntry("default/web/normal/egress.dns-b", datapath.POLICY_DIRECTION_OUT, datapath.POLICY_TIER2, 100, "allow", web),
+		newRuleEntry("/global/global/default.ingress-c", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER3, 40, "deny", global),
+		newRuleEntry("default/web/normal/ingress.deny-d", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER2, 200, "deny", web),
+		newRuleEntry("isolation-e", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER1, 100, "deny"),
+	})
+
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	Expect(names).Should(Equal([]string{
+		"default/web/normal/egress.dns-b",
+		"isolation-e",
+		"default/web/normal/ingress.deny-d",
+		"default/web/normal/ingress.http-a",
+		"/global/global/default.ingress-c",
+	}))
+	Expect(rules[2]).Should(Equal(agentv1alpha1.EffectiveRule{
+		Name:      "default/web/normal/ingress.deny-d",
+		Direction: "Ingress",
+		Tier:      "tier1",
+		Priority:  200,
+		Action:    "deny",
+		Mode:      "work",
+		SrcIPAddr: "10.0.0.1/32",
+		Policies:  []string{"default/web/normal"},
+	}))
+	Expect(rules[4].Policies).Should(Equal([]string{"/global/global"}))
+}
+
+func TestExport(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme := runtime.NewScheme()
+	Expect(clientsetscheme.AddToScheme(scheme)).Should(Succeed())
+	dp := fakeDatapath{
+		newRuleEntry("default/web/normal/ingress.http-a", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER2, 100, "allow"),
+		newRuleEntry("default/web/normal/ingress.ssh-b", datapath.POLICY_DIRECTION_IN, datapath.POLICY_TIER2, 100, "allow"),
+	}
+	exporter := &Exporter{
+		Client:    fake.NewFakeClientWithScheme(scheme),
+		Datapath:  dp,
+		Config:    Config{MaxRules: 1},
+		AgentName: "agent-a",
+	}
+
+	exporter.export()
+	ruleSet := agentv1alpha1.EffectiveRuleSet{}
+	Expect(exporter.Get(context.Background(), client.ObjectKey{Name: "agent-a"}, &ruleSet)).Should(Succeed())
+	Expect(ruleSet.RuleCount).Should(Equal(int32(2)))
+	Expect(ruleSet.Truncated).Should(BeTrue())
+	Expect(ruleSet.Rules).Should(HaveLen(1))
+	Expect(ruleSet.Hash).Should(Equal(HashRules(EffectiveRules(dp))))
+	generated := ruleSet.GeneratedTime
+
+	// unchanged rules are not exported again
+	exporter.hash = ""
+	Expect(exporter.sync(EffectiveRules(dp), ruleSet.Hash, time.Now().Add(time.Hour))).Should(Succeed())
+	Expect(exporter.Get(context.Background(), client.ObjectKey{Name: "agent-a"}, &ruleSet)).Should(Succeed())
+	Expect(ruleSet.GeneratedTime).Should(Equal(generated))
+
+	exporter.Datapath = dp[:1]
+	exporter.export()
+	ruleSet = agentv1alpha1.EffectiveRuleSet{}
+	Expect(exporter.Get(context.Background(), client.ObjectKey{Name: "agent-a"}, &ruleSet)).Should(Succeed())
+	Expect(ruleSet.RuleCount).Should(Equal(int32(1)))
+	Expect(ruleSet.Truncated).Should(BeFalse())
+	Expect(ruleSet.Rules[0].Name).Should(Equal("default/web/normal/ingress.http-a"))
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowexporter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+type Protocol string
+
+const (
+	ProtocolIPFIX     Protocol = "ipfix"
+	ProtocolNetFlowV9 Protocol = "netflow9"
+)
+
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultProtocol = ProtocolIPFIX
+)
+
+// Datapath is the datapath the rules of the flow ids installed on.
+type Datapath interface {
+	GetRuleReferencesByFlowID(flowID uint64) []string
+}
+
+type Config struct {
+	// Collector is the udp address of the collector, e.g. 10.0.0.1:4739.
+	Collector string
+	// Protocol is the protocol of the records, ipfix or netflow9.
+	Protocol Protocol
+	// Interval is the interval of exporting the connections.
+	Interval time.Duration
+	// SampleRate exports 1 in SampleRate connections, all the connections exported if
+	// less than 2. The connections sampled are chosen by the hash of the tuple, so a
+	// connection is either always exported or never.
+	SampleRate uint32
+	// ObservationDomainID identifies the agent in the messages, source id in netflow v9.
+	ObservationDomainID uint32
+}
+
+// Record is a connection matched by the security rules. The FlowID is the datapath flow of
+// the rule, exported as observationPointId and resolved to the rule by "erctl get rule -f".
+type Record struct {
+	SrcIP, DstIP     net.IP
+	SrcPort, DstPort uint16
+	Protocol         uint8
+	Packets, Bytes   uint64
+	FlowID           uint64
+	Denied           bool
+}
+
+// Exporter exports the connections matched by the security rules to an IPFIX or NetFlow v9
+// collector. A connection is exported when it's new or its counters increased since the
+// last export.
+type Exporter struct {
+	Datapath Datapath
+	Config   Config
+
+	// conn is the connection to the collector, replaced in testing
+	conn io.Writer
+	// listFlows used for list conntrack flows, replaced in testing
+	listFlows func() ([]*netlink.ConntrackFlow, error)
+	// counters are the counters of the flows in the last export
+	counters map[string]flowCounters
+	encoder  *encoder
+}
+
+type flowCounters struct {
+	packets uint64
+	bytes   uint64
+}
+
+func (e *Exporter) Run(stopChan <-chan struct{}) {
+	e.complete()
+	if e.Config.Protocol != ProtocolIPFIX && e.Config.Protocol != ProtocolNetFlowV9 {
+		klog.Errorf("unable to export flows: unknown protocol %s", e.Config.Protocol)
+		return
+	}
+	if e.conn == nil {
+		conn, err := net.Dial("udp", e.Config.Collector)
+		if err != nil {
+			klog.Errorf("unable to export flows to collector %s: %s", e.Config.Collector, err)
+			return
+		}
+		defer conn.Close()
+		e.conn = conn
+	}
+
+	klog.Infof("start exporting %s flows to %s every %s", e.Config.Protocol, e.Config.Collector, e.Config.Interval)
+	wait.Until(e.export, e.Config.Interval, stopChan)
+}
+
+func (e *Exporter) complete() {
+	if e.Config.Interval <= 0 {
+		e.Config.Interval = DefaultInterval
+	}
+	if e.Config.Protocol == "" {
+		e.Config.Protocol = DefaultProtocol
+	}
+	if e.listFlows == nil {
+		e.listFlows = listConntrackFlows
+	}
+	e.counters = make(map[string]flowCounters)
+	e.encoder = &encoder{
+		protocol:  e.Config.Protocol,
+		domainID:  e.Config.ObservationDomainID,
+		startTime: time.Now(),
+	}
+}
+
+func (e *Exporter) export() {
+	flows, err := e.listFlows()
+	if err != nil {
+		klog.Errorf("unable list conntrack flows: %s", err)
+		return
+	}
+
+	records := e.newRecords(flows)
+	for _, message := range e.encoder.encode(records, time.Now()) {
+		if _, err := e.conn.Write(message); err != nil {
+			klog.Errorf("unable send flows to collector %s: %s", e.Config.Collector, err)
+			return
+		}
+	}
+}
+
+// newRecords returns the records of the policy matched flows new or with counters increased
+// since the last export, the counters in the records are the increments.
+func (e *Exporter) newRecords(flows []*netlink.ConntrackFlow) []Record {
+	var records []Record
+	counters := make(map[string]flowCounters, len(flows))
+
+	for _, flow := range flows {
+		if flow == nil || !datapath.IsPolicyCTZone(flow.Zone) {
+			continue
+		}
+		flowID, denied := ruleOfLabels(flow.Labels)
+		if flowID == 0 || len(e.Datapath.GetRuleReferencesByFlowID(flowID)) == 0 {
+			continue
+		}
+		key := flowKey(flow)
+		if !e.sampled(key) {
+			continue
+		}
+
+		current := flowCounters{
+			packets: flow.Forward.Packets + flow.Reverse.Packets,
+			bytes:   flow.Forward.Bytes + flow.Reverse.Bytes,
+		}
+		counters[key] = current
+
+		last, ok := e.counters[key]
+		if ok && current.packets <= last.packets {
+			continue
+		}
+		if ok {
+			current = flowCounters{packets: current.packets - last.packets, bytes: current.bytes - last.bytes}
+		}
+		records = append(records, Record{
+			SrcIP:    flow.Forward.SrcIP,
+			DstIP:    flow.Forward.DstIP,
+			SrcPort:  flow.Forward.SrcPort,
+			DstPort:  flow.Forward.DstPort,
+			Protocol: flow.Forward.Protocol,
+			Packets:  current.packets,
+			Bytes:    current.bytes,
+			FlowID:   flowID,
+			Denied:   denied,
+		})
+	}
+
+	e.counters = counters
+	return records
+}
+
+func (e *Exporter) sampled(key string) bool {
+	if e.Config.SampleRate < 2 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()%e.Config.SampleRate == 0
+}
+
+// ruleOfLabels returns the flow id of the work mode rule the connection committed with, and
+// whether the rule denied it. The deny rules set the bit 127 of the label, the highest bit of
+// the netlink label in the little endian order of utils.CtLabelDecode.
+func ruleOfLabels(labels []byte) (uint64, bool) {
+	if len(labels) != 16 {
+		return 0, false
+	}
+	_, _, workFlowID := utils.CtLabelDecode(labels)
+	return workFlowID, labels[15]&0x80 != 0
+}
+
+func flowKey(flow *netlink.ConntrackFlow) string {
+	return fmt.Sprintf("%d/%s/%d/%s/%d", flow.Forward.Protocol,
+		flow.Forward.SrcIP, flow.Forward.SrcPort, flow.Forward.DstIP, flow.Forward.DstPort)
+}
+
+func listConntrackFlows() ([]*netlink.ConntrackFlow, error) {
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET)
+	if err != nil {
+		return nil, err
+	}
+	ipv6Flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	return append(flows, ipv6Flows...), nil
+}
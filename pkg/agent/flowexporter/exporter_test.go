@@ -0,0 +1,228 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowexporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+type fakeDatapath map[uint64][]string
+
+func (d fakeDatapath) GetRuleReferencesByFlowID(flowID uint64) []string {
+	return d[flowID]
+}
+
+// workLabels returns the netlink labels of the connection committed by the work mode rule.
+func workLabels(round, seq uint64, deny bool) []byte {
+	partA := round | (seq&0xF)<<60
+	partB := seq >> 4
+	if deny {
+		partB |= 1 << 63
+	}
+	labels := make([]byte, 16)
+	binary.LittleEndian.PutUint64(labels[0:8], partA)
+	binary.LittleEndian.PutUint64(labels[8:16], partB)
+	return labels
+}
+
+func newFlow(src, dst string, packets uint64, labels []byte) *netlink.ConntrackFlow {
+	return &netlink.ConntrackFlow{
+		Zone: datapath.CTZoneForPolicy,
+		Forward: netlink.IpTuple{
+			Protocol: unix.IPPROTO_TCP,
+			SrcIP:    net.ParseIP(src),
+			SrcPort:  34567,
+			DstIP:    net.ParseIP(dst),
+			DstPort:  443,
+			Packets:  packets,
+			Bytes:    packets * 100,
+		},
+		Labels: labels,
+	}
+}
+
+func TestRuleOfLabels(t *testing.T) {
+	RegisterTestingT(t)
+
+	flowID, denied := ruleOfLabels(workLabels(2, 0x123, false))
+	Expect(flowID).Should(Equal(uint64(2<<28 | 0x123)))
+	Expect(denied).Should(BeFalse())
+
+	flowID, denied = ruleOfLabels(workLabels(3, 0xABCDEF, true))
+	Expect(flowID).Should(Equal(uint64(3<<28 | 0xABCDEF)))
+	Expect(denied).Should(BeTrue())
+
+	flowID, _ = ruleOfLabels(nil)
+	Expect(flowID).Should(BeZero())
+}
+
+func TestNewRecords(t *testing.T) {
+	RegisterTestingT(t)
+
+	allowID, denyID := uint64(1<<28|10), uint64(1<<28|11)
+	e := &Exporter{Datapath: fakeDatapath{
+		allowID: {"default/policy/normal.ingress.rule1"},
+		denyID:  {"default/policy/normal.ingress.rule2"},
+	}}
+	e.complete()
+
+	allowed := newFlow("10.0.0.1", "10.0.0.2", 3, workLabels(1, 10, false))
+	denied := newFlow("10.0.0.1", "10.0.0.3", 1, workLabels(1, 11, true))
+	unknownRule := newFlow("10.0.0.1", "10.0.0.4", 1, workLabels(1, 12, false))
+	otherZone := newFlow("10.0.0.1", "10.0.0.5", 1, workLabels(1, 10, false))
+	otherZone.Zone = 1
+
+	records := e.newRecords([]*netlink.ConntrackFlow{allowed, denied, unknownRule, otherZone, nil})
+	Expect(records).Should(HaveLen(2))
+	Expect(records[0].FlowID).Should(Equal(allowID))
+	Expect(records[0].Denied).Should(BeFalse())
+	Expect(records[0].Packets).Should(Equal(uint64(3)))
+	Expect(records[1].FlowID).Should(Equal(denyID))
+	Expect(records[1].Denied).Should(BeTrue())
+
+	// only the increments exported
+	allowed.Forward.Packets, allowed.Forward.Bytes = 5, 500
+	records = e.newRecords([]*netlink.ConntrackFlow{allowed, denied})
+	Expect(records).Should(HaveLen(1))
+	Expect(records[0].Packets).Should(Equal(uint64(2)))
+	Expect(records[0].Bytes).Should(Equal(uint64(200)))
+}
+
+func TestSampled(t *testing.T) {
+	RegisterTestingT(t)
+
+	e := &Exporter{Config: Config{SampleRate: 4}}
+	var sampled int
+	for i := 0; i < 1000; i++ {
+		key := flowKey(newFlow("10.0.0.1", net.IPv4(10, 0, byte(i/256), byte(i)).String(), 1, nil))
+		if e.sampled(key) {
+			sampled++
+			Expect(e.sampled(key)).Should(BeTrue())
+		}
+	}
+	Expect(sampled).Should(BeNumerically("~", 250, 60))
+}
+
+func TestEncodeIPFIX(t *testing.T) {
+	RegisterTestingT(t)
+
+	e := &encoder{protocol: ProtocolIPFIX, domainID: 7}
+	now := time.Unix(1700000000, 0)
+	records := []Record{{
+		SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("10.0.0.2"),
+		SrcPort: 34567, DstPort: 443, Protocol: unix.IPPROTO_TCP,
+		Packets: 3, Bytes: 300, FlowID: 1<<28 | 10, Denied: true,
+	}}
+	messages := e.encode(records, now)
+	Expect(messages).Should(HaveLen(1))
+	message := messages[0]
+
+	templateSetLen := 4 + 4 + 4*len(templateIPv4)
+	dataSetLen := 4 + 38
+	Expect(message).Should(HaveLen(ipfixHeaderLen + templateSetLen + dataSetLen))
+	Expect(binary.BigEndian.Uint16(message[0:])).Should(Equal(uint16(ipfixVersion)))
+	Expect(binary.BigEndian.Uint16(message[2:])).Should(Equal(uint16(len(message))))
+	Expect(binary.BigEndian.Uint32(message[4:])).Should(Equal(uint32(now.Unix())))
+	Expect(binary.BigEndian.Uint32(message[8:])).Should(Equal(uint32(0)))
+	Expect(binary.BigEndian.Uint32(message[12:])).Should(Equal(uint32(7)))
+
+	// template set
+	Expect(binary.BigEndian.Uint16(message[16:])).Should(Equal(uint16(ipfixTemplateSetID)))
+	Expect(binary.BigEndian.Uint16(message[18:])).Should(Equal(uint16(templateSetLen)))
+	Expect(binary.BigEndian.Uint16(message[20:])).Should(Equal(uint16(templateIDIPv4)))
+	Expect(binary.BigEndian.Uint16(message[22:])).Should(Equal(uint16(len(templateIPv4))))
+
+	// data set
+	data := message[16+templateSetLen:]
+	Expect(binary.BigEndian.Uint16(data[0:])).Should(Equal(uint16(templateIDIPv4)))
+	Expect(binary.BigEndian.Uint16(data[2:])).Should(Equal(uint16(dataSetLen)))
+	Expect(net.IP(data[4:8]).String()).Should(Equal("10.0.0.1"))
+	Expect(net.IP(data[8:12]).String()).Should(Equal("10.0.0.2"))
+	Expect(binary.BigEndian.Uint16(data[12:])).Should(Equal(uint16(34567)))
+	Expect(binary.BigEndian.Uint16(data[14:])).Should(Equal(uint16(443)))
+	Expect(data[16]).Should(Equal(uint8(unix.IPPROTO_TCP)))
+	Expect(binary.BigEndian.Uint64(data[17:])).Should(Equal(uint64(3)))
+	Expect(binary.BigEndian.Uint64(data[25:])).Should(Equal(uint64(300)))
+	Expect(binary.BigEndian.Uint64(data[33:])).Should(Equal(uint64(1<<28 | 10)))
+	Expect(data[41]).Should(Equal(uint8(forwardingStatusDropped)))
+
+	// the sequence counts the data records
+	e.encode(records, now)
+	Expect(e.sequence).Should(Equal(uint32(2)))
+}
+
+func TestEncodeNetFlowV9(t *testing.T) {
+	RegisterTestingT(t)
+
+	start := time.Unix(1700000000, 0)
+	e := &encoder{protocol: ProtocolNetFlowV9, domainID: 7, startTime: start}
+	var records []Record
+	for i := 0; i < maxRecordsPerMessage+1; i++ {
+		records = append(records, Record{
+			SrcIP: net.ParseIP("fd00::1"), DstIP: net.ParseIP("fd00::2"),
+			SrcPort: 34567, DstPort: uint16(i), Protocol: unix.IPPROTO_UDP,
+			Packets: 1, Bytes: 100, FlowID: 1<<28 | 10,
+		})
+	}
+	messages := e.encode(records, start.Add(time.Second))
+	Expect(messages).Should(HaveLen(2))
+
+	message := messages[1]
+	Expect(binary.BigEndian.Uint16(message[0:])).Should(Equal(uint16(netflowV9Version)))
+	Expect(binary.BigEndian.Uint16(message[2:])).Should(Equal(uint16(2)))
+	Expect(binary.BigEndian.Uint32(message[4:])).Should(Equal(uint32(1000)))
+	Expect(binary.BigEndian.Uint32(message[12:])).Should(Equal(uint32(2)))
+	Expect(binary.BigEndian.Uint32(message[16:])).Should(Equal(uint32(7)))
+
+	// the flowsets are padded to 4 bytes
+	templateSetLen := int(binary.BigEndian.Uint16(message[netflowV9HeaderLen+2:]))
+	Expect(binary.BigEndian.Uint16(message[netflowV9HeaderLen:])).Should(Equal(uint16(netflowV9TemplateSetID)))
+	data := message[netflowV9HeaderLen+templateSetLen:]
+	Expect(binary.BigEndian.Uint16(data[0:])).Should(Equal(uint16(templateIDIPv6)))
+	Expect(int(binary.BigEndian.Uint16(data[2:])) % 4).Should(BeZero())
+	Expect(len(data)).Should(Equal(int(binary.BigEndian.Uint16(data[2:]))))
+	Expect(data[4:20]).Should(Equal([]byte(net.ParseIP("fd00::1"))))
+	Expect(data[4+62-1]).Should(Equal(uint8(forwardingStatusForwarded)))
+}
+
+func TestExport(t *testing.T) {
+	RegisterTestingT(t)
+
+	var conn bytes.Buffer
+	e := &Exporter{
+		Datapath: fakeDatapath{1<<28 | 10: {"default/policy/normal.ingress.rule1"}},
+		conn:     &conn,
+	}
+	e.complete()
+	e.listFlows = func() ([]*netlink.ConntrackFlow, error) {
+		return []*netlink.ConntrackFlow{newFlow("10.0.0.1", "10.0.0.2", 1, workLabels(1, 10, false))}, nil
+	}
+
+	e.export()
+	Expect(binary.BigEndian.Uint16(conn.Bytes())).Should(Equal(uint16(ipfixVersion)))
+	Expect(conn.Len()).Should(Equal(int(binary.BigEndian.Uint16(conn.Bytes()[2:]))))
+}
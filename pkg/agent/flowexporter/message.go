@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowexporter
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// The information elements of the records, in IANA IPFIX entities, NetFlow v9 shares the ids.
+const (
+	ieOctetDeltaCount          = 1
+	iePacketDeltaCount         = 2
+	ieProtocolIdentifier       = 4
+	ieSourceTransportPort      = 7
+	ieSourceIPv4Address        = 8
+	ieDestinationTransportPort = 11
+	ieDestinationIPv4Address   = 12
+	ieSourceIPv6Address        = 27
+	ieDestinationIPv6Address   = 28
+	ieForwardingStatus         = 89
+	ieObservationPointID       = 138
+
+	// forwardingStatus in RFC 7270, forwarded or dropped for unknown reason
+	forwardingStatusForwarded = 0x40
+	forwardingStatusDropped   = 0x80
+
+	ipfixVersion       = 10
+	ipfixHeaderLen     = 16
+	ipfixTemplateSetID = 2
+
+	netflowV9Version       = 9
+	netflowV9HeaderLen     = 20
+	netflowV9TemplateSetID = 0
+
+	templateIDIPv4 = 256
+	templateIDIPv6 = 257
+
+	// maxRecordsPerMessage keeps the messages of ipv6 records in the ethernet mtu
+	maxRecordsPerMessage = 20
+)
+
+type field struct {
+	id, length uint16
+}
+
+var (
+	templateIPv4 = []field{
+		{ieSourceIPv4Address, net.IPv4len},
+		{ieDestinationIPv4Address, net.IPv4len},
+		{ieSourceTransportPort, 2},
+		{ieDestinationTransportPort, 2},
+		{ieProtocolIdentifier, 1},
+		{iePacketDeltaCount, 8},
+		{ieOctetDeltaCount, 8},
+		{ieObservationPointID, 8},
+		{ieForwardingStatus, 1},
+	}
+	templateIPv6 = []field{
+		{ieSourceIPv6Address, net.IPv6len},
+		{ieDestinationIPv6Address, net.IPv6len},
+		{ieSourceTransportPort, 2},
+		{ieDestinationTransportPort, 2},
+		{ieProtocolIdentifier, 1},
+		{iePacketDeltaCount, 8},
+		{ieOctetDeltaCount, 8},
+		{ieObservationPointID, 8},
+		{ieForwardingStatus, 1},
+	}
+)
+
+// encoder encodes the records into the messages of the protocol, the templates are sent in
+// each message, so the collectors restarted are able to decode the records immediately.
+type encoder struct {
+	protocol Protocol
+	domainID uint32
+	// startTime is the time the sysUptime of netflow v9 counts from
+	startTime time.Time
+	// sequence is the number of the data records sent for ipfix, the messages sent for netflow v9
+	sequence uint32
+}
+
+func (e *encoder) encode(records []Record, now time.Time) [][]byte {
+	var ipv4Records, ipv6Records []Record
+	for _, record := range records {
+		if record.SrcIP.To4() != nil {
+			ipv4Records = append(ipv4Records, record)
+		} else {
+			ipv6Records = append(ipv6Records, record)
+		}
+	}
+
+	var messages [][]byte
+	for _, group := range []struct {
+		templateID uint16
+		template   []field
+		records    []Record
+	}{
+		{templateIDIPv4, templateIPv4, ipv4Records},
+		{templateIDIPv6, templateIPv6, ipv6Records},
+	} {
+		for start := 0; start < len(group.records); start += maxRecordsPerMessage {
+			end := start + maxRecordsPerMessage
+			if end > len(group.records) {
+				end = len(group.records)
+			}
+			messages = append(messages, e.encodeMessage(group.templateID, group.template, group.records[start:end], now))
+		}
+	}
+	return messages
+}
+
+func (e *encoder) encodeMessage(templateID uint16, template []field, records []Record, now time.Time) []byte {
+	var body []byte
+	templateSetID := uint16(ipfixTemplateSetID)
+	if e.protocol == ProtocolNetFlowV9 {
+		templateSetID = netflowV9TemplateSetID
+	}
+
+	// template set
+	templateRecord := appendUint16(nil, templateID, uint16(len(template)))
+	for _, f := range template {
+		templateRecord = appendUint16(templateRecord, f.id, f.length)
+	}
+	body = e.appendSet(body, templateSetID, templateRecord)
+
+	// data set
+	var data []byte
+	for _, record := range records {
+		data = appendRecord(data, record)
+	}
+	body = e.appendSet(body, templateID, data)
+
+	var header []byte
+	switch e.protocol {
+	case ProtocolNetFlowV9:
+		e.sequence++
+		header = appendUint16(make([]byte, 0, netflowV9HeaderLen), netflowV9Version, uint16(len(records)+1))
+		header = appendUint32(header,
+			uint32(now.Sub(e.startTime).Milliseconds()), uint32(now.Unix()), e.sequence, e.domainID)
+	default:
+		header = appendUint16(make([]byte, 0, ipfixHeaderLen), ipfixVersion, uint16(ipfixHeaderLen+len(body)))
+		header = appendUint32(header, uint32(now.Unix()), e.sequence, e.domainID)
+		e.sequence += uint32(len(records))
+	}
+	return append(header, body...)
+}
+
+// appendSet appends the set with its header, the netflow v9 flowsets are padded to 4 bytes.
+func (e *encoder) appendSet(b []byte, setID uint16, content []byte) []byte {
+	padding := 0
+	if e.protocol == ProtocolNetFlowV9 && (4+len(content))%4 != 0 {
+		padding = 4 - (4+len(content))%4
+	}
+	b = appendUint16(b, setID, uint16(4+len(content)+padding))
+	b = append(b, content...)
+	return append(b, make([]byte, padding)...)
+}
+
+func appendRecord(b []byte, record Record) []byte {
+	if srcIP := record.SrcIP.To4(); srcIP != nil {
+		b = append(b, srcIP...)
+		b = append(b, record.DstIP.To4()...)
+	} else {
+		b = append(b, record.SrcIP.To16()...)
+		b = append(b, record.DstIP.To16()...)
+	}
+	b = appendUint16(b, record.SrcPort, record.DstPort)
+	b = append(b, record.Protocol)
+	b = appendUint64(b, record.Packets, record.Bytes, record.FlowID)
+	if record.Denied {
+		return append(b, forwardingStatusDropped)
+	}
+	return append(b, forwardingStatusForwarded)
+}
+
+func appendUint16(b []byte, values ...uint16) []byte {
+	for _, v := range values {
+		var buf [2]byte
+		binary.BigEndian.PutUint16(buf[:], v)
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+func appendUint32(b []byte, values ...uint32) []byte {
+	for _, v := range values {
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], v)
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+func appendUint64(b []byte, values ...uint64) []byte {
+	for _, v := range values {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], v)
+		b = append(b, buf[:]...)
+	}
+	return b
+}
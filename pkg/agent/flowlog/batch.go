@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"k8s.io/klog"
+)
+
+const (
+	DefaultBatchSize  = 1000
+	DefaultMaxPending = 100000
+)
+
+// batchWriter writes the records to a remote storage in batches.
+type batchWriter interface {
+	// ensureSchema creates or updates the schema of the records, it's called before
+	// the first batch written, and retried until succeed.
+	ensureSchema() error
+	// writeBatch writes the records, returns the records failed and should be retried.
+	writeBatch(records []Record) ([]Record, error)
+}
+
+// batchSink writes the records in batches with at-least-once delivery, the records are
+// kept pending until written, and retried on the next Write. When the pending records
+// exceed maxPending, the oldest ones are dropped.
+type batchSink struct {
+	writer     batchWriter
+	batchSize  int
+	maxPending int
+
+	schemaReady bool
+	pending     []Record
+}
+
+func newBatchSink(writer batchWriter, batchSize, maxPending int) *batchSink {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPending
+	}
+	return &batchSink{writer: writer, batchSize: batchSize, maxPending: maxPending}
+}
+
+func (s *batchSink) Write(records []Record) error {
+	s.pending = append(s.pending, records...)
+	if dropped := len(s.pending) - s.maxPending; dropped > 0 {
+		klog.Warningf("drop %d flow log records, pending records exceed %d", dropped, s.maxPending)
+		s.pending = append([]Record(nil), s.pending[dropped:]...)
+	}
+
+	if !s.schemaReady {
+		if err := s.writer.ensureSchema(); err != nil {
+			return fmt.Errorf("ensure schema: %s", err)
+		}
+		s.schemaReady = true
+	}
+
+	for len(s.pending) != 0 {
+		size := s.batchSize
+		if size > len(s.pending) {
+			size = len(s.pending)
+		}
+		failed, err := s.writer.writeBatch(s.pending[:size])
+		if err == nil && len(failed) != 0 {
+			err = fmt.Errorf("write %d of %d records failed", len(failed), size)
+		}
+		// the failed records are retried before the records left
+		s.pending = append(failed, s.pending[size:]...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// httpClient sends the requests to the http api of the storages.
+type httpClient struct {
+	client   *http.Client
+	username string
+	password string
+}
+
+func (c *httpClient) do(method, url, contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return respBody, fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(respBody))
+	}
+	return respBody, nil
+}
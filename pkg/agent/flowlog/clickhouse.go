@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultClickHouseDatabase = "default"
+	DefaultClickHouseTable    = "everoute_flowlogs"
+
+	clickHouseTimeFormat = "2006-01-02 15:04:05.000"
+)
+
+// clickHouseWriter writes the records to ClickHouse by the http interface, in format
+// of JSONEachRow. The table is partitioned by day, and the expired records are
+// removed by the table TTL.
+type clickHouseWriter struct {
+	httpClient
+	url       string
+	database  string
+	table     string
+	retention time.Duration
+}
+
+// clickHouseRow is the row of the flow log table, the identities are flattened.
+type clickHouseRow struct {
+	Time         string `json:"time"`
+	Agent        string `json:"agent"`
+	Protocol     string `json:"protocol"`
+	SrcIP        string `json:"src_ip"`
+	SrcPort      uint16 `json:"src_port"`
+	DstIP        string `json:"dst_ip"`
+	DstPort      uint16 `json:"dst_port"`
+	Packets      uint64 `json:"packets"`
+	Bytes        uint64 `json:"bytes"`
+	SrcKind      string `json:"src_kind"`
+	SrcNamespace string `json:"src_namespace"`
+	SrcName      string `json:"src_name"`
+	SrcMAC       string `json:"src_mac"`
+	SrcLabels    string `json:"src_labels"`
+	DstKind      string `json:"dst_kind"`
+	DstNamespace string `json:"dst_namespace"`
+	DstName      string `json:"dst_name"`
+	DstMAC       string `json:"dst_mac"`
+	DstLabels    string `json:"dst_labels"`
+}
+
+func (w *clickHouseWriter) ensureSchema() error {
+	tableName := w.database + "." + w.table
+	statements := []string{
+		"CREATE DATABASE IF NOT EXISTS " + w.database,
+		"CREATE TABLE IF NOT EXISTS " + tableName + ` (
+			time DateTime64(3, 'UTC'),
+			agent LowCardinality(String),
+			protocol LowCardinality(String),
+			src_ip String,
+			src_port UInt16,
+			dst_ip String,
+			dst_port UInt16,
+			packets UInt64,
+			bytes UInt64,
+			src_kind LowCardinality(String),
+			src_namespace String,
+			src_name String,
+			src_mac String,
+			src_labels String,
+			dst_kind LowCardinality(String),
+			dst_namespace String,
+			dst_name String,
+			dst_mac String,
+			dst_labels String
+		) ENGINE = MergeTree()
+		PARTITION BY toDate(time)
+		ORDER BY (time, agent)`,
+	}
+	if w.retention > 0 {
+		// update the ttl of the existing table as the retention changes
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s MODIFY TTL toDateTime(time) + INTERVAL %d HOUR",
+			tableName, int64((w.retention+time.Hour-1)/time.Hour)))
+	}
+
+	for _, statement := range statements {
+		if _, err := w.do(http.MethodPost, w.url, "text/plain", []byte(statement)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *clickHouseWriter) writeBatch(records []Record) ([]Record, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for item := range records {
+		if err := encoder.Encode(newClickHouseRow(&records[item])); err != nil {
+			return nil, err
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", w.database, w.table)
+	insertURL := w.url + "?" + url.Values{"query": {query}}.Encode()
+	if strings.Contains(w.url, "?") {
+		insertURL = w.url + "&" + url.Values{"query": {query}}.Encode()
+	}
+	if _, err := w.do(http.MethodPost, insertURL, "application/json", body.Bytes()); err != nil {
+		// an insert is atomic in ClickHouse, retry the whole batch
+		return records, err
+	}
+	return nil, nil
+}
+
+func newClickHouseRow(record *Record) *clickHouseRow {
+	row := &clickHouseRow{
+		Time:     record.Time.UTC().Format(clickHouseTimeFormat),
+		Agent:    record.Agent,
+		Protocol: record.Protocol,
+		SrcIP:    record.SrcIP,
+		SrcPort:  record.SrcPort,
+		DstIP:    record.DstIP,
+		DstPort:  record.DstPort,
+		Packets:  record.Packets,
+		Bytes:    record.Bytes,
+	}
+	if src := record.Source; src != nil {
+		row.SrcKind, row.SrcNamespace, row.SrcName, row.SrcMAC = string(src.Kind), src.Namespace, src.Name, src.MAC
+		row.SrcLabels = encodeLabels(src.Labels)
+	}
+	if dst := record.Destination; dst != nil {
+		row.DstKind, row.DstNamespace, row.DstName, row.DstMAC = string(dst.Kind), dst.Namespace, dst.Name, dst.MAC
+		row.DstLabels = encodeLabels(dst.Labels)
+	}
+	return row
+}
+
+// encodeLabels encodes the labels as json object, which could be queried by the
+// JSONExtract functions.
+func encodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(labels)
+	return string(data)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const (
+	DefaultElasticsearchIndex = "everoute-flowlogs"
+
+	elasticsearchIndexDateFormat = "2006.01.02"
+)
+
+// elasticsearchWriter writes the records to Elasticsearch by the bulk api, into the
+// daily indices named index-yyyy.mm.dd. The mappings of the indices are defined by an
+// index template, and the expired indices are removed by an ilm policy.
+type elasticsearchWriter struct {
+	httpClient
+	url       string
+	index     string
+	retention time.Duration
+}
+
+var elasticsearchIdentityMapping = map[string]interface{}{
+	"properties": map[string]interface{}{
+		"kind":      map[string]string{"type": "keyword"},
+		"namespace": map[string]string{"type": "keyword"},
+		"name":      map[string]string{"type": "keyword"},
+		"mac":       map[string]string{"type": "keyword"},
+		"labels":    map[string]string{"type": "flattened"},
+	},
+}
+
+var elasticsearchMappings = map[string]interface{}{
+	"properties": map[string]interface{}{
+		"time":        map[string]string{"type": "date"},
+		"agent":       map[string]string{"type": "keyword"},
+		"protocol":    map[string]string{"type": "keyword"},
+		"srcIP":       map[string]string{"type": "ip"},
+		"srcPort":     map[string]string{"type": "integer"},
+		"dstIP":       map[string]string{"type": "ip"},
+		"dstPort":     map[string]string{"type": "integer"},
+		"packets":     map[string]string{"type": "long"},
+		"bytes":       map[string]string{"type": "long"},
+		"source":      elasticsearchIdentityMapping,
+		"destination": elasticsearchIdentityMapping,
+	},
+}
+
+func (w *elasticsearchWriter) ensureSchema() error {
+	settings := map[string]interface{}{}
+	if w.retention > 0 {
+		policyName := w.index + "-retention"
+		policy := map[string]interface{}{
+			"policy": map[string]interface{}{
+				"phases": map[string]interface{}{
+					"delete": map[string]interface{}{
+						"min_age": fmt.Sprintf("%dh", int64((w.retention+time.Hour-1)/time.Hour)),
+						"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+					},
+				},
+			},
+		}
+		if err := w.putJSON("/_ilm/policy/"+policyName, policy); err != nil {
+			return err
+		}
+		settings["index.lifecycle.name"] = policyName
+	}
+
+	template := map[string]interface{}{
+		"index_patterns": []string{w.index + "-*"},
+		"template": map[string]interface{}{
+			"settings": settings,
+			"mappings": elasticsearchMappings,
+		},
+	}
+	return w.putJSON("/_index_template/"+w.index, template)
+}
+
+func (w *elasticsearchWriter) putJSON(path string, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.do(http.MethodPut, w.url+path, "application/json", body)
+	return err
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error,omitempty"`
+	} `json:"items"`
+}
+
+func (w *elasticsearchWriter) writeBatch(records []Record) ([]Record, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for item := range records {
+		action := map[string]interface{}{
+			"index": map[string]string{"_index": w.index + "-" + records[item].Time.UTC().Format(elasticsearchIndexDateFormat)},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(&records[item]); err != nil {
+			return nil, err
+		}
+	}
+
+	respBody, err := w.do(http.MethodPost, w.url+"/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return records, err
+	}
+
+	var resp elasticsearchBulkResponse
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return records, fmt.Errorf("invalid bulk response: %s", err)
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+	return retryableBulkItems(records, &resp)
+}
+
+// retryableBulkItems returns the records failed with the retryable status, e.g. 429 too
+// many requests. The records rejected by the Elasticsearch, e.g. mapping conflicts,
+// are dropped as retry never succeed.
+func retryableBulkItems(records []Record, resp *elasticsearchBulkResponse) ([]Record, error) {
+	var failed []Record
+	var rejected int
+	var lastErr string
+
+	for index, item := range resp.Items {
+		if index >= len(records) {
+			break
+		}
+		for _, result := range item {
+			if result.Status/100 == 2 {
+				continue
+			}
+			if result.Error != nil {
+				lastErr = result.Error.Type + ": " + result.Error.Reason
+			}
+			if result.Status == http.StatusTooManyRequests || result.Status/100 == 5 {
+				failed = append(failed, records[index])
+			} else {
+				rejected++
+			}
+		}
+	}
+
+	if rejected != 0 {
+		klog.Errorf("drop %d flow log records rejected by elasticsearch: %s", rejected, lastErr)
+	}
+	if len(failed) != 0 {
+		return failed, fmt.Errorf("bulk write %d records failed: %s", len(failed), lastErr)
+	}
+	return nil, nil
+}
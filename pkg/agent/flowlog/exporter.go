@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/namecache"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultPath     = "/var/lib/everoute/flowlog.json"
+	DefaultMaxSize  = 100 << 20
+)
+
+// Datapath is the datapath the local endpoints learned on.
+type Datapath interface {
+	ListLocalEndpoints() []*datapath.Endpoint
+}
+
+type Config struct {
+	// Interval is the interval of exporting the connections.
+	Interval time.Duration
+	// LabelKeys are the keys of the endpoint labels carried in the records.
+	LabelKeys []string
+	// Path is the file the records written to.
+	Path string
+	// MaxSize is the max bytes of the file before rotated.
+	MaxSize int64
+	// Sink is the storage the records written to, the records written to the file
+	// if the sink type is empty.
+	Sink SinkConfig
+}
+
+// Exporter exports the connections tracked on the agent as flow log records. A connection
+// is exported when it's new or its counters increased since the last export.
+type Exporter struct {
+	client.Client
+	Datapath Datapath
+	// Names is the name cache, the ip addresses not owned by endpoints are named by
+	// it, e.g. the services and the nodes. Optional.
+	Names  *namecache.Cache
+	Config Config
+	// Sink is where the records written to, defaults to the sink of Config.Sink.
+	Sink Sink
+
+	AgentName string
+
+	// listFlows used for list conntrack flows, replaced in testing
+	listFlows func() ([]*netlink.ConntrackFlow, error)
+	// counters are the counters of the flows in the last export
+	counters map[string]flowCounters
+}
+
+func (e *Exporter) Run(stopChan <-chan struct{}) {
+	e.complete()
+	if e.Sink == nil {
+		sink, err := NewSink(e.Config)
+		if err != nil {
+			klog.Errorf("unable to export flow logs: %s", err)
+			return
+		}
+		e.Sink = sink
+	}
+
+	klog.Infof("start exporting flow logs every %s", e.Config.Interval)
+	wait.Until(e.export, e.Config.Interval, stopChan)
+}
+
+func (e *Exporter) complete() {
+	if e.Config.Interval <= 0 {
+		e.Config.Interval = DefaultInterval
+	}
+	if e.Config.Path == "" {
+		e.Config.Path = DefaultPath
+	}
+	if e.Config.MaxSize <= 0 {
+		e.Config.MaxSize = DefaultMaxSize
+	}
+	if e.listFlows == nil {
+		e.listFlows = listConntrackFlows
+	}
+	e.counters = make(map[string]flowCounters)
+}
+
+func (e *Exporter) export() {
+	flows, err := e.listFlows()
+	if err != nil {
+		klog.Errorf("unable list conntrack flows: %s", err)
+		return
+	}
+
+	var endpointList securityv1alpha1.EndpointList
+	if err := e.List(context.Background(), &endpointList); err != nil {
+		klog.Errorf("unable list endpoints: %s", err)
+		return
+	}
+	index := newIdentityIndex(endpointList.Items, e.Datapath.ListLocalEndpoints(), e.Names, e.Config.LabelKeys)
+
+	records := e.newRecords(flows, time.Now())
+	for item := range records {
+		records[item].Source = index.lookup(records[item].SrcIP)
+		records[item].Destination = index.lookup(records[item].DstIP)
+	}
+	// write even if no records, so the pending records of the batching sinks are retried
+	if err := e.Sink.Write(records); err != nil {
+		klog.Errorf("unable write %d flow log records: %s", len(records), err)
+	}
+}
+
+// newRecords returns the records of the flows new or with counters increased since the
+// last export. Without conntrack accounting the counters are always zero, so only the
+// new flows are exported.
+func (e *Exporter) newRecords(flows []*netlink.ConntrackFlow, now time.Time) []Record {
+	var records []Record
+	counters := make(map[string]flowCounters, len(flows))
+
+	for _, flow := range flows {
+		if flow == nil {
+			continue
+		}
+		key := flowKey(flow)
+		current := flowCounters{
+			packets: flow.Forward.Packets + flow.Reverse.Packets,
+			bytes:   flow.Forward.Bytes + flow.Reverse.Bytes,
+		}
+		counters[key] = current
+
+		last, ok := e.counters[key]
+		if ok && current.packets <= last.packets {
+			continue
+		}
+		if ok {
+			current = flowCounters{packets: current.packets - last.packets, bytes: current.bytes - last.bytes}
+		}
+		records = append(records, newRecord(flow, current, now, e.AgentName))
+	}
+
+	e.counters = counters
+	return records
+}
+
+func listConntrackFlows() ([]*netlink.ConntrackFlow, error) {
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET)
+	if err != nil {
+		return nil, err
+	}
+	ipv6Flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, unix.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	return append(flows, ipv6Flows...), nil
+}
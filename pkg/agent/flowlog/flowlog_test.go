@@ -0,0 +1,264 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/namecache"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func newFlow(src, dst string, dstPort uint16, packets uint64) *netlink.ConntrackFlow {
+	return &netlink.ConntrackFlow{
+		Forward: netlink.IpTuple{
+			Protocol: unix.IPPROTO_TCP,
+			SrcIP:    net.ParseIP(src),
+			SrcPort:  34567,
+			DstIP:    net.ParseIP(dst),
+			DstPort:  dstPort,
+			Packets:  packets,
+			Bytes:    packets * 100,
+		},
+	}
+}
+
+func TestNewRecords(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now()
+	exporter := &Exporter{AgentName: "agent1"}
+	exporter.complete()
+
+	records := exporter.newRecords([]*netlink.ConntrackFlow{newFlow("10.0.0.1", "10.0.0.2", 80, 3)}, now)
+	Expect(records).Should(Equal([]Record{{
+		Time: now, Agent: "agent1", Protocol: "TCP",
+		SrcIP: "10.0.0.1", SrcPort: 34567, DstIP: "10.0.0.2", DstPort: 80,
+		Packets: 3, Bytes: 300,
+	}}))
+
+	// the flows without new packets are not exported
+	Expect(exporter.newRecords([]*netlink.ConntrackFlow{newFlow("10.0.0.1", "10.0.0.2", 80, 3)}, now)).Should(BeEmpty())
+
+	records = exporter.newRecords([]*netlink.ConntrackFlow{newFlow("10.0.0.1", "10.0.0.2", 80, 5)}, now)
+	Expect(records).Should(HaveLen(1))
+	Expect(records[0].Packets).Should(Equal(uint64(2)))
+	Expect(records[0].Bytes).Should(Equal(uint64(200)))
+}
+
+func TestIdentityIndex(t *testing.T) {
+	RegisterTestingT(t)
+
+	endpoints := []securityv1alpha1.Endpoint{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1", Labels: map[string]string{"app": "web", "tier": "frontend"}},
+			Spec: securityv1alpha1.EndpointSpec{
+				Reference: securityv1alpha1.EndpointReference{ExternalIDName: constants.PodEndpointExternalIDName, ExternalIDValue: "uid"},
+			},
+			Status: securityv1alpha1.EndpointStatus{IPs: []types.IPAddress{"10.0.0.1"}, Agents: []string{"agent1"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm1"},
+			Spec: securityv1alpha1.EndpointSpec{
+				ExtendLabels: map[string][]string{"app": {"db", "mysql"}},
+			},
+			Status: securityv1alpha1.EndpointStatus{IPs: []types.IPAddress{"10.0.0.2"}, Agents: []string{"agent1"}},
+		},
+	}
+	localEndpoints := []*datapath.Endpoint{
+		{InterfaceName: "tap2", IPAddr: net.ParseIP("10.0.0.2"), MacAddrStr: "00:00:00:00:00:02"},
+		{InterfaceName: "tap3", IPAddr: net.ParseIP("10.0.0.3"), MacAddrStr: "00:00:00:00:00:03"},
+	}
+	names := namecache.New()
+	names.Set("service/ns/svc1", map[string]namecache.Name{"10.96.0.10": {Kind: namecache.KindService, Namespace: "ns", Name: "svc1"}})
+
+	index := newIdentityIndex(endpoints, localEndpoints, names, []string{"app"})
+	Expect(index.lookup("10.0.0.1")).Should(Equal(&Identity{
+		Kind: namecache.KindPod, Namespace: "ns", Name: "pod1", Labels: map[string]string{"app": "web"},
+	}))
+	Expect(index.lookup("10.0.0.2")).Should(Equal(&Identity{
+		Kind: namecache.KindVM, Namespace: "ns", Name: "vm1", MAC: "00:00:00:00:00:02", Labels: map[string]string{"app": "db,mysql"},
+	}))
+	Expect(index.lookup("10.0.0.3")).Should(Equal(&Identity{
+		Kind: namecache.KindInterface, Name: "tap3", MAC: "00:00:00:00:00:03",
+	}))
+	Expect(index.lookup("10.96.0.10")).Should(Equal(&Identity{Kind: namecache.KindService, Namespace: "ns", Name: "svc1"}))
+	Expect(index.lookup("8.8.8.8")).Should(BeNil())
+
+	index = newIdentityIndex(endpoints, nil, nil, nil)
+	Expect(index.lookup("10.96.0.10")).Should(BeNil())
+}
+
+func TestFileSink(t *testing.T) {
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "flowlog.json")
+	sink := &FileSink{Path: path, MaxSize: 1}
+	record := Record{Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", Source: &Identity{Kind: namecache.KindPod, Name: "pod1"}}
+
+	Expect(sink.Write([]Record{record, record})).Should(Succeed())
+	Expect(readRecords(path)).Should(HaveLen(2))
+
+	// the file exceeds the max size is rotated
+	Expect(sink.Write([]Record{record})).Should(Succeed())
+	Expect(readRecords(path)).Should(Equal([]Record{record}))
+	Expect(readRecords(path + ".1")).Should(HaveLen(2))
+}
+
+type fakeWriter struct {
+	schemaErr error
+	failNext  int
+	written   []Record
+}
+
+func (w *fakeWriter) ensureSchema() error { return w.schemaErr }
+
+func (w *fakeWriter) writeBatch(records []Record) ([]Record, error) {
+	if w.failNext > 0 {
+		w.failNext--
+		return records, fmt.Errorf("storage unavailable")
+	}
+	w.written = append(w.written, records...)
+	return nil, nil
+}
+
+func TestBatchSink(t *testing.T) {
+	RegisterTestingT(t)
+
+	records := []Record{{SrcIP: "10.0.0.1"}, {SrcIP: "10.0.0.2"}, {SrcIP: "10.0.0.3"}}
+	writer := &fakeWriter{schemaErr: fmt.Errorf("storage unavailable")}
+	sink := newBatchSink(writer, 2, 4)
+
+	// the records are pending until the schema ensured
+	Expect(sink.Write(records[:1])).ShouldNot(Succeed())
+	writer.schemaErr = nil
+
+	// the failed batch is retried on the next write
+	writer.failNext = 1
+	Expect(sink.Write(records[1:])).ShouldNot(Succeed())
+	Expect(sink.pending).Should(Equal(records))
+	Expect(sink.Write(nil)).Should(Succeed())
+	Expect(writer.written).Should(Equal(records))
+	Expect(sink.pending).Should(BeEmpty())
+
+	// the oldest records are dropped when pending exceeds the max
+	writer.failNext = 10
+	Expect(sink.Write(records)).ShouldNot(Succeed())
+	Expect(sink.Write(records)).ShouldNot(Succeed())
+	Expect(sink.pending).Should(Equal([]Record{records[2], records[0], records[1], records[2]}))
+}
+
+type fakeStorage struct {
+	lock     sync.Mutex
+	requests []string
+	response string
+}
+
+func (s *fakeStorage) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = append(s.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.RequestURI(), body))
+	_, _ = w.Write([]byte(s.response))
+}
+
+func TestClickHouseSink(t *testing.T) {
+	RegisterTestingT(t)
+
+	storage := &fakeStorage{}
+	server := httptest.NewServer(storage)
+	defer server.Close()
+
+	sink, err := NewSink(Config{Sink: SinkConfig{Type: SinkTypeClickHouse, URL: server.URL, Retention: 48 * time.Hour}})
+	Expect(err).ShouldNot(HaveOccurred())
+	record := Record{
+		Time: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2",
+		Source: &Identity{Kind: namecache.KindPod, Namespace: "ns", Name: "pod1", Labels: map[string]string{"app": "web"}},
+	}
+	Expect(sink.Write([]Record{record})).Should(Succeed())
+
+	Expect(storage.requests).Should(HaveLen(4))
+	Expect(storage.requests[0]).Should(Equal("POST / CREATE DATABASE IF NOT EXISTS default"))
+	Expect(storage.requests[1]).Should(ContainSubstring("CREATE TABLE IF NOT EXISTS default.everoute_flowlogs"))
+	Expect(storage.requests[2]).Should(Equal("POST / ALTER TABLE default.everoute_flowlogs MODIFY TTL toDateTime(time) + INTERVAL 48 HOUR"))
+	Expect(storage.requests[3]).Should(HavePrefix("POST /?query=INSERT+INTO+default.everoute_flowlogs+FORMAT+JSONEachRow"))
+	Expect(storage.requests[3]).Should(ContainSubstring(`"time":"2021-01-02 03:04:05.000"`))
+	Expect(storage.requests[3]).Should(ContainSubstring(`"src_name":"pod1","src_mac":"","src_labels":"{\"app\":\"web\"}"`))
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	RegisterTestingT(t)
+
+	storage := &fakeStorage{}
+	server := httptest.NewServer(storage)
+	defer server.Close()
+
+	sink, err := NewSink(Config{Sink: SinkConfig{Type: SinkTypeElasticsearch, URL: server.URL, Retention: 24 * time.Hour}})
+	Expect(err).ShouldNot(HaveOccurred())
+	record := Record{Time: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2"}
+
+	storage.response = `{"errors":false,"items":[{"index":{"status":201}}]}`
+	Expect(sink.Write([]Record{record})).Should(Succeed())
+	Expect(storage.requests).Should(HaveLen(3))
+	Expect(storage.requests[0]).Should(HavePrefix("PUT /_ilm/policy/everoute-flowlogs-retention "))
+	Expect(storage.requests[0]).Should(ContainSubstring(`"min_age":"24h"`))
+	Expect(storage.requests[1]).Should(HavePrefix("PUT /_index_template/everoute-flowlogs "))
+	Expect(storage.requests[1]).Should(ContainSubstring(`"index.lifecycle.name":"everoute-flowlogs-retention"`))
+	Expect(storage.requests[2]).Should(HavePrefix(`POST /_bulk {"index":{"_index":"everoute-flowlogs-2021.01.02"}}`))
+
+	// only the records failed with the retryable status are retried
+	storage.response = `{"errors":true,"items":[{"index":{"status":429}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`
+	Expect(sink.Write([]Record{record, record})).ShouldNot(Succeed())
+	storage.response = `{"errors":false,"items":[{"index":{"status":201}}]}`
+	Expect(sink.Write(nil)).Should(Succeed())
+	Expect(strings.Count(storage.requests[len(storage.requests)-1], `"_index"`)).Should(Equal(1))
+}
+
+func readRecords(path string) []Record {
+	file, err := os.Open(path)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		Expect(json.Unmarshal(scanner.Bytes(), &record)).Should(Succeed())
+		records = append(records, record)
+	}
+	return records
+}
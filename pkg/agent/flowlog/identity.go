@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"net"
+	"strings"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/namecache"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// identityIndex is the identities of the ip addresses, built at each export from the
+// endpoints, the local endpoints learned on the agent and the name cache.
+type identityIndex struct {
+	endpoints map[string]*Identity
+	macs      map[string]string
+	names     *namecache.Cache
+}
+
+func newIdentityIndex(endpoints []securityv1alpha1.Endpoint, localEndpoints []*datapath.Endpoint,
+	names *namecache.Cache, labelKeys []string) *identityIndex {
+	index := &identityIndex{
+		endpoints: make(map[string]*Identity),
+		macs:      make(map[string]string),
+		names:     names,
+	}
+
+	for item := range endpoints {
+		identity := endpointIdentity(&endpoints[item], labelKeys)
+		for _, ip := range endpoints[item].Status.IPs {
+			if parsedIP := net.ParseIP(string(ip)); parsedIP != nil {
+				index.endpoints[parsedIP.String()] = identity
+			}
+		}
+	}
+
+	for _, ep := range localEndpoints {
+		for _, ip := range []net.IP{ep.IPAddr, ep.IPv6Addr} {
+			if ip == nil || ep.MacAddrStr == "" {
+				continue
+			}
+			index.macs[ip.String()] = ep.MacAddrStr
+			if _, ok := index.endpoints[ip.String()]; !ok {
+				// the ip learned before the endpoint status updated
+				index.endpoints[ip.String()] = &Identity{Kind: namecache.KindInterface, Name: ep.InterfaceName}
+			}
+		}
+	}
+
+	return index
+}
+
+// lookup returns the identity of the ip address, nil if unknown.
+func (index *identityIndex) lookup(ip string) *Identity {
+	if endpoint, ok := index.endpoints[ip]; ok {
+		identity := *endpoint
+		identity.MAC = index.macs[ip]
+		return &identity
+	}
+	if index.names == nil {
+		return nil
+	}
+	if name, ok := index.names.Lookup(net.ParseIP(ip)); ok {
+		return &Identity{Kind: name.Kind, Namespace: name.Namespace, Name: name.Name, MAC: index.macs[ip]}
+	}
+	return nil
+}
+
+// endpointIdentity returns the identity of the endpoint, only the labels of the keys
+// are included, the values of the extend labels are joined by comma.
+func endpointIdentity(ep *securityv1alpha1.Endpoint, labelKeys []string) *Identity {
+	identity := &Identity{
+		Kind:      namecache.KindVM,
+		Namespace: ep.Namespace,
+		Name:      ep.Name,
+	}
+	switch {
+	case ep.Spec.Reference.ExternalIDName == constants.PodEndpointExternalIDName:
+		identity.Kind = namecache.KindPod
+	case len(ep.Status.Agents) == 0:
+		identity.Kind = namecache.KindExternal
+	}
+
+	for _, key := range labelKeys {
+		value, ok := ep.Labels[key]
+		if values, extendOK := ep.Spec.ExtendLabels[key]; extendOK && !ok {
+			value, ok = strings.Join(values, ","), true
+		}
+		if !ok {
+			continue
+		}
+		if identity.Labels == nil {
+			identity.Labels = make(map[string]string)
+		}
+		identity.Labels[key] = value
+	}
+	return identity
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flowlog exports the connections tracked on the agent as flow log records.
+// The records carry the identity of the workloads on both sides, joined on the ip
+// addresses and the learned macs at export time, so the flow logs are usable in SIEM
+// without joining the endpoints externally.
+package flowlog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/everoute/everoute/pkg/agent/namecache"
+)
+
+// Record is a flow log record of a connection, the counters are the increments
+// since the last export.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Agent    string    `json:"agent"`
+	Protocol string    `json:"protocol"`
+	SrcIP    string    `json:"srcIP"`
+	SrcPort  uint16    `json:"srcPort,omitempty"`
+	DstIP    string    `json:"dstIP"`
+	DstPort  uint16    `json:"dstPort,omitempty"`
+	Packets  uint64    `json:"packets"`
+	Bytes    uint64    `json:"bytes"`
+
+	Source      *Identity `json:"source,omitempty"`
+	Destination *Identity `json:"destination,omitempty"`
+}
+
+// Identity is the identity of the workload owns an ip address.
+type Identity struct {
+	Kind      namecache.Kind `json:"kind"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	// MAC is the mac address of the local endpoint, only set when the ip address
+	// learned on the agent.
+	MAC string `json:"mac,omitempty"`
+	// Labels are the endpoint labels of the configured keys.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type flowCounters struct {
+	packets uint64
+	bytes   uint64
+}
+
+// flowKey returns the key of the conntrack flow, the flows in different zones are
+// different connections.
+func flowKey(flow *netlink.ConntrackFlow) string {
+	return fmt.Sprintf("%d/%d/%s/%d/%s/%d", flow.Zone, flow.Forward.Protocol,
+		flow.Forward.SrcIP, flow.Forward.SrcPort, flow.Forward.DstIP, flow.Forward.DstPort)
+}
+
+// newRecord returns the record of the conntrack flow, the counters of both
+// directions are summed.
+func newRecord(flow *netlink.ConntrackFlow, counters flowCounters, now time.Time, agent string) Record {
+	return Record{
+		Time:     now,
+		Agent:    agent,
+		Protocol: protocolName(flow.Forward.Protocol),
+		SrcIP:    flow.Forward.SrcIP.String(),
+		SrcPort:  flow.Forward.SrcPort,
+		DstIP:    flow.Forward.DstIP.String(),
+		DstPort:  flow.Forward.DstPort,
+		Packets:  counters.packets,
+		Bytes:    counters.bytes,
+	}
+}
+
+func protocolName(protocol uint8) string {
+	switch protocol {
+	case unix.IPPROTO_TCP:
+		return "TCP"
+	case unix.IPPROTO_UDP:
+		return "UDP"
+	case unix.IPPROTO_ICMP:
+		return "ICMP"
+	case unix.IPPROTO_ICMPV6:
+		return "ICMPv6"
+	case unix.IPPROTO_SCTP:
+		return "SCTP"
+	default:
+		return strconv.Itoa(int(protocol))
+	}
+}
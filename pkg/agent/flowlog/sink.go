@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const DefaultSinkTimeout = 30 * time.Second
+
+// Sink is where the flow log records written to.
+type Sink interface {
+	Write(records []Record) error
+}
+
+type SinkType string
+
+const (
+	SinkTypeFile          SinkType = "file"
+	SinkTypeClickHouse    SinkType = "clickhouse"
+	SinkTypeElasticsearch SinkType = "elasticsearch"
+)
+
+// SinkConfig defines the storage the records written to, except the file sink.
+type SinkConfig struct {
+	// Type is the type of the sink, defaults to file.
+	Type SinkType
+	// URL is the http api address, e.g. http://clickhouse:8123, http://elasticsearch:9200.
+	URL      string
+	Username string
+	Password string
+	// Database and Table are the ClickHouse table the records inserted into.
+	Database string
+	Table    string
+	// Index is the name prefix of the Elasticsearch daily indices.
+	Index string
+	// Retention is how long the records kept in the storage, zero means forever.
+	Retention time.Duration
+	// BatchSize is the max records written in a request.
+	BatchSize int
+	// MaxPending is the max records kept for retry when the storage unavailable.
+	MaxPending int
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+}
+
+// NewSink returns the sink of the config.
+func NewSink(config Config) (Sink, error) {
+	conf := config.Sink
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultSinkTimeout
+	}
+	client := httpClient{
+		client:   &http.Client{Timeout: conf.Timeout},
+		username: conf.Username,
+		password: conf.Password,
+	}
+	baseURL := strings.TrimSuffix(conf.URL, "/")
+
+	switch conf.Type {
+	case "", SinkTypeFile:
+		return &FileSink{Path: config.Path, MaxSize: config.MaxSize}, nil
+	case SinkTypeClickHouse:
+		if baseURL == "" {
+			return nil, fmt.Errorf("clickhouse sink url is required")
+		}
+		writer := &clickHouseWriter{
+			httpClient: client,
+			url:        baseURL,
+			database:   conf.Database,
+			table:      conf.Table,
+			retention:  conf.Retention,
+		}
+		if writer.database == "" {
+			writer.database = DefaultClickHouseDatabase
+		}
+		if writer.table == "" {
+			writer.table = DefaultClickHouseTable
+		}
+		return newBatchSink(writer, conf.BatchSize, conf.MaxPending), nil
+	case SinkTypeElasticsearch:
+		if baseURL == "" {
+			return nil, fmt.Errorf("elasticsearch sink url is required")
+		}
+		writer := &elasticsearchWriter{
+			httpClient: client,
+			url:        baseURL,
+			index:      conf.Index,
+			retention:  conf.Retention,
+		}
+		if writer.index == "" {
+			writer.index = DefaultElasticsearchIndex
+		}
+		return newBatchSink(writer, conf.BatchSize, conf.MaxPending), nil
+	default:
+		return nil, fmt.Errorf("unknown flow log sink type %s", conf.Type)
+	}
+}
+
+// FileSink writes the records to the file as json lines, the file is rotated to
+// Path.1 when it exceeds MaxSize, so the log collectors could tail it.
+type FileSink struct {
+	Path string
+	// MaxSize is the max bytes of the file before rotated, zero means never rotated.
+	MaxSize int64
+}
+
+func (s *FileSink) Write(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := s.rotate(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for item := range records {
+		if err := encoder.Encode(&records[item]); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func (s *FileSink) rotate() error {
+	if s.MaxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) || err == nil && info.Size() < s.MaxSize {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.Path, s.Path+".1")
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fqdn learns the addresses of the dns names from the dns responses snooped in the
+// datapath, so the policies could define the peers by the dns names. The addresses expire
+// with the ttl of the records, the policies reference the names are recomputed when the
+// addresses of the names changed. The addresses are only allowed for the endpoint which
+// resolved them.
+package fqdn
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is the addresses of the dns names resolved by each client with the expiration time,
+// it's thread safe. The addresses resolved by a client are never used for the others, so an
+// endpoint could not open the policies of the other endpoints by forging the responses.
+type Cache struct {
+	lock sync.RWMutex
+
+	// minTTL is the min time an address kept, avoid recomputing the policies frequently
+	// for the records with the short ttl.
+	minTTL time.Duration
+	// clients are the expiration time of the addresses of each name resolved by the client.
+	clients map[string]map[string]map[string]time.Time
+}
+
+func NewCache(minTTL time.Duration) *Cache {
+	return &Cache{
+		minTTL:  minTTL,
+		clients: make(map[string]map[string]map[string]time.Time),
+	}
+}
+
+// Add adds the address of the name resolved by the client expires after the ttl, returns true
+// if the address is new to the name of the client. The expiration time of a known address is
+// extended only.
+func (c *Cache) Add(client, name string, ip net.IP, ttl time.Duration, now time.Time) bool {
+	if ttl < c.minTTL {
+		ttl = c.minTTL
+	}
+	name, expire := normalizeName(name), now.Add(ttl)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	names, ok := c.clients[client]
+	if !ok {
+		names = make(map[string]map[string]time.Time)
+		c.clients[client] = names
+	}
+	addrs, ok := names[name]
+	if !ok {
+		addrs = make(map[string]time.Time)
+		names[name] = addrs
+	}
+	old, ok := addrs[ip.String()]
+	if !ok || old.Before(expire) {
+		addrs[ip.String()] = expire
+	}
+	return !ok
+}
+
+// Expire removes the addresses expired, returns the names which addresses removed.
+func (c *Cache) Expire(now time.Time) []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var expiredNames = make(map[string]struct{})
+	for client, names := range c.clients {
+		for name, addrs := range names {
+			for ip, expire := range addrs {
+				if !expire.After(now) {
+					delete(addrs, ip)
+					expiredNames[name] = struct{}{}
+				}
+			}
+			if len(addrs) == 0 {
+				delete(names, name)
+			}
+		}
+		if len(names) == 0 {
+			delete(c.clients, client)
+		}
+	}
+
+	var nameList = make([]string, 0, len(expiredNames))
+	for name := range expiredNames {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+	return nameList
+}
+
+// Clients returns the sorted clients have any address learned.
+func (c *Cache) Clients() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var clients = make([]string, 0, len(c.clients))
+	for client := range c.clients {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// Lookup returns the sorted addresses of the names matches the fqdn resolved by the client.
+func (c *Cache) Lookup(fqdn, client string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var ips = make(map[string]struct{})
+	for name, addrs := range c.clients[client] {
+		if !Match(fqdn, name) {
+			continue
+		}
+		for ip := range addrs {
+			ips[ip] = struct{}{}
+		}
+	}
+
+	var ipList = make([]string, 0, len(ips))
+	for ip := range ips {
+		ipList = append(ipList, ip)
+	}
+	sort.Strings(ipList)
+	return ipList
+}
+
+// Match returns true if the fqdn matches the name, the fqdn *.example.com matches all the
+// subdomains of example.com, but not example.com itself. The names are case-insensitive.
+func Match(fqdn, name string) bool {
+	fqdn, name = normalizeName(fqdn), normalizeName(name)
+	if suffix := strings.TrimPrefix(fqdn, "*"); suffix != fqdn {
+		return strings.HasSuffix(name, suffix) && len(name) > len(suffix)
+	}
+	return fqdn == name
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
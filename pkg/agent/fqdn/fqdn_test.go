@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fqdn
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+func TestMatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(Match("www.example.com", "www.example.com")).Should(BeTrue())
+	Expect(Match("www.example.com", "WWW.Example.com.")).Should(BeTrue())
+	Expect(Match("www.example.com", "api.example.com")).Should(BeFalse())
+	Expect(Match("*.example.com", "api.example.com")).Should(BeTrue())
+	Expect(Match("*.example.com", "a.b.example.com")).Should(BeTrue())
+	Expect(Match("*.example.com", "example.com")).Should(BeFalse())
+	Expect(Match("*.example.com", "badexample.com")).Should(BeFalse())
+}
+
+func TestCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now()
+	cache := NewCache(time.Minute)
+	Expect(cache.Add("10.1.0.1", "www.example.com", net.ParseIP("10.0.0.1"), time.Second, now)).Should(BeTrue())
+	Expect(cache.Add("10.1.0.1", "api.example.com", net.ParseIP("10.0.0.2"), time.Hour, now)).Should(BeTrue())
+	Expect(cache.Add("10.1.0.1", "API.example.com.", net.ParseIP("10.0.0.2"), time.Minute, now)).Should(BeFalse())
+	Expect(cache.Lookup("*.example.com", "10.1.0.1")).Should(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+	Expect(cache.Lookup("www.example.com", "10.1.0.1")).Should(Equal([]string{"10.0.0.1"}))
+	Expect(cache.Lookup("example.com", "10.1.0.1")).Should(BeEmpty())
+
+	// the ttl shorter than the minTTL is extended
+	Expect(cache.Expire(now.Add(time.Second))).Should(BeEmpty())
+	Expect(cache.Expire(now.Add(time.Minute))).Should(Equal([]string{"www.example.com"}))
+	Expect(cache.Lookup("*.example.com", "10.1.0.1")).Should(Equal([]string{"10.0.0.2"}))
+
+	// the expiration time is not shortened by the records with shorter ttl
+	Expect(cache.Expire(now.Add(30 * time.Minute))).Should(BeEmpty())
+	Expect(cache.Expire(now.Add(time.Hour))).Should(Equal([]string{"api.example.com"}))
+	Expect(cache.Lookup("*.example.com", "10.1.0.1")).Should(BeEmpty())
+	Expect(cache.Clients()).Should(BeEmpty())
+}
+
+func TestCacheClients(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now()
+	cache := NewCache(0)
+	Expect(cache.Add("10.1.0.2", "www.example.com", net.ParseIP("10.0.0.1"), time.Minute, now)).Should(BeTrue())
+	Expect(cache.Add("10.1.0.1", "www.example.com", net.ParseIP("10.0.0.1"), time.Minute, now)).Should(BeTrue())
+	Expect(cache.Add("10.1.0.1", "www.example.com", net.ParseIP("10.0.0.3"), time.Hour, now)).Should(BeTrue())
+	Expect(cache.Clients()).Should(Equal([]string{"10.1.0.1", "10.1.0.2"}))
+
+	// the addresses resolved by the other clients are never returned
+	Expect(cache.Lookup("www.example.com", "10.1.0.1")).Should(Equal([]string{"10.0.0.1", "10.0.0.3"}))
+	Expect(cache.Lookup("www.example.com", "10.1.0.2")).Should(Equal([]string{"10.0.0.1"}))
+	Expect(cache.Lookup("www.example.com", "10.1.0.3")).Should(BeEmpty())
+
+	Expect(cache.Expire(now.Add(time.Minute))).Should(Equal([]string{"www.example.com"}))
+	Expect(cache.Clients()).Should(Equal([]string{"10.1.0.1"}))
+}
+
+func TestSnooperLearn(t *testing.T) {
+	RegisterTestingT(t)
+
+	var changed [][]string
+	snooper := &Snooper{
+		Cache:    NewCache(0),
+		OnChange: func(names []string) { changed = append(changed, names) },
+	}
+	records := []datapath.DNSRecord{
+		{Client: net.ParseIP("10.1.0.1"), Name: "www.example.com", IP: net.ParseIP("10.0.0.1"), TTL: time.Minute},
+		{Client: net.ParseIP("10.1.0.1"), Name: "edge.cdn.net", IP: net.ParseIP("10.0.0.1"), TTL: time.Minute},
+	}
+
+	snooper.learn(records, time.Now())
+	Expect(changed).Should(Equal([][]string{{"edge.cdn.net", "www.example.com"}}))
+
+	// the known addresses refreshed without changes
+	snooper.learn(records, time.Now())
+	Expect(changed).Should(HaveLen(1))
+
+	// the addresses are learned for each client
+	records[0].Client = net.ParseIP("10.1.0.2")
+	snooper.learn(records[:1], time.Now())
+	Expect(changed).Should(Equal([][]string{{"edge.cdn.net", "www.example.com"}, {"www.example.com"}}))
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fqdn
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+const (
+	DefaultMinTTL = time.Minute
+	// expireInterval is the interval of removing the expired addresses.
+	expireInterval = 5 * time.Second
+)
+
+// Datapath is the datapath the dns records snooped from.
+type Datapath interface {
+	// DNSRecords returns the address records snooped from the dns responses.
+	DNSRecords() <-chan []datapath.DNSRecord
+}
+
+// Snooper learns the addresses of the names in the Cache from the dns records snooped,
+// and removes the addresses expired.
+type Snooper struct {
+	Cache    *Cache
+	Datapath Datapath
+	// OnChange is called with the names which addresses added or removed.
+	OnChange func(names []string)
+}
+
+func (s *Snooper) Run(stopChan <-chan struct{}) {
+	klog.Infof("start learning the addresses of the dns names")
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case records := <-s.Datapath.DNSRecords():
+			s.learn(records, time.Now())
+		case <-ticker.C:
+			s.notify(s.Cache.Expire(time.Now()))
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+func (s *Snooper) learn(records []datapath.DNSRecord, now time.Time) {
+	changed := sets.NewString()
+	for _, record := range records {
+		if s.Cache.Add(record.Client.String(), record.Name, record.IP, record.TTL, now) {
+			klog.V(2).Infof("learned address %s of %s resolved by %s, ttl %s", record.IP, record.Name, record.Client, record.TTL)
+			changed.Insert(record.Name)
+		}
+	}
+	s.notify(changed.List())
+}
+
+func (s *Snooper) notify(names []string) {
+	if len(names) != 0 && s.OnChange != nil {
+		s.OnChange(names)
+	}
+}
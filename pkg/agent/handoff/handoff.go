@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package handoff implements the in-place upgrade of the agent. The running agent
+// serves its state on a local socket, the new agent on the same node receives the
+// state before initializing the datapath, adopts the flows installed by the old
+// agent and replaces them once its own flows installed. The old agent keeps updating
+// the datapath until the new agent takes over after its datapath initialized, so the
+// flows are never flushed during the rolling upgrade.
+package handoff
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// ProtocolVersion is the version of the handoff state, the state of other versions
+// is discarded and the new agent starts as a fresh start.
+const ProtocolVersion = 1
+
+const (
+	// DefaultDialTimeout is the timeout of connecting to the old agent.
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultIOTimeout is the timeout of sending or receiving the state.
+	DefaultIOTimeout = 30 * time.Second
+	// DefaultTakeOverTimeout is the timeout of the new agent taking over after the state
+	// received, the old agent keeps running if the new agent never takes over.
+	DefaultTakeOverTimeout = 5 * time.Minute
+)
+
+// State is the state of the agent handed off to the new agent.
+type State struct {
+	Version int `json:"version"`
+	// Rounds is the cookie round number of the flows in each vds, the new agent
+	// adopts the flows only when the round matches the previous round in ovsdb.
+	Rounds map[string]uint64 `json:"rounds"`
+	// Endpoints are the local endpoints with the learned ip addresses.
+	Endpoints []EndpointState `json:"endpoints,omitempty"`
+}
+
+// EndpointState is the learned state of a local endpoint, which could not be
+// recovered from ovsdb.
+type EndpointState struct {
+	InterfaceUUID string `json:"interfaceUUID"`
+	BridgeName    string `json:"bridgeName"`
+	PortNo        uint32 `json:"portNo"`
+	IPAddr        net.IP `json:"ipAddr,omitempty"`
+	IPv6Addr      net.IP `json:"ipv6Addr,omitempty"`
+}
+
+// Server serves the state of the agent to the new agent on the node.
+type Server struct {
+	SocketPath string
+	// Snapshot returns the current state of the agent.
+	Snapshot        func() *State
+	IOTimeout       time.Duration
+	TakeOverTimeout time.Duration
+
+	handedOff     chan struct{}
+	handedOffOnce sync.Once
+}
+
+func NewServer(socketPath string, snapshot func() *State) *Server {
+	return &Server{
+		SocketPath:      socketPath,
+		Snapshot:        snapshot,
+		IOTimeout:       DefaultIOTimeout,
+		TakeOverTimeout: DefaultTakeOverTimeout,
+		handedOff:       make(chan struct{}),
+	}
+}
+
+// HandedOff is closed after the new agent taken over, the agent must stop updating
+// the datapath from then on.
+func (s *Server) HandedOff() <-chan struct{} {
+	return s.handedOff
+}
+
+// Run listens on the socket and serves the state until stopChan closed or the
+// state handed off.
+func (s *Server) Run(stopChan <-chan struct{}) error {
+	// remove the socket of the previous agent, it has been handed off or not running
+	if err := os.Remove(s.SocketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove remaining sock file %s: %s", s.SocketPath, err)
+	}
+	listener, err := net.Listen("unix", s.SocketPath)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %s", s.SocketPath, err)
+	}
+
+	go func() {
+		select {
+		case <-stopChan:
+		case <-s.handedOff:
+		}
+		listener.Close()
+	}()
+
+	klog.Infof("handoff server is listening on %s", s.SocketPath)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-stopChan:
+				return nil
+			case <-s.handedOff:
+				return nil
+			default:
+				return fmt.Errorf("accept handoff connection: %s", err)
+			}
+		}
+		if err := s.serve(conn, stopChan); err != nil {
+			klog.Errorf("failed to hand off state: %s", err)
+			continue
+		}
+		klog.Infof("state handed off to the new agent")
+		s.handedOffOnce.Do(func() { close(s.handedOff) })
+	}
+}
+
+func (s *Server) serve(conn net.Conn, stopChan <-chan struct{}) error {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(s.IOTimeout))
+
+	state := s.Snapshot()
+	state.Version = ProtocolVersion
+	if err := json.NewEncoder(conn).Encode(state); err != nil {
+		return fmt.Errorf("send state: %s", err)
+	}
+
+	// the new agent acks after its datapath initialized, the agent keeps updating the
+	// datapath until then, and keeps running if the new agent exits before the ack
+	_ = conn.SetDeadline(time.Now().Add(s.TakeOverTimeout))
+	served := make(chan struct{})
+	defer close(served)
+	go func() {
+		select {
+		case <-stopChan:
+			conn.Close()
+		case <-served:
+		}
+	}()
+	var ack struct{}
+	if err := json.NewDecoder(conn).Decode(&ack); err != nil {
+		return fmt.Errorf("receive ack: %s", err)
+	}
+	return nil
+}
+
+// Handoff is the state received from the agent being replaced, the agent keeps running
+// until TakeOver called.
+type Handoff struct {
+	State *State
+
+	conn      net.Conn
+	ioTimeout time.Duration
+}
+
+// ReceiveState receives the state from the agent serving on the socket. It returns nil
+// without error when no agent serving, the agent should start fresh. The caller must
+// call TakeOver or Close on the handoff returned.
+func ReceiveState(socketPath string, dialTimeout, ioTimeout time.Duration) (*Handoff, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		klog.Infof("no agent to hand off from on %s: %s", socketPath, err)
+		return nil, nil
+	}
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	var state State
+	if err := json.NewDecoder(conn).Decode(&state); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("receive state: %s", err)
+	}
+	if state.Version != ProtocolVersion {
+		conn.Close()
+		return nil, fmt.Errorf("unsupported handoff protocol version %d, expect %d", state.Version, ProtocolVersion)
+	}
+	return &Handoff{State: &state, conn: conn, ioTimeout: ioTimeout}, nil
+}
+
+// TakeOver acks the agent being replaced to stop updating the datapath, it should be
+// called after the datapath of this agent initialized.
+func (h *Handoff) TakeOver() error {
+	defer h.conn.Close()
+	_ = h.conn.SetDeadline(time.Now().Add(h.ioTimeout))
+	if err := json.NewEncoder(h.conn).Encode(struct{}{}); err != nil {
+		return fmt.Errorf("send ack: %s", err)
+	}
+	return nil
+}
+
+// Close gives up taking over, the agent being replaced keeps running.
+func (h *Handoff) Close() error {
+	return h.conn.Close()
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handoff
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHandoff(t *testing.T) {
+	RegisterTestingT(t)
+
+	socketPath := filepath.Join(t.TempDir(), "handoff.sock")
+	expectState := &State{
+		Rounds: map[string]uint64{"vds1": 3},
+		Endpoints: []EndpointState{{
+			InterfaceUUID: "iface-1",
+			BridgeName:    "ovsbr1",
+			PortNo:        10,
+			IPAddr:        net.ParseIP("10.0.0.1").To4(),
+			IPv6Addr:      net.ParseIP("fd00::1"),
+		}},
+	}
+
+	t.Run("no agent to hand off from", func(t *testing.T) {
+		handoff, err := ReceiveState(socketPath, time.Second, time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(handoff).Should(BeNil())
+	})
+
+	t.Run("hand off state to the new agent", func(t *testing.T) {
+		stopChan := make(chan struct{})
+		defer close(stopChan)
+		server := NewServer(socketPath, func() *State { return expectState })
+		errChan := make(chan error, 1)
+		go func() { errChan <- server.Run(stopChan) }()
+
+		var handoff *Handoff
+		Eventually(func() *Handoff {
+			handoff, _ = ReceiveState(socketPath, time.Second, time.Second)
+			return handoff
+		}).ShouldNot(BeNil())
+		Expect(handoff.State.Version).Should(Equal(ProtocolVersion))
+		Expect(handoff.State.Rounds).Should(Equal(expectState.Rounds))
+		Expect(handoff.State.Endpoints).Should(Equal(expectState.Endpoints))
+
+		// the old agent keeps running until the new agent takes over
+		Consistently(server.HandedOff(), 500*time.Millisecond).ShouldNot(BeClosed())
+		Expect(handoff.TakeOver()).Should(Succeed())
+		Eventually(server.HandedOff()).Should(BeClosed())
+		Eventually(errChan).Should(Receive(BeNil()))
+	})
+
+	t.Run("keep running if the new agent never takes over", func(t *testing.T) {
+		stopChan := make(chan struct{})
+		server := NewServer(socketPath, func() *State { return expectState })
+		errChan := make(chan error, 1)
+		go func() { errChan <- server.Run(stopChan) }()
+
+		var handoff *Handoff
+		Eventually(func() *Handoff {
+			handoff, _ = ReceiveState(socketPath, time.Second, time.Second)
+			return handoff
+		}).ShouldNot(BeNil())
+		Expect(handoff.Close()).Should(Succeed())
+		Consistently(server.HandedOff(), 500*time.Millisecond).ShouldNot(BeClosed())
+
+		// serve the next agent after the previous one exited
+		Eventually(func() *Handoff {
+			handoff, _ = ReceiveState(socketPath, time.Second, time.Second)
+			return handoff
+		}).ShouldNot(BeNil())
+		close(stopChan)
+		Eventually(errChan).Should(Receive(BeNil()))
+		Expect(handoff.TakeOver()).ShouldNot(Succeed())
+		Expect(server.HandedOff()).ShouldNot(BeClosed())
+	})
+
+	t.Run("discard state of unsupported version", func(t *testing.T) {
+		listener, err := net.Listen("unix", socketPath)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_ = json.NewEncoder(conn).Encode(&State{Version: ProtocolVersion + 1})
+		}()
+
+		handoff, err := ReceiveState(socketPath, time.Second, time.Second)
+		Expect(err).Should(HaveOccurred())
+		Expect(handoff).Should(BeNil())
+	})
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ifaceman manages the veth pairs of the containers, one end of the veth pair in the
+// netns of the container, the other end on the host attached to the ovs bridge.
+package ifaceman
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	cniv1 "github.com/containernetworking/cni/pkg/types/100"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultMTU is the mtu of the veth pair if not specified.
+	DefaultMTU = 1500
+
+	// ExternalIDAttachedMac is the external id of the mac address of the container end.
+	ExternalIDAttachedMac = "attached-mac"
+
+	// setupRetries is the times the veth pair created, the veth pair may conflict with the
+	// stale one which not deleted yet.
+	setupRetries  = 3
+	setupInterval = 100 * time.Millisecond
+)
+
+// OvsDriver attaches the host end of the veth pairs to the bridge, it is implemented by the
+// ovsdbDriver.OvsDriver of the bridge.
+type OvsDriver interface {
+	CreatePort(intfName, intfType string, vlanTag uint) error
+	DeletePort(intfName string) error
+	IsPortNamePresent(intfName string) bool
+	UpdateInterface(ifaceName string, externalIDs map[string]string) error
+}
+
+// Veth describes a veth pair of the container.
+type Veth struct {
+	// HostIfName is the name of the host end, also the name of the ovs port.
+	HostIfName string
+	// ContainerID is the id of the container the veth pair belongs to.
+	ContainerID string
+	// ContainerIfName is the name of the container end in the netns.
+	ContainerIfName string
+	// NetNS is the path of the netns of the container.
+	NetNS string
+	// MTU of the veth pair, DefaultMTU if zero.
+	MTU int
+	// ExternalIDs are set on the ovs interface of the host end, along with the
+	// ExternalIDAttachedMac.
+	ExternalIDs map[string]string
+}
+
+// HostIfName returns the name of the host end of the veth pair of the container, the prefix
+// of the container id.
+func HostIfName(containerID string) string {
+	return "_" + containerID[:12]
+}
+
+// linkOps is the operations on the links in the host netns and the container netns.
+type linkOps interface {
+	// setupVeth creates the veth pair in the container netns, moves the host end to the host
+	// netns, and configures the addresses and routes of the result on the container end.
+	setupVeth(veth *Veth, mtu int, result *cniv1.Result) (net.HardwareAddr, error)
+	// hostLinkExists returns true if the link exists in the host netns.
+	hostLinkExists(name string) (bool, error)
+	// deleteHostLink deletes the link in the host netns, the peer deleted along with it.
+	deleteHostLink(name string) error
+}
+
+// Manager creates, attaches and deletes the veth pairs. The steps done are rolled back if any
+// of the following steps failed, so a failed setup leaves nothing behind.
+type Manager struct {
+	ovsDriver OvsDriver
+	links     linkOps
+	// store records the veth pairs for the recovery, nil if not recorded.
+	store *Store
+}
+
+// NewManager creates a Manager attaching the veth pairs with the ovs driver, and recording
+// them in the store if not nil.
+func NewManager(ovsDriver OvsDriver, store *Store) *Manager {
+	return &Manager{
+		ovsDriver: ovsDriver,
+		links:     &netlinkOps{},
+		store:     store,
+	}
+}
+
+// Setup creates the veth pair, configures the container end with the result, and attaches
+// the host end to the bridge. The stale host end left by the former setup is deleted before
+// created. Returns the mac address of the container end.
+func (m *Manager) Setup(veth *Veth, result *cniv1.Result) (net.HardwareAddr, error) {
+	mtu := veth.MTU
+	if mtu == 0 {
+		mtu = DefaultMTU
+	}
+
+	// record before setup, the veth pair left by the agent crashed is cleaned up by the recovery
+	record := &Record{
+		HostIfName:  veth.HostIfName,
+		ContainerID: veth.ContainerID,
+		ExternalIDs: veth.ExternalIDs,
+		CreatedAt:   time.Now(),
+	}
+	if err := m.saveRecord(record); err != nil {
+		return nil, fmt.Errorf("record veth %s: %s", veth.HostIfName, err)
+	}
+
+	var mac net.HardwareAddr
+	var err error
+	for i := 0; i < setupRetries; i++ {
+		if i != 0 {
+			klog.Warningf("retry to create veth %s after failed: %s", veth.HostIfName, err)
+			time.Sleep(setupInterval)
+		}
+		if err = m.removeStaleLink(veth.HostIfName); err != nil {
+			continue
+		}
+		if mac, err = m.links.setupVeth(veth, mtu, result); err == nil {
+			break
+		}
+		// the veth pair may be created before the addresses configure failed
+		m.rollbackLink(veth.HostIfName)
+	}
+	if err != nil {
+		m.rollbackRecord(veth.HostIfName)
+		return nil, fmt.Errorf("create veth %s: %s", veth.HostIfName, err)
+	}
+
+	externalIDs := make(map[string]string, len(veth.ExternalIDs)+1)
+	for key, value := range veth.ExternalIDs {
+		externalIDs[key] = value
+	}
+	externalIDs[ExternalIDAttachedMac] = mac.String()
+	record.ExternalIDs = externalIDs
+	if err = m.saveRecord(record); err == nil {
+		err = m.Attach(veth.HostIfName, externalIDs)
+	}
+	if err != nil {
+		m.rollbackLink(veth.HostIfName)
+		m.rollbackRecord(veth.HostIfName)
+		return nil, err
+	}
+	return mac, nil
+}
+
+// Attach attaches the host end to the bridge and sets the external ids of the interface. It
+// is idempotent, the port kept in ovsdb, e.g. after host reboot, is reused. The port created
+// is deleted if the external ids set failed.
+func (m *Manager) Attach(hostIfName string, externalIDs map[string]string) error {
+	created := false
+	if !m.ovsDriver.IsPortNamePresent(hostIfName) {
+		if err := m.ovsDriver.CreatePort(hostIfName, "", 0); err != nil {
+			return fmt.Errorf("add port %s to bridge: %s", hostIfName, err)
+		}
+		created = true
+	}
+
+	if err := m.ovsDriver.UpdateInterface(hostIfName, externalIDs); err != nil {
+		if created {
+			if delErr := m.ovsDriver.DeletePort(hostIfName); delErr != nil {
+				klog.Errorf("failed to rollback port %s: %s", hostIfName, delErr)
+			}
+		}
+		return fmt.Errorf("set external ids of %s: %s", hostIfName, err)
+	}
+	return nil
+}
+
+// Teardown detaches the host end from the bridge and deletes the veth pair, the port or the
+// link already deleted is ignored.
+func (m *Manager) Teardown(hostIfName string) error {
+	if m.ovsDriver.IsPortNamePresent(hostIfName) {
+		if err := m.ovsDriver.DeletePort(hostIfName); err != nil {
+			return fmt.Errorf("delete port %s from bridge: %s", hostIfName, err)
+		}
+	}
+	if err := m.removeStaleLink(hostIfName); err != nil {
+		return fmt.Errorf("delete veth %s: %s", hostIfName, err)
+	}
+	if m.store != nil {
+		if err := m.store.Delete(hostIfName); err != nil {
+			return fmt.Errorf("delete record of veth %s: %s", hostIfName, err)
+		}
+	}
+	return nil
+}
+
+// Recover reconciles the veth pairs recorded against the bridge, it should be called before
+// the local endpoints reported, e.g. after the host reboot the ovsdb may lose the ports or the
+// external ids. The veth pair still expected is re-attached with the external ids recorded,
+// the one not expected or the link lost, e.g. the container gone with the reboot, is torn down.
+// The expected could complete the external ids of the record before re-attached.
+func (m *Manager) Recover(expected func(record *Record) bool) error {
+	if m.store == nil {
+		return nil
+	}
+	records, err := m.store.List()
+	if err != nil {
+		return fmt.Errorf("list records: %s", err)
+	}
+
+	var errs []error
+	for _, record := range records {
+		exists, err := m.links.hostLinkExists(record.HostIfName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check veth %s: %s", record.HostIfName, err))
+			continue
+		}
+		if !exists || !expected(record) {
+			klog.Infof("teardown stale veth %s, link exists: %t", record.HostIfName, exists)
+			if err = m.Teardown(record.HostIfName); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err = m.Attach(record.HostIfName, record.ExternalIDs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CollectGarbage tears down the orphan veth pairs, e.g. the CNI DEL of the container missed,
+// the veth pair is orphan if the link lost with the netns of the container, or the container
+// not alive. The veth pair setup within the grace period is skipped, the container may not be
+// known by the alive yet. Returns the number of the veth pairs torn down.
+func (m *Manager) CollectGarbage(alive func(record *Record) bool, gracePeriod time.Duration) (int, error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	records, err := m.store.List()
+	if err != nil {
+		return 0, fmt.Errorf("list records: %s", err)
+	}
+
+	var collected int
+	var errs []error
+	for _, record := range records {
+		if !record.CreatedAt.IsZero() && time.Since(record.CreatedAt) < gracePeriod {
+			continue
+		}
+		exists, err := m.links.hostLinkExists(record.HostIfName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check veth %s: %s", record.HostIfName, err))
+			continue
+		}
+		if exists && alive(record) {
+			continue
+		}
+		klog.Infof("teardown orphan veth %s of container %s, link exists: %t", record.HostIfName, record.ContainerID, exists)
+		if err = m.Teardown(record.HostIfName); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		collected++
+	}
+	return collected, utilerrors.NewAggregate(errs)
+}
+
+// removeStaleLink deletes the link if exists.
+func (m *Manager) removeStaleLink(name string) error {
+	exists, err := m.links.hostLinkExists(name)
+	if err != nil || !exists {
+		return err
+	}
+	klog.Infof("delete stale veth %s", name)
+	return m.links.deleteHostLink(name)
+}
+
+func (m *Manager) rollbackLink(name string) {
+	if err := m.removeStaleLink(name); err != nil {
+		klog.Errorf("failed to rollback veth %s: %s", name, err)
+	}
+}
+
+func (m *Manager) saveRecord(record *Record) error {
+	if m.store == nil {
+		return nil
+	}
+	return m.store.Save(record)
+}
+
+func (m *Manager) rollbackRecord(name string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(name); err != nil {
+		klog.Errorf("failed to rollback record of veth %s: %s", name, err)
+	}
+}
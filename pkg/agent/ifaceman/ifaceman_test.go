@@ -0,0 +1,385 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ifaceman
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	cniv1 "github.com/containernetworking/cni/pkg/types/100"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var testMac, _ = net.ParseMAC("00:11:22:33:44:55")
+
+type fakeOvsDriver struct {
+	ports       map[string]map[string]string
+	createErr   error
+	updateErr   error
+	createCount int
+}
+
+func (d *fakeOvsDriver) CreatePort(intfName, intfType string, vlanTag uint) error {
+	d.createCount++
+	if d.createErr != nil {
+		return d.createErr
+	}
+	d.ports[intfName] = nil
+	return nil
+}
+
+func (d *fakeOvsDriver) DeletePort(intfName string) error {
+	delete(d.ports, intfName)
+	return nil
+}
+
+func (d *fakeOvsDriver) IsPortNamePresent(intfName string) bool {
+	_, ok := d.ports[intfName]
+	return ok
+}
+
+func (d *fakeOvsDriver) UpdateInterface(ifaceName string, externalIDs map[string]string) error {
+	if d.updateErr != nil {
+		return d.updateErr
+	}
+	d.ports[ifaceName] = externalIDs
+	return nil
+}
+
+type fakeLinkOps struct {
+	links sets.String
+	// setupErrs are returned by the setupVeth in order, the link is created before error
+	setupErrs []error
+}
+
+func (l *fakeLinkOps) setupVeth(veth *Veth, mtu int, result *cniv1.Result) (net.HardwareAddr, error) {
+	if l.links.Has(veth.HostIfName) {
+		return nil, fmt.Errorf("link %s already exists", veth.HostIfName)
+	}
+	l.links.Insert(veth.HostIfName)
+	if len(l.setupErrs) != 0 {
+		err := l.setupErrs[0]
+		l.setupErrs = l.setupErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return testMac, nil
+}
+
+func (l *fakeLinkOps) hostLinkExists(name string) (bool, error) {
+	return l.links.Has(name), nil
+}
+
+func (l *fakeLinkOps) deleteHostLink(name string) error {
+	l.links.Delete(name)
+	return nil
+}
+
+func newTestManager(ports map[string]map[string]string, links ...string) (*Manager, *fakeOvsDriver, *fakeLinkOps) {
+	if ports == nil {
+		ports = make(map[string]map[string]string)
+	}
+	ovsDriver := &fakeOvsDriver{ports: ports}
+	linkOps := &fakeLinkOps{links: sets.NewString(links...)}
+	return &Manager{ovsDriver: ovsDriver, links: linkOps}, ovsDriver, linkOps
+}
+
+func newTestVeth() *Veth {
+	return &Veth{
+		HostIfName:      "_container01",
+		ContainerIfName: "eth0",
+		NetNS:           "/var/run/netns/test",
+		ExternalIDs:     map[string]string{"pod-uuid": "pod-uuid"},
+	}
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("setup veth should attach with external ids", func(t *testing.T) {
+		m, ovsDriver, linkOps := newTestManager(nil)
+		mac, err := m.Setup(newTestVeth(), nil)
+		if err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if mac.String() != testMac.String() || !linkOps.links.Has("_container01") {
+			t.Fatalf("unexpect veth created, mac: %s, links: %v", mac, linkOps.links.List())
+		}
+		externalIDs := ovsDriver.ports["_container01"]
+		if externalIDs["pod-uuid"] != "pod-uuid" || externalIDs[ExternalIDAttachedMac] != testMac.String() {
+			t.Fatalf("unexpect external ids %v", externalIDs)
+		}
+	})
+
+	t.Run("setup veth should replace the stale veth", func(t *testing.T) {
+		m, _, linkOps := newTestManager(nil, "_container01")
+		if _, err := m.Setup(newTestVeth(), nil); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if !linkOps.links.Has("_container01") {
+			t.Fatalf("veth should be created")
+		}
+	})
+
+	t.Run("setup veth should retry after failed", func(t *testing.T) {
+		m, ovsDriver, linkOps := newTestManager(nil)
+		linkOps.setupErrs = []error{fmt.Errorf("device or resource busy")}
+		if _, err := m.Setup(newTestVeth(), nil); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if !linkOps.links.Has("_container01") || !ovsDriver.IsPortNamePresent("_container01") {
+			t.Fatalf("veth should be created and attached after retry")
+		}
+	})
+
+	t.Run("setup veth failed should rollback the veth", func(t *testing.T) {
+		m, ovsDriver, linkOps := newTestManager(nil)
+		for i := 0; i < setupRetries; i++ {
+			linkOps.setupErrs = append(linkOps.setupErrs, fmt.Errorf("configure address failed"))
+		}
+		if _, err := m.Setup(newTestVeth(), nil); err == nil {
+			t.Fatalf("expect error after retries")
+		}
+		if linkOps.links.Len() != 0 || ovsDriver.createCount != 0 {
+			t.Fatalf("veth should be rolled back, links: %v", linkOps.links.List())
+		}
+	})
+
+	t.Run("attach failed should rollback the port and the veth", func(t *testing.T) {
+		m, ovsDriver, linkOps := newTestManager(nil)
+		ovsDriver.updateErr = fmt.Errorf("ovsdb transact failed")
+		if _, err := m.Setup(newTestVeth(), nil); err == nil {
+			t.Fatalf("expect error when attach failed")
+		}
+		if linkOps.links.Len() != 0 || len(ovsDriver.ports) != 0 {
+			t.Fatalf("veth and port should be rolled back, links: %v, ports: %v", linkOps.links.List(), ovsDriver.ports)
+		}
+	})
+}
+
+func TestAttach(t *testing.T) {
+	t.Run("attach should reuse the existing port", func(t *testing.T) {
+		m, ovsDriver, _ := newTestManager(map[string]map[string]string{"_container01": nil}, "_container01")
+		if err := m.Attach("_container01", map[string]string{"pod-uuid": "pod-uuid"}); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if ovsDriver.createCount != 0 || ovsDriver.ports["_container01"]["pod-uuid"] != "pod-uuid" {
+			t.Fatalf("existing port should be reused and updated, ports: %v", ovsDriver.ports)
+		}
+	})
+
+	t.Run("attach failed should keep the existing port", func(t *testing.T) {
+		m, ovsDriver, _ := newTestManager(map[string]map[string]string{"_container01": nil}, "_container01")
+		ovsDriver.updateErr = fmt.Errorf("ovsdb transact failed")
+		if err := m.Attach("_container01", nil); err == nil {
+			t.Fatalf("expect error when update interface failed")
+		}
+		if !ovsDriver.IsPortNamePresent("_container01") {
+			t.Fatalf("existing port should not be deleted")
+		}
+	})
+}
+
+func TestTeardown(t *testing.T) {
+	m, ovsDriver, linkOps := newTestManager(map[string]map[string]string{"_container01": nil}, "_container01")
+	if err := m.Teardown("_container01"); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	if linkOps.links.Len() != 0 || len(ovsDriver.ports) != 0 {
+		t.Fatalf("veth and port should be deleted, links: %v, ports: %v", linkOps.links.List(), ovsDriver.ports)
+	}
+
+	// teardown is idempotent
+	if err := m.Teardown("_container01"); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir() + "/veth")
+	if records, err := store.List(); err != nil || len(records) != 0 {
+		t.Fatalf("unexpect records %v of empty store, err: %v", records, err)
+	}
+
+	record := &Record{HostIfName: "_container01", ExternalIDs: map[string]string{"pod-uuid": "pod-uuid"}}
+	if err := store.Save(record); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	records, err := store.List()
+	if err != nil || len(records) != 1 || !reflect.DeepEqual(records[0], record) {
+		t.Fatalf("unexpect records %v, err: %v", records, err)
+	}
+
+	if err = store.Delete("_container01"); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	// delete the record not exists is ignored
+	if err = store.Delete("_container01"); err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	if records, err = store.List(); err != nil || len(records) != 0 {
+		t.Fatalf("unexpect records %v after deleted, err: %v", records, err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	newRecoverManager := func(t *testing.T, records ...*Record) (*Manager, *fakeOvsDriver, *fakeLinkOps) {
+		m, ovsDriver, linkOps := newTestManager(nil)
+		m.store = NewStore(t.TempDir())
+		for _, record := range records {
+			if err := m.store.Save(record); err != nil {
+				t.Fatalf("unexpect error: %s", err)
+			}
+		}
+		return m, ovsDriver, linkOps
+	}
+	expectAll := func(*Record) bool { return true }
+	record := &Record{HostIfName: "_container01", ExternalIDs: map[string]string{"pod-uuid": "pod-uuid", ExternalIDAttachedMac: testMac.String()}}
+
+	t.Run("setup and teardown should maintain the record", func(t *testing.T) {
+		m, _, _ := newRecoverManager(t)
+		if _, err := m.Setup(newTestVeth(), nil); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if records, _ := m.store.List(); len(records) != 1 || records[0].ExternalIDs[ExternalIDAttachedMac] != testMac.String() {
+			t.Fatalf("unexpect records %v after setup", records)
+		}
+		if err := m.Teardown("_container01"); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if records, _ := m.store.List(); len(records) != 0 {
+			t.Fatalf("unexpect records %v after teardown", records)
+		}
+	})
+
+	t.Run("setup failed should rollback the record", func(t *testing.T) {
+		m, ovsDriver, _ := newRecoverManager(t)
+		ovsDriver.updateErr = fmt.Errorf("ovsdb transact failed")
+		if _, err := m.Setup(newTestVeth(), nil); err == nil {
+			t.Fatalf("expect error when attach failed")
+		}
+		if records, _ := m.store.List(); len(records) != 0 {
+			t.Fatalf("unexpect records %v after setup failed", records)
+		}
+	})
+
+	t.Run("recover should recreate the port and the external ids", func(t *testing.T) {
+		m, ovsDriver, linkOps := newRecoverManager(t, record)
+		linkOps.links.Insert("_container01")
+		if err := m.Recover(expectAll); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if !reflect.DeepEqual(ovsDriver.ports["_container01"], record.ExternalIDs) {
+			t.Fatalf("unexpect external ids %v after recover", ovsDriver.ports["_container01"])
+		}
+	})
+
+	t.Run("recover should restore the external ids of the existing port", func(t *testing.T) {
+		m, ovsDriver, linkOps := newRecoverManager(t, record)
+		linkOps.links.Insert("_container01")
+		ovsDriver.ports["_container01"] = nil
+		if err := m.Recover(expectAll); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if ovsDriver.createCount != 0 || !reflect.DeepEqual(ovsDriver.ports["_container01"], record.ExternalIDs) {
+			t.Fatalf("unexpect external ids %v after recover", ovsDriver.ports["_container01"])
+		}
+	})
+
+	t.Run("recover should teardown the veth lost", func(t *testing.T) {
+		m, ovsDriver, _ := newRecoverManager(t, record)
+		ovsDriver.ports["_container01"] = nil
+		if err := m.Recover(expectAll); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if records, _ := m.store.List(); len(records) != 0 || len(ovsDriver.ports) != 0 {
+			t.Fatalf("stale veth should be torn down, records: %v, ports: %v", records, ovsDriver.ports)
+		}
+	})
+
+	t.Run("recover should teardown the veth not expected", func(t *testing.T) {
+		m, ovsDriver, linkOps := newRecoverManager(t, record)
+		linkOps.links.Insert("_container01")
+		if err := m.Recover(func(*Record) bool { return false }); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if records, _ := m.store.List(); len(records) != 0 || len(ovsDriver.ports) != 0 || linkOps.links.Len() != 0 {
+			t.Fatalf("veth not expected should be torn down, records: %v, ports: %v", records, ovsDriver.ports)
+		}
+	})
+}
+
+func TestCollectGarbage(t *testing.T) {
+	newRecord := func(name string, createdAt time.Time) *Record {
+		return &Record{HostIfName: name, ContainerID: name, ExternalIDs: map[string]string{"pod-uuid": name}, CreatedAt: createdAt}
+	}
+	alive := func(names ...string) func(*Record) bool {
+		return func(record *Record) bool { return sets.NewString(names...).Has(record.HostIfName) }
+	}
+	expired := time.Now().Add(-time.Hour)
+
+	m, ovsDriver, linkOps := newTestManager(nil)
+	m.store = NewStore(t.TempDir())
+	for _, record := range []*Record{
+		newRecord("_alive", expired),
+		newRecord("_linklost", expired),
+		newRecord("_podgone", expired),
+		newRecord("_recent", time.Now()),
+	} {
+		if err := m.store.Save(record); err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		ovsDriver.ports[record.HostIfName] = record.ExternalIDs
+		if record.HostIfName != "_linklost" {
+			linkOps.links.Insert(record.HostIfName)
+		}
+	}
+
+	collected, err := m.CollectGarbage(alive("_alive", "_linklost"), time.Minute)
+	if err != nil {
+		t.Fatalf("unexpect error: %s", err)
+	}
+	if collected != 2 {
+		t.Fatalf("expect 2 orphan veths collected, got %d", collected)
+	}
+	// the recent one is skipped within the grace period, though the pod not alive
+	for _, name := range []string{"_alive", "_recent"} {
+		if !ovsDriver.IsPortNamePresent(name) || !linkOps.links.Has(name) {
+			t.Fatalf("veth %s should not be collected", name)
+		}
+	}
+	records, _ := m.store.List()
+	if len(records) != 2 || len(ovsDriver.ports) != 2 {
+		t.Fatalf("orphan veths should be torn down, records: %v, ports: %v", records, ovsDriver.ports)
+	}
+}
+
+func TestRecordContainerID(t *testing.T) {
+	containerID := "4dd2e4ba7e1d7b0f3e0c7e0c3a1c3c2a"
+	if name := HostIfName(containerID); name != "_4dd2e4ba7e1d" {
+		t.Fatalf("unexpect host interface name %s", name)
+	}
+	if id := (&Record{HostIfName: HostIfName(containerID), ContainerID: containerID}).GetContainerID(); id != containerID {
+		t.Fatalf("unexpect container id %s", id)
+	}
+	// the record of the former version has no container id
+	if id := (&Record{HostIfName: HostIfName(containerID)}).GetContainerID(); id != "4dd2e4ba7e1d" {
+		t.Fatalf("unexpect container id %s of the record without container id", id)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ifaceman
+
+import (
+	"net"
+
+	cniv1 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+)
+
+// netlinkOps operates the links with netlink.
+type netlinkOps struct{}
+
+func (*netlinkOps) setupVeth(veth *Veth, mtu int, result *cniv1.Result) (net.HardwareAddr, error) {
+	var mac net.HardwareAddr
+	err := ns.WithNetNSPath(veth.NetNS, func(hostNS ns.NetNS) error {
+		_, containerVeth, err := ip.SetupVethWithName(veth.ContainerIfName, veth.HostIfName, mtu, "", hostNS)
+		if err != nil {
+			return err
+		}
+		mac = containerVeth.HardwareAddr
+		if result == nil {
+			return nil
+		}
+		return ipam.ConfigureIface(veth.ContainerIfName, result)
+	})
+	return mac, err
+}
+
+func (*netlinkOps) hostLinkExists(name string) (bool, error) {
+	_, err := netlink.LinkByName(name)
+	if _, ok := err.(netlink.LinkNotFoundError); ok {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (*netlinkOps) deleteHostLink(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+	return netlink.LinkDel(link)
+}
+
+// CreateVethPair creates a veth pair in the current netns.
+func CreateVethPair(vethName, peerName string, mtu int) error {
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: vethName, MTU: mtu, TxQLen: 0},
+		PeerName:  peerName,
+	}
+	return netlink.LinkAdd(veth)
+}
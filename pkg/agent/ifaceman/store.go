@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ifaceman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const recordFileSuffix = ".json"
+
+// Record is the veth pair attached to the bridge, persisted so the port and the external ids
+// could be restored after the host reboot, which the ovsdb may lose.
+type Record struct {
+	HostIfName  string            `json:"hostIfName"`
+	ContainerID string            `json:"containerID,omitempty"`
+	ExternalIDs map[string]string `json:"externalIDs"`
+	// CreatedAt is when the veth pair setup, zero for the record of the former version.
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
+// GetContainerID returns the id of the container, the prefix of it in the name of the veth if
+// not recorded.
+func (r *Record) GetContainerID() string {
+	if r.ContainerID != "" {
+		return r.ContainerID
+	}
+	return strings.TrimPrefix(r.HostIfName, "_")
+}
+
+// Store persists the records in the directory, one file for each veth pair.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a Store persisting the records in the directory.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save creates or replaces the record of the veth pair.
+func (s *Store) Save(record *Record) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	// write to the temp file then rename, a half written record is never read
+	tmpFile := s.recordFile(record.HostIfName) + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, s.recordFile(record.HostIfName))
+}
+
+// Delete deletes the record of the veth pair, the record not exists is ignored.
+func (s *Store) Delete(hostIfName string) error {
+	err := os.Remove(s.recordFile(hostIfName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// List returns all the records in the store.
+func (s *Store) List() ([]*Record, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), recordFileSuffix) {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		record := &Record{}
+		if err = json.Unmarshal(raw, record); err != nil {
+			return nil, fmt.Errorf("parse record %s: %s", file.Name(), err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (s *Store) recordFile(hostIfName string) string {
+	return filepath.Join(s.dir, hostIfName+recordFileSuffix)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// getConntrackFlow gets the ipv4 connection by the reply tuple in the conntrack zone, instead
+// of dumping the whole table. The tuples, the zone and the labels of the flow returned are
+// parsed only, nil if the connection not found.
+func getConntrackFlow(zone uint16, reply netlink.IpTuple) (*netlink.ConntrackFlow, error) {
+	req := nl.NewNetlinkRequest((int(netlink.ConntrackTable)<<8)|nl.IPCTNL_MSG_CT_GET, 0)
+	req.AddData(&nl.Nfgenmsg{NfgenFamily: unix.AF_INET, Version: nl.NFNETLINK_V0})
+	req.AddData(newTupleAttr(nl.CTA_TUPLE_REPLY, reply))
+	req.AddData(nl.NewRtAttr(nl.CTA_ZONE, bigEndianUint16(zone)))
+
+	msgs, err := req.Execute(unix.NETLINK_NETFILTER, 0)
+	if errors.Is(err, unix.ENOENT) || (err == nil && len(msgs) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseConntrackFlow(msgs[0])
+}
+
+// newTupleAttr returns the nested attribute of the ipv4 tuple.
+func newTupleAttr(attrType int, tuple netlink.IpTuple) *nl.RtAttr {
+	attr := nl.NewRtAttr(attrType|int(nl.NLA_F_NESTED), nil)
+	ip := attr.AddRtAttr(nl.CTA_TUPLE_IP|int(nl.NLA_F_NESTED), nil)
+	ip.AddRtAttr(nl.CTA_IP_V4_SRC, tuple.SrcIP.To4())
+	ip.AddRtAttr(nl.CTA_IP_V4_DST, tuple.DstIP.To4())
+	proto := attr.AddRtAttr(nl.CTA_TUPLE_PROTO|int(nl.NLA_F_NESTED), nil)
+	proto.AddRtAttr(nl.CTA_PROTO_NUM, nl.Uint8Attr(tuple.Protocol))
+	proto.AddRtAttr(nl.CTA_PROTO_SRC_PORT, bigEndianUint16(tuple.SrcPort))
+	proto.AddRtAttr(nl.CTA_PROTO_DST_PORT, bigEndianUint16(tuple.DstPort))
+	return attr
+}
+
+// parseConntrackFlow parses the conntrack message starts with the nfgenmsg.
+func parseConntrackFlow(msg []byte) (*netlink.ConntrackFlow, error) {
+	if len(msg) < nl.SizeofNfgenmsg {
+		return nil, fmt.Errorf("conntrack message too short: %d bytes", len(msg))
+	}
+	attrs, err := nl.ParseRouteAttr(msg[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return nil, err
+	}
+
+	flow := &netlink.ConntrackFlow{FamilyType: msg[0]}
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case nl.CTA_TUPLE_ORIG:
+			err = parseTuple(attr.Value, &flow.Forward)
+		case nl.CTA_TUPLE_REPLY:
+			err = parseTuple(attr.Value, &flow.Reverse)
+		case nl.CTA_ZONE:
+			if len(attr.Value) >= 2 {
+				flow.Zone = binary.BigEndian.Uint16(attr.Value)
+			}
+		case nl.CTA_LABELS:
+			flow.Labels = append([]byte(nil), attr.Value...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return flow, nil
+}
+
+func parseTuple(b []byte, tuple *netlink.IpTuple) error {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		attrType := attr.Attr.Type & nl.NLA_TYPE_MASK
+		if attrType != nl.CTA_TUPLE_IP && attrType != nl.CTA_TUPLE_PROTO {
+			continue
+		}
+		nested, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return err
+		}
+		switch attrType {
+		case nl.CTA_TUPLE_IP:
+			for _, a := range nested {
+				switch a.Attr.Type & nl.NLA_TYPE_MASK {
+				case nl.CTA_IP_V4_SRC:
+					tuple.SrcIP = append(net.IP(nil), a.Value...)
+				case nl.CTA_IP_V4_DST:
+					tuple.DstIP = append(net.IP(nil), a.Value...)
+				}
+			}
+		case nl.CTA_TUPLE_PROTO:
+			for _, a := range nested {
+				switch a.Attr.Type & nl.NLA_TYPE_MASK {
+				case nl.CTA_PROTO_NUM:
+					if len(a.Value) >= 1 {
+						tuple.Protocol = a.Value[0]
+					}
+				case nl.CTA_PROTO_SRC_PORT:
+					if len(a.Value) >= 2 {
+						tuple.SrcPort = binary.BigEndian.Uint16(a.Value)
+					}
+				case nl.CTA_PROTO_DST_PORT:
+					if len(a.Value) >= 2 {
+						tuple.DstPort = binary.BigEndian.Uint16(a.Value)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func bigEndianUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const forbiddenBody = "request denied by everoute policy\n"
+
+// proxyHTTP checks each request of the client, forwards the requests matched to the upstream,
+// and responds 403 to the request not matched or with the path not clean then closes the
+// connection.
+func proxyHTTP(client *idleConn, reader *bufio.Reader, m matcher, dial func() (net.Conn, error)) {
+	var upstream net.Conn
+	var upstreamReader *bufio.Reader
+	defer func() {
+		if upstream != nil {
+			upstream.Close()
+		}
+	}()
+
+	for {
+		// wait the next request in the idle timeout, then read the header in the header timeout
+		if _, err := reader.Peek(1); err != nil {
+			return
+		}
+		client.setHeaderDeadline(time.Now().Add(headerTimeout))
+		req, err := http.ReadRequest(reader)
+		client.setHeaderDeadline(time.Time{})
+		if err != nil {
+			return
+		}
+		if !cleanPath(req.URL.Path) || !m.matchHTTP(req.Method, req.URL.Path) {
+			klog.V(2).Infof("l7 proxy deny request %s %s from %s", req.Method, req.URL.Path, client.RemoteAddr())
+			_ = forbidden(req).Write(client)
+			return
+		}
+
+		if upstream == nil {
+			if upstream, err = dial(); err != nil {
+				klog.Errorf("l7 proxy failed to connect upstream: %s", err)
+				return
+			}
+			upstreamReader = bufio.NewReader(upstream)
+		}
+		if err = req.Write(upstream); err != nil {
+			return
+		}
+		resp, err := http.ReadResponse(upstreamReader, req)
+		if err != nil {
+			return
+		}
+		err = resp.Write(client)
+		resp.Body.Close()
+		if err != nil || req.Close || resp.Close {
+			return
+		}
+		if resp.StatusCode == http.StatusSwitchingProtocols {
+			// the connection is no longer http, e.g. websocket
+			splice(client, reader, upstream, upstreamReader)
+			return
+		}
+	}
+}
+
+// cleanPath returns false if the path has dot segments or duplicate slashes, the upstream may
+// resolve the path different from the criteria matched. The encoded dot segments are decoded
+// in the path, so they are rejected too.
+func cleanPath(p string) bool {
+	if p == "" {
+		return false
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned == p
+}
+
+func forbidden(req *http.Request) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusForbidden,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Request:       req,
+		Header:        http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(forbiddenBody)),
+		ContentLength: int64(len(forbiddenBody)),
+		Close:         true,
+	}
+}
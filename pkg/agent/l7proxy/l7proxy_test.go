@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+var testMatcher = matcher{policycache.NewL7Match(&securityv1alpha1.L7Match{
+	HTTP: []securityv1alpha1.HTTPMatch{{Method: "GET", Path: "/api/v1/.*"}, {Method: "POST", Path: "/login"}, {Path: "/api/(v2"}},
+	SNI:  []string{"*.example.com"},
+})}
+
+func TestMatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(testMatcher.matchHTTP("GET", "/api/v1/users")).Should(BeTrue())
+	Expect(testMatcher.matchHTTP("GET", "/api/v2/users")).Should(BeFalse())
+	Expect(testMatcher.matchHTTP("GET", "/prefix/api/v1/users")).Should(BeFalse())
+	Expect(testMatcher.matchHTTP("POST", "/api/v1/users")).Should(BeFalse())
+	Expect(testMatcher.matchHTTP("POST", "/login")).Should(BeTrue())
+	Expect(testMatcher.matchHTTP("POST", "/login/admin")).Should(BeFalse())
+	Expect(testMatcher.matchHTTP("GET", "/api/(v2")).Should(BeFalse())
+	Expect(matcher{policycache.NewL7Match(&securityv1alpha1.L7Match{HTTP: []securityv1alpha1.HTTPMatch{{}}})}.matchHTTP("DELETE", "/")).Should(BeTrue())
+
+	Expect(testMatcher.matchSNI("www.example.com")).Should(BeTrue())
+	Expect(testMatcher.matchSNI("example.com")).Should(BeFalse())
+	Expect(testMatcher.matchSNI("")).Should(BeFalse())
+}
+
+func TestCleanPath(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(cleanPath("/api/v1/users")).Should(BeTrue())
+	Expect(cleanPath("/api/v1/")).Should(BeTrue())
+	Expect(cleanPath("/")).Should(BeTrue())
+	Expect(cleanPath("")).Should(BeFalse())
+	Expect(cleanPath("/api/v1/../admin")).Should(BeFalse())
+	Expect(cleanPath("/api/v1/./users")).Should(BeFalse())
+	Expect(cleanPath("/api//v1/users")).Should(BeFalse())
+
+	// the encoded dot segments are decoded in the url path
+	u, err := url.Parse("http://10.0.0.3/api/v1/%2e%2E/admin")
+	Expect(err).Should(BeNil())
+	Expect(cleanPath(u.Path)).Should(BeFalse())
+}
+
+func TestConntrackFlow(t *testing.T) {
+	RegisterTestingT(t)
+
+	forward := netlink.IpTuple{
+		Protocol: unix.IPPROTO_TCP,
+		SrcIP:    net.ParseIP("10.0.0.2").To4(),
+		SrcPort:  34567,
+		DstIP:    net.ParseIP("10.0.0.3").To4(),
+		DstPort:  80,
+	}
+	reverse := netlink.IpTuple{
+		Protocol: unix.IPPROTO_TCP,
+		SrcIP:    net.ParseIP("10.0.0.1").To4(),
+		SrcPort:  15001,
+		DstIP:    net.ParseIP("10.0.0.2").To4(),
+		DstPort:  34567,
+	}
+	labels := make([]byte, 16)
+	labels[15] = 0x1
+
+	msg := (&nl.Nfgenmsg{NfgenFamily: unix.AF_INET, Version: nl.NFNETLINK_V0}).Serialize()
+	msg = append(msg, newTupleAttr(nl.CTA_TUPLE_ORIG, forward).Serialize()...)
+	msg = append(msg, newTupleAttr(nl.CTA_TUPLE_REPLY, reverse).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.CTA_ZONE, bigEndianUint16(datapath.CTZoneForPolicy)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.CTA_LABELS, labels).Serialize()...)
+
+	flow, err := parseConntrackFlow(msg)
+	Expect(err).Should(BeNil())
+	Expect(flow.FamilyType).Should(Equal(uint8(unix.AF_INET)))
+	Expect(flow.Forward).Should(Equal(forward))
+	Expect(flow.Reverse).Should(Equal(reverse))
+	Expect(flow.Zone).Should(Equal(uint16(datapath.CTZoneForPolicy)))
+	Expect(flow.Labels).Should(Equal(labels))
+
+	_, err = parseConntrackFlow(msg[:2])
+	Expect(err).ShouldNot(BeNil())
+}
+
+func TestOriginalDestination(t *testing.T) {
+	RegisterTestingT(t)
+
+	// the labels of the connection committed by the work mode rule of round 2 and seq 0x123
+	labels := make([]byte, 16)
+	binary.LittleEndian.PutUint64(labels[0:8], 2|(0x123&0xF)<<60)
+	binary.LittleEndian.PutUint64(labels[8:16], 0x123>>4)
+	newFlow := func(zone uint16, labels []byte) *netlink.ConntrackFlow {
+		return &netlink.ConntrackFlow{
+			Zone: zone,
+			Forward: netlink.IpTuple{
+				Protocol: unix.IPPROTO_TCP,
+				SrcIP:    net.ParseIP("10.0.0.2"),
+				SrcPort:  34567,
+				DstIP:    net.ParseIP("10.0.0.3"),
+				DstPort:  80,
+			},
+			Labels: labels,
+		}
+	}
+
+	dst, flowID, ok := originalDestination(newFlow(datapath.CTZoneForPolicy, labels))
+	Expect(ok).Should(BeTrue())
+	Expect(dst.String()).Should(Equal("10.0.0.3:80"))
+	Expect(flowID).Should(Equal(uint64(2<<28 | 0x123)))
+
+	for _, flow := range []*netlink.ConntrackFlow{nil, newFlow(0, labels), newFlow(datapath.CTZoneForPolicy, nil)} {
+		_, _, ok = originalDestination(flow)
+		Expect(ok).Should(BeFalse())
+	}
+}
+
+func TestRuleIDOfReference(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(ruleIDOfReference("shop/api/normal/ingress.read-only-abcdef")).Should(Equal("shop/api/normal/ingress.read-only"))
+	Expect(ruleIDOfReference("/global/global/default-abcdef")).Should(Equal("/global/global/default"))
+}
+
+// startProxy starts proxying the client conn returned, the upstream conn returned is dialed
+// by the proxy.
+func startProxy() (net.Conn, <-chan net.Conn, <-chan struct{}) {
+	client, proxyClient := net.Pipe()
+	upstreams := make(chan net.Conn, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer proxyClient.Close()
+		proxy(proxyClient, testMatcher, func() (net.Conn, error) {
+			upstream, proxyUpstream := net.Pipe()
+			upstreams <- upstream
+			return proxyUpstream, nil
+		})
+	}()
+	return client, upstreams, done
+}
+
+func TestProxyHTTP(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, upstreams, done := startProxy()
+	defer client.Close()
+	clientReader := bufio.NewReader(client)
+
+	// the request allowed is forwarded to the upstream
+	go func() {
+		req, _ := http.NewRequest("GET", "http://10.0.0.3/api/v1/users", nil)
+		_ = req.Write(client)
+	}()
+	upstream := <-upstreams
+	defer upstream.Close()
+	upstreamReader := bufio.NewReader(upstream)
+	req, err := http.ReadRequest(upstreamReader)
+	Expect(err).Should(BeNil())
+	Expect(req.URL.Path).Should(Equal("/api/v1/users"))
+	go func() {
+		_, _ = io.WriteString(upstream, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+	}()
+	resp, err := http.ReadResponse(clientReader, req)
+	Expect(err).Should(BeNil())
+	Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+	body, _ := io.ReadAll(resp.Body)
+	Expect(string(body)).Should(Equal("ok"))
+
+	// the request denied is responded 403 and the connection is closed
+	go func() {
+		req, _ := http.NewRequest("DELETE", "http://10.0.0.3/api/v1/users", nil)
+		_ = req.Write(client)
+	}()
+	resp, err = http.ReadResponse(clientReader, nil)
+	Expect(err).Should(BeNil())
+	Expect(resp.StatusCode).Should(Equal(http.StatusForbidden))
+	Expect(resp.Close).Should(BeTrue())
+	body, _ = io.ReadAll(resp.Body)
+	Expect(string(body)).Should(Equal(forbiddenBody))
+	Eventually(done).Should(BeClosed())
+}
+
+func TestProxyHTTPPathNotClean(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, upstreams, done := startProxy()
+	defer client.Close()
+
+	// the path matches the criteria literally, but resolves to /api/admin on the upstream
+	go func() {
+		_, _ = io.WriteString(client, "GET /api/v1/%2e%2e/admin HTTP/1.1\r\nHost: 10.0.0.3\r\n\r\n")
+	}()
+	resp, err := http.ReadResponse(bufio.NewReader(client), nil)
+	Expect(err).Should(BeNil())
+	Expect(resp.StatusCode).Should(Equal(http.StatusForbidden))
+	Eventually(done).Should(BeClosed())
+	Expect(upstreams).ShouldNot(Receive())
+}
+
+func TestProxyTLS(t *testing.T) {
+	RegisterTestingT(t)
+
+	clientHello := func(client net.Conn, serverName string) {
+		go func() {
+			_ = tls.Client(client, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}).Handshake()
+		}()
+	}
+
+	// the client hello with server name matched is replayed to the upstream
+	client, upstreams, done := startProxy()
+	clientHello(client, "www.example.com")
+	upstream := <-upstreams
+	serverName, err := readServerName(upstream)
+	Expect(err).Should(BeNil())
+	Expect(serverName).Should(Equal("www.example.com"))
+	upstream.Close()
+	client.Close()
+	Eventually(done).Should(BeClosed())
+
+	// the connection with server name not matched is closed without upstream
+	client, upstreams, done = startProxy()
+	clientHello(client, "www.example.org")
+	Eventually(done).Should(BeClosed())
+	Expect(upstreams).ShouldNot(Receive())
+	client.Close()
+}
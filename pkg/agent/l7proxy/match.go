@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/fqdn"
+)
+
+// matcher is the l7 criteria of the rules a connection matched, the traffic is allowed if
+// it matches any of the criteria.
+type matcher []*policycache.L7Match
+
+func (m matcher) matchHTTP(method, path string) bool {
+	for _, l7 := range m {
+		for _, match := range l7.HTTP {
+			if match.Method != "" && match.Method != method {
+				continue
+			}
+			if match.Path == "" {
+				return true
+			}
+			// the path failed to compile matches nothing
+			if re := l7.Paths[match.Path]; re != nil && re.MatchString(path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m matcher) matchSNI(serverName string) bool {
+	if serverName == "" {
+		return false
+	}
+	for _, l7 := range m {
+		for _, sni := range l7.SNI {
+			if fqdn.Match(sni, serverName) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package l7proxy is the proxy checks the application layer traffic with the l7 criteria of
+// the policy rules. The connections matching the rules with l7 criteria are redirected to the
+// proxy by the datapath, the proxy finds the original destination and the rules from the
+// conntrack entry of the connection, and forwards the http requests or the tls connections
+// matching the criteria to the original destination. The proxy connects to the original
+// destination from the agent, so it only works on the egress rules, the server sees the
+// address of the agent instead of the client.
+package l7proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+const (
+	// dialTimeout is the timeout of connecting the original destination.
+	dialTimeout = 10 * time.Second
+	// headerTimeout is the timeout of reading the http request header or the tls client hello.
+	headerTimeout = 10 * time.Second
+	// idleTimeout is the timeout of the client connection without any traffic.
+	idleTimeout = 5 * time.Minute
+)
+
+// Datapath is the datapath the rules of the flow ids installed on.
+type Datapath interface {
+	GetRuleReferencesByFlowID(flowID uint64) []string
+	// GetCTZones returns the policy conntrack zones the connections committed in.
+	GetCTZones() map[string]uint16
+}
+
+// Rules is the rules the l7 criteria looked up from.
+type Rules interface {
+	// GetL7Match returns the l7 criteria of the rule, nil if not found.
+	GetL7Match(ruleID string) *policycache.L7Match
+}
+
+type Config struct {
+	// Address is the tcp address the proxy listens on, e.g. 10.0.0.1:15001. The redirected
+	// traffic keeps its original destination mac address, so the address should be reachable
+	// through the same next hop as the original destination.
+	Address string
+}
+
+// Proxy forwards the redirected connections matching the l7 criteria to the original
+// destination, the connections to the original destination are initiated from the agent.
+type Proxy struct {
+	Config   Config
+	Datapath Datapath
+	Rules    Rules
+}
+
+func (p *Proxy) Run(stopChan <-chan struct{}) {
+	listener, err := net.Listen("tcp", p.Config.Address)
+	if err != nil {
+		klog.Fatalf("failed to bind l7 proxy on %s: %s", p.Config.Address, err)
+	}
+	go func() {
+		<-stopChan
+		listener.Close()
+	}()
+
+	klog.Infof("start l7 proxy on %s", p.Config.Address)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-stopChan:
+				return
+			default:
+			}
+			klog.Errorf("l7 proxy failed to accept connection: %s", err)
+			continue
+		}
+		go p.serve(conn)
+	}
+}
+
+func (p *Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	dst, m, err := p.lookup(conn)
+	if err != nil {
+		klog.Errorf("l7 proxy reject connection from %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+	proxy(conn, m, func() (net.Conn, error) {
+		return net.DialTimeout("tcp", dst.String(), dialTimeout)
+	})
+}
+
+// lookup returns the original destination and the l7 criteria of the rules of the connection.
+func (p *Proxy) lookup(conn net.Conn) (*net.TCPAddr, matcher, error) {
+	client, local := conn.RemoteAddr().(*net.TCPAddr), conn.LocalAddr().(*net.TCPAddr)
+	// the redirected connection is committed in the policy conntrack zone, the reply
+	// tuple is from the local address to the client
+	reply := netlink.IpTuple{
+		Protocol: unix.IPPROTO_TCP,
+		SrcIP:    local.IP,
+		SrcPort:  uint16(local.Port),
+		DstIP:    client.IP,
+		DstPort:  uint16(client.Port),
+	}
+
+	var dst *net.TCPAddr
+	var flowID uint64
+	var ok bool
+	for _, zone := range p.Datapath.GetCTZones() {
+		flow, err := getConntrackFlow(zone, reply)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get conntrack in zone %d: %s", zone, err)
+		}
+		if dst, flowID, ok = originalDestination(flow); ok {
+			break
+		}
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("no redirected connection found")
+	}
+
+	var m matcher
+	for _, reference := range p.Datapath.GetRuleReferencesByFlowID(flowID) {
+		if l7 := p.Rules.GetL7Match(ruleIDOfReference(reference)); l7 != nil {
+			m = append(m, l7)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil, fmt.Errorf("no l7 criteria of flow %d found", flowID)
+	}
+	return dst, m, nil
+}
+
+// originalDestination returns the original destination and the flow id of the rule of the
+// redirected tcp connection committed in the policy conntrack zone.
+func originalDestination(flow *netlink.ConntrackFlow) (*net.TCPAddr, uint64, bool) {
+	if flow == nil || !datapath.IsPolicyCTZone(flow.Zone) || flow.Forward.Protocol != unix.IPPROTO_TCP {
+		return nil, 0, false
+	}
+	if len(flow.Labels) != 16 {
+		return nil, 0, false
+	}
+	_, _, workFlowID := utils.CtLabelDecode(flow.Labels)
+	return &net.TCPAddr{IP: flow.Forward.DstIP, Port: int(flow.Forward.DstPort)}, workFlowID, true
+}
+
+// ruleIDOfReference returns the rule id of the rule reference, which is in format of
+// policyNamespace/policyName/policyType/ruleName-flowKey.
+func ruleIDOfReference(reference string) string {
+	if index := strings.LastIndex(reference, "-"); index > 0 {
+		return reference[:index]
+	}
+	return reference
+}
+
+// proxy checks the traffic of the client with the matcher, and forwards the traffic matched
+// to the upstream dialed. The tls connections are checked by the server name in the client
+// hello, the others are handled as http.
+func proxy(conn net.Conn, m matcher, dial func() (net.Conn, error)) {
+	client := &idleConn{Conn: conn}
+	reader := bufio.NewReader(client)
+	client.setHeaderDeadline(time.Now().Add(headerTimeout))
+	first, err := reader.Peek(1)
+	if err != nil {
+		return
+	}
+	if first[0] == recordTypeHandshake {
+		proxyTLS(client, reader, m, dial)
+		return
+	}
+	proxyHTTP(client, reader, m, dial)
+}
+
+// idleConn is the client conn closed if idle for the idle timeout, the traffic of both
+// directions extends the read deadline. The read deadline is at most the header deadline
+// if set, which limits the time of reading the header.
+type idleConn struct {
+	net.Conn
+	headerDeadline time.Time
+}
+
+// setHeaderDeadline sets the deadline of reading the header, zero means not limited.
+func (c *idleConn) setHeaderDeadline(deadline time.Time) {
+	c.headerDeadline = deadline
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.extendDeadline(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	if err := c.extendDeadline(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *idleConn) extendDeadline() error {
+	deadline := time.Now().Add(idleTimeout)
+	if !c.headerDeadline.IsZero() && c.headerDeadline.Before(deadline) {
+		deadline = c.headerDeadline
+	}
+	return c.Conn.SetReadDeadline(deadline)
+}
+
+// splice copies the traffic between the client and the upstream until either side closed.
+func splice(client io.Writer, clientReader io.Reader, upstream io.Writer, upstreamReader io.Reader) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, clientReader)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstreamReader)
+		done <- struct{}{}
+	}()
+	<-done
+}
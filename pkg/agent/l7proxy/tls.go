@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// recordTypeHandshake is the first byte of the tls handshake records.
+const recordTypeHandshake = 0x16
+
+var errClientHelloRead = errors.New("client hello read")
+
+// proxyTLS forwards the tls connection to the upstream if the server name in the client hello
+// matched, the connection is closed if not.
+func proxyTLS(client *idleConn, reader *bufio.Reader, m matcher, dial func() (net.Conn, error)) {
+	var hello bytes.Buffer
+	serverName, err := readServerName(io.TeeReader(reader, &hello))
+	client.setHeaderDeadline(time.Time{})
+	if err != nil {
+		klog.V(2).Infof("l7 proxy failed to read client hello from %s: %s", client.RemoteAddr(), err)
+		return
+	}
+	if !m.matchSNI(serverName) {
+		klog.V(2).Infof("l7 proxy deny tls connection to %s from %s", serverName, client.RemoteAddr())
+		return
+	}
+
+	upstream, err := dial()
+	if err != nil {
+		klog.Errorf("l7 proxy failed to connect upstream: %s", err)
+		return
+	}
+	defer upstream.Close()
+
+	// replay the client hello consumed
+	if _, err = upstream.Write(hello.Bytes()); err != nil {
+		return
+	}
+	splice(client, reader, upstream, upstream)
+}
+
+// readServerName reads the client hello from the reader, and returns the server name in it.
+func readServerName(reader io.Reader) (string, error) {
+	var serverName string
+	err := tls.Server(helloConn{reader: reader}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if !errors.Is(err, errClientHelloRead) {
+		return "", err
+	}
+	return serverName, nil
+}
+
+// helloConn is the conn the tls server reads the client hello from, the writes are dropped.
+type helloConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c helloConn) Read(b []byte) (int, error)  { return c.reader.Read(b) }
+func (c helloConn) Write(b []byte) (int, error) { return len(b), nil }
+func (c helloConn) Close() error                { return nil }
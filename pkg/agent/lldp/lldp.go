@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lldp read the lldp neighbors of the host interfaces from lldpd.
+package lldp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+// Neighbors return the lldp neighbor of each interface, keyed by the interface name.
+// It requires lldpd running on the host, the interfaces without neighbor are omitted.
+func Neighbors() (map[string]agentv1alpha1.LLDPNeighbor, error) {
+	out, err := exec.Command("lldpcli", "-f", "json0", "show", "neighbors").Output()
+	if err != nil {
+		return nil, fmt.Errorf("lldpcli show neighbors: %s", err)
+	}
+	return ParseNeighbors(out)
+}
+
+// lldpValue is a value in lldpcli json0 output, e.g. {"type": "mac", "value": "00:11:22:33:44:55"}
+type lldpValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type lldpNeighbors struct {
+	LLDP []struct {
+		Interface []struct {
+			Name    string `json:"name"`
+			Chassis []struct {
+				ID    []lldpValue `json:"id"`
+				Name  []lldpValue `json:"name"`
+				Descr []lldpValue `json:"descr"`
+			} `json:"chassis"`
+			Port []struct {
+				ID    []lldpValue `json:"id"`
+				Descr []lldpValue `json:"descr"`
+			} `json:"port"`
+		} `json:"interface"`
+	} `json:"lldp"`
+}
+
+// ParseNeighbors parse the output of "lldpcli -f json0 show neighbors", only the
+// first neighbor of each interface is returned.
+func ParseNeighbors(output []byte) (map[string]agentv1alpha1.LLDPNeighbor, error) {
+	var result lldpNeighbors
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("unexpected lldpcli output: %s", err)
+	}
+
+	neighbors := make(map[string]agentv1alpha1.LLDPNeighbor)
+	for _, lldp := range result.LLDP {
+		for _, iface := range lldp.Interface {
+			if _, ok := neighbors[iface.Name]; ok || iface.Name == "" {
+				continue
+			}
+			var neighbor agentv1alpha1.LLDPNeighbor
+			if len(iface.Chassis) != 0 {
+				neighbor.ChassisID = first(iface.Chassis[0].ID)
+				neighbor.SystemName = first(iface.Chassis[0].Name)
+				neighbor.SystemDescription = strings.TrimSpace(first(iface.Chassis[0].Descr))
+			}
+			if len(iface.Port) != 0 {
+				neighbor.PortID = first(iface.Port[0].ID)
+				neighbor.PortDescription = first(iface.Port[0].Descr)
+			}
+			neighbors[iface.Name] = neighbor
+		}
+	}
+
+	return neighbors, nil
+}
+
+func first(values []lldpValue) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0].Value
+}
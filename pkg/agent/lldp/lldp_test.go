@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lldp
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+const neighborsOutput = `{
+  "lldp": [{
+    "interface": [{
+      "name": "ens192",
+      "via": "LLDP",
+      "rid": "1",
+      "chassis": [{
+        "id": [{"type": "mac", "value": "00:11:22:33:44:55"}],
+        "name": [{"value": "tor-switch-01"}],
+        "descr": [{"value": "Cisco IOS Software \n"}]
+      }],
+      "port": [{
+        "id": [{"type": "ifname", "value": "Gi1/0/1"}],
+        "descr": [{"value": "GigabitEthernet1/0/1"}]
+      }]
+    }, {
+      "name": "ens224",
+      "via": "LLDP",
+      "chassis": [{"id": [{"type": "mac", "value": "00:11:22:33:44:66"}]}]
+    }]
+  }]
+}`
+
+func TestParseNeighbors(t *testing.T) {
+	RegisterTestingT(t)
+
+	neighbors, err := ParseNeighbors([]byte(neighborsOutput))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(neighbors).Should(HaveLen(2))
+	Expect(neighbors["ens192"]).Should(Equal(agentv1alpha1.LLDPNeighbor{
+		ChassisID:         "00:11:22:33:44:55",
+		SystemName:        "tor-switch-01",
+		SystemDescription: "Cisco IOS Software",
+		PortID:            "Gi1/0/1",
+		PortDescription:   "GigabitEthernet1/0/1",
+	}))
+	Expect(neighbors["ens224"]).Should(Equal(agentv1alpha1.LLDPNeighbor{ChassisID: "00:11:22:33:44:66"}))
+
+	neighbors, err = ParseNeighbors([]byte(`{"lldp": [{}]}`))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(neighbors).Should(BeEmpty())
+
+	_, err = ParseNeighbors([]byte(`lldpd not running`))
+	Expect(err).Should(HaveOccurred())
+}
@@ -0,0 +1,379 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mirror configures the sflow sampling and the port mirrors of the bridges in ovsdb.
+// The rows configured are marked in the external_ids, and removed when no longer configured.
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	// ManagedKey in the external_ids marks the sFlow and Mirror rows configured by the agent.
+	ManagedKey = "everoute-managed"
+	// BridgeKey in the external_ids is the bridge the row configured on.
+	BridgeKey = "everoute-bridge"
+
+	TableSFlow  = "sFlow"
+	TableMirror = "Mirror"
+
+	DefaultInterval = 30 * time.Second
+	DefaultSampling = 400
+	DefaultPolling  = 30
+	DefaultHeader   = 128
+)
+
+type Config struct {
+	// SFlow is the sflow sampling on the bridges.
+	SFlow SFlowConfig
+	// Mirrors are the port mirrors on the bridges.
+	Mirrors []MirrorConfig
+	// Interval is the period of the config reconcile, the rows are recreated in case of
+	// the bridges or the ports recreated.
+	Interval time.Duration
+}
+
+type SFlowConfig struct {
+	// Bridges enable sflow on.
+	Bridges []string
+	// Targets are the collectors, e.g. 10.0.0.1:6343, sflow disabled if empty.
+	Targets []string
+	// Agent is the interface the address of which used as the agent address, chosen by ovs if empty.
+	Agent string
+	// Sampling samples 1 in Sampling packets.
+	Sampling int
+	// Polling is the interval of the counters sent in seconds.
+	Polling int
+	// Header is the bytes of the packet header sampled.
+	Header int
+}
+
+type MirrorConfig struct {
+	// Name identifies the mirror.
+	Name   string
+	Bridge string
+	// SelectAll mirrors all the packets on the bridge.
+	SelectAll bool
+	// SelectSrcPorts mirrors the packets received on the ports.
+	SelectSrcPorts []string
+	// SelectDstPorts mirrors the packets sent from the ports.
+	SelectDstPorts []string
+	// SelectVLANs mirrors only the packets in the vlans, all vlans if empty.
+	SelectVLANs []int
+	// OutputPort is the port the mirrored packets sent to, exclusive with OutputVLAN.
+	OutputPort string
+	// OutputVLAN is the vlan the mirrored packets flooded in, exclusive with OutputPort.
+	OutputVLAN int
+}
+
+func (c *MirrorConfig) validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("mirror without name")
+	case c.Bridge == "":
+		return fmt.Errorf("mirror %s without bridge", c.Name)
+	case (c.OutputPort == "") == (c.OutputVLAN == 0):
+		return fmt.Errorf("mirror %s should output to either a port or a vlan", c.Name)
+	case !c.SelectAll && len(c.SelectSrcPorts) == 0 && len(c.SelectDstPorts) == 0:
+		return fmt.Errorf("mirror %s selects no packets", c.Name)
+	}
+	return nil
+}
+
+// managedRow is a row configured by the agent.
+type managedRow struct {
+	uuid   string
+	name   string
+	bridge string
+}
+
+// Manager keeps the sflow and the mirrors of the bridges as configured, the config reapplied
+// periodically in case of the bridges or the ports recreated.
+type Manager struct {
+	Config Config
+
+	// vsctl and listManaged replaced in testing
+	vsctl       func(args ...string) error
+	listManaged func(table string) ([]managedRow, error)
+}
+
+func (m *Manager) Run(stopChan <-chan struct{}) {
+	m.complete()
+
+	klog.Infof("start mirror manager with sflow on bridges %v and %d mirrors", m.Config.SFlow.Bridges, len(m.Config.Mirrors))
+	defer klog.Infof("shutting down mirror manager")
+
+	wait.Until(m.syncOnce, m.Config.Interval, stopChan)
+}
+
+func (m *Manager) complete() {
+	if m.Config.Interval <= 0 {
+		m.Config.Interval = DefaultInterval
+	}
+	if m.Config.SFlow.Sampling <= 0 {
+		m.Config.SFlow.Sampling = DefaultSampling
+	}
+	if m.Config.SFlow.Polling <= 0 {
+		m.Config.SFlow.Polling = DefaultPolling
+	}
+	if m.Config.SFlow.Header <= 0 {
+		m.Config.SFlow.Header = DefaultHeader
+	}
+	if m.vsctl == nil {
+		m.vsctl = vsctl
+	}
+	if m.listManaged == nil {
+		m.listManaged = listManaged
+	}
+}
+
+func (m *Manager) syncOnce() {
+	m.syncSFlow()
+	m.syncMirrors()
+}
+
+func (m *Manager) syncSFlow() {
+	rows, err := m.listManaged(TableSFlow)
+	if err != nil {
+		klog.Errorf("unable list sflow: %s", err)
+		return
+	}
+	existing := make(map[string]managedRow, len(rows))
+	for _, row := range rows {
+		existing[row.bridge] = row
+	}
+
+	if len(m.Config.SFlow.Targets) != 0 {
+		for _, bridge := range m.Config.SFlow.Bridges {
+			row, ok := existing[bridge]
+			delete(existing, bridge)
+			if err := m.vsctl(sflowArgs(bridge, m.Config.SFlow, row, ok)...); err != nil {
+				klog.Errorf("unable set sflow on bridge %s: %s", bridge, err)
+			}
+		}
+	}
+
+	for bridge, row := range existing {
+		klog.Infof("remove sflow on bridge %s", bridge)
+		if err := m.vsctl("remove", "Bridge", bridge, "sflow", row.uuid); err != nil {
+			klog.Errorf("unable remove sflow on bridge %s: %s", bridge, err)
+		}
+	}
+}
+
+func (m *Manager) syncMirrors() {
+	rows, err := m.listManaged(TableMirror)
+	if err != nil {
+		klog.Errorf("unable list mirrors: %s", err)
+		return
+	}
+	existing := make(map[string]managedRow, len(rows))
+	for _, row := range rows {
+		existing[row.name] = row
+	}
+
+	for _, mirror := range m.Config.Mirrors {
+		if err := mirror.validate(); err != nil {
+			klog.Errorf("ignore invalid mirror: %s", err)
+			continue
+		}
+		row, ok := existing[mirror.Name]
+		delete(existing, mirror.Name)
+		// the mirror moved to another bridge is recreated
+		if ok && row.bridge != mirror.Bridge {
+			if err := m.vsctl("remove", "Bridge", row.bridge, "mirrors", row.uuid); err != nil {
+				klog.Errorf("unable remove mirror %s on bridge %s: %s", row.name, row.bridge, err)
+				continue
+			}
+			ok = false
+		}
+		if err := m.vsctl(mirrorArgs(mirror, row, ok)...); err != nil {
+			klog.Errorf("unable set mirror %s on bridge %s: %s", mirror.Name, mirror.Bridge, err)
+		}
+	}
+
+	for name, row := range existing {
+		klog.Infof("remove mirror %s on bridge %s", name, row.bridge)
+		if err := m.vsctl("remove", "Bridge", row.bridge, "mirrors", row.uuid); err != nil {
+			klog.Errorf("unable remove mirror %s on bridge %s: %s", name, row.bridge, err)
+		}
+	}
+}
+
+// sflowArgs returns the ovs-vsctl args to update the sflow of the bridge, created if not exists.
+func sflowArgs(bridge string, conf SFlowConfig, row managedRow, exists bool) []string {
+	agent := "agent=[]"
+	if conf.Agent != "" {
+		agent = "agent=" + strconv.Quote(conf.Agent)
+	}
+	var targets []string
+	for _, target := range conf.Targets {
+		targets = append(targets, strconv.Quote(target))
+	}
+	columns := []string{
+		agent,
+		"targets=" + strings.Join(targets, ","),
+		fmt.Sprintf("sampling=%d", conf.Sampling),
+		fmt.Sprintf("polling=%d", conf.Polling),
+		fmt.Sprintf("header=%d", conf.Header),
+	}
+
+	if exists {
+		return append([]string{"set", TableSFlow, row.uuid}, columns...)
+	}
+	args := append([]string{"--", "--id=@sflow", "create", TableSFlow}, columns...)
+	args = append(args, managedColumns(bridge)...)
+	return append(args, "--", "set", "Bridge", bridge, "sflow=@sflow")
+}
+
+// mirrorArgs returns the ovs-vsctl args to update the mirror, created if not exists.
+func mirrorArgs(conf MirrorConfig, row managedRow, exists bool) []string {
+	var args []string
+	portIDs := make(map[string]string)
+	portRefs := func(ports []string) string {
+		var refs []string
+		for _, port := range ports {
+			if _, ok := portIDs[port]; !ok {
+				portIDs[port] = fmt.Sprintf("@port%d", len(portIDs))
+				args = append(args, "--", "--id="+portIDs[port], "get", "Port", port)
+			}
+			refs = append(refs, portIDs[port])
+		}
+		if len(refs) == 0 {
+			return "[]"
+		}
+		return strings.Join(refs, ",")
+	}
+
+	var vlans []string
+	for _, vlan := range conf.SelectVLANs {
+		vlans = append(vlans, strconv.Itoa(vlan))
+	}
+	outputPort, outputVLAN := "[]", "[]"
+	if conf.OutputPort != "" {
+		outputPort = portRefs([]string{conf.OutputPort})
+	} else {
+		outputVLAN = strconv.Itoa(conf.OutputVLAN)
+	}
+	selectVLAN := "[]"
+	if len(vlans) != 0 {
+		selectVLAN = strings.Join(vlans, ",")
+	}
+
+	columns := []string{
+		fmt.Sprintf("select_all=%t", conf.SelectAll),
+		"select_src_port=" + portRefs(conf.SelectSrcPorts),
+		"select_dst_port=" + portRefs(conf.SelectDstPorts),
+		"select_vlan=" + selectVLAN,
+		"output_port=" + outputPort,
+		"output_vlan=" + outputVLAN,
+	}
+
+	if exists {
+		args = append(args, "--", "set", TableMirror, row.uuid)
+		return append(args, columns...)
+	}
+	args = append(args, "--", "--id=@mirror", "create", TableMirror, "name="+strconv.Quote(conf.Name))
+	args = append(args, columns...)
+	args = append(args, managedColumns(conf.Bridge)...)
+	return append(args, "--", "add", "Bridge", conf.Bridge, "mirrors", "@mirror")
+}
+
+func managedColumns(bridge string) []string {
+	return []string{
+		fmt.Sprintf("external_ids:%s=true", ManagedKey),
+		fmt.Sprintf("external_ids:%s=%s", BridgeKey, strconv.Quote(bridge)),
+	}
+}
+
+// listManaged returns the rows of the table configured by the agent.
+func listManaged(table string) ([]managedRow, error) {
+	columns := "_uuid,external_ids"
+	if table == TableMirror {
+		columns = "_uuid,name,external_ids"
+	}
+	args := []string{"--format=json", "--columns=" + columns, "find", table, fmt.Sprintf("external_ids:%s=true", ManagedKey)}
+	out, err := exec.Command("ovs-vsctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-vsctl %s: %s", strings.Join(args, " "), err)
+	}
+	return parseManagedRows(out)
+}
+
+// parseManagedRows parse the rows printed by ovs-vsctl in json, e.g.
+// {"data":[[["uuid","<uuid>"],"span",["map",[["everoute-bridge","ovsbr0"]]]]],"headings":["_uuid","name","external_ids"]}
+func parseManagedRows(out []byte) ([]managedRow, error) {
+	var table struct {
+		Data     [][]json.RawMessage `json:"data"`
+		Headings []string            `json:"headings"`
+	}
+	if err := json.Unmarshal(out, &table); err != nil {
+		return nil, fmt.Errorf("parse ovs-vsctl output: %s", err)
+	}
+
+	var rows []managedRow
+	for _, data := range table.Data {
+		var row managedRow
+		for i, heading := range table.Headings {
+			if i >= len(data) {
+				break
+			}
+			var err error
+			switch heading {
+			case "_uuid":
+				var uuid [2]string
+				err = json.Unmarshal(data[i], &uuid)
+				row.uuid = uuid[1]
+			case "name":
+				err = json.Unmarshal(data[i], &row.name)
+			case "external_ids":
+				// the map is printed as ["map",[[key,value],...]]
+				var externalIDs [2]json.RawMessage
+				var entries [][2]string
+				if err = json.Unmarshal(data[i], &externalIDs); err == nil {
+					err = json.Unmarshal(externalIDs[1], &entries)
+				}
+				for _, entry := range entries {
+					if entry[0] == BridgeKey {
+						row.bridge = entry[1]
+					}
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("parse column %s: %s", heading, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func vsctl(args ...string) error {
+	out, err := exec.Command("ovs-vsctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ovs-vsctl %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
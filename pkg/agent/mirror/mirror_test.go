@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mirror
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSFlowArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	conf := SFlowConfig{Targets: []string{"10.0.0.1:6343", "10.0.0.2:6343"}, Agent: "ens192", Sampling: 64, Polling: 10, Header: 128}
+	Expect(sflowArgs("ovsbr0", conf, managedRow{}, false)).Should(Equal([]string{
+		"--", "--id=@sflow", "create", "sFlow",
+		`agent="ens192"`, `targets="10.0.0.1:6343","10.0.0.2:6343"`, "sampling=64", "polling=10", "header=128",
+		"external_ids:everoute-managed=true", `external_ids:everoute-bridge="ovsbr0"`,
+		"--", "set", "Bridge", "ovsbr0", "sflow=@sflow",
+	}))
+
+	conf.Agent = ""
+	Expect(sflowArgs("ovsbr0", conf, managedRow{uuid: "uuid-1"}, true)).Should(Equal([]string{
+		"set", "sFlow", "uuid-1",
+		"agent=[]", `targets="10.0.0.1:6343","10.0.0.2:6343"`, "sampling=64", "polling=10", "header=128",
+	}))
+}
+
+func TestMirrorArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	conf := MirrorConfig{
+		Name:           "span",
+		Bridge:         "ovsbr0",
+		SelectSrcPorts: []string{"vnet0", "vnet1"},
+		SelectDstPorts: []string{"vnet0"},
+		SelectVLANs:    []int{10, 20},
+		OutputPort:     "tap0",
+	}
+	Expect(mirrorArgs(conf, managedRow{}, false)).Should(Equal([]string{
+		"--", "--id=@port0", "get", "Port", "tap0",
+		"--", "--id=@port1", "get", "Port", "vnet0",
+		"--", "--id=@port2", "get", "Port", "vnet1",
+		"--", "--id=@mirror", "create", "Mirror", `name="span"`,
+		"select_all=false", "select_src_port=@port1,@port2", "select_dst_port=@port1",
+		"select_vlan=10,20", "output_port=@port0", "output_vlan=[]",
+		"external_ids:everoute-managed=true", `external_ids:everoute-bridge="ovsbr0"`,
+		"--", "add", "Bridge", "ovsbr0", "mirrors", "@mirror",
+	}))
+
+	conf = MirrorConfig{Name: "span", Bridge: "ovsbr0", SelectAll: true, OutputVLAN: 100}
+	Expect(mirrorArgs(conf, managedRow{uuid: "uuid-1"}, true)).Should(Equal([]string{
+		"--", "set", "Mirror", "uuid-1",
+		"select_all=true", "select_src_port=[]", "select_dst_port=[]",
+		"select_vlan=[]", "output_port=[]", "output_vlan=100",
+	}))
+}
+
+func TestMirrorConfigValidate(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect((&MirrorConfig{Name: "span", Bridge: "ovsbr0", SelectAll: true, OutputPort: "tap0"}).validate()).Should(Succeed())
+	Expect((&MirrorConfig{Bridge: "ovsbr0", SelectAll: true, OutputPort: "tap0"}).validate()).ShouldNot(Succeed())
+	Expect((&MirrorConfig{Name: "span", SelectAll: true, OutputPort: "tap0"}).validate()).ShouldNot(Succeed())
+	Expect((&MirrorConfig{Name: "span", Bridge: "ovsbr0", SelectAll: true}).validate()).ShouldNot(Succeed())
+	Expect((&MirrorConfig{Name: "span", Bridge: "ovsbr0", SelectAll: true, OutputPort: "tap0", OutputVLAN: 10}).validate()).ShouldNot(Succeed())
+	Expect((&MirrorConfig{Name: "span", Bridge: "ovsbr0", OutputPort: "tap0"}).validate()).ShouldNot(Succeed())
+}
+
+func TestParseManagedRows(t *testing.T) {
+	RegisterTestingT(t)
+
+	out := `{"data":[[["uuid","5f2c1a36-9a4b-4f7e-8a39-6a1e6a6f0001"],"span",["map",[["everoute-bridge","ovsbr0"],["everoute-managed","true"]]]]],"headings":["_uuid","name","external_ids"]}`
+	rows, err := parseManagedRows([]byte(out))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(rows).Should(Equal([]managedRow{{uuid: "5f2c1a36-9a4b-4f7e-8a39-6a1e6a6f0001", name: "span", bridge: "ovsbr0"}}))
+
+	rows, err = parseManagedRows([]byte(`{"data":[],"headings":["_uuid","external_ids"]}`))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(rows).Should(BeEmpty())
+
+	_, err = parseManagedRows([]byte(`{"data":[["uuid"]],"headings":["_uuid"]}`))
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestSyncOnce(t *testing.T) {
+	RegisterTestingT(t)
+
+	var commands []string
+	m := &Manager{
+		Config: Config{
+			SFlow: SFlowConfig{Bridges: []string{"ovsbr0"}, Targets: []string{"10.0.0.1:6343"}},
+			Mirrors: []MirrorConfig{
+				{Name: "keep", Bridge: "ovsbr0", SelectAll: true, OutputPort: "tap0"},
+				{Name: "moved", Bridge: "ovsbr1", SelectAll: true, OutputPort: "tap1"},
+				{Name: "invalid", Bridge: "ovsbr0"},
+			},
+		},
+		vsctl: func(args ...string) error {
+			commands = append(commands, strings.Join(args, " "))
+			return nil
+		},
+		listManaged: func(table string) ([]managedRow, error) {
+			if table == TableSFlow {
+				return []managedRow{{uuid: "sflow-1", bridge: "ovsbr0"}, {uuid: "sflow-2", bridge: "ovsbr2"}}, nil
+			}
+			return []managedRow{
+				{uuid: "mirror-1", name: "keep", bridge: "ovsbr0"},
+				{uuid: "mirror-2", name: "moved", bridge: "ovsbr0"},
+				{uuid: "mirror-3", name: "stale", bridge: "ovsbr0"},
+			}, nil
+		},
+	}
+	m.complete()
+	m.syncOnce()
+
+	Expect(commands).Should(HaveLen(6))
+	Expect(commands[0]).Should(HavePrefix("set sFlow sflow-1 "))
+	Expect(commands[1]).Should(Equal("remove Bridge ovsbr2 sflow sflow-2"))
+	Expect(commands[2]).Should(HavePrefix("-- --id=@port0 get Port tap0 -- set Mirror mirror-1 "))
+	Expect(commands[3]).Should(Equal("remove Bridge ovsbr0 mirrors mirror-2"))
+	Expect(commands[4]).Should(HaveSuffix("-- add Bridge ovsbr1 mirrors @mirror"))
+	Expect(commands[5]).Should(Equal("remove Bridge ovsbr0 mirrors mirror-3"))
+}
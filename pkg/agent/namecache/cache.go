@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package namecache maintains the reverse mapping from ip addresses to the names of
+// the endpoints, services and nodes in the cluster, so the traces and the flow logs
+// could show human-readable names instead of the ip addresses.
+package namecache
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// Kind is the kind of the named object.
+type Kind string
+
+const (
+	KindPod      Kind = "Pod"
+	KindVM       Kind = "VM"
+	KindExternal Kind = "External"
+	// KindEndpoint is the endpoint which type is unknown, the group members not
+	// enriched with the endpoint metadata.
+	KindEndpoint  Kind = "Endpoint"
+	KindService   Kind = "Service"
+	KindInterface Kind = "Interface"
+	KindNode      Kind = "Node"
+)
+
+// kindPriority is the priority of the kinds when an ip address owned by multiple
+// objects, e.g. the pod ip reported by both the group members and the agentinfo.
+var kindPriority = map[Kind]int{
+	KindPod:       0,
+	KindVM:        0,
+	KindExternal:  0,
+	KindEndpoint:  1,
+	KindService:   2,
+	KindInterface: 3,
+	KindNode:      4,
+}
+
+// Name is the name of the object owns an ip address.
+type Name struct {
+	Kind      Kind   `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (n Name) String() string {
+	if n.Namespace == "" {
+		return string(n.Kind) + "/" + n.Name
+	}
+	return string(n.Kind) + "/" + n.Namespace + "/" + n.Name
+}
+
+// Cache is the reverse mapping from ip addresses to names, it's thread safe. The names are
+// updated by sources, e.g. a GroupMembers or an AgentInfo, an update of the source only
+// replaces the names of the source.
+type Cache struct {
+	lock sync.RWMutex
+
+	// sources are the names of each source, indexed by the ip address.
+	sources map[string]map[string]Name
+	// ipSources are the sources of each ip address.
+	ipSources map[string]map[string]struct{}
+}
+
+// New return a new empty Cache.
+func New() *Cache {
+	return &Cache{
+		sources:   make(map[string]map[string]Name),
+		ipSources: make(map[string]map[string]struct{}),
+	}
+}
+
+// Lookup return the name of the ip address. If the ip address owned by multiple objects,
+// the name of the highest priority kind returned.
+func (c *Cache) Lookup(ip net.IP) (Name, bool) {
+	if ip == nil {
+		return Name{}, false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lookup(ip.String())
+}
+
+// List return the names of all the ip addresses in the cache.
+func (c *Cache) List() map[string]Name {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	names := make(map[string]Name, len(c.ipSources))
+	for ip := range c.ipSources {
+		names[ip], _ = c.lookup(ip)
+	}
+	return names
+}
+
+// Set replaces the names of the source, the source is removed if names is empty.
+func (c *Cache) Set(source string, names map[string]Name) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for ip := range c.sources[source] {
+		if _, ok := names[ip]; !ok {
+			c.removeIPSource(ip, source)
+		}
+	}
+	if len(names) == 0 {
+		delete(c.sources, source)
+		return
+	}
+
+	c.sources[source] = make(map[string]Name, len(names))
+	for ip, name := range names {
+		c.sources[source][ip] = name
+		if c.ipSources[ip] == nil {
+			c.ipSources[ip] = make(map[string]struct{})
+		}
+		c.ipSources[ip][source] = struct{}{}
+	}
+}
+
+// Delete removes the names of the source.
+func (c *Cache) Delete(source string) {
+	c.Set(source, nil)
+}
+
+func (c *Cache) lookup(ip string) (Name, bool) {
+	sources := make([]string, 0, len(c.ipSources[ip]))
+	for source := range c.ipSources[ip] {
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return Name{}, false
+	}
+	// sort the sources to make the result stable on the same priority
+	sort.Strings(sources)
+
+	name := c.sources[sources[0]][ip]
+	for _, source := range sources[1:] {
+		if n := c.sources[source][ip]; kindPriority[n.Kind] < kindPriority[name.Kind] {
+			name = n
+		}
+	}
+	return name, true
+}
+
+func (c *Cache) removeIPSource(ip, source string) {
+	delete(c.ipSources[ip], source)
+	if len(c.ipSources[ip]) == 0 {
+		delete(c.ipSources, ip)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namecache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+)
+
+// Reconciler watches GroupMembers, AgentInfo and Service, refreshes the names of
+// each object in the Cache incrementally.
+type Reconciler struct {
+	client.Client
+	Cache *Cache
+}
+
+// SetupWithManager create the name cache controllers and add them to mgr.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	if r.Cache == nil {
+		r.Cache = New()
+	}
+
+	watches := []struct {
+		name       string
+		object     runtime.Object
+		reconciler reconcile.Func
+	}{
+		{"groupmembers name cache controller", &groupv1alpha1.GroupMembers{}, r.ReconcileGroupMembers},
+		{"agentinfo name cache controller", &agentv1alpha1.AgentInfo{}, r.ReconcileAgentInfo},
+		{"service name cache controller", &corev1.Service{}, r.ReconcileService},
+	}
+	for _, w := range watches {
+		c, err := controller.New(w.name, mgr, controller.Options{Reconciler: w.reconciler})
+		if err != nil {
+			return err
+		}
+		if err = c.Watch(&source.Kind{Type: w.object}, &handler.EnqueueRequestForObject{}); err != nil {
+			return err
+		}
+		if _, ok := w.object.(*groupv1alpha1.GroupMembers); ok {
+			// the groupmembers are not updated on each change when the patches compacted
+			if err = c.Watch(&source.Kind{Type: &groupv1alpha1.GroupMembersPatch{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(patchedGroup),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func patchedGroup(object handler.MapObject) []reconcile.Request {
+	name := object.Object.(*groupv1alpha1.GroupMembersPatch).AppliedToGroupMembers.Name
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// ReconcileGroupMembers refresh the names of the group members.
+func (r *Reconciler) ReconcileGroupMembers(req ctrl.Request) (ctrl.Result, error) {
+	sourceKey := "groupmembers/" + req.Name
+
+	groupMembers, err := groupctrl.GetGroupMembers(context.Background(), r.Client, req.Name)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.Cache.Delete(sourceKey)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	r.Cache.Set(sourceKey, GroupMembersNames(groupMembers))
+	return ctrl.Result{}, nil
+}
+
+// ReconcileAgentInfo refresh the names of the node and the interfaces of the agent.
+func (r *Reconciler) ReconcileAgentInfo(req ctrl.Request) (ctrl.Result, error) {
+	var agentInfo agentv1alpha1.AgentInfo
+	sourceKey := "agentinfo/" + req.Name
+
+	if err := r.Get(context.Background(), req.NamespacedName, &agentInfo); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.Cache.Delete(sourceKey)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	r.Cache.Set(sourceKey, AgentInfoNames(&agentInfo))
+	return ctrl.Result{}, nil
+}
+
+// ReconcileService refresh the names of the service.
+func (r *Reconciler) ReconcileService(req ctrl.Request) (ctrl.Result, error) {
+	var svc corev1.Service
+	sourceKey := "service/" + req.String()
+
+	if err := r.Get(context.Background(), req.NamespacedName, &svc); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.Cache.Delete(sourceKey)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	r.Cache.Set(sourceKey, ServiceNames(&svc))
+	return ctrl.Result{}, nil
+}
+
+// GroupMembersNames return the names of the group members. The members enriched with
+// the endpoint metadata are named by the endpoint, others by the endpoint reference.
+func GroupMembersNames(groupMembers *groupv1alpha1.GroupMembers) map[string]Name {
+	names := make(map[string]Name)
+	for _, member := range groupMembers.GroupMembers {
+		name := Name{Kind: KindEndpoint, Name: member.EndpointReference.ExternalIDValue}
+		if member.EndpointReference.ExternalIDName == constants.PodEndpointExternalIDName {
+			name.Kind = KindPod
+		}
+		if member.Metadata != nil && member.Metadata.Name != "" {
+			name = Name{Kind: Kind(member.Metadata.Type), Namespace: member.Metadata.Namespace, Name: member.Metadata.Name}
+			if name.Kind == "" {
+				name.Kind = KindEndpoint
+			}
+		}
+		for _, ip := range member.IPs {
+			addName(names, string(ip), name)
+		}
+	}
+	return names
+}
+
+// AgentInfoNames return the names of the ip addresses on the agent, the ip addresses of
+// the internal interfaces are named by the node, the others by the interface.
+func AgentInfoNames(agentInfo *agentv1alpha1.AgentInfo) map[string]Name {
+	names := make(map[string]Name)
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		for _, port := range bridge.Ports {
+			for _, iface := range port.Interfaces {
+				name := Name{Kind: KindInterface, Namespace: agentInfo.Name, Name: iface.Name}
+				if iface.Type == "internal" {
+					name = Name{Kind: KindNode, Name: agentInfo.Name}
+				}
+				for ip := range iface.IPMap {
+					addName(names, string(ip), name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ServiceNames return the names of the cluster ips and the external ips of the service.
+func ServiceNames(svc *corev1.Service) map[string]Name {
+	names := make(map[string]Name)
+	name := Name{Kind: KindService, Namespace: svc.Namespace, Name: svc.Name}
+
+	clusterIPs := svc.Spec.ClusterIPs
+	if len(clusterIPs) == 0 {
+		clusterIPs = []string{svc.Spec.ClusterIP}
+	}
+	for _, ip := range append(clusterIPs, svc.Spec.ExternalIPs...) {
+		addName(names, ip, name)
+	}
+	return names
+}
+
+// Handler return a http handler lists the names in the cache as json, the names
+// could be filtered by the query parameter ip.
+func (c *Cache) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var names map[string]Name
+		if ipStr := req.URL.Query().Get("ip"); ipStr != "" {
+			names = make(map[string]Name)
+			if name, ok := c.Lookup(net.ParseIP(ipStr)); ok {
+				names[ipStr] = name
+			}
+		} else {
+			names = c.List()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(names); err != nil {
+			klog.Errorf("failed to write names: %s", err)
+		}
+	})
+}
+
+// addName add the name of the ip address, the invalid ip addresses are ignored. The ip
+// address is formatted as net.IP String, which is the key of Lookup.
+func addName(names map[string]Name, ipStr string, name Name) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil || ip.IsUnspecified() {
+		return
+	}
+	names[ip.String()] = name
+}
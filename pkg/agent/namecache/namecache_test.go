@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namecache
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func TestCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	cache := New()
+	vm := Name{Kind: KindVM, Namespace: "ns", Name: "vm1"}
+	iface := Name{Kind: KindInterface, Namespace: "agent1", Name: "tap1"}
+	node := Name{Kind: KindNode, Name: "agent1"}
+
+	cache.Set("agentinfo/agent1", map[string]Name{"10.0.0.1": iface, "192.168.1.1": node})
+	Expect(lookup(cache, "10.0.0.1")).Should(Equal(iface))
+
+	// the endpoint name takes precedence over the interface name
+	cache.Set("groupmembers/group1", map[string]Name{"10.0.0.1": vm})
+	Expect(lookup(cache, "10.0.0.1")).Should(Equal(vm))
+	Expect(cache.List()).Should(Equal(map[string]Name{"10.0.0.1": vm, "192.168.1.1": node}))
+
+	// update the source only replaces the names of itself
+	cache.Set("agentinfo/agent1", map[string]Name{"10.0.0.1": iface})
+	_, ok := cache.Lookup(net.ParseIP("192.168.1.1"))
+	Expect(ok).Should(BeFalse())
+	Expect(lookup(cache, "10.0.0.1")).Should(Equal(vm))
+
+	cache.Delete("groupmembers/group1")
+	Expect(lookup(cache, "10.0.0.1")).Should(Equal(iface))
+	cache.Delete("agentinfo/agent1")
+	Expect(cache.List()).Should(BeEmpty())
+}
+
+func TestGroupMembersNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	groupMembers := &groupv1alpha1.GroupMembers{
+		GroupMembers: []groupv1alpha1.GroupMember{
+			{
+				EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: constants.PodEndpointExternalIDName, ExternalIDValue: "pod-uid"},
+				IPs:               []types.IPAddress{"10.0.0.1", "fe80::0001"},
+			},
+			{
+				EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: "iface-1"},
+				IPs:               []types.IPAddress{"10.0.0.2"},
+				Metadata:          &groupv1alpha1.EndpointMetadata{Namespace: "ns", Name: "vm1", Type: groupv1alpha1.EndpointTypeVM},
+			},
+			{
+				EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: "iface-2"},
+				IPs:               []types.IPAddress{"invalid"},
+			},
+		},
+	}
+	Expect(GroupMembersNames(groupMembers)).Should(Equal(map[string]Name{
+		"10.0.0.1": {Kind: KindPod, Name: "pod-uid"},
+		"fe80::1":  {Kind: KindPod, Name: "pod-uid"},
+		"10.0.0.2": {Kind: KindVM, Namespace: "ns", Name: "vm1"},
+	}))
+}
+
+func TestAgentInfoNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent1"},
+		OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+			Name: "ovsbr1",
+			Ports: []agentv1alpha1.OVSPort{
+				{Name: "ovsbr1", Interfaces: []agentv1alpha1.OVSInterface{{
+					Name: "ovsbr1", Type: "internal", IPMap: map[types.IPAddress]metav1.Time{"192.168.1.1": {}},
+				}}},
+				{Name: "tap1", Interfaces: []agentv1alpha1.OVSInterface{{
+					Name: "tap1", IPMap: map[types.IPAddress]metav1.Time{"10.0.0.1": {}},
+				}}},
+			},
+		}}},
+	}
+	Expect(AgentInfoNames(agentInfo)).Should(Equal(map[string]Name{
+		"192.168.1.1": {Kind: KindNode, Name: "agent1"},
+		"10.0.0.1":    {Kind: KindInterface, Namespace: "agent1", Name: "tap1"},
+	}))
+}
+
+func TestServiceNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc1"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "None"},
+	}
+	Expect(ServiceNames(svc)).Should(BeEmpty())
+
+	svc.Spec.ClusterIPs = []string{"10.96.0.10"}
+	svc.Spec.ExternalIPs = []string{"192.168.1.100"}
+	Expect(ServiceNames(svc)).Should(Equal(map[string]Name{
+		"10.96.0.10":    {Kind: KindService, Namespace: "ns", Name: "svc1"},
+		"192.168.1.100": {Kind: KindService, Namespace: "ns", Name: "svc1"},
+	}))
+}
+
+func lookup(cache *Cache, ip string) Name {
+	name, ok := cache.Lookup(net.ParseIP(ip))
+	Expect(ok).Should(BeTrue())
+	return name
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+const (
+	// tcpConntrackSynSent is TCP_CONNTRACK_SYN_SENT in linux nf_conntrack_tcp
+	tcpConntrackSynSent = 1
+
+	// PortScanEventReason is the reason of the event recorded on a scanning endpoint
+	PortScanEventReason = "PortScanDetected"
+	// QuarantineEventReason is the reason of the event recorded on a quarantined endpoint
+	QuarantineEventReason = "EndpointQuarantined"
+
+	// QuarantinePrefix is the name prefix of quarantines created by port scan detector
+	QuarantinePrefix = "portscan-"
+	// QuarantineSourcePortScan is the value of constants.QuarantineSourceLabelKey on port scan quarantine
+	QuarantineSourcePortScan = "portscan"
+
+	DefaultThreshold     = 100
+	DefaultInterval      = 10 * time.Second
+	DefaultQuarantineTTL = 30 * time.Minute
+)
+
+// Config defines how port scan detector works
+type Config struct {
+	// Threshold is the number of half-open connections to distinct destinations
+	// from one source, over which the source is considered as scanning.
+	Threshold int
+	// Interval is the period of conntrack table inspection.
+	Interval time.Duration
+	// Quarantine enables quarantine the scanning endpoint.
+	Quarantine bool
+	// QuarantineTTL is how long a quarantine last before removed by the quarantine controller.
+	QuarantineTTL time.Duration
+	// ManagementCIDRs are the networks still allowed to access, or accessed by
+	// the quarantined endpoint.
+	ManagementCIDRs []string
+}
+
+// Detector detect SYN fan-out patterns from local endpoints, report them
+// as PortScanEvent and quarantine the offending endpoints if enabled.
+type Detector struct {
+	client.Client
+	Recorder record.EventRecorder
+	Config   Config
+
+	// AgentName is the name of current agent, only endpoints located on
+	// current agent would be handled.
+	AgentName string
+
+	// listFlows used for list conntrack flows, replaced in testing
+	listFlows func() ([]*netlink.ConntrackFlow, error)
+}
+
+// PortScanEvent describes a detected SYN fan-out from an endpoint
+type PortScanEvent struct {
+	Endpoint     securityv1alpha1.NamespacedName
+	SourceIP     string
+	Destinations int
+	DetectedTime time.Time
+}
+
+// SetupWithManager indexes the endpoints by ips, and adds the detector to the manager.
+func (d *Detector) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &securityv1alpha1.Endpoint{}, constants.EndpointByIPIndex, IPIndexEndpointFunc)
+	if err != nil {
+		return err
+	}
+	return mgr.Add(d)
+}
+
+// Start implements manager.Runnable.
+func (d *Detector) Start(stopChan <-chan struct{}) error {
+	d.complete()
+
+	klog.Infof("start port scan detector with threshold %d, quarantine %t", d.Config.Threshold, d.Config.Quarantine)
+	defer klog.Infof("shutting down port scan detector")
+
+	wait.Until(d.detectOnce, d.Config.Interval, stopChan)
+	return nil
+}
+
+func (d *Detector) complete() {
+	if d.Config.Threshold <= 0 {
+		d.Config.Threshold = DefaultThreshold
+	}
+	if d.Config.Interval <= 0 {
+		d.Config.Interval = DefaultInterval
+	}
+	if d.Config.QuarantineTTL <= 0 {
+		d.Config.QuarantineTTL = DefaultQuarantineTTL
+	}
+	if d.listFlows == nil {
+		d.listFlows = listConntrackFlows
+	}
+}
+
+// listConntrackFlows list the conntrack flows of both ipv4 and ipv6.
+func listConntrackFlows() ([]*netlink.ConntrackFlow, error) {
+	var flows []*netlink.ConntrackFlow
+	for _, family := range []netlink.InetFamily{unix.AF_INET, unix.AF_INET6} {
+		familyFlows, err := netlink.ConntrackTableList(netlink.ConntrackTable, family)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, familyFlows...)
+	}
+	return flows, nil
+}
+
+func (d *Detector) detectOnce() {
+	flows, err := d.listFlows()
+	if err != nil {
+		klog.Errorf("unable list conntrack flows: %s", err)
+		return
+	}
+
+	for srcIP, destinations := range synFanOut(flows) {
+		if destinations.Len() < d.Config.Threshold {
+			continue
+		}
+		if err := d.handleScanner(srcIP, destinations.Len()); err != nil {
+			klog.Errorf("unable handle port scan from %s: %s", srcIP, err)
+		}
+	}
+}
+
+// synFanOut count distinct destinations of half-open tcp connections for each source ip
+func synFanOut(flows []*netlink.ConntrackFlow) map[string]sets.String {
+	var fanOut = make(map[string]sets.String)
+
+	for _, flow := range flows {
+		if flow == nil || flow.Forward.Protocol != unix.IPPROTO_TCP {
+			continue
+		}
+		if flow.ProtoInfo.TCP == nil || flow.ProtoInfo.TCP.State != tcpConntrackSynSent {
+			continue
+		}
+		srcIP := flow.Forward.SrcIP.String()
+		if _, ok := fanOut[srcIP]; !ok {
+			fanOut[srcIP] = sets.NewString()
+		}
+		fanOut[srcIP].Insert(net.JoinHostPort(flow.Forward.DstIP.String(), fmt.Sprint(flow.Forward.DstPort)))
+	}
+
+	return fanOut
+}
+
+func (d *Detector) handleScanner(srcIP string, destinations int) error {
+	endpoint, err := d.getLocalEndpointByIP(srcIP)
+	if err != nil {
+		return err
+	}
+	if endpoint == nil {
+		klog.V(4).Infof("ignore port scan from %s: not a local endpoint", srcIP)
+		return nil
+	}
+
+	event := PortScanEvent{
+		Endpoint:     securityv1alpha1.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name},
+		SourceIP:     srcIP,
+		Destinations: destinations,
+		DetectedTime: time.Now(),
+	}
+	klog.Warningf("port scan detected: %+v", event)
+	d.Recorder.Eventf(endpoint, corev1.EventTypeWarning, PortScanEventReason,
+		"agent %s found %d half-open connections from %s to distinct destinations", d.AgentName, destinations, srcIP)
+
+	if !d.Config.Quarantine {
+		return nil
+	}
+	return d.quarantine(endpoint)
+}
+
+// getLocalEndpointByIP returns the endpoint on current agent owns the ip, nil if not found.
+func (d *Detector) getLocalEndpointByIP(ip string) (*securityv1alpha1.Endpoint, error) {
+	var endpointList securityv1alpha1.EndpointList
+	if err := d.List(context.Background(), &endpointList, client.MatchingFields{constants.EndpointByIPIndex: ip}); err != nil {
+		return nil, err
+	}
+
+	for item := range endpointList.Items {
+		endpoint := &endpointList.Items[item]
+		if !sets.NewString(endpoint.Status.Agents...).Has(d.AgentName) {
+			continue
+		}
+		if sets.NewString(IPIndexEndpointFunc(endpoint)...).Has(ip) {
+			return endpoint, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// quarantine creates the Quarantine of the endpoint, or extends it if the endpoint still
+// scanning. The quarantine controller enforces it and removes it after expired.
+func (d *Detector) quarantine(endpoint *securityv1alpha1.Endpoint) error {
+	ctx := context.Background()
+	quarantine := NewQuarantine(endpoint, d.Config.ManagementCIDRs, d.Config.QuarantineTTL)
+	quarantine.Labels[constants.QuarantineAgentLabelKey] = d.AgentName
+
+	var oldQuarantine securityv1alpha1.Quarantine
+	err := d.Get(ctx, client.ObjectKey{Namespace: quarantine.Namespace, Name: quarantine.Name}, &oldQuarantine)
+	switch {
+	case errors.IsNotFound(err):
+		if err = d.Create(ctx, quarantine); err != nil {
+			return fmt.Errorf("create quarantine %s/%s: %s", quarantine.Namespace, quarantine.Name, err)
+		}
+		klog.Infof("quarantine endpoint %s/%s for %s", endpoint.Namespace, endpoint.Name, d.Config.QuarantineTTL)
+		d.Recorder.Eventf(endpoint, corev1.EventTypeWarning, QuarantineEventReason,
+			"endpoint quarantined by quarantine %s for %s", quarantine.Name, d.Config.QuarantineTTL)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	// endpoint still scanning, extend the quarantine, the ttl starts from its creation
+	ttl := time.Since(oldQuarantine.CreationTimestamp.Time).Truncate(time.Second) + d.Config.QuarantineTTL
+	oldQuarantine.Labels = quarantine.Labels
+	oldQuarantine.Spec = quarantine.Spec
+	oldQuarantine.Spec.TTL = &metav1.Duration{Duration: ttl}
+	return d.Update(ctx, &oldQuarantine)
+}
+
+// NewQuarantine return the quarantine of the endpoint for the ttl, which drop all traffics
+// of the endpoint except the traffics from or to management networks.
+func NewQuarantine(endpoint *securityv1alpha1.Endpoint, managementCIDRs []string, ttl time.Duration) *securityv1alpha1.Quarantine {
+	endpointName := endpoint.GetName()
+
+	quarantine := &securityv1alpha1.Quarantine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      QuarantinePrefix + endpointName,
+			Namespace: endpoint.GetNamespace(),
+			Labels: map[string]string{
+				constants.QuarantineSourceLabelKey: QuarantineSourcePortScan,
+			},
+		},
+		Spec: securityv1alpha1.QuarantineSpec{
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &endpointName}},
+			TTL:       &metav1.Duration{Duration: ttl},
+		},
+	}
+	for _, cidr := range managementCIDRs {
+		quarantine.Spec.Exceptions = append(quarantine.Spec.Exceptions, securityv1alpha1.SecurityPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+		})
+	}
+
+	return quarantine
+}
+
+// IPIndexEndpointFunc return the ips of the Endpoint in the canonical format of net.IP.
+func IPIndexEndpointFunc(o runtime.Object) []string {
+	ipSet := sets.NewString()
+	for _, ip := range o.(*securityv1alpha1.Endpoint).Status.IPs {
+		if parsed := net.ParseIP(string(ip)); parsed != nil {
+			ipSet.Insert(parsed.String())
+		}
+	}
+	return ipSet.List()
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portscan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func newTCPFlow(srcIP, dstIP string, dstPort uint16, state uint8) *netlink.ConntrackFlow {
+	return &netlink.ConntrackFlow{
+		Forward: netlink.IpTuple{
+			Protocol: unix.IPPROTO_TCP,
+			SrcIP:    net.ParseIP(srcIP),
+			DstIP:    net.ParseIP(dstIP),
+			SrcPort:  34567,
+			DstPort:  dstPort,
+		},
+		ProtoInfo: netlink.ProtoInfo{TCP: &netlink.ProtoInfoTCP{State: state}},
+	}
+}
+
+func TestSynFanOut(t *testing.T) {
+	RegisterTestingT(t)
+
+	flows := []*netlink.ConntrackFlow{
+		newTCPFlow("10.0.0.1", "10.0.0.2", 22, tcpConntrackSynSent),
+		newTCPFlow("10.0.0.1", "10.0.0.2", 23, tcpConntrackSynSent),
+		newTCPFlow("10.0.0.1", "10.0.0.2", 23, tcpConntrackSynSent),
+		newTCPFlow("10.0.0.1", "10.0.0.3", 80, 3), // established
+		newTCPFlow("10.0.0.4", "10.0.0.2", 443, tcpConntrackSynSent),
+		newTCPFlow("fe80::1", "fe80::2", 443, tcpConntrackSynSent),
+		{Forward: netlink.IpTuple{Protocol: unix.IPPROTO_UDP, SrcIP: net.ParseIP("10.0.0.1")}},
+		nil,
+	}
+
+	fanOut := synFanOut(flows)
+	Expect(fanOut).Should(HaveLen(3))
+	Expect(fanOut["10.0.0.1"].List()).Should(ConsistOf("10.0.0.2:22", "10.0.0.2:23"))
+	Expect(fanOut["10.0.0.4"].List()).Should(ConsistOf("10.0.0.2:443"))
+	Expect(fanOut["fe80::1"].List()).Should(ConsistOf("[fe80::2]:443"))
+}
+
+func TestNewQuarantine(t *testing.T) {
+	RegisterTestingT(t)
+
+	endpoint := &securityv1alpha1.Endpoint{ObjectMeta: metav1.ObjectMeta{Name: "ep01", Namespace: "default"}}
+
+	quarantine := NewQuarantine(endpoint, nil, time.Minute)
+	Expect(quarantine.Name).Should(Equal(QuarantinePrefix + "ep01"))
+	Expect(quarantine.Namespace).Should(Equal("default"))
+	Expect(quarantine.Labels[constants.QuarantineSourceLabelKey]).Should(Equal(QuarantineSourcePortScan))
+	Expect(*quarantine.Spec.AppliedTo[0].Endpoint).Should(Equal("ep01"))
+	Expect(quarantine.Spec.TTL.Duration).Should(Equal(time.Minute))
+	Expect(quarantine.Spec.Exceptions).Should(BeEmpty())
+
+	quarantine = NewQuarantine(endpoint, []string{"192.168.0.0/24"}, time.Minute)
+	Expect(quarantine.Spec.Exceptions).Should(HaveLen(1))
+	Expect(quarantine.Spec.Exceptions[0].IPBlock.CIDR).Should(Equal("192.168.0.0/24"))
+}
+
+func TestDetectAndQuarantine(t *testing.T) {
+	RegisterTestingT(t)
+
+	endpoint := &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep01", Namespace: "default"},
+		Status: securityv1alpha1.EndpointStatus{
+			IPs:    []types.IPAddress{"10.0.0.1", "fe80::0:1"},
+			Agents: []string{"agent01"},
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, endpoint)
+	recorder := record.NewFakeRecorder(10)
+
+	d := &Detector{
+		Client:    k8sClient,
+		Recorder:  recorder,
+		AgentName: "agent01",
+		Config:    Config{Threshold: 2, Quarantine: true, QuarantineTTL: time.Minute},
+		listFlows: func() ([]*netlink.ConntrackFlow, error) {
+			return []*netlink.ConntrackFlow{
+				newTCPFlow("10.0.0.1", "10.0.0.2", 22, tcpConntrackSynSent),
+				newTCPFlow("10.0.0.1", "10.0.0.2", 23, tcpConntrackSynSent),
+				// not a local endpoint
+				newTCPFlow("10.0.0.9", "10.0.0.2", 22, tcpConntrackSynSent),
+				newTCPFlow("10.0.0.9", "10.0.0.2", 23, tcpConntrackSynSent),
+			}, nil
+		},
+	}
+	d.complete()
+	d.detectOnce()
+
+	var quarantine securityv1alpha1.Quarantine
+	Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: QuarantinePrefix + "ep01"}, &quarantine)).Should(Succeed())
+	Expect(quarantine.Labels[constants.QuarantineAgentLabelKey]).Should(Equal("agent01"))
+	Expect(quarantine.Spec.TTL.Duration).Should(Equal(time.Minute))
+	Expect(recorder.Events).Should(HaveLen(2))
+
+	var quarantineList securityv1alpha1.QuarantineList
+	Expect(k8sClient.List(context.Background(), &quarantineList)).Should(Succeed())
+	Expect(quarantineList.Items).Should(HaveLen(1))
+
+	// should extend the quarantine when the endpoint still scanning from its ipv6 address
+	quarantine.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	Expect(k8sClient.Update(context.Background(), &quarantine)).Should(Succeed())
+	d.listFlows = func() ([]*netlink.ConntrackFlow, error) {
+		return []*netlink.ConntrackFlow{
+			newTCPFlow("fe80::1", "fe80::2", 22, tcpConntrackSynSent),
+			newTCPFlow("fe80::1", "fe80::2", 23, tcpConntrackSynSent),
+		}, nil
+	}
+	d.detectOnce()
+	Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: QuarantinePrefix + "ep01"}, &quarantine)).Should(Succeed())
+	Expect(quarantine.Spec.TTL.Duration).Should(BeNumerically(">=", time.Hour+time.Minute))
+}
@@ -26,7 +26,6 @@ import (
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	cniv1 "github.com/containernetworking/cni/pkg/types/100"
-	"github.com/containernetworking/plugins/pkg/ip"
 	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/plugins/ipam/host-local/backend/allocator"
@@ -38,16 +37,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/ifaceman"
 	cnipb "github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
 	"github.com/everoute/everoute/pkg/utils"
 )
 
 type CNIServer struct {
-	k8sClient client.Client
-	ovsDriver *ovsdbDriver.OvsDriver
-	gwName    string
-	brName    string
-	podCIDR   []cnitypes.IPNet
+	k8sClient    client.Client
+	ovsDriver    *ovsdbDriver.OvsDriver
+	ifaceManager *ifaceman.Manager
+	gwName       string
+	brName       string
+	podCIDR      []cnitypes.IPNet
 
 	mutex sync.Mutex
 }
@@ -140,43 +142,35 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniRequest) (*cni
 
 	nsPath := "/host" + request.Netns
 	// vethName - ovs port name
-	vethName := "_" + request.ContainerId[:12]
-	if err = ns.WithNetNSPath(nsPath, func(hostNS ns.NetNS) error {
-		// create veth pair in container NS and host NS
-		// TODO: MTU is a const variable here
-		_, containerVeth, err := ip.SetupVethWithName(request.Ifname, vethName, 1500, "", hostNS)
-		if err != nil {
-			klog.Errorf("create veth device error, err: %s", err)
-			return err
-		}
-		result.Interfaces[0].Mac = containerVeth.HardwareAddr.String()
-		if err = ipam.ConfigureIface(request.Ifname, result); err != nil {
-			klog.Errorf("configure ip address in container error, err: %s", err)
-			return err
-		}
-		return nil
-	}); err != nil {
-		return s.RetError(cnipb.ErrorCode_IO_FAILURE, "exec error in namespace", err)
-	}
-
-	// add the veth device to ovs bridge
-	if err = s.ovsDriver.CreatePort(vethName, "", 0); err != nil {
-		klog.Errorf("create ovs port error, vethName: %s, err: %s", vethName, err)
-		return s.RetError(cnipb.ErrorCode_IO_FAILURE, "add port to ovs bridge error", err)
-	}
+	vethName := ifaceman.HostIfName(request.ContainerId)
 
 	// set externalID on the interface for arp learning
 	externalID := make(map[string]string)
-	externalID["attached-mac"] = result.Interfaces[0].Mac
 	externalID["pod-uuid"] = utils.EncodeNamespacedName(coretypes.NamespacedName{
 		Name:      "pod-" + string(args.K8S_POD_NAME),
 		Namespace: string(args.K8S_POD_NAMESPACE),
 	})
 	externalID["attached-ipv4"] = result.IPs[0].Address.IP.String()
-	if err = s.ovsDriver.UpdateInterface(vethName, externalID); err != nil {
-		klog.Errorf("set externalID for %s error, err: %s", vethName, err)
-		return s.RetError(cnipb.ErrorCode_IO_FAILURE, "set externalID for %s error", err)
+
+	// create veth pair in container NS and host NS, and add the veth device to ovs bridge
+	// TODO: MTU is a const variable here
+	mac, err := s.ifaceManager.Setup(&ifaceman.Veth{
+		HostIfName:      vethName,
+		ContainerID:     request.ContainerId,
+		ContainerIfName: request.Ifname,
+		NetNS:           nsPath,
+		MTU:             ifaceman.DefaultMTU,
+		ExternalIDs:     externalID,
+	}, result)
+	if err != nil {
+		klog.Errorf("setup veth %s error, err: %s", vethName, err)
+		// release the ip address allocated, the veth has been rolled back
+		if delErr := ipam.ExecDel("host-local", s.GetIpamConfByte(conf)); delErr != nil {
+			klog.Errorf("release ip error, ipam conf: %s, err: %s", conf.IPAM, delErr)
+		}
+		return s.RetError(cnipb.ErrorCode_IO_FAILURE, "setup veth error", err)
 	}
+	result.Interfaces[0].Mac = mac.String()
 
 	// broadcast arp pkg in namespace
 	// pod-endpoint may not sync when sending arp, so this part may not have effects.
@@ -209,7 +203,7 @@ func (s *CNIServer) CmdCheck(ctx context.Context, request *cnipb.CniRequest) (*c
 		return s.RetError(cnipb.ErrorCode_DECODING_FAILURE, "failed to decode request", err)
 	}
 
-	vethName := "_" + request.ContainerId[:12]
+	vethName := ifaceman.HostIfName(request.ContainerId)
 
 	// check ovs port
 	if !s.ovsDriver.IsPortNamePresent(vethName) {
@@ -240,14 +234,12 @@ func (s *CNIServer) CmdDel(ctx context.Context, request *cnipb.CniRequest) (*cni
 		return s.RetError(cnipb.ErrorCode_DECODING_FAILURE, "Parse request conf error", err)
 	}
 
-	vethName := "_" + request.ContainerId[:12]
+	vethName := ifaceman.HostIfName(request.ContainerId)
 
-	// delete ovs port
-	if s.ovsDriver.IsPortNamePresent(vethName) {
-		if err = s.ovsDriver.DeletePort(vethName); err != nil {
-			klog.Errorf("delete ovs port %s error, err: %s", vethName, err)
-			return s.RetError(cnipb.ErrorCode_IO_FAILURE, "delete ovs port error", err)
-		}
+	// delete ovs port and the veth device
+	if err = s.ifaceManager.Teardown(vethName); err != nil {
+		klog.Errorf("teardown veth %s error, err: %s", vethName, err)
+		return s.RetError(cnipb.ErrorCode_IO_FAILURE, "delete ovs port error", err)
 	}
 
 	// release allocated IP
@@ -326,6 +318,7 @@ func NewCNIServer(k8sClient client.Client, datapathManager *datapath.DpManager)
 		ovsDriver: datapathManager.OvsdbDriverMap[datapathManager.Info.BridgeName][datapath.LOCAL_BRIDGE_KEYWORD],
 		podCIDR:   append([]cnitypes.IPNet{}, datapathManager.Info.PodCIDR...),
 	}
+	s.ifaceManager = ifaceman.NewManager(s.ovsDriver, ifaceman.NewStore(constants.CNIVethStorePath))
 
 	// set gateway ip address, first ip in first CIDR
 	if err := SetLinkAddr(s.gwName,
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulehit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+// DefaultCountersPath is the file the counters of the policy rules persisted in.
+const DefaultCountersPath = "/var/lib/everoute/rulecounters.json"
+
+// Counters are the cumulative counters of the policy rules. The counters of the flows reset
+// when the flows reinstalled, e.g. the agent restarted or upgraded, so the rules sum the
+// increments of their flows in each collection instead. The counters are restored from the
+// file on the agent restarted.
+type Counters struct {
+	path string
+
+	lock  sync.RWMutex
+	stats []agentv1alpha1.PolicyRuleStats
+	// flows are the counters of the flows in the last collection by the cookie
+	flows map[uint64]flowCounter
+}
+
+type flowCounter struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// countersRecord is the content of the counters file.
+type countersRecord struct {
+	Flows map[uint64]flowCounter          `json:"flows"`
+	Rules []agentv1alpha1.PolicyRuleStats `json:"rules"`
+}
+
+// NewCounters returns the Counters persisted in the file, the counters are not persisted if
+// the path is empty, and start from zero if the file not exists or could not be parsed.
+func NewCounters(path string) *Counters {
+	c := &Counters{path: path}
+	if path == "" {
+		return c
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("unable to read rule counters from %s: %s", path, err)
+		}
+		return c
+	}
+	var record countersRecord
+	if err = json.Unmarshal(raw, &record); err != nil {
+		klog.Errorf("unable to parse rule counters from %s: %s", path, err)
+		return c
+	}
+	c.flows = record.Flows
+	c.stats = record.Rules
+	klog.Infof("restore the counters of %d rules from %s", len(c.stats), path)
+	return c
+}
+
+// Stats returns the counters of the policy rules sorted by the policy and the rule.
+func (c *Counters) Stats() []agentv1alpha1.PolicyRuleStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]agentv1alpha1.PolicyRuleStats(nil), c.stats...)
+}
+
+// increment returns the counters of the flow since the last collection. The flow not in the
+// last collection, or with the counters decreased, is reinstalled and counted from zero.
+func (c *Counters) increment(flow FlowStats) flowCounter {
+	c.lock.RLock()
+	last, ok := c.flows[flow.Cookie]
+	c.lock.RUnlock()
+
+	if !ok || flow.Packets < last.Packets || flow.Bytes < last.Bytes {
+		return flowCounter{Packets: flow.Packets, Bytes: flow.Bytes}
+	}
+	return flowCounter{Packets: flow.Packets - last.Packets, Bytes: flow.Bytes - last.Bytes}
+}
+
+// add adds the increments of the rules to the counters, and records the counters of the flows
+// as the baseline of the next collection. The policyRules are the rules of the policies on the
+// agent, the counters of the other policies and the rules removed from the policies are deleted,
+// all the counters of a policy are kept if its rules are nil, e.g. the policy failed to get.
+func (c *Counters) add(flows map[uint64]flowCounter, increments []agentv1alpha1.PolicyRuleStats, policyRules map[string]sets.String) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	merged := make(map[[2]string]*agentv1alpha1.PolicyRuleStats, len(c.stats))
+	for i := range c.stats {
+		rules, ok := policyRules[c.stats[i].Policy]
+		if !ok || (rules != nil && !rules.Has(c.stats[i].Rule)) {
+			continue
+		}
+		merged[[2]string{c.stats[i].Policy, c.stats[i].Rule}] = &c.stats[i]
+	}
+	for i := range increments {
+		key := [2]string{increments[i].Policy, increments[i].Rule}
+		if merged[key] == nil {
+			merged[key] = &agentv1alpha1.PolicyRuleStats{Policy: increments[i].Policy, Rule: increments[i].Rule}
+		}
+		merged[key].Packets += increments[i].Packets
+		merged[key].Bytes += increments[i].Bytes
+	}
+
+	stats := make([]agentv1alpha1.PolicyRuleStats, 0, len(merged))
+	for _, stat := range merged {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Policy != stats[j].Policy {
+			return stats[i].Policy < stats[j].Policy
+		}
+		return stats[i].Rule < stats[j].Rule
+	})
+	c.stats = stats
+	c.flows = flows
+
+	if err := c.persist(); err != nil {
+		klog.Errorf("unable to persist rule counters to %s: %s", c.path, err)
+	}
+}
+
+// persist writes the counters to the file, the counters are not persisted if the path is empty.
+func (c *Counters) persist() error {
+	if c.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(countersRecord{Flows: c.flows, Rules: c.stats})
+	if err != nil {
+		return err
+	}
+
+	// write to the temp file then rename, a half written file is never read
+	tmpFile := c.path + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, c.path)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rulehit tracks the last time the policy rules matched. The agents collect
+// the rule hits from the flow stats, and record the last hit time of each rule in the
+// annotation of the SecurityPolicy, so the rules unmatched for a long time could be
+// found and pruned. The packets and bytes of the rules could be reported in the AgentInfo
+// as well, the controller sums them in the status of the SecurityPolicy.
+package rulehit
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// defaultRuleNames are the names of the default rules of the policies in the flow rules.
+var defaultRuleNames = []string{"default.ingress", "default.egress"}
+
+// PolicyRuleNames returns the rules of the policy, named as direction.ruleName, e.g.
+// ingress.allow-http. The default rules are not included.
+func PolicyRuleNames(policy *securityv1alpha1.SecurityPolicy) []string {
+	var names []string
+	for _, rule := range policy.Spec.IngressRules {
+		names = append(names, "ingress."+rule.Name)
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		names = append(names, "egress."+rule.Name)
+	}
+	return names
+}
+
+// LastHits returns the last hit time of the rules recorded in the policy annotation.
+func LastHits(policy *securityv1alpha1.SecurityPolicy) map[string]time.Time {
+	hits := make(map[string]time.Time)
+	value, ok := policy.GetAnnotations()[constants.RuleLastHitAnnotation]
+	if !ok {
+		return hits
+	}
+
+	var rawHits map[string]string
+	if err := json.Unmarshal([]byte(value), &rawHits); err != nil {
+		klog.Errorf("invalid annotation %s of policy %s/%s: %s", constants.RuleLastHitAnnotation, policy.Namespace, policy.Name, err)
+		return hits
+	}
+	for rule, rawTime := range rawHits {
+		hitTime, err := time.Parse(time.RFC3339, rawTime)
+		if err != nil {
+			continue
+		}
+		hits[rule] = hitTime
+	}
+	return hits
+}
+
+// MergeLastHits records the hits of the rules in the policy annotation, the hit time
+// is updated only if it is newer than the recorded one by the granularity, which
+// limits the updates of the policy. Returns true if the annotation changed.
+func MergeLastHits(policy *securityv1alpha1.SecurityPolicy, hits map[string]time.Time, granularity time.Duration) bool {
+	lastHits := LastHits(policy)
+	var changed bool
+	for rule, hitTime := range hits {
+		if lastHit, ok := lastHits[rule]; ok && !hitTime.After(lastHit.Add(granularity)) {
+			continue
+		}
+		lastHits[rule] = hitTime
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+
+	rawHits := make(map[string]string, len(lastHits))
+	for rule, hitTime := range lastHits {
+		rawHits[rule] = hitTime.UTC().Format(time.RFC3339)
+	}
+	value, _ := json.Marshal(rawHits)
+	setAnnotation(policy, constants.RuleLastHitAnnotation, string(value))
+	return true
+}
+
+// UnusedRules returns the rules of the policy unmatched for the duration, the rules
+// never matched are counted from the policy created.
+func UnusedRules(policy *securityv1alpha1.SecurityPolicy, unusedFor time.Duration, now time.Time) []string {
+	lastHits := LastHits(policy)
+	deadline := now.Add(-unusedFor)
+
+	var unused []string
+	for _, rule := range PolicyRuleNames(policy) {
+		lastHit, ok := lastHits[rule]
+		if !ok {
+			lastHit = policy.CreationTimestamp.Time
+		}
+		if lastHit.Before(deadline) {
+			unused = append(unused, rule)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// SetUnusedRules sets the unused rules in the policy annotation, the annotation is
+// removed if no unused rules. Returns true if the annotation changed.
+func SetUnusedRules(policy *securityv1alpha1.SecurityPolicy, unused []string) bool {
+	value, exist := policy.GetAnnotations()[constants.UnusedRulesAnnotation]
+	if len(unused) == 0 {
+		if exist {
+			delete(policy.Annotations, constants.UnusedRulesAnnotation)
+		}
+		return exist
+	}
+
+	newValue := strings.Join(unused, ",")
+	if exist && value == newValue {
+		return false
+	}
+	setAnnotation(policy, constants.UnusedRulesAnnotation, newValue)
+	return true
+}
+
+// parseRuleReference parses the rule reference of the flow, which is in format of
+// policyNamespace/policyName/policyType/direction.ruleName-flowKey.
+func parseRuleReference(reference string) (namespace, name, rule string, ok bool) {
+	keys := strings.SplitN(reference, "/", 4)
+	if len(keys) != 4 || keys[0] == "" || keys[2] != string(policycache.NormalPolicy) {
+		return "", "", "", false
+	}
+	index := strings.LastIndex(keys[3], "-")
+	if index <= 0 {
+		return "", "", "", false
+	}
+	return keys[0], keys[1], keys[3][:index], true
+}
+
+func setAnnotation(policy *securityv1alpha1.SecurityPolicy, key, value string) {
+	if policy.Annotations == nil {
+		policy.Annotations = make(map[string]string)
+	}
+	policy.Annotations[key] = value
+}
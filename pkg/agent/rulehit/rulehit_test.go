@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulehit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func TestParseFlowStats(t *testing.T) {
+	RegisterTestingT(t)
+
+	output := "OFPST_FLOW reply (OF1.3) (xid=0x2):\n" +
+		" cookie=0x4000000000003, duration=5.2s, table=0, n_packets=3, n_bytes=294, idle_age=2, priority=200,ip actions=drop\n" +
+		" cookie=0x4000000000004, duration=5.2s, table=0, n_packets=0, n_bytes=0, priority=100 actions=goto_table:1\n"
+	Expect(ParseFlowStats(output)).Should(Equal([]FlowStats{
+		{Cookie: 0x4000000000003, Packets: 3, Bytes: 294, IdleAge: 2 * time.Second},
+		{Cookie: 0x4000000000004, Packets: 0, IdleAge: -1},
+	}))
+}
+
+func TestTrackerHitTime(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now()
+	tracker := &Tracker{packets: map[uint64]uint64{1: 3}}
+
+	_, hit := tracker.hitTime(FlowStats{Cookie: 1, Packets: 0, IdleAge: 5 * time.Second}, now)
+	Expect(hit).Should(BeFalse())
+
+	hitTime, hit := tracker.hitTime(FlowStats{Cookie: 1, Packets: 3, IdleAge: 5 * time.Second}, now)
+	Expect(hit).Should(BeTrue())
+	Expect(hitTime).Should(Equal(now.Add(-5 * time.Second)))
+
+	_, hit = tracker.hitTime(FlowStats{Cookie: 1, Packets: 3, IdleAge: -1}, now)
+	Expect(hit).Should(BeFalse())
+	hitTime, hit = tracker.hitTime(FlowStats{Cookie: 1, Packets: 4, IdleAge: -1}, now)
+	Expect(hit).Should(BeTrue())
+	Expect(hitTime).Should(Equal(now))
+
+	// the first collection of the flow is the baseline
+	_, hit = tracker.hitTime(FlowStats{Cookie: 2, Packets: 4, IdleAge: -1}, now)
+	Expect(hit).Should(BeFalse())
+}
+
+func TestRuleNameOf(t *testing.T) {
+	RegisterTestingT(t)
+
+	names := []string{"ingress.allow", "ingress.allow.http", "egress.allow", "default.ingress", "default.egress"}
+	Expect(ruleNameOf(names, "ingress.allow")).Should(Equal("ingress.allow"))
+	Expect(ruleNameOf(names, "ingress.allow.0")).Should(Equal("ingress.allow"))
+	Expect(ruleNameOf(names, "ingress.allow.http.1")).Should(Equal("ingress.allow.http"))
+	Expect(ruleNameOf(names, "egress.allow.namedport")).Should(Equal("egress.allow"))
+	Expect(ruleNameOf(names, "default.ingress")).Should(Equal("default.ingress"))
+	Expect(ruleNameOf(names, "egress.removed")).Should(Equal("egress.removed"))
+}
+
+func TestMergeRuleCounters(t *testing.T) {
+	RegisterTestingT(t)
+
+	names := []string{"ingress.allow", "default.ingress"}
+	stats := mergeRuleCounters(names, map[string]*agentv1alpha1.PolicyRuleStats{
+		"ingress.allow.0": {Policy: "ns/policy", Rule: "ingress.allow.0", Packets: 3, Bytes: 300},
+		"ingress.allow.1": {Policy: "ns/policy", Rule: "ingress.allow.1", Packets: 2, Bytes: 200},
+		"default.ingress": {Policy: "ns/policy", Rule: "default.ingress", Packets: 1, Bytes: 60},
+	})
+	counters := NewCounters("")
+	counters.add(nil, stats, map[string]sets.String{"ns/policy": sets.NewString(names...)})
+	Expect(counters.Stats()).Should(Equal([]agentv1alpha1.PolicyRuleStats{
+		{Policy: "ns/policy", Rule: "default.ingress", Packets: 1, Bytes: 60},
+		{Policy: "ns/policy", Rule: "ingress.allow", Packets: 5, Bytes: 500},
+	}))
+}
+
+func TestCountersAcrossReinstall(t *testing.T) {
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "rulecounters.json")
+	policyRules := map[string]sets.String{"ns/policy": sets.NewString("ingress.allow")}
+	collect := func(counters *Counters, flow FlowStats) {
+		increment := counters.increment(flow)
+		counters.add(map[uint64]flowCounter{flow.Cookie: {Packets: flow.Packets, Bytes: flow.Bytes}}, []agentv1alpha1.PolicyRuleStats{
+			{Policy: "ns/policy", Rule: "ingress.allow", Packets: int64(increment.Packets), Bytes: int64(increment.Bytes)},
+		}, policyRules)
+	}
+
+	counters := NewCounters(path)
+	collect(counters, FlowStats{Cookie: 1, Packets: 3, Bytes: 300})
+	collect(counters, FlowStats{Cookie: 1, Packets: 5, Bytes: 500})
+	Expect(counters.Stats()).Should(Equal([]agentv1alpha1.PolicyRuleStats{
+		{Policy: "ns/policy", Rule: "ingress.allow", Packets: 5, Bytes: 500},
+	}))
+
+	// the agent restarted, the flow kept its counters
+	counters = NewCounters(path)
+	Expect(counters.Stats()).Should(HaveLen(1))
+	collect(counters, FlowStats{Cookie: 1, Packets: 6, Bytes: 600})
+	Expect(counters.Stats()).Should(Equal([]agentv1alpha1.PolicyRuleStats{
+		{Policy: "ns/policy", Rule: "ingress.allow", Packets: 6, Bytes: 600},
+	}))
+
+	// the flow reinstalled with a new cookie, or with the counters reset
+	collect(counters, FlowStats{Cookie: 2, Packets: 2, Bytes: 200})
+	collect(counters, FlowStats{Cookie: 2, Packets: 1, Bytes: 100})
+	Expect(counters.Stats()).Should(Equal([]agentv1alpha1.PolicyRuleStats{
+		{Policy: "ns/policy", Rule: "ingress.allow", Packets: 9, Bytes: 900},
+	}))
+
+	// the counters of the policy failed to get are kept, the policy removed are deleted
+	counters.add(nil, nil, map[string]sets.String{"ns/policy": nil})
+	Expect(counters.Stats()).Should(HaveLen(1))
+	counters.add(nil, nil, nil)
+	Expect(counters.Stats()).Should(BeEmpty())
+	Expect(NewCounters(path).Stats()).Should(BeEmpty())
+}
+
+func TestParseRuleReference(t *testing.T) {
+	RegisterTestingT(t)
+
+	namespace, name, rule, ok := parseRuleReference("ns/policy/normal/ingress.allow-http-a1b2c3")
+	Expect(ok).Should(BeTrue())
+	Expect(namespace).Should(Equal("ns"))
+	Expect(name).Should(Equal("policy"))
+	Expect(rule).Should(Equal("ingress.allow-http"))
+
+	_, _, _, ok = parseRuleReference("/default/global/global.ingress/-a1b2c3")
+	Expect(ok).Should(BeFalse())
+	_, _, _, ok = parseRuleReference("ns/policy/normal")
+	Expect(ok).Should(BeFalse())
+}
+
+func TestUnusedRules(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now().Truncate(time.Second)
+	policy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "policy",
+			Namespace:         "ns",
+			CreationTimestamp: metav1.NewTime(now.Add(-40 * 24 * time.Hour)),
+		},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			IngressRules: []securityv1alpha1.Rule{{Name: "allow-http"}, {Name: "allow-ssh"}},
+			EgressRules:  []securityv1alpha1.Rule{{Name: "allow-dns"}},
+		},
+	}
+
+	t.Run("merge last hits", func(t *testing.T) {
+		Expect(MergeLastHits(policy, map[string]time.Time{
+			"ingress.allow-http": now.Add(-time.Hour),
+			"egress.allow-dns":   now.Add(-35 * 24 * time.Hour),
+		}, time.Hour)).Should(BeTrue())
+		// older hits reported by other agents never override the newer ones
+		Expect(MergeLastHits(policy, map[string]time.Time{
+			"ingress.allow-http": now.Add(-2 * time.Hour),
+		}, time.Hour)).Should(BeFalse())
+		// the hits within the granularity are not updated
+		Expect(MergeLastHits(policy, map[string]time.Time{
+			"ingress.allow-http": now.Add(-30 * time.Minute),
+		}, time.Hour)).Should(BeFalse())
+
+		Expect(LastHits(policy)).Should(Equal(map[string]time.Time{
+			"ingress.allow-http": now.Add(-time.Hour).UTC(),
+			"egress.allow-dns":   now.Add(-35 * 24 * time.Hour).UTC(),
+		}))
+	})
+
+	t.Run("report unused rules", func(t *testing.T) {
+		unused := UnusedRules(policy, 30*24*time.Hour, now)
+		Expect(unused).Should(Equal([]string{"egress.allow-dns", "ingress.allow-ssh"}))
+
+		Expect(SetUnusedRules(policy, unused)).Should(BeTrue())
+		Expect(policy.Annotations).Should(HaveKeyWithValue(constants.UnusedRulesAnnotation, "egress.allow-dns,ingress.allow-ssh"))
+		Expect(SetUnusedRules(policy, unused)).Should(BeFalse())
+		Expect(SetUnusedRules(policy, nil)).Should(BeTrue())
+		Expect(policy.Annotations).ShouldNot(HaveKey(constants.UnusedRulesAnnotation))
+	})
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulehit
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultUnusedFor = 30 * 24 * time.Hour
+
+	// LastHitGranularity is the granularity of the recorded hit time, the policy is
+	// updated at most once per granularity for the hits, as every update of the policy
+	// is processed by all of the agents.
+	LastHitGranularity = time.Hour
+)
+
+// Datapath is the datapath the rule hits collected from.
+type Datapath interface {
+	// GetPolicyBridges returns the bridges the policy rules installed in.
+	GetPolicyBridges() []string
+	// GetRuleReferencesByFlowID returns the policy rules the flow installed for.
+	GetRuleReferencesByFlowID(flowID uint64) []string
+}
+
+type Config struct {
+	// Interval is the interval of collecting the flow stats.
+	Interval time.Duration
+	// UnusedFor is the duration of the rule unmatched before reported as unused.
+	UnusedFor time.Duration
+	// AnnotateUnused summarizes the unused rules in the policy annotation.
+	AnnotateUnused bool
+}
+
+// Tracker collects the rule hits of the local policy rules, and records the last hit
+// time in the policies.
+type Tracker struct {
+	client.Client
+	Datapath Datapath
+	Config   Config
+	// Counters sums the packets and bytes of the rules in each collection, the counters
+	// are not collected if nil.
+	Counters *Counters
+
+	// packets are the packets of the flows in the last collection, the flow matched
+	// since then if its packets increased
+	packets map[uint64]uint64
+}
+
+// FlowStats is the stats of an openflow flow.
+type FlowStats struct {
+	Cookie  uint64
+	Packets uint64
+	Bytes   uint64
+	// IdleAge is the duration since the flow last matched or modified, -1 if unknown.
+	IdleAge time.Duration
+}
+
+func (t *Tracker) Run(stopChan <-chan struct{}) {
+	if t.Config.Interval == 0 {
+		t.Config.Interval = DefaultInterval
+	}
+	if t.Config.UnusedFor == 0 {
+		t.Config.UnusedFor = DefaultUnusedFor
+	}
+	t.packets = make(map[uint64]uint64)
+
+	klog.Infof("start tracking rule hits every %s", t.Config.Interval)
+	wait.Until(t.collect, t.Config.Interval, stopChan)
+}
+
+func (t *Tracker) collect() {
+	now := time.Now()
+	policyHits := make(map[k8stypes.NamespacedName]map[string]time.Time)
+	policyCounters := make(map[k8stypes.NamespacedName]map[string]*agentv1alpha1.PolicyRuleStats)
+	packets := make(map[uint64]uint64)
+	flows := make(map[uint64]flowCounter)
+
+	for _, bridge := range t.Datapath.GetPolicyBridges() {
+		stats, err := dumpFlowStats(bridge)
+		if err != nil {
+			klog.Errorf("unable to collect rule hits on bridge %s: %s", bridge, err)
+			return
+		}
+		for _, flow := range stats {
+			references := t.Datapath.GetRuleReferencesByFlowID(flow.Cookie)
+			if len(references) == 0 {
+				continue
+			}
+			packets[flow.Cookie] = flow.Packets
+			hitTime, hit := t.hitTime(flow, now)
+			var increment flowCounter
+			if t.Counters != nil {
+				increment = t.Counters.increment(flow)
+				flows[flow.Cookie] = flowCounter{Packets: flow.Packets, Bytes: flow.Bytes}
+			}
+
+			for _, reference := range references {
+				namespace, name, rule, ok := parseRuleReference(reference)
+				if !ok {
+					continue
+				}
+				key := k8stypes.NamespacedName{Namespace: namespace, Name: name}
+				if policyHits[key] == nil {
+					policyHits[key] = make(map[string]time.Time)
+					policyCounters[key] = make(map[string]*agentv1alpha1.PolicyRuleStats)
+				}
+				if hit && hitTime.After(policyHits[key][rule]) {
+					policyHits[key][rule] = hitTime
+				}
+				if policyCounters[key][rule] == nil {
+					policyCounters[key][rule] = &agentv1alpha1.PolicyRuleStats{Policy: key.String(), Rule: rule}
+				}
+				policyCounters[key][rule].Packets += int64(increment.Packets)
+				policyCounters[key][rule].Bytes += int64(increment.Bytes)
+			}
+		}
+	}
+	t.packets = packets
+
+	var increments []agentv1alpha1.PolicyRuleStats
+	policyRules := make(map[string]sets.String, len(policyHits))
+	for key, hits := range policyHits {
+		var policy securityv1alpha1.SecurityPolicy
+		if err := t.Get(context.Background(), key, &policy); err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("unable to get policy %s: %s", key, err)
+				policyRules[key.String()] = nil
+			}
+			continue
+		}
+		names := append(PolicyRuleNames(&policy), defaultRuleNames...)
+
+		if t.Counters != nil {
+			increments = append(increments, mergeRuleCounters(names, policyCounters[key])...)
+			policyRules[key.String()] = sets.NewString(names...)
+		}
+		if err := t.updatePolicy(&policy, mergeRuleHits(names, hits), now); err != nil {
+			klog.Errorf("unable to update rule hits of policy %s: %s", key, err)
+		}
+	}
+	if t.Counters != nil {
+		t.Counters.add(flows, increments, policyRules)
+	}
+}
+
+// ruleNameOf returns the policy rule the flows generated for, the rules of the flows may have
+// suffixes to the policy rule, e.g. ingress.allow-http.0 in the symmetric mode and
+// egress.allow-http.namedport for the named ports. The longest matched name is returned.
+func ruleNameOf(names []string, rule string) string {
+	var matched string
+	for _, name := range names {
+		if (rule == name || strings.HasPrefix(rule, name+".")) && len(name) > len(matched) {
+			matched = name
+		}
+	}
+	if matched == "" {
+		return rule
+	}
+	return matched
+}
+
+// mergeRuleHits merges the hits of the flow rules into the policy rules, the latest is kept.
+func mergeRuleHits(names []string, hits map[string]time.Time) map[string]time.Time {
+	merged := make(map[string]time.Time, len(hits))
+	for rule, hitTime := range hits {
+		name := ruleNameOf(names, rule)
+		if hitTime.After(merged[name]) {
+			merged[name] = hitTime
+		}
+	}
+	return merged
+}
+
+// mergeRuleCounters sums the counters of the flow rules into the policy rules.
+func mergeRuleCounters(names []string, counters map[string]*agentv1alpha1.PolicyRuleStats) []agentv1alpha1.PolicyRuleStats {
+	merged := make(map[string]*agentv1alpha1.PolicyRuleStats, len(counters))
+	for rule, counter := range counters {
+		name := ruleNameOf(names, rule)
+		if merged[name] == nil {
+			merged[name] = &agentv1alpha1.PolicyRuleStats{Policy: counter.Policy, Rule: name}
+		}
+		merged[name].Packets += counter.Packets
+		merged[name].Bytes += counter.Bytes
+	}
+
+	stats := make([]agentv1alpha1.PolicyRuleStats, 0, len(merged))
+	for _, counter := range merged {
+		stats = append(stats, *counter)
+	}
+	return stats
+}
+
+// hitTime returns the last time the flow matched. The idle age counts from the flow
+// modified when the flow never matched, so it's used only if the flow has packets.
+// Without the idle age, the flow matched if its packets increased since the last
+// collection, the first collection of the flow is the baseline.
+func (t *Tracker) hitTime(flow FlowStats, now time.Time) (time.Time, bool) {
+	if flow.Packets == 0 {
+		return time.Time{}, false
+	}
+	if flow.IdleAge >= 0 {
+		return now.Add(-flow.IdleAge), true
+	}
+	lastPackets, ok := t.packets[flow.Cookie]
+	return now, ok && flow.Packets > lastPackets
+}
+
+// updatePolicy merges the hits into the policy. All the agents the policy applied to
+// record their hits in the same annotation, the newest hit is kept on conflicts.
+func (t *Tracker) updatePolicy(policy *securityv1alpha1.SecurityPolicy, hits map[string]time.Time, now time.Time) error {
+	changed := MergeLastHits(policy, hits, LastHitGranularity)
+	if t.Config.AnnotateUnused {
+		changed = SetUnusedRules(policy, UnusedRules(policy, t.Config.UnusedFor, now)) || changed
+	}
+	if !changed {
+		return nil
+	}
+
+	err := t.Update(context.Background(), policy)
+	if apierrors.IsConflict(err) {
+		// updated by other agents, merge the hits in the next collection
+		return nil
+	}
+	return err
+}
+
+// dumpFlowStats returns the stats of the flows on the bridge.
+func dumpFlowStats(bridge string) ([]FlowStats, error) {
+	out, err := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "dump-flows", bridge).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-ofctl dump-flows %s: %s: %s", bridge, err, strings.TrimSpace(string(out)))
+	}
+	return ParseFlowStats(string(out)), nil
+}
+
+// ParseFlowStats parses the output of ovs-ofctl dump-flows, e.g.
+// " cookie=0x4000000000003, duration=5.2s, table=0, n_packets=3, n_bytes=294, idle_age=2, priority=200,ip actions=drop"
+func ParseFlowStats(output string) []FlowStats {
+	var stats []FlowStats
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "cookie=") {
+			continue
+		}
+
+		flow := FlowStats{IdleAge: -1}
+		var valid bool
+		for _, field := range strings.Split(line, ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "cookie":
+				cookie, err := strconv.ParseUint(strings.TrimPrefix(kv[1], "0x"), 16, 64)
+				flow.Cookie, valid = cookie, err == nil
+			case "n_packets":
+				flow.Packets, _ = strconv.ParseUint(kv[1], 10, 64)
+			case "n_bytes":
+				flow.Bytes, _ = strconv.ParseUint(kv[1], 10, 64)
+			case "idle_age":
+				if age, err := strconv.ParseUint(kv[1], 10, 64); err == nil {
+					flow.IdleAge = time.Duration(age) * time.Second
+				}
+			}
+		}
+		if valid {
+			stats = append(stats, flow)
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package startup tracks the staged startup of the agent. The agent installs the
+// default flows first, then the local endpoints, then the policies, and reports
+// the duration of each phase, so the slow phase on busy hosts could be found.
+package startup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/metrics"
+)
+
+type Phase string
+
+const (
+	// PhaseDatapath is done when the bridges connected and the default flows installed.
+	PhaseDatapath Phase = "datapath"
+	// PhaseEndpoints is done when the local endpoints in ovsdb installed.
+	PhaseEndpoints Phase = "endpoints"
+	// PhasePolicy is done when the policy related resources synced, the policy rules
+	// are installed by the policy controller from then on.
+	PhasePolicy Phase = "policy"
+)
+
+var (
+	phaseDuration = metrics.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "agent_startup",
+		Name:      "phase_duration_seconds",
+		Help:      "Duration of the agent startup phase.",
+	}, []string{metrics.LabelPhase})
+	startupDuration = metrics.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "agent_startup",
+		Name:      "duration_seconds",
+		Help:      "Duration from the agent start to the last finished startup phase.",
+	})
+)
+
+func init() {
+	metrics.MustRegister(phaseDuration, startupDuration)
+}
+
+// Tracker records the duration of the startup phases, each phase starts when the
+// previous one done.
+type Tracker struct {
+	lock      sync.Mutex
+	start     time.Time
+	lastDone  time.Time
+	durations map[Phase]time.Duration
+}
+
+func NewTracker() *Tracker {
+	now := time.Now()
+	return &Tracker{
+		start:     now,
+		lastDone:  now,
+		durations: make(map[Phase]time.Duration),
+	}
+}
+
+// Done marks the phase done, the phase done again is ignored.
+func (t *Tracker) Done(phase Phase) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.durations[phase]; ok {
+		return
+	}
+	now := time.Now()
+	t.durations[phase] = now.Sub(t.lastDone)
+	t.lastDone = now
+
+	phaseDuration.WithLabelValues(string(phase)).Set(t.durations[phase].Seconds())
+	startupDuration.Set(now.Sub(t.start).Seconds())
+	klog.Infof("agent startup phase %s done in %s, %s since agent start", phase, t.durations[phase], now.Sub(t.start))
+}
+
+// Duration returns the duration of the phase, and whether the phase done.
+func (t *Tracker) Duration(phase Phase) (time.Duration, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	duration, ok := t.durations[phase]
+	return duration, ok
+}
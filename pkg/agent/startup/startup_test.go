@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTracker(t *testing.T) {
+	RegisterTestingT(t)
+
+	tracker := NewTracker()
+	_, ok := tracker.Duration(PhaseDatapath)
+	Expect(ok).Should(BeFalse())
+
+	time.Sleep(10 * time.Millisecond)
+	tracker.Done(PhaseDatapath)
+	datapathDuration, ok := tracker.Duration(PhaseDatapath)
+	Expect(ok).Should(BeTrue())
+	Expect(datapathDuration).Should(BeNumerically(">=", 10*time.Millisecond))
+
+	tracker.Done(PhaseEndpoints)
+	endpointsDuration, ok := tracker.Duration(PhaseEndpoints)
+	Expect(ok).Should(BeTrue())
+	Expect(endpointsDuration).Should(BeNumerically("<", datapathDuration))
+
+	// done again doesn't change the duration
+	time.Sleep(10 * time.Millisecond)
+	tracker.Done(PhaseDatapath)
+	duration, _ := tracker.Duration(PhaseDatapath)
+	Expect(duration).Should(Equal(datapathDuration))
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uplink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	arpFrameLen   = 42
+	arpOpRequest  = 1
+	arpOpReply    = 2
+	etherTypeARP  = 0x0806
+	etherTypeIPv4 = 0x0800
+)
+
+// ArpProbe send an arp request for the gateway out of the interface directly, and wait
+// for the reply until timeout. The interfaces attached to ovs bridges have no ip address,
+// so the sender ip is srcIP, or 0.0.0.0 as rfc5227 arp probe if srcIP not specified.
+func ArpProbe(ifaceName string, srcIP, gateway net.IP, timeout time.Duration) error {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return err
+	}
+	request, err := newArpRequest(iface.HardwareAddr, srcIP, gateway)
+	if err != nil {
+		return err
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(etherTypeARP)))
+	if err != nil {
+		return fmt.Errorf("create packet socket: %s", err)
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrLinklayer{Protocol: htons(etherTypeARP), Ifindex: iface.Index}
+	if err = unix.Bind(fd, addr); err != nil {
+		return fmt.Errorf("bind packet socket on %s: %s", ifaceName, err)
+	}
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return err
+	}
+	if err = unix.Sendto(fd, request, 0, addr); err != nil {
+		return fmt.Errorf("send arp request on %s: %s", ifaceName, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 128)
+	for time.Now().Before(deadline) {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return fmt.Errorf("no arp reply from %s on %s: %s", gateway, ifaceName, err)
+		}
+		if isArpReplyOf(buf[:n], gateway, iface.HardwareAddr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no arp reply from %s on %s in %s", gateway, ifaceName, timeout)
+}
+
+func newArpRequest(srcMAC net.HardwareAddr, srcIP, dstIP net.IP) ([]byte, error) {
+	if len(srcMAC) != 6 {
+		return nil, fmt.Errorf("invalid source mac %s", srcMAC)
+	}
+	if dstIP.To4() == nil {
+		return nil, fmt.Errorf("arp probe only support ipv4, got %s", dstIP)
+	}
+	if srcIP == nil {
+		srcIP = net.IPv4zero
+	}
+	if srcIP.To4() == nil {
+		return nil, fmt.Errorf("arp probe only support ipv4, got %s", srcIP)
+	}
+
+	frame := make([]byte, arpFrameLen)
+	// ethernet header: broadcast destination
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+	// arp payload
+	binary.BigEndian.PutUint16(frame[14:16], 1) // hardware type ethernet
+	binary.BigEndian.PutUint16(frame[16:18], etherTypeIPv4)
+	frame[18], frame[19] = 6, 4
+	binary.BigEndian.PutUint16(frame[20:22], arpOpRequest)
+	copy(frame[22:28], srcMAC)
+	copy(frame[28:32], srcIP.To4())
+	copy(frame[38:42], dstIP.To4())
+	return frame, nil
+}
+
+func isArpReplyOf(frame []byte, gateway net.IP, srcMAC net.HardwareAddr) bool {
+	if len(frame) < arpFrameLen || binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return false
+	}
+	return binary.BigEndian.Uint16(frame[20:22]) == arpOpReply &&
+		net.IP(frame[28:32]).Equal(gateway) &&
+		bytes.Equal(frame[32:38], srcMAC)
+}
+
+func htons(i uint16) uint16 {
+	return i<<8 | i>>8
+}
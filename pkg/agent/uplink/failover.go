@@ -0,0 +1,330 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uplink
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/agent/bfd"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/metrics"
+)
+
+const (
+	// FailoverEventReason is the reason of the event recorded on the agentinfo when uplink failover
+	FailoverEventReason = "UplinkFailover"
+	// NoHealthyUplinkEventReason is the reason of the event recorded when all the uplinks of a bridge failed
+	NoHealthyUplinkEventReason = "NoHealthyUplink"
+
+	DefaultProbeInterval    = time.Second
+	DefaultProbeTimeout     = 500 * time.Millisecond
+	DefaultFailureThreshold = 3
+)
+
+var (
+	failoverTotal = metrics.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "uplink",
+		Name:      "failover_total",
+		Help:      "Number of uplink failovers of the bridge.",
+	}, []string{metrics.LabelBridge, metrics.LabelFrom, metrics.LabelTo})
+	activeUplink = metrics.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "active",
+		Help:      "Whether the interface is the active uplink of the bridge.",
+	}, []string{metrics.LabelBridge, metrics.LabelInterface})
+	uplinkHealthy = metrics.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "healthy",
+		Help:      "Whether the uplink interface of the bridge is healthy.",
+	}, []string{metrics.LabelBridge, metrics.LabelInterface})
+)
+
+func init() {
+	metrics.MustRegister(failoverTotal, activeUplink, uplinkHealthy)
+}
+
+// Group is the uplinks of a bridge, only one of them is active at a time.
+type Group struct {
+	// Bridge is the uplink bridge the interfaces attached to.
+	Bridge string
+	// Bond is the active-backup bond port of the interfaces. If empty, each interface
+	// is an individual port of the bridge, and the standby ports are excluded from
+	// forwarding by openflow port config.
+	Bond string
+	// Interfaces are the uplink interfaces in priority order.
+	Interfaces []string
+	// Gateway is probed by arp through each interface if specified, the interface
+	// considered failed when the gateway unreachable.
+	Gateway string
+	// ProbeSourceIP is the sender ip of the arp probes, default 0.0.0.0.
+	ProbeSourceIP string
+}
+
+// Config defines how the uplinks are monitored and switched
+type Config struct {
+	Groups []Group
+	// ProbeInterval is the period of uplink health check.
+	ProbeInterval time.Duration
+	// ProbeTimeout is the timeout of an arp probe.
+	ProbeTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed probes, after which the
+	// interface is considered failed. Link down or bfd not forwarding fails the
+	// interface immediately.
+	FailureThreshold int
+	// Preempt switch back to the interface of higher priority once it recovers.
+	Preempt bool
+}
+
+// Manager monitor the health of the uplinks, failover to the standby uplink when
+// the active one failed, and record the failovers as events on the agentinfo.
+type Manager struct {
+	Recorder record.EventRecorder
+	Config   Config
+
+	// AgentName is the name of current agent, the events are recorded on its agentinfo
+	AgentName string
+
+	// linkUp, probe and activate replaced in testing
+	linkUp   func(iface string) (bool, error)
+	probe    func(iface string, srcIP, gateway net.IP, timeout time.Duration) error
+	activate func(group *Group, iface string) error
+
+	groups []*groupState
+}
+
+type groupState struct {
+	*Group
+	gateway  net.IP
+	sourceIP net.IP
+	// active is the current active interface, empty before the first activation
+	active   string
+	failures map[string]int
+	// noHealthy is true when all the interfaces failed, the event recorded once
+	noHealthy bool
+}
+
+func (m *Manager) Run(stopChan <-chan struct{}) {
+	if err := m.complete(); err != nil {
+		klog.Errorf("unable start uplink failover manager: %s", err)
+		return
+	}
+
+	klog.Infof("start uplink failover manager with %d groups", len(m.groups))
+	defer klog.Infof("shutting down uplink failover manager")
+
+	wait.Until(m.checkOnce, m.Config.ProbeInterval, stopChan)
+}
+
+func (m *Manager) complete() error {
+	if m.Config.ProbeInterval <= 0 {
+		m.Config.ProbeInterval = DefaultProbeInterval
+	}
+	if m.Config.ProbeTimeout <= 0 {
+		m.Config.ProbeTimeout = DefaultProbeTimeout
+	}
+	if m.Config.FailureThreshold <= 0 {
+		m.Config.FailureThreshold = DefaultFailureThreshold
+	}
+	if m.linkUp == nil {
+		m.linkUp = isLinkUp
+	}
+	if m.probe == nil {
+		m.probe = ArpProbe
+	}
+	if m.activate == nil {
+		m.activate = activateUplink
+	}
+
+	m.groups = nil
+	for i := range m.Config.Groups {
+		group := &m.Config.Groups[i]
+		if group.Bridge == "" || len(group.Interfaces) == 0 {
+			return fmt.Errorf("uplink group %d: bridge and interfaces must be specified", i)
+		}
+		state := &groupState{Group: group, failures: make(map[string]int)}
+		if group.Gateway != "" {
+			if state.gateway = net.ParseIP(group.Gateway).To4(); state.gateway == nil {
+				return fmt.Errorf("uplink group %s: invalid ipv4 gateway %s", group.Bridge, group.Gateway)
+			}
+		}
+		if group.ProbeSourceIP != "" {
+			if state.sourceIP = net.ParseIP(group.ProbeSourceIP).To4(); state.sourceIP == nil {
+				return fmt.Errorf("uplink group %s: invalid ipv4 probe source %s", group.Bridge, group.ProbeSourceIP)
+			}
+		}
+		m.groups = append(m.groups, state)
+	}
+	return nil
+}
+
+func (m *Manager) checkOnce() {
+	for _, group := range m.groups {
+		m.checkGroup(group)
+	}
+}
+
+func (m *Manager) checkGroup(group *groupState) {
+	healthy := make(map[string]bool, len(group.Interfaces))
+	for _, iface := range group.Interfaces {
+		healthy[iface] = m.isHealthy(group, iface)
+		uplinkHealthy.WithLabelValues(group.Bridge, iface).Set(boolToFloat(healthy[iface]))
+	}
+
+	target := group.active
+	if target == "" || !healthy[target] || m.Config.Preempt {
+		target = selectUplink(group.Interfaces, healthy)
+	}
+
+	if target == "" {
+		if !group.noHealthy {
+			group.noHealthy = true
+			klog.Errorf("all the uplinks %v of bridge %s failed", group.Interfaces, group.Bridge)
+			m.recordEvent(corev1.EventTypeWarning, NoHealthyUplinkEventReason,
+				"all the uplinks %v of bridge %s failed", group.Interfaces, group.Bridge)
+		}
+		if group.active != "" {
+			return
+		}
+		// activate the primary uplink at startup, even if it's not healthy yet
+		target = group.Interfaces[0]
+	} else {
+		group.noHealthy = false
+	}
+
+	if target == group.active {
+		return
+	}
+	if err := m.activate(group.Group, target); err != nil {
+		klog.Errorf("unable activate uplink %s of bridge %s: %s", target, group.Bridge, err)
+		return
+	}
+
+	if group.active == "" {
+		klog.Infof("activate uplink %s of bridge %s", target, group.Bridge)
+	} else {
+		klog.Warningf("uplink of bridge %s failover from %s to %s", group.Bridge, group.active, target)
+		failoverTotal.WithLabelValues(group.Bridge, group.active, target).Inc()
+		m.recordEvent(corev1.EventTypeWarning, FailoverEventReason,
+			"uplink of bridge %s failover from %s to %s", group.Bridge, group.active, target)
+	}
+	for _, iface := range group.Interfaces {
+		activeUplink.WithLabelValues(group.Bridge, iface).Set(boolToFloat(iface == target))
+	}
+	group.active = target
+}
+
+// isHealthy return false if the link down, or the gateway probe failed consecutively
+func (m *Manager) isHealthy(group *groupState, iface string) bool {
+	up, err := m.linkUp(iface)
+	if err != nil {
+		klog.Errorf("unable get link state of %s: %s", iface, err)
+	}
+	if !up {
+		group.failures[iface] = m.Config.FailureThreshold
+		return false
+	}
+
+	if group.gateway == nil {
+		group.failures[iface] = 0
+		return true
+	}
+	if err = m.probe(iface, group.sourceIP, group.gateway, m.Config.ProbeTimeout); err != nil {
+		klog.V(4).Infof("probe gateway through %s: %s", iface, err)
+		group.failures[iface]++
+	} else {
+		group.failures[iface] = 0
+	}
+	return group.failures[iface] < m.Config.FailureThreshold
+}
+
+func (m *Manager) recordEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if m.Recorder == nil {
+		return
+	}
+	agentInfo := &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: m.AgentName}}
+	m.Recorder.Eventf(agentInfo, eventType, reason, messageFmt, args...)
+}
+
+// selectUplink return the healthy uplink of the highest priority, or empty if none healthy.
+func selectUplink(interfaces []string, healthy map[string]bool) string {
+	for _, iface := range interfaces {
+		if healthy[iface] {
+			return iface
+		}
+	}
+	return ""
+}
+
+// isLinkUp return false if the link down, or bfd enabled on it and reports not forwarding
+func isLinkUp(iface string) (bool, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return false, err
+	}
+	if link.Attrs().OperState != netlink.OperUp {
+		return false, nil
+	}
+
+	bfdStatus, err := bfd.GetStatus(iface)
+	if err != nil {
+		return false, err
+	}
+	return bfdStatus == nil || bfdStatus.Forwarding, nil
+}
+
+// activateUplink set the active member of the bond, or exclude the standby ports
+// from forwarding when the uplinks are individual ports.
+func activateUplink(group *Group, active string) error {
+	if group.Bond != "" {
+		_, err := appctl.Run(appctl.CommandBondSetActive, group.Bond, active)
+		return err
+	}
+
+	for _, iface := range group.Interfaces {
+		actions := []string{"receive", "forward", "flood"}
+		if iface != active {
+			actions = []string{"no-receive", "no-forward", "no-flood"}
+		}
+		for _, action := range actions {
+			out, err := exec.Command("ovs-ofctl", "mod-port", group.Bridge, iface, action).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("ovs-ofctl mod-port %s %s %s: %s: %s", group.Bridge, iface, action, err, strings.TrimSpace(string(out)))
+			}
+		}
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
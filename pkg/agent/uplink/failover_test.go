@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uplink
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeUplinks struct {
+	linkDown    map[string]bool
+	probeFailed map[string]bool
+	active      string
+	activations int
+}
+
+func newManager(uplinks *fakeUplinks, preempt bool) (*Manager, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	m := &Manager{
+		Recorder:  recorder,
+		AgentName: "agent01",
+		Config: Config{
+			Groups: []Group{{
+				Bridge:     "ovsbr0-uplink",
+				Interfaces: []string{"ens192", "ens224"},
+				Gateway:    "192.168.1.1",
+			}},
+			FailureThreshold: 2,
+			Preempt:          preempt,
+		},
+		linkUp: func(iface string) (bool, error) { return !uplinks.linkDown[iface], nil },
+		probe: func(iface string, _, _ net.IP, _ time.Duration) error {
+			if uplinks.probeFailed[iface] {
+				return fmt.Errorf("timeout")
+			}
+			return nil
+		},
+		activate: func(_ *Group, iface string) error {
+			uplinks.active = iface
+			uplinks.activations++
+			return nil
+		},
+	}
+	Expect(m.complete()).Should(Succeed())
+	return m, recorder
+}
+
+func TestFailover(t *testing.T) {
+	t.Run("should failover on link down", func(t *testing.T) {
+		RegisterTestingT(t)
+		uplinks := &fakeUplinks{linkDown: map[string]bool{}, probeFailed: map[string]bool{}}
+		m, recorder := newManager(uplinks, false)
+
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens192"))
+		Expect(recorder.Events).ShouldNot(Receive())
+
+		uplinks.linkDown["ens192"] = true
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens224"))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(FailoverEventReason)))
+
+		// not preempt when the primary recovers
+		uplinks.linkDown["ens192"] = false
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens224"))
+		Expect(uplinks.activations).Should(Equal(2))
+	})
+
+	t.Run("should failover after consecutive probe failures", func(t *testing.T) {
+		RegisterTestingT(t)
+		uplinks := &fakeUplinks{linkDown: map[string]bool{}, probeFailed: map[string]bool{}}
+		m, _ := newManager(uplinks, false)
+
+		m.checkOnce()
+		uplinks.probeFailed["ens192"] = true
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens192"))
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens224"))
+	})
+
+	t.Run("should preempt when the primary recovers", func(t *testing.T) {
+		RegisterTestingT(t)
+		uplinks := &fakeUplinks{linkDown: map[string]bool{"ens192": true}, probeFailed: map[string]bool{}}
+		m, _ := newManager(uplinks, true)
+
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens224"))
+		uplinks.linkDown["ens192"] = false
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens192"))
+	})
+
+	t.Run("should keep the active uplink when all failed", func(t *testing.T) {
+		RegisterTestingT(t)
+		uplinks := &fakeUplinks{linkDown: map[string]bool{}, probeFailed: map[string]bool{}}
+		m, recorder := newManager(uplinks, false)
+
+		m.checkOnce()
+		uplinks.linkDown["ens192"], uplinks.linkDown["ens224"] = true, true
+		m.checkOnce()
+		m.checkOnce()
+		Expect(uplinks.active).Should(Equal("ens192"))
+		Expect(uplinks.activations).Should(Equal(1))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(NoHealthyUplinkEventReason)))
+		Expect(recorder.Events).ShouldNot(Receive())
+	})
+}
+
+func TestArpRequest(t *testing.T) {
+	RegisterTestingT(t)
+
+	mac, _ := net.ParseMAC("52:54:00:00:00:01")
+	gateway := net.ParseIP("192.168.1.1")
+	request, err := newArpRequest(mac, nil, gateway)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(request).Should(HaveLen(arpFrameLen))
+	Expect(request[28:32]).Should(Equal([]byte{0, 0, 0, 0}))
+	Expect(request[38:42]).Should(Equal([]byte{192, 168, 1, 1}))
+
+	_, err = newArpRequest(mac, nil, net.ParseIP("fe80::1"))
+	Expect(err).Should(HaveOccurred())
+
+	reply := make([]byte, arpFrameLen)
+	copy(reply, request)
+	reply[21] = arpOpReply
+	copy(reply[28:32], gateway.To4())
+	copy(reply[32:38], mac)
+	Expect(isArpReplyOf(reply, gateway, mac)).Should(BeTrue())
+	Expect(isArpReplyOf(reply, net.ParseIP("192.168.1.2"), mac)).Should(BeFalse())
+	Expect(isArpReplyOf(request, gateway, mac)).Should(BeFalse())
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// AccessVLAN returns the vlan of the untagged packets on the port, 0 if the port is a trunk
+// port or not tagged.
+func (c *VlanConfig) AccessVLAN() int32 {
+	if c == nil || c.VlanMode == VlanModeTrunk {
+		return 0
+	}
+	return c.Tag
+}
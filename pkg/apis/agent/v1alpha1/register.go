@@ -28,6 +28,12 @@ func init() {
 	SchemeBuilder.Register(
 		&AgentInfo{},
 		&AgentInfoList{},
+		&NetworkTopology{},
+		&NetworkTopologyList{},
+		&ClusterStatus{},
+		&ClusterStatusList{},
+		&EffectiveRuleSet{},
+		&EffectiveRuleSetList{},
 	)
 }
 
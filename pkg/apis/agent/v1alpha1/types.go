@@ -20,6 +20,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/types"
 )
 
@@ -29,7 +30,11 @@ import (
 // +k8s:openapi-gen=true
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
-// +kubebuilder:resource:scope=Cluster,path=agentinfos
+// +kubebuilder:resource:scope=Cluster,path=agentinfos,shortName=agi
+// +kubebuilder:printcolumn:name="Hostname",type="string",JSONPath=".hostname"
+// +kubebuilder:printcolumn:name="OVSVersion",type="string",JSONPath=".ovsInfo.version"
+// +kubebuilder:printcolumn:name="Healthy",type="string",JSONPath=".conditions[?(@.type==\"AgentHealthy\")].status"
+// +kubebuilder:printcolumn:name="LastHeartbeat",type="date",JSONPath=".conditions[?(@.type==\"AgentHealthy\")].lastHeartbeatTime"
 
 type AgentInfo struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -38,16 +43,155 @@ type AgentInfo struct {
 	Hostname   string           `json:"hostname,omitempty"`
 	OVSInfo    OVSInfo          `json:"ovsInfo,omitempty"`
 	Conditions []AgentCondition `json:"conditions,omitempty"`
+
+	// VTEPInfo is the hardware vtep gateways in the hardware_vtep database, only reported
+	// when the agent monitors the database.
+	VTEPInfo *VTEPInfo `json:"vtepInfo,omitempty"`
+
+	// PolicyRuleStats are the counters of the policy rules matched on the agent, only reported
+	// when the rule hit tracker reports the counters.
+	PolicyRuleStats []PolicyRuleStats `json:"policyRuleStats,omitempty"`
+
+	// Features are the optional features enabled on the agent.
+	Features []AgentFeature `json:"features,omitempty"`
+
+	// RuleFeatures are the features of the policy rules the agent supports, the agent skips
+	// the rules requiring the others. The agents not reported support the baseline features.
+	RuleFeatures []securityv1alpha1.RuleFeature `json:"ruleFeatures,omitempty"`
+
+	// Capacity is the limits of the agent, the controller estimates the policy flows on the
+	// agent against it before distributing the policies. Only reported when configured.
+	Capacity *AgentCapacity `json:"capacity,omitempty"`
 }
 
+// AgentCapacity is the limits of the flows the agent could realize.
+type AgentCapacity struct {
+	// MaxPolicyFlows is the most policy rule flows the agent could realize.
+	MaxPolicyFlows int32 `json:"maxPolicyFlows,omitempty"`
+}
+
+type AgentFeature string
+
+const (
+	FeatureFlowLog            AgentFeature = "FlowLog"
+	FeatureDenyLogging        AgentFeature = "DenyLogging"
+	FeaturePortScanQuarantine AgentFeature = "PortScanQuarantine"
+)
+
 type OVSInfo struct {
 	Version string      `json:"version,omitempty"`
 	Bridges []OVSBridge `json:"bridges,omitempty"`
 }
 
+// VTEPInfo is the physical switches of the hardware vtep gateways, and the logical switches
+// bridged by them.
+type VTEPInfo struct {
+	PhysicalSwitches []VTEPPhysicalSwitch `json:"physicalSwitches,omitempty"`
+	LogicalSwitches  []VTEPLogicalSwitch  `json:"logicalSwitches,omitempty"`
+}
+
+type VTEPPhysicalSwitch struct {
+	Name string `json:"name,omitempty"`
+	// TunnelIPs are the ips of the physical switch terminating the vxlan tunnels.
+	TunnelIPs []string           `json:"tunnelIPs,omitempty"`
+	Ports     []VTEPPhysicalPort `json:"ports,omitempty"`
+}
+
+type VTEPPhysicalPort struct {
+	Name string `json:"name,omitempty"`
+	// VlanBindings are the vlans on the port bound to the logical switches.
+	VlanBindings []VTEPVlanBinding `json:"vlanBindings,omitempty"`
+}
+
+type VTEPVlanBinding struct {
+	VLAN          int32  `json:"vlan"`
+	LogicalSwitch string `json:"logicalSwitch"`
+}
+
+type VTEPLogicalSwitch struct {
+	Name string `json:"name,omitempty"`
+	// TunnelKey is the vxlan vni of the logical switch.
+	TunnelKey int32 `json:"tunnelKey,omitempty"`
+	// LocalMACs are the macs learned by the physical switches in the logical switch, the
+	// workloads attached to the hardware gateways, at most MaxReportedVTEPLocalMACs of them.
+	LocalMACs []VTEPMAC `json:"localMACs,omitempty"`
+	// LocalMACCount is the number of the macs learned in the logical switch.
+	LocalMACCount int32 `json:"localMACCount,omitempty"`
+}
+
+// MaxReportedVTEPLocalMACs limits the local macs reported on each logical switch
+const MaxReportedVTEPLocalMACs = 1024
+
+type VTEPMAC struct {
+	MAC string `json:"mac"`
+	IP  string `json:"ip,omitempty"`
+}
+
 type OVSBridge struct {
 	Name  string    `json:"name,omitempty"`
 	Ports []OVSPort `json:"ports,omitempty"`
+
+	// SpanningTree is the spanning tree protocol enabled on the bridge, empty if disabled.
+	SpanningTree SpanningTreeMode `json:"spanningTree,omitempty"`
+
+	// ConntrackZone is the conntrack zone of the connections tracked by the policy of the vds,
+	// only reported for the bridges of the vds managed by everoute.
+	ConntrackZone *int32 `json:"conntrackZone,omitempty"`
+
+	// VRF is the vrf of the vds, empty for the default vrf. The endpoints on the bridges of
+	// different vrfs could have overlapping ips.
+	VRF string `json:"vrf,omitempty"`
+
+	// SFlow is the sflow sampling on the bridge, nil if disabled.
+	SFlow *SFlowStatus `json:"sflow,omitempty"`
+	// Mirrors are the port mirrors on the bridge.
+	Mirrors []MirrorStatus `json:"mirrors,omitempty"`
+
+	// VLANs are the broadcast domains on the bridge, discovered from the tags and the trunks
+	// of the ports.
+	VLANs []VLANInfo `json:"vlans,omitempty"`
+}
+
+// VLANInfo is a vlan on the bridge and the ports in it. The ports trunk all the vlans are
+// not listed.
+type VLANInfo struct {
+	ID int32 `json:"id"`
+	// AccessPorts are the ports the untagged packets of which are in the vlan, the endpoints
+	// attached to the ports are in the vlan.
+	AccessPorts []string `json:"accessPorts,omitempty"`
+	// TrunkPorts are the ports carry the packets of the vlan tagged.
+	TrunkPorts []string `json:"trunkPorts,omitempty"`
+}
+
+type SpanningTreeMode string
+
+const (
+	SpanningTreeSTP  SpanningTreeMode = "STP"
+	SpanningTreeRSTP SpanningTreeMode = "RSTP"
+)
+
+type SFlowStatus struct {
+	// Agent is the interface the address of which used as the agent address.
+	Agent    string   `json:"agent,omitempty"`
+	Targets  []string `json:"targets,omitempty"`
+	Sampling int32    `json:"sampling,omitempty"`
+	Polling  int32    `json:"polling,omitempty"`
+	Header   int32    `json:"header,omitempty"`
+	// Managed is true if configured by the agent.
+	Managed bool `json:"managed,omitempty"`
+}
+
+type MirrorStatus struct {
+	Name           string   `json:"name,omitempty"`
+	SelectAll      bool     `json:"selectAll,omitempty"`
+	SelectSrcPorts []string `json:"selectSrcPorts,omitempty"`
+	SelectDstPorts []string `json:"selectDstPorts,omitempty"`
+	SelectVLANs    []int32  `json:"selectVLANs,omitempty"`
+	// OutputPort is the port the mirrored packets sent to, empty if flooded in OutputVLAN.
+	OutputPort string `json:"outputPort,omitempty"`
+	OutputVLAN int32  `json:"outputVLAN,omitempty"`
+	// Managed is true if configured by the agent.
+	Managed bool `json:"managed,omitempty"`
 }
 
 type OVSPort struct {
@@ -57,6 +201,31 @@ type OVSPort struct {
 
 	VlanConfig *VlanConfig `json:"vlanConfig,omitempty"`
 	BondConfig *BondConfig `json:"bondConfig,omitempty"`
+
+	// LearnedMACs are the remote macs learned on the port, only reported for the
+	// uplink bridges, at most MaxReportedLearnedMACs of them.
+	LearnedMACs []LearnedMAC `json:"learnedMACs,omitempty"`
+	// LearnedMACCount is the number of the remote macs learned on the port.
+	LearnedMACCount int32 `json:"learnedMACCount,omitempty"`
+
+	// SpanningTreeStatus is the port state and role, only reported when spanning tree
+	// enabled on the bridge.
+	SpanningTreeStatus *SpanningTreeStatus `json:"spanningTreeStatus,omitempty"`
+}
+
+type SpanningTreeStatus struct {
+	// State is the port state, e.g. forwarding, blocking, discarding.
+	State string `json:"state,omitempty"`
+	// Role is the port role, e.g. root, designated, alternate.
+	Role string `json:"role,omitempty"`
+}
+
+// MaxReportedLearnedMACs limits the learned macs reported on each port
+const MaxReportedLearnedMACs = 64
+
+type LearnedMAC struct {
+	MAC  string `json:"mac"`
+	VLAN int32  `json:"vlan,omitempty"`
 }
 
 type VlanMode string
@@ -72,7 +241,11 @@ const (
 type VlanConfig struct {
 	VlanMode VlanMode `json:"vlanMode,omitempty"`
 	Tag      int32    `json:"tag,omitempty"`
-	Trunk    string   `json:"trunk,omitempty"`
+	// Trunk is the trunks joined by comma, kept for the former readers.
+	// Deprecated: use Trunks instead.
+	Trunk string `json:"trunk,omitempty"`
+	// Trunks are the vlans trunked on the port, sorted without duplicates.
+	Trunks types.Trunks `json:"trunks,omitempty"`
 }
 
 type BondMode string
@@ -94,6 +267,77 @@ type OVSInterface struct {
 	Ofport      int32                           `json:"ofport,omitempty"`
 	Mac         string                          `json:"mac,omitempty"`
 	IPMap       map[types.IPAddress]metav1.Time `json:"ipmap,omitempty"`
+	// IPLeaseMap is the lease expiry of the ips learned from the dhcp acks, the ips in it
+	// expire at the end of the lease instead of the aging time after learned.
+	IPLeaseMap map[types.IPAddress]metav1.Time `json:"ipLeaseMap,omitempty"`
+	// IPSourceMap is the source the ips in IPMap learned from, the one with the highest
+	// priority if learned from multiple sources.
+	IPSourceMap map[types.IPAddress]IPSource `json:"ipSourceMap,omitempty"`
+	// LinkState is the link_state of the interface in ovsdb, up or down.
+	LinkState string `json:"linkState,omitempty"`
+	// BFDStatus is the bfd_status of the interface in ovsdb, only reported when bfd enabled.
+	BFDStatus *BFDStatus `json:"bfdStatus,omitempty"`
+	// LLDPNeighbor is the neighbor learned by lldpd on the interface, only reported
+	// when lldpd running on the host.
+	LLDPNeighbor *LLDPNeighbor `json:"lldpNeighbor,omitempty"`
+	// ConnectionStats is the usage of the connections tracked of the ips in IPMap, only
+	// reported when the conntrack poller enabled.
+	ConnectionStats *ConnectionStats `json:"connectionStats,omitempty"`
+}
+
+// IPSource is the source the ip of an interface learned from.
+type IPSource string
+
+const (
+	// IPSourceDeclared is the ip bound to the interface by the management plane.
+	IPSourceDeclared IPSource = "Declared"
+	// IPSourceDHCP is the ip allocated in the dhcp ack to the interface.
+	IPSourceDHCP IPSource = "DHCP"
+	// IPSourceARP is the ip announced in the arp or the neighbor discovery packets from the
+	// interface.
+	IPSourceARP IPSource = "ARP"
+)
+
+type LLDPNeighbor struct {
+	ChassisID         string `json:"chassisID,omitempty"`
+	SystemName        string `json:"systemName,omitempty"`
+	SystemDescription string `json:"systemDescription,omitempty"`
+	PortID            string `json:"portID,omitempty"`
+	PortDescription   string `json:"portDescription,omitempty"`
+}
+
+type BFDStatus struct {
+	// State is the local bfd session state, one of admin_down, down, init, up.
+	State string `json:"state,omitempty"`
+	// Forwarding is true when the interface considered capable of forwarding by bfd.
+	Forwarding bool `json:"forwarding,omitempty"`
+	// RemoteState is the bfd session state reported by the remote endpoint.
+	RemoteState string `json:"remoteState,omitempty"`
+	// Diagnostic is the reason of the last session state change.
+	Diagnostic string `json:"diagnostic,omitempty"`
+}
+
+// ConnectionStats is the usage of the connections in the policy conntrack zones.
+type ConnectionStats struct {
+	// ActiveConnections is the number of the connections tracked.
+	ActiveConnections int32 `json:"activeConnections"`
+	// Packets and Bytes are the counters of the connections tracked in both directions, they
+	// are always zero without the conntrack accounting (net.netfilter.nf_conntrack_acct).
+	Packets int64 `json:"packets"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// PolicyRuleStats is the counters of the flows of a policy rule.
+type PolicyRuleStats struct {
+	// Policy is the namespace/name of the SecurityPolicy.
+	Policy string `json:"policy"`
+	// Rule is the rule of the policy named as direction.ruleName, e.g. ingress.allow-http,
+	// the default rules are named default.ingress and default.egress.
+	Rule string `json:"rule"`
+	// Packets and Bytes are the cumulative counters of the flows of the rule, kept across the
+	// flows reinstalled, a flow shared by the rules is counted in each of them.
+	Packets int64 `json:"packets"`
+	Bytes   int64 `json:"bytes"`
 }
 
 type AgentConditionType string
@@ -103,14 +347,18 @@ const (
 	ApiserverConnectionUp AgentConditionType = "ApiserverConnectionUp" // Status True/False is used to mark the connection status between Agent and Apiserver.
 	OVSDBConnectionUp     AgentConditionType = "OVSDBConnectionUp"     // Status True/False is used to mark OVSDB connection status.
 	OpenflowConnectionUp  AgentConditionType = "OpenflowConnectionUp"  // Status True/False is used to mark Openflow connection status.
+	FlowTableFull         AgentConditionType = "FlowTableFull"         // Status True/False is used to mark whether the datapath flows reach the limit.
+	PolicySynced          AgentConditionType = "PolicySynced"          // Status True/False is used to mark whether the policy flows have been synced since the agent start.
+	IPLearnerActive       AgentConditionType = "IPLearnerActive"       // Status True/False is used to mark whether the ip learner keeps up with the learned ips.
 )
 
 type AgentCondition struct {
-	Type              AgentConditionType     `json:"type"`
-	Status            corev1.ConditionStatus `json:"status"`
-	LastHeartbeatTime metav1.Time            `json:"lastHeartbeatTime"`
-	Reason            string                 `json:"reason,omitempty"`
-	Message           string                 `json:"message,omitempty"`
+	Type               AgentConditionType     `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastHeartbeatTime  metav1.Time            `json:"lastHeartbeatTime"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -121,3 +369,209 @@ type AgentInfoList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []AgentInfo `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=networktopologies
+
+// NetworkTopology is the network layout of an agent, built by everoute-controller
+// from the AgentInfo of the same name. It's read only for users and tools.
+type NetworkTopology struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Hostname   string `json:"hostname,omitempty"`
+	OVSVersion string `json:"ovsVersion,omitempty"`
+
+	Bridges []TopologyBridge `json:"bridges,omitempty"`
+	// Links are the patch connections between the bridges of everoute bridge chains.
+	Links []TopologyLink `json:"links,omitempty"`
+
+	// LastUpdateTime is the time the topology last changed.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+type BridgeRole string
+
+const (
+	BridgeRoleLocal  BridgeRole = "Local"
+	BridgeRolePolicy BridgeRole = "Policy"
+	BridgeRoleCls    BridgeRole = "Cls"
+	BridgeRoleUplink BridgeRole = "Uplink"
+	BridgeRoleNat    BridgeRole = "Nat"
+	// BridgeRoleUnmanaged is the bridges not part of everoute bridge chains.
+	BridgeRoleUnmanaged BridgeRole = "Unmanaged"
+)
+
+type TopologyBridge struct {
+	Name         string           `json:"name"`
+	Role         BridgeRole       `json:"role"`
+	SpanningTree SpanningTreeMode `json:"spanningTree,omitempty"`
+
+	// Uplinks are the ports of physical nics, only reported for uplink bridges.
+	Uplinks []TopologyUplink `json:"uplinks,omitempty"`
+	// Tunnels are the tunnel interfaces on the bridge.
+	Tunnels []TopologyTunnel `json:"tunnels,omitempty"`
+	// EndpointCount is the number of the vm or pod interfaces, only reported for local bridges.
+	EndpointCount int32 `json:"endpointCount,omitempty"`
+}
+
+type TopologyUplink struct {
+	Name       string                    `json:"name"`
+	BondMode   BondMode                  `json:"bondMode,omitempty"`
+	Interfaces []TopologyUplinkInterface `json:"interfaces,omitempty"`
+}
+
+type TopologyUplinkInterface struct {
+	Name      string `json:"name"`
+	Mac       string `json:"mac,omitempty"`
+	LinkState string `json:"linkState,omitempty"`
+	// BFDState is the bfd session state, empty if bfd not enabled.
+	BFDState string        `json:"bfdState,omitempty"`
+	Neighbor *LLDPNeighbor `json:"neighbor,omitempty"`
+}
+
+type TopologyTunnel struct {
+	Name string `json:"name"`
+	// Type is the interface type, e.g. geneve, vxlan.
+	Type string `json:"type"`
+	// BFDState is the bfd session state, empty if bfd not enabled.
+	BFDState string `json:"bfdState,omitempty"`
+}
+
+type TopologyLink struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NetworkTopologyList contains a list of NetworkTopology
+type NetworkTopologyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NetworkTopology `json:"items"`
+}
+
+// ClusterStatusName is the name of the singleton ClusterStatus.
+const ClusterStatusName = "everoute"
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=clusterstatuses
+// +kubebuilder:printcolumn:name="Agents",type="integer",JSONPath=".agents.total"
+// +kubebuilder:printcolumn:name="HealthyAgents",type="integer",JSONPath=".agents.healthy"
+// +kubebuilder:printcolumn:name="Policies",type="integer",JSONPath=".policies.total"
+// +kubebuilder:printcolumn:name="RealizedPolicies",type="integer",JSONPath=".policies.realized"
+// +kubebuilder:printcolumn:name="ControllerVersion",type="string",JSONPath=".controllerVersion"
+// +kubebuilder:printcolumn:name="LastFullReconcile",type="date",JSONPath=".lastFullReconcileTime"
+
+// ClusterStatus is the health summary of everoute, maintained by everoute-controller as
+// a singleton named "everoute". It's read only for users and tools.
+type ClusterStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// ControllerVersion is the version of the everoute-controller maintains the summary.
+	ControllerVersion string `json:"controllerVersion,omitempty"`
+
+	Agents   AgentsSummary   `json:"agents"`
+	Policies PoliciesSummary `json:"policies"`
+	// ErrorGroups are the EndpointGroups their members could not be resolved.
+	ErrorGroups []string `json:"errorGroups,omitempty"`
+
+	// LastFullReconcileTime is the time the summary last recomputed from all the objects.
+	LastFullReconcileTime metav1.Time `json:"lastFullReconcileTime,omitempty"`
+}
+
+type AgentsSummary struct {
+	Total int32 `json:"total"`
+	// Healthy is the number of the agents with heartbeat in time.
+	Healthy int32 `json:"healthy"`
+}
+
+type PoliciesSummary struct {
+	Total int32 `json:"total"`
+	// Realized is the number of the SecurityPolicies compiled into rules successfully.
+	Realized int32 `json:"realized"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterStatusList contains a list of ClusterStatus
+type ClusterStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterStatus `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=effectiverulesets,shortName=ers
+// +kubebuilder:printcolumn:name="Rules",type="integer",JSONPath=".ruleCount"
+// +kubebuilder:printcolumn:name="Hash",type="string",JSONPath=".hash"
+// +kubebuilder:printcolumn:name="Generated",type="date",JSONPath=".generatedTime"
+
+// EffectiveRuleSet is the rules active in the datapath of an agent, in the order they are
+// evaluated, with the policies they generated from. It's named as the agent and regenerated
+// by the agent when the rules changed, as the evidence of the deployed rules.
+type EffectiveRuleSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Hash is the sha256 of all the rules active, it changes only when the rules changed.
+	Hash string `json:"hash"`
+	// RuleCount is the number of the rules active, which may be more than the rules listed
+	// if truncated.
+	RuleCount int32 `json:"ruleCount"`
+	// Truncated means only the first rules are listed to limit the size of the object.
+	Truncated bool `json:"truncated,omitempty"`
+	// GeneratedTime is the time the rules last changed and exported.
+	GeneratedTime metav1.Time `json:"generatedTime,omitempty"`
+
+	Rules []EffectiveRule `json:"rules,omitempty"`
+}
+
+// EffectiveRule is a rule installed in the datapath.
+type EffectiveRule struct {
+	// Name of the rule, in format of policyNamespace/policyName/policyType/ruleName-flowKey
+	// for the rules of the policies.
+	Name      string `json:"name"`
+	Direction string `json:"direction"`
+	Tier      string `json:"tier"`
+	Priority  int32  `json:"priority"`
+	Action    string `json:"action"`
+	// Mode is the enforcement mode of the rule, work or monitor.
+	Mode        string `json:"mode,omitempty"`
+	SrcIPAddr   string `json:"srcIPAddr,omitempty"`
+	DstIPAddr   string `json:"dstIPAddr,omitempty"`
+	IPProtocol  int32  `json:"ipProtocol,omitempty"`
+	SrcPort     int32  `json:"srcPort,omitempty"`
+	SrcPortMask int32  `json:"srcPortMask,omitempty"`
+	DstPort     int32  `json:"dstPort,omitempty"`
+	DstPortMask int32  `json:"dstPortMask,omitempty"`
+	// Policies the rule generated from, in format of namespace/name/type, the namespace is
+	// empty for the global policy.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EffectiveRuleSetList contains a list of EffectiveRuleSet
+type EffectiveRuleSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EffectiveRuleSet `json:"items"`
+}
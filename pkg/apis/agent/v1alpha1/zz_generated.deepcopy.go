@@ -24,13 +24,31 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	types "github.com/everoute/everoute/pkg/types"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentCapacity) DeepCopyInto(out *AgentCapacity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentCapacity.
+func (in *AgentCapacity) DeepCopy() *AgentCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentCondition) DeepCopyInto(out *AgentCondition) {
 	*out = *in
 	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 	return
 }
 
@@ -57,6 +75,31 @@ func (in *AgentInfo) DeepCopyInto(out *AgentInfo) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.VTEPInfo != nil {
+		in, out := &in.VTEPInfo, &out.VTEPInfo
+		*out = new(VTEPInfo)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PolicyRuleStats != nil {
+		in, out := &in.PolicyRuleStats, &out.PolicyRuleStats
+		*out = make([]PolicyRuleStats, len(*in))
+		copy(*out, *in)
+	}
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]AgentFeature, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuleFeatures != nil {
+		in, out := &in.RuleFeatures, &out.RuleFeatures
+		*out = make([]securityv1alpha1.RuleFeature, len(*in))
+		copy(*out, *in)
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(AgentCapacity)
+		**out = **in
+	}
 	return
 }
 
@@ -111,6 +154,37 @@ func (in *AgentInfoList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentsSummary) DeepCopyInto(out *AgentsSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentsSummary.
+func (in *AgentsSummary) DeepCopy() *AgentsSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentsSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BFDStatus) DeepCopyInto(out *BFDStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BFDStatus.
+func (in *BFDStatus) DeepCopy() *BFDStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BFDStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BondConfig) DeepCopyInto(out *BondConfig) {
 	*out = *in
@@ -127,6 +201,308 @@ func (in *BondConfig) DeepCopy() *BondConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Agents = in.Agents
+	out.Policies = in.Policies
+	if in.ErrorGroups != nil {
+		in, out := &in.ErrorGroups, &out.ErrorGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastFullReconcileTime.DeepCopyInto(&out.LastFullReconcileTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
+func (in *ClusterStatus) DeepCopy() *ClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterStatusList) DeepCopyInto(out *ClusterStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatusList.
+func (in *ClusterStatusList) DeepCopy() *ClusterStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionStats) DeepCopyInto(out *ConnectionStats) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionStats.
+func (in *ConnectionStats) DeepCopy() *ConnectionStats {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveRule) DeepCopyInto(out *EffectiveRule) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveRule.
+func (in *EffectiveRule) DeepCopy() *EffectiveRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveRuleSet) DeepCopyInto(out *EffectiveRuleSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.GeneratedTime.DeepCopyInto(&out.GeneratedTime)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]EffectiveRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveRuleSet.
+func (in *EffectiveRuleSet) DeepCopy() *EffectiveRuleSet {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveRuleSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EffectiveRuleSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveRuleSetList) DeepCopyInto(out *EffectiveRuleSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EffectiveRuleSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveRuleSetList.
+func (in *EffectiveRuleSetList) DeepCopy() *EffectiveRuleSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveRuleSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EffectiveRuleSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LLDPNeighbor) DeepCopyInto(out *LLDPNeighbor) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LLDPNeighbor.
+func (in *LLDPNeighbor) DeepCopy() *LLDPNeighbor {
+	if in == nil {
+		return nil
+	}
+	out := new(LLDPNeighbor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LearnedMAC) DeepCopyInto(out *LearnedMAC) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LearnedMAC.
+func (in *LearnedMAC) DeepCopy() *LearnedMAC {
+	if in == nil {
+		return nil
+	}
+	out := new(LearnedMAC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MirrorStatus) DeepCopyInto(out *MirrorStatus) {
+	*out = *in
+	if in.SelectSrcPorts != nil {
+		in, out := &in.SelectSrcPorts, &out.SelectSrcPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SelectDstPorts != nil {
+		in, out := &in.SelectDstPorts, &out.SelectDstPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SelectVLANs != nil {
+		in, out := &in.SelectVLANs, &out.SelectVLANs
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MirrorStatus.
+func (in *MirrorStatus) DeepCopy() *MirrorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MirrorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkTopology) DeepCopyInto(out *NetworkTopology) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Bridges != nil {
+		in, out := &in.Bridges, &out.Bridges
+		*out = make([]TopologyBridge, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]TopologyLink, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkTopology.
+func (in *NetworkTopology) DeepCopy() *NetworkTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NetworkTopology) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkTopologyList) DeepCopyInto(out *NetworkTopologyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NetworkTopology, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkTopologyList.
+func (in *NetworkTopologyList) DeepCopy() *NetworkTopologyList {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkTopologyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NetworkTopologyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OVSBridge) DeepCopyInto(out *OVSBridge) {
 	*out = *in
@@ -137,6 +513,30 @@ func (in *OVSBridge) DeepCopyInto(out *OVSBridge) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ConntrackZone != nil {
+		in, out := &in.ConntrackZone, &out.ConntrackZone
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SFlow != nil {
+		in, out := &in.SFlow, &out.SFlow
+		*out = new(SFlowStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]MirrorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VLANs != nil {
+		in, out := &in.VLANs, &out.VLANs
+		*out = make([]VLANInfo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -190,6 +590,35 @@ func (in *OVSInterface) DeepCopyInto(out *OVSInterface) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.IPLeaseMap != nil {
+		in, out := &in.IPLeaseMap, &out.IPLeaseMap
+		*out = make(map[types.IPAddress]v1.Time, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.IPSourceMap != nil {
+		in, out := &in.IPSourceMap, &out.IPSourceMap
+		*out = make(map[types.IPAddress]IPSource, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BFDStatus != nil {
+		in, out := &in.BFDStatus, &out.BFDStatus
+		*out = new(BFDStatus)
+		**out = **in
+	}
+	if in.LLDPNeighbor != nil {
+		in, out := &in.LLDPNeighbor, &out.LLDPNeighbor
+		*out = new(LLDPNeighbor)
+		**out = **in
+	}
+	if in.ConnectionStats != nil {
+		in, out := &in.ConnectionStats, &out.ConnectionStats
+		*out = new(ConnectionStats)
+		**out = **in
+	}
 	return
 }
 
@@ -223,13 +652,23 @@ func (in *OVSPort) DeepCopyInto(out *OVSPort) {
 	if in.VlanConfig != nil {
 		in, out := &in.VlanConfig, &out.VlanConfig
 		*out = new(VlanConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.BondConfig != nil {
 		in, out := &in.BondConfig, &out.BondConfig
 		*out = new(BondConfig)
 		**out = **in
 	}
+	if in.LearnedMACs != nil {
+		in, out := &in.LearnedMACs, &out.LearnedMACs
+		*out = make([]LearnedMAC, len(*in))
+		copy(*out, *in)
+	}
+	if in.SpanningTreeStatus != nil {
+		in, out := &in.SpanningTreeStatus, &out.SpanningTreeStatus
+		*out = new(SpanningTreeStatus)
+		**out = **in
+	}
 	return
 }
 
@@ -243,9 +682,344 @@ func (in *OVSPort) DeepCopy() *OVSPort {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoliciesSummary) DeepCopyInto(out *PoliciesSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoliciesSummary.
+func (in *PoliciesSummary) DeepCopy() *PoliciesSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(PoliciesSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRuleStats) DeepCopyInto(out *PolicyRuleStats) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRuleStats.
+func (in *PolicyRuleStats) DeepCopy() *PolicyRuleStats {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRuleStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFlowStatus) DeepCopyInto(out *SFlowStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFlowStatus.
+func (in *SFlowStatus) DeepCopy() *SFlowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SFlowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpanningTreeStatus) DeepCopyInto(out *SpanningTreeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpanningTreeStatus.
+func (in *SpanningTreeStatus) DeepCopy() *SpanningTreeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SpanningTreeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyBridge) DeepCopyInto(out *TopologyBridge) {
+	*out = *in
+	if in.Uplinks != nil {
+		in, out := &in.Uplinks, &out.Uplinks
+		*out = make([]TopologyUplink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tunnels != nil {
+		in, out := &in.Tunnels, &out.Tunnels
+		*out = make([]TopologyTunnel, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyBridge.
+func (in *TopologyBridge) DeepCopy() *TopologyBridge {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyBridge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyLink) DeepCopyInto(out *TopologyLink) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyLink.
+func (in *TopologyLink) DeepCopy() *TopologyLink {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyLink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyTunnel) DeepCopyInto(out *TopologyTunnel) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyTunnel.
+func (in *TopologyTunnel) DeepCopy() *TopologyTunnel {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyTunnel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyUplink) DeepCopyInto(out *TopologyUplink) {
+	*out = *in
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]TopologyUplinkInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyUplink.
+func (in *TopologyUplink) DeepCopy() *TopologyUplink {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyUplink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyUplinkInterface) DeepCopyInto(out *TopologyUplinkInterface) {
+	*out = *in
+	if in.Neighbor != nil {
+		in, out := &in.Neighbor, &out.Neighbor
+		*out = new(LLDPNeighbor)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyUplinkInterface.
+func (in *TopologyUplinkInterface) DeepCopy() *TopologyUplinkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyUplinkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VLANInfo) DeepCopyInto(out *VLANInfo) {
+	*out = *in
+	if in.AccessPorts != nil {
+		in, out := &in.AccessPorts, &out.AccessPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TrunkPorts != nil {
+		in, out := &in.TrunkPorts, &out.TrunkPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLANInfo.
+func (in *VLANInfo) DeepCopy() *VLANInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(VLANInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPInfo) DeepCopyInto(out *VTEPInfo) {
+	*out = *in
+	if in.PhysicalSwitches != nil {
+		in, out := &in.PhysicalSwitches, &out.PhysicalSwitches
+		*out = make([]VTEPPhysicalSwitch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LogicalSwitches != nil {
+		in, out := &in.LogicalSwitches, &out.LogicalSwitches
+		*out = make([]VTEPLogicalSwitch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPInfo.
+func (in *VTEPInfo) DeepCopy() *VTEPInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPLogicalSwitch) DeepCopyInto(out *VTEPLogicalSwitch) {
+	*out = *in
+	if in.LocalMACs != nil {
+		in, out := &in.LocalMACs, &out.LocalMACs
+		*out = make([]VTEPMAC, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPLogicalSwitch.
+func (in *VTEPLogicalSwitch) DeepCopy() *VTEPLogicalSwitch {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPLogicalSwitch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPMAC) DeepCopyInto(out *VTEPMAC) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPMAC.
+func (in *VTEPMAC) DeepCopy() *VTEPMAC {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPMAC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPPhysicalPort) DeepCopyInto(out *VTEPPhysicalPort) {
+	*out = *in
+	if in.VlanBindings != nil {
+		in, out := &in.VlanBindings, &out.VlanBindings
+		*out = make([]VTEPVlanBinding, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPPhysicalPort.
+func (in *VTEPPhysicalPort) DeepCopy() *VTEPPhysicalPort {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPPhysicalPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPPhysicalSwitch) DeepCopyInto(out *VTEPPhysicalSwitch) {
+	*out = *in
+	if in.TunnelIPs != nil {
+		in, out := &in.TunnelIPs, &out.TunnelIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]VTEPPhysicalPort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPPhysicalSwitch.
+func (in *VTEPPhysicalSwitch) DeepCopy() *VTEPPhysicalSwitch {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPPhysicalSwitch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VTEPVlanBinding) DeepCopyInto(out *VTEPVlanBinding) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VTEPVlanBinding.
+func (in *VTEPVlanBinding) DeepCopy() *VTEPVlanBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(VTEPVlanBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VlanConfig) DeepCopyInto(out *VlanConfig) {
 	*out = *in
+	if in.Trunks != nil {
+		in, out := &in.Trunks, &out.Trunks
+		*out = make(types.Trunks, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
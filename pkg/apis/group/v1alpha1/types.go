@@ -53,6 +53,36 @@ type GroupMember struct {
 	EndpointAgent []string             `json:"endpointAgent,omitempty"`
 	IPs           []types.IPAddress    `json:"ips,omitempty"`
 	Ports         []v1alpha1.NamedPort `json:"ports,omitempty"`
+	// Metadata of the endpoint, only populated when the group members enrichment
+	// enabled in the controller.
+	// +optional
+	Metadata *EndpointMetadata `json:"metadata,omitempty"`
+}
+
+// EndpointType is the type of the member endpoint.
+type EndpointType string
+
+const (
+	EndpointTypePod      EndpointType = "Pod"
+	EndpointTypeVM       EndpointType = "VM"
+	EndpointTypeExternal EndpointType = "External"
+)
+
+// EndpointMetadata describes the member endpoint, so the agents and the UIs could
+// use the members without lookup the endpoints.
+type EndpointMetadata struct {
+	// Namespace of the endpoint.
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the endpoint.
+	Name string `json:"name,omitempty"`
+	// Node where the endpoint located, empty if the endpoint not located or located on
+	// multiple nodes, e.g. during migration.
+	Node string `json:"node,omitempty"`
+	// LabelsHash is the hash of the labels and the extend labels of the endpoint, it
+	// changes when the labels change.
+	LabelsHash string `json:"labelsHash,omitempty"`
+	// Type of the endpoint, Pod, VM or External.
+	Type EndpointType `json:"type,omitempty"`
 }
 
 type EndpointReference struct {
@@ -105,21 +135,23 @@ type GroupMembersPatchList struct {
 
 // +genclient
 // +genclient:nonNamespaced
-// +genclient:noStatus
 // +k8s:openapi-gen=true
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
-// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:resource:scope=Cluster,shortName=eg
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="EndpointSelector",type="string",JSONPath=".spec.endpointSelector"
 // +kubebuilder:printcolumn:name="NamespaceSelector",type="string",JSONPath=".spec.namespaceSelector"
 // +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".spec.namespace"
 // +kubebuilder:printcolumn:name="Endpoint",type="string",JSONPath=".spec.endpoint"
+// +kubebuilder:printcolumn:name="Members",type="integer",JSONPath=".status.memberCount"
 
 type EndpointGroup struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec EndpointGroupSpec `json:"spec"`
+	Spec   EndpointGroupSpec   `json:"spec"`
+	Status EndpointGroupStatus `json:"status,omitempty"`
 }
 
 // EndpointGroupSpec defines the desired state for EndpointGroup.
@@ -154,8 +186,35 @@ type EndpointGroupSpec struct {
 	Namespace *string `json:"namespace,omitempty"`
 
 	Endpoint *v1alpha1.NamespacedName `json:"endpoint,omitempty"`
+
+	// VRF selects the endpoints in the VRF only, empty means the default VRF.
+	// +optional
+	VRF string `json:"vrf,omitempty"`
+
+	// VLANs selects the endpoints in the vlans only, empty means the endpoints in all the
+	// vlans. The vlan of an endpoint is the vlan in its status, or the VID if not reported.
+	// +optional
+	VLANs []int32 `json:"vlans,omitempty"`
 }
 
+// EndpointGroupStatus is the membership of the group, so the users could verify the selectors
+// match the endpoints they expect without reading the GroupMembers.
+type EndpointGroupStatus struct {
+	// Revision is the revision of the GroupMembers the status synced from.
+	Revision int32 `json:"revision,omitempty"`
+	// MemberCount is the number of the members.
+	MemberCount int32 `json:"memberCount,omitempty"`
+	// MembersHash is the hash of the members, the sha256 in hex of the sorted namespaced
+	// names of the member endpoints joined by newlines.
+	MembersHash string `json:"membersHash,omitempty"`
+	// Members are the namespaced names of the member endpoints, sorted, at most
+	// MaxReportedMembers of them. Read the GroupMembers for all the members.
+	Members []v1alpha1.NamespacedName `json:"members,omitempty"`
+}
+
+// MaxReportedMembers limits the members reported in the status of each group
+const MaxReportedMembers = 64
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // EndpointGroupList contains a list of EndpointGroup
@@ -35,6 +35,7 @@ func (in *EndpointGroup) DeepCopyInto(out *EndpointGroup) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -112,6 +113,11 @@ func (in *EndpointGroupSpec) DeepCopyInto(out *EndpointGroupSpec) {
 		*out = new(securityv1alpha1.NamespacedName)
 		**out = **in
 	}
+	if in.VLANs != nil {
+		in, out := &in.VLANs, &out.VLANs
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -125,6 +131,43 @@ func (in *EndpointGroupSpec) DeepCopy() *EndpointGroupSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointGroupStatus) DeepCopyInto(out *EndpointGroupStatus) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]securityv1alpha1.NamespacedName, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointGroupStatus.
+func (in *EndpointGroupStatus) DeepCopy() *EndpointGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointMetadata) DeepCopyInto(out *EndpointMetadata) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointMetadata.
+func (in *EndpointMetadata) DeepCopy() *EndpointMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EndpointReference) DeepCopyInto(out *EndpointReference) {
 	*out = *in
@@ -160,6 +203,11 @@ func (in *GroupMember) DeepCopyInto(out *GroupMember) {
 		*out = make([]securityv1alpha1.NamedPort, len(*in))
 		copy(*out, *in)
 	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(EndpointMetadata)
+		**out = **in
+	}
 	return
 }
 
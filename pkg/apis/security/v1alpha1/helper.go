@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"reflect"
+	"sort"
+
 	networkingv1 "k8s.io/api/networking/v1"
 )
 
@@ -45,3 +48,83 @@ func (p *SecurityPolicy) IsEnable() (ingressEnabled bool, egressEnabled bool) {
 func (p PolicyMode) String() string {
 	return string(p)
 }
+
+// GetVLAN returns the vlan the endpoint in, the vlan reported in the status takes
+// precedence over the VID in the spec.
+func (e *Endpoint) GetVLAN() int32 {
+	if e.Status.VLAN != 0 {
+		return e.Status.VLAN
+	}
+	return int32(e.Spec.VID)
+}
+
+// GetPorts returns the ports of the exception, the well-known ports of the type are
+// returned if the ports not specified, empty matches all ports.
+func (e *GlobalPolicyException) GetPorts() []SecurityPolicyPort {
+	if len(e.Ports) != 0 {
+		return e.Ports
+	}
+	switch e.Type {
+	case GlobalPolicyExceptionKubeAPIServer:
+		return []SecurityPolicyPort{{Protocol: ProtocolTCP, PortRange: "6443"}}
+	case GlobalPolicyExceptionDNS:
+		return []SecurityPolicyPort{{Protocol: ProtocolTCP, PortRange: "53"}, {Protocol: ProtocolUDP, PortRange: "53"}}
+	}
+	return nil
+}
+
+// RequiredFeatures returns the rule features the rule requires, in the order of the
+// RuleFeature constants, empty if the rule supported by all the agents.
+func (r *Rule) RequiredFeatures() []RuleFeature {
+	var fqdn, builtin, icmpTypeCode bool
+	for _, peers := range [][]SecurityPolicyPeer{r.From, r.To} {
+		for _, peer := range peers {
+			fqdn = fqdn || peer.FQDN != ""
+			builtin = builtin || peer.Builtin != ""
+		}
+	}
+	for _, port := range r.Ports {
+		icmpTypeCode = icmpTypeCode || port.ICMPType != nil || port.ICMPCode != nil
+	}
+
+	var features []RuleFeature
+	if fqdn {
+		features = append(features, RuleFeatureFQDN)
+	}
+	if r.L7 != nil {
+		features = append(features, RuleFeatureL7)
+	}
+	if r.Action == RuleActionRedirect {
+		features = append(features, RuleFeatureRedirect)
+	}
+	if icmpTypeCode {
+		features = append(features, RuleFeatureICMPTypeCode)
+	}
+	if builtin {
+		features = append(features, RuleFeatureBuiltinPeer)
+	}
+	return features
+}
+
+// RequiredRuleFeatures returns the features required by the rules of the policy, the rules
+// named as direction.ruleName, the rules without any required feature are omitted.
+func (p *SecurityPolicy) RequiredRuleFeatures() []RuleFeatureRequirement {
+	var requirements []RuleFeatureRequirement
+	for direction, rules := range map[string][]Rule{"ingress": p.Spec.IngressRules, "egress": p.Spec.EgressRules} {
+		for i := range rules {
+			if features := rules[i].RequiredFeatures(); len(features) != 0 {
+				requirements = append(requirements, RuleFeatureRequirement{Rule: direction + "." + rules[i].Name, Features: features})
+			}
+		}
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].Rule < requirements[j].Rule })
+	return requirements
+}
+
+// ProposalChanged returns true if the changes, the groups or the ports of the plan changed,
+// which are proposed by the author.
+func (s *PolicyPlanSpec) ProposalChanged(oldSpec *PolicyPlanSpec) bool {
+	return !reflect.DeepEqual(oldSpec.Changes, s.Changes) ||
+		!reflect.DeepEqual(oldSpec.Groups, s.Groups) ||
+		!reflect.DeepEqual(oldSpec.Ports, s.Ports)
+}
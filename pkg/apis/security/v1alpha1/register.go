@@ -32,6 +32,18 @@ func init() {
 		&SecurityPolicyList{},
 		&GlobalPolicy{},
 		&GlobalPolicyList{},
+		&Quarantine{},
+		&QuarantineList{},
+		&ComplianceReport{},
+		&ComplianceReportList{},
+		&ReachabilityMatrix{},
+		&ReachabilityMatrixList{},
+		&PolicyPlan{},
+		&PolicyPlanList{},
+		&NamespaceDefaultPolicy{},
+		&NamespaceDefaultPolicyList{},
+		&Tier{},
+		&TierList{},
 	)
 }
 
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"strings"
 
+	authv1 "k8s.io/api/authentication/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
@@ -27,6 +28,7 @@ import (
 	"github.com/everoute/everoute/pkg/types"
 )
 
+// +kubebuilder:validation:Enum=work;monitor
 type PolicyMode string
 
 const (
@@ -39,10 +41,13 @@ const (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=secpol
 // +kubebuilder:printcolumn:name="Tier",type="string",JSONPath=".spec.tier"
 // +kubebuilder:printcolumn:name="SymmetricMode",type="boolean",JSONPath=".spec.symmetricMode"
 // +kubebuilder:printcolumn:name="PolicyTypes",type="string",JSONPath=".spec.policyTypes"
 // +kubebuilder:printcolumn:name="Enforcement",type="string",JSONPath=".spec.securityPolicyEnforcementMode"
+// +kubebuilder:printcolumn:name="AppliedEndpoints",type="integer",JSONPath=".status.appliedEndpoints"
+// +kubebuilder:printcolumn:name="RealizedNodes",type="string",JSONPath=".status.realizedNodes",priority=1
 
 // SecurityPolicy describes what network traffic is allowed for a set of Endpoint.
 // Follow NetworkPolicy https://github.com/kubernetes/api/blob/v0.22.1/networking/v1/types.go#L29.
@@ -52,8 +57,84 @@ type SecurityPolicy struct {
 
 	// Specification of the desired behavior for this SecurityPolicy.
 	Spec SecurityPolicySpec `json:"spec"`
+
+	// Status is the current state of the SecurityPolicy.
+	// +optional
+	Status SecurityPolicyStatus `json:"status,omitempty"`
+}
+
+// SecurityPolicyStatus describe the current state of the SecurityPolicy
+type SecurityPolicyStatus struct {
+	// Conditions are the latest observations of the SecurityPolicy.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// AppliedEndpoints is the number of the endpoints the policy applied to.
+	// +optional
+	AppliedEndpoints int32 `json:"appliedEndpoints,omitempty"`
+
+	// RealizedNodes are the agents where the applied endpoints located, the policy
+	// flows are realized on these agents.
+	// +optional
+	RealizedNodes []string `json:"realizedNodes,omitempty"`
+
+	// RuleStats are the counters of the rules summed from the realized nodes, the counters
+	// are cumulative across the flows reinstalled, e.g. the agent restarted.
+	// +optional
+	RuleStats []RuleStats `json:"ruleStats,omitempty"`
+
+	// RuleFeatures are the features required by the rules, the agents skip the rules requiring
+	// the features they don't support, include the features introduced after the agents.
+	// +optional
+	RuleFeatures []RuleFeatureRequirement `json:"ruleFeatures,omitempty"`
 }
 
+// RuleFeatureRequirement is the features required by a rule of the SecurityPolicy.
+type RuleFeatureRequirement struct {
+	// Rule is named as direction.ruleName, e.g. ingress.allow-http.
+	Rule     string        `json:"rule"`
+	Features []RuleFeature `json:"features"`
+}
+
+// RuleFeature is a construct of the rules which might not be supported by the agents, the
+// agents of the former versions during upgrades, or the agents with the feature disabled.
+type RuleFeature string
+
+const (
+	// RuleFeatureFQDN is the peers by the domain names, requires the fqdn policy enabled.
+	RuleFeatureFQDN RuleFeature = "FQDN"
+	// RuleFeatureL7 is the application layer criteria, requires the l7 proxy enabled.
+	RuleFeatureL7 RuleFeature = "L7"
+	// RuleFeatureRedirect is the action Redirect.
+	RuleFeatureRedirect RuleFeature = "Redirect"
+	// RuleFeatureICMPTypeCode is the icmp type and code of the ports.
+	RuleFeatureICMPTypeCode RuleFeature = "ICMPTypeCode"
+	// RuleFeatureBuiltinPeer is the builtin peers.
+	RuleFeatureBuiltinPeer RuleFeature = "BuiltinPeer"
+)
+
+// BaselineRuleFeatures are the rule features supported by the agents not advertised their
+// rule features, the agents before the negotiation introduced.
+var BaselineRuleFeatures = []RuleFeature{RuleFeatureRedirect, RuleFeatureICMPTypeCode, RuleFeatureBuiltinPeer}
+
+// RuleStats is the counters of the packets and bytes matched by a rule of the SecurityPolicy.
+type RuleStats struct {
+	// Rule is named as direction.ruleName, e.g. ingress.allow-http, the default rules are
+	// named default.ingress and default.egress.
+	Rule    string `json:"rule"`
+	Packets int64  `json:"packets"`
+	Bytes   int64  `json:"bytes"`
+}
+
+const (
+	// SecurityPolicyCompiled is false if the policy could not be compiled, e.g. the policy
+	// created before the validation changed.
+	SecurityPolicyCompiled = "Compiled"
+	// SecurityPolicyFeaturesSupported is false if any realized node doesn't support the features
+	// required by the rules, the rules are skipped on the node.
+	SecurityPolicyFeaturesSupported = "FeaturesSupported"
+)
+
 // DefaultRuleType defines default rule type inSecurityPolicy.
 // +kubebuilder:validation:Enum=drop;allow;none
 type DefaultRuleType string
@@ -70,10 +151,12 @@ const (
 // SecurityPolicySpec provides the specification of a SecurityPolicy
 type SecurityPolicySpec struct {
 	// Tier specifies the tier to which this SecurityPolicy belongs to.
-	// In v1alpha1, Tier only support tier0, tier1, tier2, tier-ecp.
+	// In v1alpha1, Tier support tier0, tier1, tier2, tier-ecp and the name of any Tier.
 	Tier string `json:"tier"`
 
-	// Work mode specify the policy enforcement state: monitor or work
+	// Work mode specify the policy enforcement state: monitor or work.
+	// The rules in monitor mode only count the packets matched and log the packets they
+	// would deny when deny logging enabled, the traffic is not dropped.
 	// +kubebuilder:default=work
 	SecurityPolicyEnforcementMode PolicyMode `json:"securityPolicyEnforcementMode,omitempty"`
 
@@ -111,6 +194,12 @@ type SecurityPolicySpec struct {
 	// an Egress section and would otherwise default to just [ "Ingress" ]).
 	// +optional
 	PolicyTypes []networkingv1.PolicyType `json:"policyTypes,omitempty"`
+
+	// VRF is the logical network the SecurityPolicy scoped in. The policy only applies to and
+	// selects the endpoints in the VRF, the IPBlocks in the rules are the addresses of the VRF.
+	// Empty means the default VRF.
+	// +optional
+	VRF string `json:"vrf,omitempty"`
 }
 
 // ApplyToPeer describes sets of endpoints which this SecurityPolicy object applies
@@ -133,6 +222,11 @@ type ApplyToPeer struct {
 	// If this field is set then neither of the other fields can be.
 	// +optional
 	EndpointSelector *labels.Selector `json:"endpointSelector,omitempty"`
+
+	// VLANs limits the endpoints selected by EndpointSelector to the vlans, empty means
+	// the endpoints in all the vlans.
+	// +optional
+	VLANs []int32 `json:"vlans,omitempty"`
 }
 
 // Rule describes a particular set of traffic that is allowed from/to the endpoints
@@ -166,11 +260,86 @@ type Rule struct {
 	// This field only works when rule is egress.
 	// +optional
 	To []SecurityPolicyPeer `json:"to,omitempty"`
+
+	// Action of the rule, default Allow. Redirect means steer the matching traffic
+	// to RedirectTo, e.g. a honeypot, instead of the original destination.
+	// +optional
+	// +kubebuilder:default=Allow
+	Action RuleAction `json:"action,omitempty"`
+
+	// RedirectTo is the target of matching traffic, must be set when Action is Redirect.
+	// The redirected traffic keeps its original destination mac address, so the target
+	// should be reachable through the same next hop as the original destination.
+	// +optional
+	RedirectTo *RedirectTarget `json:"redirectTo,omitempty"`
+
+	// L7 limits the allowed traffic by the application layer criteria, it's experimental.
+	// The tcp connections matching the rule are redirected to the l7 proxy of the agent,
+	// which allows the http requests matching HTTP, or the tls connections with the server
+	// name matching SNI. Only works in the egress rules of the policy not in symmetric mode,
+	// the proxy connects to the server from the agent, the server sees the address of the
+	// agent instead of the endpoint. The rule is ignored if the l7 proxy is disabled on the agent.
+	// +optional
+	L7 *L7Match `json:"l7,omitempty"`
+}
+
+// L7Match describes the application layer criteria of the rule, the traffic is allowed if
+// any of the criteria matched.
+type L7Match struct {
+	// HTTP matches the http requests by the method and the path.
+	// +optional
+	HTTP []HTTPMatch `json:"http,omitempty"`
+
+	// SNI matches the server name indication in the tls client hello, e.g. www.example.com,
+	// or *.example.com matches all the subdomains of example.com.
+	// +optional
+	SNI []string `json:"sni,omitempty"`
+}
+
+// HTTPMatch describes the http requests to match.
+type HTTPMatch struct {
+	// Method of the request, e.g. GET, empty matches all the methods.
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Path is the regular expression matches the whole path of the request, e.g. /api/v1/.*,
+	// empty matches all the paths.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// RuleAction defines actions supported for Rule.
+// +kubebuilder:validation:Enum=Allow;Redirect
+type RuleAction string
+
+const (
+	// RuleActionAllow allow the matching traffic.
+	RuleActionAllow RuleAction = "Allow"
+	// RuleActionRedirect redirect the matching traffic to the RedirectTo target.
+	RuleActionRedirect RuleAction = "Redirect"
+)
+
+// RedirectTarget describes where the traffic redirect to.
+type RedirectTarget struct {
+	// IP of the redirect target.
+	IP string `json:"ip"`
+
+	// Port of the redirect target, keep the original destination port if not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
 }
 
 // SecurityPolicyPeer describes a peer to allow traffic to/from. Only certain combinations
 // of fields are allowed
 type SecurityPolicyPeer struct {
+	// Builtin defines policy on the peer computed by everoute, the endpoints and the cidrs
+	// in the cluster, or everything out of the cluster. If this field is set then neither
+	// of the other fields can be.
+	// +optional
+	Builtin BuiltinPeer `json:"builtin,omitempty"`
+
 	// DisableSymmetric if set true, won't generate symmetric rules for the peer even if
 	// SymmetricMode of policy set true, the default value is false
 	// +optional
@@ -180,6 +349,13 @@ type SecurityPolicyPeer struct {
 	// +optional
 	IPBlock *networkingv1.IPBlock `json:"ipBlock,omitempty"`
 
+	// FQDN defines policy on the ip addresses the dns name resolved to, e.g. www.example.com,
+	// or *.example.com matches all the subdomains of example.com. The addresses are learned
+	// from the dns responses to the endpoints, and expire with the ttl of the records. If this
+	// field is set then neither of the other fields can be.
+	// +optional
+	FQDN string `json:"fqdn,omitempty"`
+
 	// Endpoint defines policy on a specific Endpoint. If this field is set then
 	// neither of the other fields can be.
 	// +optional
@@ -202,8 +378,31 @@ type SecurityPolicyPeer struct {
 	// Otherwise, it selects all Endpoints in the Namespaces selected by NamespaceSelector.
 	// +optional
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ServiceAccount selects the endpoints of the pods running under the service account
+	// in the policy's own Namespace, or in the Namespaces selected by NamespaceSelector. If
+	// EndpointSelector is also set, the endpoints must match both.
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// VLANs limits the endpoints selected by EndpointSelector or NamespaceSelector to the
+	// vlans, empty means the endpoints in all the vlans.
+	// +optional
+	VLANs []int32 `json:"vlans,omitempty"`
 }
 
+// BuiltinPeer is the peer computed by everoute instead of listed in the policy.
+// +kubebuilder:validation:Enum=ClusterInternal;ClusterExternal
+type BuiltinPeer string
+
+const (
+	// BuiltinPeerClusterInternal is the ips of all the endpoints in the vrf of the policy,
+	// and the pod and the vm cidrs of the cluster.
+	BuiltinPeerClusterInternal BuiltinPeer = "ClusterInternal"
+	// BuiltinPeerClusterExternal is the ipv4 addresses not in ClusterInternal.
+	BuiltinPeerClusterExternal BuiltinPeer = "ClusterExternal"
+)
+
 // PortType defaines the PortRange is real port numbers or port names which needed resolve. If it is empty, equal to "number".
 // +kubebuilder:validation:Enum=number;name
 type PortType string
@@ -222,12 +421,25 @@ type SecurityPolicyPort struct {
 	// want match single port, you should write like 22. If you want match a range of port, you
 	// should write like 20-80, ports between 20 and 80 (include 20 and 80) will matches. If you
 	// want match multiple ports, you should write like 20,22-24,90.
-	PortRange string `json:"portRange,omitempty"` // only valid when Protocol is not ICMP
+	PortRange string `json:"portRange,omitempty"` // only valid when Protocol is TCP or UDP
 
 	// Type defines the PortRange is real port numbers or port names which needed resolve. If it is empty,
 	// the effect is equal to "number" for compatibility.
 	// +kubebuilder:default:=number
 	Type PortType `json:"type,omitempty"`
+
+	// ICMPType is the type of the ICMP or ICMPv6 message which traffic must match, e.g. 8 for
+	// the echo request of ICMP. If it is empty, all types match. Only valid when Protocol is
+	// ICMP or ICMPv6.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	ICMPType *int32 `json:"icmpType,omitempty"`
+
+	// ICMPCode is the code of the ICMP or ICMPv6 message which traffic must match. If it is
+	// empty, all codes match. Only valid when ICMPType is set.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	ICMPCode *int32 `json:"icmpCode,omitempty"`
 }
 
 // NamespacedName contains information to specify an object.
@@ -244,7 +456,7 @@ func (n NamespacedName) String() string {
 }
 
 // Protocol defines network protocols supported for SecurityPolicy.
-// +kubebuilder:validation:Enum=TCP;UDP;ICMP;IPIP;VRRP
+// +kubebuilder:validation:Enum=TCP;UDP;ICMP;ICMPv6;IPIP;VRRP
 type Protocol string
 
 const (
@@ -254,6 +466,8 @@ const (
 	ProtocolUDP Protocol = "UDP"
 	// ProtocolICMP is the ICMP protocol.
 	ProtocolICMP Protocol = "ICMP"
+	// ProtocolICMPv6 is the ICMPv6 protocol.
+	ProtocolICMPv6 Protocol = "ICMPv6"
 	// ProtocolIPIP is the IPIP protocol.
 	ProtocolIPIP Protocol = "IPIP"
 	// ProtocolVRRP is the VRRP protocol.
@@ -277,6 +491,8 @@ type SecurityPolicyList struct {
 // +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".spec.reference.externalIDName"
 // +kubebuilder:printcolumn:name="EXTERNAL-VALUE",type="string",JSONPath=".spec.reference.externalIDValue"
 // +kubebuilder:printcolumn:name="IPADDR",type="string",JSONPath=".status.ips"
+// +kubebuilder:printcolumn:name="AGENTS",type="string",JSONPath=".status.agents"
+// +kubebuilder:printcolumn:name="LAST-SEEN",type="date",JSONPath=".status.lastSeenTime"
 // +kubebuilder:printcolumn:name="EXTEND-LABELS",type="string",JSONPath=".spec.extendLabels"
 
 // Endpoint is a network communication entity. It's provided by the endpoint provider,
@@ -310,6 +526,11 @@ type EndpointSpec struct {
 	// VID describe the endpoint in which VLAN
 	VID uint32 `json:"vid"`
 
+	// Trunks are the vlans the endpoint trunked, the ranges like "100-110" are accepted besides
+	// the vlan ids, e.g. [10, "100-110"].
+	// +optional
+	Trunks types.Trunks `json:"trunks,omitempty"`
+
 	// ExtendLabels contains extend labels of endpoint. Each key in the labels
 	// could have multiple values, but at least one should be specified.
 	// The ExtendLabels could be selected by selector in SecurityPolicy or EndpointGroup.
@@ -325,6 +546,11 @@ type EndpointSpec struct {
 	Type EndpointType `json:"type,omitempty"`
 
 	Ports []NamedPort `json:"ports,omitempty"`
+
+	// VRF is the logical network the endpoint belongs to, endpoints in different VRFs could
+	// have overlapping IPs. Empty means the default VRF.
+	// +optional
+	VRF string `json:"vrf,omitempty"`
 }
 
 // EndpointReference uniquely identifies an endpoint
@@ -344,6 +570,14 @@ type EndpointStatus struct {
 	MacAddress string `json:"macAddress,omitempty"`
 	// Agents where this endpoint is currently located
 	Agents []string `json:"agents,omitempty"`
+	// VLAN is the access vlan of the ovs port the endpoint attached to, 0 if untagged.
+	VLAN int32 `json:"vlan,omitempty"`
+	// AppliedPolicies are the SecurityPolicies applied to the endpoint, maintained by
+	// the controller as the reverse index of the policies appliedTo.
+	AppliedPolicies []NamespacedName `json:"appliedPolicies,omitempty"`
+	// LastSeenTime is the time the ips of the endpoint last learned by the agents, it's
+	// refreshed at most once per minute.
+	LastSeenTime *metav1.Time `json:"lastSeenTime,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -385,8 +619,43 @@ type GlobalPolicySpec struct {
 	// GlobalPolicy enforcement mode
 	// +kubebuilder:default=work
 	GlobalPolicyEnforcementMode PolicyMode `json:"globalPolicyEnforcementMode,omitempty"`
+
+	// Exceptions are the infrastructure traffics always allowed regardless of the DefaultAction,
+	// e.g. kube-apiserver, DNS and node-to-node health checks, so enabling the global default
+	// drop won't break the control plane traffics.
+	// +optional
+	Exceptions []GlobalPolicyException `json:"exceptions,omitempty"`
 }
 
+// GlobalPolicyException describes the infrastructure traffics always allowed.
+type GlobalPolicyException struct {
+	// Type of the infrastructure traffics, it decides the ports if Ports not specified: 6443/TCP
+	// for KubeAPIServer, 53/TCP and 53/UDP for DNS, all ports for NodeHealthCheck and Custom.
+	// +kubebuilder:default=Custom
+	Type GlobalPolicyExceptionType `json:"type,omitempty"`
+
+	// CIDRs of the infrastructure, the traffics to the CIDRs are allowed for KubeAPIServer and DNS, from
+	// the CIDRs for NodeHealthCheck, from or to the CIDRs for Custom.
+	// +kubebuilder:validation:MinItems=1
+	CIDRs []string `json:"cidrs"`
+
+	// Ports of the traffics allowed, match the destination ports. If it is empty, the well-known
+	// ports of the Type are used.
+	// +optional
+	Ports []SecurityPolicyPort `json:"ports,omitempty"`
+}
+
+// GlobalPolicyExceptionType defines the types of the infrastructure traffics.
+// +kubebuilder:validation:Enum=KubeAPIServer;DNS;NodeHealthCheck;Custom
+type GlobalPolicyExceptionType string
+
+const (
+	GlobalPolicyExceptionKubeAPIServer   GlobalPolicyExceptionType = "KubeAPIServer"
+	GlobalPolicyExceptionDNS             GlobalPolicyExceptionType = "DNS"
+	GlobalPolicyExceptionNodeHealthCheck GlobalPolicyExceptionType = "NodeHealthCheck"
+	GlobalPolicyExceptionCustom          GlobalPolicyExceptionType = "Custom"
+)
+
 // GlobalDefaultAction defines actions supported for GlobalPolicy.
 // +kubebuilder:validation:Enum=Allow;Drop
 type GlobalDefaultAction string
@@ -406,6 +675,496 @@ type GlobalPolicyList struct {
 	Items           []GlobalPolicy `json:"items"`
 }
 
+// +genclient
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="TTL",type="string",JSONPath=".spec.ttl"
+// +kubebuilder:printcolumn:name="ExpireTime",type="string",JSONPath=".status.expireTime"
+// +kubebuilder:printcolumn:name="Policy",type="string",JSONPath=".status.policy"
+
+// Quarantine isolates a set of Endpoint for incident response. All traffics
+// of the quarantined endpoints would be dropped, except traffics from or to
+// the exceptions. Quarantine is enforced in the highest tier, so it takes
+// precedence over all other policies.
+type Quarantine struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains description of the quarantine
+	Spec QuarantineSpec `json:"spec"`
+
+	// Status is the current state of the Quarantine
+	Status QuarantineStatus `json:"status,omitempty"`
+}
+
+// QuarantineSpec provides the specification of a Quarantine
+type QuarantineSpec struct {
+	// AppliedTo selects the endpoints to be quarantined.
+	// +kubebuilder:validation:MinItems=1
+	AppliedTo []ApplyToPeer `json:"appliedTo"`
+
+	// Exceptions are peers still allowed to access, or accessed by the
+	// quarantined endpoints, e.g. management or forensic networks.
+	// +optional
+	Exceptions []SecurityPolicyPeer `json:"exceptions,omitempty"`
+
+	// TTL is how long the quarantine last. The Quarantine would be removed
+	// automatically after expired. Never expire if not set.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// QuarantineStatus describe the current state of the Quarantine
+type QuarantineStatus struct {
+	// Policy is the name of the SecurityPolicy which enforce this quarantine.
+	Policy string `json:"policy,omitempty"`
+	// ExpireTime is the time when the quarantine would be removed.
+	ExpireTime *metav1.Time `json:"expireTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// QuarantineList contains a list of Quarantine
+type QuarantineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Quarantine `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Score",type="integer",JSONPath=".status.score"
+// +kubebuilder:printcolumn:name="Passed",type="integer",JSONPath=".status.passed"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Generated",type="date",JSONPath=".status.generatedTime"
+
+// ComplianceReport evaluates the cluster state against the compliance checks,
+// the checks and the score are reported in the status by the controller, and
+// re-evaluated when the policies, endpoints or agents changed.
+type ComplianceReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains description of the compliance checks
+	Spec ComplianceReportSpec `json:"spec,omitempty"`
+
+	// Status is the result of the compliance checks
+	Status ComplianceReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=DefaultDeny;RiskyRuleLogging;NoAnyAnyAllow;QuarantineEnabled
+type ComplianceCheckType string
+
+const (
+	// ComplianceCheckDefaultDeny passes if the GlobalPolicy drops by default, or all
+	// the endpoints are applied by SecurityPolicies.
+	ComplianceCheckDefaultDeny ComplianceCheckType = "DefaultDeny"
+	// ComplianceCheckRiskyRuleLogging passes if flow logging is enabled on all the
+	// agents when there are rules allowing the risky ports.
+	ComplianceCheckRiskyRuleLogging ComplianceCheckType = "RiskyRuleLogging"
+	// ComplianceCheckNoAnyAnyAllow passes if no rule allows any peer on any port.
+	ComplianceCheckNoAnyAnyAllow ComplianceCheckType = "NoAnyAnyAllow"
+	// ComplianceCheckQuarantineEnabled passes if port scan quarantine is enabled on
+	// all the agents.
+	ComplianceCheckQuarantineEnabled ComplianceCheckType = "QuarantineEnabled"
+)
+
+// ComplianceReportSpec provides the specification of a ComplianceReport
+type ComplianceReportSpec struct {
+	// Checks are the compliance checks evaluated, all the checks are evaluated if empty.
+	// +optional
+	Checks []ComplianceCheckType `json:"checks,omitempty"`
+
+	// RiskyPorts are the ports the rules allowing which should be logged, defaults
+	// to the remote access and file sharing ports: 21, 22, 23, 135, 139, 445, 3389.
+	// +optional
+	RiskyPorts []int32 `json:"riskyPorts,omitempty"`
+}
+
+// ComplianceReportStatus is the result of the compliance checks
+type ComplianceReportStatus struct {
+	// Score is the percentage of the checks passed.
+	Score  int32 `json:"score"`
+	Passed int32 `json:"passed"`
+	Failed int32 `json:"failed"`
+	// Checks are the results of each compliance check.
+	Checks []ComplianceCheckResult `json:"checks,omitempty"`
+	// GeneratedTime is the time the checks evaluated.
+	GeneratedTime metav1.Time `json:"generatedTime,omitempty"`
+}
+
+// ComplianceCheckResult is the result of a compliance check
+type ComplianceCheckResult struct {
+	Type    ComplianceCheckType `json:"type"`
+	Passed  bool                `json:"passed"`
+	Message string              `json:"message,omitempty"`
+	// Findings are the objects failed the check, e.g. the rules or the agents.
+	Findings []string `json:"findings,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ComplianceReportList contains a list of ComplianceReport
+type ComplianceReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComplianceReport `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Allowed",type="integer",JSONPath=".status.allowed"
+// +kubebuilder:printcolumn:name="Denied",type="integer",JSONPath=".status.denied"
+// +kubebuilder:printcolumn:name="Partial",type="integer",JSONPath=".status.partial"
+// +kubebuilder:printcolumn:name="Generated",type="date",JSONPath=".status.generatedTime"
+
+// ReachabilityMatrix analyzes the policies offline, the verdicts of the traffic between the
+// EndpointGroups on each port are reported in the status by the controller, and re-evaluated
+// when the policies or the members of the groups changed.
+type ReachabilityMatrix struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains description of the groups and the ports analyzed
+	Spec ReachabilityMatrixSpec `json:"spec,omitempty"`
+
+	// Status is the verdicts of the traffic between the groups
+	Status ReachabilityMatrixStatus `json:"status,omitempty"`
+}
+
+// ReachabilityMatrixSpec provides the specification of a ReachabilityMatrix
+type ReachabilityMatrixSpec struct {
+	// Groups are the names of the EndpointGroups in the matrix, all the EndpointGroups except
+	// the ones generated for the policies are included if empty.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Ports are the destination ports the traffic between the groups analyzed on.
+	// +kubebuilder:validation:MinItems=1
+	Ports []ReachabilityPort `json:"ports"`
+}
+
+// ReachabilityPort is the destination port of the traffic analyzed.
+type ReachabilityPort struct {
+	// Protocol of the traffic.
+	Protocol Protocol `json:"protocol"`
+
+	// Port is the destination port of the traffic, ignored for ICMP.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Allow;Deny;Partial
+type ReachabilityVerdict string
+
+const (
+	// ReachabilityAllow all the endpoints in the source group could reach all the endpoints
+	// in the destination group.
+	ReachabilityAllow ReachabilityVerdict = "Allow"
+	// ReachabilityDeny none of the endpoints in the source group could reach the endpoints
+	// in the destination group.
+	ReachabilityDeny ReachabilityVerdict = "Deny"
+	// ReachabilityPartial part of the endpoints in the source group could reach the endpoints
+	// in the destination group.
+	ReachabilityPartial ReachabilityVerdict = "Partial"
+)
+
+// ReachabilityMatrixStatus is the verdicts of the traffic between the groups
+type ReachabilityMatrixStatus struct {
+	// Allowed, Denied and Partial are the number of the entries of each verdict.
+	Allowed int32 `json:"allowed"`
+	Denied  int32 `json:"denied"`
+	Partial int32 `json:"partial"`
+	// Entries are the verdicts of the traffic from each group to each group on each port.
+	Entries []ReachabilityEntry `json:"entries,omitempty"`
+	// GeneratedTime is the time the matrix analyzed.
+	GeneratedTime metav1.Time `json:"generatedTime,omitempty"`
+}
+
+// ReachabilityEntry is the verdict of the traffic from a group to a group on a port
+type ReachabilityEntry struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Protocol Protocol            `json:"protocol"`
+	Port     int32               `json:"port,omitempty"`
+	Verdict  ReachabilityVerdict `json:"verdict"`
+	// Rules are the rules decided the verdict, in format of namespace/policy/direction.rule,
+	// the default rules of the policies are namespace/policy/default.direction, and the
+	// default action of the GlobalPolicy is globalpolicy/name.
+	Rules []string `json:"rules,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ReachabilityMatrixList contains a list of ReachabilityMatrix
+type ReachabilityMatrixList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReachabilityMatrix `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Approved",type="boolean",JSONPath=".spec.approved"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Affected",type="integer",JSONPath=".status.affectedEndpointCount"
+// +kubebuilder:printcolumn:name="Planned",type="date",JSONPath=".status.plannedTime"
+
+// PolicyPlan proposes the changes of the SecurityPolicies for review, e.g. from a GitOps
+// pipeline. The controller computes the endpoints affected and the reachability changed by
+// the changes into the status, and applies the changes after the plan approved.
+type PolicyPlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains description of the changes proposed
+	Spec PolicyPlanSpec `json:"spec,omitempty"`
+
+	// Status is the impact of the changes
+	Status PolicyPlanStatus `json:"status,omitempty"`
+}
+
+// PolicyPlanSpec provides the specification of a PolicyPlan
+type PolicyPlanSpec struct {
+	// Changes are the changes of the SecurityPolicies proposed.
+	// +kubebuilder:validation:MinItems=1
+	Changes []PolicyChange `json:"changes"`
+
+	// Groups are the names of the EndpointGroups the reachability compared between, all the
+	// EndpointGroups except the ones generated for the policies are included if empty.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Ports are the destination ports the reachability compared on, the reachability is not
+	// compared if empty.
+	// +optional
+	Ports []ReachabilityPort `json:"ports,omitempty"`
+
+	// Approved applies the changes. The plan could only be approved after planned, and the
+	// changes couldn't be modified along with the approval. Approving requires the verb
+	// approve on the policyplans besides update.
+	// +optional
+	Approved bool `json:"approved,omitempty"`
+
+	// Author is the user proposed the changes, set by the webhook.
+	// +optional
+	Author *authv1.UserInfo `json:"author,omitempty"`
+
+	// Approver is the user approved the plan, set by the webhook. The changes are applied
+	// only if both the Author and the Approver are allowed to change the SecurityPolicies.
+	// +optional
+	Approver *authv1.UserInfo `json:"approver,omitempty"`
+}
+
+// PolicyChangeOperation is the operation of the change.
+// +kubebuilder:validation:Enum=Apply;Delete
+type PolicyChangeOperation string
+
+const (
+	// PolicyChangeApply creates the policy, or updates the policy if exists.
+	PolicyChangeApply PolicyChangeOperation = "Apply"
+	// PolicyChangeDelete deletes the policy.
+	PolicyChangeDelete PolicyChangeOperation = "Delete"
+)
+
+// PolicyChange describes a change of a SecurityPolicy.
+type PolicyChange struct {
+	// Operation of the change, default Apply.
+	// +optional
+	// +kubebuilder:default=Apply
+	Operation PolicyChangeOperation `json:"operation,omitempty"`
+
+	// Namespace and Name of the SecurityPolicy changed.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Spec of the SecurityPolicy applied, must be set when Operation is Apply.
+	// +optional
+	Spec *SecurityPolicySpec `json:"spec,omitempty"`
+}
+
+// PolicyPlanPhase is the phase of the plan.
+type PolicyPlanPhase string
+
+const (
+	// PolicyPlanPlanned the impact of the changes computed, waiting for the approval.
+	PolicyPlanPlanned PolicyPlanPhase = "Planned"
+	// PolicyPlanApplied the changes applied after approved, the plan is not re-planned.
+	PolicyPlanApplied PolicyPlanPhase = "Applied"
+	// PolicyPlanFailed the changes failed to apply, retried until applied.
+	PolicyPlanFailed PolicyPlanPhase = "Failed"
+)
+
+// PolicyPlanStatus is the impact of the changes
+type PolicyPlanStatus struct {
+	// Phase of the plan.
+	Phase PolicyPlanPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec planned.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Message is the reason the changes failed to apply.
+	Message string `json:"message,omitempty"`
+
+	// AffectedEndpointCount is the number of AffectedEndpoints.
+	AffectedEndpointCount int32 `json:"affectedEndpointCount"`
+	// AffectedEndpoints are the endpoints the changed policies applied to, before or after
+	// the changes, in format of namespace/name.
+	AffectedEndpoints []string `json:"affectedEndpoints,omitempty"`
+	// ReachabilityChanges are the verdicts changed between the groups on the ports.
+	ReachabilityChanges []ReachabilityChange `json:"reachabilityChanges,omitempty"`
+	// NodeFlowEstimates are the policy flows estimated on the nodes changed by the changes,
+	// only the nodes reported the capacity are included. The plan is refused to apply if any
+	// node would be pushed over its capacity.
+	NodeFlowEstimates []NodeFlowEstimate `json:"nodeFlowEstimates,omitempty"`
+
+	// PlannedTime is the time the impact computed.
+	PlannedTime metav1.Time `json:"plannedTime,omitempty"`
+	// AppliedTime is the time the changes applied.
+	AppliedTime *metav1.Time `json:"appliedTime,omitempty"`
+}
+
+// ReachabilityChange is the verdict of the traffic from a group to a group on a port changed
+type ReachabilityChange struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Protocol Protocol            `json:"protocol"`
+	Port     int32               `json:"port,omitempty"`
+	Before   ReachabilityVerdict `json:"before"`
+	After    ReachabilityVerdict `json:"after"`
+}
+
+// NodeFlowEstimate is the policy flows estimated on a node before and after the changes
+type NodeFlowEstimate struct {
+	Node   string `json:"node"`
+	Before int32  `json:"before"`
+	After  int32  `json:"after"`
+	// Limit is the MaxPolicyFlows reported by the agent on the node.
+	Limit int32 `json:"limit"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolicyPlanList contains a list of PolicyPlan
+type PolicyPlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyPlan `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="PolicyTypes",type="string",JSONPath=".spec.policyTypes"
+// +kubebuilder:printcolumn:name="Namespaces",type="string",JSONPath=".status.namespaces"
+
+// NamespaceDefaultPolicy enables the default deny of the selected namespaces without
+// writing the policies. The controller generates a SecurityPolicy drop the traffic of
+// all the endpoints in each of the namespaces, the endpoints created in the namespaces
+// later are isolated once they are selected by the policy.
+type NamespaceDefaultPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains description of the namespaces and the directions denied
+	Spec NamespaceDefaultPolicySpec `json:"spec,omitempty"`
+
+	// Status is the namespaces the default deny enforced in
+	Status NamespaceDefaultPolicyStatus `json:"status,omitempty"`
+}
+
+// NamespaceDefaultPolicySpec provides the specification of a NamespaceDefaultPolicy
+type NamespaceDefaultPolicySpec struct {
+	// NamespaceSelector selects the namespaces the default deny enforced in, if present
+	// but empty, it selects all namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+
+	// PolicyTypes are the directions of the traffic denied by default. The traffic allowed
+	// by the other policies in the namespaces are not affected.
+	// +kubebuilder:validation:MinItems=1
+	PolicyTypes []networkingv1.PolicyType `json:"policyTypes"`
+}
+
+// NamespaceDefaultPolicyStatus is the namespaces the default deny enforced in
+type NamespaceDefaultPolicyStatus struct {
+	// Namespaces are the namespaces selected, sorted by name.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NamespaceDefaultPolicyList contains a list of NamespaceDefaultPolicy
+type NamespaceDefaultPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceDefaultPolicy `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority"
+// +kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description"
+
+// Tier groups the SecurityPolicies with the same precedence, the policies reference it by
+// name in spec.tier. The tiers are evaluated in strict order of their priorities, all the
+// rules of a tier, including the default rules of its policies, take effect before any rule
+// of the tiers with lower precedence. The tiers are evaluated after tier0, tier1 and
+// tier-ecp, and before tier2, so the platform-level rules in the tiers always override the
+// application policies in tier2.
+type Tier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec contains the priority of the tier
+	Spec TierSpec `json:"spec,omitempty"`
+}
+
+// TierSpec provides the specification of a Tier
+type TierSpec struct {
+	// Priority of the tier, the lower value has the higher precedence. The priority must
+	// be unique among all the tiers.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Priority int32 `json:"priority"`
+
+	// Description of the tier.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TierList contains a list of Tier
+type TierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Tier `json:"items"`
+}
+
 // NamedPort represents a Port with a name on Pod.
 type NamedPort struct {
 	// Port represents the Port number.
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolicyView is the read only view of a SecurityPolicy, with the groups it references and
+// the endpoints it realized on. The views are namespaced, a tenant could read the views of
+// its namespace by the namespace RBAC, without the cluster-wide read of the groups.
+type PolicyView struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the spec of the SecurityPolicy.
+	Spec securityv1alpha1.SecurityPolicySpec `json:"spec"`
+
+	// Groups are the EndpointGroups referenced by the policy, with the current members.
+	Groups []GroupView `json:"groups,omitempty"`
+
+	// AppliedEndpoints are the Endpoints in the policy namespace the policy applied to.
+	AppliedEndpoints []securityv1alpha1.NamespacedName `json:"appliedEndpoints,omitempty"`
+}
+
+// GroupView is an EndpointGroup referenced by the policy.
+type GroupView struct {
+	// Name is the name of the EndpointGroup.
+	Name string `json:"name"`
+	// Applied is true if the group is an appliedTo group of the policy, otherwise a peer group.
+	Applied bool `json:"applied,omitempty"`
+	// Members are the members of the group, empty if the GroupMembers not generated yet.
+	Members []groupv1alpha1.GroupMember `json:"members,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolicyViewList contains a list of PolicyView
+type PolicyViewList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyView `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IPOwner is the read only view of an ip address in the cluster, named by the address. It
+// contains what owns the ip, the groups contain it and the policies reference it, for the
+// troubleshooting of the unknown addresses in the flows.
+type IPOwner struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Owners are the objects own the ip, empty if the ip is unknown in the cluster.
+	Owners []IPOwnerReference `json:"owners,omitempty"`
+
+	// Groups are the EndpointGroups contain the ip in their members.
+	Groups []string `json:"groups,omitempty"`
+
+	// Policies are the SecurityPolicies reference the ip, by the groups contain it or the
+	// ipBlocks of the peers.
+	Policies []securityv1alpha1.NamespacedName `json:"policies,omitempty"`
+}
+
+// IPOwnerKind is the kind of the object owns the ip.
+type IPOwnerKind string
+
+const (
+	// IPOwnerEndpoint is the Endpoint with the ip in its status.
+	IPOwnerEndpoint IPOwnerKind = "Endpoint"
+	// IPOwnerInterface is the ovs interface the agent learned the ip from.
+	IPOwnerInterface IPOwnerKind = "Interface"
+	// IPOwnerService is the Service with the ip as its cluster ip, external ip or load
+	// balancer vip.
+	IPOwnerService IPOwnerKind = "Service"
+)
+
+// IPOwnerReference is an object owns the ip.
+type IPOwnerReference struct {
+	Kind IPOwnerKind `json:"kind"`
+	// Namespace is empty for the cluster scoped owners.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Type is the type of the address of the owner, e.g. ClusterIP, ExternalIP or
+	// LoadBalancer of the Service, or the bridge of the Interface.
+	Type string `json:"type,omitempty"`
+	// Nodes are the nodes the ip located on, empty if not located on any node.
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IPHistory is the history of the endpoints associated with an ip address, named by the
+// address, so the traffics in the past could be attributed to the workloads.
+type IPHistory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Records are the associations changed of the ip, sorted by the time.
+	Records []IPHistoryRecord `json:"records,omitempty"`
+}
+
+// IPHistoryEvent is the change of the association between an ip and an endpoint.
+type IPHistoryEvent string
+
+const (
+	// IPHistoryLearned means the ip associated with the endpoint.
+	IPHistoryLearned IPHistoryEvent = "Learned"
+	// IPHistoryUnlearned means the ip no longer associated with the endpoint, or the endpoint deleted.
+	IPHistoryUnlearned IPHistoryEvent = "Unlearned"
+	// IPHistoryMigrated means the endpoint with the ip moved to other agents.
+	IPHistoryMigrated IPHistoryEvent = "Migrated"
+)
+
+// IPHistoryRecord is a change of the association between an ip and an endpoint.
+type IPHistoryRecord struct {
+	Time     metav1.Time                     `json:"time"`
+	IP       string                          `json:"ip"`
+	Event    IPHistoryEvent                  `json:"event"`
+	Endpoint securityv1alpha1.NamespacedName `json:"endpoint"`
+	// Agents are the agents the endpoint located on after the change, or before the change
+	// for the Unlearned.
+	Agents []string `json:"agents,omitempty"`
+}
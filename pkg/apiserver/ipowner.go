@@ -0,0 +1,333 @@
+/*
+Copyright 2023 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+// setupIPIndexes indexes the objects by the ips they own, the ip owners are looked up by the
+// indexes instead of listing all the objects on each request.
+func setupIPIndexes(indexer client.FieldIndexer) error {
+	ctx := context.Background()
+	if err := indexer.IndexField(ctx, &securityv1alpha1.Endpoint{}, constants.EndpointByIPIndex, ipIndexEndpointFunc); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &groupv1alpha1.GroupMembers{}, constants.GroupMembersByIPIndex, ipIndexGroupMembersFunc); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &agentv1alpha1.AgentInfo{}, constants.AgentInfoByIPIndex, ipIndexAgentInfoFunc); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, &corev1.Service{}, constants.ServiceByIPIndex, ipIndexServiceFunc)
+}
+
+func ipIndexEndpointFunc(o runtime.Object) []string {
+	return ipKeys(o.(*securityv1alpha1.Endpoint).Status.IPs...)
+}
+
+func ipIndexGroupMembersFunc(o runtime.Object) []string {
+	var ips []types.IPAddress
+	for _, member := range o.(*groupv1alpha1.GroupMembers).GroupMembers {
+		ips = append(ips, member.IPs...)
+	}
+	return ipKeys(ips...)
+}
+
+func ipIndexAgentInfoFunc(o runtime.Object) []string {
+	var ips []types.IPAddress
+	for _, bridge := range o.(*agentv1alpha1.AgentInfo).OVSInfo.Bridges {
+		for _, port := range bridge.Ports {
+			for _, iface := range port.Interfaces {
+				for ip := range iface.IPMap {
+					ips = append(ips, ip)
+				}
+			}
+		}
+	}
+	return ipKeys(ips...)
+}
+
+func ipIndexServiceFunc(o runtime.Object) []string {
+	var ips []types.IPAddress
+	for _, address := range serviceAddresses(o.(*corev1.Service)) {
+		ips = append(ips, address.ip)
+	}
+	return ipKeys(ips...)
+}
+
+// getIPOwner returns the owners of the ip, the groups contain it and the policies reference it.
+// The ip not owned by any object is returned with the empty owners instead of not found, the
+// policies may reference it by the ipBlocks.
+func (s *Server) getIPOwner(ctx context.Context, name string) (*viewsv1alpha1.IPOwner, error) {
+	ip := ipKey(types.IPAddress(name))
+	if ip == "" {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid ip address %s", name))
+	}
+
+	ipOwner := &viewsv1alpha1.IPOwner{
+		TypeMeta:   metav1.TypeMeta{Kind: "IPOwner", APIVersion: viewsv1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: ip},
+	}
+
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := s.Reader.List(ctx, &endpointList, client.MatchingFields{constants.EndpointByIPIndex: ip}); err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpointList.Items {
+		if sets.NewString(ipKeys(endpoint.Status.IPs...)...).Has(ip) {
+			ipOwner.Owners = append(ipOwner.Owners, viewsv1alpha1.IPOwnerReference{
+				Kind:      viewsv1alpha1.IPOwnerEndpoint,
+				Namespace: endpoint.Namespace,
+				Name:      endpoint.Name,
+				Nodes:     endpoint.Status.Agents,
+			})
+		}
+	}
+
+	agentInfoList := agentv1alpha1.AgentInfoList{}
+	if err := s.Reader.List(ctx, &agentInfoList, client.MatchingFields{constants.AgentInfoByIPIndex: ip}); err != nil {
+		return nil, err
+	}
+	for i := range agentInfoList.Items {
+		ipOwner.Owners = append(ipOwner.Owners, learnedInterfaces(&agentInfoList.Items[i], ip)...)
+	}
+
+	serviceList := corev1.ServiceList{}
+	if err := s.Reader.List(ctx, &serviceList, client.MatchingFields{constants.ServiceByIPIndex: ip}); err != nil {
+		return nil, err
+	}
+	for i := range serviceList.Items {
+		for _, address := range serviceAddresses(&serviceList.Items[i]) {
+			if ipKey(address.ip) == ip {
+				ipOwner.Owners = append(ipOwner.Owners, viewsv1alpha1.IPOwnerReference{
+					Kind:      viewsv1alpha1.IPOwnerService,
+					Namespace: serviceList.Items[i].Namespace,
+					Name:      serviceList.Items[i].Name,
+					Type:      address.addressType,
+				})
+			}
+		}
+	}
+
+	groupMembersList := groupv1alpha1.GroupMembersList{}
+	if err := s.Reader.List(ctx, &groupMembersList, client.MatchingFields{constants.GroupMembersByIPIndex: ip}); err != nil {
+		return nil, err
+	}
+	// the ip might be added to the groups by the patches not compacted yet
+	candidates, err := groupctrl.PatchedGroups(ctx, s.Reader, func(member *groupv1alpha1.GroupMember) bool {
+		return sets.NewString(ipKeys(member.IPs...)...).Has(ip)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range groupMembersList.Items {
+		candidates.Insert(groupMembersList.Items[i].Name)
+	}
+	groups := sets.NewString()
+	for _, group := range candidates.List() {
+		groupMembers, err := groupctrl.GetGroupMembers(ctx, s.Reader, group)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err == nil && sets.NewString(ipIndexGroupMembersFunc(groupMembers)...).Has(ip) {
+			groups.Insert(group)
+		}
+	}
+	ipOwner.Groups = groups.List()
+
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := s.Reader.List(ctx, &policyList); err != nil {
+		return nil, err
+	}
+	for i := range policyList.Items {
+		if referenceIP(&policyList.Items[i], groups, ip) {
+			ipOwner.Policies = append(ipOwner.Policies, securityv1alpha1.NamespacedName{
+				Namespace: policyList.Items[i].Namespace,
+				Name:      policyList.Items[i].Name,
+			})
+		}
+	}
+
+	sort.SliceStable(ipOwner.Owners, func(i, j int) bool {
+		if ipOwner.Owners[i].Kind != ipOwner.Owners[j].Kind {
+			return ipOwner.Owners[i].Kind < ipOwner.Owners[j].Kind
+		}
+		if ipOwner.Owners[i].Namespace != ipOwner.Owners[j].Namespace {
+			return ipOwner.Owners[i].Namespace < ipOwner.Owners[j].Namespace
+		}
+		return ipOwner.Owners[i].Name < ipOwner.Owners[j].Name
+	})
+	sort.Slice(ipOwner.Policies, func(i, j int) bool { return ipOwner.Policies[i].String() < ipOwner.Policies[j].String() })
+	return ipOwner, nil
+}
+
+// getIPHistory returns the history of the ip since the time in RFC3339, or all the history
+// kept if since is empty.
+func (s *Server) getIPHistory(name, since string) (*viewsv1alpha1.IPHistory, error) {
+	ip := ipKey(types.IPAddress(name))
+	if ip == "" {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid ip address %s", name))
+	}
+	var sinceTime time.Time
+	if since != "" {
+		var err error
+		if sinceTime, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid since time %s: %s", since, err))
+		}
+	}
+
+	records, err := s.IPHistory.Query(ip, sinceTime)
+	if err != nil {
+		return nil, err
+	}
+	return &viewsv1alpha1.IPHistory{
+		TypeMeta:   metav1.TypeMeta{Kind: "IPHistory", APIVersion: viewsv1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: ip},
+		Records:    records,
+	}, nil
+}
+
+// learnedInterfaces returns the interfaces the agent learned the ip from.
+func learnedInterfaces(agentInfo *agentv1alpha1.AgentInfo, ip string) []viewsv1alpha1.IPOwnerReference {
+	var owners []viewsv1alpha1.IPOwnerReference
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		for _, port := range bridge.Ports {
+			for _, iface := range port.Interfaces {
+				for learnedIP := range iface.IPMap {
+					if ipKey(learnedIP) == ip {
+						owners = append(owners, viewsv1alpha1.IPOwnerReference{
+							Kind:  viewsv1alpha1.IPOwnerInterface,
+							Name:  iface.Name,
+							Type:  bridge.Name,
+							Nodes: []string{agentInfo.Name},
+						})
+						break
+					}
+				}
+			}
+		}
+	}
+	return owners
+}
+
+// referenceIP returns true if the policy references any of the groups, or the ip in the
+// ipBlocks of the peers and not in the excepts.
+func referenceIP(policy *securityv1alpha1.SecurityPolicy, groups sets.String, ip string) bool {
+	if groups.HasAny(policyctrl.EndpointGroupIndexSecurityPolicyFunc(policy)...) {
+		return true
+	}
+
+	var peers []securityv1alpha1.SecurityPolicyPeer
+	for _, rule := range policy.Spec.IngressRules {
+		peers = append(peers, rule.From...)
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		peers = append(peers, rule.To...)
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil && cidrContains(peer.IPBlock.CIDR, ip) {
+			excepted := false
+			for _, except := range peer.IPBlock.Except {
+				excepted = excepted || cidrContains(except, ip)
+			}
+			if !excepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type serviceAddress struct {
+	ip          types.IPAddress
+	addressType string
+}
+
+// serviceAddresses returns the cluster ips, external ips and load balancer vips of the service.
+func serviceAddresses(service *corev1.Service) []serviceAddress {
+	var addresses []serviceAddress
+	clusterIPs := service.Spec.ClusterIPs
+	if len(clusterIPs) == 0 && service.Spec.ClusterIP != "" {
+		clusterIPs = []string{service.Spec.ClusterIP}
+	}
+	for _, ip := range clusterIPs {
+		addresses = append(addresses, serviceAddress{ip: types.IPAddress(ip), addressType: "ClusterIP"})
+	}
+	for _, ip := range service.Spec.ExternalIPs {
+		addresses = append(addresses, serviceAddress{ip: types.IPAddress(ip), addressType: "ExternalIP"})
+	}
+	vips := sets.NewString()
+	if service.Spec.LoadBalancerIP != "" {
+		vips.Insert(service.Spec.LoadBalancerIP)
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			vips.Insert(ingress.IP)
+		}
+	}
+	for _, ip := range vips.List() {
+		addresses = append(addresses, serviceAddress{ip: types.IPAddress(ip), addressType: "LoadBalancer"})
+	}
+	return addresses
+}
+
+// ipKey returns the canonical format of the ip, the prefix length if any is ignored. Returns
+// empty if not a valid ip, e.g. the cluster ip None of the headless services.
+func ipKey(address types.IPAddress) string {
+	ip := net.ParseIP(strings.Split(string(address), "/")[0])
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func ipKeys(addresses ...types.IPAddress) []string {
+	keys := sets.NewString()
+	for _, address := range addresses {
+		if key := ipKey(address); key != "" {
+			keys.Insert(key)
+		}
+	}
+	return keys.List()
+}
+
+func cidrContains(cidr string, ip string) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	return err == nil && ipNet.Contains(net.ParseIP(ip))
+}
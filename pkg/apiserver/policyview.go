@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+)
+
+// listPolicyViews returns the views of the policies in the namespace, or in all namespaces
+// if namespace is empty, sorted by namespace and name.
+func (s *Server) listPolicyViews(ctx context.Context, namespace string) (*viewsv1alpha1.PolicyViewList, error) {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := s.Reader.List(ctx, &policyList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	appliedEndpoints, err := s.appliedEndpoints(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	viewList := &viewsv1alpha1.PolicyViewList{
+		TypeMeta: metav1.TypeMeta{Kind: "PolicyViewList", APIVersion: viewsv1alpha1.SchemeGroupVersion.String()},
+		Items:    []viewsv1alpha1.PolicyView{},
+	}
+	for item := range policyList.Items {
+		view, err := s.policyView(ctx, &policyList.Items[item], appliedEndpoints)
+		if err != nil {
+			return nil, err
+		}
+		viewList.Items = append(viewList.Items, *view)
+	}
+
+	sort.Slice(viewList.Items, func(i, j int) bool {
+		if viewList.Items[i].Namespace != viewList.Items[j].Namespace {
+			return viewList.Items[i].Namespace < viewList.Items[j].Namespace
+		}
+		return viewList.Items[i].Name < viewList.Items[j].Name
+	})
+	return viewList, nil
+}
+
+func (s *Server) getPolicyView(ctx context.Context, namespace, name string) (*viewsv1alpha1.PolicyView, error) {
+	policy := securityv1alpha1.SecurityPolicy{}
+	if err := s.Reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &policy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, apierrors.NewNotFound(viewsv1alpha1.Resource("policyviews"), name)
+		}
+		return nil, err
+	}
+	appliedEndpoints, err := s.appliedEndpoints(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return s.policyView(ctx, &policy, appliedEndpoints)
+}
+
+// policyView returns the view of the policy, appliedEndpoints are the endpoints index by the
+// policies in their status.appliedPolicies.
+func (s *Server) policyView(ctx context.Context, policy *securityv1alpha1.SecurityPolicy,
+	appliedEndpoints map[securityv1alpha1.NamespacedName][]securityv1alpha1.NamespacedName) (*viewsv1alpha1.PolicyView, error) {
+	view := &viewsv1alpha1.PolicyView{
+		TypeMeta: metav1.TypeMeta{Kind: "PolicyView", APIVersion: viewsv1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              policy.Name,
+			Namespace:         policy.Namespace,
+			UID:               policy.UID,
+			ResourceVersion:   policy.ResourceVersion,
+			CreationTimestamp: policy.CreationTimestamp,
+			Labels:            policy.Labels,
+		},
+		Spec:             *policy.Spec.DeepCopy(),
+		AppliedEndpoints: appliedEndpoints[securityv1alpha1.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}],
+	}
+
+	appliedGroups := sets.NewString(policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy)...)
+	for _, group := range policyctrl.EndpointGroupIndexSecurityPolicyFunc(policy) {
+		groupView := viewsv1alpha1.GroupView{Name: group, Applied: appliedGroups.Has(group)}
+		groupMembers, err := groupctrl.GetGroupMembers(ctx, s.Reader, group)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err == nil {
+			groupView.Members = groupMembers.GroupMembers
+		}
+		view.Groups = append(view.Groups, groupView)
+	}
+
+	return view, nil
+}
+
+// appliedEndpoints returns the endpoints in the namespace index by the policies applied to.
+func (s *Server) appliedEndpoints(ctx context.Context, namespace string) (map[securityv1alpha1.NamespacedName][]securityv1alpha1.NamespacedName, error) {
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := s.Reader.List(ctx, &endpointList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	appliedEndpoints := make(map[securityv1alpha1.NamespacedName][]securityv1alpha1.NamespacedName)
+	for _, endpoint := range endpointList.Items {
+		name := securityv1alpha1.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}
+		for _, policy := range endpoint.Status.AppliedPolicies {
+			appliedEndpoints[policy] = append(appliedEndpoints[policy], name)
+		}
+	}
+	for _, endpoints := range appliedEndpoints {
+		sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].String() < endpoints[j].String() })
+	}
+	return appliedEndpoints, nil
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apiserver serves the read only views of the policies and the ip owners as an
+// aggregated api. The
+// requests are authenticated and authorized by the kube-apiserver with the RBAC of the
+// requester, then proxied to the server with the front proxy client cert.
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	"github.com/everoute/everoute/pkg/controller/iphistory"
+)
+
+const (
+	DefaultPort = 9445
+
+	// authenticationConfigMap is published by the kube-apiserver, contains the ca and the
+	// allowed names of the front proxy client cert.
+	authenticationConfigMapNamespace = "kube-system"
+	authenticationConfigMapName      = "extension-apiserver-authentication"
+)
+
+// Server is the aggregated api server of the views.
+type Server struct {
+	// Reader reads the policies, groups and endpoints, usually the cached client.
+	Reader client.Reader
+	// APIReader reads the authentication configmap from the apiserver.
+	APIReader client.Reader
+	// CertDir contains the tls.crt and tls.key the server serves with.
+	CertDir string
+	Port    int
+	// IPHistory is the store of the ip history, the ip histories not served if nil.
+	IPHistory *iphistory.Store
+
+	// allowedNames are the common names of the front proxy client cert, any if empty.
+	allowedNames sets.String
+}
+
+// SetupWithManager registers the ip indexes of the ip owners, and adds the server to the manager.
+func (s *Server) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	if err := setupIPIndexes(mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+	return mgr.Add(s)
+}
+
+// Start implements manager.Runnable.
+func (s *Server) Start(stopChan <-chan struct{}) error {
+	if s.Port == 0 {
+		s.Port = DefaultPort
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", s.Port),
+		Handler:   s,
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		<-stopChan
+		_ = server.Shutdown(context.Background())
+	}()
+
+	klog.Infof("start views aggregated api server on %s", server.Addr)
+	defer klog.Infof("shutting down views aggregated api server")
+
+	if err = server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the requests are proxied
+// to any replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// tlsConfig verifies the client cert if given, the requests without the front proxy client
+// cert are rejected by the handler. The ca is read once, restart the server if rotated.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	if err != nil {
+		return nil, fmt.Errorf("load server cert: %s", err)
+	}
+
+	configMap := corev1.ConfigMap{}
+	configMapReq := types.NamespacedName{Namespace: authenticationConfigMapNamespace, Name: authenticationConfigMapName}
+	if err = s.APIReader.Get(context.Background(), configMapReq, &configMap); err != nil {
+		return nil, fmt.Errorf("get configmap %s: %s", configMapReq, err)
+	}
+
+	clientCA := configMap.Data["requestheader-client-ca-file"]
+	if clientCA == "" {
+		return nil, fmt.Errorf("configmap %s has no requestheader-client-ca-file, front proxy not enabled on kube-apiserver", configMapReq)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM([]byte(clientCA)) {
+		return nil, fmt.Errorf("no valid certs in requestheader-client-ca-file of configmap %s", configMapReq)
+	}
+
+	var allowedNames []string
+	if names := configMap.Data["requestheader-allowed-names"]; names != "" {
+		if err = json.Unmarshal([]byte(names), &allowedNames); err != nil {
+			return nil, fmt.Errorf("invalid requestheader-allowed-names of configmap %s: %s", configMapReq, err)
+		}
+	}
+	s.allowedNames = sets.NewString(allowedNames...)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// authenticated returns true if the request proxied by the kube-apiserver.
+func (s *Server) authenticated(req *http.Request) bool {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	return s.allowedNames.Len() == 0 || s.allowedNames.Has(req.TLS.VerifiedChains[0][0].Subject.CommonName)
+}
+
+// ServeHTTP serves the discovery of the group version, get or list the views in
+// "/apis/views.everoute.io/v1alpha1[/namespaces/{namespace}]/policyviews[/{name}]", and get
+// the owners or the history of the ip in "/apis/views.everoute.io/v1alpha1/{ipowners,iphistories}/{ip}".
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.authenticated(req) {
+		writeError(w, apierrors.NewUnauthorized("requests must be proxied by the kube-apiserver"))
+		return
+	}
+	if req.Method != http.MethodGet {
+		writeError(w, apierrors.NewMethodNotSupported(viewsv1alpha1.Resource("policyviews"), req.Method))
+		return
+	}
+
+	ctx := req.Context()
+	prefix := "/apis/" + viewsv1alpha1.SchemeGroupVersion.String()
+	if req.URL.Path != prefix && !strings.HasPrefix(req.URL.Path, prefix+"/") {
+		writeError(w, apierrors.NewNotFound(viewsv1alpha1.Resource(""), req.URL.Path))
+		return
+	}
+
+	var segments []string
+	if path := strings.Trim(strings.TrimPrefix(req.URL.Path, prefix), "/"); path != "" {
+		segments = strings.Split(path, "/")
+	}
+
+	var obj interface{}
+	var err error
+	switch {
+	case len(segments) == 0:
+		obj = s.discovery()
+	case len(segments) == 1 && segments[0] == "policyviews":
+		obj, err = s.listPolicyViews(ctx, metav1.NamespaceAll)
+	case len(segments) == 3 && segments[0] == "namespaces" && segments[2] == "policyviews":
+		obj, err = s.listPolicyViews(ctx, segments[1])
+	case len(segments) == 4 && segments[0] == "namespaces" && segments[2] == "policyviews":
+		obj, err = s.getPolicyView(ctx, segments[1], segments[3])
+	case len(segments) == 2 && segments[0] == "ipowners":
+		obj, err = s.getIPOwner(ctx, segments[1])
+	case len(segments) == 2 && segments[0] == "iphistories" && s.IPHistory != nil:
+		obj, err = s.getIPHistory(segments[1], req.URL.Query().Get("since"))
+	default:
+		err = apierrors.NewNotFound(viewsv1alpha1.Resource(""), req.URL.Path)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeObject(w, http.StatusOK, obj)
+}
+
+func (s *Server) discovery() *metav1.APIResourceList {
+	resourceList := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: viewsv1alpha1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{{
+			Name:         "policyviews",
+			SingularName: "policyview",
+			Namespaced:   true,
+			Kind:         "PolicyView",
+			Verbs:        metav1.Verbs{"get", "list"},
+		}, {
+			Name:         "ipowners",
+			SingularName: "ipowner",
+			Namespaced:   false,
+			Kind:         "IPOwner",
+			Verbs:        metav1.Verbs{"get"},
+		}},
+	}
+	if s.IPHistory != nil {
+		resourceList.APIResources = append(resourceList.APIResources, metav1.APIResource{
+			Name:         "iphistories",
+			SingularName: "iphistory",
+			Namespaced:   false,
+			Kind:         "IPHistory",
+			Verbs:        metav1.Verbs{"get"},
+		})
+	}
+	return resourceList
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		klog.Errorf("unable serve views request: %s", err)
+		status = apierrors.NewInternalError(err)
+	}
+	errStatus := status.Status()
+	errStatus.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	writeObject(w, int(errStatus.Code), &errStatus)
+}
+
+func writeObject(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		klog.Errorf("unable write views response: %s", err)
+	}
+}
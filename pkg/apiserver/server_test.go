@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/controller/iphistory"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func newTestServer() *Server {
+	endpointName := "ep01"
+	policy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy01", Namespace: "tenant01"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &endpointName}},
+		},
+	}
+	otherPolicy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy02", Namespace: "tenant02"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &endpointName}},
+		},
+	}
+	endpoint := &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: endpointName, Namespace: "tenant01"},
+		Status: securityv1alpha1.EndpointStatus{
+			AppliedPolicies: []securityv1alpha1.NamespacedName{{Namespace: "tenant01", Name: "policy01"}},
+		},
+	}
+	groupMembers := &groupv1alpha1.GroupMembers{
+		ObjectMeta:   metav1.ObjectMeta{Name: policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy)[0]},
+		GroupMembers: []groupv1alpha1.GroupMember{{IPs: []types.IPAddress{"10.0.0.1"}}},
+	}
+
+	return &Server{
+		Reader:       fake.NewFakeClientWithScheme(clientsetscheme.Scheme, policy, otherPolicy, endpoint, groupMembers),
+		allowedNames: sets.NewString("front-proxy-client"),
+	}
+}
+
+func doRequest(server *Server, path, clientName string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if clientName != "" {
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+			{Subject: pkix.Name{CommonName: clientName}},
+		}}}
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestServeAuthentication(t *testing.T) {
+	RegisterTestingT(t)
+	server := newTestServer()
+
+	Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1", "").Code).Should(Equal(http.StatusUnauthorized))
+	Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1", "someone").Code).Should(Equal(http.StatusUnauthorized))
+	Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1", "front-proxy-client").Code).Should(Equal(http.StatusOK))
+}
+
+func TestServePolicyViews(t *testing.T) {
+	RegisterTestingT(t)
+	server := newTestServer()
+
+	t.Run("should serve discovery", func(t *testing.T) {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1", "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		resourceList := metav1.APIResourceList{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &resourceList)).Should(Succeed())
+		Expect(resourceList.APIResources).Should(HaveLen(2))
+		Expect(resourceList.APIResources[0].Name).Should(Equal("policyviews"))
+		Expect(resourceList.APIResources[1].Name).Should(Equal("ipowners"))
+	})
+
+	t.Run("should list the views in the namespace only", func(t *testing.T) {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1/namespaces/tenant01/policyviews", "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		viewList := viewsv1alpha1.PolicyViewList{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &viewList)).Should(Succeed())
+		Expect(viewList.Items).Should(HaveLen(1))
+
+		view := viewList.Items[0]
+		Expect(view.Name).Should(Equal("policy01"))
+		Expect(view.AppliedEndpoints).Should(ConsistOf(securityv1alpha1.NamespacedName{Namespace: "tenant01", Name: "ep01"}))
+		Expect(view.Groups).Should(HaveLen(1))
+		Expect(view.Groups[0].Applied).Should(BeTrue())
+		Expect(view.Groups[0].Members).Should(HaveLen(1))
+	})
+
+	t.Run("should list the views in all namespaces", func(t *testing.T) {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1/policyviews", "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		viewList := viewsv1alpha1.PolicyViewList{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &viewList)).Should(Succeed())
+		Expect(viewList.Items).Should(HaveLen(2))
+		Expect(viewList.Items[1].AppliedEndpoints).Should(BeEmpty())
+	})
+
+	t.Run("should get the view", func(t *testing.T) {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1/namespaces/tenant02/policyviews/policy02", "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1/namespaces/tenant01/policyviews/policy02", "front-proxy-client").Code).
+			Should(Equal(http.StatusNotFound))
+		Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1/namespaces/tenant01/groups", "front-proxy-client").Code).
+			Should(Equal(http.StatusNotFound))
+	})
+}
+
+func TestServeIPOwners(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme := runtime.NewScheme()
+	_ = kubescheme.AddToScheme(scheme)
+	_ = clientsetscheme.AddToScheme(scheme)
+
+	server := newTestServer()
+	endpointName := "ep01"
+	policy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy01", Namespace: "tenant01"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &endpointName}},
+		},
+	}
+	cidrPolicy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy02", Namespace: "tenant02"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			IngressRules: []securityv1alpha1.Rule{{
+				From: []securityv1alpha1.SecurityPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/24", Except: []string{"10.0.0.128/25"}},
+				}},
+			}},
+		},
+	}
+	endpoint := &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: endpointName, Namespace: "tenant01"},
+		Status:     securityv1alpha1.EndpointStatus{IPs: []types.IPAddress{"10.0.0.1"}, Agents: []string{"node01"}},
+	}
+	groupMembers := &groupv1alpha1.GroupMembers{
+		ObjectMeta:   metav1.ObjectMeta{Name: policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy)[0]},
+		GroupMembers: []groupv1alpha1.GroupMember{{IPs: []types.IPAddress{"10.0.0.1"}}},
+	}
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "node01"},
+		OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+			Name: "ovsbr0",
+			Ports: []agentv1alpha1.OVSPort{{
+				Name: "vnet01",
+				Interfaces: []agentv1alpha1.OVSInterface{{
+					Name:  "vnet01",
+					IPMap: map[types.IPAddress]metav1.Time{"10.0.0.1": metav1.Now()},
+				}},
+			}},
+		}}},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc01", Namespace: "tenant02"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10", ExternalIPs: []string{"10.0.0.200"}},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "10.0.0.200"}},
+		}},
+	}
+	server.Reader = fake.NewFakeClientWithScheme(scheme, policy, cidrPolicy, endpoint, groupMembers, agentInfo, service)
+
+	getIPOwner := func(ip string) viewsv1alpha1.IPOwner {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1/ipowners/"+ip, "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		ipOwner := viewsv1alpha1.IPOwner{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &ipOwner)).Should(Succeed())
+		return ipOwner
+	}
+
+	t.Run("should get the owners of the endpoint ip", func(t *testing.T) {
+		ipOwner := getIPOwner("10.0.0.1")
+		Expect(ipOwner.Name).Should(Equal("10.0.0.1"))
+		Expect(ipOwner.Owners).Should(Equal([]viewsv1alpha1.IPOwnerReference{
+			{Kind: viewsv1alpha1.IPOwnerEndpoint, Namespace: "tenant01", Name: "ep01", Nodes: []string{"node01"}},
+			{Kind: viewsv1alpha1.IPOwnerInterface, Name: "vnet01", Type: "ovsbr0", Nodes: []string{"node01"}},
+		}))
+		Expect(ipOwner.Groups).Should(ConsistOf(groupMembers.Name))
+		Expect(ipOwner.Policies).Should(Equal([]securityv1alpha1.NamespacedName{
+			{Namespace: "tenant01", Name: "policy01"},
+			{Namespace: "tenant02", Name: "policy02"},
+		}))
+	})
+
+	t.Run("should get the owners of the service vip", func(t *testing.T) {
+		ipOwner := getIPOwner("10.0.0.200")
+		Expect(ipOwner.Owners).Should(Equal([]viewsv1alpha1.IPOwnerReference{
+			{Kind: viewsv1alpha1.IPOwnerService, Namespace: "tenant02", Name: "svc01", Type: "ExternalIP"},
+			{Kind: viewsv1alpha1.IPOwnerService, Namespace: "tenant02", Name: "svc01", Type: "LoadBalancer"},
+		}))
+		Expect(ipOwner.Groups).Should(BeEmpty())
+		// the ip in the except of the ipBlock
+		Expect(ipOwner.Policies).Should(BeEmpty())
+	})
+
+	t.Run("should get the unknown ip", func(t *testing.T) {
+		ipOwner := getIPOwner("192.168.0.1")
+		Expect(ipOwner.Owners).Should(BeEmpty())
+		Expect(ipOwner.Policies).Should(BeEmpty())
+	})
+
+	t.Run("should reject the invalid ip", func(t *testing.T) {
+		Expect(doRequest(server, "/apis/views.everoute.io/v1alpha1/ipowners/foo", "front-proxy-client").Code).
+			Should(Equal(http.StatusBadRequest))
+	})
+}
+
+func TestServeIPHistories(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := newTestServer()
+	path := "/apis/views.everoute.io/v1alpha1/iphistories/10.0.0.1"
+
+	t.Run("should not serve without the store", func(t *testing.T) {
+		Expect(doRequest(server, path, "front-proxy-client").Code).Should(Equal(http.StatusNotFound))
+	})
+
+	server.IPHistory = &iphistory.Store{Path: t.TempDir() + "/history.log"}
+	lastDay := metav1.NewTime(time.Now().Add(-24 * time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	endpoint := securityv1alpha1.NamespacedName{Namespace: "tenant01", Name: "ep01"}
+	Expect(server.IPHistory.Append(
+		viewsv1alpha1.IPHistoryRecord{Time: lastDay, IP: "10.0.0.1", Event: viewsv1alpha1.IPHistoryLearned, Endpoint: endpoint},
+		viewsv1alpha1.IPHistoryRecord{Time: lastDay, IP: "10.0.0.2", Event: viewsv1alpha1.IPHistoryLearned, Endpoint: endpoint},
+		viewsv1alpha1.IPHistoryRecord{Time: now, IP: "10.0.0.1", Event: viewsv1alpha1.IPHistoryUnlearned, Endpoint: endpoint},
+	)).Should(Succeed())
+
+	getIPHistory := func(path string) viewsv1alpha1.IPHistory {
+		resp := doRequest(server, path, "front-proxy-client")
+		Expect(resp.Code).Should(Equal(http.StatusOK))
+		ipHistory := viewsv1alpha1.IPHistory{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &ipHistory)).Should(Succeed())
+		return ipHistory
+	}
+
+	t.Run("should serve discovery with the store", func(t *testing.T) {
+		resp := doRequest(server, "/apis/views.everoute.io/v1alpha1", "front-proxy-client")
+		resourceList := metav1.APIResourceList{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &resourceList)).Should(Succeed())
+		Expect(resourceList.APIResources).Should(HaveLen(3))
+		Expect(resourceList.APIResources[2].Name).Should(Equal("iphistories"))
+	})
+
+	t.Run("should get the history of the ip", func(t *testing.T) {
+		ipHistory := getIPHistory(path)
+		Expect(ipHistory.Name).Should(Equal("10.0.0.1"))
+		Expect(ipHistory.Records).Should(HaveLen(2))
+		Expect(ipHistory.Records[0].Event).Should(Equal(viewsv1alpha1.IPHistoryLearned))
+		Expect(ipHistory.Records[1].Event).Should(Equal(viewsv1alpha1.IPHistoryUnlearned))
+	})
+
+	t.Run("should get the history of the ip since the time", func(t *testing.T) {
+		since := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		ipHistory := getIPHistory(path + "?since=" + since)
+		Expect(ipHistory.Records).Should(HaveLen(1))
+		Expect(ipHistory.Records[0].Event).Should(Equal(viewsv1alpha1.IPHistoryUnlearned))
+	})
+
+	t.Run("should reject the invalid since", func(t *testing.T) {
+		Expect(doRequest(server, path+"?since=yesterday", "front-proxy-client").Code).
+			Should(Equal(http.StatusBadRequest))
+	})
+}
@@ -28,6 +28,9 @@ import (
 type AgentV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	AgentInfosGetter
+	ClusterStatusesGetter
+	EffectiveRuleSetsGetter
+	NetworkTopologiesGetter
 }
 
 // AgentV1alpha1Client is used to interact with features provided by the agent.everoute.io group.
@@ -39,6 +42,18 @@ func (c *AgentV1alpha1Client) AgentInfos() AgentInfoInterface {
 	return newAgentInfos(c)
 }
 
+func (c *AgentV1alpha1Client) ClusterStatuses() ClusterStatusInterface {
+	return newClusterStatuses(c)
+}
+
+func (c *AgentV1alpha1Client) EffectiveRuleSets() EffectiveRuleSetInterface {
+	return newEffectiveRuleSets(c)
+}
+
+func (c *AgentV1alpha1Client) NetworkTopologies() NetworkTopologyInterface {
+	return newNetworkTopologies(c)
+}
+
 // NewForConfig creates a new AgentV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*AgentV1alpha1Client, error) {
 	config := *c
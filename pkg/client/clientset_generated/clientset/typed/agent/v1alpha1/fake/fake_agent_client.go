@@ -33,6 +33,18 @@ func (c *FakeAgentV1alpha1) AgentInfos() v1alpha1.AgentInfoInterface {
 	return &FakeAgentInfos{c}
 }
 
+func (c *FakeAgentV1alpha1) ClusterStatuses() v1alpha1.ClusterStatusInterface {
+	return &FakeClusterStatuses{c}
+}
+
+func (c *FakeAgentV1alpha1) EffectiveRuleSets() v1alpha1.EffectiveRuleSetInterface {
+	return &FakeEffectiveRuleSets{c}
+}
+
+func (c *FakeAgentV1alpha1) NetworkTopologies() v1alpha1.NetworkTopologyInterface {
+	return &FakeNetworkTopologies{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeAgentV1alpha1) RESTClient() rest.Interface {
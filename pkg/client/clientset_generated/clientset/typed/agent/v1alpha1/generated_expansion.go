@@ -19,3 +19,9 @@ limitations under the License.
 package v1alpha1
 
 type AgentInfoExpansion interface{}
+
+type ClusterStatusExpansion interface{}
+
+type EffectiveRuleSetExpansion interface{}
+
+type NetworkTopologyExpansion interface{}
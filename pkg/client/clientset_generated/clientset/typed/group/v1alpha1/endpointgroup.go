@@ -41,6 +41,7 @@ type EndpointGroupsGetter interface {
 type EndpointGroupInterface interface {
 	Create(ctx context.Context, endpointGroup *v1alpha1.EndpointGroup, opts v1.CreateOptions) (*v1alpha1.EndpointGroup, error)
 	Update(ctx context.Context, endpointGroup *v1alpha1.EndpointGroup, opts v1.UpdateOptions) (*v1alpha1.EndpointGroup, error)
+	UpdateStatus(ctx context.Context, endpointGroup *v1alpha1.EndpointGroup, opts v1.UpdateOptions) (*v1alpha1.EndpointGroup, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.EndpointGroup, error)
@@ -129,6 +130,21 @@ func (c *endpointGroups) Update(ctx context.Context, endpointGroup *v1alpha1.End
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *endpointGroups) UpdateStatus(ctx context.Context, endpointGroup *v1alpha1.EndpointGroup, opts v1.UpdateOptions) (result *v1alpha1.EndpointGroup, err error) {
+	result = &v1alpha1.EndpointGroup{}
+	err = c.client.Put().
+		Resource("endpointgroups").
+		Name(endpointGroup.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(endpointGroup).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the endpointGroup and deletes it. Returns an error if one occurs.
 func (c *endpointGroups) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
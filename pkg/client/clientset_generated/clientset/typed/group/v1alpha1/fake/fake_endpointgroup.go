@@ -97,6 +97,17 @@ func (c *FakeEndpointGroups) Update(ctx context.Context, endpointGroup *v1alpha1
 	return obj.(*v1alpha1.EndpointGroup), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeEndpointGroups) UpdateStatus(ctx context.Context, endpointGroup *v1alpha1.EndpointGroup, opts v1.UpdateOptions) (*v1alpha1.EndpointGroup, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(endpointgroupsResource, "status", endpointGroup), &v1alpha1.EndpointGroup{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.EndpointGroup), err
+}
+
 // Delete takes name of the endpointGroup and deletes it. Returns an error if one occurs.
 func (c *FakeEndpointGroups) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -29,6 +29,10 @@ type FakeSecurityV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeSecurityV1alpha1) ComplianceReports() v1alpha1.ComplianceReportInterface {
+	return &FakeComplianceReports{c}
+}
+
 func (c *FakeSecurityV1alpha1) Endpoints(namespace string) v1alpha1.EndpointInterface {
 	return &FakeEndpoints{c, namespace}
 }
@@ -37,10 +41,30 @@ func (c *FakeSecurityV1alpha1) GlobalPolicies() v1alpha1.GlobalPolicyInterface {
 	return &FakeGlobalPolicies{c}
 }
 
+func (c *FakeSecurityV1alpha1) NamespaceDefaultPolicies() v1alpha1.NamespaceDefaultPolicyInterface {
+	return &FakeNamespaceDefaultPolicies{c}
+}
+
+func (c *FakeSecurityV1alpha1) PolicyPlans() v1alpha1.PolicyPlanInterface {
+	return &FakePolicyPlans{c}
+}
+
+func (c *FakeSecurityV1alpha1) Quarantines(namespace string) v1alpha1.QuarantineInterface {
+	return &FakeQuarantines{c, namespace}
+}
+
+func (c *FakeSecurityV1alpha1) ReachabilityMatrices() v1alpha1.ReachabilityMatrixInterface {
+	return &FakeReachabilityMatrices{c}
+}
+
 func (c *FakeSecurityV1alpha1) SecurityPolicies(namespace string) v1alpha1.SecurityPolicyInterface {
 	return &FakeSecurityPolicies{c, namespace}
 }
 
+func (c *FakeSecurityV1alpha1) Tiers() v1alpha1.TierInterface {
+	return &FakeTiers{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeSecurityV1alpha1) RESTClient() rest.Interface {
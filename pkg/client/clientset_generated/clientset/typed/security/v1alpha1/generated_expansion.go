@@ -18,8 +18,20 @@ limitations under the License.
 
 package v1alpha1
 
+type ComplianceReportExpansion interface{}
+
 type EndpointExpansion interface{}
 
 type GlobalPolicyExpansion interface{}
 
+type NamespaceDefaultPolicyExpansion interface{}
+
+type PolicyPlanExpansion interface{}
+
+type QuarantineExpansion interface{}
+
+type ReachabilityMatrixExpansion interface{}
+
 type SecurityPolicyExpansion interface{}
+
+type TierExpansion interface{}
@@ -27,9 +27,15 @@ import (
 
 type SecurityV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ComplianceReportsGetter
 	EndpointsGetter
 	GlobalPoliciesGetter
+	NamespaceDefaultPoliciesGetter
+	PolicyPlansGetter
+	QuarantinesGetter
+	ReachabilityMatricesGetter
 	SecurityPoliciesGetter
+	TiersGetter
 }
 
 // SecurityV1alpha1Client is used to interact with features provided by the security.everoute.io group.
@@ -37,6 +43,10 @@ type SecurityV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *SecurityV1alpha1Client) ComplianceReports() ComplianceReportInterface {
+	return newComplianceReports(c)
+}
+
 func (c *SecurityV1alpha1Client) Endpoints(namespace string) EndpointInterface {
 	return newEndpoints(c, namespace)
 }
@@ -45,10 +55,30 @@ func (c *SecurityV1alpha1Client) GlobalPolicies() GlobalPolicyInterface {
 	return newGlobalPolicies(c)
 }
 
+func (c *SecurityV1alpha1Client) NamespaceDefaultPolicies() NamespaceDefaultPolicyInterface {
+	return newNamespaceDefaultPolicies(c)
+}
+
+func (c *SecurityV1alpha1Client) PolicyPlans() PolicyPlanInterface {
+	return newPolicyPlans(c)
+}
+
+func (c *SecurityV1alpha1Client) Quarantines(namespace string) QuarantineInterface {
+	return newQuarantines(c, namespace)
+}
+
+func (c *SecurityV1alpha1Client) ReachabilityMatrices() ReachabilityMatrixInterface {
+	return newReachabilityMatrices(c)
+}
+
 func (c *SecurityV1alpha1Client) SecurityPolicies(namespace string) SecurityPolicyInterface {
 	return newSecurityPolicies(c, namespace)
 }
 
+func (c *SecurityV1alpha1Client) Tiers() TierInterface {
+	return newTiers(c)
+}
+
 // NewForConfig creates a new SecurityV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*SecurityV1alpha1Client, error) {
 	config := *c
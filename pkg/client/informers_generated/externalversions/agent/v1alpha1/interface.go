@@ -26,6 +26,12 @@ import (
 type Interface interface {
 	// AgentInfos returns a AgentInfoInformer.
 	AgentInfos() AgentInfoInformer
+	// ClusterStatuses returns a ClusterStatusInformer.
+	ClusterStatuses() ClusterStatusInformer
+	// EffectiveRuleSets returns a EffectiveRuleSetInformer.
+	EffectiveRuleSets() EffectiveRuleSetInformer
+	// NetworkTopologies returns a NetworkTopologyInformer.
+	NetworkTopologies() NetworkTopologyInformer
 }
 
 type version struct {
@@ -43,3 +49,18 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) AgentInfos() AgentInfoInformer {
 	return &agentInfoInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// ClusterStatuses returns a ClusterStatusInformer.
+func (v *version) ClusterStatuses() ClusterStatusInformer {
+	return &clusterStatusInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// EffectiveRuleSets returns a EffectiveRuleSetInformer.
+func (v *version) EffectiveRuleSets() EffectiveRuleSetInformer {
+	return &effectiveRuleSetInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// NetworkTopologies returns a NetworkTopologyInformer.
+func (v *version) NetworkTopologies() NetworkTopologyInformer {
+	return &networkTopologyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
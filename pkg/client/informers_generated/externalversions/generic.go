@@ -59,6 +59,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=agent.everoute.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("agentinfos"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Agent().V1alpha1().AgentInfos().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterstatuses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Agent().V1alpha1().ClusterStatuses().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("effectiverulesets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Agent().V1alpha1().EffectiveRuleSets().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("networktopologies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Agent().V1alpha1().NetworkTopologies().Informer()}, nil
 
 		// Group=group.everoute.io, Version=v1alpha1
 	case groupv1alpha1.SchemeGroupVersion.WithResource("endpointgroups"):
@@ -69,12 +75,24 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Group().V1alpha1().GroupMembersPatches().Informer()}, nil
 
 		// Group=security.everoute.io, Version=v1alpha1
+	case securityv1alpha1.SchemeGroupVersion.WithResource("compliancereports"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().ComplianceReports().Informer()}, nil
 	case securityv1alpha1.SchemeGroupVersion.WithResource("endpoints"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().Endpoints().Informer()}, nil
 	case securityv1alpha1.SchemeGroupVersion.WithResource("globalpolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().GlobalPolicies().Informer()}, nil
+	case securityv1alpha1.SchemeGroupVersion.WithResource("namespacedefaultpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().NamespaceDefaultPolicies().Informer()}, nil
+	case securityv1alpha1.SchemeGroupVersion.WithResource("policyplans"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().PolicyPlans().Informer()}, nil
+	case securityv1alpha1.SchemeGroupVersion.WithResource("quarantines"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().Quarantines().Informer()}, nil
+	case securityv1alpha1.SchemeGroupVersion.WithResource("reachabilitymatrices"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().ReachabilityMatrices().Informer()}, nil
 	case securityv1alpha1.SchemeGroupVersion.WithResource("securitypolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().SecurityPolicies().Informer()}, nil
+	case securityv1alpha1.SchemeGroupVersion.WithResource("tiers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Security().V1alpha1().Tiers().Informer()}, nil
 
 		// Group=service.everoute.io, Version=v1alpha1
 	case servicev1alpha1.SchemeGroupVersion.WithResource("serviceports"):
@@ -24,12 +24,24 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ComplianceReports returns a ComplianceReportInformer.
+	ComplianceReports() ComplianceReportInformer
 	// Endpoints returns a EndpointInformer.
 	Endpoints() EndpointInformer
 	// GlobalPolicies returns a GlobalPolicyInformer.
 	GlobalPolicies() GlobalPolicyInformer
+	// NamespaceDefaultPolicies returns a NamespaceDefaultPolicyInformer.
+	NamespaceDefaultPolicies() NamespaceDefaultPolicyInformer
+	// PolicyPlans returns a PolicyPlanInformer.
+	PolicyPlans() PolicyPlanInformer
+	// Quarantines returns a QuarantineInformer.
+	Quarantines() QuarantineInformer
+	// ReachabilityMatrices returns a ReachabilityMatrixInformer.
+	ReachabilityMatrices() ReachabilityMatrixInformer
 	// SecurityPolicies returns a SecurityPolicyInformer.
 	SecurityPolicies() SecurityPolicyInformer
+	// Tiers returns a TierInformer.
+	Tiers() TierInformer
 }
 
 type version struct {
@@ -43,6 +55,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ComplianceReports returns a ComplianceReportInformer.
+func (v *version) ComplianceReports() ComplianceReportInformer {
+	return &complianceReportInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Endpoints returns a EndpointInformer.
 func (v *version) Endpoints() EndpointInformer {
 	return &endpointInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -53,7 +70,32 @@ func (v *version) GlobalPolicies() GlobalPolicyInformer {
 	return &globalPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// NamespaceDefaultPolicies returns a NamespaceDefaultPolicyInformer.
+func (v *version) NamespaceDefaultPolicies() NamespaceDefaultPolicyInformer {
+	return &namespaceDefaultPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// PolicyPlans returns a PolicyPlanInformer.
+func (v *version) PolicyPlans() PolicyPlanInformer {
+	return &policyPlanInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// Quarantines returns a QuarantineInformer.
+func (v *version) Quarantines() QuarantineInformer {
+	return &quarantineInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ReachabilityMatrices returns a ReachabilityMatrixInformer.
+func (v *version) ReachabilityMatrices() ReachabilityMatrixInformer {
+	return &reachabilityMatrixInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // SecurityPolicies returns a SecurityPolicyInformer.
 func (v *version) SecurityPolicies() SecurityPolicyInformer {
 	return &securityPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// Tiers returns a TierInformer.
+func (v *version) Tiers() TierInformer {
+	return &tierInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -21,3 +21,15 @@ package v1alpha1
 // AgentInfoListerExpansion allows custom methods to be added to
 // AgentInfoLister.
 type AgentInfoListerExpansion interface{}
+
+// ClusterStatusListerExpansion allows custom methods to be added to
+// ClusterStatusLister.
+type ClusterStatusListerExpansion interface{}
+
+// EffectiveRuleSetListerExpansion allows custom methods to be added to
+// EffectiveRuleSetLister.
+type EffectiveRuleSetListerExpansion interface{}
+
+// NetworkTopologyListerExpansion allows custom methods to be added to
+// NetworkTopologyLister.
+type NetworkTopologyListerExpansion interface{}
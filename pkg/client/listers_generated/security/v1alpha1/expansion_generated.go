@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha1
 
+// ComplianceReportListerExpansion allows custom methods to be added to
+// ComplianceReportLister.
+type ComplianceReportListerExpansion interface{}
+
 // EndpointListerExpansion allows custom methods to be added to
 // EndpointLister.
 type EndpointListerExpansion interface{}
@@ -30,6 +34,26 @@ type EndpointNamespaceListerExpansion interface{}
 // GlobalPolicyLister.
 type GlobalPolicyListerExpansion interface{}
 
+// NamespaceDefaultPolicyListerExpansion allows custom methods to be added to
+// NamespaceDefaultPolicyLister.
+type NamespaceDefaultPolicyListerExpansion interface{}
+
+// PolicyPlanListerExpansion allows custom methods to be added to
+// PolicyPlanLister.
+type PolicyPlanListerExpansion interface{}
+
+// QuarantineListerExpansion allows custom methods to be added to
+// QuarantineLister.
+type QuarantineListerExpansion interface{}
+
+// QuarantineNamespaceListerExpansion allows custom methods to be added to
+// QuarantineNamespaceLister.
+type QuarantineNamespaceListerExpansion interface{}
+
+// ReachabilityMatrixListerExpansion allows custom methods to be added to
+// ReachabilityMatrixLister.
+type ReachabilityMatrixListerExpansion interface{}
+
 // SecurityPolicyListerExpansion allows custom methods to be added to
 // SecurityPolicyLister.
 type SecurityPolicyListerExpansion interface{}
@@ -37,3 +61,7 @@ type SecurityPolicyListerExpansion interface{}
 // SecurityPolicyNamespaceListerExpansion allows custom methods to be added to
 // SecurityPolicyNamespaceLister.
 type SecurityPolicyNamespaceListerExpansion interface{}
+
+// TierListerExpansion allows custom methods to be added to
+// TierLister.
+type TierListerExpansion interface{}
@@ -18,11 +18,21 @@ package constants
 
 const (
 	// InternalWhitelistPriority is the priority of internal whitelist IP, we set different priorities
-	// with NormalPolicyRulePriority to make sure normal rules won't cover internal whitelist rules
-	InternalWhitelistPriority       = 120
-	NormalPolicyRulePriority        = 100
-	DefaultPolicyRulePriority       = 70
-	GlobalDefaultPolicyRulePriority = 40
+	// with NormalPolicyRulePriority to make sure normal rules won't cover internal whitelist rules,
+	// it is above the priorities allocated to the Tiers as well.
+	InternalWhitelistPriority = 20000
+	// GlobalExceptionPolicyRulePriority is the priority of the GlobalPolicy exceptions, they are
+	// above the priorities allocated to the Tiers, so the infrastructure traffics always allowed.
+	GlobalExceptionPolicyRulePriority = 19000
+	NormalPolicyRulePriority          = 100
+	DefaultPolicyRulePriority         = 70
+	GlobalDefaultPolicyRulePriority   = 40
+	// MaxTierPriority is the max priority of the Tiers, the lower priority has the higher precedence.
+	MaxTierPriority = 100
+	// TierPriorityBand is the range of the flow priorities allocated to each priority of the Tiers.
+	// The rules of the Tiers are installed in the tier2 table, with the rule priorities plus the
+	// offset of the tier, the tier with higher precedence has the higher offset.
+	TierPriorityBand = 100
 
 	DefaultMaxConcurrentReconciles   = 4
 	NumOfRetainedGroupMembersPatches = 3
@@ -30,6 +40,25 @@ const (
 	OwnerGroupLabelKey               = "label.everoute.io/ownergroup"
 	OwnerPolicyLabelKey              = "label.everoute.io/ownerpolicy"
 	IsGlobalPolicyRuleLabel          = "label.everoute.io/isglobalpolicy"
+	QuarantineSourceLabelKey         = "label.everoute.io/quarantine-source"
+	QuarantineAgentLabelKey          = "label.everoute.io/quarantine-agent"
+	NamespaceDefaultPolicyLabelKey   = "label.everoute.io/namespace-default-policy"
+	RuleLastHitAnnotation            = "everoute.io/rule-last-hit"
+	PodEndpointExternalIDName        = "pod-uuid"
+	UnusedRulesAnnotation            = "everoute.io/unused-rules"
+	// EnforcementBypassExpireTimeAnnotation annotated on an endpoint with the time in RFC3339, the
+	// policies are bypassed for the endpoint until the time, for the emergency troubleshooting.
+	EnforcementBypassExpireTimeAnnotation = "everoute.io/enforcement-bypass-expire-time"
+	EnforcementBypassReasonAnnotation     = "everoute.io/enforcement-bypass-reason"
+	EnforcementBypassLabelKey             = "label.everoute.io/enforcement-bypass"
+	// DelegationLabelPrefix labeled on a namespace with the name of another namespace, e.g.
+	// delegation.everoute.io/tenant-a=allow, allows the policies in the other namespace to
+	// reference the endpoints of the namespace.
+	DelegationLabelPrefix = "delegation.everoute.io/"
+	DelegationAllow       = "allow"
+	// ServiceAccountLabelKey labeled on the endpoints of the pods with the name of the service
+	// account the pod runs under, it's reserved and overwrites the same label of the pods.
+	ServiceAccountLabelKey = "label.everoute.io/serviceaccount"
 
 	// Tier0 used for isolation policy and forensic one side drop
 	Tier0 = "tier0"
@@ -39,15 +68,37 @@ const (
 	Tier2 = "tier2"
 	// TierECP used for ecp network policy
 	TierECP = "tier-ecp"
+	// TierEmergency is the default Tier with the highest precedence, used for the emergency response
+	TierEmergency = "emergency"
+	// TierSecurityOps is the default Tier used for the policies of the security operators
+	TierSecurityOps = "security-ops"
+	// TierApplication is the default Tier with the lowest precedence, used for the application
+	// policies, only tier2 is evaluated after it
+	TierApplication = "application"
 
 	SecurityPolicyByEndpointGroupIndex = "SecurityPolicyByEndpointGroupIndex"
+	SecurityPolicyByAppliedGroupIndex  = "SecurityPolicyByAppliedGroupIndex"
+	GroupMembersByEndpointIndex        = "GroupMembersByEndpointIndex"
+	EndpointByReferenceIndex           = "EndpointByReferenceIndex"
+	EndpointByIPIndex                  = "EndpointByIPIndex"
+	GroupMembersByIPIndex              = "GroupMembersByIPIndex"
+	AgentInfoByIPIndex                 = "AgentInfoByIPIndex"
+	ServiceByIPIndex                   = "ServiceByIPIndex"
 
-	EverouteWebhookName     = "validator.everoute.io"
-	EverouteSecretName      = "everoute-controller-tls"
-	EverouteSecretNamespace = "kube-system"
+	EverouteWebhookName         = "validator.everoute.io"
+	EverouteMutatingWebhookName = "mutator.everoute.io"
+	EverouteSecretName          = "everoute-controller-tls"
+	EverouteSecretNamespace     = "kube-system"
+
+	EverouteViewsAPIServiceName = "v1alpha1.views.everoute.io"
 
 	ControllerRuntimeQPS   = 1000.0
 	ControllerRuntimeBurst = 2000
+	// ControllerRuntimeAcceptContentTypes prefers protobuf for the kubernetes builtin resources,
+	// e.g. pods, nodes and services. The custom resources, include GroupMembers, GroupMembersPatch
+	// and AgentInfo, are always served in json by the apiserver, which doesn't support protobuf
+	// for custom resources, so protobuf is not generated for the everoute apis.
+	ControllerRuntimeAcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
 
 	AgentNodeNameENV    = "NODE_NAME"
 	AgentNameConfigPath = "/var/lib/everoute/agent/name"
@@ -56,12 +107,21 @@ const (
 
 	EverouteComponentType = 0x0
 
-	RPCSocketAddr   = "/var/lib/everoute/rpc.sock"
-	EverouteLibPath = "/var/lib/everoute"
+	RPCSocketAddr     = "/var/lib/everoute/rpc.sock"
+	HandoffSocketAddr = "/var/lib/everoute/handoff.sock"
+	EverouteLibPath   = "/var/lib/everoute"
+	// CNIVethStorePath is where the veth pairs attached by the cni recorded, for the recovery
+	// after the host reboot
+	CNIVethStorePath = "/var/lib/everoute/cni/veth"
 
 	AllEpWithNamedPort = "all-endpoints-with-named-port"
+	// ClusterInternalEndpoints is the group of all the endpoints, the builtin peers of the
+	// policies are computed from it
+	ClusterInternalEndpoints = "cluster-internal-endpoints"
 
 	HealthCheckPath = "/healthz"
+	NameCachePath   = "/names"
+	CRDSchemaPath   = "/schemas"
 
 	EncapModeGeneve = "geneve"
 
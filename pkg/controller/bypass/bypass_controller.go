@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bypass
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+const (
+	// PolicyPrefix is the name prefix of the policies which bypass the enforcement of endpoints
+	PolicyPrefix = "enforcement-bypass-"
+	// MaxTTL is the longest time the enforcement of an endpoint could be bypassed
+	MaxTTL = 24 * time.Hour
+
+	ReasonBypassed = "EnforcementBypassed"
+	ReasonExpired  = "EnforcementBypassExpired"
+	ReasonReleased = "EnforcementBypassReleased"
+	ReasonFailed   = "EnforcementBypassFailed"
+)
+
+// Reconciler watch endpoints, bypass the enforcement of the endpoints annotated with
+// constants.EnforcementBypassExpireTimeAnnotation by a tier0 SecurityPolicy allows all
+// traffics, and release the endpoints after expired.
+type Reconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Reconcile receive endpoint from work queue, synchronize the bypass policy of the endpoint.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("BypassReconciler received endpoint %s reconcile", req.NamespacedName)
+
+	endpoint := securityv1alpha1.Endpoint{}
+	if err := r.Get(ctx, req.NamespacedName, &endpoint); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, r.release(ctx, nil, req.Namespace, req.Name)
+		}
+		klog.Errorf("unable to fetch endpoint %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	expireTime, err := ExpireTime(&endpoint)
+	if err != nil {
+		r.Recorder.Eventf(&endpoint, corev1.EventTypeWarning, ReasonFailed, "unable to bypass enforcement: %s", err)
+		return ctrl.Result{}, r.release(ctx, &endpoint, endpoint.Namespace, endpoint.Name)
+	}
+	if expireTime == nil || !endpoint.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.release(ctx, &endpoint, endpoint.Namespace, endpoint.Name)
+	}
+
+	if !time.Now().Before(expireTime.Time) {
+		r.Recorder.Eventf(&endpoint, corev1.EventTypeNormal, ReasonExpired, "enforcement bypass expired at %s", expireTime.UTC().Format(time.RFC3339))
+		if err = r.removeAnnotations(ctx, &endpoint); err != nil {
+			klog.Errorf("unable to remove bypass annotations of endpoint %s: %s", req.NamespacedName, err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.release(ctx, &endpoint, endpoint.Namespace, endpoint.Name)
+	}
+
+	if err = r.syncPolicy(ctx, &endpoint, expireTime); err != nil {
+		r.Recorder.Eventf(&endpoint, corev1.EventTypeWarning, ReasonFailed, "unable to bypass enforcement: %s", err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: time.Until(expireTime.Time)}, nil
+}
+
+// SetupWithManager create and add Bypass Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("bypass-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.Endpoint{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// enqueue the owner endpoint when its bypass policy been modified or removed
+	return c.Watch(&source.Kind{Type: &securityv1alpha1.SecurityPolicy{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &securityv1alpha1.Endpoint{},
+		IsController: true,
+	})
+}
+
+func (r *Reconciler) syncPolicy(ctx context.Context, endpoint *securityv1alpha1.Endpoint, expireTime *metav1.Time) error {
+	policy := NewBypassPolicy(endpoint)
+	if err := controllerutil.SetControllerReference(endpoint, policy, r.Scheme); err != nil {
+		return err
+	}
+
+	var oldPolicy securityv1alpha1.SecurityPolicy
+	err := r.Get(ctx, client.ObjectKey{Namespace: policy.Namespace, Name: policy.Name}, &oldPolicy)
+	switch {
+	case errors.IsNotFound(err):
+		if err = r.Create(ctx, policy); err != nil {
+			return fmt.Errorf("create bypass policy %s/%s: %s", policy.Namespace, policy.Name, err)
+		}
+		reason := endpoint.Annotations[constants.EnforcementBypassReasonAnnotation]
+		klog.Infof("endpoint %s/%s enforcement bypassed until %s, reason: %s", endpoint.Namespace, endpoint.Name, expireTime, reason)
+		r.Recorder.Eventf(endpoint, corev1.EventTypeWarning, ReasonBypassed, "enforcement bypassed by policy %s until %s, reason: %s",
+			policy.Name, expireTime.UTC().Format(time.RFC3339), reason)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if reflect.DeepEqual(oldPolicy.Spec, policy.Spec) && reflect.DeepEqual(oldPolicy.Labels, policy.Labels) {
+		return nil
+	}
+	oldPolicy.Labels = policy.Labels
+	oldPolicy.Spec = policy.Spec
+	if err = r.Update(ctx, &oldPolicy); err != nil {
+		return fmt.Errorf("update bypass policy %s/%s: %s", policy.Namespace, policy.Name, err)
+	}
+	klog.Infof("endpoint %s/%s bypass policy %s updated", endpoint.Namespace, endpoint.Name, policy.Name)
+	return nil
+}
+
+// release remove the bypass policy of the endpoint immediately, without waiting for garbage
+// collection. The endpoint may be nil if it has gone.
+func (r *Reconciler) release(ctx context.Context, endpoint *securityv1alpha1.Endpoint, namespace, name string) error {
+	policy := securityv1alpha1.SecurityPolicy{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: PolicyPrefix + name}, &policy)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if policy.Labels[constants.EnforcementBypassLabelKey] != name {
+		return nil
+	}
+
+	if err = r.Delete(ctx, &policy); client.IgnoreNotFound(err) != nil {
+		klog.Errorf("unable to remove bypass policy %s/%s: %s", namespace, policy.Name, err)
+		return err
+	}
+	klog.Infof("endpoint %s/%s enforcement bypass released", namespace, name)
+
+	if endpoint == nil {
+		// the endpoint may have gone, record the event with its reference only
+		endpoint = &securityv1alpha1.Endpoint{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	r.Recorder.Eventf(endpoint, corev1.EventTypeNormal, ReasonReleased, "enforcement bypass released, policy %s removed", policy.Name)
+	return nil
+}
+
+func (r *Reconciler) removeAnnotations(ctx context.Context, endpoint *securityv1alpha1.Endpoint) error {
+	patch := client.MergeFrom(endpoint.DeepCopy())
+	delete(endpoint.Annotations, constants.EnforcementBypassExpireTimeAnnotation)
+	delete(endpoint.Annotations, constants.EnforcementBypassReasonAnnotation)
+	return client.IgnoreNotFound(r.Patch(ctx, endpoint, patch))
+}
+
+// ExpireTime return the time when the enforcement bypass of the endpoint expired, nil means
+// the enforcement of the endpoint not bypassed.
+func ExpireTime(endpoint *securityv1alpha1.Endpoint) (*metav1.Time, error) {
+	expireTimeStr, ok := endpoint.GetAnnotations()[constants.EnforcementBypassExpireTimeAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	expireTime, err := time.Parse(time.RFC3339, expireTimeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expire time %s: %s", expireTimeStr, err)
+	}
+	return &metav1.Time{Time: expireTime}, nil
+}
+
+// NewBypassPolicy return the policy bypass the enforcement of the endpoint. The policy allows
+// all traffics of the endpoint in tier0, which takes precedence over all the other tiers, the
+// quarantines included.
+func NewBypassPolicy(endpoint *securityv1alpha1.Endpoint) *securityv1alpha1.SecurityPolicy {
+	endpointName := endpoint.GetName()
+
+	return &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PolicyPrefix + endpointName,
+			Namespace: endpoint.GetNamespace(),
+			Labels: map[string]string{
+				constants.EnforcementBypassLabelKey: endpointName,
+			},
+		},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:         constants.Tier0,
+			AppliedTo:    []securityv1alpha1.ApplyToPeer{{Endpoint: &endpointName}},
+			IngressRules: []securityv1alpha1.Rule{{Name: "bypass-ingress"}},
+			EgressRules:  []securityv1alpha1.Rule{{Name: "bypass-egress"}},
+			DefaultRule:  securityv1alpha1.DefaultRuleNone,
+			PolicyTypes:  []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bypass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = kubescheme.AddToScheme(scheme)
+	_ = clientsetscheme.AddToScheme(scheme)
+	return scheme
+}
+
+func newEndpoint(name string, expireTime time.Time) *securityv1alpha1.Endpoint {
+	return &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Annotations: map[string]string{
+				constants.EnforcementBypassExpireTimeAnnotation: expireTime.UTC().Format(time.RFC3339),
+				constants.EnforcementBypassReasonAnnotation:     "suspected false positive drops",
+			},
+		},
+	}
+}
+
+func TestNewBypassPolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	policy := NewBypassPolicy(newEndpoint("ep01", time.Now()))
+	Expect(policy.Name).Should(Equal(PolicyPrefix + "ep01"))
+	Expect(policy.Namespace).Should(Equal("default"))
+	Expect(policy.Labels[constants.EnforcementBypassLabelKey]).Should(Equal("ep01"))
+	Expect(policy.Spec.Tier).Should(Equal(constants.Tier0))
+	Expect(policy.Spec.DefaultRule).Should(Equal(securityv1alpha1.DefaultRuleNone))
+	Expect(policy.Spec.PolicyTypes).Should(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+	Expect(*policy.Spec.AppliedTo[0].Endpoint).Should(Equal("ep01"))
+	Expect(policy.Spec.IngressRules).Should(HaveLen(1))
+	Expect(policy.Spec.IngressRules[0].From).Should(BeEmpty())
+	Expect(policy.Spec.EgressRules).Should(HaveLen(1))
+	Expect(policy.Spec.EgressRules[0].To).Should(BeEmpty())
+}
+
+func TestExpireTime(t *testing.T) {
+	RegisterTestingT(t)
+
+	expireTime, err := ExpireTime(&securityv1alpha1.Endpoint{})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(expireTime).Should(BeNil())
+
+	now := time.Now().Truncate(time.Second)
+	expireTime, err = ExpireTime(newEndpoint("ep01", now))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(expireTime.Time.Equal(now)).Should(BeTrue())
+
+	endpoint := newEndpoint("ep01", now)
+	endpoint.Annotations[constants.EnforcementBypassExpireTimeAnnotation] = "10m"
+	_, err = ExpireTime(endpoint)
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	endpoint := newEndpoint("ep01", time.Now().Add(time.Hour))
+	scheme := newScheme()
+	k8sClient := fake.NewFakeClientWithScheme(scheme, endpoint)
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Client: k8sClient, Scheme: scheme, Recorder: recorder}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "ep01"}}
+	policyKey := k8stypes.NamespacedName{Namespace: "default", Name: PolicyPrefix + "ep01"}
+
+	t.Run("should bypass the enforcement of the endpoint", func(t *testing.T) {
+		RegisterTestingT(t)
+		result, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.RequeueAfter).Should(BeNumerically("~", time.Hour, time.Minute))
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(k8sClient.Get(ctx, policyKey, &policy)).Should(Succeed())
+		Expect(policy.OwnerReferences).Should(HaveLen(1))
+		Expect(recorder.Events).Should(Receive(And(ContainSubstring(ReasonBypassed), ContainSubstring("suspected false positive drops"))))
+	})
+
+	t.Run("should release the endpoint after expired", func(t *testing.T) {
+		RegisterTestingT(t)
+		var current securityv1alpha1.Endpoint
+		Expect(k8sClient.Get(ctx, req.NamespacedName, &current)).Should(Succeed())
+		current.Annotations[constants.EnforcementBypassExpireTimeAnnotation] = time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+		Expect(k8sClient.Update(ctx, &current)).Should(Succeed())
+
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, policyKey, &policy))).Should(BeTrue())
+		var released securityv1alpha1.Endpoint
+		Expect(k8sClient.Get(ctx, req.NamespacedName, &released)).Should(Succeed())
+		Expect(released.Annotations).ShouldNot(HaveKey(constants.EnforcementBypassExpireTimeAnnotation))
+		Expect(released.Annotations).ShouldNot(HaveKey(constants.EnforcementBypassReasonAnnotation))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonExpired)))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonReleased)))
+	})
+
+	t.Run("should release the endpoint when annotation removed", func(t *testing.T) {
+		RegisterTestingT(t)
+		Expect(k8sClient.Create(ctx, NewBypassPolicy(endpoint))).Should(Succeed())
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, policyKey, &policy))).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonReleased)))
+	})
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+const (
+	ReasonOverCapacity   = "PolicyFlowsOverCapacity"
+	ReasonWithinCapacity = "PolicyFlowsWithinCapacity"
+)
+
+// clusterRequest is the only request of the controller, the estimation is always done for
+// all the nodes at once.
+var clusterRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: "cluster"}}
+
+// Reconciler estimates the policy flows on the nodes reported the capacity, and warns on the
+// agentinfos of the nodes over their capacity, e.g. the policies changed without a plan.
+type Reconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// overloaded are the flows of the nodes over the capacity last warned
+	overloaded map[string]int32
+}
+
+// Reconcile estimates the policy flows of the current policies.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("CapacityReconciler received %s reconcile", req.Name)
+
+	simulator, err := NewSimulator(ctx, r.Client)
+	if err != nil {
+		klog.Errorf("unable to create capacity simulator: %s", err)
+		return ctrl.Result{}, err
+	}
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err = r.List(ctx, &policyList); err != nil {
+		klog.Errorf("unable to list policies: %s", err)
+		return ctrl.Result{}, err
+	}
+	flows, err := simulator.Estimate(ctx, policyList.Items)
+	if err != nil {
+		klog.Errorf("unable to estimate policy flows: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	overloaded := make(map[string]int32)
+	for _, node := range simulator.nodes {
+		limit, ok := simulator.Limit(node)
+		if !ok {
+			continue
+		}
+		agentInfo := &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: node}}
+		lastFlows, warned := r.overloaded[node]
+
+		if flows[node] > limit {
+			overloaded[node] = flows[node]
+			if !warned || lastFlows != flows[node] {
+				klog.Warningf("node %s policy flows %d estimated over capacity %d", node, flows[node], limit)
+				r.Recorder.Eventf(agentInfo, corev1.EventTypeWarning, ReasonOverCapacity,
+					"%d policy flows estimated, over the capacity %d", flows[node], limit)
+			}
+			continue
+		}
+		if warned {
+			klog.Infof("node %s policy flows %d estimated within capacity %d", node, flows[node], limit)
+			r.Recorder.Eventf(agentInfo, corev1.EventTypeNormal, ReasonWithinCapacity,
+				"%d policy flows estimated, within the capacity %d", flows[node], limit)
+		}
+	}
+	r.overloaded = overloaded
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Capacity Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("capacity-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	enqueueCluster := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(handler.MapObject) []reconcile.Request {
+			return []reconcile.Request{clusterRequest}
+		}),
+	}
+	for _, object := range []runtime.Object{
+		&securityv1alpha1.SecurityPolicy{},
+		&securityv1alpha1.Endpoint{},
+		&groupv1alpha1.GroupMembers{},
+		&groupv1alpha1.GroupMembersPatch{},
+	} {
+		if err = c.Watch(&source.Kind{Type: object}, enqueueCluster); err != nil {
+			return err
+		}
+	}
+	// the agentinfos are updated on each heartbeat, only the capacity changes are concerned
+	return c.Watch(&source.Kind{Type: &agentv1alpha1.AgentInfo{}}, enqueueCluster, predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, oldOk := e.ObjectOld.(*agentv1alpha1.AgentInfo)
+			newObj, newOk := e.ObjectNew.(*agentv1alpha1.AgentInfo)
+			return !oldOk || !newOk || !reflect.DeepEqual(oldObj.Capacity, newObj.Capacity)
+		},
+	})
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func newEndpoint(name, app, agent string, ips ...types.IPAddress) *securityv1alpha1.Endpoint {
+	return &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": app}},
+		Spec: securityv1alpha1.EndpointSpec{
+			Reference: securityv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: name},
+		},
+		Status: securityv1alpha1.EndpointStatus{IPs: ips, Agents: []string{agent}},
+	}
+}
+
+func newAgentInfo(name string, maxPolicyFlows int32) *agentv1alpha1.AgentInfo {
+	agentInfo := &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if maxPolicyFlows != 0 {
+		agentInfo.Capacity = &agentv1alpha1.AgentCapacity{MaxPolicyFlows: maxPolicyFlows}
+	}
+	return agentInfo
+}
+
+func selectApp(app string) *labels.Selector {
+	return &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}}
+}
+
+// newDBPolicy returns the policy applied to the db, allows mysql from the web and the cidr.
+func newDBPolicy() securityv1alpha1.SecurityPolicy {
+	return securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:      constants.Tier2,
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{EndpointSelector: selectApp("db")}},
+			IngressRules: []securityv1alpha1.Rule{{
+				Name: "mysql",
+				Ports: []securityv1alpha1.SecurityPolicyPort{
+					{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "3306"},
+					{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "33060"},
+				},
+				From: []securityv1alpha1.SecurityPolicyPeer{
+					{EndpointSelector: selectApp("web")},
+					{IPBlock: &networkingv1.IPBlock{CIDR: "192.168.0.0/16"}},
+				},
+			}},
+			EgressRules: []securityv1alpha1.Rule{{Name: "all"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		newEndpoint("web01", "web", "node01", "10.0.1.1"),
+		newEndpoint("web02", "web", "node02", "10.0.1.2", "10.0.1.3"),
+		newEndpoint("db01", "db", "node01", "10.0.2.1"),
+		newEndpoint("db02", "db", "node02", "10.0.2.2", "10.0.2.3"),
+		newAgentInfo("node01", 10),
+		newAgentInfo("node02", 0),
+	)
+	simulator, err := NewSimulator(ctx, c)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	limit, ok := simulator.Limit("node01")
+	Expect(ok).Should(BeTrue())
+	Expect(limit).Should(Equal(int32(10)))
+	_, ok = simulator.Limit("node02")
+	Expect(ok).Should(BeFalse())
+
+	// for each local ip: ingress (3 web ips + 1 cidr) * 2 ports + 1 default, egress 1 rule + 1 default
+	flows, err := simulator.Estimate(ctx, []securityv1alpha1.SecurityPolicy{newDBPolicy()})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(flows).Should(Equal(map[string]int32{"node01": 11, "node02": 22}))
+
+	// the empty AppliedTo applies to all the endpoints in the vrf
+	policy := newDBPolicy()
+	policy.Spec.AppliedTo = nil
+	policy.Spec.DefaultRule = securityv1alpha1.DefaultRuleNone
+	flows, err = simulator.Estimate(ctx, []securityv1alpha1.SecurityPolicy{policy})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(flows).Should(Equal(map[string]int32{"node01": 18, "node02": 36}))
+}
+
+func TestCompare(t *testing.T) {
+	RegisterTestingT(t)
+
+	simulator := &Simulator{limits: map[string]int32{"node01": 10, "node02": 10, "node03": 10}}
+	estimates := simulator.Compare(
+		map[string]int32{"node01": 5, "node02": 12, "node03": 4, "node04": 1},
+		map[string]int32{"node01": 11, "node02": 11, "node03": 4, "node04": 100},
+	)
+	Expect(estimates).Should(Equal([]securityv1alpha1.NodeFlowEstimate{
+		{Node: "node01", Before: 5, After: 11, Limit: 10},
+		{Node: "node02", Before: 12, After: 11, Limit: 10},
+	}))
+	Expect(OverCapacity(estimates)).Should(Equal(&estimates[0]))
+	// the nodes over the capacity already are allowed to reduce the flows
+	Expect(OverCapacity(estimates[1:])).Should(BeNil())
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	policy := newDBPolicy()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		newEndpoint("web01", "web", "node01", "10.0.1.1"),
+		newEndpoint("db01", "db", "node01", "10.0.2.1"),
+		newAgentInfo("node01", 5),
+		&policy,
+	)
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Client: c, Recorder: recorder}
+
+	_, err := r.Reconcile(clusterRequest)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonOverCapacity)))
+
+	// not warned again when the flows unchanged
+	_, err = r.Reconcile(clusterRequest)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(recorder.Events).ShouldNot(Receive())
+
+	agentInfo := &agentv1alpha1.AgentInfo{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Name: "node01"}, agentInfo)).Should(Succeed())
+	agentInfo.Capacity.MaxPolicyFlows = 100
+	Expect(c.Update(ctx, agentInfo)).Should(Succeed())
+	_, err = r.Reconcile(clusterRequest)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonWithinCapacity)))
+}
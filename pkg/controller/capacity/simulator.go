@@ -0,0 +1,256 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+)
+
+// Simulator estimates the policy flows the agents would realize for the policies, so the
+// policies pushing the agents over their capacity could be found before distributed. The
+// estimation is the upper bound of the flows, each rule realized on a node is counted as
+// the local applied ips by the peer ips by the ports.
+type Simulator struct {
+	client client.Client
+	// limits are the MaxPolicyFlows of the agents reported the capacity
+	limits map[string]int32
+	// nodes are all the agents, the members without agents apply to all of them
+	nodes []string
+	// members cache the group members fetched, keyed by the group name
+	members map[string][]groupv1alpha1.GroupMember
+	// endpoints are the endpoints of the vrfs, for the policies applied to all the endpoints
+	endpoints []securityv1alpha1.Endpoint
+}
+
+// NewSimulator returns a Simulator with the capacity of the agents. The simulator caches the
+// group members, it should be discarded after the estimation.
+func NewSimulator(ctx context.Context, c client.Client) (*Simulator, error) {
+	agentInfoList := agentv1alpha1.AgentInfoList{}
+	if err := c.List(ctx, &agentInfoList); err != nil {
+		return nil, fmt.Errorf("list agentinfos: %s", err)
+	}
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := c.List(ctx, &endpointList); err != nil {
+		return nil, fmt.Errorf("list endpoints: %s", err)
+	}
+
+	s := &Simulator{
+		client:    c,
+		limits:    make(map[string]int32),
+		members:   make(map[string][]groupv1alpha1.GroupMember),
+		endpoints: endpointList.Items,
+	}
+	for _, agentInfo := range agentInfoList.Items {
+		s.nodes = append(s.nodes, agentInfo.Name)
+		if agentInfo.Capacity != nil && agentInfo.Capacity.MaxPolicyFlows > 0 {
+			s.limits[agentInfo.Name] = agentInfo.Capacity.MaxPolicyFlows
+		}
+	}
+	sort.Strings(s.nodes)
+	return s, nil
+}
+
+// Limit returns the MaxPolicyFlows of the node, false if the node not reported the capacity.
+func (s *Simulator) Limit(node string) (int32, bool) {
+	limit, ok := s.limits[node]
+	return limit, ok
+}
+
+// Estimate returns the policy flows estimated on the nodes for the policies.
+func (s *Simulator) Estimate(ctx context.Context, policies []securityv1alpha1.SecurityPolicy) (map[string]int32, error) {
+	flows := make(map[string]int32)
+	for i := range policies {
+		if err := s.estimatePolicy(ctx, &policies[i], flows); err != nil {
+			return nil, fmt.Errorf("estimate policy %s/%s: %s", policies[i].Namespace, policies[i].Name, err)
+		}
+	}
+	return flows, nil
+}
+
+// Compare returns the estimates of the nodes reported the capacity with the flows changed,
+// sorted by the node name.
+func (s *Simulator) Compare(before, after map[string]int32) []securityv1alpha1.NodeFlowEstimate {
+	var estimates []securityv1alpha1.NodeFlowEstimate
+	for node, limit := range s.limits {
+		if before[node] == after[node] {
+			continue
+		}
+		estimates = append(estimates, securityv1alpha1.NodeFlowEstimate{
+			Node:   node,
+			Before: before[node],
+			After:  after[node],
+			Limit:  limit,
+		})
+	}
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i].Node < estimates[j].Node })
+	return estimates
+}
+
+// OverCapacity returns the first estimate pushed over the capacity by the changes, nil if
+// none. The nodes over the capacity already are not refused to reduce the flows.
+func OverCapacity(estimates []securityv1alpha1.NodeFlowEstimate) *securityv1alpha1.NodeFlowEstimate {
+	for i := range estimates {
+		if estimates[i].After > estimates[i].Limit && estimates[i].After > estimates[i].Before {
+			return &estimates[i]
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) estimatePolicy(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, flows map[string]int32) error {
+	localIPs, err := s.appliedIPs(ctx, policy)
+	if err != nil {
+		return err
+	}
+	if len(localIPs) == 0 {
+		return nil
+	}
+
+	var ruleFlows int32
+	ingressEnabled, egressEnabled := policy.IsEnable()
+	if ingressEnabled {
+		for _, rule := range policy.Spec.IngressRules {
+			count, err := s.ruleFlows(ctx, policy, rule.Ports, rule.From)
+			if err != nil {
+				return err
+			}
+			ruleFlows += count
+		}
+		if policy.Spec.DefaultRule != securityv1alpha1.DefaultRuleNone {
+			ruleFlows++
+		}
+	}
+	if egressEnabled {
+		for _, rule := range policy.Spec.EgressRules {
+			count, err := s.ruleFlows(ctx, policy, rule.Ports, rule.To)
+			if err != nil {
+				return err
+			}
+			ruleFlows += count
+		}
+		if policy.Spec.DefaultRule != securityv1alpha1.DefaultRuleNone {
+			ruleFlows++
+		}
+	}
+
+	for node, ips := range localIPs {
+		flows[node] += ips * ruleFlows
+	}
+	return nil
+}
+
+// appliedIPs returns the number of the ips the policy applied to on each node.
+func (s *Simulator) appliedIPs(ctx context.Context, policy *securityv1alpha1.SecurityPolicy) (map[string]int32, error) {
+	localIPs := make(map[string]int32)
+	addIPs := func(agents []string, ips int) {
+		if len(agents) == 0 {
+			// the member without agents applies to all the agents
+			agents = s.nodes
+		}
+		for _, agent := range agents {
+			localIPs[agent] += int32(ips)
+		}
+	}
+
+	if len(policy.Spec.AppliedTo) == 0 {
+		// the empty AppliedTo applies to all the endpoints in the vrf
+		for _, endpoint := range s.endpoints {
+			if endpoint.Spec.VRF == policy.Spec.VRF {
+				addIPs(endpoint.Status.Agents, len(endpoint.Status.IPs))
+			}
+		}
+		return localIPs, nil
+	}
+
+	for _, applied := range policy.Spec.AppliedTo {
+		peer := ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, applied)
+		members, err := s.groupMembers(ctx, ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer))
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			addIPs(member.EndpointAgent, len(member.IPs))
+		}
+	}
+	return localIPs, nil
+}
+
+// ruleFlows returns the flows of the rule for each local applied ip.
+func (s *Simulator) ruleFlows(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, ports []securityv1alpha1.SecurityPolicyPort,
+	peers []securityv1alpha1.SecurityPolicyPeer) (int32, error) {
+	peerIPs, err := s.peerIPs(ctx, policy, peers)
+	if err != nil {
+		return 0, err
+	}
+	if len(ports) == 0 {
+		return peerIPs, nil
+	}
+	return peerIPs * int32(len(ports)), nil
+}
+
+// peerIPs returns the number of the ips and cidrs matched by the peers, the empty peers
+// match all the addresses as a cidr.
+func (s *Simulator) peerIPs(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, peers []securityv1alpha1.SecurityPolicyPeer) (int32, error) {
+	if len(peers) == 0 {
+		return 1, nil
+	}
+
+	var ips int32
+	for _, peer := range peers {
+		switch {
+		case peer.IPBlock != nil:
+			ips += 1 + int32(len(peer.IPBlock.Except))
+		case peer.FQDN != "":
+			// the addresses of the names are learned on the agents, count as one
+			ips++
+		default:
+			members, err := s.groupMembers(ctx, ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer))
+			if err != nil {
+				return 0, err
+			}
+			for _, member := range members {
+				ips += int32(len(member.IPs))
+			}
+		}
+	}
+	return ips, nil
+}
+
+func (s *Simulator) groupMembers(ctx context.Context, group *groupv1alpha1.EndpointGroup) ([]groupv1alpha1.GroupMember, error) {
+	if group == nil {
+		return nil, nil
+	}
+	if members, ok := s.members[group.Name]; ok {
+		return members, nil
+	}
+	members, err := groupctrl.FetchGroupMembers(ctx, s.client, group)
+	if err != nil {
+		return nil, fmt.Errorf("fetch group %s members: %s", group.Name, err)
+	}
+	s.members[group.Name] = members.GroupMembers
+	return members.GroupMembers, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstatus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/version"
+)
+
+const (
+	// AgentDownTimeout is the duration without heartbeat, after which an agent is unhealthy.
+	AgentDownTimeout = 3 * time.Minute
+	// ResyncPeriod is the max interval between two full reconciles, the agents health
+	// changes with the time even if no object changed.
+	ResyncPeriod = time.Minute
+)
+
+// Reconciler maintains the singleton ClusterStatus, the summary is recomputed from all the
+// AgentInfos, SecurityPolicies and EndpointGroups on any of them changed.
+type Reconciler struct {
+	client.Client
+}
+
+// Reconcile recompute the cluster status, update it if changed or not refreshed in ResyncPeriod.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	now := time.Now()
+	klog.V(4).Infof("ClusterStatusReconciler received %s reconcile", req.Name)
+
+	expect, err := r.buildClusterStatus(ctx, now)
+	if err != nil {
+		klog.Errorf("unable to summarize cluster status: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	status := agentv1alpha1.ClusterStatus{}
+	err = r.Get(ctx, types.NamespacedName{Name: agentv1alpha1.ClusterStatusName}, &status)
+	switch {
+	case errors.IsNotFound(err):
+		expect.LastFullReconcileTime = metav1.NewTime(now)
+		if err = r.Create(ctx, expect); err != nil {
+			klog.Errorf("failed to create cluster status: %s", err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: ResyncPeriod}, nil
+	case err != nil:
+		klog.Errorf("unable to fetch cluster status: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	nextResync := status.LastFullReconcileTime.Add(ResyncPeriod)
+	if summaryEqual(&status, expect) && now.Before(nextResync) {
+		return ctrl.Result{RequeueAfter: nextResync.Sub(now)}, nil
+	}
+	status.ControllerVersion = expect.ControllerVersion
+	status.Agents = expect.Agents
+	status.Policies = expect.Policies
+	status.ErrorGroups = expect.ErrorGroups
+	status.LastFullReconcileTime = metav1.NewTime(now)
+	if err = r.Update(ctx, &status); err != nil {
+		klog.Errorf("failed to update cluster status: %s", err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: ResyncPeriod}, nil
+}
+
+// SetupWithManager create and add ClusterStatus Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	// the summary is computed from all the objects, a single worker is enough
+	c, err := controller.New("clusterstatus-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	// all the changes are merged into the singleton request by the work queue
+	enqueueClusterStatus := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(handler.MapObject) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: agentv1alpha1.ClusterStatusName}}}
+		}),
+	}
+	for _, object := range []runtime.Object{
+		&agentv1alpha1.AgentInfo{},
+		&securityv1alpha1.SecurityPolicy{},
+		&groupv1alpha1.EndpointGroup{},
+		&groupv1alpha1.GroupMembers{},
+		// rebuild the cluster status when it's modified or removed by others
+		&agentv1alpha1.ClusterStatus{},
+	} {
+		if err = c.Watch(&source.Kind{Type: object}, enqueueClusterStatus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) buildClusterStatus(ctx context.Context, now time.Time) (*agentv1alpha1.ClusterStatus, error) {
+	status := &agentv1alpha1.ClusterStatus{
+		ObjectMeta:        metav1.ObjectMeta{Name: agentv1alpha1.ClusterStatusName},
+		ControllerVersion: version.Version,
+	}
+
+	agentInfoList := agentv1alpha1.AgentInfoList{}
+	if err := r.List(ctx, &agentInfoList); err != nil {
+		return nil, fmt.Errorf("list agentinfos: %s", err)
+	}
+	for i := range agentInfoList.Items {
+		status.Agents.Total++
+		if isAgentHealthy(&agentInfoList.Items[i], now) {
+			status.Agents.Healthy++
+		}
+	}
+
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(ctx, &policyList); err != nil {
+		return nil, fmt.Errorf("list policies: %s", err)
+	}
+	for _, policy := range policyList.Items {
+		status.Policies.Total++
+		// the policies not compiled yet are realized unless failed, the same as the agents do
+		if !meta.IsStatusConditionFalse(policy.Status.Conditions, securityv1alpha1.SecurityPolicyCompiled) {
+			status.Policies.Realized++
+		}
+	}
+
+	groupList := groupv1alpha1.EndpointGroupList{}
+	if err := r.List(ctx, &groupList); err != nil {
+		return nil, fmt.Errorf("list endpointgroups: %s", err)
+	}
+	groupMembersList := groupv1alpha1.GroupMembersList{}
+	if err := r.List(ctx, &groupMembersList); err != nil {
+		return nil, fmt.Errorf("list groupmembers: %s", err)
+	}
+	syncedGroups := sets.NewString()
+	for _, groupMembers := range groupMembersList.Items {
+		syncedGroups.Insert(groupMembers.Name)
+	}
+	for i := range groupList.Items {
+		if isErrorGroup(&groupList.Items[i], syncedGroups) {
+			status.ErrorGroups = append(status.ErrorGroups, groupList.Items[i].Name)
+		}
+	}
+	sort.Strings(status.ErrorGroups)
+
+	return status, nil
+}
+
+func summaryEqual(actual, expect *agentv1alpha1.ClusterStatus) bool {
+	return actual.ControllerVersion == expect.ControllerVersion &&
+		actual.Agents == expect.Agents &&
+		actual.Policies == expect.Policies &&
+		reflect.DeepEqual(actual.ErrorGroups, expect.ErrorGroups)
+}
+
+func isAgentHealthy(agentInfo *agentv1alpha1.AgentInfo, now time.Time) bool {
+	for _, condition := range agentInfo.Conditions {
+		if condition.Type == agentv1alpha1.AgentHealthy {
+			return condition.Status == corev1.ConditionTrue &&
+				now.Sub(condition.LastHeartbeatTime.Time) <= AgentDownTimeout
+		}
+	}
+	return false
+}
+
+// isErrorGroup return true if the members of the group could not be resolved: the namespace
+// selector is invalid, or the group controller has not synced the GroupMembers of the group.
+func isErrorGroup(group *groupv1alpha1.EndpointGroup, syncedGroups sets.String) bool {
+	if group.DeletionTimestamp != nil {
+		return false
+	}
+	if group.Spec.Namespace == nil && group.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(group.Spec.NamespaceSelector); err != nil {
+			return true
+		}
+	}
+	// the group controller adds the finalizer to the new group first, then syncs its members
+	return len(group.Finalizers) != 0 && !syncedGroups.Has(group.Name)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstatus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+)
+
+func newAgentInfo(name string, lastHeartbeat time.Time) *agentv1alpha1.AgentInfo {
+	return &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Conditions: []agentv1alpha1.AgentCondition{{
+			Type:              agentv1alpha1.AgentHealthy,
+			Status:            corev1.ConditionTrue,
+			LastHeartbeatTime: metav1.NewTime(lastHeartbeat),
+		}},
+	}
+}
+
+func newPolicy(name string, tier string) *securityv1alpha1.SecurityPolicy {
+	return &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:      tier,
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &name}},
+		},
+	}
+}
+
+func newGroup(name string, namespaceSelector *metav1.LabelSelector) *groupv1alpha1.EndpointGroup {
+	return &groupv1alpha1.EndpointGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Finalizers: []string{constants.DependentsCleanFinalizer}},
+		Spec:       groupv1alpha1.EndpointGroupSpec{NamespaceSelector: namespaceSelector},
+	}
+}
+
+func newGroupMembers(name string) *groupv1alpha1.GroupMembers {
+	return &groupv1alpha1.GroupMembers{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	invalidSelector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "env", Operator: "Unknown"},
+	}}
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		newAgentInfo("agent01", time.Now()),
+		newAgentInfo("agent02", time.Now().Add(-time.Hour)),
+		newPolicy("policy01", constants.Tier2),
+		newPolicy("policy02", "Tier_Unknown"),
+		newGroup("group01", nil),
+		newGroupMembers("group01"),
+		newGroup("group02", nil),
+		newGroup("group03", invalidSelector),
+		newGroupMembers("group03"),
+	)
+	// the compiled conditions are written as the controller starts
+	Expect(ctrlpolicy.CompileExistingPolicies(ctx, c, c.Status())).Should(Succeed())
+	// the policy created after, not compiled yet
+	Expect(c.Create(ctx, newPolicy("policy03", constants.Tier2))).Should(Succeed())
+	r := &Reconciler{Client: c}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: agentv1alpha1.ClusterStatusName}}
+	getClusterStatus := func() *agentv1alpha1.ClusterStatus {
+		status := &agentv1alpha1.ClusterStatus{}
+		Expect(c.Get(ctx, req.NamespacedName, status)).Should(Succeed())
+		return status
+	}
+
+	result, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(result.RequeueAfter).Should(Equal(ResyncPeriod))
+	status := getClusterStatus()
+	Expect(status.Agents).Should(Equal(agentv1alpha1.AgentsSummary{Total: 2, Healthy: 1}))
+	Expect(status.Policies).Should(Equal(agentv1alpha1.PoliciesSummary{Total: 3, Realized: 2}))
+	Expect(status.ErrorGroups).Should(Equal([]string{"group02", "group03"}))
+	Expect(status.ControllerVersion).ShouldNot(BeEmpty())
+	Expect(status.LastFullReconcileTime.IsZero()).Should(BeFalse())
+
+	// not updated when the summary unchanged in the resync period
+	result, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(result.RequeueAfter).Should(BeNumerically("<=", ResyncPeriod))
+	Expect(getClusterStatus().ResourceVersion).Should(Equal(status.ResourceVersion))
+
+	Expect(c.Create(ctx, newGroupMembers("group02"))).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getClusterStatus().ErrorGroups).Should(Equal([]string{"group03"}))
+
+	// refreshed when not refreshed in the resync period
+	status = getClusterStatus()
+	status.LastFullReconcileTime = metav1.NewTime(time.Now().Add(-ResyncPeriod))
+	Expect(c.Update(ctx, status)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getClusterStatus().LastFullReconcileTime.After(status.LastFullReconcileTime.Time)).Should(BeTrue())
+
+	// rebuilt when removed by others
+	Expect(c.Delete(ctx, getClusterStatus())).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getClusterStatus().Agents.Total).Should(Equal(int32(2)))
+}
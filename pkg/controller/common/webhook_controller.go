@@ -59,6 +59,11 @@ func (r *WebhookReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		klog.Fatalf("could not found secret %s/%s, err: %s", secretReq.Namespace, secretReq.Name, err)
 	}
 
+	if req.Name == constants.EverouteMutatingWebhookName {
+		r.updateMutatingWebhook(ctx, req, secret)
+		return ctrl.Result{}, nil
+	}
+
 	webhook := &admv1.ValidatingWebhookConfiguration{}
 	if err := r.Get(ctx, req.NamespacedName, webhook); err != nil {
 		klog.Fatalf("could not found secret %s/%s, err: %s", secretReq.Namespace, secretReq.Name, err)
@@ -82,6 +87,23 @@ func (r *WebhookReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+// updateMutatingWebhook sets the ca of the secret as the caBundle of the mutating webhook.
+func (r *WebhookReconciler) updateMutatingWebhook(ctx context.Context, req ctrl.Request, secret *corev1.Secret) {
+	webhookObj := &admv1.MutatingWebhookConfiguration{}
+	if err := backoff.Retry(func() error {
+		if err := r.Get(ctx, req.NamespacedName, webhookObj); err != nil {
+			return err
+		}
+		if bytes.Equal(webhookObj.Webhooks[0].ClientConfig.CABundle, secret.Data["ca.crt"]) {
+			return nil
+		}
+		webhookObj.Webhooks[0].ClientConfig.CABundle = append([]byte{}, secret.Data["ca.crt"]...)
+		return r.Update(ctx, webhookObj)
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 10)); err != nil {
+		klog.Fatalf("fail to update mutating webhook after 10 tries. err: %s", err)
+	}
+}
+
 // SetupWithManager create and add Webhook Controller to the manager.
 func (r *WebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if mgr == nil {
@@ -120,5 +142,28 @@ func (r *WebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err = c.Watch(&source.Kind{Type: &admv1.MutatingWebhookConfiguration{}}, &handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			if e.Object == nil {
+				klog.Errorf("receive create event with no object %v", e)
+				return
+			}
+			if e.Meta.GetName() == constants.EverouteMutatingWebhookName {
+				q.Add(ctrl.Request{NamespacedName: types.NamespacedName{
+					Name: e.Meta.GetName(),
+				}})
+			}
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			if e.MetaNew.GetName() == constants.EverouteMutatingWebhookName {
+				q.Add(ctrl.Request{NamespacedName: types.NamespacedName{
+					Name: e.MetaNew.GetName(),
+				}})
+			}
+		},
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
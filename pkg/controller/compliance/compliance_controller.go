@@ -0,0 +1,395 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+// DefaultRiskyPorts are the remote access and file sharing ports: ftp, ssh, telnet, msrpc,
+// netbios, smb and rdp.
+var DefaultRiskyPorts = []int32{21, 22, 23, 135, 139, 445, 3389}
+
+// AllChecks are the checks evaluated if the ComplianceReport doesn't specify the checks.
+var AllChecks = []securityv1alpha1.ComplianceCheckType{
+	securityv1alpha1.ComplianceCheckDefaultDeny,
+	securityv1alpha1.ComplianceCheckRiskyRuleLogging,
+	securityv1alpha1.ComplianceCheckNoAnyAnyAllow,
+	securityv1alpha1.ComplianceCheckQuarantineEnabled,
+}
+
+// Reconciler evaluates the compliance checks of the ComplianceReports, the reports are
+// re-evaluated on the policies, endpoints or the features of the agents changed.
+type Reconciler struct {
+	client.Client
+}
+
+// clusterState is the objects the compliance checks evaluated against.
+type clusterState struct {
+	globalPolicies []securityv1alpha1.GlobalPolicy
+	policies       []securityv1alpha1.SecurityPolicy
+	endpoints      []securityv1alpha1.Endpoint
+	agentInfos     []agentv1alpha1.AgentInfo
+}
+
+// Reconcile evaluates the checks of the report, update the status if the result changed.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("ComplianceReconciler received report %s reconcile", req.Name)
+
+	report := securityv1alpha1.ComplianceReport{}
+	if err := r.Get(ctx, req.NamespacedName, &report); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		klog.Errorf("unable to fetch compliance report %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	state, err := r.fetchClusterState(ctx)
+	if err != nil {
+		klog.Errorf("unable to fetch cluster state: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	status := evaluate(&report.Spec, state)
+	if statusEqual(&report.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	status.GeneratedTime = metav1.NewTime(time.Now())
+	report.Status = *status
+	if err = r.Status().Update(ctx, &report); err != nil {
+		klog.Errorf("failed to update compliance report %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	klog.Infof("compliance report %s scored %d, %d checks failed", req.Name, status.Score, status.Failed)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Compliance Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("compliance-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.ComplianceReport{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+
+	enqueueReports := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.allReports),
+	}
+	for _, object := range []runtime.Object{
+		&securityv1alpha1.GlobalPolicy{},
+		&securityv1alpha1.SecurityPolicy{},
+		&securityv1alpha1.Endpoint{},
+	} {
+		if err = c.Watch(&source.Kind{Type: object}, enqueueReports); err != nil {
+			return err
+		}
+	}
+	// the agentinfos are updated on each heartbeat, only the features changes are concerned
+	return c.Watch(&source.Kind{Type: &agentv1alpha1.AgentInfo{}}, enqueueReports, predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, oldOk := e.ObjectOld.(*agentv1alpha1.AgentInfo)
+			newObj, newOk := e.ObjectNew.(*agentv1alpha1.AgentInfo)
+			return !oldOk || !newOk || !reflect.DeepEqual(oldObj.Features, newObj.Features)
+		},
+	})
+}
+
+func (r *Reconciler) allReports(handler.MapObject) []reconcile.Request {
+	reportList := securityv1alpha1.ComplianceReportList{}
+	if err := r.List(context.Background(), &reportList); err != nil {
+		klog.Errorf("unable to list compliance reports: %s", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(reportList.Items))
+	for _, report := range reportList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: report.Name}})
+	}
+	return requests
+}
+
+func (r *Reconciler) fetchClusterState(ctx context.Context) (*clusterState, error) {
+	globalPolicyList := securityv1alpha1.GlobalPolicyList{}
+	if err := r.List(ctx, &globalPolicyList); err != nil {
+		return nil, fmt.Errorf("list globalpolicies: %s", err)
+	}
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(ctx, &policyList); err != nil {
+		return nil, fmt.Errorf("list policies: %s", err)
+	}
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := r.List(ctx, &endpointList); err != nil {
+		return nil, fmt.Errorf("list endpoints: %s", err)
+	}
+	agentInfoList := agentv1alpha1.AgentInfoList{}
+	if err := r.List(ctx, &agentInfoList); err != nil {
+		return nil, fmt.Errorf("list agentinfos: %s", err)
+	}
+	return &clusterState{
+		globalPolicies: globalPolicyList.Items,
+		policies:       policyList.Items,
+		endpoints:      endpointList.Items,
+		agentInfos:     agentInfoList.Items,
+	}, nil
+}
+
+// evaluate the checks of the spec against the cluster state, the score is the percentage
+// of the checks passed.
+func evaluate(spec *securityv1alpha1.ComplianceReportSpec, state *clusterState) *securityv1alpha1.ComplianceReportStatus {
+	checks := spec.Checks
+	if len(checks) == 0 {
+		checks = AllChecks
+	}
+	riskyPorts := spec.RiskyPorts
+	if len(riskyPorts) == 0 {
+		riskyPorts = DefaultRiskyPorts
+	}
+
+	status := &securityv1alpha1.ComplianceReportStatus{}
+	for _, check := range checks {
+		var result securityv1alpha1.ComplianceCheckResult
+		switch check {
+		case securityv1alpha1.ComplianceCheckDefaultDeny:
+			result = checkDefaultDeny(state)
+		case securityv1alpha1.ComplianceCheckRiskyRuleLogging:
+			result = checkRiskyRuleLogging(state, riskyPorts)
+		case securityv1alpha1.ComplianceCheckNoAnyAnyAllow:
+			result = checkNoAnyAnyAllow(state)
+		case securityv1alpha1.ComplianceCheckQuarantineEnabled:
+			result = checkQuarantineEnabled(state)
+		default:
+			result = securityv1alpha1.ComplianceCheckResult{Message: "unknown compliance check"}
+		}
+		result.Type = check
+		sort.Strings(result.Findings)
+		status.Checks = append(status.Checks, result)
+
+		if result.Passed {
+			status.Passed++
+		} else {
+			status.Failed++
+		}
+	}
+	if len(status.Checks) != 0 {
+		status.Score = status.Passed * 100 / int32(len(status.Checks))
+	}
+	return status
+}
+
+// checkDefaultDeny passes if the GlobalPolicy drops by default, or all the endpoints are
+// isolated by the SecurityPolicies applied to them.
+func checkDefaultDeny(state *clusterState) securityv1alpha1.ComplianceCheckResult {
+	for _, globalPolicy := range state.globalPolicies {
+		if globalPolicy.Spec.DefaultAction == securityv1alpha1.GlobalDefaultActionDrop {
+			return securityv1alpha1.ComplianceCheckResult{Passed: true, Message: "global policy drops by default"}
+		}
+	}
+
+	var findings []string
+	for _, endpoint := range state.endpoints {
+		if len(endpoint.Status.AppliedPolicies) == 0 {
+			findings = append(findings, "endpoint "+endpoint.Namespace+"/"+endpoint.Name)
+		}
+	}
+	if len(findings) != 0 {
+		return securityv1alpha1.ComplianceCheckResult{
+			Message:  fmt.Sprintf("global policy allows by default, %d endpoints not applied by any policy", len(findings)),
+			Findings: findings,
+		}
+	}
+	return securityv1alpha1.ComplianceCheckResult{Passed: true, Message: "all endpoints are applied by policies"}
+}
+
+// checkRiskyRuleLogging passes if the flow logging is enabled on all the agents when any
+// rule allows the risky ports.
+func checkRiskyRuleLogging(state *clusterState, riskyPorts []int32) securityv1alpha1.ComplianceCheckResult {
+	var riskyRules int
+	forEachAllowRule(state.policies, func(_ string, rule *securityv1alpha1.Rule, _ []securityv1alpha1.SecurityPolicyPeer) {
+		if allowsAnyPort(rule.Ports, riskyPorts) {
+			riskyRules++
+		}
+	})
+	if riskyRules == 0 {
+		return securityv1alpha1.ComplianceCheckResult{Passed: true, Message: "no rule allows the risky ports"}
+	}
+
+	findings := agentsWithoutFeature(state.agentInfos, agentv1alpha1.FeatureFlowLog)
+	if len(findings) != 0 {
+		return securityv1alpha1.ComplianceCheckResult{
+			Message:  fmt.Sprintf("%d rules allow the risky ports, flow logging disabled on %d agents", riskyRules, len(findings)),
+			Findings: findings,
+		}
+	}
+	return securityv1alpha1.ComplianceCheckResult{
+		Passed:  true,
+		Message: fmt.Sprintf("%d rules allow the risky ports, flow logging enabled on all agents", riskyRules),
+	}
+}
+
+// checkNoAnyAnyAllow passes if no rule allows all the peers on all the ports.
+func checkNoAnyAnyAllow(state *clusterState) securityv1alpha1.ComplianceCheckResult {
+	var findings []string
+	forEachAllowRule(state.policies, func(ruleName string, rule *securityv1alpha1.Rule, peers []securityv1alpha1.SecurityPolicyPeer) {
+		if len(rule.Ports) == 0 && isAnyPeer(peers) {
+			findings = append(findings, "rule "+ruleName)
+		}
+	})
+	if len(findings) != 0 {
+		return securityv1alpha1.ComplianceCheckResult{
+			Message:  fmt.Sprintf("%d rules allow any peer on any port", len(findings)),
+			Findings: findings,
+		}
+	}
+	return securityv1alpha1.ComplianceCheckResult{Passed: true, Message: "no rule allows any peer on any port"}
+}
+
+// checkQuarantineEnabled passes if all the agents quarantine the endpoints scanning ports.
+func checkQuarantineEnabled(state *clusterState) securityv1alpha1.ComplianceCheckResult {
+	findings := agentsWithoutFeature(state.agentInfos, agentv1alpha1.FeaturePortScanQuarantine)
+	if len(findings) != 0 {
+		return securityv1alpha1.ComplianceCheckResult{
+			Message:  fmt.Sprintf("port scan quarantine disabled on %d agents", len(findings)),
+			Findings: findings,
+		}
+	}
+	return securityv1alpha1.ComplianceCheckResult{Passed: true, Message: "port scan quarantine enabled on all agents"}
+}
+
+// forEachAllowRule calls fn with the rules allowing traffics, the name of the rule is in
+// format of namespace/policy/direction.rule, the peers are the from of the ingress rule or
+// the to of the egress rule.
+func forEachAllowRule(policies []securityv1alpha1.SecurityPolicy, fn func(string, *securityv1alpha1.Rule, []securityv1alpha1.SecurityPolicyPeer)) {
+	for i := range policies {
+		policy := &policies[i]
+		prefix := policy.Namespace + "/" + policy.Name + "/"
+		for j := range policy.Spec.IngressRules {
+			rule := &policy.Spec.IngressRules[j]
+			if isAllowRule(rule) {
+				fn(prefix+"ingress."+rule.Name, rule, rule.From)
+			}
+		}
+		for j := range policy.Spec.EgressRules {
+			rule := &policy.Spec.EgressRules[j]
+			if isAllowRule(rule) {
+				fn(prefix+"egress."+rule.Name, rule, rule.To)
+			}
+		}
+	}
+}
+
+func isAllowRule(rule *securityv1alpha1.Rule) bool {
+	return rule.Action == "" || rule.Action == securityv1alpha1.RuleActionAllow
+}
+
+// isAnyPeer returns true if the peers match all the ips, the empty peers match all.
+func isAnyPeer(peers []securityv1alpha1.SecurityPolicyPeer) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil && len(peer.IPBlock.Except) == 0 && strings.HasSuffix(peer.IPBlock.CIDR, "/0") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAnyPort returns true if the ports match any of the given port numbers, the empty
+// ports match all. The named ports are resolved on the agents, which are not considered.
+func allowsAnyPort(ports []securityv1alpha1.SecurityPolicyPort, numbers []int32) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, port := range ports {
+		if port.Protocol == securityv1alpha1.ProtocolICMP || port.Protocol == securityv1alpha1.ProtocolICMPv6 || port.Type == securityv1alpha1.PortTypeName {
+			continue
+		}
+		for _, portRange := range strings.Split(port.PortRange, ",") {
+			begin, end, err := policycache.UnmarshalPortRange(strings.TrimSpace(portRange))
+			if err != nil {
+				continue
+			}
+			if begin == 0 && end == 0 {
+				return true
+			}
+			for _, number := range numbers {
+				if number >= int32(begin) && number <= int32(end) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func agentsWithoutFeature(agentInfos []agentv1alpha1.AgentInfo, feature agentv1alpha1.AgentFeature) []string {
+	var agents []string
+	for _, agentInfo := range agentInfos {
+		enabled := false
+		for _, item := range agentInfo.Features {
+			if item == feature {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			agents = append(agents, "agent "+agentInfo.Name)
+		}
+	}
+	return agents
+}
+
+func statusEqual(actual, expect *securityv1alpha1.ComplianceReportStatus) bool {
+	return actual.Score == expect.Score &&
+		actual.Passed == expect.Passed &&
+		actual.Failed == expect.Failed &&
+		reflect.DeepEqual(actual.Checks, expect.Checks)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compliance
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+)
+
+func newAgentInfo(name string, features ...agentv1alpha1.AgentFeature) *agentv1alpha1.AgentInfo {
+	return &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: name}, Features: features}
+}
+
+func newEndpoint(name string, appliedPolicies ...string) *securityv1alpha1.Endpoint {
+	endpoint := &securityv1alpha1.Endpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	for _, policy := range appliedPolicies {
+		endpoint.Status.AppliedPolicies = append(endpoint.Status.AppliedPolicies, securityv1alpha1.NamespacedName{Namespace: "default", Name: policy})
+	}
+	return endpoint
+}
+
+func newPolicy(name string, ingress ...securityv1alpha1.Rule) *securityv1alpha1.SecurityPolicy {
+	return &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       securityv1alpha1.SecurityPolicySpec{IngressRules: ingress},
+	}
+}
+
+func TestAllowsAnyPort(t *testing.T) {
+	RegisterTestingT(t)
+
+	tcp := func(portRange string) securityv1alpha1.SecurityPolicyPort {
+		return securityv1alpha1.SecurityPolicyPort{Protocol: securityv1alpha1.ProtocolTCP, PortRange: portRange}
+	}
+	Expect(allowsAnyPort(nil, DefaultRiskyPorts)).Should(BeTrue())
+	Expect(allowsAnyPort([]securityv1alpha1.SecurityPolicyPort{tcp("")}, DefaultRiskyPorts)).Should(BeTrue())
+	Expect(allowsAnyPort([]securityv1alpha1.SecurityPolicyPort{tcp("80,443")}, DefaultRiskyPorts)).Should(BeFalse())
+	Expect(allowsAnyPort([]securityv1alpha1.SecurityPolicyPort{tcp("80,20-22")}, DefaultRiskyPorts)).Should(BeTrue())
+	Expect(allowsAnyPort([]securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolICMP}}, DefaultRiskyPorts)).Should(BeFalse())
+	Expect(allowsAnyPort([]securityv1alpha1.SecurityPolicyPort{tcp("8080")}, []int32{8080})).Should(BeTrue())
+}
+
+func TestEvaluate(t *testing.T) {
+	RegisterTestingT(t)
+
+	state := &clusterState{
+		globalPolicies: []securityv1alpha1.GlobalPolicy{{Spec: securityv1alpha1.GlobalPolicySpec{DefaultAction: securityv1alpha1.GlobalDefaultActionAllow}}},
+		policies: []securityv1alpha1.SecurityPolicy{
+			*newPolicy("web",
+				securityv1alpha1.Rule{Name: "http", Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "80"}}},
+				securityv1alpha1.Rule{Name: "ssh", Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "22"}},
+					From: []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+			),
+			*newPolicy("any",
+				securityv1alpha1.Rule{Name: "all", From: []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}}}},
+				securityv1alpha1.Rule{Name: "honeypot", Action: securityv1alpha1.RuleActionRedirect},
+			),
+		},
+		endpoints:  []securityv1alpha1.Endpoint{*newEndpoint("web01", "web"), *newEndpoint("db01")},
+		agentInfos: []agentv1alpha1.AgentInfo{*newAgentInfo("agent01", agentv1alpha1.FeatureFlowLog, agentv1alpha1.FeaturePortScanQuarantine), *newAgentInfo("agent02")},
+	}
+
+	status := evaluate(&securityv1alpha1.ComplianceReportSpec{}, state)
+	Expect(status.Passed).Should(Equal(int32(0)))
+	Expect(status.Failed).Should(Equal(int32(4)))
+	Expect(status.Score).Should(Equal(int32(0)))
+	Expect(status.Checks).Should(Equal([]securityv1alpha1.ComplianceCheckResult{{
+		Type:     securityv1alpha1.ComplianceCheckDefaultDeny,
+		Message:  "global policy allows by default, 1 endpoints not applied by any policy",
+		Findings: []string{"endpoint default/db01"},
+	}, {
+		Type:     securityv1alpha1.ComplianceCheckRiskyRuleLogging,
+		Message:  "2 rules allow the risky ports, flow logging disabled on 1 agents",
+		Findings: []string{"agent agent02"},
+	}, {
+		Type:     securityv1alpha1.ComplianceCheckNoAnyAnyAllow,
+		Message:  "1 rules allow any peer on any port",
+		Findings: []string{"rule default/any/ingress.all"},
+	}, {
+		Type:     securityv1alpha1.ComplianceCheckQuarantineEnabled,
+		Message:  "port scan quarantine disabled on 1 agents",
+		Findings: []string{"agent agent02"},
+	}}))
+
+	state.globalPolicies[0].Spec.DefaultAction = securityv1alpha1.GlobalDefaultActionDrop
+	state.policies = state.policies[:1]
+	state.agentInfos = state.agentInfos[:1]
+	status = evaluate(&securityv1alpha1.ComplianceReportSpec{}, state)
+	Expect(status.Passed).Should(Equal(int32(4)))
+	Expect(status.Score).Should(Equal(int32(100)))
+
+	// only the specified checks are evaluated
+	status = evaluate(&securityv1alpha1.ComplianceReportSpec{
+		Checks:     []securityv1alpha1.ComplianceCheckType{securityv1alpha1.ComplianceCheckRiskyRuleLogging},
+		RiskyPorts: []int32{3306},
+	}, state)
+	Expect(status.Checks).Should(HaveLen(1))
+	Expect(status.Checks[0].Message).Should(Equal("no rule allows the risky ports"))
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		&securityv1alpha1.ComplianceReport{ObjectMeta: metav1.ObjectMeta{Name: "pci"}},
+		newEndpoint("web01"),
+		newAgentInfo("agent01", agentv1alpha1.FeaturePortScanQuarantine),
+	)
+	r := &Reconciler{Client: c}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "pci"}}
+	getReport := func() *securityv1alpha1.ComplianceReport {
+		report := &securityv1alpha1.ComplianceReport{}
+		Expect(c.Get(ctx, req.NamespacedName, report)).Should(Succeed())
+		return report
+	}
+
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	report := getReport()
+	Expect(report.Status.Score).Should(Equal(int32(75)))
+	Expect(report.Status.Failed).Should(Equal(int32(1)))
+	Expect(report.Status.GeneratedTime.IsZero()).Should(BeFalse())
+
+	// not updated when the result unchanged
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getReport().ResourceVersion).Should(Equal(report.ResourceVersion))
+
+	Expect(c.Create(ctx, newPolicy("web"))).Should(Succeed())
+	endpoint := &securityv1alpha1.Endpoint{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "web01"}, endpoint)).Should(Succeed())
+	endpoint.Status.AppliedPolicies = []securityv1alpha1.NamespacedName{{Namespace: "default", Name: "web"}}
+	Expect(c.Update(ctx, endpoint)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getReport().Status.Score).Should(Equal(int32(100)))
+
+	// the removed report is ignored
+	Expect(c.Delete(ctx, getReport())).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+}
@@ -63,6 +63,10 @@ const (
 	k8sEndpointExternalIDKey     = "pod-uuid"
 	ifaceIPAddrTimeout       int = 1800
 	IfaceIPAddrCleanInterval int = 5
+
+	// lastSeenTimeResolution the endpoint status is not updated only for the last seen time
+	// refreshed within it, the ips are learned again on each packet from the endpoint
+	lastSeenTimeResolution = time.Minute
 )
 
 // Reconcile receive endpoint from work queue, synchronize the endpoint status
@@ -91,7 +95,7 @@ func (r *EndpointReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		if len(endpoint.Status.IPs) == 0 {
 			return ctrl.Result{}, nil
 		}
-		expectStatus = r.fetchEndpointStatusByIP(endpoint.Status.IPs)
+		expectStatus = r.fetchEndpointStatusByIP(endpoint.Spec.VRF, endpoint.Status.IPs)
 	default:
 		// Fetch enpoint status from agentinfo.
 		expectStatus, err = r.fetchEndpointStatusFromAgentInfo(GetEndpointID(endpoint))
@@ -106,6 +110,8 @@ func (r *EndpointReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, nil
 	}
 
+	// the applied policies are maintained by the policy controller
+	expectStatus.AppliedPolicies = endpoint.Status.AppliedPolicies
 	endpoint.Status = *expectStatus
 	if err := r.Status().Update(ctx, &endpoint); err != nil {
 		klog.Errorf("failed to update endpoint %s status: %s", endpoint.Name, err.Error())
@@ -211,10 +217,13 @@ func (r *EndpointReconciler) addAgentInfo(e event.CreateEvent, q workqueue.RateL
 				iface := &iface{
 					agentName:           agentInfo.Name,
 					name:                ovsIface.Name,
+					vrf:                 bridge.VRF,
+					vlan:                port.VlanConfig.AccessVLAN(),
 					agentTime:           t,
 					externalIDs:         ovsIface.ExternalIDs,
 					mac:                 ovsIface.Mac,
 					ipLastUpdateTimeMap: ovsIface.IPMap,
+					ipLeaseExpiryMap:    ovsIface.IPLeaseMap,
 				}
 				_ = r.ifaceCache.Add(iface)
 			}
@@ -247,10 +256,13 @@ func (r *EndpointReconciler) updateAgentInfo(e event.UpdateEvent, q workqueue.Ra
 				iface := &iface{
 					agentName:           newAgentInfo.Name,
 					name:                ovsIface.Name,
+					vrf:                 bridge.VRF,
+					vlan:                port.VlanConfig.AccessVLAN(),
 					agentTime:           t,
 					externalIDs:         ovsIface.ExternalIDs,
 					mac:                 ovsIface.Mac,
 					ipLastUpdateTimeMap: ovsIface.IPMap,
+					ipLeaseExpiryMap:    ovsIface.IPLeaseMap,
 				}
 				_ = r.ifaceCache.Add(iface)
 			}
@@ -302,7 +314,7 @@ func (r *EndpointReconciler) toUpdatedAgentInfo(newAgentInfo *agentv1alpha1.Agen
 		for i, bridge := range agentInfo.OVSInfo.Bridges {
 			for j, port := range bridge.Ports {
 				for k, ovsIface := range port.Interfaces {
-					ipNeedDelete := r.getDeletedIP(agentInfo.Name, ovsIface, newAgentInfo)
+					ipNeedDelete := r.getDeletedIP(agentInfo.Name, bridge.VRF, ovsIface, newAgentInfo)
 					if ipNeedDelete.Len() == 0 {
 						continue
 					}
@@ -325,8 +337,13 @@ func (r *EndpointReconciler) toUpdatedAgentInfo(newAgentInfo *agentv1alpha1.Agen
 	return updatedAgentInfoes
 }
 
-func (r *EndpointReconciler) getDeletedIP(agentName string, ovsInterface agentv1alpha1.OVSInterface, agentInfo *agentv1alpha1.AgentInfo) sets.String {
+// getDeletedIP returns the ips of the interface which have been learned by the other interfaces in the same vrf,
+// the interfaces in the different vrfs could have overlapping ips.
+func (r *EndpointReconciler) getDeletedIP(agentName, vrf string, ovsInterface agentv1alpha1.OVSInterface, agentInfo *agentv1alpha1.AgentInfo) sets.String {
 	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		if bridge.VRF != vrf {
+			continue
+		}
 		for _, port := range bridge.Ports {
 			for _, ovsIface := range port.Interfaces {
 				if agentInfo.Name == agentName && ovsIface.Name == ovsInterface.Name {
@@ -351,7 +368,7 @@ func (r *EndpointReconciler) enqueueEndpointsOnAgentLocked(epList securityv1alph
 		ifaces = append(ifaces, ifacesExt...)
 		if ep.Spec.Type == securityv1alpha1.EndpointStaticIP {
 			for _, ip := range ep.Status.IPs {
-				ifacesIPAddr, _ := r.ifaceCache.ByIndex(ipAddrIndex, ip.String())
+				ifacesIPAddr, _ := r.ifaceCache.ByIndex(ipAddrIndex, ipAddrIndexKey(ep.Spec.VRF, ip.String()))
 				ifaces = append(ifaces, ifacesIPAddr...)
 			}
 		}
@@ -424,6 +441,8 @@ func (r *EndpointReconciler) updateExpiredIface(expiredIPMap map[string][]string
 					}
 					for _, ip := range expiredIPs {
 						delete(agentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPMap, types.IPAddress(ip))
+						delete(agentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPLeaseMap, types.IPAddress(ip))
+						delete(agentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPSourceMap, types.IPAddress(ip))
 					}
 					isAgentInfoUpdated = true
 				}
@@ -460,17 +479,21 @@ func (r *EndpointReconciler) fetchEndpointStatusFromAgentInfo(id ctrltypes.Exter
 		// combine all ifaces status into endpoint status
 		ipsets := sets.NewString()
 		agentSets := sets.NewString()
+		var lastSeenTime *metav1.Time
 		for _, item := range ifaces {
 			if len(item.(*iface).ipLastUpdateTimeMap) != 0 {
 				agentSets.Insert(item.(*iface).agentName)
-				for ip := range item.(*iface).ipLastUpdateTimeMap {
+				for ip, updateTime := range item.(*iface).ipLastUpdateTimeMap {
 					ipsets.Insert(ip.String())
+					lastSeenTime = laterTime(lastSeenTime, updateTime)
 				}
 			}
 		}
 		endpointStatus := &securityv1alpha1.EndpointStatus{
-			MacAddress: ifaces[0].(*iface).mac,
-			Agents:     agentSets.List(),
+			MacAddress:   ifaces[0].(*iface).mac,
+			Agents:       agentSets.List(),
+			VLAN:         ifaces[0].(*iface).vlan,
+			LastSeenTime: lastSeenTime,
 		}
 		for _, ip := range ipsets.List() {
 			endpointStatus.IPs = append(endpointStatus.IPs, types.IPAddress(ip))
@@ -479,19 +502,24 @@ func (r *EndpointReconciler) fetchEndpointStatusFromAgentInfo(id ctrltypes.Exter
 	}
 }
 
-func (r *EndpointReconciler) fetchEndpointStatusByIP(ips []types.IPAddress) *securityv1alpha1.EndpointStatus {
+func (r *EndpointReconciler) fetchEndpointStatusByIP(vrf string, ips []types.IPAddress) *securityv1alpha1.EndpointStatus {
 	r.ifaceCacheLock.RLock()
 	defer r.ifaceCacheLock.RUnlock()
 	agents := sets.NewString()
+	var lastSeenTime *metav1.Time
 	for _, ip := range ips {
-		ifaces, _ := r.ifaceCache.ByIndex(ipAddrIndex, ip.String())
+		ifaces, _ := r.ifaceCache.ByIndex(ipAddrIndex, ipAddrIndexKey(vrf, ip.String()))
 		for _, item := range ifaces {
 			agents.Insert(item.(*iface).agentName)
+			if updateTime, ok := item.(*iface).ipLastUpdateTimeMap[ip]; ok {
+				lastSeenTime = laterTime(lastSeenTime, updateTime)
+			}
 		}
 	}
 	return &securityv1alpha1.EndpointStatus{
-		IPs:    ips,
-		Agents: agents.List(),
+		IPs:          ips,
+		Agents:       agents.List(),
+		LastSeenTime: lastSeenTime,
 	}
 }
 
@@ -501,8 +529,28 @@ func EqualEndpointStatus(s securityv1alpha1.EndpointStatus, e securityv1alpha1.E
 	macEqual := s.MacAddress == e.MacAddress
 	ipsEqual := utils.EqualIPs(s.IPs, e.IPs)
 	agentEqual := utils.EqualStringSlice(s.Agents, e.Agents)
+	vlanEqual := s.VLAN == e.VLAN
+	lastSeenEqual := equalLastSeenTime(s.LastSeenTime, e.LastSeenTime)
+
+	return macEqual && ipsEqual && agentEqual && vlanEqual && lastSeenEqual
+}
+
+// equalLastSeenTime return true if the two last seen time both unset, or differ less than
+// lastSeenTimeResolution.
+func equalLastSeenTime(s, e *metav1.Time) bool {
+	if s == nil || e == nil {
+		return s == nil && e == nil
+	}
+	diff := s.Sub(e.Time)
+	return diff > -lastSeenTimeResolution && diff < lastSeenTimeResolution
+}
 
-	return macEqual && ipsEqual && agentEqual
+// laterTime return the later one of the last seen time and t.
+func laterTime(lastSeen *metav1.Time, t metav1.Time) *metav1.Time {
+	if lastSeen == nil || t.After(lastSeen.Time) {
+		return t.DeepCopy()
+	}
+	return lastSeen
 }
 
 // GetEndpointID return ID of an endpoint, it's unique in one cluster.
@@ -517,6 +565,9 @@ func computeInterfaceExpiredIPs(timeout int, iface *iface) []string {
 	var expiredIPs []string
 	for ip, t := range iface.ipLastUpdateTimeMap {
 		expireTime := t.Add(time.Duration(timeout) * time.Second)
+		if leaseExpiry, ok := iface.ipLeaseExpiryMap[ip]; ok {
+			expireTime = leaseExpiry.Time
+		}
 		if iface.agentTime.After(expireTime) {
 			expiredIPs = append(expiredIPs, ip.String())
 		}
@@ -559,10 +610,17 @@ type iface struct {
 	agentName string
 	name      string
 	agentTime metav1.Time
+	// vrf is the vrf of the bridge the interface on
+	vrf string
+	// vlan is the access vlan of the port the interface on
+	vlan int32
 
 	externalIDs         map[string]string
 	mac                 string
 	ipLastUpdateTimeMap map[types.IPAddress]metav1.Time
+	// ipLeaseExpiryMap is the lease expiry of the ips learned from dhcp, the leased ips
+	// expire at the end of the lease instead of the timeout
+	ipLeaseExpiryMap map[types.IPAddress]metav1.Time
 }
 
 func (i *iface) String() string {
@@ -595,11 +653,20 @@ func externalIDIndexFunc(obj interface{}) ([]string, error) {
 func ipAddrIndexFunc(obj interface{}) ([]string, error) {
 	var ipAddr []string
 	for ip := range obj.(*iface).ipLastUpdateTimeMap {
-		ipAddr = append(ipAddr, ip.String())
+		ipAddr = append(ipAddr, ipAddrIndexKey(obj.(*iface).vrf, ip.String()))
 	}
 	return ipAddr, nil
 }
 
+// ipAddrIndexKey returns the ipAddrIndex key of the ip in the vrf, the ips in the default vrf
+// are indexed by the ip itself.
+func ipAddrIndexKey(vrf, ip string) string {
+	if vrf == "" {
+		return ip
+	}
+	return vrf + "/" + ip
+}
+
 func toIPStringSet(ipMap map[types.IPAddress]metav1.Time) sets.String {
 	ipStringSet := sets.NewString()
 	for ip := range ipMap {
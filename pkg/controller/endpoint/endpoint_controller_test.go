@@ -18,6 +18,8 @@ package endpoint
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -537,3 +539,155 @@ func testInterfaceIPUpdate(t *testing.T) {
 		}
 	})
 }
+
+func TestComputeInterfaceExpiredIPs(t *testing.T) {
+	now := time.Now()
+	iface := &iface{
+		agentTime: v1.NewTime(now),
+		ipLastUpdateTimeMap: map[types.IPAddress]v1.Time{
+			"10.0.0.1": v1.NewTime(now.Add(-time.Hour)),
+			"10.0.0.2": v1.NewTime(now.Add(-time.Minute)),
+			"10.0.0.3": v1.NewTime(now.Add(-time.Hour)),
+			"10.0.0.4": v1.NewTime(now.Add(-time.Minute)),
+		},
+		ipLeaseExpiryMap: map[types.IPAddress]v1.Time{
+			// the lease outlives the timeout
+			"10.0.0.3": v1.NewTime(now.Add(time.Hour)),
+			// the lease expired before the timeout
+			"10.0.0.4": v1.NewTime(now.Add(-time.Second)),
+		},
+	}
+
+	expiredIPs := computeInterfaceExpiredIPs(1800, iface)
+	sort.Strings(expiredIPs)
+	if !reflect.DeepEqual(expiredIPs, []string{"10.0.0.1", "10.0.0.4"}) {
+		t.Errorf("unexpected expired ips %v", expiredIPs)
+	}
+}
+
+func TestGetDeletedIPInVRF(t *testing.T) {
+	r := newFakeReconciler()
+	ip := types.IPAddress("10.0.0.1")
+	ovsIface := agentv1alpha1.OVSInterface{Name: "iface1", IPMap: map[types.IPAddress]v1.Time{ip: v1.Now()}}
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: v1.ObjectMeta{Name: "agent2"},
+		OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+			Name: "ovsbr1",
+			VRF:  "tenant-a",
+			Ports: []agentv1alpha1.OVSPort{{Interfaces: []agentv1alpha1.OVSInterface{
+				{Name: "iface2", IPMap: map[types.IPAddress]v1.Time{ip: v1.Now()}},
+			}}},
+		}}},
+	}
+
+	if ips := r.getDeletedIP("agent1", "", ovsIface, agentInfo); ips.Len() != 0 {
+		t.Errorf("ips should not be deleted when learned in the other vrf, got %v", ips.List())
+	}
+	if ips := r.getDeletedIP("agent1", "tenant-a", ovsIface, agentInfo); !ips.Has(ip.String()) {
+		t.Errorf("ip %s should be deleted when learned in the same vrf, got %v", ip, ips.List())
+	}
+}
+
+func TestIPAddrIndexFunc(t *testing.T) {
+	keys, _ := ipAddrIndexFunc(&iface{vrf: "tenant-a", ipLastUpdateTimeMap: map[types.IPAddress]v1.Time{"10.0.0.1": v1.Now()}})
+	if !reflect.DeepEqual(keys, []string{"tenant-a/10.0.0.1"}) {
+		t.Errorf("unexpected ip index keys %v", keys)
+	}
+	keys, _ = ipAddrIndexFunc(&iface{ipLastUpdateTimeMap: map[types.IPAddress]v1.Time{"10.0.0.1": v1.Now()}})
+	if !reflect.DeepEqual(keys, []string{"10.0.0.1"}) {
+		t.Errorf("unexpected ip index keys %v", keys)
+	}
+}
+
+func TestFetchEndpointStatusVLAN(t *testing.T) {
+	r := newFakeReconciler()
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: v1.ObjectMeta{Name: "agent1"},
+		OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+			Name: "ovsbr1",
+			Ports: []agentv1alpha1.OVSPort{{
+				Name:       "tap1",
+				VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeAccess, Tag: 100},
+				Interfaces: []agentv1alpha1.OVSInterface{{
+					Name:        "tap1",
+					ExternalIDs: map[string]string{endpointExternalIDKey: "ep1"},
+					IPMap:       map[types.IPAddress]v1.Time{"10.0.0.1": v1.Now()},
+				}},
+			}},
+		}}},
+		Conditions: []agentv1alpha1.AgentCondition{{LastHeartbeatTime: v1.Now()}},
+	}
+	r.addAgentInfo(event.CreateEvent{Meta: agentInfo, Object: agentInfo}, queue)
+
+	endpoint := securityv1alpha1.Endpoint{Spec: securityv1alpha1.EndpointSpec{
+		Reference: securityv1alpha1.EndpointReference{ExternalIDName: endpointExternalIDKey, ExternalIDValue: "ep1"},
+	}}
+	status, err := r.fetchEndpointStatusFromAgentInfo(GetEndpointID(endpoint))
+	if err != nil {
+		t.Fatalf("unable fetch endpoint status: %s", err)
+	}
+	if status.VLAN != 100 {
+		t.Errorf("endpoint should in the access vlan 100 of the port, got %d", status.VLAN)
+	}
+}
+
+func TestFetchEndpointStatusLastSeenTime(t *testing.T) {
+	r := newFakeReconciler()
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	now := v1.NewTime(time.Now().Truncate(time.Second))
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: v1.ObjectMeta{Name: "agent1"},
+		OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+			Name: "ovsbr1",
+			Ports: []agentv1alpha1.OVSPort{{
+				Name: "tap1",
+				Interfaces: []agentv1alpha1.OVSInterface{{
+					Name:        "tap1",
+					ExternalIDs: map[string]string{endpointExternalIDKey: "ep1"},
+					IPMap: map[types.IPAddress]v1.Time{
+						"10.0.0.1": v1.NewTime(now.Add(-time.Hour)),
+						"10.0.0.2": now,
+					},
+				}},
+			}},
+		}}},
+		Conditions: []agentv1alpha1.AgentCondition{{LastHeartbeatTime: now}},
+	}
+	r.addAgentInfo(event.CreateEvent{Meta: agentInfo, Object: agentInfo}, queue)
+
+	endpoint := securityv1alpha1.Endpoint{Spec: securityv1alpha1.EndpointSpec{
+		Reference: securityv1alpha1.EndpointReference{ExternalIDName: endpointExternalIDKey, ExternalIDValue: "ep1"},
+	}}
+	status, err := r.fetchEndpointStatusFromAgentInfo(GetEndpointID(endpoint))
+	if err != nil {
+		t.Fatalf("unable fetch endpoint status: %s", err)
+	}
+	if status.LastSeenTime == nil || !status.LastSeenTime.Equal(&now) {
+		t.Errorf("endpoint should last seen at %s, got %v", now, status.LastSeenTime)
+	}
+
+	status = r.fetchEndpointStatusByIP("", []types.IPAddress{"10.0.0.1"})
+	if status.LastSeenTime == nil || !status.LastSeenTime.Time.Equal(now.Add(-time.Hour)) {
+		t.Errorf("static ip endpoint should last seen at %s, got %v", now.Add(-time.Hour), status.LastSeenTime)
+	}
+}
+
+func TestEqualLastSeenTime(t *testing.T) {
+	now := v1.Now()
+	refreshed := v1.NewTime(now.Add(lastSeenTimeResolution / 2))
+	expired := v1.NewTime(now.Add(lastSeenTimeResolution))
+
+	if !equalLastSeenTime(nil, nil) {
+		t.Errorf("unset last seen time should be equal")
+	}
+	if equalLastSeenTime(&now, nil) || equalLastSeenTime(nil, &now) {
+		t.Errorf("last seen time should not equal to unset")
+	}
+	if !equalLastSeenTime(&now, &refreshed) {
+		t.Errorf("last seen time refreshed within the resolution should be equal")
+	}
+	if equalLastSeenTime(&now, &expired) || equalLastSeenTime(&expired, &now) {
+		t.Errorf("last seen time refreshed out of the resolution should not be equal")
+	}
+}
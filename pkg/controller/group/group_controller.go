@@ -18,8 +18,13 @@ package group
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -29,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -46,11 +52,24 @@ import (
 	"github.com/everoute/everoute/pkg/utils"
 )
 
+const (
+	ReasonMembersChanged = "MembersChanged"
+)
+
 // GroupReconciler watch endpoints and endpointgroups resources, create, update
 // or delete groupmembers and groupmemberspatches according to group members changes.
 type GroupReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Recorder records the membership changes on the endpointgroups, no events if nil.
+	Recorder record.EventRecorder
+	// EnrichMembers populate the metadata of the endpoints in the group members.
+	EnrichMembers bool
+	// CompactPatches is the number of the patches accumulated before they are compacted into
+	// the groupmembers, so the large groups are not rewritten on each change and the agents
+	// receive the patches only. The groupmembers are rewritten on each change if zero. The
+	// groupmembers might lag behind the patches, read them by GetGroupMembers.
+	CompactPatches int32
 }
 
 // Reconcile receive endpointgroup from work queue, first it create groupmemberspatch,
@@ -158,6 +177,8 @@ func (r *GroupReconciler) updateEndpoint(e event.UpdateEvent, q workqueue.RateLi
 
 	if k8slabels.Equals(newEndpoint.Labels, oldEndpoint.Labels) &&
 		labels.Equals(newEndpoint.Spec.ExtendLabels, oldEndpoint.Spec.ExtendLabels) &&
+		newEndpoint.Spec.VRF == oldEndpoint.Spec.VRF &&
+		newEndpoint.GetVLAN() == oldEndpoint.GetVLAN() &&
 		utils.EqualIPs(newEndpoint.Status.IPs, oldEndpoint.Status.IPs) &&
 		utils.EqualStringSlice(newEndpoint.Status.Agents, oldEndpoint.Status.Agents) {
 		return
@@ -319,9 +340,18 @@ func (r *GroupReconciler) filterEndpointGroupsByEndpoint(ctx context.Context, en
 	endpointNamespaceLabels = endpointNamespace.Labels
 
 	for _, group := range groupList.Items {
+		// the group only selects the endpoints in its vrf
+		if group.Spec.VRF != endpoint.Spec.VRF {
+			continue
+		}
+		// the group with vlans only selects the endpoints in the vlans
+		if !inVLANs(group.Spec.VLANs, endpoint.GetVLAN()) {
+			continue
+		}
+
 		// Only SecurityPolicy's named port feature need all-endpoins group,
 		// so if endpoint doesn't define named port, it doesn't need to related to the group.
-		if group.Name == constants.AllEpWithNamedPort {
+		if isAllEpsGroup(group.Name, group.Spec.VRF) {
 			if namedPortExists {
 				groupNameSet.Insert(group.Name)
 			}
@@ -468,7 +498,7 @@ func (r *GroupReconciler) processEndpointGroupUpdate(ctx context.Context, group
 		return ctrl.Result{}, err
 	}
 
-	currGroupMembers, err := r.fetchCurrGroupMembers(ctx, &group)
+	currGroupMembers, memberEndpoints, err := r.fetchCurrGroupMembers(ctx, &group)
 	if err != nil {
 		klog.Errorf("while process endpointgroup %s update, can't fetch curr groupmembers: %s", group.Name, err)
 		return ctrl.Result{}, err
@@ -495,28 +525,50 @@ func (r *GroupReconciler) processEndpointGroupUpdate(ctx context.Context, group
 		return ctrl.Result{}, err
 	}
 
-	err = r.syncGroupMembers(ctx, group.Name, members)
+	compactedRevision, err := r.syncGroupMembers(ctx, group.Name, members)
 	if err != nil {
 		klog.Errorf("failed to sync groupmembers of revision %d for group %s: %s", members.Revision, group.Name, err)
 		return ctrl.Result{}, err
 	}
 
-	err = r.cleanupOldPatches(ctx, group.Name, members.Revision)
+	if r.Recorder != nil && len(patch.AddedGroupMembers)+len(patch.RemovedGroupMembers) != 0 {
+		r.Recorder.Eventf(&group, corev1.EventTypeNormal, ReasonMembersChanged, "%d members, %d added, %d removed",
+			len(members.GroupMembers), len(patch.AddedGroupMembers), len(patch.RemovedGroupMembers))
+	}
+
+	err = r.cleanupOldPatches(ctx, group.Name, compactedRevision)
 	if err != nil {
 		klog.Errorf("wile remove old patches of group %s: %s", group.Name, err)
 		return ctrl.Result{}, err
 	}
 
+	status := NewEndpointGroupStatus(members.Revision, memberEndpoints)
+	if !reflect.DeepEqual(group.Status, status) {
+		group.Status = status
+		if err = r.Status().Update(ctx, &group); err != nil {
+			klog.Errorf("failed to update endpointgroup %s status: %s", group.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
-// fetchCurrGroupMembers get endpoints by selector, and return as GroupMembers
-func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *groupv1alpha1.EndpointGroup) (*groupv1alpha1.GroupMembers, error) {
+// FetchGroupMembers returns the members of the group computed from the endpoints, the group
+// and its GroupMembers are not required to exist.
+func FetchGroupMembers(ctx context.Context, c client.Client, group *groupv1alpha1.EndpointGroup) (*groupv1alpha1.GroupMembers, error) {
+	members, _, err := (&GroupReconciler{Client: c}).fetchCurrGroupMembers(ctx, group)
+	return members, err
+}
+
+// fetchCurrGroupMembers get endpoints by selector, and return as GroupMembers, with the
+// namespaced names of the member endpoints.
+func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *groupv1alpha1.EndpointGroup) (*groupv1alpha1.GroupMembers, []securityv1alpha1.NamespacedName, error) {
 	var (
 		matchedNamespaces []string
 		matchedEndpoints  []securityv1alpha1.Endpoint
 	)
-	isAllEpsGroup := group.Name == constants.AllEpWithNamedPort
+	allEpsGroup := isAllEpsGroup(group.Name, group.Spec.VRF)
 
 	// filter matched namespace
 	if group.Spec.Namespace == nil && group.Spec.NamespaceSelector == nil {
@@ -533,13 +585,13 @@ func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *grou
 			// matching EndpointSelector in the Namespaces selected by NamespaceSelector.
 			namespaceSelector, err := metav1.LabelSelectorAsSelector(group.Spec.NamespaceSelector)
 			if err != nil {
-				return nil, fmt.Errorf("invalid namespace selector %+v: %s", group.Spec.NamespaceSelector, err)
+				return nil, nil, fmt.Errorf("invalid namespace selector %+v: %s", group.Spec.NamespaceSelector, err)
 			}
 
 			namespaceList := corev1.NamespaceList{}
 			err = r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: namespaceSelector})
 			if err != nil {
-				return nil, fmt.Errorf("list namespaces: %s", err)
+				return nil, nil, fmt.Errorf("list namespaces: %s", err)
 			}
 
 			for _, namespace := range namespaceList.Items {
@@ -553,9 +605,9 @@ func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *grou
 		err := r.Get(ctx, k8stypes.NamespacedName{Name: group.Spec.Endpoint.Name, Namespace: group.Spec.Endpoint.Namespace}, &endpoint)
 		// ignore non-existent endpoint
 		if err != nil && !apierrors.IsNotFound(err) {
-			return nil, fmt.Errorf("failed to get endpoint: %s, err: %s", group.Spec.Endpoint, err)
+			return nil, nil, fmt.Errorf("failed to get endpoint: %s, err: %s", group.Spec.Endpoint, err)
 		}
-		if err == nil {
+		if err == nil && endpoint.Spec.VRF == group.Spec.VRF && inVLANs(group.Spec.VLANs, endpoint.GetVLAN()) {
 			matchedEndpoints = append(matchedEndpoints, endpoint)
 		}
 	}
@@ -564,15 +616,18 @@ func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *grou
 		endpointList := securityv1alpha1.EndpointList{}
 		err := r.List(ctx, &endpointList, client.InNamespace(namespace))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// list API unsupport custom selector, so we need to filter endpoints here
 		for _, endpoint := range endpointList.Items {
+			if endpoint.Spec.VRF != group.Spec.VRF || !inVLANs(group.Spec.VLANs, endpoint.GetVLAN()) {
+				continue
+			}
 			labelSet, err := labels.AsSet(endpoint.Labels, endpoint.Spec.ExtendLabels)
 			if err != nil {
 				// this should never happen, the labels has been validated by webhook
-				return nil, fmt.Errorf("invalid enpoint selector %+v: %s", group.Spec.EndpointSelector, err)
+				return nil, nil, fmt.Errorf("invalid enpoint selector %+v: %s", group.Spec.EndpointSelector, err)
 			}
 			if group.Spec.EndpointSelector.Matches(labelSet) {
 				matchedEndpoints = append(matchedEndpoints, endpoint)
@@ -582,13 +637,14 @@ func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *grou
 
 	// conversion endpoint list to member list
 	memberList := make([]groupv1alpha1.GroupMember, 0, len(matchedEndpoints))
+	memberEndpoints := make([]securityv1alpha1.NamespacedName, 0, len(matchedEndpoints))
 	for _, ep := range matchedEndpoints {
 		if len(ep.Status.IPs) == 0 {
 			// skip ep with empty ip addresses
 			continue
 		}
 
-		if isAllEpsGroup && len(ep.Spec.Ports) == 0 {
+		if allEpsGroup && len(ep.Spec.Ports) == 0 {
 			// for AllEndpointsGroup skip endpoint has no named port
 			continue
 		}
@@ -602,10 +658,35 @@ func (r *GroupReconciler) fetchCurrGroupMembers(ctx context.Context, group *grou
 			IPs:           ep.Status.IPs,
 			Ports:         ep.Spec.Ports,
 		}
+		if r.EnrichMembers {
+			member.Metadata = NewEndpointMetadata(&ep)
+		}
 		memberList = append(memberList, member)
+		memberEndpoints = append(memberEndpoints, securityv1alpha1.NamespacedName{Namespace: ep.Namespace, Name: ep.Name})
 	}
 
-	return &groupv1alpha1.GroupMembers{GroupMembers: memberList}, nil
+	return &groupv1alpha1.GroupMembers{GroupMembers: memberList}, memberEndpoints, nil
+}
+
+// isAllEpsGroup returns whether the group is the AllEpWithNamedPort group of the vrf.
+func isAllEpsGroup(name, vrf string) bool {
+	if vrf == "" {
+		return name == constants.AllEpWithNamedPort
+	}
+	return name == constants.AllEpWithNamedPort+"-"+vrf
+}
+
+// inVLANs returns true if the vlan is one of the vlans, or the vlans is empty.
+func inVLANs(vlans []int32, vlan int32) bool {
+	if len(vlans) == 0 {
+		return true
+	}
+	for _, item := range vlans {
+		if item == vlan {
+			return true
+		}
+	}
+	return false
 }
 
 // fetchPrevGroupMembers read groupmembers and groupmemberspatches, calculate
@@ -618,18 +699,84 @@ func (r *GroupReconciler) fetchPrevGroupMembers(ctx context.Context, group *grou
 		return nil, err
 	}
 
+	if err = applyPendingPatches(ctx, r.Client, group.Name, &groupMembers); err != nil {
+		return nil, err
+	}
+	return &groupMembers, nil
+}
+
+// GetGroupMembers returns the groupmembers of the group in the latest revision, with the
+// patches not compacted into the groupmembers yet applied.
+func GetGroupMembers(ctx context.Context, reader client.Reader, name string) (*groupv1alpha1.GroupMembers, error) {
+	groupMembers := groupv1alpha1.GroupMembers{}
+	if err := reader.Get(ctx, k8stypes.NamespacedName{Name: name}, &groupMembers); err != nil {
+		return nil, err
+	}
+	if err := applyPendingPatches(ctx, reader, name, &groupMembers); err != nil {
+		return nil, err
+	}
+	return &groupMembers, nil
+}
+
+// ListGroupMembers returns the groupmembers in the latest revision, like GetGroupMembers.
+func ListGroupMembers(ctx context.Context, reader client.Reader, opts ...client.ListOption) ([]groupv1alpha1.GroupMembers, error) {
+	groupMembersList := groupv1alpha1.GroupMembersList{}
+	if err := reader.List(ctx, &groupMembersList, opts...); err != nil {
+		return nil, err
+	}
+	if len(groupMembersList.Items) == 0 {
+		return nil, nil
+	}
 	patchList := groupv1alpha1.GroupMembersPatchList{}
-	err = r.List(ctx, &patchList, client.MatchingLabels{constants.OwnerGroupLabelKey: group.Name})
-	if err != nil {
+	if err := reader.List(ctx, &patchList); err != nil {
 		return nil, err
 	}
 
-	ApplyGroupMembersPatches(&groupMembers, patchList.Items)
+	patches := make(map[string][]groupv1alpha1.GroupMembersPatch)
+	for _, patch := range patchList.Items {
+		name := patch.AppliedToGroupMembers.Name
+		patches[name] = append(patches[name], patch)
+	}
+	for item := range groupMembersList.Items {
+		ApplyGroupMembersPatches(&groupMembersList.Items[item], patches[groupMembersList.Items[item].Name])
+	}
+	return groupMembersList.Items, nil
+}
 
-	return &groupMembers, nil
+// PatchedGroups returns the names of the groups with the members matched added or updated
+// in the patches, the groups contain the members now might not be found in the groupmembers
+// by the indexes when the patches have not been compacted.
+func PatchedGroups(ctx context.Context, reader client.Reader, match func(member *groupv1alpha1.GroupMember) bool) (sets.String, error) {
+	patchList := groupv1alpha1.GroupMembersPatchList{}
+	if err := reader.List(ctx, &patchList); err != nil {
+		return nil, err
+	}
+	groups := sets.NewString()
+	for _, patch := range patchList.Items {
+		for _, members := range [][]groupv1alpha1.GroupMember{patch.AddedGroupMembers, patch.UpdatedGroupMembers} {
+			for item := range members {
+				if match(&members[item]) {
+					groups.Insert(patch.AppliedToGroupMembers.Name)
+				}
+			}
+		}
+	}
+	return groups, nil
+}
+
+func applyPendingPatches(ctx context.Context, reader client.Reader, name string, groupMembers *groupv1alpha1.GroupMembers) error {
+	patchList := groupv1alpha1.GroupMembersPatchList{}
+	if err := reader.List(ctx, &patchList, client.MatchingLabels{constants.OwnerGroupLabelKey: name}); err != nil {
+		return err
+	}
+	ApplyGroupMembersPatches(groupMembers, patchList.Items)
+	return nil
 }
 
-func (r *GroupReconciler) syncGroupMembers(ctx context.Context, groupName string, members groupv1alpha1.GroupMembers) error {
+// syncGroupMembers updates the groupmembers to the revision of the members, unless the patches
+// accumulated since the last update are fewer than CompactPatches. It returns the revision of
+// the groupmembers stored.
+func (r *GroupReconciler) syncGroupMembers(ctx context.Context, groupName string, members groupv1alpha1.GroupMembers) (int32, error) {
 	groupMembers := groupv1alpha1.GroupMembers{}
 	err := r.Get(ctx, k8stypes.NamespacedName{Name: groupName}, &groupMembers)
 	if err != nil && apierrors.IsNotFound(err) {
@@ -640,26 +787,30 @@ func (r *GroupReconciler) syncGroupMembers(ctx context.Context, groupName string
 			Labels:    map[string]string{constants.OwnerGroupLabelKey: groupName},
 		}
 		if err = r.Create(ctx, &groupMembers); err != nil {
-			return fmt.Errorf("create groupmembers %s: %s", groupName, err)
+			return 0, fmt.Errorf("create groupmembers %s: %s", groupName, err)
 		}
 	}
 	if err != nil {
-		return fmt.Errorf("fetch groupmembers %s: %s", groupName, err)
+		return 0, fmt.Errorf("fetch groupmembers %s: %s", groupName, err)
 	}
 
 	if groupMembers.Revision >= members.Revision {
 		// GroupMembers has already a high revision, ignore
-		return nil
+		return groupMembers.Revision, nil
+	}
+	if members.Revision-groupMembers.Revision < r.CompactPatches {
+		// the agents apply the patches, compact them when accumulated
+		return groupMembers.Revision, nil
 	}
 
 	groupMembers.GroupMembers = members.GroupMembers
 	groupMembers.Revision = members.Revision
 	if err := r.Update(ctx, &groupMembers); err != nil {
-		return fmt.Errorf("fetch groupmembers %s: %s", groupName, err)
+		return 0, fmt.Errorf("fetch groupmembers %s: %s", groupName, err)
 	}
 	klog.Infof("updated groupmembers %s to revision %d, numbers of members %d", groupMembers.Name, groupMembers.Revision, len(groupMembers.GroupMembers))
 
-	return nil
+	return groupMembers.Revision, nil
 }
 
 func (r *GroupReconciler) syncGroupMembersPatch(ctx context.Context, groupName string, patch groupv1alpha1.GroupMembersPatch) error {
@@ -681,7 +832,8 @@ func (r *GroupReconciler) syncGroupMembersPatch(ctx context.Context, groupName s
 }
 
 // cleanupOldPatches remove pathes which revision under <revision> for group <groupName>, but we will always
-// retained the nearest three groupMembersPatches for debug.
+// retained the nearest three groupMembersPatches for debug. The revision is the revision of the groupmembers
+// stored, the patches not compacted into it are required by the agents.
 func (r *GroupReconciler) cleanupOldPatches(ctx context.Context, groupName string, revision int32) error {
 	patchList := groupv1alpha1.GroupMembersPatchList{}
 	if err := r.List(ctx, &patchList, client.MatchingLabels{constants.OwnerGroupLabelKey: groupName}); err != nil {
@@ -730,7 +882,8 @@ func ToGroupMembersPatch(prev *groupv1alpha1.GroupMembers, curr *groupv1alpha1.G
 			patch.AddedGroupMembers = append(patch.AddedGroupMembers, member)
 		} else {
 			if !utils.EqualIPs(prevEp.IPs, member.IPs) ||
-				!utils.EqualStringSlice(prevEp.EndpointAgent, member.EndpointAgent) {
+				!utils.EqualStringSlice(prevEp.EndpointAgent, member.EndpointAgent) ||
+				!reflect.DeepEqual(prevEp.Metadata, member.Metadata) {
 				// If member changes, it's an update member.
 				patch.UpdatedGroupMembers = append(patch.UpdatedGroupMembers, member)
 			}
@@ -746,6 +899,68 @@ func ToGroupMembersPatch(prev *groupv1alpha1.GroupMembers, curr *groupv1alpha1.G
 	return patch
 }
 
+// NewEndpointMetadata return the metadata of the endpoint as a group member.
+func NewEndpointMetadata(ep *securityv1alpha1.Endpoint) *groupv1alpha1.EndpointMetadata {
+	metadata := &groupv1alpha1.EndpointMetadata{
+		Namespace:  ep.Namespace,
+		Name:       ep.Name,
+		LabelsHash: labelsHash(ep.Labels, ep.Spec.ExtendLabels),
+	}
+	if len(ep.Status.Agents) == 1 {
+		metadata.Node = ep.Status.Agents[0]
+	}
+
+	switch {
+	case ep.Spec.Reference.ExternalIDName == constants.PodEndpointExternalIDName:
+		metadata.Type = groupv1alpha1.EndpointTypePod
+	case len(ep.Status.Agents) == 0:
+		// the endpoint not located on any agent, e.g. the physical servers
+		metadata.Type = groupv1alpha1.EndpointTypeExternal
+	default:
+		metadata.Type = groupv1alpha1.EndpointTypeVM
+	}
+	return metadata
+}
+
+// NewEndpointGroupStatus return the status of the group with the member endpoints, the hash
+// covers all the members while at most MaxReportedMembers of them listed.
+func NewEndpointGroupStatus(revision int32, memberEndpoints []securityv1alpha1.NamespacedName) groupv1alpha1.EndpointGroupStatus {
+	members := append([]securityv1alpha1.NamespacedName(nil), memberEndpoints...)
+	sort.Slice(members, func(i, j int) bool { return members[i].String() < members[j].String() })
+
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.String())
+	}
+	hash := sha256.Sum256([]byte(strings.Join(names, "\n")))
+
+	if len(members) > groupv1alpha1.MaxReportedMembers {
+		members = members[:groupv1alpha1.MaxReportedMembers]
+	}
+	return groupv1alpha1.EndpointGroupStatus{
+		Revision:    revision,
+		MemberCount: int32(len(names)),
+		MembersHash: hex.EncodeToString(hash[:]),
+		Members:     members,
+	}
+}
+
+// labelsHash return the hash of the labels, the extend label values are sorted
+// so the hash is stable.
+func labelsHash(labels map[string]string, extendLabels map[string][]string) string {
+	allLabels := make(map[string][]string, len(labels)+len(extendLabels))
+	for key, value := range labels {
+		allLabels[key] = []string{value}
+	}
+	for key, values := range extendLabels {
+		allLabels[key] = sets.NewString(values...).List()
+	}
+	// encoding/json sort the map keys
+	data, _ := json.Marshal(allLabels)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:8])
+}
+
 // IsEmptyPatch return true if and only if the patch is empty.
 func IsEmptyPatch(patch groupv1alpha1.GroupMembersPatch) bool {
 	return len(patch.RemovedGroupMembers) == 0 &&
@@ -28,11 +28,15 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
 	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
 	"github.com/everoute/everoute/pkg/labels"
 	"github.com/everoute/everoute/pkg/types"
 )
@@ -102,6 +106,13 @@ var _ = Describe("GroupController", func() {
 				It("should update groupmembers contains the endpoint", func() {
 					assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{endpointToGroupMember(ep)}})
 				})
+				It("should update the membership in the endpointgroup status", func() {
+					Eventually(func() []securityv1alpha1.NamespacedName {
+						group := groupv1alpha1.EndpointGroup{}
+						Expect(k8sClient.Get(ctx, client.ObjectKey{Name: epGroup.Name}, &group)).Should(Succeed())
+						return group.Status.Members
+					}, timeout, interval).Should(Equal([]securityv1alpha1.NamespacedName{{Namespace: namespace, Name: ep.Name}}))
+				})
 			})
 		})
 		Context("an endpoint in the group", func() {
@@ -335,6 +346,46 @@ var _ = Describe("GroupController", func() {
 				})
 			})
 		})
+
+		When("create namespace unmatch group.spec and endpoint in it", func() {
+			var ep *securityv1alpha1.Endpoint
+			var namespace *corev1.Namespace
+
+			BeforeEach(func() {
+				namespace = newTestNamespace(nil)
+				ep = newTestEndpoint(namespace.GetName(), "192.168.1.1", "agent1", endpointLabel, nil)
+
+				By(fmt.Sprintf("create namespace %s", namespace))
+				Expect(k8sClient.Create(ctx, namespace)).Should(Succeed())
+
+				By(fmt.Sprintf("create endpoint %s in namespace %s with labels %v", ep.GetName(), ep.GetNamespace(), ep.GetLabels()))
+				Expect(k8sClient.Create(ctx, ep)).Should(Succeed())
+				Expect(k8sClient.Status().Update(ctx, ep)).Should(Succeed())
+			})
+			AfterEach(func() {
+				By(fmt.Sprintf("remove test namespace %s and endpoint %s", namespace.GetName(), ep.GetName()))
+				Expect(k8sClient.Delete(ctx, namespace)).Should(Succeed())
+				Expect(k8sClient.Delete(ctx, ep)).Should(Succeed())
+			})
+
+			It("should update groupmembers contains no endpoints", func() {
+				assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{}})
+			})
+
+			When("update namespace labels match group.spec", func() {
+				BeforeEach(func() {
+					updateNamespace := namespace.DeepCopy()
+					updateNamespace.Labels = namespaceLabel
+
+					By(fmt.Sprintf("update namespace %s labels to %v", namespace.GetName(), namespaceLabel))
+					Expect(k8sClient.Patch(ctx, updateNamespace, client.MergeFrom(namespace))).Should(Succeed())
+				})
+
+				It("should update groupmembers contains the endpoint", func() {
+					assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{endpointToGroupMember(ep)}})
+				})
+			})
+		})
 	})
 
 	When("create EndpointGroup with specific namespace", func() {
@@ -483,9 +534,197 @@ var _ = Describe("GroupController", func() {
 			})
 		})
 	})
+
+	When("create EndpointGroup in the vrf", func() {
+		var epGroup *groupv1alpha1.EndpointGroup
+		var endpointLabel map[string]string
+
+		BeforeEach(func() {
+			endpointLabel = map[string]string{"foo": "bar"}
+			epGroup = newTestEndpointGroup(endpointLabel, nil, nil, "")
+			epGroup.Spec.VRF = "tenant-a"
+
+			By(fmt.Sprintf("create endpointgroup %s with spec %v", epGroup.Name, epGroup.Spec))
+			Expect(k8sClient.Create(ctx, epGroup)).Should(Succeed())
+		})
+
+		When("create endpoints with the same ip in the vrf and the default vrf", func() {
+			var epInVRF, epDefault *securityv1alpha1.Endpoint
+
+			BeforeEach(func() {
+				epInVRF = newTestEndpoint(metav1.NamespaceDefault, "192.168.1.1", "agent1", endpointLabel, nil)
+				epInVRF.Spec.VRF = "tenant-a"
+				epDefault = newTestEndpoint(metav1.NamespaceDefault, "192.168.1.1", "agent1", endpointLabel, nil)
+
+				for _, ep := range []*securityv1alpha1.Endpoint{epInVRF, epDefault} {
+					By(fmt.Sprintf("create endpoint %s in vrf %q", ep.GetName(), ep.Spec.VRF))
+					Expect(k8sClient.Create(ctx, ep)).Should(Succeed())
+					Expect(k8sClient.Status().Update(ctx, ep)).Should(Succeed())
+				}
+			})
+
+			It("should update groupmembers contains the endpoint in the vrf only", func() {
+				assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{endpointToGroupMember(epInVRF)}})
+			})
+
+			When("move the endpoint out of the vrf", func() {
+				BeforeEach(func() {
+					assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{endpointToGroupMember(epInVRF)}})
+
+					updateEndpoint := epInVRF.DeepCopy()
+					updateEndpoint.Spec.VRF = ""
+
+					By(fmt.Sprintf("update endpoint %s vrf to the default vrf", epInVRF.GetName()))
+					Expect(k8sClient.Patch(ctx, updateEndpoint, client.MergeFrom(epInVRF))).Should(Succeed())
+				})
+
+				It("should update groupmembers contains no endpoints", func() {
+					assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{}})
+				})
+			})
+		})
+	})
+
+	When("create EndpointGroup in the vlans", func() {
+		var epGroup *groupv1alpha1.EndpointGroup
+		var endpointLabel map[string]string
+
+		BeforeEach(func() {
+			endpointLabel = map[string]string{"foo": "bar"}
+			epGroup = newTestEndpointGroup(endpointLabel, nil, nil, "")
+			epGroup.Spec.VLANs = []int32{100}
+
+			By(fmt.Sprintf("create endpointgroup %s with spec %v", epGroup.Name, epGroup.Spec))
+			Expect(k8sClient.Create(ctx, epGroup)).Should(Succeed())
+		})
+
+		When("create endpoints in the vlan and out of the vlan", func() {
+			var epInVLAN, epOutVLAN *securityv1alpha1.Endpoint
+
+			BeforeEach(func() {
+				epInVLAN = newTestEndpoint(metav1.NamespaceDefault, "192.168.1.1", "agent1", endpointLabel, nil)
+				epInVLAN.Spec.VID = 100
+				// the vlan reported in the status takes precedence over the vid
+				epOutVLAN = newTestEndpoint(metav1.NamespaceDefault, "192.168.1.2", "agent1", endpointLabel, nil)
+				epOutVLAN.Spec.VID = 100
+				epOutVLAN.Status.VLAN = 200
+
+				for _, ep := range []*securityv1alpha1.Endpoint{epInVLAN, epOutVLAN} {
+					By(fmt.Sprintf("create endpoint %s in vlan %d", ep.GetName(), ep.GetVLAN()))
+					status := ep.Status.DeepCopy()
+					Expect(k8sClient.Create(ctx, ep)).Should(Succeed())
+					ep.Status = *status
+					Expect(k8sClient.Status().Update(ctx, ep)).Should(Succeed())
+				}
+			})
+
+			It("should update groupmembers contains the endpoints in the vlan only", func() {
+				assertHasGroupMembers(epGroup, groupv1alpha1.GroupMembers{GroupMembers: []groupv1alpha1.GroupMember{endpointToGroupMember(epInVLAN)}})
+			})
+		})
+	})
 })
 
 // endpointToGroupMember conversion endpoint to GroupMember.
+var _ = Describe("NewEndpointMetadata", func() {
+	It("should set endpoint type and node", func() {
+		ep := newTestEndpoint("ns", "10.0.0.1", "agent-a", map[string]string{"app": "web"}, nil)
+		metadata := groupctrl.NewEndpointMetadata(ep)
+		Expect(metadata.Namespace).Should(Equal("ns"))
+		Expect(metadata.Name).Should(Equal(ep.Name))
+		Expect(metadata.Node).Should(Equal("agent-a"))
+		Expect(metadata.Type).Should(Equal(groupv1alpha1.EndpointTypeVM))
+
+		ep.Spec.Reference.ExternalIDName = constants.PodEndpointExternalIDName
+		Expect(groupctrl.NewEndpointMetadata(ep).Type).Should(Equal(groupv1alpha1.EndpointTypePod))
+
+		ep.Spec.Reference.ExternalIDName = "iface-id"
+		ep.Status.Agents = nil
+		metadata = groupctrl.NewEndpointMetadata(ep)
+		Expect(metadata.Node).Should(BeEmpty())
+		Expect(metadata.Type).Should(Equal(groupv1alpha1.EndpointTypeExternal))
+	})
+
+	It("should hash labels regardless of the extend label values order", func() {
+		ep1 := newTestEndpoint("ns", "10.0.0.1", "agent-a", map[string]string{"app": "web"}, map[string][]string{"env": {"a", "b"}})
+		ep2 := newTestEndpoint("ns", "10.0.0.2", "agent-b", map[string]string{"app": "web"}, map[string][]string{"env": {"b", "a"}})
+		ep3 := newTestEndpoint("ns", "10.0.0.3", "agent-a", map[string]string{"app": "db"}, map[string][]string{"env": {"a", "b"}})
+
+		Expect(groupctrl.NewEndpointMetadata(ep1).LabelsHash).Should(Equal(groupctrl.NewEndpointMetadata(ep2).LabelsHash))
+		Expect(groupctrl.NewEndpointMetadata(ep1).LabelsHash).ShouldNot(Equal(groupctrl.NewEndpointMetadata(ep3).LabelsHash))
+	})
+})
+
+var _ = Describe("NewEndpointGroupStatus", func() {
+	It("should count and hash all the members", func() {
+		var members []securityv1alpha1.NamespacedName
+		for i := groupv1alpha1.MaxReportedMembers; i >= 0; i-- {
+			members = append(members, securityv1alpha1.NamespacedName{Namespace: "ns", Name: fmt.Sprintf("ep%03d", i)})
+		}
+		status := groupctrl.NewEndpointGroupStatus(3, members)
+		Expect(status.Revision).Should(Equal(int32(3)))
+		Expect(status.MemberCount).Should(Equal(int32(groupv1alpha1.MaxReportedMembers + 1)))
+		Expect(status.Members).Should(HaveLen(groupv1alpha1.MaxReportedMembers))
+		Expect(status.Members[0].Name).Should(Equal("ep000"))
+
+		// the hash is independent of the members order
+		reversed := make([]securityv1alpha1.NamespacedName, 0, len(members))
+		for i := len(members) - 1; i >= 0; i-- {
+			reversed = append(reversed, members[i])
+		}
+		Expect(groupctrl.NewEndpointGroupStatus(3, reversed).MembersHash).Should(Equal(status.MembersHash))
+		Expect(groupctrl.NewEndpointGroupStatus(3, members[1:]).MembersHash).ShouldNot(Equal(status.MembersHash))
+	})
+})
+
+var _ = Describe("CompactPatches", func() {
+	It("should compact the patches into the groupmembers when accumulated", func() {
+		ctx := context.Background()
+		epGroup := newTestEndpointGroup(map[string]string{"label.key": "label.value"}, nil, nil, "")
+		epGroup.Finalizers = []string{constants.DependentsCleanFinalizer}
+		c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, epGroup)
+		r := &groupctrl.GroupReconciler{Client: c, CompactPatches: 3}
+
+		var members []groupv1alpha1.GroupMember
+		addMember := func(ip string) {
+			ep := newTestEndpoint(metav1.NamespaceDefault, ip, "agent1", map[string]string{"label.key": "label.value"}, nil)
+			Expect(c.Create(ctx, ep)).Should(Succeed())
+			members = append(members, endpointToGroupMember(ep))
+			_, err := r.Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: epGroup.Name}})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		compactedRevision := func() int32 {
+			groupMembers := groupv1alpha1.GroupMembers{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: epGroup.Name}, &groupMembers)).Should(Succeed())
+			return groupMembers.Revision
+		}
+
+		for index, ip := range []string{"192.168.1.1", "192.168.1.2"} {
+			addMember(ip)
+			Expect(compactedRevision()).Should(BeZero())
+
+			groupMembers, err := groupctrl.GetGroupMembers(ctx, c, epGroup.Name)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(groupMembers.Revision).Should(Equal(int32(index + 1)))
+			Expect(groupMembers.GroupMembers).Should(ConsistOf(members))
+
+			groups, err := groupctrl.PatchedGroups(ctx, c, func(member *groupv1alpha1.GroupMember) bool {
+				return member.IPs[0] == types.IPAddress(ip)
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(groups.List()).Should(ConsistOf(epGroup.Name))
+		}
+
+		addMember("192.168.1.3")
+		Expect(compactedRevision()).Should(Equal(int32(3)))
+		groupMembersList, err := groupctrl.ListGroupMembers(ctx, c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(groupMembersList).Should(HaveLen(1))
+		Expect(groupMembersList[0].Revision).Should(Equal(int32(3)))
+		Expect(groupMembersList[0].GroupMembers).Should(ConsistOf(members))
+	})
+})
+
 func endpointToGroupMember(ep *securityv1alpha1.Endpoint) groupv1alpha1.GroupMember {
 	return groupv1alpha1.GroupMember{
 		EndpointReference: groupv1alpha1.EndpointReference{
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iphistory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// Reconciler watch endpoints, records the ips learned and unlearned by the endpoints, and
+// the endpoints migrated, into the store.
+type Reconciler struct {
+	client.Client
+	Store *Store
+
+	// associations are the ips and agents of the endpoints last recorded, loaded from the
+	// store on the first reconcile
+	associations map[securityv1alpha1.NamespacedName]association
+}
+
+type association struct {
+	ips    sets.String
+	agents []string
+}
+
+// Reconcile receive endpoint from work queue, records the changes of its ips and agents.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("IPHistoryReconciler received endpoint %s reconcile", req.NamespacedName)
+
+	if r.associations == nil {
+		if err := r.load(ctx); err != nil {
+			klog.Errorf("unable to load ip history: %s", err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	endpoint := securityv1alpha1.Endpoint{}
+	err := r.Get(ctx, req.NamespacedName, &endpoint)
+	if client.IgnoreNotFound(err) != nil {
+		klog.Errorf("unable to fetch endpoint %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	name := securityv1alpha1.NamespacedName{Namespace: req.Namespace, Name: req.Name}
+	curr := association{ips: sets.NewString()}
+	if err == nil {
+		curr = endpointAssociation(&endpoint)
+	}
+	if err = r.record(name, curr); err != nil {
+		klog.Errorf("unable to record endpoint %s ip history: %s", name, err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add IPHistory Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	if r.Store == nil {
+		return fmt.Errorf("can't setup without store")
+	}
+
+	c, err := controller.New("iphistory-controller", mgr, controller.Options{
+		// the associations are not protected, reconcile the endpoints one by one
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &securityv1alpha1.Endpoint{}}, &handler.EnqueueRequestForObject{})
+}
+
+// load replays the store for the associations last recorded, and records the endpoints
+// deleted while the controller down.
+func (r *Reconciler) load(ctx context.Context) error {
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := r.List(ctx, &endpointList); err != nil {
+		return err
+	}
+
+	associations := make(map[securityv1alpha1.NamespacedName]association)
+	err := r.Store.Scan(func(record viewsv1alpha1.IPHistoryRecord) {
+		item, ok := associations[record.Endpoint]
+		if !ok {
+			item = association{ips: sets.NewString()}
+		}
+		switch record.Event {
+		case viewsv1alpha1.IPHistoryLearned:
+			item.ips.Insert(record.IP)
+			item.agents = record.Agents
+		case viewsv1alpha1.IPHistoryMigrated:
+			item.agents = record.Agents
+		case viewsv1alpha1.IPHistoryUnlearned:
+			item.ips.Delete(record.IP)
+		}
+		associations[record.Endpoint] = item
+	})
+	if err != nil {
+		return err
+	}
+	for name, item := range associations {
+		if item.ips.Len() == 0 {
+			delete(associations, name)
+		}
+	}
+	r.associations = associations
+
+	existing := sets.NewString()
+	for _, endpoint := range endpointList.Items {
+		existing.Insert(securityv1alpha1.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}.String())
+	}
+	for name := range associations {
+		if !existing.Has(name.String()) {
+			if err = r.record(name, association{ips: sets.NewString()}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// record appends the changes from the association last recorded to the current one.
+func (r *Reconciler) record(name securityv1alpha1.NamespacedName, curr association) error {
+	prev, ok := r.associations[name]
+	if !ok {
+		prev = association{ips: sets.NewString()}
+	}
+
+	now := metav1.Now()
+	newRecord := func(ip string, event viewsv1alpha1.IPHistoryEvent, agents []string) viewsv1alpha1.IPHistoryRecord {
+		return viewsv1alpha1.IPHistoryRecord{Time: now, IP: ip, Event: event, Endpoint: name, Agents: agents}
+	}
+
+	var records []viewsv1alpha1.IPHistoryRecord
+	for _, ip := range prev.ips.Difference(curr.ips).List() {
+		records = append(records, newRecord(ip, viewsv1alpha1.IPHistoryUnlearned, prev.agents))
+	}
+	if !utils.EqualStringSlice(prev.agents, curr.agents) {
+		for _, ip := range prev.ips.Intersection(curr.ips).List() {
+			records = append(records, newRecord(ip, viewsv1alpha1.IPHistoryMigrated, curr.agents))
+		}
+	}
+	for _, ip := range curr.ips.Difference(prev.ips).List() {
+		records = append(records, newRecord(ip, viewsv1alpha1.IPHistoryLearned, curr.agents))
+	}
+
+	if err := r.Store.Append(records...); err != nil {
+		return err
+	}
+	if curr.ips.Len() == 0 {
+		delete(r.associations, name)
+	} else {
+		r.associations[name] = curr
+	}
+	return nil
+}
+
+func endpointAssociation(endpoint *securityv1alpha1.Endpoint) association {
+	item := association{ips: sets.NewString()}
+	for _, ip := range endpoint.Status.IPs {
+		item.ips.Insert(string(ip))
+	}
+	if len(endpoint.Status.Agents) != 0 {
+		item.agents = append([]string(nil), endpoint.Status.Agents...)
+		sort.Strings(item.agents)
+	}
+	return item
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iphistory
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+var endpointRequest = ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "ep01"}}
+
+func newEndpoint(agent string, ips ...types.IPAddress) *securityv1alpha1.Endpoint {
+	return &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ep01"},
+		Status:     securityv1alpha1.EndpointStatus{IPs: ips, Agents: []string{agent}},
+	}
+}
+
+// events returns the events of the records as "ip/event/agents".
+func events(records []viewsv1alpha1.IPHistoryRecord) []string {
+	var events []string
+	for _, record := range records {
+		item := record.IP + "/" + string(record.Event)
+		for _, agent := range record.Agents {
+			item += "/" + agent
+		}
+		events = append(events, item)
+	}
+	return events
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	store := &Store{Path: t.TempDir() + "/history.log"}
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, newEndpoint("node01", "10.0.0.1", "10.0.0.2"))
+	r := &Reconciler{Client: c, Store: store}
+
+	reconcile := func() []string {
+		_, err := r.Reconcile(endpointRequest)
+		Expect(err).ShouldNot(HaveOccurred())
+		records, err := store.Query("", time.Time{})
+		Expect(err).ShouldNot(HaveOccurred())
+		return events(records)
+	}
+	update := func(agent string, ips ...types.IPAddress) {
+		endpoint := &securityv1alpha1.Endpoint{}
+		Expect(c.Get(ctx, endpointRequest.NamespacedName, endpoint)).Should(Succeed())
+		endpoint.Status = newEndpoint(agent, ips...).Status
+		Expect(c.Update(ctx, endpoint)).Should(Succeed())
+	}
+
+	Expect(reconcile()).Should(Equal([]string{"10.0.0.1/Learned/node01", "10.0.0.2/Learned/node01"}))
+	// nothing recorded when unchanged
+	Expect(reconcile()).Should(HaveLen(2))
+
+	update("node02", "10.0.0.2", "10.0.0.3")
+	Expect(reconcile()[2:]).Should(Equal([]string{
+		"10.0.0.1/Unlearned/node01",
+		"10.0.0.2/Migrated/node02",
+		"10.0.0.3/Learned/node02",
+	}))
+
+	// the endpoint deleted while the controller down is recorded on the next start
+	Expect(c.Delete(ctx, newEndpoint("node02"))).Should(Succeed())
+	r = &Reconciler{Client: c, Store: store}
+	Expect(reconcile()[5:]).Should(Equal([]string{"10.0.0.2/Unlearned/node02", "10.0.0.3/Unlearned/node02"}))
+	Expect(r.associations).Should(BeEmpty())
+}
+
+func TestStore(t *testing.T) {
+	RegisterTestingT(t)
+
+	store := &Store{Path: t.TempDir() + "/history.log", MaxSize: 1}
+	lastDay := metav1.NewTime(time.Now().Add(-24 * time.Hour))
+	now := metav1.Now()
+
+	Expect(store.Append(viewsv1alpha1.IPHistoryRecord{Time: lastDay, IP: "10.0.0.1", Event: viewsv1alpha1.IPHistoryLearned})).Should(Succeed())
+	Expect(store.Append(viewsv1alpha1.IPHistoryRecord{Time: now, IP: "10.0.0.2", Event: viewsv1alpha1.IPHistoryLearned})).Should(Succeed())
+
+	records, err := store.Query("", time.Time{})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(events(records)).Should(Equal([]string{"10.0.0.1/Learned", "10.0.0.2/Learned"}))
+
+	records, err = store.Query("10.0.0.1", time.Time{})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(events(records)).Should(Equal([]string{"10.0.0.1/Learned"}))
+
+	records, err = store.Query("", now.Add(-time.Hour))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(events(records)).Should(Equal([]string{"10.0.0.2/Learned"}))
+
+	// the records before the last rotation dropped, the corrupted lines skipped
+	Expect(store.Append(viewsv1alpha1.IPHistoryRecord{Time: now, IP: "10.0.0.3", Event: viewsv1alpha1.IPHistoryLearned})).Should(Succeed())
+	file, err := os.OpenFile(store.Path, os.O_APPEND|os.O_WRONLY, 0644)
+	Expect(err).ShouldNot(HaveOccurred())
+	_, err = file.WriteString("{\"time\":")
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(file.Close()).Should(Succeed())
+
+	records, err = store.Query("", time.Time{})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(events(records)).Should(Equal([]string{"10.0.0.2/Learned", "10.0.0.3/Learned"}))
+}
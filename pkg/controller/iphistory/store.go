@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iphistory
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+)
+
+const (
+	DefaultPath    = "/var/lib/everoute/iphistory/history.log"
+	DefaultMaxSize = 100 << 20
+)
+
+// Store persists the ip history records as json lines in the file. The file is rotated to
+// Path.1 when it exceeds MaxSize, the records in the former Path.1 are dropped, so at most
+// twice of MaxSize kept on the disk.
+type Store struct {
+	Path string
+	// MaxSize is the max bytes of the file before rotated, DefaultMaxSize if zero.
+	MaxSize int64
+
+	lock sync.Mutex
+}
+
+// Append writes the records to the end of the file.
+func (s *Store) Append(records ...viewsv1alpha1.IPHistoryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	if err := s.rotate(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for item := range records {
+		if err := encoder.Encode(&records[item]); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// Query returns the records of the ip since the time, or the records of all the ips if ip
+// is empty, sorted by the time they appended.
+func (s *Store) Query(ip string, since time.Time) ([]viewsv1alpha1.IPHistoryRecord, error) {
+	var records []viewsv1alpha1.IPHistoryRecord
+	err := s.Scan(func(record viewsv1alpha1.IPHistoryRecord) {
+		if (ip == "" || record.IP == ip) && !record.Time.Time.Before(since) {
+			records = append(records, record)
+		}
+	})
+	return records, err
+}
+
+// Scan calls fn on each record in the order they appended, the corrupted lines, e.g. the
+// last line written partially on crash, are skipped.
+func (s *Store) Scan(fn func(record viewsv1alpha1.IPHistoryRecord)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, path := range []string{s.Path + ".1", s.Path} {
+		if err := scanFile(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanFile(path string, fn func(record viewsv1alpha1.IPHistoryRecord)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := viewsv1alpha1.IPHistoryRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			klog.Warningf("skip corrupted ip history record in %s: %s", path, err)
+			continue
+		}
+		fn(record)
+	}
+	return scanner.Err()
+}
+
+func (s *Store) rotate() error {
+	maxSize := s.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) || err == nil && info.Size() < maxSize {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.Path, s.Path+".1")
+}
@@ -19,7 +19,7 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"strings"
+	"reflect"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -27,12 +27,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -47,61 +46,94 @@ type NetworkPolicyReconciler struct {
 	Scheme *runtime.Scheme
 }
 
-// Reconcile receive endpoint from work queue, synchronize the endpoint status
+// Reconcile receive NetworkPolicy from work queue, synchronize the SecurityPolicy converted from it.
+// The SecurityPolicy is owned by the NetworkPolicy, and garbage collected with it.
 func (r *NetworkPolicyReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 	klog.Infof("NetworkPolicyReconciler received NetworkPolicy %s reconcile", req.NamespacedName)
 
 	networkPolicy := networkingv1.NetworkPolicy{}
-	securityPolicyName := "np-" + req.Name
-
-	// delete securityPolicy if networkPolicy is not found
-	if err := r.Get(ctx, req.NamespacedName, &networkPolicy); err != nil && errors.IsNotFound(err) {
-		klog.Infof("Delete securityPolicy %s", securityPolicyName)
-		securityPolicy := v1alpha1.SecurityPolicy{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      securityPolicyName,
-				Namespace: req.Namespace,
-			},
-		}
-		if err = r.Delete(ctx, &securityPolicy); err != nil && !errors.IsNotFound(err) {
-			klog.Errorf("Delete securityPolicy %s failed, err: %s", securityPolicyName, err)
+	securityPolicyReq := types.NamespacedName{
+		Namespace: req.Namespace,
+		Name:      "np-" + req.Name,
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, &networkPolicy); err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("Get networkPolicy %s error, err: %s", req.NamespacedName, err)
 			return ctrl.Result{}, err
 		}
+		// delete securityPolicy if networkPolicy is not found, without waiting for garbage collection
+		return ctrl.Result{}, r.deleteSecurityPolicy(ctx, securityPolicyReq, req.Name)
+	}
+	if !networkPolicy.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
 
-	securityPolicy := &v1alpha1.SecurityPolicy{}
-	securityPolicyReq := types.NamespacedName{
-		Namespace: req.Namespace,
-		Name:      securityPolicyName,
-	}
-	err := r.Get(ctx, securityPolicyReq, securityPolicy)
 	// generate new securityPolicy
 	newSecurityPolicy := getSecurityPolicy(&networkPolicy)
+	if err := controllerutil.SetControllerReference(&networkPolicy, newSecurityPolicy, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	securityPolicy := &v1alpha1.SecurityPolicy{}
+	err := r.Get(ctx, securityPolicyReq, securityPolicy)
 	if errors.IsNotFound(err) {
 		// submit creation
 		if err := r.Create(ctx, newSecurityPolicy); err != nil {
 			klog.Errorf("create securityPolicy %s, err: %s", newSecurityPolicy.Name, err)
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{}, nil
 	}
-	if err == nil {
-		// submit update
-		securityPolicy.Spec = *(newSecurityPolicy.Spec.DeepCopy())
-		if err := r.Update(ctx, securityPolicy); err != nil {
-			klog.Errorf("update securityPolicy %s, err: %s", securityPolicy.Name, err)
-			return ctrl.Result{}, err
-		}
-	}
-	if err != nil && !errors.IsNotFound(err) {
+	if err != nil {
 		klog.Errorf("Get securityPolicy error, err: %s", err)
 		return ctrl.Result{}, err
 	}
 
+	owner := metav1.GetControllerOf(securityPolicy)
+	if owner != nil && owner.UID != networkPolicy.UID {
+		// owned by others, or by the networkPolicy deleted with the same name, which the
+		// securityPolicy would be garbage collected and converted again
+		klog.Errorf("securityPolicy %s not converted from networkPolicy %s, skip update", securityPolicyReq, req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+	if owner != nil && reflect.DeepEqual(securityPolicy.Spec, newSecurityPolicy.Spec) {
+		return ctrl.Result{}, nil
+	}
+
+	// submit update, the securityPolicy converted by the former versions without owner is adopted
+	securityPolicy.Spec = *(newSecurityPolicy.Spec.DeepCopy())
+	if err := controllerutil.SetControllerReference(&networkPolicy, securityPolicy, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Update(ctx, securityPolicy); err != nil {
+		klog.Errorf("update securityPolicy %s, err: %s", securityPolicy.Name, err)
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// deleteSecurityPolicy deletes the securityPolicy converted from the networkPolicy, the securityPolicy
+// owned by others is kept.
+func (r *NetworkPolicyReconciler) deleteSecurityPolicy(ctx context.Context, req types.NamespacedName, networkPolicyName string) error {
+	securityPolicy := v1alpha1.SecurityPolicy{}
+	if err := r.Get(ctx, req, &securityPolicy); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if owner := metav1.GetControllerOf(&securityPolicy); owner != nil && (owner.Kind != "NetworkPolicy" || owner.Name != networkPolicyName) {
+		return nil
+	}
+
+	klog.Infof("Delete securityPolicy %s", req)
+	if err := r.Delete(ctx, &securityPolicy); err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Delete securityPolicy %s failed, err: %s", req, err)
+		return err
+	}
+	return nil
+}
+
 // SetupWithManager create and add networkPolicy Controller to the manager.
 func (r *NetworkPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if mgr == nil {
@@ -120,28 +152,11 @@ func (r *NetworkPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	if err = c.Watch(&source.Kind{Type: &v1alpha1.SecurityPolicy{}}, &handler.Funcs{
-		CreateFunc: r.addSecurityPolicy,
-	}); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *NetworkPolicyReconciler) addSecurityPolicy(e event.CreateEvent, q workqueue.RateLimitingInterface) {
-	if e.Object == nil {
-		klog.Errorf("receive create event with no object %v", e)
-		return
-	}
-
-	// only handle addSecurityPolicy with "np-" prefix
-	if strings.HasPrefix(e.Meta.GetName(), "np-") {
-		q.Add(ctrl.Request{NamespacedName: types.NamespacedName{
-			Namespace: e.Meta.GetNamespace(),
-			Name:      strings.TrimPrefix(e.Meta.GetName(), "np-"),
-		}})
-	}
+	// enqueue the owner NetworkPolicy when its securityPolicy been modified or removed
+	return c.Watch(&source.Kind{Type: &v1alpha1.SecurityPolicy{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &networkingv1.NetworkPolicy{},
+		IsController: true,
+	})
 }
 
 // getSecurityPolicy convert NetworkPolicy into SecurityPolicy
@@ -126,6 +126,29 @@ var _ = Describe("pod controller", func() {
 			Expect(securityPolicy.Spec.Tier).Should(Equal(constants.Tier2))
 			Expect(securityPolicy.Spec.SymmetricMode).Should(BeFalse())
 			Expect(len(securityPolicy.Spec.IngressRules)).Should(Equal(1))
+			Expect(metav1.GetControllerOf(&securityPolicy)).ShouldNot(BeNil())
+			Expect(metav1.GetControllerOf(&securityPolicy).Name).Should(Equal(networkPolicy.Name))
+
+			Expect(k8sClient.Delete(ctx, networkPolicy)).Should(Succeed())
+			Eventually(func() int {
+				securityPolicyList := securityv1alpha1.SecurityPolicyList{}
+				Expect(k8sClient.List(ctx, &securityPolicyList)).Should(Succeed())
+				return len(securityPolicyList.Items)
+			}, timeout, interval).Should(BeZero())
+		})
+
+		It("should restore the modified security policy", func() {
+			Eventually(func() error {
+				return k8sClient.Get(ctx, securityPolicyReq, &securityPolicy)
+			}, time.Minute, interval).Should(Succeed())
+
+			securityPolicy.Spec.IngressRules = nil
+			Expect(k8sClient.Update(ctx, &securityPolicy)).Should(Succeed())
+
+			Eventually(func() int {
+				Expect(k8sClient.Get(ctx, securityPolicyReq, &securityPolicy)).Should(Succeed())
+				return len(securityPolicy.Spec.IngressRules)
+			}, timeout, interval).Should(Equal(1))
 
 			Expect(k8sClient.Delete(ctx, networkPolicy)).Should(Succeed())
 			Eventually(func() int {
@@ -19,13 +19,16 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -81,6 +84,17 @@ func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		Namespace: req.Namespace,
 		Name:      endpointName,
 	}
+	expectLabels := make(map[string]string, len(pod.Labels))
+	for key, value := range pod.Labels {
+		expectLabels[key] = value
+	}
+	// the label is reserved for the service account, never inherit it from the pod
+	delete(expectLabels, constants.ServiceAccountLabelKey)
+	if serviceAccount := podServiceAccount(&pod); serviceAccount != "" {
+		expectLabels[constants.ServiceAccountLabelKey] = serviceAccount
+	}
+	expectPorts := podNamedPorts(&pod)
+
 	err := r.Get(ctx, endpointReq, &endpoint)
 	switch errors.ReasonForError(err) {
 	case metav1.StatusReasonNotFound:
@@ -88,52 +102,48 @@ func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		endpoint.Name = endpointName
 		endpoint.Namespace = req.Namespace
 		endpoint.Spec.VID = 0
-		endpoint.Spec.Reference.ExternalIDName = "pod-uuid"
+		endpoint.Spec.Reference.ExternalIDName = constants.PodEndpointExternalIDName
 		endpoint.Spec.Reference.ExternalIDValue = utils.EncodeNamespacedName(k8stypes.NamespacedName{
 			Name:      endpointName,
 			Namespace: req.Namespace,
 		})
 		endpoint.Spec.Type = v1alpha1.EndpointStatic
-		endpoint.ObjectMeta.Labels = map[string]string{}
-		for key, value := range pod.ObjectMeta.Labels {
-			endpoint.ObjectMeta.Labels[key] = value
-		}
-
-		var namedPorts []v1alpha1.NamedPort
-		for _, container := range pod.Spec.Containers {
-			namedPorts = append(namedPorts, toNamedPorts(container.Ports)...)
-		}
-		endpoint.Spec.Ports = namedPorts
+		endpoint.ObjectMeta.Labels = expectLabels
+		endpoint.Spec.Ports = expectPorts
 
 		// submit creation
 		if err := r.Create(ctx, &endpoint); err != nil {
 			klog.Errorf("create endpoint %s err: %s", endpointName, err)
 			return ctrl.Result{}, err
 		}
+		klog.Infof("endpoint %s/%s created for pod %s", endpoint.Namespace, endpointName, req.NamespacedName)
 	case metav1.StatusReasonUnknown: // no error
-		// update pod label
-		endpoint.ObjectMeta.Labels = map[string]string{} // clear old labels
-		for key, value := range pod.ObjectMeta.Labels {
-			endpoint.ObjectMeta.Labels[key] = value
-		}
-		// submit update
-		if err := r.Update(ctx, &endpoint); err != nil {
-			klog.Errorf("update endpoint %s err: %s", endpointName, err)
-			return ctrl.Result{}, err
+		// the endpoint created by the former version may lack the named ports
+		if !labels.Equals(endpoint.Labels, expectLabels) || !equalNamedPorts(endpoint.Spec.Ports, expectPorts) {
+			endpoint.ObjectMeta.Labels = expectLabels
+			endpoint.Spec.Ports = expectPorts
+			if err := r.Update(ctx, &endpoint); err != nil {
+				klog.Errorf("update endpoint %s err: %s", endpointName, err)
+				return ctrl.Result{}, err
+			}
 		}
-		// update status
-		endpoint.Status.Agents = []string{pod.Spec.NodeName}
-		endpoint.Status.IPs = []types.IPAddress{types.IPAddress(pod.Status.PodIP)}
-		if err := r.Status().Update(ctx, &endpoint); err != nil {
-			klog.Errorf("update endpoint status %s err: %s", endpointName, err)
-			return ctrl.Result{}, err
-		}
-
 	default: // other errors
 		klog.Errorf("Get endpoint error, err: %s", err)
 		return ctrl.Result{}, err
 	}
 
+	// the status is ignored on creation, update it along with the endpoint created
+	expectIPs, expectAgents := podEndpointIPs(&pod), podEndpointAgents(&pod)
+	if utils.EqualIPs(endpoint.Status.IPs, expectIPs) && utils.EqualStringSlice(endpoint.Status.Agents, expectAgents) {
+		return ctrl.Result{}, nil
+	}
+	endpoint.Status.IPs = expectIPs
+	endpoint.Status.Agents = expectAgents
+	if err := r.Status().Update(ctx, &endpoint); err != nil {
+		klog.Errorf("update endpoint status %s err: %s", endpointName, err)
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -178,3 +188,53 @@ func (r *PodReconciler) addEndpoint(e event.CreateEvent, q workqueue.RateLimitin
 		}})
 	}
 }
+
+// podEndpointIPs returns the ips of the pod, empty if the pod not assigned the ips yet or
+// terminated, the ips of the terminated pods may have been reassigned to the other pods.
+func podEndpointIPs(pod *corev1.Pod) []types.IPAddress {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+
+	var ips []types.IPAddress
+	for _, podIP := range pod.Status.PodIPs {
+		if podIP.IP != "" {
+			ips = append(ips, types.IPAddress(podIP.IP))
+		}
+	}
+	// the pod reported by the former kubelet may lack the PodIPs
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = append(ips, types.IPAddress(pod.Status.PodIP))
+	}
+	return ips
+}
+
+// podEndpointAgents returns the node the pod scheduled to, empty if not scheduled yet.
+func podEndpointAgents(pod *corev1.Pod) []string {
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// podServiceAccount returns the service account the pod runs under, empty if the name not
+// available as a label value, e.g. longer than 63 characters.
+func podServiceAccount(pod *corev1.Pod) string {
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = pod.Spec.DeprecatedServiceAccount
+	}
+	if errs := validation.IsValidLabelValue(serviceAccount); len(errs) != 0 {
+		klog.Warningf("service account %s of pod %s/%s not a available label value: %s",
+			serviceAccount, pod.GetNamespace(), pod.GetName(), strings.Join(errs, ", "))
+		return ""
+	}
+	return serviceAccount
+}
+
+func equalNamedPorts(ports1, ports2 []v1alpha1.NamedPort) bool {
+	if len(ports1) == 0 && len(ports2) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(ports1, ports2)
+}
@@ -18,6 +18,7 @@ package k8s
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -29,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	ertypes "github.com/everoute/everoute/pkg/types"
 	"github.com/everoute/everoute/pkg/utils"
 )
 
@@ -168,4 +170,83 @@ var _ = Describe("pod controller", func() {
 
 		})
 	})
+
+	Context("Test pod named ports", func() {
+		It("should resolve the named ports of all the containers", func() {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "web",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+								{Name: "dns", ContainerPort: 53},
+								{ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+							},
+						},
+						{
+							Name: "sidecar",
+							Ports: []corev1.ContainerPort{
+								{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolUDP},
+								{Name: "sctp", ContainerPort: 3868, Protocol: corev1.ProtocolSCTP},
+							},
+						},
+					},
+				},
+			}
+			Expect(podNamedPorts(pod)).Should(ConsistOf(
+				securityv1alpha1.NamedPort{Name: "http", Port: 80, Protocol: securityv1alpha1.ProtocolTCP},
+				securityv1alpha1.NamedPort{Name: "dns", Port: 53, Protocol: securityv1alpha1.ProtocolTCP},
+				securityv1alpha1.NamedPort{Name: "metrics", Port: 9090, Protocol: securityv1alpha1.ProtocolUDP},
+			))
+		})
+	})
+
+	Context("Test pod service account", func() {
+		It("should resolve the service account of the pod", func() {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{ServiceAccountName: "backend"}}
+			Expect(podServiceAccount(pod)).Should(Equal("backend"))
+
+			// the pod created by the former client may set the deprecated field only
+			pod = &corev1.Pod{Spec: corev1.PodSpec{DeprecatedServiceAccount: "backend"}}
+			Expect(podServiceAccount(pod)).Should(Equal("backend"))
+		})
+
+		It("should not resolve the service account not available as a label value", func() {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{ServiceAccountName: strings.Repeat("a", 64)}}
+			Expect(podServiceAccount(pod)).Should(BeEmpty())
+			Expect(podServiceAccount(&corev1.Pod{})).Should(BeEmpty())
+		})
+	})
+
+	Context("Test pod endpoint status", func() {
+		It("should resolve the ips and the agent of the pod", func() {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{NodeName: "node01"},
+				Status: corev1.PodStatus{
+					Phase:  corev1.PodRunning,
+					PodIP:  "10.0.0.1",
+					PodIPs: []corev1.PodIP{{IP: "10.0.0.1"}, {IP: "fd00::1"}},
+				},
+			}
+			Expect(podEndpointIPs(pod)).Should(Equal([]ertypes.IPAddress{"10.0.0.1", "fd00::1"}))
+			Expect(podEndpointAgents(pod)).Should(Equal([]string{"node01"}))
+
+			// the pod reported by the former kubelet lacks the PodIPs
+			pod.Status.PodIPs = nil
+			Expect(podEndpointIPs(pod)).Should(Equal([]ertypes.IPAddress{"10.0.0.1"}))
+		})
+
+		It("should not resolve the ips of the pod not started or terminated", func() {
+			pod := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}
+			Expect(podEndpointIPs(pod)).Should(BeEmpty())
+			Expect(podEndpointAgents(pod)).Should(BeEmpty())
+
+			pod = &corev1.Pod{
+				Spec:   corev1.PodSpec{NodeName: "node01"},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded, PodIP: "10.0.0.1"},
+			}
+			Expect(podEndpointIPs(pod)).Should(BeEmpty())
+		})
+	})
 })
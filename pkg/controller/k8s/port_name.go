@@ -6,16 +6,31 @@ import (
 	"github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 )
 
+// podNamedPorts returns the named ports of all the containers of the pod, the named ports in
+// the policy rules are resolved against them for each endpoint.
+func podNamedPorts(pod *corev1.Pod) []v1alpha1.NamedPort {
+	var namedPorts []v1alpha1.NamedPort
+	for _, container := range pod.Spec.Containers {
+		namedPorts = append(namedPorts, toNamedPorts(container.Ports)...)
+	}
+	return namedPorts
+}
+
 func toNamedPorts(containerPorts []corev1.ContainerPort) []v1alpha1.NamedPort {
 	namedPorts := make([]v1alpha1.NamedPort, 0, len(containerPorts))
 	for _, item := range containerPorts {
-		if item.Protocol == corev1.ProtocolSCTP {
+		// the port without name could never be referenced by the policy
+		if item.Name == "" || item.Protocol == corev1.ProtocolSCTP {
 			continue
 		}
+		protocol := item.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
 		namedPort := v1alpha1.NamedPort{
 			Name:     item.Name,
 			Port:     item.ContainerPort,
-			Protocol: v1alpha1.Protocol(item.Protocol),
+			Protocol: v1alpha1.Protocol(protocol),
 		}
 		namedPorts = append(namedPorts, namedPort)
 	}
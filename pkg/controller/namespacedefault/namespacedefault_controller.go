@@ -0,0 +1,261 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespacedefault
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+const (
+	// PolicyPrefix is the name prefix of the policies which enforce the default deny
+	PolicyPrefix = "namespace-default-"
+
+	ReasonEnforced = "DefaultDenyEnforced"
+	ReasonReleased = "DefaultDenyReleased"
+	ReasonFailed   = "DefaultDenyFailed"
+)
+
+// Reconciler watch NamespaceDefaultPolicies and namespaces, enforce the default deny with
+// a tier2 SecurityPolicy in each of the selected namespaces.
+type Reconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Reconcile receive NamespaceDefaultPolicy from work queue, synchronize the policies of the
+// selected namespaces.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("NamespaceDefaultReconciler received NamespaceDefaultPolicy %s reconcile", req.Name)
+
+	ndp := securityv1alpha1.NamespaceDefaultPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, &ndp); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, r.release(ctx, req.Name, nil)
+		}
+		klog.Errorf("unable to fetch NamespaceDefaultPolicy %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	if !ndp.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	namespaces, err := r.selectNamespaces(ctx, &ndp)
+	if err != nil {
+		klog.Errorf("unable to select namespaces of NamespaceDefaultPolicy %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	var errList []error
+	for _, namespace := range namespaces {
+		if err = r.syncPolicy(ctx, &ndp, namespace); err != nil {
+			r.Recorder.Eventf(&ndp, corev1.EventTypeWarning, ReasonFailed, "unable to enforce default deny in namespace %s: %s", namespace, err)
+			errList = append(errList, err)
+		}
+	}
+	if err = r.release(ctx, ndp.Name, sets.NewString(namespaces...)); err != nil {
+		errList = append(errList, err)
+	}
+	if len(errList) != 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(errList)
+	}
+
+	expectStatus := securityv1alpha1.NamespaceDefaultPolicyStatus{Namespaces: namespaces}
+	if !reflect.DeepEqual(ndp.Status, expectStatus) {
+		ndp.Status = expectStatus
+		if err = r.Status().Update(ctx, &ndp); err != nil {
+			klog.Errorf("failed to update NamespaceDefaultPolicy %s status: %s", req.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add NamespaceDefault Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("namespacedefault-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.NamespaceDefaultPolicy{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// the namespaces selected may change when a namespace created, removed or relabeled
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.allPolicies),
+	})
+	if err != nil {
+		return err
+	}
+
+	// enqueue the owner NamespaceDefaultPolicy when its policies been modified or removed
+	return c.Watch(&source.Kind{Type: &securityv1alpha1.SecurityPolicy{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &securityv1alpha1.NamespaceDefaultPolicy{},
+		IsController: true,
+	})
+}
+
+func (r *Reconciler) allPolicies(handler.MapObject) []reconcile.Request {
+	ndpList := securityv1alpha1.NamespaceDefaultPolicyList{}
+	if err := r.List(context.Background(), &ndpList); err != nil {
+		klog.Errorf("unable to list NamespaceDefaultPolicies: %s", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(ndpList.Items))
+	for _, ndp := range ndpList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ndp.Name}})
+	}
+	return requests
+}
+
+// selectNamespaces return the sorted names of the namespaces selected, the terminating
+// namespaces are ignored.
+func (r *Reconciler) selectNamespaces(ctx context.Context, ndp *securityv1alpha1.NamespaceDefaultPolicy) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(ndp.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaceSelector: %s", err)
+	}
+
+	namespaceList := corev1.NamespaceList{}
+	if err = r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		if namespace.DeletionTimestamp.IsZero() {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (r *Reconciler) syncPolicy(ctx context.Context, ndp *securityv1alpha1.NamespaceDefaultPolicy, namespace string) error {
+	policy := NewDefaultDenyPolicy(ndp, namespace)
+	if err := controllerutil.SetControllerReference(ndp, policy, r.Scheme); err != nil {
+		return err
+	}
+
+	var oldPolicy securityv1alpha1.SecurityPolicy
+	err := r.Get(ctx, client.ObjectKey{Namespace: policy.Namespace, Name: policy.Name}, &oldPolicy)
+	switch {
+	case errors.IsNotFound(err):
+		if err = r.Create(ctx, policy); err != nil {
+			return fmt.Errorf("create policy %s/%s: %s", policy.Namespace, policy.Name, err)
+		}
+		klog.Infof("NamespaceDefaultPolicy %s enforced in namespace %s by policy %s", ndp.Name, namespace, policy.Name)
+		r.Recorder.Eventf(ndp, corev1.EventTypeNormal, ReasonEnforced, "default deny enforced in namespace %s", namespace)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if reflect.DeepEqual(oldPolicy.Spec, policy.Spec) && reflect.DeepEqual(oldPolicy.Labels, policy.Labels) {
+		return nil
+	}
+	oldPolicy.Labels = policy.Labels
+	oldPolicy.Spec = policy.Spec
+	if err = r.Update(ctx, &oldPolicy); err != nil {
+		return fmt.Errorf("update policy %s/%s: %s", policy.Namespace, policy.Name, err)
+	}
+	klog.Infof("NamespaceDefaultPolicy %s policy %s/%s updated", ndp.Name, namespace, policy.Name)
+	return nil
+}
+
+// release remove the policies of the NamespaceDefaultPolicy in the namespaces not selected,
+// all the policies are removed if selected is nil, without waiting for garbage collection.
+func (r *Reconciler) release(ctx context.Context, name string, selected sets.String) error {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(ctx, &policyList, client.MatchingLabels{constants.NamespaceDefaultPolicyLabelKey: name}); err != nil {
+		return err
+	}
+
+	for index := range policyList.Items {
+		policy := &policyList.Items[index]
+		if selected.Has(policy.Namespace) {
+			continue
+		}
+		if err := r.Delete(ctx, policy); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("unable to remove policy %s/%s: %s", policy.Namespace, policy.Name, err)
+			return err
+		}
+		klog.Infof("NamespaceDefaultPolicy %s released from namespace %s", name, policy.Namespace)
+
+		// the NamespaceDefaultPolicy may have gone, record the event with its reference only
+		r.Recorder.Eventf(&securityv1alpha1.NamespaceDefaultPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+		}, corev1.EventTypeNormal, ReasonReleased, "default deny released from namespace %s", policy.Namespace)
+	}
+	return nil
+}
+
+// NewDefaultDenyPolicy return the policy enforce the default deny in the namespace. The policy
+// selects all the endpoints in the namespace without any rule, so only the traffic allowed by
+// the other policies in the same tier is passed.
+func NewDefaultDenyPolicy(ndp *securityv1alpha1.NamespaceDefaultPolicy, namespace string) *securityv1alpha1.SecurityPolicy {
+	return &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PolicyPrefix + ndp.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.NamespaceDefaultPolicyLabelKey: ndp.Name,
+			},
+		},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:        constants.Tier2,
+			AppliedTo:   []securityv1alpha1.ApplyToPeer{{EndpointSelector: &labels.Selector{}}},
+			DefaultRule: securityv1alpha1.DefaultRuleDrop,
+			PolicyTypes: ndp.Spec.DeepCopy().PolicyTypes,
+		},
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespacedefault
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = kubescheme.AddToScheme(scheme)
+	_ = clientsetscheme.AddToScheme(scheme)
+	return scheme
+}
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newNamespaceDefaultPolicy(name string, matchLabels map[string]string, policyTypes ...networkingv1.PolicyType) *securityv1alpha1.NamespaceDefaultPolicy {
+	return &securityv1alpha1.NamespaceDefaultPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: securityv1alpha1.NamespaceDefaultPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+			PolicyTypes:       policyTypes,
+		},
+	}
+}
+
+func TestNewDefaultDenyPolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	policy := NewDefaultDenyPolicy(newNamespaceDefaultPolicy("isolate", nil, networkingv1.PolicyTypeIngress), "prod")
+	Expect(policy.Name).Should(Equal(PolicyPrefix + "isolate"))
+	Expect(policy.Namespace).Should(Equal("prod"))
+	Expect(policy.Labels[constants.NamespaceDefaultPolicyLabelKey]).Should(Equal("isolate"))
+	Expect(policy.Spec.Tier).Should(Equal(constants.Tier2))
+	Expect(policy.Spec.DefaultRule).Should(Equal(securityv1alpha1.DefaultRuleDrop))
+	Expect(policy.Spec.PolicyTypes).Should(ConsistOf(networkingv1.PolicyTypeIngress))
+	Expect(policy.Spec.AppliedTo).Should(HaveLen(1))
+	Expect(policy.Spec.AppliedTo[0].EndpointSelector).ShouldNot(BeNil())
+	Expect(policy.Spec.IngressRules).Should(BeEmpty())
+	Expect(policy.Spec.EgressRules).Should(BeEmpty())
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	prod := newNamespace("prod", map[string]string{"env": "prod"})
+	test := newNamespace("test", map[string]string{"env": "test"})
+	ndp := newNamespaceDefaultPolicy("isolate", map[string]string{"env": "prod"}, networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress)
+	scheme := newScheme()
+	k8sClient := fake.NewFakeClientWithScheme(scheme, prod, test, ndp)
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Client: k8sClient, Scheme: scheme, Recorder: recorder}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "isolate"}}
+	policyKey := func(namespace string) k8stypes.NamespacedName {
+		return k8stypes.NamespacedName{Namespace: namespace, Name: PolicyPrefix + "isolate"}
+	}
+
+	t.Run("should enforce default deny in selected namespaces", func(t *testing.T) {
+		RegisterTestingT(t)
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(k8sClient.Get(ctx, policyKey("prod"), &policy)).Should(Succeed())
+		Expect(policy.Spec.PolicyTypes).Should(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		Expect(policy.OwnerReferences).Should(HaveLen(1))
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, policyKey("test"), &policy))).Should(BeTrue())
+
+		var current securityv1alpha1.NamespaceDefaultPolicy
+		Expect(k8sClient.Get(ctx, req.NamespacedName, &current)).Should(Succeed())
+		Expect(current.Status.Namespaces).Should(Equal([]string{"prod"}))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonEnforced)))
+	})
+
+	t.Run("should follow the namespaces relabeled", func(t *testing.T) {
+		RegisterTestingT(t)
+		test.Labels["env"] = "prod"
+		Expect(k8sClient.Update(ctx, test)).Should(Succeed())
+		prod.Labels["env"] = "dev"
+		Expect(k8sClient.Update(ctx, prod)).Should(Succeed())
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(k8sClient.Get(ctx, policyKey("test"), &policy)).Should(Succeed())
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, policyKey("prod"), &policy))).Should(BeTrue())
+
+		var current securityv1alpha1.NamespaceDefaultPolicy
+		Expect(k8sClient.Get(ctx, req.NamespacedName, &current)).Should(Succeed())
+		Expect(current.Status.Namespaces).Should(Equal([]string{"test"}))
+	})
+
+	t.Run("should remove policies when NamespaceDefaultPolicy removed", func(t *testing.T) {
+		RegisterTestingT(t)
+		Expect(k8sClient.Delete(ctx, ndp)).Should(Succeed())
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, policyKey("test"), &policy))).Should(BeTrue())
+	})
+}
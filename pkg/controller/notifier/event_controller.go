@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/everoute/everoute/pkg/agent/portscan"
+	"github.com/everoute/everoute/pkg/controller/quarantine"
+)
+
+// eventNotificationTypes map the reason of the events to the notification types
+var eventNotificationTypes = map[string]NotificationType{
+	portscan.PortScanEventReason:   NotificationTypePolicyViolation,
+	portscan.QuarantineEventReason: NotificationTypeQuarantine,
+	quarantine.ReasonEnforced:      NotificationTypeQuarantine,
+	quarantine.ReasonUpdated:       NotificationTypeQuarantine,
+	quarantine.ReasonExpired:       NotificationTypeQuarantine,
+	quarantine.ReasonReleased:      NotificationTypeQuarantine,
+	quarantine.ReasonFailed:        NotificationTypeRealizationFailure,
+}
+
+// EventReconciler watch the kubernetes events about policy violations, quarantines
+// and realization failures, and forward them to the external webhook endpoints.
+type EventReconciler struct {
+	client.Client
+	Notifier *Notifier
+
+	// startTime is when the reconciler start, events happened before are ignored
+	startTime time.Time
+	// notifiedLock protect notified
+	notifiedLock sync.Mutex
+	// notified is the count of each event when it's last notified
+	notified map[types.NamespacedName]int32
+}
+
+// Reconcile receive event from work queue, notify the webhook endpoints.
+func (r *EventReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("EventReconciler received event %s reconcile", req.NamespacedName)
+
+	k8sEvent := corev1.Event{}
+	if err := r.Get(ctx, req.NamespacedName, &k8sEvent); err != nil {
+		if errors.IsNotFound(err) {
+			r.forget(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		klog.Errorf("unable to fetch event %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	notification := NewNotification(&k8sEvent)
+	if notification == nil || notification.Timestamp.Before(r.startTime) || !r.shouldNotify(req.NamespacedName, k8sEvent.Count) {
+		return ctrl.Result{}, nil
+	}
+
+	// notifier retries internally, give up the notification after retries
+	// instead of requeue it and blocking the following ones
+	if err := r.Notifier.Notify(ctx, notification); err != nil {
+		klog.Errorf("failed to notify event %s: %s", req.NamespacedName, err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Event Controller to the manager.
+func (r *EventReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	if r.Notifier == nil {
+		return fmt.Errorf("can't setup with nil notifier")
+	}
+	r.startTime = time.Now()
+	r.notified = make(map[types.NamespacedName]int32)
+
+	c, err := controller.New("notifier-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &corev1.Event{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isNotifiable(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return isNotifiable(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isNotifiable(e.Object) },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	})
+}
+
+func (r *EventReconciler) shouldNotify(key types.NamespacedName, count int32) bool {
+	r.notifiedLock.Lock()
+	defer r.notifiedLock.Unlock()
+
+	if lastCount, ok := r.notified[key]; ok && count <= lastCount {
+		return false
+	}
+	r.notified[key] = count
+	return true
+}
+
+func (r *EventReconciler) forget(key types.NamespacedName) {
+	r.notifiedLock.Lock()
+	defer r.notifiedLock.Unlock()
+	delete(r.notified, key)
+}
+
+func isNotifiable(obj interface{}) bool {
+	k8sEvent, ok := obj.(*corev1.Event)
+	return ok && notificationTypeOf(k8sEvent) != ""
+}
+
+func notificationTypeOf(k8sEvent *corev1.Event) NotificationType {
+	if notificationType, ok := eventNotificationTypes[k8sEvent.Reason]; ok {
+		return notificationType
+	}
+	// warnings about failures on everoute objects are regarded as realization failures
+	if k8sEvent.Type == corev1.EventTypeWarning && strings.HasSuffix(k8sEvent.Reason, "Failed") &&
+		strings.Contains(k8sEvent.InvolvedObject.APIVersion, "everoute.io") {
+		return NotificationTypeRealizationFailure
+	}
+	return ""
+}
+
+// NewNotification convert the kubernetes event to notification, return nil if the
+// event is not notifiable.
+func NewNotification(k8sEvent *corev1.Event) *Notification {
+	notificationType := notificationTypeOf(k8sEvent)
+	if notificationType == "" {
+		return nil
+	}
+
+	timestamp := k8sEvent.LastTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = k8sEvent.EventTime.Time
+	}
+	if timestamp.IsZero() {
+		timestamp = k8sEvent.CreationTimestamp.Time
+	}
+
+	return &Notification{
+		Type:     notificationType,
+		Reason:   k8sEvent.Reason,
+		Message:  k8sEvent.Message,
+		Severity: k8sEvent.Type,
+		Object: ObjectReference{
+			APIVersion: k8sEvent.InvolvedObject.APIVersion,
+			Kind:       k8sEvent.InvolvedObject.Kind,
+			Namespace:  k8sEvent.InvolvedObject.Namespace,
+			Name:       k8sEvent.InvolvedObject.Name,
+			UID:        string(k8sEvent.InvolvedObject.UID),
+		},
+		Source:    k8sEvent.Source.Component,
+		Host:      k8sEvent.Source.Host,
+		Count:     k8sEvent.Count,
+		Timestamp: timestamp,
+	}
+}
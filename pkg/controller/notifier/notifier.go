@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"k8s.io/klog"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the request, in format "sha256=<hex>"
+	SignatureHeader = "X-Everoute-Signature"
+	// TimestampHeader carries the unix timestamp when the request signed
+	TimestampHeader = "X-Everoute-Timestamp"
+	// EventTypeHeader carries the NotificationType of the request
+	EventTypeHeader = "X-Everoute-Event"
+
+	DefaultMaxRetries    = 5
+	DefaultRetryInterval = time.Second
+	DefaultTimeout       = 10 * time.Second
+)
+
+// NotificationType is the category of a notification
+type NotificationType string
+
+const (
+	NotificationTypePolicyViolation    NotificationType = "PolicyViolation"
+	NotificationTypeQuarantine         NotificationType = "Quarantine"
+	NotificationTypeRealizationFailure NotificationType = "RealizationFailure"
+)
+
+// Notification is the JSON body posted to the webhook endpoints
+type Notification struct {
+	Type     NotificationType `json:"type"`
+	Reason   string           `json:"reason"`
+	Message  string           `json:"message"`
+	Severity string           `json:"severity"`
+	Object   ObjectReference  `json:"object"`
+	// Source is the component reports the notification, e.g. everoute-agent
+	Source    string    `json:"source,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Count     int32     `json:"count,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ObjectReference is the object which the notification about
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// Endpoint is an external webhook which receives notifications
+type Endpoint struct {
+	URL string
+	// Secret used for sign the request, the request would not be signed if empty.
+	Secret string
+}
+
+// Config defines where and how the notifications been delivered
+type Config struct {
+	Endpoints []Endpoint
+	// MaxRetries is the max number of retries for each endpoint after first try failed.
+	MaxRetries int
+	// RetryInterval is the initial interval between retries, grows exponentially.
+	RetryInterval time.Duration
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+}
+
+// Notifier deliver notifications to the external webhook endpoints, e.g. SOAR
+// systems, with retries. Receivers could verify the notifications with Sign.
+type Notifier struct {
+	config Config
+	client *http.Client
+}
+
+// New create a Notifier with the config, unset fields would be defaulted.
+func New(config Config) *Notifier {
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Notifier{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Notify post the notification to all the endpoints, return error if failed
+// delivering to any of them after retries.
+func (n *Notifier) Notify(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %s", err)
+	}
+
+	var errs []error
+	for _, endpoint := range n.config.Endpoints {
+		if err = n.notifyEndpoint(ctx, endpoint, notification.Type, body); err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %s", endpoint.URL, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func (n *Notifier) notifyEndpoint(ctx context.Context, endpoint Endpoint, notificationType NotificationType, body []byte) error {
+	policy := backoff.NewExponentialBackOff()
+	policy.InitialInterval = n.config.RetryInterval
+	policy.MaxElapsedTime = 0
+
+	return backoff.RetryNotify(func() error {
+		return n.post(ctx, endpoint, notificationType, body)
+	}, backoff.WithContext(backoff.WithMaxRetries(policy, uint64(n.config.MaxRetries)), ctx), func(err error, next time.Duration) {
+		klog.Errorf("failed to notify %s: %s, retry after %s", endpoint.URL, err, next)
+	})
+}
+
+func (n *Notifier) post(ctx context.Context, endpoint Endpoint, notificationType NotificationType, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, string(notificationType))
+	req.Header.Set(TimestampHeader, timestamp)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		// the request was rejected by the endpoint, retry won't help
+		return backoff.Permanent(fmt.Errorf("unexpected response status %s", resp.Status))
+	default:
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+}
+
+// Sign return the signature of the request body, it's the hex encoded HMAC-SHA256
+// of "<timestamp>.<body>" keyed by the secret, prefixed with "sha256=".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
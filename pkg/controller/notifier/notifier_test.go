@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/everoute/everoute/pkg/agent/portscan"
+	"github.com/everoute/everoute/pkg/controller/quarantine"
+)
+
+type receiver struct {
+	*httptest.Server
+	requests      int32
+	failTimes     int32
+	failStatus    int
+	notifications chan *Notification
+}
+
+func newReceiver(secret string, failTimes int32, failStatus int) *receiver {
+	r := &receiver{
+		failTimes:     failTimes,
+		failStatus:    failStatus,
+		notifications: make(chan *Notification, 10),
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&r.requests, 1) <= r.failTimes {
+			w.WriteHeader(r.failStatus)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(SignatureHeader) != Sign(secret, req.Header.Get(TimestampHeader), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var notification Notification
+		_ = json.Unmarshal(body, &notification)
+		r.notifications <- &notification
+	}))
+	return r
+}
+
+func newNotifier(urls ...string) *Notifier {
+	config := Config{MaxRetries: 3, RetryInterval: time.Millisecond}
+	for _, url := range urls {
+		config.Endpoints = append(config.Endpoints, Endpoint{URL: url, Secret: "secret"})
+	}
+	return New(config)
+}
+
+func TestSign(t *testing.T) {
+	RegisterTestingT(t)
+
+	signature := Sign("secret", "1600000000", []byte(`{"type":"Quarantine"}`))
+	Expect(signature).Should(HavePrefix("sha256="))
+	Expect(signature).Should(Equal(Sign("secret", "1600000000", []byte(`{"type":"Quarantine"}`))))
+	Expect(signature).ShouldNot(Equal(Sign("secret", "1600000001", []byte(`{"type":"Quarantine"}`))))
+	Expect(signature).ShouldNot(Equal(Sign("other", "1600000000", []byte(`{"type":"Quarantine"}`))))
+}
+
+func TestNotify(t *testing.T) {
+	notification := &Notification{
+		Type:      NotificationTypeQuarantine,
+		Reason:    quarantine.ReasonEnforced,
+		Object:    ObjectReference{Kind: "Quarantine", Namespace: "default", Name: "q01"},
+		Timestamp: time.Now(),
+	}
+
+	t.Run("should post signed notification", func(t *testing.T) {
+		RegisterTestingT(t)
+		r := newReceiver("secret", 0, 0)
+		defer r.Close()
+
+		Expect(newNotifier(r.URL).Notify(context.Background(), notification)).Should(Succeed())
+		var received *Notification
+		Expect(r.notifications).Should(Receive(&received))
+		Expect(received.Type).Should(Equal(NotificationTypeQuarantine))
+		Expect(received.Object.Name).Should(Equal("q01"))
+	})
+
+	t.Run("should retry on server errors", func(t *testing.T) {
+		RegisterTestingT(t)
+		r := newReceiver("secret", 2, http.StatusServiceUnavailable)
+		defer r.Close()
+
+		Expect(newNotifier(r.URL).Notify(context.Background(), notification)).Should(Succeed())
+		Expect(r.notifications).Should(Receive())
+		Expect(atomic.LoadInt32(&r.requests)).Should(Equal(int32(3)))
+	})
+
+	t.Run("should give up after max retries", func(t *testing.T) {
+		RegisterTestingT(t)
+		r := newReceiver("secret", 10, http.StatusServiceUnavailable)
+		defer r.Close()
+
+		Expect(newNotifier(r.URL).Notify(context.Background(), notification)).ShouldNot(Succeed())
+		Expect(atomic.LoadInt32(&r.requests)).Should(Equal(int32(4)))
+	})
+
+	t.Run("should not retry on rejected requests", func(t *testing.T) {
+		RegisterTestingT(t)
+		r := newReceiver("secret", 10, http.StatusBadRequest)
+		defer r.Close()
+
+		Expect(newNotifier(r.URL).Notify(context.Background(), notification)).ShouldNot(Succeed())
+		Expect(atomic.LoadInt32(&r.requests)).Should(Equal(int32(1)))
+	})
+
+	t.Run("should notify all the endpoints", func(t *testing.T) {
+		RegisterTestingT(t)
+		r1, r2 := newReceiver("secret", 0, 0), newReceiver("secret", 0, 0)
+		defer r1.Close()
+		defer r2.Close()
+
+		Expect(newNotifier(r1.URL, r2.URL).Notify(context.Background(), notification)).Should(Succeed())
+		Expect(r1.notifications).Should(Receive())
+		Expect(r2.notifications).Should(Receive())
+	})
+}
+
+func newEvent(name, reason, eventType string, count int32) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "security.everoute.io/v1alpha1",
+			Kind:       "Endpoint",
+			Namespace:  "default",
+			Name:       "ep01",
+		},
+		Reason:        reason,
+		Type:          eventType,
+		Count:         count,
+		LastTimestamp: metav1.Now(),
+		Source:        corev1.EventSource{Component: "everoute-agent", Host: "node01"},
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	RegisterTestingT(t)
+
+	notification := NewNotification(newEvent("e01", portscan.PortScanEventReason, corev1.EventTypeWarning, 1))
+	Expect(notification).ShouldNot(BeNil())
+	Expect(notification.Type).Should(Equal(NotificationTypePolicyViolation))
+	Expect(notification.Object.Name).Should(Equal("ep01"))
+	Expect(notification.Source).Should(Equal("everoute-agent"))
+	Expect(notification.Host).Should(Equal("node01"))
+
+	Expect(NewNotification(newEvent("e02", quarantine.ReasonFailed, corev1.EventTypeWarning, 1)).Type).Should(Equal(NotificationTypeRealizationFailure))
+	Expect(NewNotification(newEvent("e03", "SyncFailed", corev1.EventTypeWarning, 1)).Type).Should(Equal(NotificationTypeRealizationFailure))
+	Expect(NewNotification(newEvent("e04", "SyncFailed", corev1.EventTypeNormal, 1))).Should(BeNil())
+	Expect(NewNotification(newEvent("e05", "Scheduled", corev1.EventTypeNormal, 1))).Should(BeNil())
+}
+
+func TestEventReconcile(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	r := newReceiver("secret", 0, 0)
+	defer r.Close()
+
+	scanEvent := newEvent("scan", portscan.PortScanEventReason, corev1.EventTypeWarning, 1)
+	staleEvent := newEvent("stale", portscan.PortScanEventReason, corev1.EventTypeWarning, 1)
+	staleEvent.LastTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	scheme := runtime.NewScheme()
+	Expect(corev1.AddToScheme(scheme)).Should(Succeed())
+	k8sClient := fake.NewFakeClientWithScheme(scheme, scanEvent, staleEvent)
+	reconciler := &EventReconciler{
+		Client:    k8sClient,
+		Notifier:  newNotifier(r.URL),
+		startTime: time.Now().Add(-time.Minute),
+		notified:  make(map[k8stypes.NamespacedName]int32),
+	}
+	reconcile := func(name string) {
+		_, err := reconciler.Reconcile(ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: name}})
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	reconcile("scan")
+	Expect(r.notifications).Should(Receive())
+
+	reconcile("scan")
+	Expect(r.notifications).ShouldNot(Receive())
+
+	scanEvent.Count = 2
+	Expect(k8sClient.Update(ctx, scanEvent)).Should(Succeed())
+	reconcile("scan")
+	Expect(r.notifications).Should(Receive())
+
+	reconcile("stale")
+	Expect(r.notifications).ShouldNot(Receive())
+
+	Expect(k8sClient.Delete(ctx, scanEvent)).Should(Succeed())
+	reconcile("scan")
+	Expect(reconciler.notified).ShouldNot(HaveKey(k8stypes.NamespacedName{Namespace: "default", Name: "scan"}))
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+)
+
+// AppliedPoliciesReconcile maintains the status.appliedPolicies of the Endpoint, the reverse
+// index of the SecurityPolicies appliedTo. A policy applies to an endpoint when the endpoint
+// is a member of any appliedTo group of the policy.
+func (r *Reconciler) AppliedPoliciesReconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+
+	endpoint := securityv1alpha1.Endpoint{}
+	if err := r.Get(ctx, req.NamespacedName, &endpoint); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	appliedPolicies, err := r.listAppliedPolicies(ctx, &endpoint)
+	if err != nil {
+		klog.Errorf("unable list policies applied to endpoint %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	if reflect.DeepEqual(endpoint.Status.AppliedPolicies, appliedPolicies) {
+		return ctrl.Result{}, nil
+	}
+
+	endpoint.Status.AppliedPolicies = appliedPolicies
+	if err := r.Status().Update(ctx, &endpoint); err != nil {
+		klog.Errorf("failed to update endpoint %s applied policies: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+	klog.V(2).Infof("endpoint %s applied policies has been update to: %v", req.NamespacedName, appliedPolicies)
+
+	return ctrl.Result{}, nil
+}
+
+// listAppliedPolicies returns the policies applied to the endpoint sorted by namespace and name.
+func (r *Reconciler) listAppliedPolicies(ctx context.Context, endpoint *securityv1alpha1.Endpoint) ([]securityv1alpha1.NamespacedName, error) {
+	key := endpointReferenceKey(endpoint.Spec.Reference.ExternalIDName, endpoint.Spec.Reference.ExternalIDValue)
+	groupMembersList := groupv1alpha1.GroupMembersList{}
+	err := r.List(ctx, &groupMembersList, client.MatchingFields{constants.GroupMembersByEndpointIndex: key})
+	if err != nil {
+		return nil, err
+	}
+	// the endpoint might be added to the groups by the patches not compacted yet
+	groups, err := groupctrl.PatchedGroups(ctx, r.Client, func(member *groupv1alpha1.GroupMember) bool {
+		return memberKey(*member) == key
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, groupMembers := range groupMembersList.Items {
+		groups.Insert(groupMembers.Name)
+	}
+
+	var appliedPolicies []securityv1alpha1.NamespacedName
+	policySet := sets.NewString()
+
+	for _, group := range groups.List() {
+		// the patches not compacted might have removed the endpoint from the group found by the index
+		groupMembers, err := groupctrl.GetGroupMembers(ctx, r.Client, group)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err != nil || !endpointReferenceSet(groupMembers.GroupMembers).Has(key) {
+			continue
+		}
+
+		policyList := securityv1alpha1.SecurityPolicyList{}
+		err = r.List(ctx, &policyList, client.MatchingFields{
+			constants.SecurityPolicyByAppliedGroupIndex: group,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policyList.Items {
+			name := securityv1alpha1.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}
+			if !policySet.Has(name.String()) {
+				policySet.Insert(name.String())
+				appliedPolicies = append(appliedPolicies, name)
+			}
+		}
+	}
+
+	sort.Slice(appliedPolicies, func(i, j int) bool {
+		return appliedPolicies[i].String() < appliedPolicies[j].String()
+	})
+	return appliedPolicies, nil
+}
+
+func (r *Reconciler) addEndpoint(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+		Namespace: e.Meta.GetNamespace(),
+		Name:      e.Meta.GetName(),
+	}})
+}
+
+func (r *Reconciler) updateEndpoint(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	newEndpoint := e.ObjectNew.(*securityv1alpha1.Endpoint)
+	oldEndpoint := e.ObjectOld.(*securityv1alpha1.Endpoint)
+
+	// the group members reference the endpoint by the reference
+	if newEndpoint.Spec.Reference != oldEndpoint.Spec.Reference {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: newEndpoint.GetNamespace(),
+			Name:      newEndpoint.GetName(),
+		}})
+	}
+}
+
+func (r *Reconciler) addGroupMembers(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	groupMembers := e.Object.(*groupv1alpha1.GroupMembers)
+	r.enqueueMembersIfApplied(groupMembers.Name, groupMembers.GroupMembers, q)
+}
+
+func (r *Reconciler) updateGroupMembers(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	newGroupMembers := e.ObjectNew.(*groupv1alpha1.GroupMembers)
+	oldGroupMembers := e.ObjectOld.(*groupv1alpha1.GroupMembers)
+
+	// only the members added or removed need to update
+	newMembers := endpointReferenceSet(newGroupMembers.GroupMembers)
+	oldMembers := endpointReferenceSet(oldGroupMembers.GroupMembers)
+	var changedMembers []groupv1alpha1.GroupMember
+	for _, member := range newGroupMembers.GroupMembers {
+		if !oldMembers.Has(memberKey(member)) {
+			changedMembers = append(changedMembers, member)
+		}
+	}
+	for _, member := range oldGroupMembers.GroupMembers {
+		if !newMembers.Has(memberKey(member)) {
+			changedMembers = append(changedMembers, member)
+		}
+	}
+
+	r.enqueueMembersIfApplied(newGroupMembers.Name, changedMembers, q)
+}
+
+func (r *Reconciler) deleteGroupMembers(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	groupMembers := e.Object.(*groupv1alpha1.GroupMembers)
+	r.enqueueMembersIfApplied(groupMembers.Name, groupMembers.GroupMembers, q)
+}
+
+// addGroupMembersPatch enqueue the members added or removed by the patch, the groupmembers
+// are not updated on each change when the patches compacted.
+func (r *Reconciler) addGroupMembersPatch(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	patch := e.Object.(*groupv1alpha1.GroupMembersPatch)
+	changedMembers := append(append([]groupv1alpha1.GroupMember(nil), patch.AddedGroupMembers...), patch.RemovedGroupMembers...)
+	r.enqueueMembersIfApplied(patch.AppliedToGroupMembers.Name, changedMembers, q)
+}
+
+func (r *Reconciler) addAppliedPolicy(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	policy := e.Object.(*securityv1alpha1.SecurityPolicy)
+	r.enqueueGroupsMembers(AppliedGroupIndexSecurityPolicyFunc(policy), q)
+}
+
+func (r *Reconciler) updateAppliedPolicy(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	newGroups := sets.NewString(AppliedGroupIndexSecurityPolicyFunc(e.ObjectNew)...)
+	oldGroups := sets.NewString(AppliedGroupIndexSecurityPolicyFunc(e.ObjectOld)...)
+
+	// the members of the groups both in old and new policy are unchanged
+	r.enqueueGroupsMembers(newGroups.Difference(oldGroups).Union(oldGroups.Difference(newGroups)).List(), q)
+}
+
+func (r *Reconciler) deleteAppliedPolicy(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	policy := e.Object.(*securityv1alpha1.SecurityPolicy)
+	r.enqueueGroupsMembers(AppliedGroupIndexSecurityPolicyFunc(policy), q)
+}
+
+// enqueueMembersIfApplied enqueue the endpoints of the members, if the group is an appliedTo
+// group of any policy, e.g. the members of the peer only groups are ignored.
+func (r *Reconciler) enqueueMembersIfApplied(groupName string, members []groupv1alpha1.GroupMember, q workqueue.RateLimitingInterface) {
+	if len(members) == 0 {
+		return
+	}
+
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	err := r.List(context.Background(), &policyList, client.MatchingFields{
+		constants.SecurityPolicyByAppliedGroupIndex: groupName,
+	})
+	if err != nil {
+		klog.Errorf("list of SecurityPolicies applied to EndpointGroup %s: %s", groupName, err)
+		return
+	}
+	if len(policyList.Items) == 0 {
+		return
+	}
+
+	r.enqueueMembers(members, q)
+}
+
+func (r *Reconciler) enqueueGroupsMembers(groupNames []string, q workqueue.RateLimitingInterface) {
+	for _, groupName := range groupNames {
+		groupMembers, err := groupctrl.GetGroupMembers(context.Background(), r.Client, groupName)
+		if err != nil {
+			// the members are enqueued when the groupmembers created
+			if client.IgnoreNotFound(err) != nil {
+				klog.Errorf("unable get groupmembers %s: %s", groupName, err)
+			}
+			continue
+		}
+		r.enqueueMembers(groupMembers.GroupMembers, q)
+	}
+}
+
+func (r *Reconciler) enqueueMembers(members []groupv1alpha1.GroupMember, q workqueue.RateLimitingInterface) {
+	for _, member := range members {
+		endpointList := securityv1alpha1.EndpointList{}
+		err := r.List(context.Background(), &endpointList, client.MatchingFields{
+			constants.EndpointByReferenceIndex: memberKey(member),
+		})
+		if err != nil {
+			klog.Errorf("list of Endpoints with reference %s: %s", memberKey(member), err)
+			continue
+		}
+		for _, endpoint := range endpointList.Items {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: endpoint.GetNamespace(),
+				Name:      endpoint.GetName(),
+			}})
+		}
+	}
+}
+
+// AppliedGroupIndexSecurityPolicyFunc return the appliedTo EndpointGroup names of the SecurityPolicy
+func AppliedGroupIndexSecurityPolicyFunc(o runtime.Object) []string {
+	policy := o.(*securityv1alpha1.SecurityPolicy)
+	groupSet := sets.NewString()
+
+	for _, appliedTo := range policy.Spec.AppliedTo {
+		group := appliedAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, appliedTo)
+		if group != nil {
+			groupSet.Insert(group.GetName())
+		}
+	}
+
+	return groupSet.List()
+}
+
+// EndpointIndexGroupMembersFunc return the endpoint references of the GroupMembers
+func EndpointIndexGroupMembersFunc(o runtime.Object) []string {
+	return endpointReferenceSet(o.(*groupv1alpha1.GroupMembers).GroupMembers).List()
+}
+
+// ReferenceIndexEndpointFunc return the reference of the Endpoint
+func ReferenceIndexEndpointFunc(o runtime.Object) []string {
+	endpoint := o.(*securityv1alpha1.Endpoint)
+	return []string{endpointReferenceKey(endpoint.Spec.Reference.ExternalIDName, endpoint.Spec.Reference.ExternalIDValue)}
+}
+
+func endpointReferenceSet(members []groupv1alpha1.GroupMember) sets.String {
+	referenceSet := sets.NewString()
+	for _, member := range members {
+		referenceSet.Insert(memberKey(member))
+	}
+	return referenceSet
+}
+
+func memberKey(member groupv1alpha1.GroupMember) string {
+	return endpointReferenceKey(member.EndpointReference.ExternalIDName, member.EndpointReference.ExternalIDValue)
+}
+
+func endpointReferenceKey(externalIDName, externalIDValue string) string {
+	return externalIDName + "/" + externalIDValue
+}
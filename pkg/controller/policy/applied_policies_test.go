@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+var _ = Describe("AppliedPolicies", func() {
+	var ctx context.Context
+	var namespace string
+	var endpoint *securityv1alpha1.Endpoint
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		namespaceList := corev1.NamespaceList{}
+		Expect(k8sClient.List(ctx, &namespaceList)).Should(Succeed())
+		// run test in rand namespace
+		namespace = namespaceList.Items[rand.IntnRange(0, len(namespaceList.Items))].GetName()
+
+		endpoint = new(securityv1alpha1.Endpoint)
+		endpoint.Name = rand.String(10)
+		endpoint.Namespace = namespace
+		endpoint.Spec.Reference = securityv1alpha1.EndpointReference{
+			ExternalIDName:  "iface-id",
+			ExternalIDValue: rand.String(10),
+		}
+		By(fmt.Sprintf("create Endpoint %+v", endpoint))
+		Expect(k8sClient.Create(ctx, endpoint)).Should(Succeed())
+	})
+	AfterEach(func() {
+		By("delete all SecurityPolicies")
+		Expect(k8sClient.DeleteAllOf(ctx, &securityv1alpha1.SecurityPolicy{}, client.InNamespace(namespace))).Should(Succeed())
+		By("delete all GroupMembersPatches")
+		Expect(k8sClient.DeleteAllOf(ctx, &groupv1alpha1.GroupMembersPatch{})).Should(Succeed())
+		By("delete all GroupMembers")
+		Expect(k8sClient.DeleteAllOf(ctx, &groupv1alpha1.GroupMembers{})).Should(Succeed())
+		By("delete all EndpointGroups")
+		Expect(k8sClient.DeleteAllOf(ctx, &groupv1alpha1.EndpointGroup{})).Should(Succeed())
+		By("delete all Endpoints")
+		Expect(k8sClient.DeleteAllOf(ctx, &securityv1alpha1.Endpoint{}, client.InNamespace(namespace))).Should(Succeed())
+	})
+
+	When("create SecurityPolicy applied to the Endpoint", func() {
+		var policy *securityv1alpha1.SecurityPolicy
+
+		BeforeEach(func() {
+			policy = newTestPolicyWithoutRule(namespace, nil, &endpoint.Name)
+			By(fmt.Sprintf("create SecurityPolicy %+v", policy))
+			Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+
+			By("create the GroupMembers of the applied group")
+			for _, group := range policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy) {
+				createGroupMembersOfEndpoint(ctx, group, endpoint)
+			}
+		})
+		It("should index the policy in the endpoint status", func() {
+			assertAppliedPolicies(ctx, endpoint, securityv1alpha1.NamespacedName{Namespace: namespace, Name: policy.Name})
+		})
+
+		When("create another SecurityPolicy with the same applied group", func() {
+			var anotherPolicy *securityv1alpha1.SecurityPolicy
+
+			BeforeEach(func() {
+				anotherPolicy = newTestPolicyWithoutRule(namespace, nil, &endpoint.Name)
+				By(fmt.Sprintf("create SecurityPolicy %+v", anotherPolicy))
+				Expect(k8sClient.Create(ctx, anotherPolicy)).Should(Succeed())
+			})
+			It("should index both the policies in the endpoint status", func() {
+				assertAppliedPolicies(ctx, endpoint,
+					securityv1alpha1.NamespacedName{Namespace: namespace, Name: policy.Name},
+					securityv1alpha1.NamespacedName{Namespace: namespace, Name: anotherPolicy.Name},
+				)
+			})
+		})
+
+		When("remove the Endpoint from the applied group by a patch not compacted", func() {
+			BeforeEach(func() {
+				for _, group := range policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy) {
+					patch := new(groupv1alpha1.GroupMembersPatch)
+					patch.Name = rand.String(10)
+					patch.Labels = map[string]string{constants.OwnerGroupLabelKey: group}
+					patch.AppliedToGroupMembers = groupv1alpha1.GroupMembersReference{Name: group}
+					patch.RemovedGroupMembers = []groupv1alpha1.GroupMember{{
+						EndpointReference: groupv1alpha1.EndpointReference{
+							ExternalIDName:  endpoint.Spec.Reference.ExternalIDName,
+							ExternalIDValue: endpoint.Spec.Reference.ExternalIDValue,
+						},
+					}}
+					By(fmt.Sprintf("create GroupMembersPatch %+v", patch))
+					Expect(k8sClient.Create(ctx, patch)).Should(Succeed())
+				}
+			})
+			It("should remove the policy from the endpoint status", func() {
+				assertAppliedPolicies(ctx, endpoint)
+			})
+		})
+
+		When("delete the SecurityPolicy", func() {
+			BeforeEach(func() {
+				By(fmt.Sprintf("delete SecurityPolicy %+v", policy))
+				Expect(k8sClient.Delete(ctx, policy)).Should(Succeed())
+			})
+			It("should remove the policy from the endpoint status", func() {
+				assertAppliedPolicies(ctx, endpoint)
+			})
+		})
+	})
+
+	When("create SecurityPolicy with the Endpoint as peer only", func() {
+		BeforeEach(func() {
+			policy := newTestPolicyWithoutRule(namespace, labels.FromLabelSelector(newRandomSelector()), nil)
+			peerGroup := policyctrl.PeerAsEndpointGroup(namespace, "", securityv1alpha1.SecurityPolicyPeer{
+				Endpoint: &securityv1alpha1.NamespacedName{Namespace: namespace, Name: endpoint.Name},
+			})
+			policy.Spec.IngressRules = []securityv1alpha1.Rule{{
+				Name: "ingress",
+				From: []securityv1alpha1.SecurityPolicyPeer{{
+					Endpoint: &securityv1alpha1.NamespacedName{Namespace: namespace, Name: endpoint.Name},
+				}},
+			}}
+			By(fmt.Sprintf("create SecurityPolicy %+v", policy))
+			Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+			createGroupMembersOfEndpoint(ctx, peerGroup.Name, endpoint)
+		})
+		It("should not index the policy in the endpoint status", func() {
+			Consistently(func() []securityv1alpha1.NamespacedName {
+				return getAppliedPolicies(ctx, endpoint)
+			}, timeout/5, interval).Should(BeEmpty())
+		})
+	})
+})
+
+func createGroupMembersOfEndpoint(ctx context.Context, group string, endpoint *securityv1alpha1.Endpoint) {
+	groupMembers := new(groupv1alpha1.GroupMembers)
+	groupMembers.Name = group
+	groupMembers.GroupMembers = []groupv1alpha1.GroupMember{{
+		EndpointReference: groupv1alpha1.EndpointReference{
+			ExternalIDName:  endpoint.Spec.Reference.ExternalIDName,
+			ExternalIDValue: endpoint.Spec.Reference.ExternalIDValue,
+		},
+	}}
+	Expect(k8sClient.Create(ctx, groupMembers)).Should(Succeed())
+}
+
+func getAppliedPolicies(ctx context.Context, endpoint *securityv1alpha1.Endpoint) []securityv1alpha1.NamespacedName {
+	ep := securityv1alpha1.Endpoint{}
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}, &ep)).Should(Succeed())
+	return ep.Status.AppliedPolicies
+}
+
+func assertAppliedPolicies(ctx context.Context, endpoint *securityv1alpha1.Endpoint, policies ...securityv1alpha1.NamespacedName) {
+	Eventually(func() []securityv1alpha1.NamespacedName {
+		return getAppliedPolicies(ctx, endpoint)
+	}, timeout, interval).Should(ConsistOf(policies))
+}
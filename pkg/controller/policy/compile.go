@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/webhook/validates"
+)
+
+const (
+	// ReasonCompileFailed is the reason of the condition Compiled=False.
+	ReasonCompileFailed = "CompileFailed"
+	// ReasonCompileSucceeded is the reason of the condition Compiled=True.
+	ReasonCompileSucceeded = "CompileSucceeded"
+)
+
+// CompileExistingPolicies dry-run compiles all the existing SecurityPolicies, it should be
+// called on the controller start before serving webhooks. The policies created before may
+// no longer compile after the API or the validation changed, they would be failed silently
+// at distribution time otherwise. The condition Compiled is written on each policy, it is
+// kept up to date on each generation by the policy status controller afterwards.
+func CompileExistingPolicies(ctx context.Context, reader client.Reader, writer client.StatusWriter) error {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := reader.List(ctx, &policyList); err != nil {
+		return fmt.Errorf("list SecurityPolicies: %s", err)
+	}
+
+	var errList []error
+	for item := range policyList.Items {
+		policy := &policyList.Items[item]
+		condition := compiledCondition(policy)
+		if !conditionChanged(policy.Status.Conditions, condition) {
+			continue
+		}
+		if condition.Status == metav1.ConditionFalse {
+			klog.Errorf("SecurityPolicy %s/%s no longer compile: %s", policy.Namespace, policy.Name, condition.Message)
+		}
+		meta.SetStatusCondition(&policy.Status.Conditions, *condition)
+		if err := writer.Update(ctx, policy); err != nil {
+			errList = append(errList, fmt.Errorf("update SecurityPolicy %s/%s status: %s", policy.Namespace, policy.Name, err))
+		}
+	}
+	return errors.NewAggregate(errList)
+}
+
+// CompilePolicy validates the policy as the webhook does and the EndpointGroups generated
+// from it, without writing anything.
+func CompilePolicy(policy *securityv1alpha1.SecurityPolicy) error {
+	if err := validates.ValidateSecurityPolicy(policy); err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, applied := range policy.Spec.AppliedTo {
+		if group := appliedAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, applied); group != nil {
+			if err := validates.ValidateEndpointGroupSpec(&group.Spec); err != nil {
+				errList = append(errList, fmt.Errorf("error format of appliedTo group: %s", err))
+			}
+		}
+	}
+	for _, rule := range append(policy.Spec.IngressRules, policy.Spec.EgressRules...) {
+		for _, peer := range append(rule.From, rule.To...) {
+			if group := PeerAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, peer); group != nil {
+				if err := validates.ValidateEndpointGroupSpec(&group.Spec); err != nil {
+					errList = append(errList, fmt.Errorf("error format of rule %s peer group: %s", rule.Name, err))
+				}
+			}
+		}
+	}
+	return errors.NewAggregate(errList)
+}
+
+// compiledCondition returns the condition Compiled of the current generation of the policy.
+func compiledCondition(policy *securityv1alpha1.SecurityPolicy) *metav1.Condition {
+	condition := &metav1.Condition{
+		Type:               securityv1alpha1.SecurityPolicyCompiled,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: policy.GetGeneration(),
+		Reason:             ReasonCompileSucceeded,
+		Message:            "policy compiled successfully",
+	}
+	if err := CompilePolicy(policy); err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonCompileFailed
+		condition.Message = err.Error()
+	}
+	return condition
+}
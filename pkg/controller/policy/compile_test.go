@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+var _ = Describe("PolicyCompiled", func() {
+	var ctx context.Context
+	var namespace string
+	var policy *securityv1alpha1.SecurityPolicy
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		namespaceList := corev1.NamespaceList{}
+		Expect(k8sClient.List(ctx, &namespaceList)).Should(Succeed())
+		// run test in rand namespace
+		namespace = namespaceList.Items[rand.IntnRange(0, len(namespaceList.Items))].GetName()
+
+		policy = newTestPolicyWithoutRule(namespace, labels.FromLabelSelector(newRandomSelector()), nil)
+		policy.Spec.Tier = constants.Tier2
+	})
+	AfterEach(func() {
+		By("delete all SecurityPolicies")
+		Expect(k8sClient.DeleteAllOf(ctx, &securityv1alpha1.SecurityPolicy{}, client.InNamespace(namespace))).Should(Succeed())
+	})
+
+	When("the SecurityPolicy compiles", func() {
+		BeforeEach(func() {
+			By(fmt.Sprintf("create SecurityPolicy %+v", policy))
+			Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+		})
+		It("should write the condition compiled true", func() {
+			assertCompiledCondition(ctx, policy, metav1.ConditionTrue, policyctrl.ReasonCompileSucceeded)
+		})
+	})
+
+	When("the SecurityPolicy no longer compiles", func() {
+		BeforeEach(func() {
+			policy.Spec.Tier = "Tier_Unknown"
+			By(fmt.Sprintf("create SecurityPolicy %+v", policy))
+			Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+		})
+		It("should write the condition compiled false", func() {
+			assertCompiledCondition(ctx, policy, metav1.ConditionFalse, policyctrl.ReasonCompileFailed)
+		})
+
+		When("the SecurityPolicy has been fixed", func() {
+			BeforeEach(func() {
+				assertCompiledCondition(ctx, policy, metav1.ConditionFalse, policyctrl.ReasonCompileFailed)
+				Eventually(func() error {
+					p := securityv1alpha1.SecurityPolicy{}
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: policy.Name}, &p)).Should(Succeed())
+					p.Spec.Tier = constants.Tier2
+					return k8sClient.Update(ctx, &p)
+				}, timeout, interval).Should(Succeed())
+			})
+			It("should write the condition compiled true without restart", func() {
+				assertCompiledCondition(ctx, policy, metav1.ConditionTrue, policyctrl.ReasonCompileSucceeded)
+			})
+		})
+
+		It("should write the condition compiled false on the controller start", func() {
+			Eventually(func() error {
+				return policyctrl.CompileExistingPolicies(ctx, k8sClient, k8sClient.Status())
+			}, timeout, interval).Should(Succeed())
+			assertCompiledCondition(ctx, policy, metav1.ConditionFalse, policyctrl.ReasonCompileFailed)
+		})
+	})
+})
+
+// assertCompiledCondition asserts the compiled condition of the current generation of the policy.
+func assertCompiledCondition(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, status metav1.ConditionStatus, reason string) {
+	Eventually(func() bool {
+		p := securityv1alpha1.SecurityPolicy{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, &p); err != nil {
+			return false
+		}
+		condition := meta.FindStatusCondition(p.Status.Conditions, securityv1alpha1.SecurityPolicyCompiled)
+		return condition != nil && condition.Status == status && condition.Reason == reason && condition.ObservedGeneration == p.Generation
+	}, timeout, interval).Should(BeTrue())
+}
@@ -130,7 +130,7 @@ func (r *Reconciler) deleteSecurityPolicy(e event.DeleteEvent, q workqueue.RateL
 
 func (r *Reconciler) getEndpointGroupFromSecurityPolicy(policy *securityv1alpha1.SecurityPolicy, groupName string) *groupv1alpha1.EndpointGroup {
 	for _, appliedTo := range policy.Spec.AppliedTo {
-		group := appliedAsEndpointGroup(policy.GetNamespace(), appliedTo)
+		group := appliedAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, appliedTo)
 		if group != nil && group.GetName() == groupName {
 			return group
 		}
@@ -138,7 +138,7 @@ func (r *Reconciler) getEndpointGroupFromSecurityPolicy(policy *securityv1alpha1
 
 	for _, rule := range policy.Spec.IngressRules {
 		for _, peer := range rule.From {
-			group := PeerAsEndpointGroup(policy.GetNamespace(), peer)
+			group := PeerAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, peer)
 			if group != nil && group.GetName() == groupName {
 				return group
 			}
@@ -147,13 +147,13 @@ func (r *Reconciler) getEndpointGroupFromSecurityPolicy(policy *securityv1alpha1
 
 	for _, rule := range policy.Spec.EgressRules {
 		if isNamedPortExists(rule.Ports) && len(rule.To) == 0 {
-			group := GetAllEpWithNamedPortGroup()
+			group := GetAllEpWithNamedPortGroup(policy.Spec.VRF)
 			if group.GetName() == groupName {
 				return group
 			}
 		}
 		for _, peer := range rule.To {
-			group := PeerAsEndpointGroup(policy.GetNamespace(), peer)
+			group := PeerAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, peer)
 			if group != nil && group.GetName() == groupName {
 				return group
 			}
@@ -163,10 +163,29 @@ func (r *Reconciler) getEndpointGroupFromSecurityPolicy(policy *securityv1alpha1
 	return nil
 }
 
-func GetAllEpWithNamedPortGroup() *groupv1alpha1.EndpointGroup {
+// GetAllEpWithNamedPortGroup returns the group of all the endpoints with named port in the vrf,
+// the group of the default vrf keeps the name AllEpWithNamedPort.
+func GetAllEpWithNamedPortGroup(vrf string) *groupv1alpha1.EndpointGroup {
 	group := new(groupv1alpha1.EndpointGroup)
 	group.Name = constants.AllEpWithNamedPort
+	if vrf != "" {
+		group.Name = fmt.Sprintf("%s-%s", constants.AllEpWithNamedPort, vrf)
+	}
+	group.Spec.EndpointSelector = &labels.Selector{}
+	group.Spec.VRF = vrf
+	return group
+}
+
+// GetClusterInternalGroup returns the group of all the endpoints in the vrf, which the builtin
+// peers computed from, the group of the default vrf named ClusterInternalEndpoints.
+func GetClusterInternalGroup(vrf string) *groupv1alpha1.EndpointGroup {
+	group := new(groupv1alpha1.EndpointGroup)
+	group.Name = constants.ClusterInternalEndpoints
+	if vrf != "" {
+		group.Name = fmt.Sprintf("%s-%s", constants.ClusterInternalEndpoints, vrf)
+	}
 	group.Spec.EndpointSelector = &labels.Selector{}
+	group.Spec.VRF = vrf
 	return group
 }
 
@@ -176,7 +195,7 @@ func EndpointGroupIndexSecurityPolicyFunc(o runtime.Object) []string {
 	groupSet := sets.NewString()
 
 	for _, appliedTo := range policy.Spec.AppliedTo {
-		group := appliedAsEndpointGroup(policy.GetNamespace(), appliedTo)
+		group := appliedAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, appliedTo)
 		if group != nil {
 			groupSet.Insert(group.GetName())
 		}
@@ -184,7 +203,7 @@ func EndpointGroupIndexSecurityPolicyFunc(o runtime.Object) []string {
 
 	for _, rule := range policy.Spec.IngressRules {
 		for _, peer := range rule.From {
-			group := PeerAsEndpointGroup(policy.GetNamespace(), peer)
+			group := PeerAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, peer)
 			if group != nil {
 				groupSet.Insert(group.GetName())
 			}
@@ -196,11 +215,11 @@ func EndpointGroupIndexSecurityPolicyFunc(o runtime.Object) []string {
 		// reuses the AllEndpointsGroup matching all Endpoints in all Namespaces,
 		// such that it can be used to resolve the named ports.
 		if isNamedPortExists(rule.Ports) && len(rule.To) == 0 {
-			groupSet.Insert(GetAllEpWithNamedPortGroup().GetName())
+			groupSet.Insert(GetAllEpWithNamedPortGroup(policy.Spec.VRF).GetName())
 			continue
 		}
 		for _, peer := range rule.To {
-			group := PeerAsEndpointGroup(policy.GetNamespace(), peer)
+			group := PeerAsEndpointGroup(policy.GetNamespace(), policy.Spec.VRF, peer)
 			if group != nil {
 				groupSet.Insert(group.GetName())
 			}
@@ -210,13 +229,23 @@ func EndpointGroupIndexSecurityPolicyFunc(o runtime.Object) []string {
 	return groupSet.List()
 }
 
-func PeerAsEndpointGroup(namespace string, peer securityv1alpha1.SecurityPolicyPeer) *groupv1alpha1.EndpointGroup {
-	if peer.EndpointSelector == nil && peer.NamespaceSelector == nil && peer.Endpoint == nil {
+// PeerAsEndpointGroup returns the group of the endpoints selected by the peer in the vrf.
+func PeerAsEndpointGroup(namespace, vrf string, peer securityv1alpha1.SecurityPolicyPeer) *groupv1alpha1.EndpointGroup {
+	if peer.Builtin != "" {
+		// the builtin peers are computed from all the endpoints in the vrf
+		return GetClusterInternalGroup(vrf)
+	}
+	if peer.EndpointSelector == nil && peer.NamespaceSelector == nil && peer.Endpoint == nil && peer.ServiceAccount == "" {
 		return nil
 	}
 
 	group := new(groupv1alpha1.EndpointGroup)
 
+	if peer.ServiceAccount != "" {
+		// the pods running under the service account are labeled by the pod controller
+		peer.EndpointSelector = serviceAccountSelector(peer.EndpointSelector, peer.ServiceAccount)
+	}
+
 	if peer.NamespaceSelector != nil {
 		endpointSelector := peer.EndpointSelector
 		if peer.EndpointSelector == nil {
@@ -241,20 +270,38 @@ func PeerAsEndpointGroup(namespace string, peer securityv1alpha1.SecurityPolicyP
 	if peer.Endpoint != nil {
 		group.Spec.Endpoint = peer.Endpoint.DeepCopy()
 	}
+	group.Spec.VRF = vrf
+	group.Spec.VLANs = peer.VLANs
 
 	group.Name = GenerateGroupName(&group.Spec)
 
 	return group
 }
 
-func appliedAsEndpointGroup(namespace string, applied securityv1alpha1.ApplyToPeer) *groupv1alpha1.EndpointGroup {
+// serviceAccountSelector returns the selector limits the endpoints selected to the pods
+// running under the service account.
+func serviceAccountSelector(selector *labels.Selector, serviceAccount string) *labels.Selector {
+	if selector == nil {
+		selector = new(labels.Selector)
+	} else {
+		selector = selector.DeepCopy()
+	}
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = make(map[string]string, 1)
+	}
+	selector.MatchLabels[constants.ServiceAccountLabelKey] = serviceAccount
+	return selector
+}
+
+func appliedAsEndpointGroup(namespace, vrf string, applied securityv1alpha1.ApplyToPeer) *groupv1alpha1.EndpointGroup {
 	securityPolicyPeer := AppliedAsSecurityPeer(namespace, applied)
-	return PeerAsEndpointGroup(namespace, securityPolicyPeer)
+	return PeerAsEndpointGroup(namespace, vrf, securityPolicyPeer)
 }
 
 func AppliedAsSecurityPeer(namespace string, applied securityv1alpha1.ApplyToPeer) securityv1alpha1.SecurityPolicyPeer {
 	securityPolicyPeer := securityv1alpha1.SecurityPolicyPeer{
 		EndpointSelector: applied.EndpointSelector,
+		VLANs:            applied.VLANs,
 	}
 
 	if applied.Endpoint != nil {
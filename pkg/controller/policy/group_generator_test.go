@@ -31,6 +31,8 @@ import (
 
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
 	"github.com/everoute/everoute/pkg/labels"
 )
 
@@ -288,6 +290,74 @@ var _ = Describe("GroupGenerator", func() {
 	})
 })
 
+var _ = Describe("PeerAsEndpointGroup", func() {
+	var peer securityv1alpha1.SecurityPolicyPeer
+
+	BeforeEach(func() {
+		peer = securityv1alpha1.SecurityPolicyPeer{EndpointSelector: labels.FromLabelSelector(newRandomSelector())}
+	})
+
+	It("should keep the group name of the default vrf", func() {
+		namespace := metav1.NamespaceDefault
+		group := policyctrl.PeerAsEndpointGroup(namespace, "", peer)
+		Expect(group.Name).Should(Equal(policyctrl.GenerateGroupName(&groupv1alpha1.EndpointGroupSpec{
+			EndpointSelector: peer.EndpointSelector,
+			Namespace:        &namespace,
+		})))
+		Expect(policyctrl.GetAllEpWithNamedPortGroup("").Name).Should(Equal(constants.AllEpWithNamedPort))
+	})
+
+	It("should generate different groups for the vrfs", func() {
+		groupA := policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "tenant-a", peer)
+		groupB := policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "tenant-b", peer)
+		Expect(groupA.Spec.VRF).Should(Equal("tenant-a"))
+		Expect(groupA.Name).ShouldNot(Equal(groupB.Name))
+		Expect(groupA.Name).ShouldNot(Equal(policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "", peer).Name))
+
+		allEpGroup := policyctrl.GetAllEpWithNamedPortGroup("tenant-a")
+		Expect(allEpGroup.Name).Should(Equal(constants.AllEpWithNamedPort + "-tenant-a"))
+		Expect(allEpGroup.Spec.VRF).Should(Equal("tenant-a"))
+	})
+
+	It("should generate different groups for the vlans", func() {
+		vlanPeer := *peer.DeepCopy()
+		vlanPeer.VLANs = []int32{100}
+		group := policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "", vlanPeer)
+		Expect(group.Spec.VLANs).Should(Equal([]int32{100}))
+		Expect(group.Name).ShouldNot(Equal(policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "", peer).Name))
+
+		applied := securityv1alpha1.ApplyToPeer{EndpointSelector: peer.EndpointSelector, VLANs: []int32{100}}
+		Expect(policyctrl.AppliedAsSecurityPeer(metav1.NamespaceDefault, applied).VLANs).Should(Equal([]int32{100}))
+	})
+
+	It("should select the endpoints of the service account", func() {
+		namespace := metav1.NamespaceDefault
+		group := policyctrl.PeerAsEndpointGroup(namespace, "", securityv1alpha1.SecurityPolicyPeer{ServiceAccount: "backend"})
+		Expect(group.Spec.Namespace).Should(Equal(&namespace))
+		Expect(group.Spec.EndpointSelector.MatchLabels).Should(Equal(map[string]string{constants.ServiceAccountLabelKey: "backend"}))
+
+		saPeer := *peer.DeepCopy()
+		saPeer.ServiceAccount = "backend"
+		group = policyctrl.PeerAsEndpointGroup(namespace, "", saPeer)
+		Expect(group.Spec.EndpointSelector.MatchLabels).Should(HaveKeyWithValue(constants.ServiceAccountLabelKey, "backend"))
+		Expect(group.Name).ShouldNot(Equal(policyctrl.PeerAsEndpointGroup(namespace, "", peer).Name))
+		Expect(peer.EndpointSelector.MatchLabels).ShouldNot(HaveKey(constants.ServiceAccountLabelKey))
+	})
+
+	It("should generate the cluster internal group for the builtin peers", func() {
+		internal := securityv1alpha1.SecurityPolicyPeer{Builtin: securityv1alpha1.BuiltinPeerClusterInternal}
+		external := securityv1alpha1.SecurityPolicyPeer{Builtin: securityv1alpha1.BuiltinPeerClusterExternal}
+		Expect(policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "", internal).Name).Should(Equal(constants.ClusterInternalEndpoints))
+		Expect(policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "", external).Name).Should(Equal(constants.ClusterInternalEndpoints))
+
+		group := policyctrl.PeerAsEndpointGroup(metav1.NamespaceDefault, "tenant-a", external)
+		Expect(group.Name).Should(Equal(constants.ClusterInternalEndpoints + "-tenant-a"))
+		Expect(group.Spec.VRF).Should(Equal("tenant-a"))
+		Expect(group.Spec.Namespace).Should(BeNil())
+		Expect(group.Spec.EndpointSelector).ShouldNot(BeNil())
+	})
+})
+
 func newTestPolicyWithoutRule(namespace string, endpointSelector *labels.Selector, endpoint *string) *securityv1alpha1.SecurityPolicy {
 	policy := new(securityv1alpha1.SecurityPolicy)
 	policy.Name = rand.String(10)
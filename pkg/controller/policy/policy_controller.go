@@ -29,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
@@ -82,5 +83,116 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	return nil
+	if err = r.setupAppliedPoliciesWithManager(mgr); err != nil {
+		return err
+	}
+
+	return r.setupPolicyStatusWithManager(mgr)
+}
+
+// setupAppliedPoliciesWithManager create the controller maintains the applied policies of the endpoints.
+func (r *Reconciler) setupAppliedPoliciesWithManager(mgr ctrl.Manager) error {
+	appliedPolicies, err := controller.New("applied-policies", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              reconcile.Func(r.AppliedPoliciesReconcile),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = appliedPolicies.Watch(&source.Kind{Type: &securityv1alpha1.Endpoint{}}, &handler.Funcs{
+		CreateFunc: r.addEndpoint,
+		UpdateFunc: r.updateEndpoint,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = appliedPolicies.Watch(&source.Kind{Type: &groupv1alpha1.GroupMembers{}}, &handler.Funcs{
+		CreateFunc: r.addGroupMembers,
+		UpdateFunc: r.updateGroupMembers,
+		DeleteFunc: r.deleteGroupMembers,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = appliedPolicies.Watch(&source.Kind{Type: &groupv1alpha1.GroupMembersPatch{}}, &handler.Funcs{
+		CreateFunc: r.addGroupMembersPatch,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = appliedPolicies.Watch(&source.Kind{Type: &securityv1alpha1.SecurityPolicy{}}, &handler.Funcs{
+		CreateFunc: r.addAppliedPolicy,
+		UpdateFunc: r.updateAppliedPolicy,
+		DeleteFunc: r.deleteAppliedPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &securityv1alpha1.SecurityPolicy{},
+		constants.SecurityPolicyByAppliedGroupIndex,
+		AppliedGroupIndexSecurityPolicyFunc,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &groupv1alpha1.GroupMembers{},
+		constants.GroupMembersByEndpointIndex,
+		EndpointIndexGroupMembersFunc,
+	)
+	if err != nil {
+		return err
+	}
+
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &securityv1alpha1.Endpoint{},
+		constants.EndpointByReferenceIndex,
+		ReferenceIndexEndpointFunc,
+	)
+}
+
+// setupPolicyStatusWithManager create the controller maintains the status of the policies, it
+// depends on the applied group index of the policies.
+func (r *Reconciler) setupPolicyStatusWithManager(mgr ctrl.Manager) error {
+	policyStatus, err := controller.New("policy-status", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              reconcile.Func(r.PolicyStatusReconcile),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = policyStatus.Watch(&source.Kind{Type: &securityv1alpha1.SecurityPolicy{}}, &handler.Funcs{
+		CreateFunc: r.addStatusPolicy,
+		UpdateFunc: r.updateStatusPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = policyStatus.Watch(&source.Kind{Type: &groupv1alpha1.GroupMembers{}}, &handler.Funcs{
+		CreateFunc: r.addStatusGroupMembers,
+		UpdateFunc: r.updateStatusGroupMembers,
+		DeleteFunc: r.deleteStatusGroupMembers,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = policyStatus.Watch(&source.Kind{Type: &groupv1alpha1.GroupMembersPatch{}}, &handler.Funcs{
+		CreateFunc: r.addStatusGroupMembersPatch,
+	})
+	if err != nil {
+		return err
+	}
+
+	return policyStatus.Watch(&source.Kind{Type: &agentv1alpha1.AgentInfo{}}, &handler.Funcs{
+		CreateFunc: r.addStatusAgentInfo,
+		UpdateFunc: r.updateStatusAgentInfo,
+		DeleteFunc: r.deleteStatusAgentInfo,
+	})
 }
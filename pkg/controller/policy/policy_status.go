@@ -0,0 +1,354 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+)
+
+const (
+	// ReasonFeaturesUnsupported is the reason of the condition FeaturesSupported=False.
+	ReasonFeaturesUnsupported = "FeaturesUnsupported"
+	// ReasonFeaturesSupported is the reason of the condition FeaturesSupported=True.
+	ReasonFeaturesSupported = "FeaturesSupported"
+)
+
+// PolicyStatusReconcile maintains the status.appliedEndpoints and status.realizedNodes of the
+// SecurityPolicy, from the members of the appliedTo groups of the policy. The members without
+// agents are not located yet, they are counted but not realized on any node. The status.ruleStats
+// are summed from the rule counters reported by the agents of the realized nodes. The features
+// required by the rules are published in status.ruleFeatures, the condition FeaturesSupported=False
+// lists the realized nodes skipping the rules for the features their agents don't support. The
+// condition Compiled is evaluated on each generation of the policy.
+func (r *Reconciler) PolicyStatusReconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+
+	policy := securityv1alpha1.SecurityPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, &policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	endpointSet := sets.NewString()
+	nodeSet := sets.NewString()
+	for _, groupName := range AppliedGroupIndexSecurityPolicyFunc(&policy) {
+		groupMembers, err := groupctrl.GetGroupMembers(ctx, r.Client, groupName)
+		if client.IgnoreNotFound(err) != nil {
+			klog.Errorf("unable get groupmembers %s: %s", groupName, err)
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			continue
+		}
+		for _, member := range groupMembers.GroupMembers {
+			endpointSet.Insert(memberKey(member))
+			nodeSet.Insert(member.EndpointAgent...)
+		}
+	}
+
+	appliedEndpoints := int32(endpointSet.Len())
+	var realizedNodes []string
+	if nodeSet.Len() != 0 {
+		realizedNodes = nodeSet.List()
+	}
+	ruleStats, err := r.fetchRuleStats(ctx, req.NamespacedName, realizedNodes)
+	if err != nil {
+		klog.Errorf("unable get rule stats of policy %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+	ruleFeatures := policy.RequiredRuleFeatures()
+	featuresCondition, err := r.featuresCondition(ctx, &policy, ruleFeatures, realizedNodes)
+	if err != nil {
+		klog.Errorf("unable check rule features of policy %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+	compiled := compiledCondition(&policy)
+	if policy.Status.AppliedEndpoints == appliedEndpoints && reflect.DeepEqual(policy.Status.RealizedNodes, realizedNodes) &&
+		reflect.DeepEqual(policy.Status.RuleStats, ruleStats) && reflect.DeepEqual(policy.Status.RuleFeatures, ruleFeatures) &&
+		!conditionChanged(policy.Status.Conditions, featuresCondition) && !conditionChanged(policy.Status.Conditions, compiled) {
+		return ctrl.Result{}, nil
+	}
+
+	policy.Status.AppliedEndpoints = appliedEndpoints
+	policy.Status.RealizedNodes = realizedNodes
+	policy.Status.RuleStats = ruleStats
+	policy.Status.RuleFeatures = ruleFeatures
+	if conditionChanged(policy.Status.Conditions, featuresCondition) {
+		if featuresCondition.Status == metav1.ConditionFalse {
+			klog.Warningf("policy %s %s", req.NamespacedName, featuresCondition.Message)
+		}
+		meta.SetStatusCondition(&policy.Status.Conditions, *featuresCondition)
+	}
+	if conditionChanged(policy.Status.Conditions, compiled) {
+		if compiled.Status == metav1.ConditionFalse {
+			klog.Errorf("policy %s could not be compiled: %s", req.NamespacedName, compiled.Message)
+		}
+		meta.SetStatusCondition(&policy.Status.Conditions, *compiled)
+	}
+	if err := r.Status().Update(ctx, &policy); err != nil {
+		klog.Errorf("failed to update policy %s status: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+	klog.V(2).Infof("policy %s applied to %d endpoints on nodes %v", req.NamespacedName, appliedEndpoints, realizedNodes)
+
+	return ctrl.Result{}, nil
+}
+
+// fetchRuleStats sums the rule counters of the policy reported by the agents on the nodes.
+func (r *Reconciler) fetchRuleStats(ctx context.Context, policy types.NamespacedName, nodes []string) ([]securityv1alpha1.RuleStats, error) {
+	statsMap := make(map[string]*securityv1alpha1.RuleStats)
+	for _, node := range nodes {
+		agentInfo := agentv1alpha1.AgentInfo{}
+		err := r.Get(ctx, types.NamespacedName{Name: node}, &agentInfo)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		for _, stat := range agentInfo.PolicyRuleStats {
+			if stat.Policy != policy.String() {
+				continue
+			}
+			if statsMap[stat.Rule] == nil {
+				statsMap[stat.Rule] = &securityv1alpha1.RuleStats{Rule: stat.Rule}
+			}
+			statsMap[stat.Rule].Packets += stat.Packets
+			statsMap[stat.Rule].Bytes += stat.Bytes
+		}
+	}
+
+	var ruleStats []securityv1alpha1.RuleStats
+	for _, stat := range statsMap {
+		ruleStats = append(ruleStats, *stat)
+	}
+	sort.Slice(ruleStats, func(i, j int) bool { return ruleStats[i].Rule < ruleStats[j].Rule })
+	return ruleStats, nil
+}
+
+// featuresCondition returns the condition FeaturesSupported of the policy, false if any agent
+// of the nodes doesn't support the features required by the rules. The agents not reported
+// their rule features support the baseline features, the nodes without agentinfo are skipped.
+// Returns nil if the condition never set and all the features supported.
+func (r *Reconciler) featuresCondition(ctx context.Context, policy *securityv1alpha1.SecurityPolicy,
+	requirements []securityv1alpha1.RuleFeatureRequirement, nodes []string) (*metav1.Condition, error) {
+	required := sets.NewString()
+	for _, requirement := range requirements {
+		for _, feature := range requirement.Features {
+			required.Insert(string(feature))
+		}
+	}
+
+	var unsupported []string
+	for _, node := range nodes {
+		if required.Len() == 0 {
+			break
+		}
+		agentInfo := agentv1alpha1.AgentInfo{}
+		err := r.Get(ctx, types.NamespacedName{Name: node}, &agentInfo)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		supportedFeatures := agentInfo.RuleFeatures
+		if supportedFeatures == nil {
+			supportedFeatures = securityv1alpha1.BaselineRuleFeatures
+		}
+		supported := sets.NewString()
+		for _, feature := range supportedFeatures {
+			supported.Insert(string(feature))
+		}
+		if missing := required.Difference(supported); missing.Len() != 0 {
+			unsupported = append(unsupported, fmt.Sprintf("%s(%s)", node, strings.Join(missing.List(), ",")))
+		}
+	}
+
+	condition := &metav1.Condition{
+		Type:               securityv1alpha1.SecurityPolicyFeaturesSupported,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: policy.GetGeneration(),
+		Reason:             ReasonFeaturesSupported,
+		Message:            "rule features supported on all the realized nodes",
+	}
+	if len(unsupported) != 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonFeaturesUnsupported
+		condition.Message = "rules requiring the features skipped on nodes " + strings.Join(unsupported, ", ")
+	}
+	if len(unsupported) == 0 && meta.FindStatusCondition(policy.Status.Conditions, condition.Type) == nil {
+		return nil, nil
+	}
+	return condition, nil
+}
+
+// conditionChanged returns true if the condition is not nil and differs from the existing one.
+func conditionChanged(conditions []metav1.Condition, condition *metav1.Condition) bool {
+	if condition == nil {
+		return false
+	}
+	existing := meta.FindStatusCondition(conditions, condition.Type)
+	return existing == nil || existing.Status != condition.Status || existing.Reason != condition.Reason ||
+		existing.Message != condition.Message || existing.ObservedGeneration != condition.ObservedGeneration
+}
+
+func (r *Reconciler) addStatusPolicy(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+		Namespace: e.Meta.GetNamespace(),
+		Name:      e.Meta.GetName(),
+	}})
+}
+
+func (r *Reconciler) updateStatusPolicy(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	newGroups := sets.NewString(AppliedGroupIndexSecurityPolicyFunc(e.ObjectNew)...)
+	oldGroups := sets.NewString(AppliedGroupIndexSecurityPolicyFunc(e.ObjectOld)...)
+
+	// the status only changes with the applied groups, the features required by the rules and
+	// the generation compiled
+	if !newGroups.Equal(oldGroups) || e.MetaNew.GetGeneration() != e.MetaOld.GetGeneration() ||
+		!reflect.DeepEqual(e.ObjectNew.(*securityv1alpha1.SecurityPolicy).RequiredRuleFeatures(),
+			e.ObjectOld.(*securityv1alpha1.SecurityPolicy).RequiredRuleFeatures()) {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: e.MetaNew.GetNamespace(),
+			Name:      e.MetaNew.GetName(),
+		}})
+	}
+}
+
+func (r *Reconciler) addStatusGroupMembers(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	r.enqueueAppliedPolicies(e.Meta.GetName(), q)
+}
+
+func (r *Reconciler) updateStatusGroupMembers(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	r.enqueueAppliedPolicies(e.MetaNew.GetName(), q)
+}
+
+func (r *Reconciler) deleteStatusGroupMembers(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	r.enqueueAppliedPolicies(e.Meta.GetName(), q)
+}
+
+func (r *Reconciler) addStatusGroupMembersPatch(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	r.enqueueAppliedPolicies(e.Object.(*groupv1alpha1.GroupMembersPatch).AppliedToGroupMembers.Name, q)
+}
+
+func (r *Reconciler) addStatusAgentInfo(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	enqueueRuleStatsPolicies(nil, e.Object.(*agentv1alpha1.AgentInfo).PolicyRuleStats, q)
+	r.enqueueRealizedPolicies(e.Meta.GetName(), q)
+}
+
+func (r *Reconciler) updateStatusAgentInfo(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldAgentInfo, newAgentInfo := e.ObjectOld.(*agentv1alpha1.AgentInfo), e.ObjectNew.(*agentv1alpha1.AgentInfo)
+	enqueueRuleStatsPolicies(oldAgentInfo.PolicyRuleStats, newAgentInfo.PolicyRuleStats, q)
+	if !reflect.DeepEqual(oldAgentInfo.RuleFeatures, newAgentInfo.RuleFeatures) {
+		r.enqueueRealizedPolicies(e.MetaNew.GetName(), q)
+	}
+}
+
+func (r *Reconciler) deleteStatusAgentInfo(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	enqueueRuleStatsPolicies(e.Object.(*agentv1alpha1.AgentInfo).PolicyRuleStats, nil, q)
+	r.enqueueRealizedPolicies(e.Meta.GetName(), q)
+}
+
+// enqueueRealizedPolicies enqueue the policies realized on the node.
+func (r *Reconciler) enqueueRealizedPolicies(node string, q workqueue.RateLimitingInterface) {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(context.Background(), &policyList); err != nil {
+		klog.Errorf("list of SecurityPolicies realized on node %s: %s", node, err)
+		return
+	}
+
+	for _, policy := range policyList.Items {
+		if sets.NewString(policy.Status.RealizedNodes...).Has(node) {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: policy.GetNamespace(),
+				Name:      policy.GetName(),
+			}})
+		}
+	}
+}
+
+// enqueueRuleStatsPolicies enqueue the policies with the rule counters changed in the agentinfo.
+func enqueueRuleStatsPolicies(oldStats, newStats []agentv1alpha1.PolicyRuleStats, q workqueue.RateLimitingInterface) {
+	toStatsMap := func(stats []agentv1alpha1.PolicyRuleStats) map[string]agentv1alpha1.PolicyRuleStats {
+		statsMap := make(map[string]agentv1alpha1.PolicyRuleStats, len(stats))
+		for _, stat := range stats {
+			statsMap[stat.Policy+"/"+stat.Rule] = stat
+		}
+		return statsMap
+	}
+	oldStatsMap, newStatsMap := toStatsMap(oldStats), toStatsMap(newStats)
+
+	changedPolicies := sets.NewString()
+	for key, stat := range oldStatsMap {
+		if newStat, ok := newStatsMap[key]; !ok || newStat != stat {
+			changedPolicies.Insert(stat.Policy)
+		}
+	}
+	for key, stat := range newStatsMap {
+		if _, ok := oldStatsMap[key]; !ok {
+			changedPolicies.Insert(stat.Policy)
+		}
+	}
+
+	for policy := range changedPolicies {
+		keys := strings.SplitN(policy, "/", 2)
+		if len(keys) != 2 {
+			continue
+		}
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: keys[0],
+			Name:      keys[1],
+		}})
+	}
+}
+
+// enqueueAppliedPolicies enqueue the policies applied to the group.
+func (r *Reconciler) enqueueAppliedPolicies(groupName string, q workqueue.RateLimitingInterface) {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	err := r.List(context.Background(), &policyList, client.MatchingFields{
+		constants.SecurityPolicyByAppliedGroupIndex: groupName,
+	})
+	if err != nil {
+		klog.Errorf("list of SecurityPolicies applied to EndpointGroup %s: %s", groupName, err)
+		return
+	}
+
+	for _, policy := range policyList.Items {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: policy.GetNamespace(),
+			Name:      policy.GetName(),
+		}})
+	}
+}
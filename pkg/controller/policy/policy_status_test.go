@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	policyctrl "github.com/everoute/everoute/pkg/controller/policy"
+)
+
+var _ = Describe("PolicyStatus", func() {
+	var ctx context.Context
+	var namespace string
+	var policy *securityv1alpha1.SecurityPolicy
+	var groupName string
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		namespaceList := corev1.NamespaceList{}
+		Expect(k8sClient.List(ctx, &namespaceList)).Should(Succeed())
+		// run test in rand namespace
+		namespace = namespaceList.Items[rand.IntnRange(0, len(namespaceList.Items))].GetName()
+
+		endpointName := rand.String(10)
+		policy = newTestPolicyWithoutRule(namespace, nil, &endpointName)
+		By(fmt.Sprintf("create SecurityPolicy %+v", policy))
+		Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+		groupName = policyctrl.AppliedGroupIndexSecurityPolicyFunc(policy)[0]
+	})
+	AfterEach(func() {
+		By("delete all SecurityPolicies")
+		Expect(k8sClient.DeleteAllOf(ctx, &securityv1alpha1.SecurityPolicy{}, client.InNamespace(namespace))).Should(Succeed())
+		By("delete all GroupMembers")
+		Expect(k8sClient.DeleteAllOf(ctx, &groupv1alpha1.GroupMembers{})).Should(Succeed())
+		By("delete all EndpointGroups")
+		Expect(k8sClient.DeleteAllOf(ctx, &groupv1alpha1.EndpointGroup{})).Should(Succeed())
+	})
+
+	When("create the GroupMembers of the applied group", func() {
+		var groupMembers *groupv1alpha1.GroupMembers
+
+		BeforeEach(func() {
+			groupMembers = new(groupv1alpha1.GroupMembers)
+			groupMembers.Name = groupName
+			groupMembers.GroupMembers = []groupv1alpha1.GroupMember{
+				newGroupMember("node02"),
+				newGroupMember("node01"),
+				newGroupMember("node01", "node02"),
+				newGroupMember(),
+			}
+			By(fmt.Sprintf("create GroupMembers %+v", groupMembers))
+			Expect(k8sClient.Create(ctx, groupMembers)).Should(Succeed())
+		})
+		It("should count the endpoints and the nodes in the policy status", func() {
+			assertPolicyStatus(ctx, policy, 4, "node01", "node02")
+		})
+
+		When("remove the members on the node", func() {
+			BeforeEach(func() {
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: groupName}, groupMembers)).Should(Succeed())
+				groupMembers.GroupMembers = groupMembers.GroupMembers[1:2]
+				Expect(k8sClient.Update(ctx, groupMembers)).Should(Succeed())
+			})
+			It("should remove the node from the policy status", func() {
+				assertPolicyStatus(ctx, policy, 1, "node01")
+			})
+		})
+
+		When("delete the GroupMembers", func() {
+			BeforeEach(func() {
+				Expect(k8sClient.Delete(ctx, groupMembers)).Should(Succeed())
+			})
+			It("should clean the policy status", func() {
+				assertPolicyStatus(ctx, policy, 0)
+			})
+		})
+
+		When("the agents report the rule counters", func() {
+			BeforeEach(func() {
+				policyKey := types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}.String()
+				for _, agentName := range []string{"node01", "node02", "node03"} {
+					agentInfo := &agentv1alpha1.AgentInfo{}
+					agentInfo.Name = agentName
+					agentInfo.PolicyRuleStats = []agentv1alpha1.PolicyRuleStats{
+						{Policy: policyKey, Rule: "default.ingress", Packets: 2, Bytes: 120},
+						{Policy: policyKey, Rule: "ingress.allow", Packets: 10, Bytes: 1000},
+						{Policy: "other/policy", Rule: "ingress.allow", Packets: 1, Bytes: 100},
+					}
+					By(fmt.Sprintf("create AgentInfo %+v", agentInfo))
+					Expect(k8sClient.Create(ctx, agentInfo)).Should(Succeed())
+				}
+			})
+			AfterEach(func() {
+				Expect(k8sClient.DeleteAllOf(ctx, &agentv1alpha1.AgentInfo{})).Should(Succeed())
+			})
+			It("should sum the counters of the realized nodes in the policy status", func() {
+				Eventually(func() []securityv1alpha1.RuleStats {
+					p := securityv1alpha1.SecurityPolicy{}
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, &p)).Should(Succeed())
+					return p.Status.RuleStats
+				}, timeout, interval).Should(Equal([]securityv1alpha1.RuleStats{
+					{Rule: "default.ingress", Packets: 4, Bytes: 240},
+					{Rule: "ingress.allow", Packets: 20, Bytes: 2000},
+				}))
+			})
+		})
+
+		When("the rules require the features not supported on the nodes", func() {
+			BeforeEach(func() {
+				for _, agentName := range []string{"node01", "node02"} {
+					agentInfo := &agentv1alpha1.AgentInfo{}
+					agentInfo.Name = agentName
+					if agentName == "node01" {
+						agentInfo.RuleFeatures = []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureFQDN}
+					}
+					By(fmt.Sprintf("create AgentInfo %+v", agentInfo))
+					Expect(k8sClient.Create(ctx, agentInfo)).Should(Succeed())
+				}
+
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, policy)).Should(Succeed())
+				policy.Spec.EgressRules = []securityv1alpha1.Rule{{
+					Name: "dns",
+					To:   []securityv1alpha1.SecurityPolicyPeer{{FQDN: "www.example.com"}},
+				}}
+				Expect(k8sClient.Update(ctx, policy)).Should(Succeed())
+			})
+			AfterEach(func() {
+				Expect(k8sClient.DeleteAllOf(ctx, &agentv1alpha1.AgentInfo{})).Should(Succeed())
+			})
+			It("should publish the required features and the unsupported nodes in the policy status", func() {
+				assertFeaturesCondition(ctx, policy, metav1.ConditionFalse, "node02(FQDN)")
+				p := securityv1alpha1.SecurityPolicy{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, &p)).Should(Succeed())
+				Expect(p.Status.RuleFeatures).Should(Equal([]securityv1alpha1.RuleFeatureRequirement{
+					{Rule: "egress.dns", Features: []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureFQDN}},
+				}))
+			})
+
+			When("the agent upgraded to support the features", func() {
+				BeforeEach(func() {
+					assertFeaturesCondition(ctx, policy, metav1.ConditionFalse, "node02(FQDN)")
+					agentInfo := &agentv1alpha1.AgentInfo{}
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "node02"}, agentInfo)).Should(Succeed())
+					agentInfo.RuleFeatures = []securityv1alpha1.RuleFeature{securityv1alpha1.RuleFeatureFQDN}
+					Expect(k8sClient.Update(ctx, agentInfo)).Should(Succeed())
+				})
+				It("should recover the condition", func() {
+					assertFeaturesCondition(ctx, policy, metav1.ConditionTrue, "")
+				})
+			})
+		})
+	})
+})
+
+func newGroupMember(agents ...string) groupv1alpha1.GroupMember {
+	return groupv1alpha1.GroupMember{
+		EndpointReference: groupv1alpha1.EndpointReference{
+			ExternalIDName:  "iface-id",
+			ExternalIDValue: rand.String(10),
+		},
+		EndpointAgent: agents,
+	}
+}
+
+func assertPolicyStatus(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, appliedEndpoints int32, realizedNodes ...string) {
+	Eventually(func() securityv1alpha1.SecurityPolicyStatus {
+		p := securityv1alpha1.SecurityPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, &p)).Should(Succeed())
+		p.Status.Conditions = nil
+		return p.Status
+	}, timeout, interval).Should(Equal(securityv1alpha1.SecurityPolicyStatus{
+		AppliedEndpoints: appliedEndpoints,
+		RealizedNodes:    realizedNodes,
+	}))
+}
+
+func assertFeaturesCondition(ctx context.Context, policy *securityv1alpha1.SecurityPolicy, status metav1.ConditionStatus, node string) {
+	Eventually(func() *metav1.Condition {
+		p := securityv1alpha1.SecurityPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, &p)).Should(Succeed())
+		return meta.FindStatusCondition(p.Status.Conditions, securityv1alpha1.SecurityPolicyFeaturesSupported)
+	}, timeout, interval).Should(SatisfyAll(
+		Not(BeNil()),
+		WithTransform(func(c *metav1.Condition) metav1.ConditionStatus { return c.Status }, Equal(status)),
+		WithTransform(func(c *metav1.Condition) string { return c.Message }, ContainSubstring(node)),
+	))
+}
@@ -0,0 +1,421 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policyplan
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/controller/capacity"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/controller/reachability"
+	"github.com/everoute/everoute/pkg/webhook/validates"
+)
+
+// Reconciler computes the impact of the changes of the PolicyPlans, and applies the changes
+// of the plans approved. The plans not applied are re-planned on the policies or the groups
+// changed.
+type Reconciler struct {
+	client.Client
+}
+
+// Reconcile plans the plan, and applies the changes if the plan approved.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("PolicyPlanReconciler received plan %s reconcile", req.Name)
+
+	plan := securityv1alpha1.PolicyPlan{}
+	if err := r.Get(ctx, req.NamespacedName, &plan); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		klog.Errorf("unable to fetch policy plan %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	if plan.Status.Phase == securityv1alpha1.PolicyPlanApplied {
+		return ctrl.Result{}, nil
+	}
+
+	status, err := r.plan(ctx, &plan.Spec)
+	if err != nil {
+		klog.Errorf("unable to plan policy plan %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	status.ObservedGeneration = plan.Generation
+	status.PlannedTime = metav1.Now()
+
+	if !plan.Spec.Approved {
+		status.Phase = securityv1alpha1.PolicyPlanPlanned
+		if statusEqual(&plan.Status, status) {
+			return ctrl.Result{}, nil
+		}
+		plan.Status = *status
+		if err = r.Status().Update(ctx, &plan); err != nil {
+			klog.Errorf("failed to update policy plan %s: %s", req.Name, err)
+			return ctrl.Result{}, err
+		}
+		klog.Infof("policy plan %s planned, %d endpoints affected, %d reachability changed", req.Name, status.AffectedEndpointCount, len(status.ReachabilityChanges))
+		return ctrl.Result{}, nil
+	}
+
+	if over := capacity.OverCapacity(status.NodeFlowEstimates); over != nil {
+		// refuse to apply, the plan is re-planned on the capacity or the policies changed
+		status.Phase = securityv1alpha1.PolicyPlanFailed
+		status.Message = fmt.Sprintf("node %s would be pushed over its capacity, %d policy flows estimated, limit %d", over.Node, over.After, over.Limit)
+		if statusEqual(&plan.Status, status) {
+			return ctrl.Result{}, nil
+		}
+		plan.Status = *status
+		if err = r.Status().Update(ctx, &plan); err != nil {
+			klog.Errorf("failed to update policy plan %s: %s", req.Name, err)
+			return ctrl.Result{}, err
+		}
+		klog.Errorf("refuse to apply policy plan %s: %s", req.Name, status.Message)
+		return ctrl.Result{}, nil
+	}
+
+	applyErr := r.review(ctx, &plan)
+	if applyErr == nil {
+		applyErr = r.apply(ctx, plan.Spec.Changes)
+	}
+	if applyErr != nil {
+		status.Phase, status.Message = securityv1alpha1.PolicyPlanFailed, applyErr.Error()
+	} else {
+		appliedTime := metav1.Now()
+		status.Phase, status.AppliedTime = securityv1alpha1.PolicyPlanApplied, &appliedTime
+	}
+	plan.Status = *status
+	if err = r.Status().Update(ctx, &plan); err != nil {
+		klog.Errorf("failed to update policy plan %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	if applyErr != nil {
+		klog.Errorf("failed to apply policy plan %s: %s", req.Name, applyErr)
+		return ctrl.Result{}, applyErr
+	}
+	klog.Infof("policy plan %s applied, %d policies changed", req.Name, len(plan.Spec.Changes))
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add PolicyPlan Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("policyplan-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.PolicyPlan{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+
+	enqueuePlans := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.allPlans),
+	}
+	for _, object := range []runtime.Object{
+		&securityv1alpha1.GlobalPolicy{},
+		&securityv1alpha1.SecurityPolicy{},
+		&groupv1alpha1.EndpointGroup{},
+		&groupv1alpha1.GroupMembers{},
+		&groupv1alpha1.GroupMembersPatch{},
+	} {
+		if err = c.Watch(&source.Kind{Type: object}, enqueuePlans); err != nil {
+			return err
+		}
+	}
+	// the agentinfos are updated on each heartbeat, only the capacity changes are concerned
+	return c.Watch(&source.Kind{Type: &agentv1alpha1.AgentInfo{}}, enqueuePlans, predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, oldOk := e.ObjectOld.(*agentv1alpha1.AgentInfo)
+			newObj, newOk := e.ObjectNew.(*agentv1alpha1.AgentInfo)
+			return !oldOk || !newOk || !reflect.DeepEqual(oldObj.Capacity, newObj.Capacity)
+		},
+	})
+}
+
+func (r *Reconciler) allPlans(handler.MapObject) []reconcile.Request {
+	planList := securityv1alpha1.PolicyPlanList{}
+	if err := r.List(context.Background(), &planList); err != nil {
+		klog.Errorf("unable to list policy plans: %s", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(planList.Items))
+	for _, plan := range planList.Items {
+		if plan.Status.Phase == securityv1alpha1.PolicyPlanApplied {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: plan.Name}})
+	}
+	return requests
+}
+
+// plan computes the endpoints affected, the reachability and the node flows changed by the changes.
+func (r *Reconciler) plan(ctx context.Context, spec *securityv1alpha1.PolicyPlanSpec) (*securityv1alpha1.PolicyPlanStatus, error) {
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(ctx, &policyList); err != nil {
+		return nil, fmt.Errorf("list policies: %s", err)
+	}
+	proposed := proposedPolicies(policyList.Items, spec.Changes)
+
+	affected, err := r.affectedEndpoints(ctx, policyList.Items, proposed, spec.Changes)
+	if err != nil {
+		return nil, err
+	}
+	status := &securityv1alpha1.PolicyPlanStatus{
+		AffectedEndpointCount: int32(len(affected)),
+		AffectedEndpoints:     affected,
+	}
+
+	if len(spec.Ports) != 0 {
+		matrixSpec := &securityv1alpha1.ReachabilityMatrixSpec{Groups: spec.Groups, Ports: spec.Ports}
+		before, err := reachability.Analyze(ctx, r.Client, matrixSpec, policyList.Items)
+		if err != nil {
+			return nil, fmt.Errorf("analyze reachability before changes: %s", err)
+		}
+		after, err := reachability.Analyze(ctx, r.Client, matrixSpec, proposed)
+		if err != nil {
+			return nil, fmt.Errorf("analyze reachability after changes: %s", err)
+		}
+		status.ReachabilityChanges = reachabilityChanges(before.Entries, after.Entries)
+	}
+
+	simulator, err := capacity.NewSimulator(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	flowsBefore, err := simulator.Estimate(ctx, policyList.Items)
+	if err != nil {
+		return nil, fmt.Errorf("estimate policy flows before changes: %s", err)
+	}
+	flowsAfter, err := simulator.Estimate(ctx, proposed)
+	if err != nil {
+		return nil, fmt.Errorf("estimate policy flows after changes: %s", err)
+	}
+	status.NodeFlowEstimates = simulator.Compare(flowsBefore, flowsAfter)
+	return status, nil
+}
+
+// proposedPolicies returns the policies after the changes applied to the current policies.
+func proposedPolicies(current []securityv1alpha1.SecurityPolicy, changes []securityv1alpha1.PolicyChange) []securityv1alpha1.SecurityPolicy {
+	changed := sets.NewString()
+	for _, change := range changes {
+		changed.Insert(change.Namespace + "/" + change.Name)
+	}
+
+	proposed := make([]securityv1alpha1.SecurityPolicy, 0, len(current)+len(changes))
+	for _, policy := range current {
+		if !changed.Has(policy.Namespace + "/" + policy.Name) {
+			proposed = append(proposed, policy)
+		}
+	}
+	for _, change := range changes {
+		if change.Operation == securityv1alpha1.PolicyChangeDelete || change.Spec == nil {
+			continue
+		}
+		proposed = append(proposed, securityv1alpha1.SecurityPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: change.Namespace, Name: change.Name},
+			Spec:       *change.Spec.DeepCopy(),
+		})
+	}
+	return proposed
+}
+
+// affectedEndpoints returns the endpoints the changed policies applied to, before or after the
+// changes, in format of namespace/name.
+func (r *Reconciler) affectedEndpoints(ctx context.Context, current, proposed []securityv1alpha1.SecurityPolicy, changes []securityv1alpha1.PolicyChange) ([]string, error) {
+	endpointList := securityv1alpha1.EndpointList{}
+	if err := r.List(ctx, &endpointList); err != nil {
+		return nil, fmt.Errorf("list endpoints: %s", err)
+	}
+	endpointNames := make(map[groupv1alpha1.EndpointReference]string, len(endpointList.Items))
+	for _, endpoint := range endpointList.Items {
+		reference := groupv1alpha1.EndpointReference{
+			ExternalIDName:  endpoint.Spec.Reference.ExternalIDName,
+			ExternalIDValue: endpoint.Spec.Reference.ExternalIDValue,
+		}
+		endpointNames[reference] = endpoint.Namespace + "/" + endpoint.Name
+	}
+
+	changed := sets.NewString()
+	for _, change := range changes {
+		changed.Insert(change.Namespace + "/" + change.Name)
+	}
+
+	affected := sets.NewString()
+	for _, policies := range [][]securityv1alpha1.SecurityPolicy{current, proposed} {
+		for i := range policies {
+			policy := &policies[i]
+			if !changed.Has(policy.Namespace + "/" + policy.Name) {
+				continue
+			}
+
+			if len(policy.Spec.AppliedTo) == 0 {
+				// the empty AppliedTo applies to all the endpoints in the vrf
+				for _, endpoint := range endpointList.Items {
+					if endpoint.Spec.VRF == policy.Spec.VRF {
+						affected.Insert(endpoint.Namespace + "/" + endpoint.Name)
+					}
+				}
+				continue
+			}
+
+			for _, applied := range policy.Spec.AppliedTo {
+				group := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, applied))
+				if group == nil {
+					continue
+				}
+				members, err := groupctrl.FetchGroupMembers(ctx, r.Client, group)
+				if err != nil {
+					return nil, fmt.Errorf("fetch group %s members: %s", group.Name, err)
+				}
+				for _, member := range members.GroupMembers {
+					if name, ok := endpointNames[member.EndpointReference]; ok {
+						affected.Insert(name)
+					}
+				}
+			}
+		}
+	}
+
+	if affected.Len() == 0 {
+		return nil, nil
+	}
+	return affected.List(), nil
+}
+
+// reachabilityChanges returns the entries with the verdicts changed.
+func reachabilityChanges(before, after []securityv1alpha1.ReachabilityEntry) []securityv1alpha1.ReachabilityChange {
+	type entryKey struct {
+		from, to string
+		protocol securityv1alpha1.Protocol
+		port     int32
+	}
+	verdicts := make(map[entryKey]securityv1alpha1.ReachabilityVerdict, len(before))
+	for _, entry := range before {
+		verdicts[entryKey{entry.From, entry.To, entry.Protocol, entry.Port}] = entry.Verdict
+	}
+
+	var changes []securityv1alpha1.ReachabilityChange
+	for _, entry := range after {
+		verdict, ok := verdicts[entryKey{entry.From, entry.To, entry.Protocol, entry.Port}]
+		if !ok || verdict == entry.Verdict {
+			continue
+		}
+		changes = append(changes, securityv1alpha1.ReachabilityChange{
+			From:     entry.From,
+			To:       entry.To,
+			Protocol: entry.Protocol,
+			Port:     entry.Port,
+			Before:   verdict,
+			After:    entry.Verdict,
+		})
+	}
+	return changes
+}
+
+// review returns error if the approver not allowed to approve the plan, or either the author
+// or the approver not allowed to change the policies of the changes, because the changes are
+// applied by the controller on behalf of them.
+func (r *Reconciler) review(ctx context.Context, plan *securityv1alpha1.PolicyPlan) error {
+	if err := validates.ReviewPolicyPlanApproval(ctx, r.Client, plan.Spec.Approver, plan.Name); err != nil {
+		return fmt.Errorf("approver: %s", err)
+	}
+	if err := validates.ReviewPolicyChanges(ctx, r.Client, plan.Spec.Author, plan.Spec.Changes); err != nil {
+		return fmt.Errorf("author: %s", err)
+	}
+	if err := validates.ReviewPolicyChanges(ctx, r.Client, plan.Spec.Approver, plan.Spec.Changes); err != nil {
+		return fmt.Errorf("approver: %s", err)
+	}
+	return nil
+}
+
+// apply creates or updates the policies of the Apply changes, and deletes the policies of the
+// Delete changes. It's safe to retry after part of the changes applied.
+func (r *Reconciler) apply(ctx context.Context, changes []securityv1alpha1.PolicyChange) error {
+	for _, change := range changes {
+		key := types.NamespacedName{Namespace: change.Namespace, Name: change.Name}
+		policy := securityv1alpha1.SecurityPolicy{}
+		err := r.Get(ctx, key, &policy)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("get policy %s: %s", key, err)
+		}
+		exists := err == nil
+
+		if change.Operation == securityv1alpha1.PolicyChangeDelete {
+			if exists {
+				if err = r.Delete(ctx, &policy); err != nil && !errors.IsNotFound(err) {
+					return fmt.Errorf("delete policy %s: %s", key, err)
+				}
+			}
+			continue
+		}
+
+		if change.Spec == nil {
+			return fmt.Errorf("spec of policy %s not set", key)
+		}
+		if exists {
+			policy.Spec = *change.Spec.DeepCopy()
+			err = r.Update(ctx, &policy)
+		} else {
+			policy = securityv1alpha1.SecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: change.Namespace, Name: change.Name},
+				Spec:       *change.Spec.DeepCopy(),
+			}
+			err = r.Create(ctx, &policy)
+		}
+		if err != nil {
+			return fmt.Errorf("apply policy %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func statusEqual(actual, expect *securityv1alpha1.PolicyPlanStatus) bool {
+	return actual.Phase == expect.Phase &&
+		actual.ObservedGeneration == expect.ObservedGeneration &&
+		actual.Message == expect.Message &&
+		actual.AffectedEndpointCount == expect.AffectedEndpointCount &&
+		reflect.DeepEqual(actual.AffectedEndpoints, expect.AffectedEndpoints) &&
+		reflect.DeepEqual(actual.ReachabilityChanges, expect.ReachabilityChanges) &&
+		reflect.DeepEqual(actual.NodeFlowEstimates, expect.NodeFlowEstimates)
+}
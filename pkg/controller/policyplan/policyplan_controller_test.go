@@ -0,0 +1,348 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policyplan
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+var (
+	mysql    = securityv1alpha1.ReachabilityPort{Protocol: securityv1alpha1.ProtocolTCP, Port: 3306}
+	author   = &authv1.UserInfo{Username: "author"}
+	approver = &authv1.UserInfo{Username: "approver"}
+)
+
+// reviewClient denies the verb of the user denied in the SubjectAccessReview, and allows the others.
+type reviewClient struct {
+	client.Client
+	denied string
+	verb   string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		review.Status.Allowed = review.Spec.User != c.denied || review.Spec.ResourceAttributes.Verb != c.verb
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func newEndpoint(name, ip, app string) *securityv1alpha1.Endpoint {
+	return &securityv1alpha1.Endpoint{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": app}},
+		Spec: securityv1alpha1.EndpointSpec{
+			Reference: securityv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: name},
+		},
+		Status: securityv1alpha1.EndpointStatus{IPs: []types.IPAddress{types.IPAddress(ip)}},
+	}
+}
+
+func newMember(name, ip string) groupv1alpha1.GroupMember {
+	return groupv1alpha1.GroupMember{
+		EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: name},
+		IPs:               []types.IPAddress{types.IPAddress(ip)},
+	}
+}
+
+func selectApp(app string) *labels.Selector {
+	return &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}}
+}
+
+// newDBPolicySpec returns the spec isolates the db, and allows mysql from the apps.
+func newDBPolicySpec(apps ...string) *securityv1alpha1.SecurityPolicySpec {
+	spec := &securityv1alpha1.SecurityPolicySpec{
+		Tier:      constants.Tier2,
+		AppliedTo: []securityv1alpha1.ApplyToPeer{{EndpointSelector: selectApp("db")}},
+	}
+	for _, app := range apps {
+		spec.IngressRules = append(spec.IngressRules, securityv1alpha1.Rule{
+			Name:  app,
+			Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "3306"}},
+			From:  []securityv1alpha1.SecurityPolicyPeer{{EndpointSelector: selectApp(app)}},
+		})
+	}
+	return spec
+}
+
+func TestProposedPolicies(t *testing.T) {
+	RegisterTestingT(t)
+
+	current := []securityv1alpha1.SecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: *newDBPolicySpec()},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy"}},
+	}
+	proposed := proposedPolicies(current, []securityv1alpha1.PolicyChange{
+		{Operation: securityv1alpha1.PolicyChangeApply, Namespace: "default", Name: "db", Spec: newDBPolicySpec("web")},
+		{Operation: securityv1alpha1.PolicyChangeDelete, Namespace: "default", Name: "legacy"},
+		{Namespace: "shop", Name: "api", Spec: &securityv1alpha1.SecurityPolicySpec{Tier: constants.Tier2}},
+	})
+	Expect(proposed).Should(HaveLen(3))
+	Expect(proposed[0].Name).Should(Equal("web"))
+	Expect(proposed[1].Name).Should(Equal("db"))
+	Expect(proposed[1].Spec.IngressRules).Should(HaveLen(1))
+	Expect(proposed[2].Namespace).Should(Equal("shop"))
+	// the current policies are not modified
+	Expect(current[0].Spec.IngressRules).Should(BeEmpty())
+}
+
+func TestReachabilityChanges(t *testing.T) {
+	RegisterTestingT(t)
+
+	before := []securityv1alpha1.ReachabilityEntry{
+		{From: "web", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306, Verdict: securityv1alpha1.ReachabilityDeny},
+		{From: "db", To: "web", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306, Verdict: securityv1alpha1.ReachabilityAllow},
+	}
+	after := []securityv1alpha1.ReachabilityEntry{
+		{From: "db", To: "web", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306, Verdict: securityv1alpha1.ReachabilityAllow},
+		{From: "web", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306, Verdict: securityv1alpha1.ReachabilityPartial},
+		{From: "new", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306, Verdict: securityv1alpha1.ReachabilityAllow},
+	}
+	Expect(reachabilityChanges(before, after)).Should(Equal([]securityv1alpha1.ReachabilityChange{{
+		From: "web", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306,
+		Before: securityv1alpha1.ReachabilityDeny, After: securityv1alpha1.ReachabilityPartial,
+	}}))
+	Expect(reachabilityChanges(before, before)).Should(BeNil())
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		&securityv1alpha1.PolicyPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-web-db", Generation: 1},
+			Spec: securityv1alpha1.PolicyPlanSpec{
+				Changes: []securityv1alpha1.PolicyChange{{Namespace: "default", Name: "db", Spec: newDBPolicySpec("web")}},
+				Ports:   []securityv1alpha1.ReachabilityPort{mysql},
+				Author:  author,
+			},
+		},
+		&securityv1alpha1.SecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: *newDBPolicySpec()},
+		newEndpoint("web01", "10.0.1.1", "web"),
+		newEndpoint("db01", "10.0.2.1", "db"),
+		&groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: groupv1alpha1.EndpointGroupSpec{EndpointSelector: selectApp("web")}},
+		&groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Spec: groupv1alpha1.EndpointGroupSpec{EndpointSelector: selectApp("db")}},
+		&groupv1alpha1.GroupMembers{ObjectMeta: metav1.ObjectMeta{Name: "web"}, GroupMembers: []groupv1alpha1.GroupMember{newMember("web01", "10.0.1.1")}},
+		&groupv1alpha1.GroupMembers{ObjectMeta: metav1.ObjectMeta{Name: "db"}, GroupMembers: []groupv1alpha1.GroupMember{newMember("db01", "10.0.2.1")}},
+	)
+	r := &Reconciler{Client: &reviewClient{Client: c}}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "allow-web-db"}}
+	getPlan := func() *securityv1alpha1.PolicyPlan {
+		plan := &securityv1alpha1.PolicyPlan{}
+		Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+		return plan
+	}
+
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	plan := getPlan()
+	Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanPlanned))
+	Expect(plan.Status.ObservedGeneration).Should(Equal(int64(1)))
+	Expect(plan.Status.AffectedEndpoints).Should(Equal([]string{"default/db01"}))
+	Expect(plan.Status.AffectedEndpointCount).Should(Equal(int32(1)))
+	Expect(plan.Status.ReachabilityChanges).Should(Equal([]securityv1alpha1.ReachabilityChange{{
+		From: "web", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306,
+		Before: securityv1alpha1.ReachabilityDeny, After: securityv1alpha1.ReachabilityAllow,
+	}}))
+
+	// not updated when the plan unchanged
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getPlan().ResourceVersion).Should(Equal(plan.ResourceVersion))
+
+	// the changes are applied after approved
+	plan.Spec.Approved = true
+	plan.Spec.Approver = approver
+	plan.Generation = 2
+	Expect(c.Update(ctx, plan)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	plan = getPlan()
+	Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanApplied))
+	Expect(plan.Status.ObservedGeneration).Should(Equal(int64(2)))
+	Expect(plan.Status.AppliedTime).ShouldNot(BeNil())
+	policy := &securityv1alpha1.SecurityPolicy{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "db"}, policy)).Should(Succeed())
+	Expect(policy.Spec.IngressRules).Should(HaveLen(1))
+
+	// the plan applied is not re-planned
+	Expect(c.Delete(ctx, policy)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getPlan().ResourceVersion).Should(Equal(plan.ResourceVersion))
+}
+
+func TestReconcileDelete(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		&securityv1alpha1.PolicyPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "remove-db"},
+			Spec: securityv1alpha1.PolicyPlanSpec{
+				Changes: []securityv1alpha1.PolicyChange{
+					{Operation: securityv1alpha1.PolicyChangeDelete, Namespace: "default", Name: "db"},
+					{Operation: securityv1alpha1.PolicyChangeDelete, Namespace: "default", Name: "not-exist"},
+				},
+				Approved: true,
+				Author:   author,
+				Approver: approver,
+			},
+		},
+		&securityv1alpha1.SecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: *newDBPolicySpec()},
+		newEndpoint("db01", "10.0.2.1", "db"),
+	)
+	r := &Reconciler{Client: &reviewClient{Client: c}}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "remove-db"}}
+
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	plan := &securityv1alpha1.PolicyPlan{}
+	Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+	Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanApplied))
+	Expect(plan.Status.AffectedEndpoints).Should(Equal([]string{"default/db01"}))
+	Expect(plan.Status.ReachabilityChanges).Should(BeEmpty())
+
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	Expect(c.List(ctx, &policyList)).Should(Succeed())
+	Expect(policyList.Items).Should(BeEmpty())
+}
+
+func TestReconcileOverCapacity(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	db01 := newEndpoint("db01", "10.0.2.1", "db")
+	db01.Status.Agents = []string{"node01"}
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "node01"},
+		Capacity:   &agentv1alpha1.AgentCapacity{MaxPolicyFlows: 1},
+	}
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		&securityv1alpha1.PolicyPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-web-db"},
+			Spec: securityv1alpha1.PolicyPlanSpec{
+				Changes:  []securityv1alpha1.PolicyChange{{Namespace: "default", Name: "db", Spec: newDBPolicySpec("web")}},
+				Approved: true,
+				Author:   author,
+				Approver: approver,
+			},
+		},
+		&securityv1alpha1.SecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: *newDBPolicySpec()},
+		newEndpoint("web01", "10.0.1.1", "web"),
+		db01,
+		agentInfo,
+	)
+	r := &Reconciler{Client: &reviewClient{Client: c}}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "allow-web-db"}}
+
+	// the ingress rule from web01 and the default rule of db01 on node01
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	plan := &securityv1alpha1.PolicyPlan{}
+	Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+	Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanFailed))
+	Expect(plan.Status.Message).Should(ContainSubstring("node01"))
+	Expect(plan.Status.NodeFlowEstimates).Should(Equal([]securityv1alpha1.NodeFlowEstimate{
+		{Node: "node01", Before: 1, After: 2, Limit: 1},
+	}))
+	policy := &securityv1alpha1.SecurityPolicy{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "db"}, policy)).Should(Succeed())
+	Expect(policy.Spec.IngressRules).Should(BeEmpty())
+
+	// applied after the capacity raised
+	agentInfo = &agentv1alpha1.AgentInfo{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Name: "node01"}, agentInfo)).Should(Succeed())
+	agentInfo.Capacity.MaxPolicyFlows = 2
+	Expect(c.Update(ctx, agentInfo)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	plan = &securityv1alpha1.PolicyPlan{}
+	Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+	Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanApplied))
+}
+
+func TestReconcileAccessDenied(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	newClient := func(denied, verb string) *reviewClient {
+		c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+			&securityv1alpha1.PolicyPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "remove-db"},
+				Spec: securityv1alpha1.PolicyPlanSpec{
+					Changes:  []securityv1alpha1.PolicyChange{{Operation: securityv1alpha1.PolicyChangeDelete, Namespace: "default", Name: "db"}},
+					Approved: true,
+					Author:   author,
+					Approver: approver,
+				},
+			},
+			&securityv1alpha1.SecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: *newDBPolicySpec()},
+		)
+		return &reviewClient{Client: c, denied: denied, verb: verb}
+	}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "remove-db"}}
+
+	for _, denied := range []struct{ user, verb string }{
+		{user: author.Username, verb: "delete"},
+		{user: approver.Username, verb: "delete"},
+		{user: approver.Username, verb: "approve"},
+	} {
+		c := newClient(denied.user, denied.verb)
+		r := &Reconciler{Client: c}
+		_, err := r.Reconcile(req)
+		Expect(err).Should(HaveOccurred())
+
+		plan := &securityv1alpha1.PolicyPlan{}
+		Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+		Expect(plan.Status.Phase).Should(Equal(securityv1alpha1.PolicyPlanFailed))
+		Expect(plan.Status.Message).Should(ContainSubstring(denied.user))
+		policy := &securityv1alpha1.SecurityPolicy{}
+		Expect(c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "db"}, policy)).Should(Succeed())
+	}
+
+	// the plan without the approver recorded is never applied
+	c := newClient("", "")
+	plan := &securityv1alpha1.PolicyPlan{}
+	Expect(c.Get(ctx, req.NamespacedName, plan)).Should(Succeed())
+	plan.Spec.Approver = nil
+	Expect(c.Update(ctx, plan)).Should(Succeed())
+	_, err := (&Reconciler{Client: c}).Reconcile(req)
+	Expect(err).Should(HaveOccurred())
+	policy := &securityv1alpha1.SecurityPolicy{}
+	Expect(c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "db"}, policy)).Should(Succeed())
+}
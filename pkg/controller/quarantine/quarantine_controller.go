@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quarantine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+const (
+	// PolicyPrefix is the name prefix of the policies which enforce quarantines
+	PolicyPrefix = "quarantine-"
+	// SourceQuarantine is the value of constants.QuarantineSourceLabelKey on quarantine policies
+	SourceQuarantine = "quarantine"
+
+	ReasonEnforced = "QuarantineEnforced"
+	ReasonUpdated  = "QuarantineUpdated"
+	ReasonExpired  = "QuarantineExpired"
+	ReasonReleased = "QuarantineReleased"
+	ReasonFailed   = "QuarantineFailed"
+)
+
+// Reconciler watch quarantines, enforce each of them with a tier0 SecurityPolicy,
+// and remove the quarantines after expired.
+type Reconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Reconcile receive quarantine from work queue, synchronize the quarantine policy.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("QuarantineReconciler received quarantine %s reconcile", req.NamespacedName)
+
+	quarantine := securityv1alpha1.Quarantine{}
+	if err := r.Get(ctx, req.NamespacedName, &quarantine); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, r.release(ctx, req.Namespace, req.Name)
+		}
+		klog.Errorf("unable to fetch quarantine %s: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	if !quarantine.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	expireTime := ExpireTime(&quarantine)
+	if expireTime != nil && !time.Now().Before(expireTime.Time) {
+		r.Recorder.Eventf(&quarantine, corev1.EventTypeNormal, ReasonExpired, "quarantine expired at %s", expireTime.UTC().Format(time.RFC3339))
+		if err := r.Delete(ctx, &quarantine); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("unable to remove expired quarantine %s: %s", req.NamespacedName, err)
+			return ctrl.Result{}, err
+		}
+		klog.Infof("remove expired quarantine %s", req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.syncPolicy(ctx, &quarantine); err != nil {
+		r.Recorder.Eventf(&quarantine, corev1.EventTypeWarning, ReasonFailed, "unable to enforce quarantine: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	expectStatus := securityv1alpha1.QuarantineStatus{
+		Policy:     PolicyPrefix + quarantine.Name,
+		ExpireTime: expireTime,
+	}
+	if !reflect.DeepEqual(quarantine.Status, expectStatus) {
+		quarantine.Status = expectStatus
+		if err := r.Status().Update(ctx, &quarantine); err != nil {
+			klog.Errorf("failed to update quarantine %s status: %s", req.NamespacedName, err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if expireTime != nil {
+		return ctrl.Result{RequeueAfter: time.Until(expireTime.Time)}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Quarantine Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("quarantine-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.Quarantine{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// enqueue the owner quarantine when its policy been modified or removed
+	return c.Watch(&source.Kind{Type: &securityv1alpha1.SecurityPolicy{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &securityv1alpha1.Quarantine{},
+		IsController: true,
+	})
+}
+
+func (r *Reconciler) syncPolicy(ctx context.Context, quarantine *securityv1alpha1.Quarantine) error {
+	policy := NewQuarantinePolicy(quarantine)
+	if err := controllerutil.SetControllerReference(quarantine, policy, r.Scheme); err != nil {
+		return err
+	}
+
+	var oldPolicy securityv1alpha1.SecurityPolicy
+	err := r.Get(ctx, client.ObjectKey{Namespace: policy.Namespace, Name: policy.Name}, &oldPolicy)
+	switch {
+	case errors.IsNotFound(err):
+		if err = r.Create(ctx, policy); err != nil {
+			return fmt.Errorf("create quarantine policy %s/%s: %s", policy.Namespace, policy.Name, err)
+		}
+		klog.Infof("quarantine %s/%s enforced by policy %s", quarantine.Namespace, quarantine.Name, policy.Name)
+		r.Recorder.Eventf(quarantine, corev1.EventTypeWarning, ReasonEnforced, "quarantine enforced by policy %s", policy.Name)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if reflect.DeepEqual(oldPolicy.Spec, policy.Spec) && reflect.DeepEqual(oldPolicy.Labels, policy.Labels) {
+		return nil
+	}
+	oldPolicy.Labels = policy.Labels
+	oldPolicy.Spec = policy.Spec
+	if err = r.Update(ctx, &oldPolicy); err != nil {
+		return fmt.Errorf("update quarantine policy %s/%s: %s", policy.Namespace, policy.Name, err)
+	}
+	klog.Infof("quarantine %s/%s policy %s updated", quarantine.Namespace, quarantine.Name, policy.Name)
+	r.Recorder.Eventf(quarantine, corev1.EventTypeNormal, ReasonUpdated, "quarantine policy %s updated", policy.Name)
+	return nil
+}
+
+// release remove the policy of a removed quarantine immediately, without
+// waiting for garbage collection.
+func (r *Reconciler) release(ctx context.Context, namespace, name string) error {
+	policy := securityv1alpha1.SecurityPolicy{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: PolicyPrefix + name}, &policy)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if policy.Labels[constants.QuarantineSourceLabelKey] != SourceQuarantine {
+		return nil
+	}
+
+	if err = r.Delete(ctx, &policy); client.IgnoreNotFound(err) != nil {
+		klog.Errorf("unable to remove quarantine policy %s/%s: %s", namespace, policy.Name, err)
+		return err
+	}
+	klog.Infof("quarantine %s/%s released", namespace, name)
+
+	// the quarantine has gone, record the event with its reference only
+	r.Recorder.Eventf(&securityv1alpha1.Quarantine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}, corev1.EventTypeNormal, ReasonReleased, "quarantine released, policy %s removed", policy.Name)
+	return nil
+}
+
+// ExpireTime return the time when the quarantine expired, nil means never expire.
+func ExpireTime(quarantine *securityv1alpha1.Quarantine) *metav1.Time {
+	if quarantine.Spec.TTL == nil {
+		return nil
+	}
+	expireTime := metav1.NewTime(quarantine.CreationTimestamp.Add(quarantine.Spec.TTL.Duration))
+	return &expireTime
+}
+
+// NewQuarantinePolicy return the policy enforce the quarantine. The policy drop all
+// traffics of the quarantined endpoints except the exceptions. It's in tier0, which
+// takes precedence over all the other tiers.
+func NewQuarantinePolicy(quarantine *securityv1alpha1.Quarantine) *securityv1alpha1.SecurityPolicy {
+	policy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PolicyPrefix + quarantine.Name,
+			Namespace: quarantine.Namespace,
+			Labels: map[string]string{
+				constants.QuarantineSourceLabelKey: SourceQuarantine,
+			},
+		},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:          constants.Tier0,
+			SymmetricMode: true,
+			AppliedTo:     quarantine.Spec.DeepCopy().AppliedTo,
+			DefaultRule:   securityv1alpha1.DefaultRuleDrop,
+			PolicyTypes:   []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+
+	if len(quarantine.Spec.Exceptions) != 0 {
+		exceptions := quarantine.Spec.DeepCopy().Exceptions
+		policy.Spec.IngressRules = []securityv1alpha1.Rule{{Name: "exceptions", From: exceptions}}
+		policy.Spec.EgressRules = []securityv1alpha1.Rule{{Name: "exceptions", To: exceptions}}
+	}
+
+	return policy
+}
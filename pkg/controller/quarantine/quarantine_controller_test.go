@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quarantine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func newQuarantine(name string, ttl *metav1.Duration, creationTime time.Time) *securityv1alpha1.Quarantine {
+	endpoint := "ep01"
+	return &securityv1alpha1.Quarantine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(creationTime),
+		},
+		Spec: securityv1alpha1.QuarantineSpec{
+			AppliedTo:  []securityv1alpha1.ApplyToPeer{{Endpoint: &endpoint}},
+			Exceptions: []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "192.168.0.0/24"}}},
+			TTL:        ttl,
+		},
+	}
+}
+
+func TestNewQuarantinePolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	policy := NewQuarantinePolicy(newQuarantine("q01", nil, time.Now()))
+	Expect(policy.Name).Should(Equal(PolicyPrefix + "q01"))
+	Expect(policy.Namespace).Should(Equal("default"))
+	Expect(policy.Labels[constants.QuarantineSourceLabelKey]).Should(Equal(SourceQuarantine))
+	Expect(policy.Spec.Tier).Should(Equal(constants.Tier0))
+	Expect(policy.Spec.DefaultRule).Should(Equal(securityv1alpha1.DefaultRuleDrop))
+	Expect(policy.Spec.PolicyTypes).Should(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+	Expect(*policy.Spec.AppliedTo[0].Endpoint).Should(Equal("ep01"))
+	Expect(policy.Spec.IngressRules).Should(HaveLen(1))
+	Expect(policy.Spec.IngressRules[0].From[0].IPBlock.CIDR).Should(Equal("192.168.0.0/24"))
+	Expect(policy.Spec.EgressRules).Should(HaveLen(1))
+	Expect(policy.Spec.EgressRules[0].To[0].IPBlock.CIDR).Should(Equal("192.168.0.0/24"))
+}
+
+func TestExpireTime(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Now()
+	Expect(ExpireTime(newQuarantine("q01", nil, now))).Should(BeNil())
+	Expect(ExpireTime(newQuarantine("q01", &metav1.Duration{Duration: time.Hour}, now)).Time).Should(BeTemporally("==", now.Add(time.Hour)))
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	active := newQuarantine("active", &metav1.Duration{Duration: time.Hour}, time.Now())
+	expired := newQuarantine("expired", &metav1.Duration{Duration: time.Minute}, time.Now().Add(-time.Hour))
+	k8sClient := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, active, expired)
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Client: k8sClient, Scheme: clientsetscheme.Scheme, Recorder: recorder}
+
+	t.Run("should enforce quarantine with policy", func(t *testing.T) {
+		RegisterTestingT(t)
+		req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "active"}}
+		result, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.RequeueAfter).Should(BeNumerically(">", 0))
+
+		var policy securityv1alpha1.SecurityPolicy
+		Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: PolicyPrefix + "active"}, &policy)).Should(Succeed())
+		Expect(policy.Spec.Tier).Should(Equal(constants.Tier0))
+		Expect(policy.OwnerReferences).Should(HaveLen(1))
+
+		var quarantine securityv1alpha1.Quarantine
+		Expect(k8sClient.Get(ctx, req.NamespacedName, &quarantine)).Should(Succeed())
+		Expect(quarantine.Status.Policy).Should(Equal(policy.Name))
+		Expect(quarantine.Status.ExpireTime).ShouldNot(BeNil())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonEnforced)))
+	})
+
+	t.Run("should remove expired quarantine", func(t *testing.T) {
+		RegisterTestingT(t)
+		req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "expired"}}
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var quarantine securityv1alpha1.Quarantine
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, req.NamespacedName, &quarantine))).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonExpired)))
+	})
+
+	t.Run("should remove policy when quarantine released", func(t *testing.T) {
+		RegisterTestingT(t)
+		req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "active"}}
+		Expect(k8sClient.Delete(ctx, active)).Should(Succeed())
+		_, err := r.Reconcile(req)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var policy securityv1alpha1.SecurityPolicy
+		err = k8sClient.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: PolicyPrefix + "active"}, &policy)
+		Expect(errors.IsNotFound(err)).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(ReasonReleased)))
+	})
+}
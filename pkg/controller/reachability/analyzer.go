@@ -0,0 +1,366 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reachability
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	policycache "github.com/everoute/everoute/pkg/agent/controller/policy/cache"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+// orderedTiers returns the tiers of the policies in the order the datapath evaluates, the Tiers
+// are evaluated by their priorities before tier2.
+func orderedTiers(customTiers []securityv1alpha1.Tier) []string {
+	sorted := append([]securityv1alpha1.Tier(nil), customTiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Spec.Priority < sorted[j].Spec.Priority })
+
+	tiers := []string{constants.Tier0, constants.Tier1, constants.TierECP}
+	for _, tier := range sorted {
+		tiers = append(tiers, tier.Name)
+	}
+	return append(tiers, constants.Tier2)
+}
+
+const (
+	ingress = "ingress"
+	egress  = "egress"
+)
+
+// analyzer evaluates the traffic between the group members with the policies, as the datapath
+// does: the egress is evaluated on the source and the ingress on the destination, in each
+// direction the tiers are evaluated in order, the allow rules matched accept the traffic,
+// otherwise the default rules of the policies applied drop it, otherwise the next tier is
+// evaluated, the GlobalPolicy decides the traffic matches none of the tiers.
+type analyzer struct {
+	state *clusterState
+	tiers []string
+	// groupEndpoints are the endpoints in each group, indexed by the group name
+	groupEndpoints map[string]map[groupv1alpha1.EndpointReference]struct{}
+}
+
+func newAnalyzer(state *clusterState) *analyzer {
+	groupEndpoints := make(map[string]map[groupv1alpha1.EndpointReference]struct{}, len(state.groupMembers))
+	for group, members := range state.groupMembers {
+		endpoints := make(map[groupv1alpha1.EndpointReference]struct{}, len(members))
+		for _, member := range members {
+			endpoints[member.EndpointReference] = struct{}{}
+		}
+		groupEndpoints[group] = endpoints
+	}
+	return &analyzer{state: state, tiers: orderedTiers(state.tiers), groupEndpoints: groupEndpoints}
+}
+
+// analyze returns the verdict of the traffic from the group to the group on the port, and
+// the rules decided the verdict.
+func (a *analyzer) analyze(from, to string, port securityv1alpha1.ReachabilityPort) (securityv1alpha1.ReachabilityVerdict, []string) {
+	var allowed, denied int
+	rules := sets.NewString()
+	for i := range a.state.groupMembers[from] {
+		src := &a.state.groupMembers[from][i]
+		for j := range a.state.groupMembers[to] {
+			dst := &a.state.groupMembers[to][j]
+			if src.EndpointReference == dst.EndpointReference {
+				// the traffic of the endpoint to itself is not through the policies
+				continue
+			}
+			allow, decidedBy := a.reachable(src, dst, port)
+			if allow {
+				allowed++
+			} else {
+				denied++
+			}
+			rules.Insert(decidedBy...)
+		}
+	}
+
+	var ruleList []string
+	if rules.Len() != 0 {
+		// keep nil if empty, as the entries read back from the apiserver
+		ruleList = rules.List()
+	}
+	switch {
+	case allowed != 0 && denied == 0:
+		return securityv1alpha1.ReachabilityAllow, ruleList
+	case allowed != 0:
+		return securityv1alpha1.ReachabilityPartial, ruleList
+	default:
+		return securityv1alpha1.ReachabilityDeny, ruleList
+	}
+}
+
+// reachable returns true if the traffic from the src to the dst on the port is allowed by
+// both the egress of the src and the ingress of the dst.
+func (a *analyzer) reachable(src, dst *groupv1alpha1.GroupMember, port securityv1alpha1.ReachabilityPort) (bool, []string) {
+	allow, egressRules := a.evaluate(egress, src, dst, dst, port)
+	if !allow {
+		return false, egressRules
+	}
+	allow, ingressRules := a.evaluate(ingress, dst, src, dst, port)
+	return allow, append(egressRules, ingressRules...)
+}
+
+// evaluate the traffic of the direction on the local endpoint, the remote is the peer of the
+// traffic, and the dst resolves the named ports.
+func (a *analyzer) evaluate(direction string, local, remote, dst *groupv1alpha1.GroupMember, port securityv1alpha1.ReachabilityPort) (bool, []string) {
+	for _, tier := range a.tiers {
+		var allowRules, dropRules []string
+		for i := range a.state.policies {
+			policy := &a.state.policies[i]
+			if policy.Spec.Tier != tier || policy.Spec.SecurityPolicyEnforcementMode == securityv1alpha1.MonitorMode {
+				continue
+			}
+			prefix := policy.Namespace + "/" + policy.Name + "/"
+
+			if a.applied(policy, local) {
+				if rules, enabled := policyRules(policy, direction); enabled {
+					for j := range rules {
+						if a.ruleMatches(policy, &rules[j], direction, remote, dst, port, false) {
+							allowRules = append(allowRules, prefix+direction+"."+rules[j].Name)
+						}
+					}
+					if policy.Spec.DefaultRule == "" || policy.Spec.DefaultRule == securityv1alpha1.DefaultRuleDrop {
+						dropRules = append(dropRules, prefix+"default."+direction)
+					}
+				}
+			}
+
+			// the rules of the opposite direction generate the symmetric rules on the remote
+			if policy.Spec.SymmetricMode && a.applied(policy, remote) {
+				opposite := oppositeDirection(direction)
+				if rules, enabled := policyRules(policy, opposite); enabled {
+					for j := range rules {
+						if a.ruleMatches(policy, &rules[j], opposite, local, dst, port, true) {
+							allowRules = append(allowRules, prefix+opposite+"."+rules[j].Name)
+						}
+					}
+				}
+			}
+		}
+
+		if len(allowRules) != 0 {
+			return true, allowRules
+		}
+		if len(dropRules) != 0 {
+			return false, dropRules
+		}
+	}
+
+	for _, globalPolicy := range a.state.globalPolicies {
+		rule := "globalpolicy/" + globalPolicy.Name
+		if globalPolicy.Spec.DefaultAction == securityv1alpha1.GlobalDefaultActionDrop &&
+			globalPolicy.Spec.GlobalPolicyEnforcementMode != securityv1alpha1.MonitorMode {
+			return false, []string{rule}
+		}
+		return true, []string{rule}
+	}
+	return true, nil
+}
+
+// policyRules returns the rules of the direction, and whether the direction enabled.
+func policyRules(policy *securityv1alpha1.SecurityPolicy, direction string) ([]securityv1alpha1.Rule, bool) {
+	ingressEnabled, egressEnabled := policy.IsEnable()
+	if direction == ingress {
+		return policy.Spec.IngressRules, ingressEnabled
+	}
+	return policy.Spec.EgressRules, egressEnabled
+}
+
+func oppositeDirection(direction string) string {
+	if direction == ingress {
+		return egress
+	}
+	return ingress
+}
+
+// applied returns true if the policy applies to the member, the empty AppliedTo applies to
+// all the endpoints.
+func (a *analyzer) applied(policy *securityv1alpha1.SecurityPolicy, member *groupv1alpha1.GroupMember) bool {
+	if len(policy.Spec.AppliedTo) == 0 {
+		return true
+	}
+	for _, applied := range policy.Spec.AppliedTo {
+		peer := ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, applied)
+		if a.peerMatches(policy, peer, member) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMatches returns true if the allow rule matches the remote on the port. Only the peers
+// generating the symmetric rules are matched if symmetric.
+func (a *analyzer) ruleMatches(policy *securityv1alpha1.SecurityPolicy, rule *securityv1alpha1.Rule, direction string,
+	remote, dst *groupv1alpha1.GroupMember, port securityv1alpha1.ReachabilityPort, symmetric bool) bool {
+	if rule.Action != "" && rule.Action != securityv1alpha1.RuleActionAllow {
+		return false
+	}
+	if !portsMatch(rule.Ports, dst, port) {
+		return false
+	}
+
+	peers := rule.From
+	if direction == egress {
+		peers = rule.To
+	}
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if symmetric && peer.DisableSymmetric {
+			continue
+		}
+		if a.peerMatches(policy, peer, remote) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerMatches returns true if the peer selects the member. The FQDN peers are resolved on
+// the agents, which never match.
+func (a *analyzer) peerMatches(policy *securityv1alpha1.SecurityPolicy, peer securityv1alpha1.SecurityPolicyPeer, member *groupv1alpha1.GroupMember) bool {
+	switch {
+	case peer.Builtin == securityv1alpha1.BuiltinPeerClusterExternal:
+		return false
+	case peer.IPBlock != nil:
+		return ipBlockContains(peer.IPBlock.CIDR, peer.IPBlock.Except, member.IPs)
+	case peer.FQDN != "":
+		return false
+	}
+	group := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer)
+	if group == nil {
+		return false
+	}
+	_, ok := a.groupEndpoints[group.Name][member.EndpointReference]
+	return ok
+}
+
+// ipBlockContains returns true if any of the ips in the cidr and not in the excepts.
+func ipBlockContains(cidr string, excepts []string, ips []types.IPAddress) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	for _, item := range ips {
+		ip := net.ParseIP(strings.Split(string(item), "/")[0])
+		if ip == nil || !ipNet.Contains(ip) {
+			continue
+		}
+		excepted := false
+		for _, except := range excepts {
+			if _, exceptNet, err := net.ParseCIDR(except); err == nil && exceptNet.Contains(ip) {
+				excepted = true
+				break
+			}
+		}
+		if !excepted {
+			return true
+		}
+	}
+	return false
+}
+
+// portsMatch returns true if the ports match the port, the empty ports match all. The named
+// ports are resolved from the ports of the dst.
+func portsMatch(ports []securityv1alpha1.SecurityPolicyPort, dst *groupv1alpha1.GroupMember, port securityv1alpha1.ReachabilityPort) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, item := range ports {
+		if item.Protocol != port.Protocol {
+			continue
+		}
+		if item.Protocol != securityv1alpha1.ProtocolTCP && item.Protocol != securityv1alpha1.ProtocolUDP {
+			// the protocols without port match all the traffic of the protocol
+			return true
+		}
+
+		if item.Type == securityv1alpha1.PortTypeName {
+			names := sets.NewString(strings.Split(item.PortRange, ",")...)
+			for _, namedPort := range dst.Ports {
+				if namedPort.Protocol == port.Protocol && namedPort.Port == port.Port && names.Has(namedPort.Name) {
+					return true
+				}
+			}
+			continue
+		}
+
+		for _, portRange := range strings.Split(item.PortRange, ",") {
+			begin, end, err := policycache.UnmarshalPortRange(strings.TrimSpace(portRange))
+			if err != nil {
+				continue
+			}
+			if begin == 0 && end == 0 {
+				return true
+			}
+			if port.Port >= int32(begin) && port.Port <= int32(end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyGroups returns the groups of the endpoints the policy applied to and the peers selected.
+func policyGroups(policy *securityv1alpha1.SecurityPolicy) []*groupv1alpha1.EndpointGroup {
+	var peers []securityv1alpha1.SecurityPolicyPeer
+	for _, applied := range policy.Spec.AppliedTo {
+		peers = append(peers, ctrlpolicy.AppliedAsSecurityPeer(policy.Namespace, applied))
+	}
+	for _, rule := range policy.Spec.IngressRules {
+		peers = append(peers, rule.From...)
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		peers = append(peers, rule.To...)
+	}
+
+	var groups []*groupv1alpha1.EndpointGroup
+	for _, peer := range peers {
+		if group := ctrlpolicy.PeerAsEndpointGroup(policy.Namespace, policy.Spec.VRF, peer); group != nil {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// defaultGroups returns the names of the EndpointGroups not generated for the policies.
+func defaultGroups(groups []groupv1alpha1.EndpointGroup) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if isGeneratedGroup(group.Name) {
+			continue
+		}
+		names = append(names, group.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isGeneratedGroup(name string) bool {
+	return strings.HasPrefix(name, "sys-") ||
+		strings.HasPrefix(name, constants.ClusterInternalEndpoints) ||
+		strings.HasPrefix(name, constants.AllEpWithNamedPort)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reachability
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	groupctrl "github.com/everoute/everoute/pkg/controller/group"
+)
+
+// Reconciler analyzes the reachability between the groups of the ReachabilityMatrices, the
+// matrices are re-analyzed on the policies or the groups changed.
+type Reconciler struct {
+	client.Client
+}
+
+// clusterState is the objects the reachability analyzed against.
+type clusterState struct {
+	globalPolicies []securityv1alpha1.GlobalPolicy
+	policies       []securityv1alpha1.SecurityPolicy
+	tiers          []securityv1alpha1.Tier
+	groups         []groupv1alpha1.EndpointGroup
+	// groupMembers are the members of each group, indexed by the group name
+	groupMembers map[string][]groupv1alpha1.GroupMember
+}
+
+// Reconcile analyzes the matrix, update the status if the result changed.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("ReachabilityReconciler received matrix %s reconcile", req.Name)
+
+	matrix := securityv1alpha1.ReachabilityMatrix{}
+	if err := r.Get(ctx, req.NamespacedName, &matrix); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		klog.Errorf("unable to fetch reachability matrix %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	state, err := r.fetchClusterState(ctx)
+	if err != nil {
+		klog.Errorf("unable to fetch cluster state: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	status := evaluate(&matrix.Spec, state)
+	if statusEqual(&matrix.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	status.GeneratedTime = metav1.NewTime(time.Now())
+	matrix.Status = *status
+	if err = r.Status().Update(ctx, &matrix); err != nil {
+		klog.Errorf("failed to update reachability matrix %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	klog.Infof("reachability matrix %s analyzed, %d allowed, %d denied, %d partial", req.Name, status.Allowed, status.Denied, status.Partial)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Reachability Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("reachability-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.ReachabilityMatrix{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+
+	enqueueMatrices := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.allMatrices),
+	}
+	for _, object := range []runtime.Object{
+		&securityv1alpha1.GlobalPolicy{},
+		&securityv1alpha1.SecurityPolicy{},
+		&securityv1alpha1.Tier{},
+		&groupv1alpha1.EndpointGroup{},
+		&groupv1alpha1.GroupMembers{},
+		&groupv1alpha1.GroupMembersPatch{},
+	} {
+		if err = c.Watch(&source.Kind{Type: object}, enqueueMatrices); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) allMatrices(handler.MapObject) []reconcile.Request {
+	matrixList := securityv1alpha1.ReachabilityMatrixList{}
+	if err := r.List(context.Background(), &matrixList); err != nil {
+		klog.Errorf("unable to list reachability matrices: %s", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(matrixList.Items))
+	for _, matrix := range matrixList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: matrix.Name}})
+	}
+	return requests
+}
+
+func (r *Reconciler) fetchClusterState(ctx context.Context) (*clusterState, error) {
+	globalPolicyList := securityv1alpha1.GlobalPolicyList{}
+	if err := r.List(ctx, &globalPolicyList); err != nil {
+		return nil, fmt.Errorf("list globalpolicies: %s", err)
+	}
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := r.List(ctx, &policyList); err != nil {
+		return nil, fmt.Errorf("list policies: %s", err)
+	}
+	tierList := securityv1alpha1.TierList{}
+	if err := r.List(ctx, &tierList); err != nil {
+		return nil, fmt.Errorf("list tiers: %s", err)
+	}
+	groupList := groupv1alpha1.EndpointGroupList{}
+	if err := r.List(ctx, &groupList); err != nil {
+		return nil, fmt.Errorf("list endpointgroups: %s", err)
+	}
+	groupMembersList, err := groupctrl.ListGroupMembers(ctx, r.Client)
+	if err != nil {
+		return nil, fmt.Errorf("list groupmembers: %s", err)
+	}
+
+	groupMembers := make(map[string][]groupv1alpha1.GroupMember, len(groupMembersList))
+	for _, item := range groupMembersList {
+		groupMembers[item.Name] = item.GroupMembers
+	}
+	return &clusterState{
+		globalPolicies: globalPolicyList.Items,
+		policies:       policyList.Items,
+		tiers:          tierList.Items,
+		groups:         groupList.Items,
+		groupMembers:   groupMembers,
+	}, nil
+}
+
+// evaluate the verdicts of the traffic from each group to each group on each port of the spec.
+func evaluate(spec *securityv1alpha1.ReachabilityMatrixSpec, state *clusterState) *securityv1alpha1.ReachabilityMatrixStatus {
+	groups := spec.Groups
+	if len(groups) == 0 {
+		groups = defaultGroups(state.groups)
+	}
+
+	a := newAnalyzer(state)
+	status := &securityv1alpha1.ReachabilityMatrixStatus{}
+	for _, port := range spec.Ports {
+		for _, from := range groups {
+			for _, to := range groups {
+				verdict, rules := a.analyze(from, to, port)
+				status.Entries = append(status.Entries, securityv1alpha1.ReachabilityEntry{
+					From:     from,
+					To:       to,
+					Protocol: port.Protocol,
+					Port:     port.Port,
+					Verdict:  verdict,
+					Rules:    rules,
+				})
+
+				switch verdict {
+				case securityv1alpha1.ReachabilityAllow:
+					status.Allowed++
+				case securityv1alpha1.ReachabilityDeny:
+					status.Denied++
+				case securityv1alpha1.ReachabilityPartial:
+					status.Partial++
+				}
+			}
+		}
+	}
+	return status
+}
+
+// Analyze evaluates the spec with the policies given instead of the policies in the cluster,
+// e.g. the policies proposed. The members of the groups the policies select are computed from
+// the endpoints if the groups not generated yet.
+func Analyze(ctx context.Context, c client.Client, spec *securityv1alpha1.ReachabilityMatrixSpec, policies []securityv1alpha1.SecurityPolicy) (*securityv1alpha1.ReachabilityMatrixStatus, error) {
+	state, err := (&Reconciler{Client: c}).fetchClusterState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.policies = policies
+
+	for i := range policies {
+		for _, group := range policyGroups(&policies[i]) {
+			if _, ok := state.groupMembers[group.Name]; ok {
+				continue
+			}
+			members, err := groupctrl.FetchGroupMembers(ctx, c, group)
+			if err != nil {
+				return nil, fmt.Errorf("fetch group %s members: %s", group.Name, err)
+			}
+			state.groupMembers[group.Name] = members.GroupMembers
+		}
+	}
+	return evaluate(spec, state), nil
+}
+
+func statusEqual(actual, expect *securityv1alpha1.ReachabilityMatrixStatus) bool {
+	return actual.Allowed == expect.Allowed &&
+		actual.Denied == expect.Denied &&
+		actual.Partial == expect.Partial &&
+		reflect.DeepEqual(actual.Entries, expect.Entries)
+}
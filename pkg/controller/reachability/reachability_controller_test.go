@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reachability
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+	"github.com/everoute/everoute/pkg/constants"
+	ctrlpolicy "github.com/everoute/everoute/pkg/controller/policy"
+	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+var (
+	webSelector = &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}
+	dbSelector  = &labels.Selector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}
+
+	web01    = newMember("web01", "10.0.1.1")
+	web02    = newMember("web02", "10.0.1.2")
+	db01     = newMember("db01", "10.0.2.1", securityv1alpha1.NamedPort{Name: "mysql", Port: 3306, Protocol: securityv1alpha1.ProtocolTCP})
+	client01 = newMember("client01", "10.0.3.1")
+
+	http  = securityv1alpha1.ReachabilityPort{Protocol: securityv1alpha1.ProtocolTCP, Port: 80}
+	mysql = securityv1alpha1.ReachabilityPort{Protocol: securityv1alpha1.ProtocolTCP, Port: 3306}
+)
+
+func newMember(name, ip string, ports ...securityv1alpha1.NamedPort) groupv1alpha1.GroupMember {
+	return groupv1alpha1.GroupMember{
+		EndpointReference: groupv1alpha1.EndpointReference{ExternalIDName: "iface-id", ExternalIDValue: name},
+		IPs:               []types.IPAddress{types.IPAddress(ip)},
+		Ports:             ports,
+	}
+}
+
+func newGroupMembers(name string, members ...groupv1alpha1.GroupMember) *groupv1alpha1.GroupMembers {
+	return &groupv1alpha1.GroupMembers{ObjectMeta: metav1.ObjectMeta{Name: name}, GroupMembers: members}
+}
+
+// selectorGroup returns the name of the group the policies generated for the selector.
+func selectorGroup(selector *labels.Selector) string {
+	return ctrlpolicy.PeerAsEndpointGroup("default", "", securityv1alpha1.SecurityPolicyPeer{EndpointSelector: selector}).Name
+}
+
+func newPolicy(name, tier string, applied *labels.Selector, ingress ...securityv1alpha1.Rule) *securityv1alpha1.SecurityPolicy {
+	return &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:         tier,
+			AppliedTo:    []securityv1alpha1.ApplyToPeer{{EndpointSelector: applied}},
+			IngressRules: ingress,
+		},
+	}
+}
+
+// newTestObjects returns the groups web, db and client, the db allows mysql from the web,
+// the web allows http from the client cidr, and web02 is isolated in tier0.
+func newTestObjects() []*groupv1alpha1.GroupMembers {
+	return []*groupv1alpha1.GroupMembers{
+		newGroupMembers("web", web01, web02),
+		newGroupMembers("db", db01),
+		newGroupMembers("client", client01),
+		newGroupMembers(selectorGroup(webSelector), web01, web02),
+		newGroupMembers(selectorGroup(dbSelector), db01),
+	}
+}
+
+func newTestState() *clusterState {
+	isolate := newPolicy("isolate", constants.Tier0, nil)
+	isolate.Spec.AppliedTo = []securityv1alpha1.ApplyToPeer{{Endpoint: &[]string{"web02"}[0]}}
+	isolate.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+
+	state := &clusterState{
+		globalPolicies: []securityv1alpha1.GlobalPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec:       securityv1alpha1.GlobalPolicySpec{DefaultAction: securityv1alpha1.GlobalDefaultActionAllow},
+		}},
+		policies: []securityv1alpha1.SecurityPolicy{
+			*newPolicy("db", constants.Tier2, dbSelector, securityv1alpha1.Rule{
+				Name:  "mysql",
+				Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "mysql", Type: securityv1alpha1.PortTypeName}},
+				From:  []securityv1alpha1.SecurityPolicyPeer{{EndpointSelector: webSelector}},
+			}),
+			*newPolicy("web", constants.Tier2, webSelector, securityv1alpha1.Rule{
+				Name:  "http",
+				Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "80,443"}},
+				From:  []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.3.0/24"}}},
+			}),
+			*isolate,
+		},
+		groupMembers: map[string][]groupv1alpha1.GroupMember{},
+	}
+	for _, item := range newTestObjects() {
+		state.groupMembers[item.Name] = item.GroupMembers
+	}
+	state.groupMembers[ctrlpolicy.PeerAsEndpointGroup("default", "", ctrlpolicy.AppliedAsSecurityPeer("default", isolate.Spec.AppliedTo[0])).Name] = []groupv1alpha1.GroupMember{web02}
+	return state
+}
+
+func TestPortsMatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	tcp := func(portRange string) securityv1alpha1.SecurityPolicyPort {
+		return securityv1alpha1.SecurityPolicyPort{Protocol: securityv1alpha1.ProtocolTCP, PortRange: portRange}
+	}
+	Expect(portsMatch(nil, &db01, mysql)).Should(BeTrue())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{tcp("")}, &db01, mysql)).Should(BeTrue())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{tcp("80,3000-4000")}, &db01, mysql)).Should(BeTrue())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{tcp("80,443")}, &db01, mysql)).Should(BeFalse())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolUDP}}, &db01, mysql)).Should(BeFalse())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolICMP}}, &db01,
+		securityv1alpha1.ReachabilityPort{Protocol: securityv1alpha1.ProtocolICMP})).Should(BeTrue())
+
+	named := securityv1alpha1.SecurityPolicyPort{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "http,mysql", Type: securityv1alpha1.PortTypeName}
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{named}, &db01, mysql)).Should(BeTrue())
+	Expect(portsMatch([]securityv1alpha1.SecurityPolicyPort{named}, &web01, mysql)).Should(BeFalse())
+}
+
+func TestIPBlockContains(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(ipBlockContains("10.0.0.0/16", nil, web01.IPs)).Should(BeTrue())
+	Expect(ipBlockContains("10.0.0.0/16", []string{"10.0.1.0/24"}, web01.IPs)).Should(BeFalse())
+	Expect(ipBlockContains("192.168.0.0/16", nil, web01.IPs)).Should(BeFalse())
+	Expect(ipBlockContains("0.0.0.0/0", nil, nil)).Should(BeFalse())
+}
+
+func TestDefaultGroups(t *testing.T) {
+	RegisterTestingT(t)
+
+	var groups []groupv1alpha1.EndpointGroup
+	for _, name := range []string{"web", selectorGroup(webSelector), constants.ClusterInternalEndpoints, constants.AllEpWithNamedPort + "-vrf01", "db"} {
+		groups = append(groups, groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	Expect(defaultGroups(groups)).Should(Equal([]string{"db", "web"}))
+}
+
+func TestEvaluate(t *testing.T) {
+	RegisterTestingT(t)
+
+	state := newTestState()
+	entry := func(status *securityv1alpha1.ReachabilityMatrixStatus, from, to string, port securityv1alpha1.ReachabilityPort) securityv1alpha1.ReachabilityEntry {
+		for _, item := range status.Entries {
+			if item.From == from && item.To == to && item.Protocol == port.Protocol && item.Port == port.Port {
+				return item
+			}
+		}
+		return securityv1alpha1.ReachabilityEntry{}
+	}
+
+	status := evaluate(&securityv1alpha1.ReachabilityMatrixSpec{
+		Groups: []string{"web", "db", "client"},
+		Ports:  []securityv1alpha1.ReachabilityPort{http, mysql},
+	}, state)
+	Expect(status.Entries).Should(HaveLen(18))
+	Expect(status.Allowed + status.Denied + status.Partial).Should(Equal(int32(18)))
+
+	Expect(entry(status, "web", "db", mysql)).Should(Equal(securityv1alpha1.ReachabilityEntry{
+		From: "web", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 3306,
+		Verdict: securityv1alpha1.ReachabilityPartial,
+		Rules:   []string{"default/db/ingress.mysql", "default/isolate/default.egress", "globalpolicy/default"},
+	}))
+	Expect(entry(status, "client", "db", mysql).Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+	Expect(entry(status, "client", "db", mysql).Rules).Should(Equal([]string{"default/db/default.ingress", "globalpolicy/default"}))
+	Expect(entry(status, "web", "db", http).Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+	Expect(entry(status, "client", "web", http).Verdict).Should(Equal(securityv1alpha1.ReachabilityPartial))
+	Expect(entry(status, "web", "web", http).Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+	Expect(entry(status, "db", "client", http).Verdict).Should(Equal(securityv1alpha1.ReachabilityAllow))
+	// no traffic between the different endpoints in the group
+	Expect(entry(status, "db", "db", http)).Should(Equal(securityv1alpha1.ReachabilityEntry{
+		From: "db", To: "db", Protocol: securityv1alpha1.ProtocolTCP, Port: 80, Verdict: securityv1alpha1.ReachabilityDeny,
+	}))
+
+	// the policies in monitor mode don't deny the traffic
+	state.policies[2].Spec.SecurityPolicyEnforcementMode = securityv1alpha1.MonitorMode
+	status = evaluate(&securityv1alpha1.ReachabilityMatrixSpec{Groups: []string{"web", "db"}, Ports: []securityv1alpha1.ReachabilityPort{mysql}}, state)
+	Expect(entry(status, "web", "db", mysql).Verdict).Should(Equal(securityv1alpha1.ReachabilityAllow))
+
+	// the symmetric rules allow the egress of the peers
+	state.policies[1].Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+	status = evaluate(&securityv1alpha1.ReachabilityMatrixSpec{Groups: []string{"web", "db"}, Ports: []securityv1alpha1.ReachabilityPort{mysql}}, state)
+	Expect(entry(status, "web", "db", mysql).Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+	Expect(entry(status, "web", "db", mysql).Rules).Should(Equal([]string{"default/web/default.egress"}))
+	state.policies[0].Spec.SymmetricMode = true
+	status = evaluate(&securityv1alpha1.ReachabilityMatrixSpec{Groups: []string{"web", "db"}, Ports: []securityv1alpha1.ReachabilityPort{mysql}}, state)
+	Expect(entry(status, "web", "db", mysql).Verdict).Should(Equal(securityv1alpha1.ReachabilityAllow))
+	Expect(entry(status, "web", "db", mysql).Rules).Should(Equal([]string{"default/db/ingress.mysql"}))
+
+	// the global policy drops the traffic not matched by the policies
+	state.globalPolicies[0].Spec.DefaultAction = securityv1alpha1.GlobalDefaultActionDrop
+	status = evaluate(&securityv1alpha1.ReachabilityMatrixSpec{Groups: []string{"db", "client"}, Ports: []securityv1alpha1.ReachabilityPort{http}}, state)
+	Expect(entry(status, "db", "client", http).Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+	Expect(entry(status, "db", "client", http).Rules).Should(Equal([]string{"globalpolicy/default"}))
+}
+
+func TestOrderedTiers(t *testing.T) {
+	RegisterTestingT(t)
+
+	newTier := func(name string, priority int32) securityv1alpha1.Tier {
+		return securityv1alpha1.Tier{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: securityv1alpha1.TierSpec{Priority: priority}}
+	}
+	Expect(orderedTiers(nil)).Should(Equal([]string{constants.Tier0, constants.Tier1, constants.TierECP, constants.Tier2}))
+	Expect(orderedTiers([]securityv1alpha1.Tier{newTier("application", 100), newTier("emergency", 10)})).Should(Equal([]string{
+		constants.Tier0, constants.Tier1, constants.TierECP, "emergency", "application", constants.Tier2,
+	}))
+}
+
+func TestEvaluateTiers(t *testing.T) {
+	RegisterTestingT(t)
+
+	// the db is isolated by the security operators, which overrides the application policies
+	state := newTestState()
+	state.tiers = []securityv1alpha1.Tier{{ObjectMeta: metav1.ObjectMeta{Name: constants.TierSecurityOps}, Spec: securityv1alpha1.TierSpec{Priority: 50}}}
+	state.policies = append(state.policies, *newPolicy("quarantine-db", constants.TierSecurityOps, dbSelector))
+
+	status := evaluate(&securityv1alpha1.ReachabilityMatrixSpec{Groups: []string{"web", "db"}, Ports: []securityv1alpha1.ReachabilityPort{mysql}}, state)
+	Expect(status.Entries).Should(HaveLen(4))
+	for _, item := range status.Entries {
+		if item.From == "web" && item.To == "db" {
+			Expect(item.Verdict).Should(Equal(securityv1alpha1.ReachabilityDeny))
+			Expect(item.Rules).Should(ContainElement("default/quarantine-db/default.ingress"))
+			Expect(item.Rules).ShouldNot(ContainElement("default/db/ingress.mysql"))
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme,
+		&securityv1alpha1.ReachabilityMatrix{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-db"},
+			Spec: securityv1alpha1.ReachabilityMatrixSpec{
+				Ports: []securityv1alpha1.ReachabilityPort{mysql},
+			},
+		},
+		&groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		&groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: "db"}},
+		&groupv1alpha1.EndpointGroup{ObjectMeta: metav1.ObjectMeta{Name: selectorGroup(dbSelector)}},
+		newTestObjects()[0],
+		newTestObjects()[1],
+		newTestObjects()[3],
+		newTestObjects()[4],
+	)
+	r := &Reconciler{Client: c}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "web-db"}}
+	getMatrix := func() *securityv1alpha1.ReachabilityMatrix {
+		matrix := &securityv1alpha1.ReachabilityMatrix{}
+		Expect(c.Get(ctx, req.NamespacedName, matrix)).Should(Succeed())
+		return matrix
+	}
+
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	matrix := getMatrix()
+	Expect(matrix.Status.Entries).Should(HaveLen(4))
+	Expect(matrix.Status.Allowed).Should(Equal(int32(3)))
+	Expect(matrix.Status.Denied).Should(Equal(int32(1)))
+	Expect(matrix.Status.GeneratedTime.IsZero()).Should(BeFalse())
+
+	// not updated when the result unchanged
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getMatrix().ResourceVersion).Should(Equal(matrix.ResourceVersion))
+
+	// the policy isolates the db
+	Expect(c.Create(ctx, newPolicy("db", constants.Tier2, dbSelector))).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	matrix = getMatrix()
+	Expect(matrix.Status.Allowed).Should(Equal(int32(2)))
+	Expect(matrix.Status.Denied).Should(Equal(int32(2)))
+
+	// the removed matrix is ignored
+	Expect(c.Delete(ctx, matrix)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+}
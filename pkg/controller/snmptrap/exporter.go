@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snmptrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+const (
+	// DefaultEnterpriseOID is the net-snmp experimental subtree, replace it
+	// with the private enterprise oid of the NOC if required.
+	DefaultEnterpriseOID    = "1.3.6.1.4.1.8072.9999.9999"
+	DefaultCommunity        = "public"
+	DefaultInterval         = 30 * time.Second
+	DefaultAgentDownTimeout = 3 * time.Minute
+
+	// uplinkBridgeSuffix is the name suffix of uplink bridges
+	uplinkBridgeSuffix = "-uplink"
+)
+
+// AlarmType is the type of critical conditions
+type AlarmType int
+
+// The trap of an alarm is "<enterprise>.0.<2*type-1>" when raised, and
+// "<enterprise>.0.<2*type>" when cleared.
+const (
+	AlarmAgentDown AlarmType = iota + 1
+	AlarmUplinkDown
+	AlarmFlowTableFull
+	AlarmBFDDown
+)
+
+func (t AlarmType) String() string {
+	switch t {
+	case AlarmAgentDown:
+		return "AgentDown"
+	case AlarmUplinkDown:
+		return "UplinkDown"
+	case AlarmFlowTableFull:
+		return "FlowTableFull"
+	case AlarmBFDDown:
+		return "BFDDown"
+	}
+	return fmt.Sprintf("AlarmType(%d)", int(t))
+}
+
+// Alarm is a critical condition on an agent
+type Alarm struct {
+	Type  AlarmType
+	Agent string
+	// Object is the resource in the agent, e.g. the uplink interface
+	Object  string
+	Message string
+}
+
+func (a Alarm) key() string {
+	return fmt.Sprintf("%s/%s/%s", a.Type, a.Agent, a.Object)
+}
+
+// Target is a snmp trap receiver
+type Target struct {
+	// Address of the receiver, in format host[:port].
+	Address   string
+	Community string
+}
+
+// Config defines where the traps been sent and how the alarms been detected
+type Config struct {
+	Targets       []Target
+	EnterpriseOID string
+	// Interval is the period of alarm inspection.
+	Interval time.Duration
+	// AgentDownTimeout is the duration without heartbeat, after which an agent is considered down.
+	AgentDownTimeout time.Duration
+}
+
+// Exporter inspect the agentinfos periodically, send a trap to all the targets
+// when an alarm raised or cleared. The varbinds of the traps are
+// "<enterprise>.1.1" agent, "<enterprise>.1.2" object and "<enterprise>.1.3" message.
+type Exporter struct {
+	client.Reader
+	Config Config
+
+	sender *Sender
+	// alarms are the active alarms sent to the targets
+	alarms map[string]Alarm
+}
+
+// Start implements manager.Runnable, only the leader would send traps.
+func (e *Exporter) Start(stopChan <-chan struct{}) error {
+	if err := e.complete(); err != nil {
+		return err
+	}
+
+	klog.Infof("start snmp trap exporter with %d targets", len(e.Config.Targets))
+	defer klog.Infof("shutting down snmp trap exporter")
+
+	wait.Until(e.inspectOnce, e.Config.Interval, stopChan)
+	return nil
+}
+
+func (e *Exporter) complete() error {
+	if e.Config.EnterpriseOID == "" {
+		e.Config.EnterpriseOID = DefaultEnterpriseOID
+	}
+	if err := ValidOID(e.Config.EnterpriseOID); err != nil {
+		return fmt.Errorf("invalid enterprise oid %s: %s", e.Config.EnterpriseOID, err)
+	}
+	if e.Config.Interval <= 0 {
+		e.Config.Interval = DefaultInterval
+	}
+	if e.Config.AgentDownTimeout <= 0 {
+		e.Config.AgentDownTimeout = DefaultAgentDownTimeout
+	}
+	for i := range e.Config.Targets {
+		if e.Config.Targets[i].Community == "" {
+			e.Config.Targets[i].Community = DefaultCommunity
+		}
+	}
+	if e.sender == nil {
+		e.sender = NewSender()
+	}
+	if e.alarms == nil {
+		e.alarms = make(map[string]Alarm)
+	}
+	return nil
+}
+
+func (e *Exporter) inspectOnce() {
+	agentInfoList := agentv1alpha1.AgentInfoList{}
+	if err := e.List(context.Background(), &agentInfoList); err != nil {
+		klog.Errorf("unable list agentinfos: %s", err)
+		return
+	}
+
+	alarms := make(map[string]Alarm)
+	for _, alarm := range Alarms(agentInfoList.Items, time.Now(), e.Config.AgentDownTimeout) {
+		alarms[alarm.key()] = alarm
+	}
+
+	for key, alarm := range alarms {
+		if _, ok := e.alarms[key]; !ok {
+			klog.Warningf("alarm %s raised: %s", key, alarm.Message)
+			e.sendTrap(alarm, true)
+		}
+	}
+	for key, alarm := range e.alarms {
+		if _, ok := alarms[key]; !ok {
+			klog.Infof("alarm %s cleared", key)
+			e.sendTrap(alarm, false)
+		}
+	}
+	e.alarms = alarms
+}
+
+func (e *Exporter) sendTrap(alarm Alarm, raised bool) {
+	trapNumber := 2 * int(alarm.Type)
+	if raised {
+		trapNumber--
+	}
+	message := alarm.Message
+	if !raised {
+		message = fmt.Sprintf("%s cleared", alarm.Type)
+	}
+
+	for _, target := range e.Config.Targets {
+		trap := Trap{
+			Community: target.Community,
+			TrapOID:   fmt.Sprintf("%s.0.%d", e.Config.EnterpriseOID, trapNumber),
+			VarBinds: []VarBind{
+				{OID: e.Config.EnterpriseOID + ".1.1", Value: alarm.Agent},
+				{OID: e.Config.EnterpriseOID + ".1.2", Value: alarm.Object},
+				{OID: e.Config.EnterpriseOID + ".1.3", Value: message},
+			},
+		}
+		if err := e.sender.Send(target.Address, trap); err != nil {
+			klog.Errorf("unable send trap %s to %s: %s", alarm.key(), target.Address, err)
+		}
+	}
+}
+
+// Alarms return the alarms of the agents at the time. The other alarms of
+// an agent are ignored when it's down, because its agentinfo is out of date.
+func Alarms(agentInfos []agentv1alpha1.AgentInfo, now time.Time, agentDownTimeout time.Duration) []Alarm {
+	var alarms []Alarm
+
+	for _, agentInfo := range agentInfos {
+		if lastHeartbeat := lastHeartbeatTime(&agentInfo); now.Sub(lastHeartbeat) > agentDownTimeout {
+			alarms = append(alarms, Alarm{
+				Type:    AlarmAgentDown,
+				Agent:   agentInfo.Name,
+				Object:  agentInfo.Hostname,
+				Message: fmt.Sprintf("agent has no heartbeat since %s", lastHeartbeat.UTC().Format(time.RFC3339)),
+			})
+			continue
+		}
+
+		for _, bridge := range agentInfo.OVSInfo.Bridges {
+			if !strings.HasSuffix(bridge.Name, uplinkBridgeSuffix) {
+				continue
+			}
+			for _, port := range bridge.Ports {
+				for _, iface := range port.Interfaces {
+					if !isPhysicalInterface(&iface) || iface.LinkState != "down" {
+						continue
+					}
+					alarms = append(alarms, Alarm{
+						Type:    AlarmUplinkDown,
+						Agent:   agentInfo.Name,
+						Object:  iface.Name,
+						Message: fmt.Sprintf("uplink %s of bridge %s is down", iface.Name, bridge.Name),
+					})
+				}
+			}
+		}
+
+		for _, bridge := range agentInfo.OVSInfo.Bridges {
+			for _, port := range bridge.Ports {
+				for _, iface := range port.Interfaces {
+					if !isBFDDown(iface.BFDStatus) {
+						continue
+					}
+					alarms = append(alarms, Alarm{
+						Type:   AlarmBFDDown,
+						Agent:  agentInfo.Name,
+						Object: iface.Name,
+						Message: fmt.Sprintf("bfd session on interface %s of bridge %s is %s: %s",
+							iface.Name, bridge.Name, iface.BFDStatus.State, iface.BFDStatus.Diagnostic),
+					})
+				}
+			}
+		}
+
+		for _, condition := range agentInfo.Conditions {
+			if condition.Type == agentv1alpha1.FlowTableFull && condition.Status == corev1.ConditionTrue {
+				alarms = append(alarms, Alarm{
+					Type:    AlarmFlowTableFull,
+					Agent:   agentInfo.Name,
+					Object:  agentInfo.Hostname,
+					Message: condition.Message,
+				})
+			}
+		}
+	}
+
+	return alarms
+}
+
+func lastHeartbeatTime(agentInfo *agentv1alpha1.AgentInfo) time.Time {
+	for _, condition := range agentInfo.Conditions {
+		if condition.Type == agentv1alpha1.AgentHealthy {
+			return condition.LastHeartbeatTime.Time
+		}
+	}
+	return agentInfo.CreationTimestamp.Time
+}
+
+// isBFDDown return true if bfd enabled and the session not up, the sessions disabled
+// administratively are ignored.
+func isBFDDown(status *agentv1alpha1.BFDStatus) bool {
+	return status != nil && status.State != "up" && status.State != "admin_down"
+}
+
+// isPhysicalInterface return true for the nics, internal and patch ports are excluded
+func isPhysicalInterface(iface *agentv1alpha1.OVSInterface) bool {
+	return iface.Type == "" || iface.Type == "system"
+}
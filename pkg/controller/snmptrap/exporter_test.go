@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snmptrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+)
+
+func TestTrapMarshal(t *testing.T) {
+	RegisterTestingT(t)
+
+	trap := Trap{
+		Community: "private",
+		Uptime:    1234500 * time.Millisecond,
+		TrapOID:   "1.3.6.1.4.1.8072.9999.9999.0.1",
+		VarBinds:  []VarBind{{OID: "1.3.6.1.4.1.8072.9999.9999.1.1", Value: "agent01"}},
+	}
+	message, err := trap.Marshal(42)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	var decoded struct {
+		Version   int
+		Community []byte
+		PDU       asn1.RawValue
+	}
+	rest, err := asn1.Unmarshal(message, &decoded)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(rest).Should(BeEmpty())
+	Expect(decoded.Version).Should(Equal(1))
+	Expect(string(decoded.Community)).Should(Equal("private"))
+	Expect(decoded.PDU.Class).Should(Equal(asn1.ClassContextSpecific))
+	Expect(decoded.PDU.Tag).Should(Equal(7))
+
+	var requestID, errorStatus, errorIndex int
+	var varBinds asn1.RawValue
+	rest, err = asn1.Unmarshal(decoded.PDU.Bytes, &requestID)
+	Expect(err).ShouldNot(HaveOccurred())
+	rest, err = asn1.Unmarshal(rest, &errorStatus)
+	Expect(err).ShouldNot(HaveOccurred())
+	rest, err = asn1.Unmarshal(rest, &errorIndex)
+	Expect(err).ShouldNot(HaveOccurred())
+	_, err = asn1.Unmarshal(rest, &varBinds)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(requestID).Should(Equal(42))
+
+	var upTime struct {
+		OID   asn1.ObjectIdentifier
+		Ticks asn1.RawValue
+	}
+	rest, err = asn1.Unmarshal(varBinds.Bytes, &upTime)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(upTime.OID.String()).Should(Equal(oidSysUpTime))
+	Expect(upTime.Ticks.Class).Should(Equal(asn1.ClassApplication))
+	Expect(upTime.Ticks.Tag).Should(Equal(3))
+	Expect(upTime.Ticks.Bytes).Should(Equal([]byte{0x01, 0xe2, 0x3a})) // 123450
+
+	var trapOID struct {
+		OID   asn1.ObjectIdentifier
+		Value asn1.ObjectIdentifier
+	}
+	rest, err = asn1.Unmarshal(rest, &trapOID)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(trapOID.OID.String()).Should(Equal(oidSNMPTrapOID))
+	Expect(trapOID.Value.String()).Should(Equal(trap.TrapOID))
+
+	var agent struct {
+		OID   asn1.ObjectIdentifier
+		Value []byte
+	}
+	rest, err = asn1.Unmarshal(rest, &agent)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(rest).Should(BeEmpty())
+	Expect(agent.OID.String()).Should(Equal("1.3.6.1.4.1.8072.9999.9999.1.1"))
+	Expect(string(agent.Value)).Should(Equal("agent01"))
+}
+
+func TestEncoding(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(encodeLength(0x7f)).Should(Equal([]byte{0x7f}))
+	Expect(encodeLength(0x80)).Should(Equal([]byte{0x81, 0x80}))
+	Expect(encodeLength(0x1234)).Should(Equal([]byte{0x82, 0x12, 0x34}))
+	Expect(encodeInteger(128)).Should(Equal([]byte{0x02, 0x02, 0x00, 0x80}))
+	Expect(encodeInteger(-129)).Should(Equal([]byte{0x02, 0x02, 0xff, 0x7f}))
+	Expect(ValidOID("1.3.6.1")).Should(Succeed())
+	Expect(ValidOID("1")).ShouldNot(Succeed())
+	Expect(ValidOID("1.3.a")).ShouldNot(Succeed())
+	Expect(ValidOID("1.40.1")).ShouldNot(Succeed())
+}
+
+func newAgentInfo(name string, heartbeat time.Time, uplinkState string, flowTableFull corev1.ConditionStatus) *agentv1alpha1.AgentInfo {
+	return &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Hostname:   name + "-host",
+		OVSInfo: agentv1alpha1.OVSInfo{
+			Bridges: []agentv1alpha1.OVSBridge{{
+				Name: "ovsbr0-uplink",
+				Ports: []agentv1alpha1.OVSPort{
+					{Name: "ens192", Interfaces: []agentv1alpha1.OVSInterface{{Name: "ens192", LinkState: uplinkState}}},
+					{Name: "ovsbr0-uplink", Interfaces: []agentv1alpha1.OVSInterface{{Name: "ovsbr0-uplink", Type: "internal", LinkState: "down"}}},
+				},
+			}, {
+				Name: "ovsbr0",
+				Ports: []agentv1alpha1.OVSPort{
+					{Name: "vnet0", Interfaces: []agentv1alpha1.OVSInterface{{Name: "vnet0", LinkState: "down"}}},
+				},
+			}},
+		},
+		Conditions: []agentv1alpha1.AgentCondition{
+			{Type: agentv1alpha1.AgentHealthy, Status: corev1.ConditionTrue, LastHeartbeatTime: metav1.NewTime(heartbeat)},
+			{Type: agentv1alpha1.FlowTableFull, Status: flowTableFull, Message: "flows reached limit"},
+		},
+	}
+}
+
+func TestAlarms(t *testing.T) {
+	RegisterTestingT(t)
+	now := time.Now()
+
+	bfdDownAgentInfo := newAgentInfo("bfd", now, "up", corev1.ConditionFalse)
+	bfdDownAgentInfo.OVSInfo.Bridges[0].Ports[0].Interfaces[0].BFDStatus = &agentv1alpha1.BFDStatus{State: "down"}
+	bfdDownAgentInfo.OVSInfo.Bridges[0].Ports[1].Interfaces[0].BFDStatus = &agentv1alpha1.BFDStatus{State: "admin_down"}
+
+	alarms := Alarms([]agentv1alpha1.AgentInfo{
+		*newAgentInfo("healthy", now, "up", corev1.ConditionFalse),
+		*newAgentInfo("down", now.Add(-time.Hour), "down", corev1.ConditionTrue),
+		*newAgentInfo("uplink", now, "down", corev1.ConditionFalse),
+		*newAgentInfo("full", now, "up", corev1.ConditionTrue),
+		*bfdDownAgentInfo,
+	}, now, time.Minute)
+
+	var keys []string
+	for _, alarm := range alarms {
+		keys = append(keys, alarm.key())
+	}
+	Expect(keys).Should(ConsistOf("AgentDown/down/down-host", "UplinkDown/uplink/ens192", "FlowTableFull/full/full-host", "BFDDown/bfd/ens192"))
+}
+
+func TestExporter(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).ShouldNot(HaveOccurred())
+	defer receiver.Close()
+	receive := func() []byte {
+		buf := make([]byte, 4096)
+		_ = receiver.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := receiver.ReadFrom(buf)
+		if err != nil {
+			return nil
+		}
+		return buf[:n]
+	}
+
+	agentInfo := newAgentInfo("agent01", time.Now(), "down", corev1.ConditionFalse)
+	k8sClient := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, agentInfo)
+	exporter := &Exporter{
+		Reader: k8sClient,
+		Config: Config{Targets: []Target{{Address: receiver.LocalAddr().String()}}},
+	}
+	Expect(exporter.complete()).Should(Succeed())
+
+	raisedOID, _ := encodeOID(DefaultEnterpriseOID + ".0.3")
+	clearedOID, _ := encodeOID(DefaultEnterpriseOID + ".0.4")
+
+	exporter.inspectOnce()
+	message := receive()
+	Expect(bytes.Contains(message, []byte(DefaultCommunity))).Should(BeTrue())
+	Expect(bytes.Contains(message, raisedOID)).Should(BeTrue())
+
+	// no trap when alarms unchanged
+	exporter.inspectOnce()
+	Expect(receive()).Should(BeNil())
+
+	agentInfo.OVSInfo.Bridges[0].Ports[0].Interfaces[0].LinkState = "up"
+	Expect(k8sClient.Update(ctx, agentInfo)).Should(Succeed())
+	exporter.inspectOnce()
+	Expect(bytes.Contains(receive(), clearedOID)).Should(BeTrue())
+}
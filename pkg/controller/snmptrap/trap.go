@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snmptrap
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// snmpVersion2c is the version field value of SNMPv2c messages
+	snmpVersion2c = 1
+
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagTimeTicks   = 0x43
+	tagSNMPv2Trap  = 0xa7
+
+	// DefaultTrapPort is the well-known port of snmp trap receivers
+	DefaultTrapPort = "162"
+
+	// oidSysUpTime is sysUpTime.0, the first varbind of all SNMPv2 traps
+	oidSysUpTime = "1.3.6.1.2.1.1.3.0"
+	// oidSNMPTrapOID is snmpTrapOID.0, the second varbind of all SNMPv2 traps
+	oidSNMPTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// VarBind is a variable binding with octet string value
+type VarBind struct {
+	OID   string
+	Value string
+}
+
+// Trap is a SNMPv2c trap
+type Trap struct {
+	Community string
+	// Uptime is the time since the trap sender started
+	Uptime time.Duration
+	// TrapOID identify the trap, it's the value of snmpTrapOID.0
+	TrapOID  string
+	VarBinds []VarBind
+}
+
+// Marshal encode the trap as SNMPv2c message in BER.
+func (t *Trap) Marshal(requestID int32) ([]byte, error) {
+	trapOID, err := encodeOID(t.TrapOID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trap oid %s: %s", t.TrapOID, err)
+	}
+	sysUpTime, _ := encodeOID(oidSysUpTime)
+	snmpTrapOID, _ := encodeOID(oidSNMPTrapOID)
+
+	varBinds := [][]byte{
+		tlv(tagSequence, sysUpTime, tlv(tagTimeTicks, encodeUnsigned(uint32(t.Uptime/(10*time.Millisecond))))),
+		tlv(tagSequence, snmpTrapOID, trapOID),
+	}
+	for _, varBind := range t.VarBinds {
+		oid, err := encodeOID(varBind.OID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid varbind oid %s: %s", varBind.OID, err)
+		}
+		varBinds = append(varBinds, tlv(tagSequence, oid, tlv(tagOctetString, []byte(varBind.Value))))
+	}
+
+	pdu := tlv(tagSNMPv2Trap,
+		encodeInteger(int64(requestID)),
+		encodeInteger(0), // error-status
+		encodeInteger(0), // error-index
+		tlv(tagSequence, varBinds...),
+	)
+
+	return tlv(tagSequence,
+		encodeInteger(snmpVersion2c),
+		tlv(tagOctetString, []byte(t.Community)),
+		pdu,
+	), nil
+}
+
+// Sender send SNMPv2c traps over UDP
+type Sender struct {
+	startTime time.Time
+	timeout   time.Duration
+}
+
+// NewSender create a trap sender, the uptime in the traps is counted from now.
+func NewSender() *Sender {
+	return &Sender{
+		startTime: time.Now(),
+		timeout:   5 * time.Second,
+	}
+}
+
+// Send the trap to the address, the address could omit the port, default 162.
+func (s *Sender) Send(address string, trap Trap) error {
+	trap.Uptime = time.Since(s.startTime)
+	message, err := trap.Marshal(rand.Int31())
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DefaultTrapPort)
+	}
+	conn, err := net.DialTimeout("udp", address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	_, err = conn.Write(message)
+	return err
+}
+
+func tlv(tag byte, contents ...[]byte) []byte {
+	var length int
+	for _, content := range contents {
+		length += len(content)
+	}
+	buf := append([]byte{tag}, encodeLength(length)...)
+	for _, content := range contents {
+		buf = append(buf, content...)
+	}
+	return buf
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var buf []byte
+	for ; length > 0; length >>= 8 {
+		buf = append([]byte{byte(length)}, buf...)
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+func encodeInteger(value int64) []byte {
+	buf := []byte{byte(value)}
+	for value > 127 || value < -128 {
+		value >>= 8
+		buf = append([]byte{byte(value)}, buf...)
+	}
+	return tlv(tagInteger, buf)
+}
+
+// encodeUnsigned return the contents of an unsigned integer, e.g. TimeTicks
+func encodeUnsigned(value uint32) []byte {
+	buf := []byte{byte(value)}
+	for value >>= 8; value > 0; value >>= 8 {
+		buf = append([]byte{byte(value)}, buf...)
+	}
+	if buf[0]&0x80 != 0 {
+		buf = append([]byte{0}, buf...)
+	}
+	return buf
+}
+
+func encodeOID(oid string) ([]byte, error) {
+	items := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(items) < 2 {
+		return nil, fmt.Errorf("at least two arcs required")
+	}
+
+	arcs := make([]uint32, 0, len(items))
+	for _, item := range items {
+		arc, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		arcs = append(arcs, uint32(arc))
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid first two arcs")
+	}
+
+	buf := encodeBase128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		buf = append(buf, encodeBase128(arc)...)
+	}
+	return tlv(tagOID, buf), nil
+}
+
+func encodeBase128(value uint32) []byte {
+	buf := []byte{byte(value & 0x7f)}
+	for value >>= 7; value > 0; value >>= 7 {
+		buf = append([]byte{byte(value&0x7f) | 0x80}, buf...)
+	}
+	return buf
+}
+
+// ValidOID return error if the oid is not in dotted decimal notation.
+func ValidOID(oid string) error {
+	_, err := encodeOID(oid)
+	return err
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+const defaultTiersRetryInterval = 5 * time.Second
+
+// DefaultTiers returns the Tiers created by default, the platform-level policies are expected
+// in emergency and security-ops, which always override the application policies.
+func DefaultTiers() []securityv1alpha1.Tier {
+	return []securityv1alpha1.Tier{
+		newTier(constants.TierEmergency, 10, "emergency response, overrides all the other tiers"),
+		newTier(constants.TierSecurityOps, 50, "policies of the security operators"),
+		newTier(constants.TierApplication, constants.MaxTierPriority, "policies of the applications"),
+	}
+}
+
+func newTier(name string, priority int32, description string) securityv1alpha1.Tier {
+	return securityv1alpha1.Tier{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: securityv1alpha1.TierSpec{
+			Priority:    priority,
+			Description: description,
+		},
+	}
+}
+
+// Reconciler keeps the default Tiers exist, they are recreated once removed. The other Tiers
+// are managed by the users.
+type Reconciler struct {
+	client.Client
+}
+
+// Reconcile receive Tier from work queue, create it if it's a default Tier and not exists.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("TierReconciler received Tier %s reconcile", req.Name)
+
+	tiers := DefaultTiers()
+	for i := range tiers {
+		tier := &tiers[i]
+		if tier.Name != req.Name {
+			continue
+		}
+		err := r.Get(ctx, req.NamespacedName, &securityv1alpha1.Tier{})
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// the priority may be taken by a Tier of the users, the webhook rejects it until released
+		if err = r.Create(ctx, tier); err != nil && !errors.IsAlreadyExists(err) {
+			klog.Errorf("failed to create default tier %s: %s", tier.Name, err)
+			return ctrl.Result{}, err
+		}
+		klog.Infof("default tier %s created with priority %d", tier.Name, tier.Spec.Priority)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Tier Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("tier-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &securityv1alpha1.Tier{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// create the default tiers on start, the watch only enqueues the tiers exist
+	return mgr.Add(manager.RunnableFunc(func(stopChan <-chan struct{}) error {
+		return wait.PollImmediateUntil(defaultTiersRetryInterval, r.ensureDefaultTiers, stopChan)
+	}))
+}
+
+func (r *Reconciler) ensureDefaultTiers() (bool, error) {
+	for _, tier := range DefaultTiers() {
+		if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Name: tier.Name}}); err != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tier
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+func TestDefaultTiers(t *testing.T) {
+	RegisterTestingT(t)
+
+	priorities := make(map[int32]string)
+	for _, tier := range DefaultTiers() {
+		Expect(priorities).ShouldNot(HaveKey(tier.Spec.Priority))
+		Expect(tier.Spec.Priority).Should(BeNumerically(">=", 1))
+		Expect(tier.Spec.Priority).Should(BeNumerically("<=", constants.MaxTierPriority))
+		priorities[tier.Spec.Priority] = tier.Name
+	}
+	Expect(priorities).Should(HaveLen(3))
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	Expect(securityv1alpha1.AddToScheme(scheme)).Should(Succeed())
+	k8sClient := fake.NewFakeClientWithScheme(scheme)
+	r := &Reconciler{Client: k8sClient}
+
+	t.Run("should create the default tiers", func(t *testing.T) {
+		RegisterTestingT(t)
+		done, err := r.ensureDefaultTiers()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(done).Should(BeTrue())
+
+		tierList := securityv1alpha1.TierList{}
+		Expect(k8sClient.List(ctx, &tierList)).Should(Succeed())
+		Expect(tierList.Items).Should(HaveLen(len(DefaultTiers())))
+	})
+
+	t.Run("should recreate the default tier removed", func(t *testing.T) {
+		RegisterTestingT(t)
+		key := types.NamespacedName{Name: constants.TierEmergency}
+		tier := securityv1alpha1.Tier{}
+		Expect(k8sClient.Get(ctx, key, &tier)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, &tier)).Should(Succeed())
+
+		_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, &tier)).Should(Succeed())
+		Expect(tier.Spec.Priority).Should(Equal(int32(10)))
+	})
+
+	t.Run("should keep the default tier modified", func(t *testing.T) {
+		RegisterTestingT(t)
+		key := types.NamespacedName{Name: constants.TierSecurityOps}
+		tier := securityv1alpha1.Tier{}
+		Expect(k8sClient.Get(ctx, key, &tier)).Should(Succeed())
+		tier.Spec.Priority = 60
+		Expect(k8sClient.Update(ctx, &tier)).Should(Succeed())
+
+		_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, &tier)).Should(Succeed())
+		Expect(tier.Spec.Priority).Should(Equal(int32(60)))
+	})
+
+	t.Run("should ignore the tiers of the users", func(t *testing.T) {
+		RegisterTestingT(t)
+		key := types.NamespacedName{Name: "custom"}
+		_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, key, &securityv1alpha1.Tier{}))).Should(BeTrue())
+	})
+}
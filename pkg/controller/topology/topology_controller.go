@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// bridgeRoleSuffixes are the name suffixes of the bridges in everoute bridge chains,
+// the bridge chain "br0" consists of br0, br0-policy, br0-cls, br0-uplink and br0-nat.
+var bridgeRoleSuffixes = []struct {
+	suffix string
+	role   agentv1alpha1.BridgeRole
+}{
+	{"-policy", agentv1alpha1.BridgeRolePolicy},
+	{"-cls", agentv1alpha1.BridgeRoleCls},
+	{"-uplink", agentv1alpha1.BridgeRoleUplink},
+	{"-nat", agentv1alpha1.BridgeRoleNat},
+}
+
+var tunnelTypes = map[string]bool{
+	"geneve": true,
+	"vxlan":  true,
+	"gre":    true,
+	"stt":    true,
+	"erspan": true,
+}
+
+// Reconciler build a NetworkTopology for each AgentInfo, the topology has the same
+// name as the AgentInfo and is owned by it.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// Reconcile receive agentinfo from work queue, synchronize its network topology.
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	klog.V(4).Infof("TopologyReconciler received agentinfo %s reconcile", req.Name)
+
+	agentInfo := agentv1alpha1.AgentInfo{}
+	if err := r.Get(ctx, req.NamespacedName, &agentInfo); err != nil {
+		if errors.IsNotFound(err) {
+			// the topology is also removed by garbage collector as an owned object
+			topology := agentv1alpha1.NetworkTopology{ObjectMeta: metav1.ObjectMeta{Name: req.Name}}
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, &topology))
+		}
+		klog.Errorf("unable to fetch agentinfo %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	expect := BuildTopology(&agentInfo)
+	if err := controllerutil.SetControllerReference(&agentInfo, expect, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	topology := agentv1alpha1.NetworkTopology{}
+	err := r.Get(ctx, req.NamespacedName, &topology)
+	switch {
+	case errors.IsNotFound(err):
+		expect.LastUpdateTime = metav1.Now()
+		if err = r.Create(ctx, expect); err != nil {
+			klog.Errorf("failed to create network topology %s: %s", req.Name, err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	case err != nil:
+		klog.Errorf("unable to fetch network topology %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	if topologyEqual(&topology, expect) {
+		return ctrl.Result{}, nil
+	}
+	topology.OwnerReferences = expect.OwnerReferences
+	topology.Hostname = expect.Hostname
+	topology.OVSVersion = expect.OVSVersion
+	topology.Bridges = expect.Bridges
+	topology.Links = expect.Links
+	topology.LastUpdateTime = metav1.Now()
+	if err = r.Update(ctx, &topology); err != nil {
+		klog.Errorf("failed to update network topology %s: %s", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager create and add Topology Controller to the manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+
+	c, err := controller.New("topology-controller", mgr, controller.Options{
+		MaxConcurrentReconciles: constants.DefaultMaxConcurrentReconciles,
+		Reconciler:              r,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.Watch(&source.Kind{Type: &agentv1alpha1.AgentInfo{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// rebuild the topology when it's modified or removed by others
+	return c.Watch(&source.Kind{Type: &agentv1alpha1.NetworkTopology{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &agentv1alpha1.AgentInfo{},
+		IsController: true,
+	})
+}
+
+func topologyEqual(actual, expect *agentv1alpha1.NetworkTopology) bool {
+	return reflect.DeepEqual(actual.OwnerReferences, expect.OwnerReferences) &&
+		actual.Hostname == expect.Hostname &&
+		actual.OVSVersion == expect.OVSVersion &&
+		reflect.DeepEqual(actual.Bridges, expect.Bridges) &&
+		reflect.DeepEqual(actual.Links, expect.Links)
+}
+
+// BuildTopology build the network topology from the agentinfo, the bridges are
+// sorted by name for stable output.
+func BuildTopology(agentInfo *agentv1alpha1.AgentInfo) *agentv1alpha1.NetworkTopology {
+	topology := &agentv1alpha1.NetworkTopology{
+		ObjectMeta: metav1.ObjectMeta{Name: agentInfo.Name},
+		Hostname:   agentInfo.Hostname,
+		OVSVersion: agentInfo.OVSInfo.Version,
+	}
+
+	bridgeNames := make(map[string]bool, len(agentInfo.OVSInfo.Bridges))
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		bridgeNames[bridge.Name] = true
+	}
+
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		topologyBridge := agentv1alpha1.TopologyBridge{
+			Name:         bridge.Name,
+			Role:         bridgeRole(bridge.Name, bridgeNames),
+			SpanningTree: bridge.SpanningTree,
+		}
+
+		for _, port := range bridge.Ports {
+			for _, iface := range port.Interfaces {
+				if tunnelTypes[iface.Type] {
+					topologyBridge.Tunnels = append(topologyBridge.Tunnels, agentv1alpha1.TopologyTunnel{
+						Name:     iface.Name,
+						Type:     iface.Type,
+						BFDState: bfdState(iface.BFDStatus),
+					})
+				}
+			}
+			if port.Name == bridge.Name || !isPhysicalPort(&port) {
+				continue
+			}
+			switch topologyBridge.Role {
+			case agentv1alpha1.BridgeRoleUplink:
+				topologyBridge.Uplinks = append(topologyBridge.Uplinks, newTopologyUplink(&port))
+			case agentv1alpha1.BridgeRoleLocal:
+				topologyBridge.EndpointCount++
+			}
+		}
+
+		topology.Bridges = append(topology.Bridges, topologyBridge)
+	}
+
+	sort.Slice(topology.Bridges, func(i, j int) bool { return topology.Bridges[i].Name < topology.Bridges[j].Name })
+	for _, bridge := range topology.Bridges {
+		if bridge.Role == agentv1alpha1.BridgeRoleLocal {
+			topology.Links = append(topology.Links, bridgeChainLinks(bridge.Name, bridgeNames)...)
+		}
+	}
+	return topology
+}
+
+func bridgeRole(name string, bridgeNames map[string]bool) agentv1alpha1.BridgeRole {
+	for _, item := range bridgeRoleSuffixes {
+		if strings.HasSuffix(name, item.suffix) && bridgeNames[strings.TrimSuffix(name, item.suffix)] {
+			return item.role
+		}
+	}
+	if bridgeNames[name+"-policy"] {
+		return agentv1alpha1.BridgeRoleLocal
+	}
+	return agentv1alpha1.BridgeRoleUnmanaged
+}
+
+// bridgeChainLinks return the links of the bridge chain: local - policy - cls - uplink, and local - nat.
+func bridgeChainLinks(local string, bridgeNames map[string]bool) []agentv1alpha1.TopologyLink {
+	var links []agentv1alpha1.TopologyLink
+
+	chain := []string{local, local + "-policy", local + "-cls", local + "-uplink"}
+	for i := 0; i+1 < len(chain) && bridgeNames[chain[i+1]]; i++ {
+		links = append(links, agentv1alpha1.TopologyLink{From: chain[i], To: chain[i+1]})
+	}
+	if bridgeNames[local+"-nat"] {
+		links = append(links, agentv1alpha1.TopologyLink{From: local, To: local + "-nat"})
+	}
+	return links
+}
+
+func newTopologyUplink(port *agentv1alpha1.OVSPort) agentv1alpha1.TopologyUplink {
+	uplink := agentv1alpha1.TopologyUplink{Name: port.Name}
+	if len(port.Interfaces) > 1 && port.BondConfig != nil {
+		uplink.BondMode = port.BondConfig.BondMode
+	}
+	for _, iface := range port.Interfaces {
+		uplink.Interfaces = append(uplink.Interfaces, agentv1alpha1.TopologyUplinkInterface{
+			Name:      iface.Name,
+			Mac:       iface.Mac,
+			LinkState: iface.LinkState,
+			BFDState:  bfdState(iface.BFDStatus),
+			Neighbor:  iface.LLDPNeighbor.DeepCopy(),
+		})
+	}
+	return uplink
+}
+
+// isPhysicalPort return true if all the interfaces of the port are system interfaces,
+// e.g. nics, taps and veths. The internal, patch and tunnel ports are excluded.
+func isPhysicalPort(port *agentv1alpha1.OVSPort) bool {
+	for _, iface := range port.Interfaces {
+		if iface.Type != "" && iface.Type != "system" {
+			return false
+		}
+	}
+	return len(port.Interfaces) != 0
+}
+
+func bfdState(status *agentv1alpha1.BFDStatus) string {
+	if status == nil {
+		return ""
+	}
+	return status.State
+}
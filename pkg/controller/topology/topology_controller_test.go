@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	clientsetscheme "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/scheme"
+)
+
+func newBridge(name string, ports ...agentv1alpha1.OVSPort) agentv1alpha1.OVSBridge {
+	// the bridge always has an internal port with the same name
+	internal := agentv1alpha1.OVSPort{Name: name, Interfaces: []agentv1alpha1.OVSInterface{{Name: name, Type: "internal"}}}
+	return agentv1alpha1.OVSBridge{Name: name, Ports: append([]agentv1alpha1.OVSPort{internal}, ports...)}
+}
+
+func newPort(name, ifaceType string) agentv1alpha1.OVSPort {
+	return agentv1alpha1.OVSPort{Name: name, Interfaces: []agentv1alpha1.OVSInterface{{Name: name, Type: ifaceType}}}
+}
+
+func newAgentInfo() *agentv1alpha1.AgentInfo {
+	bond := agentv1alpha1.OVSPort{
+		Name:       "bond0",
+		BondConfig: &agentv1alpha1.BondConfig{BondMode: agentv1alpha1.BondModeActiveBackup},
+		Interfaces: []agentv1alpha1.OVSInterface{
+			{Name: "ens192", Mac: "52:54:00:00:00:01", LinkState: "up",
+				LLDPNeighbor: &agentv1alpha1.LLDPNeighbor{SystemName: "tor01", PortID: "Ethernet1"}},
+			{Name: "ens224", Mac: "52:54:00:00:00:02", LinkState: "down",
+				BFDStatus: &agentv1alpha1.BFDStatus{State: "down"}},
+		},
+	}
+
+	return &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent01"},
+		Hostname:   "node01",
+		OVSInfo: agentv1alpha1.OVSInfo{
+			Version: "2.14.2",
+			Bridges: []agentv1alpha1.OVSBridge{
+				newBridge("ovsbr0-uplink", bond, newPort("ovsbr0-uplink-cls", "patch")),
+				newBridge("ovsbr0", newPort("tap01", ""), newPort("tap02", "system"), newPort("ovsbr0-policy", "patch")),
+				newBridge("ovsbr0-policy"),
+				newBridge("ovsbr0-cls"),
+				newBridge("ovsbr0-nat"),
+				newBridge("br-tun", newPort("geneve0", "geneve")),
+			},
+		},
+	}
+}
+
+func TestBuildTopology(t *testing.T) {
+	RegisterTestingT(t)
+
+	topology := BuildTopology(newAgentInfo())
+	Expect(topology.Name).Should(Equal("agent01"))
+	Expect(topology.Hostname).Should(Equal("node01"))
+	Expect(topology.OVSVersion).Should(Equal("2.14.2"))
+
+	roles := make(map[string]agentv1alpha1.BridgeRole)
+	bridges := make(map[string]agentv1alpha1.TopologyBridge)
+	var names []string
+	for _, bridge := range topology.Bridges {
+		names = append(names, bridge.Name)
+		roles[bridge.Name] = bridge.Role
+		bridges[bridge.Name] = bridge
+	}
+	Expect(names).Should(Equal([]string{"br-tun", "ovsbr0", "ovsbr0-cls", "ovsbr0-nat", "ovsbr0-policy", "ovsbr0-uplink"}))
+	Expect(roles).Should(Equal(map[string]agentv1alpha1.BridgeRole{
+		"br-tun":        agentv1alpha1.BridgeRoleUnmanaged,
+		"ovsbr0":        agentv1alpha1.BridgeRoleLocal,
+		"ovsbr0-policy": agentv1alpha1.BridgeRolePolicy,
+		"ovsbr0-cls":    agentv1alpha1.BridgeRoleCls,
+		"ovsbr0-uplink": agentv1alpha1.BridgeRoleUplink,
+		"ovsbr0-nat":    agentv1alpha1.BridgeRoleNat,
+	}))
+
+	Expect(bridges["ovsbr0"].EndpointCount).Should(Equal(int32(2)))
+	Expect(bridges["br-tun"].Tunnels).Should(Equal([]agentv1alpha1.TopologyTunnel{{Name: "geneve0", Type: "geneve"}}))
+
+	uplinks := bridges["ovsbr0-uplink"].Uplinks
+	Expect(uplinks).Should(HaveLen(1))
+	Expect(uplinks[0].Name).Should(Equal("bond0"))
+	Expect(uplinks[0].BondMode).Should(Equal(agentv1alpha1.BondModeActiveBackup))
+	Expect(uplinks[0].Interfaces).Should(HaveLen(2))
+	Expect(uplinks[0].Interfaces[0].Neighbor.SystemName).Should(Equal("tor01"))
+	Expect(uplinks[0].Interfaces[1].LinkState).Should(Equal("down"))
+	Expect(uplinks[0].Interfaces[1].BFDState).Should(Equal("down"))
+
+	Expect(topology.Links).Should(Equal([]agentv1alpha1.TopologyLink{
+		{From: "ovsbr0", To: "ovsbr0-policy"},
+		{From: "ovsbr0-policy", To: "ovsbr0-cls"},
+		{From: "ovsbr0-cls", To: "ovsbr0-uplink"},
+		{From: "ovsbr0", To: "ovsbr0-nat"},
+	}))
+}
+
+func TestReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx := context.Background()
+	agentInfo := newAgentInfo()
+	c := fake.NewFakeClientWithScheme(clientsetscheme.Scheme, agentInfo)
+	r := &Reconciler{Client: c, Scheme: clientsetscheme.Scheme}
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: agentInfo.Name}}
+	getTopology := func() *agentv1alpha1.NetworkTopology {
+		topology := &agentv1alpha1.NetworkTopology{}
+		Expect(c.Get(ctx, req.NamespacedName, topology)).Should(Succeed())
+		return topology
+	}
+
+	_, err := r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	topology := getTopology()
+	Expect(topology.Bridges).Should(HaveLen(6))
+	Expect(topology.OwnerReferences).Should(HaveLen(1))
+	Expect(topology.OwnerReferences[0].Name).Should(Equal("agent01"))
+
+	// not updated when the topology unchanged
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getTopology().ResourceVersion).Should(Equal(topology.ResourceVersion))
+
+	Expect(c.Get(ctx, req.NamespacedName, agentInfo)).Should(Succeed())
+	agentInfo.OVSInfo.Bridges = agentInfo.OVSInfo.Bridges[:1]
+	Expect(c.Update(ctx, agentInfo)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(getTopology().Bridges).Should(HaveLen(1))
+
+	Expect(c.Delete(ctx, agentInfo)).Should(Succeed())
+	_, err = r.Reconcile(req)
+	Expect(err).ShouldNot(HaveOccurred())
+	err = c.Get(ctx, req.NamespacedName, &agentv1alpha1.NetworkTopology{})
+	Expect(errors.IsNotFound(err)).Should(BeTrue())
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdschema
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// IndexFile lists the schemas in the schemas root.
+const IndexFile = "index.json"
+
+// schemasFS are the schemas generated by make schemas-gen.
+//
+//go:embed schemas
+var schemasFS embed.FS
+
+func schemasRoot() fs.FS {
+	root, err := fs.Sub(schemasFS, "schemas")
+	if err != nil {
+		panic(err)
+	}
+	return root
+}
+
+// Index returns the schemas embedded, without the contents.
+func Index() ([]Schema, error) {
+	data, err := fs.ReadFile(schemasRoot(), IndexFile)
+	if err != nil {
+		return nil, err
+	}
+	var schemas []Schema
+	return schemas, json.Unmarshal(data, &schemas)
+}
+
+// Handler serves the index of the schemas at the prefix, and the schemas at prefix/<path>.
+func Handler(prefix string) http.Handler {
+	root := schemasRoot()
+	files := http.FileServer(http.FS(root))
+	return http.StripPrefix(strings.TrimSuffix(prefix, "/"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || r.URL.Path == "/" {
+			r.URL.Path = "/" + IndexFile
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
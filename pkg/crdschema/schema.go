@@ -0,0 +1,238 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdschema converts the openAPIV3Schema of the everoute CRDs into standalone JSON
+// schemas for the IaC tools and the IDEs. The schemas generated from deploy/chart/templates/crds
+// are embedded, and served by the controller.
+package crdschema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// JSONSchemaDraft is the JSON schema version of the schemas.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the JSON schema of a served version of a CRD.
+type Schema struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// Path of the schema relative to the schemas root, <group>/<kind>_<version>.json in
+	// lower case, the same layout as the public CRD schema catalogs.
+	Path string `json:"path"`
+
+	Content []byte `json:"-"`
+}
+
+type crd struct {
+	Spec struct {
+		Group string `json:"group"`
+		Names struct {
+			Kind string `json:"kind"`
+		} `json:"names"`
+		Versions []struct {
+			Name   string `json:"name"`
+			Served bool   `json:"served"`
+			Schema struct {
+				OpenAPIV3Schema map[string]interface{} `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+// FromCRDs returns the schemas of the served versions of the CRDs in the yaml documents.
+func FromCRDs(data []byte) ([]Schema, error) {
+	var schemas []Schema
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		var crd crd
+		if err = json.Unmarshal(raw, &crd); err != nil {
+			return nil, err
+		}
+		if crd.Spec.Group == "" {
+			continue
+		}
+
+		for _, version := range crd.Spec.Versions {
+			if !version.Served || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			schema := Schema{
+				Group:   crd.Spec.Group,
+				Version: version.Name,
+				Kind:    crd.Spec.Names.Kind,
+			}
+			schema.Path = path.Join(schema.Group, strings.ToLower(schema.Kind)+"_"+schema.Version+".json")
+			if schema.Content, err = convertRoot(version.Schema.OpenAPIV3Schema, schema); err != nil {
+				return nil, fmt.Errorf("convert %s: %s", schema.Path, err)
+			}
+			schemas = append(schemas, schema)
+		}
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Path < schemas[j].Path })
+	return schemas, nil
+}
+
+// convertRoot converts the schema of the object, the apiVersion and the kind are fixed, and the
+// metadata fields are completed for the autocompletion.
+func convertRoot(node map[string]interface{}, schema Schema) ([]byte, error) {
+	convert(node)
+
+	properties, _ := node["properties"].(map[string]interface{})
+	if properties == nil {
+		return nil, fmt.Errorf("no properties in openAPIV3Schema")
+	}
+	if apiVersion, ok := properties["apiVersion"].(map[string]interface{}); ok {
+		apiVersion["enum"] = []string{schema.Group + "/" + schema.Version}
+	}
+	if kind, ok := properties["kind"].(map[string]interface{}); ok {
+		kind["enum"] = []string{schema.Kind}
+	}
+	properties["metadata"] = objectMetaSchema()
+	node["required"] = mergeRequired(node["required"], "apiVersion", "kind", "metadata")
+	node["$schema"] = JSONSchemaDraft
+	node["title"] = schema.Kind
+
+	content, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// convert rewrites the kubernetes extensions of the structural schema into JSON schema in place:
+// nullable becomes the null type, and the objects with properties reject unknown fields, as they
+// are pruned by the apiserver, unless x-kubernetes-preserve-unknown-fields.
+func convert(node map[string]interface{}) {
+	if nullable, _ := node["nullable"].(bool); nullable {
+		if t, ok := node["type"].(string); ok {
+			node["type"] = []string{t, "null"}
+		}
+	}
+	delete(node, "nullable")
+
+	preserveUnknown, _ := node["x-kubernetes-preserve-unknown-fields"].(bool)
+	if _, ok := node["properties"]; ok && !preserveUnknown {
+		if _, ok := node["additionalProperties"]; !ok {
+			node["additionalProperties"] = false
+		}
+	}
+
+	if properties, ok := node["properties"].(map[string]interface{}); ok {
+		for _, property := range properties {
+			convertChild(property)
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		convertChild(node[key])
+	}
+	for _, key := range []string{"anyOf", "allOf", "oneOf"} {
+		if children, ok := node[key].([]interface{}); ok {
+			for _, child := range children {
+				convertChild(child)
+			}
+		}
+	}
+}
+
+func convertChild(child interface{}) {
+	if node, ok := child.(map[string]interface{}); ok {
+		convert(node)
+	}
+}
+
+func mergeRequired(required interface{}, fields ...string) []string {
+	var merged []string
+	seen := make(map[string]bool)
+	list, _ := required.([]interface{})
+	for _, item := range list {
+		if field, ok := item.(string); ok && !seen[field] {
+			seen[field] = true
+			merged = append(merged, field)
+		}
+	}
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			merged = append(merged, field)
+		}
+	}
+	return merged
+}
+
+// objectMetaSchema is the schema of the metadata fields set by the users.
+func objectMetaSchema() map[string]interface{} {
+	stringMap := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":         map[string]interface{}{"type": "string"},
+			"generateName": map[string]interface{}{"type": "string"},
+			"namespace":    map[string]interface{}{"type": "string"},
+			"labels":       stringMap,
+			"annotations":  stringMap,
+		},
+	}
+}
+
+// LoadCRDDir returns the schemas of the CRDs in the yaml files of the directory.
+func LoadCRDDir(dir string) ([]Schema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var schemas []Schema
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileSchemas, err := FromCRDs(data)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %s", file, err)
+		}
+		schemas = append(schemas, fileSchemas...)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Path < schemas[j].Path })
+	return schemas, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdschema
+
+import (
+	"encoding/json"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const testCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+spec:
+  group: example.everoute.io
+  names:
+    kind: Example
+  versions:
+  - name: v1alpha1
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        required: [spec]
+        properties:
+          apiVersion:
+            type: string
+          kind:
+            type: string
+          metadata:
+            type: object
+          spec:
+            type: object
+            properties:
+              port:
+                anyOf:
+                - type: integer
+                - type: string
+                x-kubernetes-int-or-string: true
+              expireTime:
+                type: string
+                format: date-time
+                nullable: true
+              extra:
+                type: object
+                x-kubernetes-preserve-unknown-fields: true
+                properties:
+                  name:
+                    type: string
+  - name: v1alpha0
+    served: false
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+
+func TestFromCRDs(t *testing.T) {
+	RegisterTestingT(t)
+
+	schemas, err := FromCRDs([]byte(testCRD))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(schemas).Should(HaveLen(1))
+	Expect(schemas[0].Path).Should(Equal("example.everoute.io/example_v1alpha1.json"))
+
+	var schema map[string]interface{}
+	Expect(json.Unmarshal(schemas[0].Content, &schema)).Should(Succeed())
+	Expect(schema["$schema"]).Should(Equal(JSONSchemaDraft))
+	Expect(schema["required"]).Should(Equal([]interface{}{"spec", "apiVersion", "kind", "metadata"}))
+	Expect(schema["additionalProperties"]).Should(Equal(false))
+
+	properties := schema["properties"].(map[string]interface{})
+	Expect(properties["apiVersion"]).Should(HaveKeyWithValue("enum", []interface{}{"example.everoute.io/v1alpha1"}))
+	Expect(properties["kind"]).Should(HaveKeyWithValue("enum", []interface{}{"Example"}))
+	Expect(properties["metadata"]).Should(HaveKey("properties"))
+
+	spec := properties["spec"].(map[string]interface{})
+	Expect(spec["additionalProperties"]).Should(Equal(false))
+	specProperties := spec["properties"].(map[string]interface{})
+	Expect(specProperties["expireTime"]).Should(HaveKeyWithValue("type", []interface{}{"string", "null"}))
+	Expect(specProperties["expireTime"]).ShouldNot(HaveKey("nullable"))
+	Expect(specProperties["port"]).ShouldNot(HaveKey("additionalProperties"))
+	Expect(specProperties["extra"]).ShouldNot(HaveKey("additionalProperties"))
+}
+
+// TestSchemasUpToDate asserts the schemas embedded are generated from the current CRDs.
+func TestSchemasUpToDate(t *testing.T) {
+	RegisterTestingT(t)
+
+	schemas, err := LoadCRDDir("../../deploy/chart/templates/crds")
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(schemas).ShouldNot(BeEmpty())
+
+	index, err := Index()
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(index).Should(HaveLen(len(schemas)), "schemas outdated, run make schemas-gen")
+	for i, schema := range schemas {
+		Expect(index[i].Path).Should(Equal(schema.Path))
+		content, err := fs.ReadFile(schemasRoot(), schema.Path)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(content)).Should(Equal(string(schema.Content)), "schema %s outdated, run make schemas-gen", schema.Path)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(Handler("/schemas"))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/schemas/")
+	Expect(err).ShouldNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+	var index []Schema
+	Expect(json.NewDecoder(resp.Body).Decode(&index)).Should(Succeed())
+	Expect(index).ShouldNot(BeEmpty())
+
+	resp, err = http.Get(server.URL + "/schemas/security.everoute.io/securitypolicy_v1alpha1.json")
+	Expect(err).ShouldNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+	body, err := ioutil.ReadAll(resp.Body)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(json.Valid(body)).Should(BeTrue())
+
+	resp, err = http.Get(server.URL + "/schemas/security.everoute.io/unknown_v1alpha1.json")
+	Expect(err).ShouldNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).Should(Equal(http.StatusNotFound))
+}
@@ -0,0 +1,52 @@
+package erctl
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/utils"
+)
+
+// DiagnosticsSubresource is the virtual subresource of agentinfos, whose get permission
+// guards the datapath diagnostics of the agent, and update permission guards the
+// datapath control, e.g. flush fdb.
+const DiagnosticsSubresource = "diagnostics"
+
+// ConnectAppctl check if the current user could access the datapath diagnostics of
+// current agent with the verb.
+func ConnectAppctl(verb string) error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       agentv1alpha1.SchemeGroupVersion.Group,
+				Resource:    "agentinfos",
+				Subresource: DiagnosticsSubresource,
+				Verb:        verb,
+				Name:        utils.CurrentAgentName(),
+			},
+		},
+	}
+	review, err = kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("%s agentinfos/%s of %s denied: %s", verb, DiagnosticsSubresource, utils.CurrentAgentName(), review.Status.Reason)
+	}
+	return nil
+}
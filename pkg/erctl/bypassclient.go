@@ -0,0 +1,74 @@
+package erctl
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+var bypassconn clientset.Interface
+
+func ConnectBypass() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	bypassconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+// NewBypassPatch return the merge patch of the endpoint annotations, bypass the enforcement
+// of the endpoint until expireTime. The annotations are removed if expireTime is zero.
+func NewBypassPatch(expireTime time.Time, reason string) ([]byte, error) {
+	annotations := map[string]interface{}{
+		constants.EnforcementBypassExpireTimeAnnotation: nil,
+		constants.EnforcementBypassReasonAnnotation:     nil,
+	}
+	if !expireTime.IsZero() {
+		annotations[constants.EnforcementBypassExpireTimeAnnotation] = expireTime.UTC().Format(time.RFC3339)
+		annotations[constants.EnforcementBypassReasonAnnotation] = reason
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+}
+
+func BypassEndpoint(namespace, endpoint string, ttl time.Duration, reason string) (*securityv1alpha1.Endpoint, error) {
+	patch, err := NewBypassPatch(time.Now().Add(ttl), reason)
+	if err != nil {
+		return nil, err
+	}
+	return bypassconn.SecurityV1alpha1().Endpoints(namespace).Patch(context.Background(), endpoint, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+func ReleaseBypassEndpoint(namespace, endpoint string) error {
+	patch, err := NewBypassPatch(time.Time{}, "")
+	if err != nil {
+		return err
+	}
+	_, err = bypassconn.SecurityV1alpha1().Endpoints(namespace).Patch(context.Background(), endpoint, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// GetBypassEndpoints return the endpoints with the enforcement bypassed.
+func GetBypassEndpoints(namespace string) ([]securityv1alpha1.Endpoint, error) {
+	endpointList, err := bypassconn.SecurityV1alpha1().Endpoints(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []securityv1alpha1.Endpoint
+	for _, endpoint := range endpointList.Items {
+		if _, ok := endpoint.Annotations[constants.EnforcementBypassExpireTimeAnnotation]; ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
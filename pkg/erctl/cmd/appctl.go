@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var appctlFlushPort string
+
+var appctlCmd = &cobra.Command{
+	Use:   "appctl",
+	Short: "datapath diagnostics by ovs-appctl",
+	Long: "run ovs-appctl commands and print the results in json\n" +
+		"you should use [appctl dump-flows], [appctl fdb BRIDGE], [appctl fdb-flush BRIDGE] or [appctl coverage]\n" +
+		"requires get permission of agentinfos/diagnostics of current agent",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return erctl.ConnectAppctl("get")
+	},
+}
+
+var appctlDumpFlowsCmd = &cobra.Command{
+	Use:   "dump-flows",
+	Short: "show the flows in the kernel datapath, by dpctl/dump-flows",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flows, err := appctl.DumpDatapathFlows()
+		if err != nil {
+			return err
+		}
+		return printAppctl(flows)
+	},
+}
+
+var appctlFDBCmd = &cobra.Command{
+	Use:   "fdb BRIDGE",
+	Short: "show the learned macs of the bridge, by fdb/show",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := appctl.ShowFDB(args[0])
+		if err != nil {
+			return err
+		}
+		return printAppctl(entries)
+	},
+}
+
+var appctlFDBFlushCmd = &cobra.Command{
+	Use:   "fdb-flush BRIDGE",
+	Short: "remove the learned macs of the bridge",
+	Long: "remove the learned macs on the port of the bridge, or all the macs of the bridge if no port specified\n" +
+		"--port is the ofport number or LOCAL, as shown by [appctl fdb BRIDGE]\n" +
+		"requires update permission of agentinfos/diagnostics of current agent",
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return erctl.ConnectAppctl("update")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flushed, err := appctl.FlushFDB(args[0], appctlFlushPort)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%d fdb entries of bridge %s flushed\n", flushed, args[0])
+		return err
+	},
+}
+
+var appctlCoverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "show the ovs internal event counters, by coverage/show",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		counters, err := appctl.ShowCoverage()
+		if err != nil {
+			return err
+		}
+		return printAppctl(counters)
+	},
+}
+
+func printAppctl(something interface{}) error {
+	out, err := setOutput()
+	if err != nil {
+		return err
+	}
+	return print(out, something)
+}
+
+func init() {
+	rootCmd.AddCommand(appctlCmd)
+	appctlCmd.AddCommand(appctlDumpFlowsCmd)
+	appctlCmd.AddCommand(appctlFDBCmd)
+	appctlCmd.AddCommand(appctlFDBFlushCmd)
+	appctlCmd.AddCommand(appctlCoverageCmd)
+	appctlFDBFlushCmd.Flags().StringVar(&appctlFlushPort, "port", "", "only flush the macs learned on the port")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var appliedPolicyNamespace string
+
+var appliedPolicyCmd = &cobra.Command{
+	Use:   "applied-policies ENDPOINT",
+	Short: "get the security policies applied to an endpoint",
+	Long: "list the security policies applied to the endpoint, by the reverse index maintained by the controller\n" +
+		"-n means the namespace of the endpoint",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectPolicy(); err != nil {
+			return err
+		}
+		policies, err := erctl.GetAppliedPolicies(appliedPolicyNamespace, args[0])
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, policies)
+	},
+}
+
+func init() {
+	getCmd.AddCommand(appliedPolicyCmd)
+	appliedPolicyCmd.Flags().StringVarP(&appliedPolicyNamespace, "namespace", "n", "default", "specify namespace of the endpoint")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	bypassNamespace string
+	bypassTTL       time.Duration
+	bypassReason    string
+)
+
+var bypassCmd = &cobra.Command{
+	Use:   "bypass",
+	Short: "bypass enforcement of endpoints for troubleshooting",
+	Long: "allow all traffics of an endpoint temporarily, for the suspected false positive drops\n" +
+		"you should use [bypass endpoint NAME], [bypass release NAME] or [bypass list]",
+}
+
+var bypassEndpointCmd = &cobra.Command{
+	Use:   "endpoint NAME",
+	Short: "bypass enforcement of an endpoint",
+	Long: "allow all traffics of the endpoint, takes precedence over all policies\n" +
+		"--ttl is required, the enforcement restored automatically after the duration\n" +
+		"--reason is recorded in the events of the endpoint for audit",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bypassTTL <= 0 {
+			return fmt.Errorf("--ttl must be specified")
+		}
+		if err := erctl.ConnectBypass(); err != nil {
+			return err
+		}
+		endpoint, err := erctl.BypassEndpoint(bypassNamespace, args[0], bypassTTL, bypassReason)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, endpoint)
+	},
+}
+
+var bypassReleaseCmd = &cobra.Command{
+	Use:   "release NAME",
+	Short: "restore enforcement of an endpoint",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectBypass(); err != nil {
+			return err
+		}
+		if err := erctl.ReleaseBypassEndpoint(bypassNamespace, args[0]); err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "endpoint %s/%s enforcement restored\n", bypassNamespace, args[0])
+		return err
+	},
+}
+
+var bypassListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list endpoints with enforcement bypassed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectBypass(); err != nil {
+			return err
+		}
+		endpoints, err := erctl.GetBypassEndpoints(bypassNamespace)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, endpoints)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bypassCmd)
+	bypassCmd.AddCommand(bypassEndpointCmd, bypassReleaseCmd, bypassListCmd)
+	bypassCmd.PersistentFlags().StringVarP(&bypassNamespace, "namespace", "n", "default", "specify namespace of the endpoint")
+	bypassEndpointCmd.Flags().DurationVar(&bypassTTL, "ttl", 0, "restore enforcement after the duration, at most 24h")
+	bypassEndpointCmd.Flags().StringVar(&bypassReason, "reason", "", "specify the reason for audit")
+}
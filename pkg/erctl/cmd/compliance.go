@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var complianceCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "export compliance reports",
+	Long: "export the results of the ComplianceReports evaluated by the controller in json\n" +
+		"you should use [compliance export NAME] or [compliance list]",
+}
+
+var complianceExportCmd = &cobra.Command{
+	Use:   "export NAME",
+	Short: "export a compliance report",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectComplianceReport(); err != nil {
+			return err
+		}
+		report, err := erctl.GetComplianceReport(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, report)
+	},
+}
+
+var complianceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list compliance reports",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectComplianceReport(); err != nil {
+			return err
+		}
+		reports, err := erctl.GetComplianceReports()
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, reports)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(complianceCmd)
+	complianceCmd.AddCommand(complianceExportCmd, complianceListCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+	"github.com/everoute/everoute/pkg/migrate"
+)
+
+var (
+	convertFiles     []string
+	convertNamespace string
+	convertStrict    bool
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "convert SecurityPolicies into Kubernetes NetworkPolicies",
+	Long: "convert the SecurityPolicies into the equivalent Kubernetes NetworkPolicies where possible, and print them as yaml\n" +
+		"-f means convert the SecurityPolicies in the files, - for stdin, default the SecurityPolicies in the cluster\n" +
+		"-n means convert the SecurityPolicies in the namespace of the cluster, default all namespaces\n" +
+		"the constructs could not be converted exactly are listed in the compatibility report on stderr, review it before\n" +
+		"applying the NetworkPolicies, with --strict exit with error if any construct not supported",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := convertSecurityPolicies()
+		if err != nil {
+			return err
+		}
+
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		if err = migrate.EncodeNetworkPolicies(out, result.NetworkPolicies); err != nil {
+			return err
+		}
+		if _, err = fmt.Fprint(cmd.ErrOrStderr(), result.Report.String()); err != nil {
+			return err
+		}
+
+		if unsupported := result.Report.Count(migrate.SeverityUnsupported); convertStrict && unsupported != 0 {
+			return fmt.Errorf("%d unsupported constructs", unsupported)
+		}
+		return nil
+	},
+}
+
+func convertSecurityPolicies() (*migrate.NetworkPolicyResult, error) {
+	if len(convertFiles) == 0 {
+		if err := erctl.ConnectPolicy(); err != nil {
+			return nil, err
+		}
+		policies, err := erctl.GetSecurityPolicies(convertNamespace)
+		if err != nil {
+			return nil, err
+		}
+		return migrate.ConvertSecurityPolicies(policies), nil
+	}
+
+	var data bytes.Buffer
+	for _, file := range convertFiles {
+		var content []byte
+		var err error
+		if file == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		data.Write(content)
+		data.WriteString("\n---\n")
+	}
+	return migrate.ConvertToNetworkPolicies(data.Bytes())
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringSliceVarP(&convertFiles, "filename", "f", nil, "specify files of the SecurityPolicies to convert")
+	convertCmd.Flags().StringVarP(&convertNamespace, "namespace", "n", "", "specify namespace of the SecurityPolicies in the cluster")
+	convertCmd.Flags().BoolVar(&convertStrict, "strict", false, "exit with error if any construct not supported")
+}
@@ -7,7 +7,7 @@ import (
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "get something",
-	Long:  `you shold use [get rule], [get flow] or [get svc]`,
+	Long:  `you shold use [get rule], [get flow], [get pipeline], [get svc] or [get unused-rules]`,
 }
 
 func init() {
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	pipelineBridges []string
+	pipelineFormat  string
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "get the openflow pipeline of the bridges",
+	Long: "list the tables of the bridges with their purpose, the number of flows and the tables they go to\n" +
+		"--format json or dot, the dot output could be rendered by graphviz, e.g. erctl get pipeline --format dot | dot -Tsvg",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pipelineFormat != "json" && pipelineFormat != "dot" {
+			return fmt.Errorf("unsupported format %s, only json and dot supported", pipelineFormat)
+		}
+		if err := erctl.ConnectFlow(); err != nil {
+			return err
+		}
+		pipelines, err := erctl.GetPipelines(pipelineBridges...)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		if pipelineFormat == "dot" {
+			return erctl.WritePipelineDOT(out, pipelines)
+		}
+		return print(out, pipelines)
+	},
+}
+
+func init() {
+	getCmd.AddCommand(pipelineCmd)
+	pipelineCmd.Flags().StringSliceVar(&pipelineBridges, "bridge", []string{}, "bridge's name")
+	pipelineCmd.Flags().StringVar(&pipelineFormat, "format", "json", "output format, json or dot")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "export or approve policy plans",
+	Long: "export the PolicyPlans planned by the controller in json, or approve them to apply\n" +
+		"you should use [plan export NAME], [plan list] or [plan approve NAME]",
+}
+
+var planExportCmd = &cobra.Command{
+	Use:   "export NAME",
+	Short: "export a policy plan",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectPolicyPlan(); err != nil {
+			return err
+		}
+		plan, err := erctl.GetPolicyPlan(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, plan)
+	},
+}
+
+var planListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list policy plans",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectPolicyPlan(); err != nil {
+			return err
+		}
+		plans, err := erctl.GetPolicyPlans()
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, plans)
+	},
+}
+
+var planApproveCmd = &cobra.Command{
+	Use:   "approve NAME",
+	Short: "approve a policy plan to apply its changes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectPolicyPlan(); err != nil {
+			return err
+		}
+		plan, err := erctl.ApprovePolicyPlan(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, plan)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planExportCmd, planListCmd, planApproveCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	quarantineNamespace string
+	quarantineTTL       time.Duration
+	quarantineExcepts   []string
+)
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "quarantine endpoints for incident response",
+	Long: "create Quarantine to drop all traffics of an endpoint immediately\n" +
+		"you should use [quarantine endpoint NAME], [quarantine release NAME] or [quarantine list]",
+}
+
+var quarantineEndpointCmd = &cobra.Command{
+	Use:   "endpoint NAME",
+	Short: "quarantine an endpoint",
+	Long: "drop all traffics of the endpoint, takes precedence over all other policies\n" +
+		"--except allows traffics from or to the cidrs\n" +
+		"--ttl means release the endpoint automatically after the duration",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectQuarantine(); err != nil {
+			return err
+		}
+		quarantine, err := erctl.QuarantineEndpoint(quarantineNamespace, args[0], quarantineTTL, quarantineExcepts)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, quarantine)
+	},
+}
+
+var quarantineReleaseCmd = &cobra.Command{
+	Use:   "release NAME",
+	Short: "release a quarantined endpoint",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectQuarantine(); err != nil {
+			return err
+		}
+		if err := erctl.ReleaseEndpoint(quarantineNamespace, args[0]); err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "endpoint %s/%s released\n", quarantineNamespace, args[0])
+		return err
+	},
+}
+
+var quarantineListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list quarantines",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectQuarantine(); err != nil {
+			return err
+		}
+		quarantines, err := erctl.GetQuarantines(quarantineNamespace)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, quarantines)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(quarantineCmd)
+	quarantineCmd.AddCommand(quarantineEndpointCmd, quarantineReleaseCmd, quarantineListCmd)
+	quarantineCmd.PersistentFlags().StringVarP(&quarantineNamespace, "namespace", "n", "default", "specify namespace of the endpoint")
+	quarantineEndpointCmd.Flags().DurationVar(&quarantineTTL, "ttl", 0, "release the endpoint after the duration, never if not set")
+	quarantineEndpointCmd.Flags().StringSliceVar(&quarantineExcepts, "except", []string{}, "specify cidrs still allowed")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var reachabilityCmd = &cobra.Command{
+	Use:   "reachability",
+	Short: "export reachability matrices",
+	Long: "export the results of the ReachabilityMatrices analyzed by the controller in json\n" +
+		"you should use [reachability export NAME] or [reachability list]",
+}
+
+var reachabilityExportCmd = &cobra.Command{
+	Use:   "export NAME",
+	Short: "export a reachability matrix",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectReachabilityMatrix(); err != nil {
+			return err
+		}
+		matrix, err := erctl.GetReachabilityMatrix(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, matrix)
+	},
+}
+
+var reachabilityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list reachability matrices",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectReachabilityMatrix(); err != nil {
+			return err
+		}
+		matrices, err := erctl.GetReachabilityMatrices()
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, matrices)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reachabilityCmd)
+	reachabilityCmd.AddCommand(reachabilityExportCmd, reachabilityListCmd)
+}
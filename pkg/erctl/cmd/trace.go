@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	traceRequest erctl.TraceRequest
+	traceFormat  string
+)
+
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "trace a packet in the datapath",
+	Long: "trace the packet by ofproto/trace across all the tables of the bridges, show whether it's allowed or denied\n" +
+		"and the policy rules it matched\n" +
+		"--src and --dst are the endpoints in format of namespace/name or the ipv4 addresses\n" +
+		"--bridge and --in-port are required if the source is not an endpoint on current agent\n" +
+		"requires get permission of agentinfos/diagnostics of current agent\n" +
+		"e.g. erctl trace --src default/web --dst 10.0.0.2 --protocol tcp --dst-port 80",
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if traceRequest.Src == "" || traceRequest.Dst == "" {
+			return fmt.Errorf("--src and --dst must be specified")
+		}
+		if traceFormat != "text" && traceFormat != "json" {
+			return fmt.Errorf("unsupported format %s, only text and json supported", traceFormat)
+		}
+		return erctl.ConnectAppctl("get")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verdict, err := erctl.Trace(traceRequest)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		if traceFormat == "json" {
+			return print(out, verdict)
+		}
+		return erctl.WriteTraceVerdict(out, verdict)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(traceCmd)
+	traceCmd.Flags().StringVar(&traceRequest.Src, "src", "", "source endpoint namespace/name or ip")
+	traceCmd.Flags().StringVar(&traceRequest.Dst, "dst", "", "destination endpoint namespace/name or ip")
+	traceCmd.Flags().StringVar(&traceRequest.Protocol, "protocol", "", "ip protocol, tcp, udp, sctp or icmp, default any ip")
+	traceCmd.Flags().Uint16Var(&traceRequest.SrcPort, "src-port", 0, "source port")
+	traceCmd.Flags().Uint16Var(&traceRequest.DstPort, "dst-port", 0, "destination port")
+	traceCmd.Flags().StringVar(&traceRequest.Bridge, "bridge", "", "bridge the packet comes from, default the bridge of the source endpoint")
+	traceCmd.Flags().StringVar(&traceRequest.InPort, "in-port", "", "port the packet comes from, default the interface of the source endpoint")
+	traceCmd.Flags().StringVar(&traceFormat, "format", "text", "output format, text or json")
+}
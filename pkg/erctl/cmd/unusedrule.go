@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/agent/rulehit"
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	unusedRuleNamespace string
+	unusedRuleFor       time.Duration
+)
+
+var unusedRuleCmd = &cobra.Command{
+	Use:   "unused-rules",
+	Short: "get policy rules unmatched for a duration",
+	Long: "list the policy rules unmatched for --unused-for, by the last hit time recorded by the agents\n" +
+		"the rule hit tracking of the agents must be enabled, the rules never matched are counted from the policy created\n" +
+		"-n means get the policies in the namespace, default all namespaces",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectPolicy(); err != nil {
+			return err
+		}
+		reports, err := erctl.GetUnusedRules(unusedRuleNamespace, unusedRuleFor)
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, reports)
+	},
+}
+
+func init() {
+	getCmd.AddCommand(unusedRuleCmd)
+	unusedRuleCmd.Flags().StringVarP(&unusedRuleNamespace, "namespace", "n", "", "specify namespace of the policies")
+	unusedRuleCmd.Flags().DurationVar(&unusedRuleFor, "unused-for", rulehit.DefaultUnusedFor, "specify the duration of the rules unmatched")
+}
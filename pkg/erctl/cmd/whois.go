@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/everoute/everoute/pkg/erctl"
+)
+
+var (
+	whoisHistory bool
+	whoisSince   time.Duration
+)
+
+var whoisCmd = &cobra.Command{
+	Use:   "whois IP",
+	Short: "find what owns an ip in the cluster",
+	Long: "show the endpoints, learned interfaces and services own the ip, on which nodes, the groups contain it\n" +
+		"and the policies reference it, by the ip indexes of the controller, the policy views must be enabled\n" +
+		"with --history, show the endpoints learned, unlearned the ip and migrated in the past instead, the ip\n" +
+		"history must be enabled on the controller",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := erctl.ConnectWhois(); err != nil {
+			return err
+		}
+		var obj interface{}
+		var err error
+		if whoisHistory {
+			obj, err = erctl.GetIPHistory(args[0], whoisSince)
+		} else {
+			obj, err = erctl.GetIPOwner(args[0])
+		}
+		if err != nil {
+			return err
+		}
+		out, err := setOutput()
+		if err != nil {
+			return err
+		}
+		return print(out, obj)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoisCmd)
+	whoisCmd.Flags().BoolVar(&whoisHistory, "history", false, "show the history of the ip")
+	whoisCmd.Flags().DurationVar(&whoisSince, "since", 0, "only the history in the duration, e.g. 24h, all if zero")
+}
@@ -0,0 +1,34 @@
+package erctl
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+var complianceconn clientset.Interface
+
+func ConnectComplianceReport() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	complianceconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+func GetComplianceReport(name string) (*securityv1alpha1.ComplianceReport, error) {
+	return complianceconn.SecurityV1alpha1().ComplianceReports().Get(context.Background(), name, metav1.GetOptions{})
+}
+
+func GetComplianceReports() ([]securityv1alpha1.ComplianceReport, error) {
+	reportList, err := complianceconn.SecurityV1alpha1().ComplianceReports().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return reportList.Items, nil
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net"
 	"os/exec"
-	"regexp"
 	"strings"
 
 	"google.golang.org/grpc"
@@ -54,22 +53,10 @@ func GetFlows(dp bool, names ...string) (map[string][]string, error) {
 		names = allBridge
 		dp = true
 	} else if len(names) != 0 {
-		ans := []string{}
-		added := make([]bool, len(allBridge))
-		for _, name := range names {
-			want, err := regexp.Compile(name)
-			if err != nil {
-				return nil, err
-			}
-			for i := 0; i < len(allBridge) && !added[i]; i++ {
-				bridge := allBridge[i]
-				if want.FindString(bridge) == bridge {
-					ans = append(ans, bridge)
-					added[i] = true
-				}
-			}
+		var err error
+		if names, err = matchBridges(names); err != nil {
+			return nil, err
 		}
-		names = ans
 	}
 	laste := errors.New("cmd has err")
 	ans := map[string][]string{}
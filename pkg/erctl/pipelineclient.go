@@ -0,0 +1,198 @@
+package erctl
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+// PipelineTable is a table of the bridge with the flows installed.
+type PipelineTable struct {
+	ID          uint8  `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Flows       int    `json:"flows"`
+	// Gotos are the tables the packets sent to from this table, by goto_table, resubmit or ct.
+	Gotos []uint8 `json:"gotos,omitempty"`
+}
+
+// BridgePipeline is the tables of the bridge, in the order of the id.
+type BridgePipeline struct {
+	Bridge string          `json:"bridge"`
+	Tables []PipelineTable `json:"tables"`
+}
+
+var (
+	flowTableRegexp = regexp.MustCompile(`(?:^|[ ,])table=(\d+)`)
+	// goto_table:N, resubmit(,N), resubmit(port,N) and ct(table=N)
+	flowGotoRegexp = regexp.MustCompile(`goto_table:(\d+)|resubmit\([^,)]*,(\d+)\)|ct\((?:[^()]|\([^()]*\))*?table=(\d+)`)
+)
+
+// GetPipelines returns the pipelines of the bridges matched the names, all the bridges if no
+// names specified.
+func GetPipelines(names ...string) ([]BridgePipeline, error) {
+	if len(names) == 0 {
+		names = allBridge
+	} else {
+		var err error
+		if names, err = matchBridges(names); err != nil {
+			return nil, err
+		}
+	}
+
+	var pipelines []BridgePipeline
+	for _, name := range names {
+		// goto_table is only shown in openflow 1.1 and later
+		out, err := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "dump-flows", name).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("dump flows of bridge %s: %s, %s", name, err, strings.TrimSpace(string(out)))
+		}
+		pipelines = append(pipelines, parsePipeline(name, strings.Split(string(out), "\n")))
+	}
+	return pipelines, nil
+}
+
+// parsePipeline counts the flows and the gotos of each table from the flows dumped, the tables
+// are described by the layout of the bridge in datapath.PipelineLayout.
+func parsePipeline(bridge string, flows []string) BridgePipeline {
+	flowCounts := make(map[uint8]int)
+	gotos := make(map[uint8]sets.Int)
+	for _, flow := range flows {
+		actionsIndex := strings.Index(flow, "actions=")
+		if actionsIndex == -1 {
+			continue
+		}
+		// the flows without table are in table 0
+		var table uint8
+		if match := flowTableRegexp.FindStringSubmatch(flow[:actionsIndex]); match != nil {
+			id, err := strconv.ParseUint(match[1], 10, 8)
+			if err != nil {
+				continue
+			}
+			table = uint8(id)
+		}
+		flowCounts[table]++
+
+		for _, match := range flowGotoRegexp.FindAllStringSubmatch(flow[actionsIndex:], -1) {
+			for _, group := range match[1:] {
+				if id, err := strconv.ParseUint(group, 10, 8); err == nil && uint8(id) != table {
+					if gotos[table] == nil {
+						gotos[table] = sets.NewInt()
+					}
+					gotos[table].Insert(int(id))
+				}
+			}
+		}
+	}
+
+	pipeline := BridgePipeline{Bridge: bridge}
+	layout := pipelineLayoutOf(bridge, flowCounts)
+	for id, count := range flowCounts {
+		table := PipelineTable{ID: id, Flows: count}
+		if info, ok := layout[id]; ok {
+			table.Name, table.Description = info.Name, info.Description
+		}
+		if id == datapath.PipelineVersionTable {
+			table.Name, table.Description = "PipelineVersionTable", "Registers the pipeline version of the bridge."
+		}
+		for _, next := range gotos[id].List() {
+			table.Gotos = append(table.Gotos, uint8(next))
+		}
+		pipeline.Tables = append(pipeline.Tables, table)
+	}
+	sort.Slice(pipeline.Tables, func(i, j int) bool { return pipeline.Tables[i].ID < pipeline.Tables[j].ID })
+	return pipeline
+}
+
+// pipelineLayoutOf returns the tables of the bridge in the layout, the layout of the mode
+// defines the most tables installed is chosen when the bridge has layouts in many modes.
+func pipelineLayoutOf(bridge string, installed map[uint8]int) map[uint8]datapath.PipelineTable {
+	keyword := datapath.LOCAL_BRIDGE_KEYWORD
+	for _, suffix := range bridgeNameSuffix {
+		if suffix != "" && strings.HasSuffix(bridge, suffix) {
+			keyword = strings.TrimPrefix(suffix, "-")
+		}
+	}
+
+	var layout map[uint8]datapath.PipelineTable
+	var matched int
+	for _, pipeline := range datapath.PipelineLayout {
+		if pipeline.Bridge != keyword {
+			continue
+		}
+		tables := make(map[uint8]datapath.PipelineTable, len(pipeline.Tables))
+		var count int
+		for _, table := range pipeline.Tables {
+			tables[table.ID] = table
+			if _, ok := installed[table.ID]; ok {
+				count++
+			}
+		}
+		if layout == nil || count > matched {
+			layout, matched = tables, count
+		}
+	}
+	return layout
+}
+
+// WritePipelineDOT writes the pipelines as a graphviz digraph, each bridge is a cluster of the
+// tables, and each goto is an edge.
+func WritePipelineDOT(w io.Writer, pipelines []BridgePipeline) error {
+	lines := []string{"digraph pipeline {", "\trankdir=LR;", "\tnode [shape=box];"}
+	for i, pipeline := range pipelines {
+		lines = append(lines,
+			fmt.Sprintf("\tsubgraph cluster_%d {", i),
+			fmt.Sprintf("\t\tlabel=%q;", pipeline.Bridge),
+		)
+		for _, table := range pipeline.Tables {
+			label := fmt.Sprintf("table %d", table.ID)
+			if table.Name != "" {
+				label = fmt.Sprintf("%s\n%s", label, table.Name)
+			}
+			label = fmt.Sprintf("%s\n%d flows", label, table.Flows)
+			lines = append(lines, fmt.Sprintf("\t\t%q [label=%q];", dotNodeID(pipeline.Bridge, table.ID), label))
+		}
+		for _, table := range pipeline.Tables {
+			for _, next := range table.Gotos {
+				lines = append(lines, fmt.Sprintf("\t\t%q -> %q;", dotNodeID(pipeline.Bridge, table.ID), dotNodeID(pipeline.Bridge, next)))
+			}
+		}
+		lines = append(lines, "\t}")
+	}
+	lines = append(lines, "}")
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func dotNodeID(bridge string, table uint8) string {
+	return fmt.Sprintf("%s/%d", bridge, table)
+}
+
+// matchBridges returns the bridges whose full names matched any of the regexps.
+func matchBridges(names []string) ([]string, error) {
+	ans := []string{}
+	added := make([]bool, len(allBridge))
+	for _, name := range names {
+		want, err := regexp.Compile(name)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(allBridge) && !added[i]; i++ {
+			bridge := allBridge[i]
+			if want.FindString(bridge) == bridge {
+				ans = append(ans, bridge)
+				added[i] = true
+			}
+		}
+	}
+	return ans, nil
+}
@@ -0,0 +1,74 @@
+package erctl
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+)
+
+func TestParsePipeline(t *testing.T) {
+	RegisterTestingT(t)
+
+	flows := []string{
+		"OFPST_FLOW reply (OF1.3) (xid=0x2):",
+		" cookie=0x1, duration=1.1s, table=0, n_packets=0, n_bytes=0, priority=100,ip actions=ct(table=1,zone=65520)",
+		" cookie=0x1, duration=1.1s, table=0, n_packets=0, n_bytes=0, priority=0 actions=goto_table:10",
+		" cookie=0x1, duration=1.1s, table=1, n_packets=0, n_bytes=0, priority=100,ct_state=+est+trk,ip actions=resubmit(,80)",
+		" cookie=0x1, duration=1.1s, table=1, n_packets=0, n_bytes=0, priority=100,ct_state=+new+trk,ip actions=goto_table:10",
+		" cookie=0x1, duration=1.1s, table=70, n_packets=0, n_bytes=0, priority=100,ip actions=ct(commit,table=80,zone=65520,exec(load:0x1->NXM_NX_CT_LABEL[]))",
+		" cookie=0x1, duration=1.1s, table=253, n_packets=0, n_bytes=0, priority=100,metadata=0x1 actions=drop",
+		"",
+	}
+	pipeline := parsePipeline("ovsbr0-policy", flows)
+	Expect(pipeline).Should(Equal(BridgePipeline{
+		Bridge: "ovsbr0-policy",
+		Tables: []PipelineTable{
+			{ID: 0, Name: "INPUT_TABLE", Description: "Sends the ip packets to the conntrack.", Flows: 2, Gotos: []uint8{1, 10}},
+			{ID: 1, Name: "CT_STATE_TABLE", Description: "Drops the invalid connections, forwards the established connections to SFC_POLICY_TABLE.", Flows: 2, Gotos: []uint8{10, 80}},
+			{ID: 70, Name: "CT_COMMIT_TABLE", Description: "Commits the connections allowed by the rules.", Flows: 1, Gotos: []uint8{80}},
+			{ID: datapath.PipelineVersionTable, Name: "PipelineVersionTable", Description: "Registers the pipeline version of the bridge.", Flows: 1},
+		},
+	}))
+}
+
+func TestParsePipelineLayoutMode(t *testing.T) {
+	RegisterTestingT(t)
+
+	// the tables installed match the overlay layout of the local bridge
+	pipeline := parsePipeline("ovsbr0", []string{
+		" table=0, priority=100,arp actions=goto_table:10",
+		" table=10, priority=100,arp actions=goto_table:110",
+		" table=30, priority=100,in_port=1 actions=goto_table:60",
+		" table=110, priority=100 actions=output:NXM_NX_REG2[0..15]",
+	})
+	Expect(pipeline.Tables).Should(HaveLen(4))
+	Expect(pipeline.Tables[1].Name).Should(Equal("LBOArpProxyTable"))
+	Expect(pipeline.Tables[3].Name).Should(Equal("LBOOutputTable"))
+}
+
+func TestWritePipelineDOT(t *testing.T) {
+	RegisterTestingT(t)
+
+	var buf bytes.Buffer
+	Expect(WritePipelineDOT(&buf, []BridgePipeline{{
+		Bridge: "ovsbr0-policy",
+		Tables: []PipelineTable{
+			{ID: 0, Name: "INPUT_TABLE", Flows: 2, Gotos: []uint8{1}},
+			{ID: 1, Flows: 1},
+		},
+	}})).Should(Succeed())
+	Expect(buf.String()).Should(Equal(`digraph pipeline {
+	rankdir=LR;
+	node [shape=box];
+	subgraph cluster_0 {
+		label="ovsbr0-policy";
+		"ovsbr0-policy/0" [label="table 0\nINPUT_TABLE\n2 flows"];
+		"ovsbr0-policy/1" [label="table 1\n1 flows"];
+		"ovsbr0-policy/0" -> "ovsbr0-policy/1";
+	}
+}
+`))
+}
@@ -0,0 +1,80 @@
+package erctl
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/everoute/everoute/pkg/agent/rulehit"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+var policyconn clientset.Interface
+
+func ConnectPolicy() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	policyconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+// UnusedRules is the rules of a policy unmatched for a duration.
+type UnusedRules struct {
+	Namespace   string               `json:"namespace"`
+	Name        string               `json:"name"`
+	UnusedRules []string             `json:"unusedRules"`
+	LastHits    map[string]time.Time `json:"lastHits,omitempty"`
+	// AllUnused means none of the rules matched, the policy may be removed.
+	AllUnused bool `json:"allUnused"`
+}
+
+// GetUnusedRules return the policies with rules unmatched for the duration, of all
+// namespaces if the namespace is empty.
+func GetUnusedRules(namespace string, unusedFor time.Duration) ([]UnusedRules, error) {
+	policyList, err := policyconn.SecurityV1alpha1().SecurityPolicies(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []UnusedRules
+	now := time.Now()
+	for item := range policyList.Items {
+		policy := &policyList.Items[item]
+		unused := rulehit.UnusedRules(policy, unusedFor, now)
+		if len(unused) == 0 {
+			continue
+		}
+		reports = append(reports, UnusedRules{
+			Namespace:   policy.Namespace,
+			Name:        policy.Name,
+			UnusedRules: unused,
+			LastHits:    rulehit.LastHits(policy),
+			AllUnused:   len(unused) == len(rulehit.PolicyRuleNames(policy)),
+		})
+	}
+	return reports, nil
+}
+
+// GetAppliedPolicies return the policies applied to the endpoint, from the reverse index
+// maintained by the controller in the endpoint status.
+func GetAppliedPolicies(namespace, endpoint string) ([]securityv1alpha1.NamespacedName, error) {
+	ep, err := policyconn.SecurityV1alpha1().Endpoints(namespace).Get(context.Background(), endpoint, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ep.Status.AppliedPolicies, nil
+}
+
+// GetSecurityPolicies return the policies in the namespace, of all namespaces if the namespace is empty.
+func GetSecurityPolicies(namespace string) ([]securityv1alpha1.SecurityPolicy, error) {
+	policyList, err := policyconn.SecurityV1alpha1().SecurityPolicies(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return policyList.Items, nil
+}
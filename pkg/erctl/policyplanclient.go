@@ -0,0 +1,40 @@
+package erctl
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+var policyplanconn clientset.Interface
+
+func ConnectPolicyPlan() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	policyplanconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+func GetPolicyPlan(name string) (*securityv1alpha1.PolicyPlan, error) {
+	return policyplanconn.SecurityV1alpha1().PolicyPlans().Get(context.Background(), name, metav1.GetOptions{})
+}
+
+func GetPolicyPlans() ([]securityv1alpha1.PolicyPlan, error) {
+	planList, err := policyplanconn.SecurityV1alpha1().PolicyPlans().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return planList.Items, nil
+}
+
+func ApprovePolicyPlan(name string) (*securityv1alpha1.PolicyPlan, error) {
+	patch := []byte(`{"spec":{"approved":true}}`)
+	return policyplanconn.SecurityV1alpha1().PolicyPlans().Patch(context.Background(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
@@ -0,0 +1,65 @@
+package erctl
+
+import (
+	"context"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+const quarantineEndpointPrefix = "endpoint-"
+
+var quarantineconn clientset.Interface
+
+func ConnectQuarantine() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	quarantineconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+// NewEndpointQuarantine return a quarantine of the endpoint, allow traffics from or to exceptCIDRs only.
+func NewEndpointQuarantine(namespace, endpoint string, ttl time.Duration, exceptCIDRs []string) *securityv1alpha1.Quarantine {
+	quarantine := &securityv1alpha1.Quarantine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      quarantineEndpointPrefix + endpoint,
+		},
+		Spec: securityv1alpha1.QuarantineSpec{
+			AppliedTo: []securityv1alpha1.ApplyToPeer{{Endpoint: &endpoint}},
+		},
+	}
+	for _, cidr := range exceptCIDRs {
+		quarantine.Spec.Exceptions = append(quarantine.Spec.Exceptions, securityv1alpha1.SecurityPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+		})
+	}
+	if ttl > 0 {
+		quarantine.Spec.TTL = &metav1.Duration{Duration: ttl}
+	}
+	return quarantine
+}
+
+func QuarantineEndpoint(namespace, endpoint string, ttl time.Duration, exceptCIDRs []string) (*securityv1alpha1.Quarantine, error) {
+	quarantine := NewEndpointQuarantine(namespace, endpoint, ttl, exceptCIDRs)
+	return quarantineconn.SecurityV1alpha1().Quarantines(namespace).Create(context.Background(), quarantine, metav1.CreateOptions{})
+}
+
+func ReleaseEndpoint(namespace, endpoint string) error {
+	return quarantineconn.SecurityV1alpha1().Quarantines(namespace).Delete(context.Background(), quarantineEndpointPrefix+endpoint, metav1.DeleteOptions{})
+}
+
+func GetQuarantines(namespace string) ([]securityv1alpha1.Quarantine, error) {
+	quarantineList, err := quarantineconn.SecurityV1alpha1().Quarantines(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return quarantineList.Items, nil
+}
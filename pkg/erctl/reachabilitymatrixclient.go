@@ -0,0 +1,34 @@
+package erctl
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+var reachabilityconn clientset.Interface
+
+func ConnectReachabilityMatrix() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	reachabilityconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+func GetReachabilityMatrix(name string) (*securityv1alpha1.ReachabilityMatrix, error) {
+	return reachabilityconn.SecurityV1alpha1().ReachabilityMatrices().Get(context.Background(), name, metav1.GetOptions{})
+}
+
+func GetReachabilityMatrices() ([]securityv1alpha1.ReachabilityMatrix, error) {
+	matrixList, err := reachabilityconn.SecurityV1alpha1().ReachabilityMatrices().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return matrixList.Items, nil
+}
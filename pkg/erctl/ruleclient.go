@@ -100,12 +100,18 @@ type tuple struct {
 	srcIP, dstIP, status string
 	srcPort, dstPort     uint32
 	protocol             uint32
+	// redirectTo is the actual destination of redirected flow, dstIP and
+	// dstPort keep the original destination.
+	redirectTo string
 }
 
 func (t tuple) MarshalJSON() ([]byte, error) {
-	s := fmt.Sprintf(`"SrcIP:%s, DstIP:%s, SrcPort:%d, DstPort:%d, Protocol:%d, status:%s"`,
+	s := fmt.Sprintf(`SrcIP:%s, DstIP:%s, SrcPort:%d, DstPort:%d, Protocol:%d, status:%s`,
 		t.srcIP, t.dstIP, t.srcPort, t.dstPort, t.protocol, t.status)
-	return []byte(s), nil
+	if t.redirectTo != "" {
+		s += fmt.Sprintf(", RedirectTo:%s", t.redirectTo)
+	}
+	return []byte(`"` + s + `"`), nil
 }
 
 func parseFlows(flows []conntrack.Flow) {
@@ -121,6 +127,9 @@ func parseFlows(flows []conntrack.Flow) {
 			protocol: uint32(flow.TupleOrig.Proto.Protocol),
 			status:   flow.Status.String(),
 		}
+		if flow.Status.DstNAT() {
+			origTuple.redirectTo = fmt.Sprintf("%s:%d", flow.TupleReply.IP.SourceAddress, flow.TupleReply.Proto.SourcePort)
+		}
 		u1, u2, u3 := utils.CtLabelDecode(flow.Labels)
 		addCnt(origTuple, u1, u2, u3)
 	}
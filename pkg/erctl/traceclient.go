@@ -0,0 +1,283 @@
+package erctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+const (
+	TraceAllowed = "allowed"
+	TraceDenied  = "denied"
+)
+
+// TraceRequest describes the packet to trace, the source and the destination are the endpoints
+// in format of namespace/name or the ip addresses.
+type TraceRequest struct {
+	Src      string
+	Dst      string
+	Protocol string
+	SrcPort  uint16
+	DstPort  uint16
+	// Bridge and InPort the packet comes from, resolved from the source endpoint if not set.
+	Bridge string
+	InPort string
+}
+
+// TracePolicyRule is a policy rule the traced packet matched.
+type TracePolicyRule struct {
+	Bridge string `json:"bridge"`
+	Table  uint8  `json:"table"`
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	// Policies are the policies the rule generated from, in format of namespace/name/type
+	Policies []string `json:"policies,omitempty"`
+}
+
+// TraceVerdict is the result of the packet traced in the datapath.
+type TraceVerdict struct {
+	Verdict string `json:"verdict"`
+	// DecidedBy is the last policy rule the packet matched, empty if matched no policy rule
+	DecidedBy *TracePolicyRule    `json:"decidedBy,omitempty"`
+	Rules     []TracePolicyRule   `json:"rules,omitempty"`
+	Trace     *appctl.TraceResult `json:"trace"`
+}
+
+// traceEndpoint is the address of the endpoint and the ovs interface it attached to.
+type traceEndpoint struct {
+	ip, mac       string
+	bridge, iface string
+}
+
+// Trace the packet in the datapath by ofproto/trace, and find out the policy rules it matched by
+// the cookies of the flows.
+func Trace(req TraceRequest) (*TraceVerdict, error) {
+	src, err := resolveTraceEndpoint(req.Src)
+	if err != nil {
+		return nil, fmt.Errorf("resolve source %s: %s", req.Src, err)
+	}
+	dst, err := resolveTraceEndpoint(req.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("resolve destination %s: %s", req.Dst, err)
+	}
+	if req.Bridge == "" {
+		req.Bridge = src.bridge
+	}
+	if req.InPort == "" {
+		req.InPort = src.iface
+	}
+	if req.Bridge == "" || req.InPort == "" {
+		return nil, fmt.Errorf("bridge and in port must be specified when the source is not a local endpoint")
+	}
+
+	flow, err := buildTraceFlow(req, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	result, err := appctl.Trace(req.Bridge, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ConnectClient(); err != nil {
+		return nil, err
+	}
+	return traceVerdict(result, getRuleEntriesByFlow)
+}
+
+func getRuleEntriesByFlow(flowIDs []uint64) ([]*v1alpha1.RuleEntry, error) {
+	ruleEntries, err := ruleconn.GetRulesByFlow(context.Background(), &v1alpha1.FlowIDs{FlowIDs: flowIDs})
+	if err != nil {
+		return nil, err
+	}
+	return ruleEntries.RuleEntries, nil
+}
+
+// traceVerdict finds the policy rules of the flows the packet matched, the rules are looked up by
+// the cookies of the flows, which are the flow ids.
+func traceVerdict(result *appctl.TraceResult, getRules func([]uint64) ([]*v1alpha1.RuleEntry, error)) (*TraceVerdict, error) {
+	verdict := &TraceVerdict{Verdict: TraceAllowed, Trace: result}
+	if result.Dropped() {
+		verdict.Verdict = TraceDenied
+	}
+
+	for _, step := range result.Steps {
+		if step.Cookie == 0 {
+			continue
+		}
+		entries, err := getRules([]uint64{step.Cookie})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			rule := TracePolicyRule{Bridge: step.Bridge, Table: step.Table}
+			if entry.EveroutePolicyRule != nil {
+				rule.Rule = entry.EveroutePolicyRule.RuleID
+				rule.Action = entry.EveroutePolicyRule.Action
+			}
+			for _, ref := range entry.PolicyRuleReference {
+				rule.Policies = append(rule.Policies, strings.TrimPrefix(ref.NameSpace+"/"+ref.Name+"/"+ref.Type, "/"))
+			}
+			verdict.Rules = append(verdict.Rules, rule)
+		}
+	}
+	if len(verdict.Rules) != 0 {
+		verdict.DecidedBy = &verdict.Rules[len(verdict.Rules)-1]
+	}
+
+	return verdict, nil
+}
+
+// buildTraceFlow returns the flow of ofproto/trace describes the packet.
+func buildTraceFlow(req TraceRequest, src, dst *traceEndpoint) (string, error) {
+	protocol := strings.ToLower(req.Protocol)
+	switch protocol {
+	case "":
+		protocol = "ip"
+	case "ip", "icmp", "tcp", "udp", "sctp":
+	default:
+		return "", fmt.Errorf("unsupported protocol %s", req.Protocol)
+	}
+	if (req.SrcPort != 0 || req.DstPort != 0) && (protocol == "ip" || protocol == "icmp") {
+		return "", fmt.Errorf("ports are only supported with tcp, udp and sctp")
+	}
+
+	fields := []string{protocol, "in_port=" + req.InPort}
+	if src.mac != "" {
+		fields = append(fields, "dl_src="+src.mac)
+	}
+	if dst.mac != "" {
+		fields = append(fields, "dl_dst="+dst.mac)
+	}
+	fields = append(fields, "nw_src="+src.ip, "nw_dst="+dst.ip)
+	if req.SrcPort != 0 {
+		fields = append(fields, fmt.Sprintf("tp_src=%d", req.SrcPort))
+	}
+	if req.DstPort != 0 {
+		fields = append(fields, fmt.Sprintf("tp_dst=%d", req.DstPort))
+	}
+	return strings.Join(fields, ","), nil
+}
+
+// resolveTraceEndpoint resolves the ip address, or the endpoint in format of namespace/name.
+func resolveTraceEndpoint(addr string) (*traceEndpoint, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("only ipv4 address supported")
+		}
+		return &traceEndpoint{ip: ip.String()}, nil
+	}
+
+	keys := strings.Split(addr, "/")
+	if len(keys) != 2 {
+		return nil, fmt.Errorf("should be an ipv4 address or an endpoint in format of namespace/name")
+	}
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := client.SecurityV1alpha1().Endpoints(keys[0]).Get(context.Background(), keys[1], metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	traced := &traceEndpoint{mac: endpoint.Status.MacAddress}
+	for _, ip := range endpoint.Status.IPs {
+		if parsed := net.ParseIP(string(ip)); parsed != nil && parsed.To4() != nil {
+			traced.ip = parsed.String()
+			break
+		}
+	}
+	if traced.ip == "" {
+		return nil, fmt.Errorf("endpoint has no ipv4 address")
+	}
+
+	// the endpoint may be on the other agents, only the local interface could be the in port
+	ref := endpoint.Spec.Reference
+	out, err := exec.Command("ovs-vsctl", "--bare", "--columns=name", "find", "Interface",
+		fmt.Sprintf("external_ids:%s=%s", ref.ExternalIDName, ref.ExternalIDValue)).CombinedOutput()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return traced, nil
+	}
+	traced.iface = strings.Fields(string(out))[0]
+	out, err = exec.Command("ovs-vsctl", "iface-to-br", traced.iface).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("find bridge of interface %s: %s, %s", traced.iface, err, strings.TrimSpace(string(out)))
+	}
+	traced.bridge = strings.TrimSpace(string(out))
+	return traced, nil
+}
+
+// WriteTraceVerdict writes the verdict and the tables the packet went through in human-readable text.
+func WriteTraceVerdict(w io.Writer, verdict *TraceVerdict) error {
+	lines := []string{fmt.Sprintf("Flow: %s", verdict.Trace.Flow)}
+	switch {
+	case verdict.DecidedBy != nil:
+		lines = append(lines, fmt.Sprintf("Verdict: %s by rule %s (%s) of %s",
+			verdict.Verdict, verdict.DecidedBy.Rule, verdict.DecidedBy.Action, strings.Join(verdict.DecidedBy.Policies, ", ")))
+	case verdict.Verdict == TraceDenied:
+		lines = append(lines, fmt.Sprintf("Verdict: %s, matched no policy rule", verdict.Verdict))
+	default:
+		lines = append(lines, fmt.Sprintf("Verdict: %s, matched no policy rule, allowed by default", verdict.Verdict))
+	}
+	lines = append(lines, fmt.Sprintf("Datapath actions: %s", verdict.Trace.DatapathActions), "", "Pipeline:")
+
+	names := make(map[string]map[uint8]string)
+	for _, step := range verdict.Trace.Steps {
+		if names[step.Bridge] == nil {
+			names[step.Bridge] = traceTableNames(step.Bridge, verdict.Trace.Steps)
+		}
+		table := fmt.Sprintf("%s table %d", step.Bridge, step.Table)
+		if name := names[step.Bridge][step.Table]; name != "" {
+			table = fmt.Sprintf("%s (%s)", table, name)
+		}
+		switch {
+		case step.NoMatch:
+			lines = append(lines, fmt.Sprintf("  %s: no match", table))
+		case step.Match == "":
+			lines = append(lines, fmt.Sprintf("  %s: priority %d", table, step.Priority))
+		default:
+			lines = append(lines, fmt.Sprintf("  %s: %s, priority %d", table, step.Match, step.Priority))
+		}
+		for _, rule := range verdict.Rules {
+			if rule.Bridge == step.Bridge && rule.Table == step.Table && rule.Rule != "" {
+				lines = append(lines, fmt.Sprintf("    rule %s (%s) of %s", rule.Rule, rule.Action, strings.Join(rule.Policies, ", ")))
+			}
+		}
+		for _, action := range step.Actions {
+			lines = append(lines, "    "+action)
+		}
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// traceTableNames returns the names of the tables of the bridge in the pipeline layout.
+func traceTableNames(bridge string, steps []appctl.TraceStep) map[uint8]string {
+	installed := make(map[uint8]int)
+	for _, step := range steps {
+		if step.Bridge == bridge {
+			installed[step.Table]++
+		}
+	}
+	names := make(map[uint8]string)
+	for id, table := range pipelineLayoutOf(bridge, installed) {
+		names[id] = table.Name
+	}
+	return names
+}
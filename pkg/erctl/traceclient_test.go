@@ -0,0 +1,73 @@
+package erctl
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+)
+
+func TestBuildTraceFlow(t *testing.T) {
+	RegisterTestingT(t)
+
+	src := &traceEndpoint{ip: "10.0.0.1", mac: "52:54:00:5e:2a:0c", bridge: "ovsbr0", iface: "vnet0"}
+	dst := &traceEndpoint{ip: "10.0.0.2"}
+	req := TraceRequest{Protocol: "TCP", SrcPort: 1234, DstPort: 80, InPort: "vnet0"}
+	flow, err := buildTraceFlow(req, src, dst)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(flow).Should(Equal("tcp,in_port=vnet0,dl_src=52:54:00:5e:2a:0c,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_src=1234,tp_dst=80"))
+
+	flow, err = buildTraceFlow(TraceRequest{InPort: "vnet0"}, src, dst)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(flow).Should(Equal("ip,in_port=vnet0,dl_src=52:54:00:5e:2a:0c,nw_src=10.0.0.1,nw_dst=10.0.0.2"))
+
+	_, err = buildTraceFlow(TraceRequest{Protocol: "ICMP", DstPort: 80, InPort: "vnet0"}, src, dst)
+	Expect(err).Should(HaveOccurred())
+	_, err = buildTraceFlow(TraceRequest{Protocol: "GRE", InPort: "vnet0"}, src, dst)
+	Expect(err).Should(HaveOccurred())
+}
+
+func TestTraceVerdict(t *testing.T) {
+	RegisterTestingT(t)
+
+	result := &appctl.TraceResult{
+		Flow: "tcp,in_port=vnet0,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_dst=80",
+		Steps: []appctl.TraceStep{
+			{Bridge: "ovsbr0-policy", Table: 0, Priority: 0, Actions: []string{"goto_table:10"}},
+			{Bridge: "ovsbr0-policy", Table: 10, Match: "tcp,nw_dst=10.0.0.2", Priority: 300, Cookie: 0x3, Actions: []string{"drop"}},
+		},
+		DatapathActions: "drop",
+	}
+	entries := map[uint64][]*v1alpha1.RuleEntry{
+		0x3: {{
+			EveroutePolicyRule:  &v1alpha1.PolicyRule{RuleID: "default/deny-web/normal/ingress.web-abcd", Action: "deny"},
+			PolicyRuleReference: []*v1alpha1.PolicyRuleReference{{NameSpace: "default", Name: "deny-web", Type: "normal"}},
+		}},
+	}
+	verdict, err := traceVerdict(result, func(flowIDs []uint64) ([]*v1alpha1.RuleEntry, error) {
+		return entries[flowIDs[0]], nil
+	})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(verdict.Verdict).Should(Equal(TraceDenied))
+	Expect(verdict.DecidedBy).Should(Equal(&TracePolicyRule{
+		Bridge:   "ovsbr0-policy",
+		Table:    10,
+		Rule:     "default/deny-web/normal/ingress.web-abcd",
+		Action:   "deny",
+		Policies: []string{"default/deny-web/normal"},
+	}))
+
+	var out bytes.Buffer
+	Expect(WriteTraceVerdict(&out, verdict)).Should(Succeed())
+	Expect(out.String()).Should(ContainSubstring("Verdict: denied by rule default/deny-web/normal/ingress.web-abcd (deny) of default/deny-web/normal"))
+	Expect(out.String()).Should(ContainSubstring("ovsbr0-policy table 10"))
+
+	result.DatapathActions = "2"
+	verdict, err = traceVerdict(result, func([]uint64) ([]*v1alpha1.RuleEntry, error) { return nil, nil })
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(verdict.Verdict).Should(Equal(TraceAllowed))
+	Expect(verdict.DecidedBy).Should(BeNil())
+}
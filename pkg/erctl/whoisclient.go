@@ -0,0 +1,52 @@
+package erctl
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	viewsv1alpha1 "github.com/everoute/everoute/pkg/apis/views/v1alpha1"
+	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
+)
+
+var whoisconn clientset.Interface
+
+func ConnectWhois() error {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	whoisconn, err = clientset.NewForConfig(config)
+	return err
+}
+
+// GetIPOwner returns the owners of the ip from the views aggregated api of the controller,
+// the policy views must be enabled on the controller.
+func GetIPOwner(ip string) (*viewsv1alpha1.IPOwner, error) {
+	raw, err := whoisconn.Discovery().RESTClient().Get().
+		AbsPath("/apis", viewsv1alpha1.SchemeGroupVersion.Group, viewsv1alpha1.SchemeGroupVersion.Version, "ipowners", ip).
+		DoRaw(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	ipOwner := &viewsv1alpha1.IPOwner{}
+	return ipOwner, json.Unmarshal(raw, ipOwner)
+}
+
+// GetIPHistory returns the history of the ip in the last duration, or all the history kept if
+// the duration is zero, the ip history must be enabled on the controller.
+func GetIPHistory(ip string, since time.Duration) (*viewsv1alpha1.IPHistory, error) {
+	req := whoisconn.Discovery().RESTClient().Get().
+		AbsPath("/apis", viewsv1alpha1.SchemeGroupVersion.Group, viewsv1alpha1.SchemeGroupVersion.Version, "iphistories", ip)
+	if since > 0 {
+		req = req.Param("since", time.Now().Add(-since).UTC().Format(time.RFC3339))
+	}
+	raw, err := req.DoRaw(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	ipHistory := &viewsv1alpha1.IPHistory{}
+	return ipHistory, json.Unmarshal(raw, ipHistory)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errors provides the error categories shared by the monitor, datapath
+// and controllers. The caller decides whether to retry by the category of the
+// error, instead of matching the error message.
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reason is the category of an error.
+type Reason string
+
+const (
+	// ReasonUnknown means the error has no category.
+	ReasonUnknown Reason = ""
+	// ReasonNotFound means the required object not exists (yet).
+	ReasonNotFound Reason = "NotFound"
+	// ReasonTransient means the error may disappear by retry, e.g. ovsdb not ready.
+	ReasonTransient Reason = "Transient"
+	// ReasonConflict means the object already exists or conflicts with others.
+	ReasonConflict Reason = "Conflict"
+	// ReasonFatal means the error could not recover by retry, e.g. resources exhausted.
+	ReasonFatal Reason = "Fatal"
+)
+
+var (
+	// The sentinel errors of each category, use them with errors.Is, e.g.
+	// errors.Is(err, ErrNotFound) returns true for any error wraps a NotFound error.
+	ErrNotFound  = &Error{Reason: ReasonNotFound}
+	ErrTransient = &Error{Reason: ReasonTransient}
+	ErrConflict  = &Error{Reason: ReasonConflict}
+	ErrFatal     = &Error{Reason: ReasonFatal}
+)
+
+// Error is an error with category.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return string(e.Reason)
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an Error with the same reason.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
+}
+
+// New returns an Error of the reason with formatted message, the format
+// supports %w like fmt.Errorf.
+func New(reason Reason, format string, args ...interface{}) error {
+	return &Error{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap returns an Error of the reason wraps the err, returns nil if err is nil.
+func Wrap(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+func NewNotFound(format string, args ...interface{}) error {
+	return New(ReasonNotFound, format, args...)
+}
+
+func NewTransient(format string, args ...interface{}) error {
+	return New(ReasonTransient, format, args...)
+}
+
+func NewConflict(format string, args ...interface{}) error {
+	return New(ReasonConflict, format, args...)
+}
+
+func NewFatal(format string, args ...interface{}) error {
+	return New(ReasonFatal, format, args...)
+}
+
+// ReasonForError returns the reason of the first Error in the err chain.
+func ReasonForError(err error) Reason {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ReasonUnknown
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+func IsFatal(err error) bool {
+	return errors.Is(err, ErrFatal)
+}
+
+// IsRetriable returns true if the err may disappear by retry.
+func IsRetriable(err error) bool {
+	return IsNotFound(err) || IsTransient(err)
+}
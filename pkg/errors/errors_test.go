@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestErrorCategory(t *testing.T) {
+	RegisterTestingT(t)
+
+	err := NewNotFound("group %s members not found", "group01")
+	Expect(err.Error()).Should(Equal("group group01 members not found"))
+	Expect(IsNotFound(err)).Should(BeTrue())
+	Expect(IsTransient(err)).Should(BeFalse())
+	Expect(IsRetriable(err)).Should(BeTrue())
+
+	wrapped := fmt.Errorf("calculate policy rules: %w", err)
+	Expect(IsNotFound(wrapped)).Should(BeTrue())
+	Expect(errors.Is(wrapped, ErrNotFound)).Should(BeTrue())
+	Expect(ReasonForError(wrapped)).Should(Equal(ReasonNotFound))
+
+	var e *Error
+	Expect(errors.As(wrapped, &e)).Should(BeTrue())
+	Expect(e.Reason).Should(Equal(ReasonNotFound))
+
+	plain := fmt.Errorf("plain error")
+	Expect(IsNotFound(plain)).Should(BeFalse())
+	Expect(ReasonForError(plain)).Should(Equal(ReasonUnknown))
+	Expect(IsFatal(Wrap(ReasonFatal, plain))).Should(BeTrue())
+	Expect(errors.Is(Wrap(ReasonFatal, plain), plain)).Should(BeTrue())
+	Expect(Wrap(ReasonFatal, nil)).Should(BeNil())
+	Expect(IsConflict(NewConflict("rule existed"))).Should(BeTrue())
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+// exportKind is a kind of the objects could be exported.
+type exportKind struct {
+	// resource is the resource the requesters are authorized for
+	resource   schema.GroupResource
+	namespaced bool
+	newObject  func() runtime.Object
+	newList    func() runtime.Object
+}
+
+var exportKinds = map[string]exportKind{
+	"SecurityPolicy": {
+		resource:   securityv1alpha1.Resource("securitypolicies"),
+		namespaced: true,
+		newObject:  func() runtime.Object { return &securityv1alpha1.SecurityPolicy{} },
+		newList:    func() runtime.Object { return &securityv1alpha1.SecurityPolicyList{} },
+	},
+	"GlobalPolicy": {
+		resource:  securityv1alpha1.Resource("globalpolicies"),
+		newObject: func() runtime.Object { return &securityv1alpha1.GlobalPolicy{} },
+		newList:   func() runtime.Object { return &securityv1alpha1.GlobalPolicyList{} },
+	},
+	"Tier": {
+		resource:  securityv1alpha1.Resource("tiers"),
+		newObject: func() runtime.Object { return &securityv1alpha1.Tier{} },
+		newList:   func() runtime.Object { return &securityv1alpha1.TierList{} },
+	},
+	"Endpoint": {
+		resource:   securityv1alpha1.Resource("endpoints"),
+		namespaced: true,
+		newObject:  func() runtime.Object { return &securityv1alpha1.Endpoint{} },
+		newList:    func() runtime.Object { return &securityv1alpha1.EndpointList{} },
+	},
+	"EndpointGroup": {
+		resource:  groupv1alpha1.Resource("endpointgroups"),
+		newObject: func() runtime.Object { return &groupv1alpha1.EndpointGroup{} },
+		newList:   func() runtime.Object { return &groupv1alpha1.EndpointGroupList{} },
+	},
+	// GroupMembers lag behind the patches not compacted, the consumers apply the patches of
+	// the revisions after the GroupMembers, as the agents do.
+	"GroupMembers": {
+		resource:  groupv1alpha1.Resource("groupmembers"),
+		newObject: func() runtime.Object { return &groupv1alpha1.GroupMembers{} },
+		newList:   func() runtime.Object { return &groupv1alpha1.GroupMembersList{} },
+	},
+	"GroupMembersPatch": {
+		resource:  groupv1alpha1.Resource("groupmemberspatches"),
+		newObject: func() runtime.Object { return &groupv1alpha1.GroupMembersPatch{} },
+		newList:   func() runtime.Object { return &groupv1alpha1.GroupMembersPatchList{} },
+	},
+	"AgentInfo": {
+		resource:  agentv1alpha1.Resource("agentinfos"),
+		newObject: func() runtime.Object { return &agentv1alpha1.AgentInfo{} },
+		newList:   func() runtime.Object { return &agentv1alpha1.AgentInfoList{} },
+	},
+}
+
+func supportedKinds() []string {
+	kinds := make([]string, 0, len(exportKinds))
+	for kind := range exportKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// metaObject is the metadata the objects filtered by.
+type metaObject interface {
+	GetNamespace() string
+	GetName() string
+	GetLabels() map[string]string
+}
+
+// validatePaths checks the paths of the field mask are the json field names joined by dots.
+func validatePaths(paths []string) error {
+	for _, path := range paths {
+		for _, field := range strings.Split(path, ".") {
+			if field == "" {
+				return fmt.Errorf("path %q with empty field", path)
+			}
+		}
+	}
+	return nil
+}
+
+// maskObject returns the json of the object with only the fields in the paths, the whole
+// object if the paths are empty. The paths not found in the object are ignored.
+func maskObject(obj runtime.Object, paths []string) ([]byte, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil || len(paths) == 0 {
+		return raw, err
+	}
+
+	var content map[string]interface{}
+	if err = json.Unmarshal(raw, &content); err != nil {
+		return nil, err
+	}
+	masked := make(map[string]interface{})
+	for _, path := range paths {
+		copyPath(content, masked, strings.Split(path, "."))
+	}
+	return json.Marshal(masked)
+}
+
+// copyPath copies the field in the path from src to dst with its parent fields, returns
+// false if the field not found.
+func copyPath(src, dst map[string]interface{}, fields []string) bool {
+	value, ok := src[fields[0]]
+	if !ok {
+		return false
+	}
+	if len(fields) == 1 {
+		dst[fields[0]] = value
+		return true
+	}
+
+	srcChild, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	dstChild, ok := dst[fields[0]].(map[string]interface{})
+	if ok {
+		return copyPath(srcChild, dstChild, fields[1:])
+	}
+	dstChild = make(map[string]interface{})
+	if !copyPath(srcChild, dstChild, fields[1:]) {
+		return false
+	}
+	dst[fields[0]] = dstChild
+	return true
+}
@@ -0,0 +1,414 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exporter serves the policies, groups, endpoints and agents as a read only grpc
+// stream from the cache of the controller, the heavy consumers list and watch them without
+// loading the kube-apiserver. The requesters are authenticated by their bearer tokens and
+// authorized with their RBAC by the kube-apiserver.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+)
+
+const (
+	DefaultPort = 9446
+
+	// watchBufferSize is the events buffered for a watching stream, the stream is closed
+	// if the consumer falls behind, it should list and watch again.
+	watchBufferSize = 1024
+)
+
+// The types of the events, the same as the kubernetes watch events, except SYNCED.
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+	EventSynced   = "SYNCED"
+)
+
+// Server is the grpc server of the Exporter service.
+type Server struct {
+	// Cache lists and watches the objects, usually the cache of the manager.
+	Cache cache.Cache
+	// Client reviews the tokens and the access of the requesters.
+	Client client.Client
+	// CertDir contains the tls.crt and tls.key the server serves with.
+	CertDir string
+	Port    int
+
+	lock sync.RWMutex
+	// watchers are the watching streams by the kind
+	watchers map[string]map[*watcher]struct{}
+}
+
+type event struct {
+	typ string
+	obj runtime.Object
+}
+
+type watcher struct {
+	events chan event
+	// overflow is closed when the events buffer full
+	overflow chan struct{}
+}
+
+// SetupWithManager adds the server to the manager.
+func (s *Server) SetupWithManager(mgr ctrl.Manager) error {
+	if mgr == nil {
+		return fmt.Errorf("can't setup with nil manager")
+	}
+	return mgr.Add(s)
+}
+
+// Start implements manager.Runnable.
+func (s *Server) Start(stopChan <-chan struct{}) error {
+	if s.Port == 0 {
+		s.Port = DefaultPort
+	}
+
+	// the handlers are added before any stream watching, the existing objects replayed
+	// to the new handlers are not sent as the changes
+	if err := s.addEventHandlers(); err != nil {
+		return err
+	}
+
+	creds, err := credentials.NewServerTLSFromFile(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	if err != nil {
+		return fmt.Errorf("load server cert: %s", err)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	v1alpha1.RegisterExporterServer(server, s)
+	reflection.Register(server)
+	go func() {
+		<-stopChan
+		server.Stop()
+	}()
+
+	klog.Infof("start exporter rpc server on %s", listener.Addr())
+	defer klog.Infof("shutting down exporter rpc server")
+	return server.Serve(listener)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the streams are served
+// by any replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Export lists the objects selected, and streams their changes if watch.
+func (s *Server) Export(req *v1alpha1.ExportRequest, stream v1alpha1.Exporter_ExportServer) error {
+	ctx := stream.Context()
+
+	kind, ok := exportKinds[req.GetKind()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "unsupported kind %q, supported kinds: %s", req.GetKind(), strings.Join(supportedKinds(), ", "))
+	}
+	if !kind.namespaced && req.GetNamespace() != "" {
+		return status.Errorf(codes.InvalidArgument, "kind %s is not namespaced", req.GetKind())
+	}
+	selector, err := labels.Parse(req.GetLabelSelector())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid labelSelector: %s", err)
+	}
+	paths := req.GetFieldMask().GetPaths()
+	if err = validatePaths(paths); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid fieldMask: %s", err)
+	}
+	if err = s.authorize(ctx, kind, req); err != nil {
+		return err
+	}
+
+	filter := newFilter(req.GetNamespace(), req.GetNames(), selector)
+	send := func(typ string, obj runtime.Object) error {
+		accessor, err := meta.Accessor(obj)
+		if err != nil || !filter(accessor) {
+			return nil
+		}
+		raw, err := maskObject(obj, paths)
+		if err != nil {
+			return status.Errorf(codes.Internal, "encode %s %s: %s", req.GetKind(), accessor.GetName(), err)
+		}
+		return stream.Send(&v1alpha1.ExportEvent{
+			Type:      typ,
+			Kind:      req.GetKind(),
+			Namespace: accessor.GetNamespace(),
+			Name:      accessor.GetName(),
+			Object:    raw,
+		})
+	}
+
+	// watch before list, the events buffered during the list older than the objects listed
+	// are dropped, so the consumers never go backwards
+	var w *watcher
+	if req.GetWatch() {
+		w = s.watch(req.GetKind())
+		defer s.unwatch(req.GetKind(), w)
+	}
+
+	list := kind.newList()
+	if err = s.Cache.List(ctx, list, client.InNamespace(req.GetNamespace()), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return status.Errorf(codes.Unavailable, "list %s: %s", req.GetKind(), err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return status.Errorf(codes.Internal, "list %s: %s", req.GetKind(), err)
+	}
+	listed := make(map[string]uint64, len(items))
+	for _, item := range items {
+		if key, version, ok := objectVersion(item); ok {
+			listed[key] = version
+		}
+		if err = send(EventAdded, item); err != nil {
+			return err
+		}
+	}
+
+	if w == nil {
+		return nil
+	}
+	if err = stream.Send(&v1alpha1.ExportEvent{Type: EventSynced, Kind: req.GetKind()}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.overflow:
+			return status.Errorf(codes.ResourceExhausted, "the events of %s fall behind, list and watch again", req.GetKind())
+		case e := <-w.events:
+			if len(listed) != 0 && isListed(listed, e) {
+				continue
+			}
+			if err = send(e.typ, e.obj); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isListed returns true if the event is not newer than the object listed, the event has been
+// buffered during the list. The objects listed are forgotten after their first newer event.
+func isListed(listed map[string]uint64, e event) bool {
+	key, version, ok := objectVersion(e.obj)
+	if !ok {
+		return false
+	}
+	listedVersion, ok := listed[key]
+	if !ok {
+		return false
+	}
+
+	// the object deleted carries its last version, which might be the version listed
+	if version < listedVersion || (version == listedVersion && e.typ != EventDeleted) {
+		return true
+	}
+	delete(listed, key)
+	return false
+}
+
+// objectVersion returns the namespaced name and the resource version of the object.
+func objectVersion(obj runtime.Object) (string, uint64, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", 0, false
+	}
+	version, err := strconv.ParseUint(accessor.GetResourceVersion(), 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName(), version, true
+}
+
+// authorize reviews the bearer token of the request, and allows the requester could list,
+// and watch if watching, the objects of the kind in the namespace.
+func (s *Server) authorize(ctx context.Context, kind exportKind, req *v1alpha1.ExportRequest) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if strings.HasPrefix(value, "Bearer ") {
+				token = strings.TrimPrefix(value, "Bearer ")
+			}
+		}
+	}
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "bearer token required in the authorization metadata")
+	}
+
+	tokenReview := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Client.Create(ctx, tokenReview); err != nil {
+		return status.Errorf(codes.Unavailable, "review token: %s", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return status.Errorf(codes.Unauthenticated, "invalid bearer token: %s", tokenReview.Status.Error)
+	}
+	user := tokenReview.Status.User
+
+	verbs := []string{"list"}
+	if req.GetWatch() {
+		verbs = append(verbs, "watch")
+	}
+	for _, verb := range verbs {
+		accessReview := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user.Username,
+				Groups: user.Groups,
+				UID:    user.UID,
+				Extra:  make(map[string]authorizationv1.ExtraValue, len(user.Extra)),
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: req.GetNamespace(),
+					Verb:      verb,
+					Group:     kind.resource.Group,
+					Resource:  kind.resource.Resource,
+				},
+			},
+		}
+		for key, value := range user.Extra {
+			accessReview.Spec.Extra[key] = authorizationv1.ExtraValue(value)
+		}
+		if err := s.Client.Create(ctx, accessReview); err != nil {
+			return status.Errorf(codes.Unavailable, "review access: %s", err)
+		}
+		if !accessReview.Status.Allowed {
+			return status.Errorf(codes.PermissionDenied, "user %s cannot %s %s", user.Username, verb, kind.resource)
+		}
+	}
+	return nil
+}
+
+// addEventHandlers broadcasts the changes of the objects of all the kinds to the watchers.
+func (s *Server) addEventHandlers() error {
+	for name, kind := range exportKinds {
+		informer, err := s.Cache.GetInformer(context.Background(), kind.newObject())
+		if err != nil {
+			return fmt.Errorf("get informer of %s: %s", name, err)
+		}
+		informer.AddEventHandler(s.eventHandler(name))
+	}
+	return nil
+}
+
+func (s *Server) eventHandler(kind string) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.broadcast(kind, EventAdded, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldAccessor, oldErr := meta.Accessor(oldObj)
+			newAccessor, newErr := meta.Accessor(newObj)
+			if oldErr == nil && newErr == nil && oldAccessor.GetResourceVersion() == newAccessor.GetResourceVersion() {
+				// resync of the informer, not changed
+				return
+			}
+			s.broadcast(kind, EventModified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			s.broadcast(kind, EventDeleted, obj)
+		},
+	}
+}
+
+// broadcast sends the event to the watchers of the kind without blocking, the watchers with
+// the buffer full are closed and removed.
+func (s *Server) broadcast(kind string, typ string, obj interface{}) {
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for w := range s.watchers[kind] {
+		select {
+		case w.events <- event{typ: typ, obj: runtimeObj}:
+		default:
+			close(w.overflow)
+			delete(s.watchers[kind], w)
+		}
+	}
+}
+
+func (s *Server) watch(kind string) *watcher {
+	w := &watcher{
+		events:   make(chan event, watchBufferSize),
+		overflow: make(chan struct{}),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.watchers == nil {
+		s.watchers = make(map[string]map[*watcher]struct{})
+	}
+	if s.watchers[kind] == nil {
+		s.watchers[kind] = make(map[*watcher]struct{})
+	}
+	s.watchers[kind][w] = struct{}{}
+	return w
+}
+
+func (s *Server) unwatch(kind string, w *watcher) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.watchers[kind], w)
+}
+
+// newFilter returns whether the object selected by the namespace, the names and the labels.
+func newFilter(namespace string, names []string, selector labels.Selector) func(obj metaObject) bool {
+	nameSet := sets.NewString(names...)
+	return func(obj metaObject) bool {
+		if namespace != "" && obj.GetNamespace() != namespace {
+			return false
+		}
+		if nameSet.Len() != 0 && !nameSet.Has(obj.GetName()) {
+			return false
+		}
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	}
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/apis/rpc/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+func TestMaskObject(t *testing.T) {
+	policy := &securityv1alpha1.SecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy1", Namespace: "ns1", Labels: map[string]string{"app": "web"}},
+		Spec:       securityv1alpha1.SecurityPolicySpec{Tier: "tier2"},
+	}
+
+	tests := []struct {
+		name   string
+		paths  []string
+		expect map[string]interface{}
+	}{
+		{
+			name:  "should keep the fields in the paths",
+			paths: []string{"metadata.name", "spec.tier"},
+			expect: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "policy1"},
+				"spec":     map[string]interface{}{"tier": "tier2"},
+			},
+		},
+		{
+			name:  "should keep the whole field of the parent path",
+			paths: []string{"metadata.labels", "metadata"},
+			expect: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":              "policy1",
+					"namespace":         "ns1",
+					"labels":            map[string]interface{}{"app": "web"},
+					"creationTimestamp": nil,
+				},
+			},
+		},
+		{
+			name:   "should ignore the paths not found",
+			paths:  []string{"spec.notfound", "metadata.name.notfound"},
+			expect: map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := maskObject(policy, tt.paths)
+			if err != nil {
+				t.Fatalf("unexpect error: %s", err)
+			}
+			expect, _ := json.Marshal(tt.expect)
+			if string(raw) != string(expect) {
+				t.Errorf("expect %s, got %s", expect, raw)
+			}
+		})
+	}
+
+	if err := validatePaths([]string{"spec..tier"}); err == nil {
+		t.Errorf("expect error for the path with empty field")
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	selector, _ := labels.Parse("app=web")
+	filter := newFilter("ns1", []string{"policy1"}, selector)
+
+	tests := []struct {
+		meta   metav1.ObjectMeta
+		expect bool
+	}{
+		{metav1.ObjectMeta{Namespace: "ns1", Name: "policy1", Labels: map[string]string{"app": "web"}}, true},
+		{metav1.ObjectMeta{Namespace: "ns2", Name: "policy1", Labels: map[string]string{"app": "web"}}, false},
+		{metav1.ObjectMeta{Namespace: "ns1", Name: "policy2", Labels: map[string]string{"app": "web"}}, false},
+		{metav1.ObjectMeta{Namespace: "ns1", Name: "policy1", Labels: map[string]string{"app": "db"}}, false},
+	}
+	for _, tt := range tests {
+		if got := filter(&tt.meta); got != tt.expect {
+			t.Errorf("expect %v for %+v, got %v", tt.expect, tt.meta, got)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	s := &Server{}
+	handler := s.eventHandler("AgentInfo")
+	agent := &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: "agent1", ResourceVersion: "1"}}
+
+	t.Run("should send the changes to the watchers", func(t *testing.T) {
+		w := s.watch("AgentInfo")
+		defer s.unwatch("AgentInfo", w)
+
+		updated := agent.DeepCopy()
+		updated.ResourceVersion = "2"
+		handler.OnAdd(agent)
+		handler.OnUpdate(agent, agent.DeepCopy())
+		handler.OnUpdate(agent, updated)
+		handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "agent1", Obj: updated})
+
+		for _, expect := range []string{EventAdded, EventModified, EventDeleted} {
+			e := <-w.events
+			if e.typ != expect {
+				t.Errorf("expect event %s, got %s", expect, e.typ)
+			}
+		}
+		if len(w.events) != 0 {
+			t.Errorf("expect the resync not sent, got %d events", len(w.events))
+		}
+	})
+
+	t.Run("should close the watchers fall behind", func(t *testing.T) {
+		w := s.watch("AgentInfo")
+		for i := 0; i <= watchBufferSize; i++ {
+			handler.OnAdd(agent)
+		}
+		select {
+		case <-w.overflow:
+		default:
+			t.Errorf("expect the watcher closed after the buffer full")
+		}
+		if _, ok := s.watchers["AgentInfo"][w]; ok {
+			t.Errorf("expect the watcher removed after closed")
+		}
+	})
+}
+
+func TestIsListed(t *testing.T) {
+	newAgent := func(version string) runtime.Object {
+		return &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: "agent1", ResourceVersion: version}}
+	}
+
+	tests := []struct {
+		name   string
+		event  event
+		expect bool
+	}{
+		{name: "should drop the modified older than listed", event: event{typ: EventModified, obj: newAgent("4")}, expect: true},
+		{name: "should drop the modified listed", event: event{typ: EventModified, obj: newAgent("5")}, expect: true},
+		{name: "should drop the deleted before recreated", event: event{typ: EventDeleted, obj: newAgent("4")}, expect: true},
+		{name: "should send the deleted after listed", event: event{typ: EventDeleted, obj: newAgent("5")}, expect: false},
+		{name: "should send the modified newer than listed", event: event{typ: EventModified, obj: newAgent("6")}, expect: false},
+		{
+			name:   "should send the object not listed",
+			event:  event{typ: EventAdded, obj: &agentv1alpha1.AgentInfo{ObjectMeta: metav1.ObjectMeta{Name: "agent2", ResourceVersion: "1"}}},
+			expect: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			listed := map[string]uint64{"/agent1": 5}
+			if got := isListed(listed, tc.event); got != tc.expect {
+				t.Errorf("expect isListed %t, got %t", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = securityv1alpha1.AddToScheme(scheme)
+	_ = authenticationv1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
+
+	newPolicy := func(namespace, name string) *securityv1alpha1.SecurityPolicy {
+		return &securityv1alpha1.SecurityPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       securityv1alpha1.SecurityPolicySpec{Tier: "tier2"},
+		}
+	}
+	reader := fake.NewFakeClientWithScheme(scheme, newPolicy("ns1", "policy1"), newPolicy("ns1", "policy2"), newPolicy("ns2", "policy1"))
+	s := &Server{
+		Cache:  &fakeCache{Reader: reader},
+		Client: &reviewClient{Client: fake.NewFakeClientWithScheme(scheme), token: "token", allowed: "ns1"},
+	}
+
+	export := func(token string, req *v1alpha1.ExportRequest) ([]*v1alpha1.ExportEvent, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		stream := &fakeStream{ctx: ctx}
+		err := s.Export(req, stream)
+		return stream.events, err
+	}
+
+	t.Run("should list the objects selected with the field mask", func(t *testing.T) {
+		events, err := export("token", &v1alpha1.ExportRequest{
+			Kind:      "SecurityPolicy",
+			Namespace: "ns1",
+			Names:     []string{"policy1"},
+			FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"spec.tier"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpect error: %s", err)
+		}
+		if len(events) != 1 || events[0].Type != EventAdded || events[0].Name != "policy1" || events[0].Namespace != "ns1" {
+			t.Fatalf("unexpect events %+v", events)
+		}
+		if string(events[0].Object) != `{"spec":{"tier":"tier2"}}` {
+			t.Errorf("unexpect object %s", events[0].Object)
+		}
+	})
+
+	t.Run("should reject the invalid request", func(t *testing.T) {
+		for _, req := range []*v1alpha1.ExportRequest{
+			{Kind: "Unknown"},
+			{Kind: "Tier", Namespace: "ns1"},
+			{Kind: "SecurityPolicy", LabelSelector: "app in (web"},
+			{Kind: "SecurityPolicy", FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"spec."}}},
+		} {
+			if _, err := export("token", req); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expect InvalidArgument for %+v, got %v", req, err)
+			}
+		}
+	})
+
+	t.Run("should reject the requester unauthenticated", func(t *testing.T) {
+		for _, token := range []string{"", "invalid"} {
+			if _, err := export(token, &v1alpha1.ExportRequest{Kind: "SecurityPolicy", Namespace: "ns1"}); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("expect Unauthenticated for token %q, got %v", token, err)
+			}
+		}
+	})
+
+	t.Run("should reject the requester without access", func(t *testing.T) {
+		if _, err := export("token", &v1alpha1.ExportRequest{Kind: "SecurityPolicy", Namespace: "ns2"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expect PermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("should stream the changes after synced", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token")))
+		stream := &fakeStream{ctx: ctx, sent: make(chan *v1alpha1.ExportEvent, 10)}
+		done := make(chan error)
+		go func() {
+			done <- s.Export(&v1alpha1.ExportRequest{Kind: "SecurityPolicy", Namespace: "ns1", Watch: true}, stream)
+		}()
+
+		for _, expect := range []string{EventAdded, EventAdded, EventSynced} {
+			if e := <-stream.sent; e.Type != expect {
+				t.Fatalf("expect event %s, got %+v", expect, e)
+			}
+		}
+		handler := s.eventHandler("SecurityPolicy")
+		handler.OnAdd(newPolicy("ns2", "policy3"))
+		handler.OnAdd(newPolicy("ns1", "policy3"))
+		select {
+		case e := <-stream.sent:
+			if e.Type != EventAdded || e.Namespace != "ns1" || e.Name != "policy3" {
+				t.Errorf("unexpect event %+v", e)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("timeout waiting for the event")
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("unexpect error: %s", err)
+		}
+	})
+}
+
+// fakeCache lists the objects from the Reader.
+type fakeCache struct {
+	cache.Informers
+	client.Reader
+}
+
+// reviewClient authenticates the token, and allows the access in the namespace allowed.
+type reviewClient struct {
+	client.Client
+	token   string
+	allowed string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		review.Status.Authenticated = review.Spec.Token == c.token
+		review.Status.User.Username = "user1"
+	case *authorizationv1.SubjectAccessReview:
+		review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == c.allowed
+	}
+	return nil
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*v1alpha1.ExportEvent
+	sent   chan *v1alpha1.ExportEvent
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(e *v1alpha1.ExportEvent) error {
+	s.events = append(s.events, e)
+	if s.sent != nil {
+		s.sent <- e
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the metrics documentation of the definitions as a markdown table.
+func WriteMarkdown(w io.Writer, defs []Definition) error {
+	lines := []string{
+		"---",
+		`title: "Metrics"`,
+		`linkTitle: "Metrics"`,
+		"---",
+		"",
+		"<!-- Code generated by hack/metrics-docs-gen. DO NOT EDIT. -->",
+		"",
+		"The metrics are served on the metrics address of the everoute agent. The",
+		"exemplars of the latency histograms are only exposed in the OpenMetrics format on `" + OpenMetricsPath + "`.",
+		"",
+		"| Name | Type | Labels | Description |",
+		"| ---- | ---- | ------ | ----------- |",
+	}
+	for _, def := range defs {
+		var labels []string
+		for _, label := range def.Labels {
+			labels = append(labels, "`"+label+"`")
+		}
+		lines = append(lines, fmt.Sprintf("| `%s` | %s | %s | %s |", def.Name, def.Type, strings.Join(labels, ", "), def.Help))
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
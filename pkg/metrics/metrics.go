@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics defines the conventions of the everoute metrics. All the metrics are
+// created by the constructors of this package, which name them under the "everoute_"
+// prefix and record their definitions for the metrics documentation.
+package metrics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Namespace is the prefix of all the everoute metrics.
+const Namespace = "everoute"
+
+// The label names shared by the metrics, the same dimension must be labeled by the
+// same name, so the metrics could be joined in the dashboards.
+const (
+	LabelBridge    = "bridge"
+	LabelInterface = "interface"
+	LabelPhase     = "phase"
+	LabelTable     = "table"
+	LabelColumn    = "column"
+	LabelFrom      = "from"
+	LabelTo        = "to"
+	LabelType      = "type"
+)
+
+// OpenMetricsPath is the path the metrics served in the OpenMetrics format.
+const OpenMetricsPath = "/openmetrics"
+
+// ExemplarTraceID is the exemplar label of the trace id, which is also logged, so the
+// dashboards could jump from a latency sample to the logs of it.
+const ExemplarTraceID = "trace_id"
+
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Definition is the definition of a metric.
+type Definition struct {
+	Name   string
+	Type   Type
+	Help   string
+	Labels []string
+}
+
+var (
+	definitionsLock sync.Mutex
+	definitions     = make(map[string]Definition)
+)
+
+func define(metricType Type, subsystem, name, help string, labels []string) {
+	definitionsLock.Lock()
+	defer definitionsLock.Unlock()
+
+	fqName := prometheus.BuildFQName(Namespace, subsystem, name)
+	definitions[fqName] = Definition{Name: fqName, Type: metricType, Help: help, Labels: labels}
+}
+
+// Definitions returns the definitions of the metrics created, sorted by name.
+func Definitions() []Definition {
+	definitionsLock.Lock()
+	defer definitionsLock.Unlock()
+
+	var defs []Definition
+	for _, def := range definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+func NewCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	opts.Namespace = Namespace
+	define(TypeCounter, opts.Subsystem, opts.Name, opts.Help, nil)
+	return prometheus.NewCounter(opts)
+}
+
+func NewCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	opts.Namespace = Namespace
+	define(TypeCounter, opts.Subsystem, opts.Name, opts.Help, labels)
+	return prometheus.NewCounterVec(opts, labels)
+}
+
+func NewGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	opts.Namespace = Namespace
+	define(TypeGauge, opts.Subsystem, opts.Name, opts.Help, nil)
+	return prometheus.NewGauge(opts)
+}
+
+func NewGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	opts.Namespace = Namespace
+	define(TypeGauge, opts.Subsystem, opts.Name, opts.Help, labels)
+	return prometheus.NewGaugeVec(opts, labels)
+}
+
+func NewHistogram(opts prometheus.HistogramOpts) prometheus.Histogram {
+	opts.Namespace = Namespace
+	define(TypeHistogram, opts.Subsystem, opts.Name, opts.Help, nil)
+	return prometheus.NewHistogram(opts)
+}
+
+func NewHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	opts.Namespace = Namespace
+	define(TypeHistogram, opts.Subsystem, opts.Name, opts.Help, labels)
+	return prometheus.NewHistogramVec(opts, labels)
+}
+
+// MustRegister registers the collectors to the registry served by the metrics server.
+func MustRegister(collectors ...prometheus.Collector) {
+	metrics.Registry.MustRegister(collectors...)
+}
+
+// ObserveWithExemplar observes the value with the trace id as exemplar, the exemplars
+// are exposed in the OpenMetrics format only.
+func ObserveWithExemplar(observer prometheus.Observer, value float64, traceID string) {
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || traceID == "" {
+		observer.Observe(value)
+		return
+	}
+	exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{ExemplarTraceID: traceID})
+}
+
+// NewTraceID returns a random trace id in the format of the w3c trace context.
+func NewTraceID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// OpenMetricsHandler serves the metrics in the OpenMetrics format if negotiated, which
+// carries the exemplars. The metrics server of controller-runtime does not enable it.
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDefinitions(t *testing.T) {
+	RegisterTestingT(t)
+
+	NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "test",
+		Name:      "events_total",
+		Help:      "Number of test events.",
+	}, []string{LabelType})
+	NewGauge(prometheus.GaugeOpts{
+		Namespace: "other",
+		Name:      "test_gauge",
+		Help:      "Test gauge.",
+	})
+
+	defs := Definitions()
+	Expect(defs).Should(ContainElement(Definition{
+		Name: "everoute_test_events_total", Type: TypeCounter, Help: "Number of test events.", Labels: []string{LabelType},
+	}))
+	Expect(defs).Should(ContainElement(Definition{
+		Name: "everoute_test_gauge", Type: TypeGauge, Help: "Test gauge.",
+	}))
+	for item := 1; item < len(defs); item++ {
+		Expect(defs[item-1].Name < defs[item].Name).Should(BeTrue())
+	}
+}
+
+func TestObserveWithExemplar(t *testing.T) {
+	RegisterTestingT(t)
+
+	histogram := NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "test",
+		Name:      "duration_seconds",
+		Help:      "Test duration.",
+		Buckets:   []float64{1, 10},
+	})
+	traceID := NewTraceID()
+	Expect(traceID).Should(HaveLen(32))
+	Expect(NewTraceID()).ShouldNot(Equal(traceID))
+
+	ObserveWithExemplar(histogram, 5, traceID)
+	ObserveWithExemplar(histogram, 0.5, "")
+
+	var metric dto.Metric
+	Expect(histogram.Write(&metric)).Should(Succeed())
+	Expect(metric.GetHistogram().GetSampleCount()).Should(BeEquivalentTo(2))
+
+	buckets := metric.GetHistogram().GetBucket()
+	Expect(buckets).Should(HaveLen(2))
+	Expect(buckets[0].GetExemplar()).Should(BeNil())
+	Expect(buckets[1].GetExemplar().GetValue()).Should(Equal(5.0))
+	Expect(buckets[1].GetExemplar().GetLabel()).Should(HaveLen(1))
+	Expect(buckets[1].GetExemplar().GetLabel()[0].GetName()).Should(Equal(ExemplarTraceID))
+	Expect(buckets[1].GetExemplar().GetLabel()[0].GetValue()).Should(Equal(traceID))
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	RegisterTestingT(t)
+
+	var buf bytes.Buffer
+	Expect(WriteMarkdown(&buf, []Definition{
+		{Name: "everoute_a_total", Type: TypeCounter, Help: "Number of a.", Labels: []string{LabelBridge, LabelInterface}},
+		{Name: "everoute_b", Type: TypeGauge, Help: "Value of b."},
+	})).Should(Succeed())
+
+	Expect(buf.String()).Should(ContainSubstring("| `everoute_a_total` | counter | `bridge`, `interface` | Number of a. |\n"))
+	Expect(buf.String()).Should(ContainSubstring("| `everoute_b` | gauge |  | Value of b. |\n"))
+	Expect(buf.String()).Should(ContainSubstring("`" + OpenMetricsPath + "`"))
+}
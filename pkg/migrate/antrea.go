@@ -0,0 +1,391 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+const (
+	antreaGroup = "crd.antrea.io"
+	// antreaDefaultTier is the tier of the ClusterNetworkPolicy without tier specified
+	antreaDefaultTier = "application"
+	// antreaMaxGroupDepth is the nesting depth of the ClusterGroups allowed by Antrea
+	antreaMaxGroupDepth = 1
+)
+
+// The fields of the Antrea ClusterNetworkPolicy and ClusterGroup read by the conversion,
+// the unsupported constructs are kept raw to be reported.
+type antreaClusterNetworkPolicy struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              antreaPolicySpec `json:"spec"`
+}
+
+type antreaPolicySpec struct {
+	Tier      string            `json:"tier,omitempty"`
+	AppliedTo []antreaAppliedTo `json:"appliedTo,omitempty"`
+	Ingress   []antreaRule      `json:"ingress,omitempty"`
+	Egress    []antreaRule      `json:"egress,omitempty"`
+}
+
+type antreaAppliedTo struct {
+	PodSelector            *metav1.LabelSelector `json:"podSelector,omitempty"`
+	NamespaceSelector      *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	Group                  string                `json:"group,omitempty"`
+	ServiceAccount         json.RawMessage       `json:"serviceAccount,omitempty"`
+	Service                json.RawMessage       `json:"service,omitempty"`
+	NodeSelector           *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	ExternalEntitySelector *metav1.LabelSelector `json:"externalEntitySelector,omitempty"`
+}
+
+type antreaRule struct {
+	Name        string            `json:"name,omitempty"`
+	Action      string            `json:"action,omitempty"`
+	Ports       []antreaPort      `json:"ports,omitempty"`
+	Protocols   []antreaProtocol  `json:"protocols,omitempty"`
+	From        []antreaPeer      `json:"from,omitempty"`
+	To          []antreaPeer      `json:"to,omitempty"`
+	AppliedTo   []antreaAppliedTo `json:"appliedTo,omitempty"`
+	ToServices  json.RawMessage   `json:"toServices,omitempty"`
+	L7Protocols json.RawMessage   `json:"l7Protocols,omitempty"`
+}
+
+type antreaPort struct {
+	Protocol string              `json:"protocol,omitempty"`
+	Port     *intstr.IntOrString `json:"port,omitempty"`
+	EndPort  *int32              `json:"endPort,omitempty"`
+}
+
+type antreaProtocol struct {
+	ICMP *struct {
+		ICMPType *int32 `json:"icmpType,omitempty"`
+		ICMPCode *int32 `json:"icmpCode,omitempty"`
+	} `json:"icmp,omitempty"`
+	IGMP json.RawMessage `json:"igmp,omitempty"`
+}
+
+type antreaPeer struct {
+	PodSelector            *metav1.LabelSelector `json:"podSelector,omitempty"`
+	NamespaceSelector      *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	Namespaces             json.RawMessage       `json:"namespaces,omitempty"`
+	IPBlock                *networkingv1.IPBlock `json:"ipBlock,omitempty"`
+	Group                  string                `json:"group,omitempty"`
+	FQDN                   string                `json:"fqdn,omitempty"`
+	ServiceAccount         json.RawMessage       `json:"serviceAccount,omitempty"`
+	NodeSelector           *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	ExternalEntitySelector *metav1.LabelSelector `json:"externalEntitySelector,omitempty"`
+}
+
+type antreaClusterGroup struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		PodSelector            *metav1.LabelSelector  `json:"podSelector,omitempty"`
+		NamespaceSelector      *metav1.LabelSelector  `json:"namespaceSelector,omitempty"`
+		IPBlock                *networkingv1.IPBlock  `json:"ipBlock,omitempty"`
+		IPBlocks               []networkingv1.IPBlock `json:"ipBlocks,omitempty"`
+		ChildGroups            []string               `json:"childGroups,omitempty"`
+		ServiceReference       json.RawMessage        `json:"serviceReference,omitempty"`
+		ExternalEntitySelector *metav1.LabelSelector  `json:"externalEntitySelector,omitempty"`
+	} `json:"spec"`
+}
+
+// convertAntreaPolicy converts the ClusterNetworkPolicy into the policies in the namespace
+// of the options. The policy with the appliedTo in the rules is split into one per rule.
+func (c *converter) convertAntreaPolicy(obj *object) error {
+	policy := &antreaClusterNetworkPolicy{}
+	if err := json.Unmarshal(obj.raw, policy); err != nil {
+		return err
+	}
+	source := obj.source()
+
+	if policy.Spec.Tier != "" && !strings.EqualFold(policy.Spec.Tier, antreaDefaultTier) {
+		c.result.Report.approximated(source, "spec.tier", "tier %s and the priority are flattened into the everoute tier %s",
+			policy.Spec.Tier, c.options.Tier)
+	}
+
+	if len(policy.Spec.AppliedTo) != 0 {
+		appliedTo, ok := c.antreaAppliedTo(source, "spec.appliedTo", policy.Spec.AppliedTo)
+		if !ok {
+			return nil
+		}
+		c.addPolicies(obj, c.options.Namespace, "", appliedTo,
+			c.antreaRules(source, "ingress", policy.Spec.Ingress, 0),
+			c.antreaRules(source, "egress", policy.Spec.Egress, 0))
+		return nil
+	}
+
+	for _, direction := range []string{"ingress", "egress"} {
+		rules := policy.Spec.Ingress
+		if direction == "egress" {
+			rules = policy.Spec.Egress
+		}
+		for i, rule := range rules {
+			appliedTo, ok := c.antreaAppliedTo(source, fmt.Sprintf("spec.%s[%d].appliedTo", direction, i), rule.AppliedTo)
+			if !ok {
+				continue
+			}
+			suffix := "-" + ruleName(rule.Name, direction, i, nil)
+			converted := c.antreaRules(source, direction, rules[i:i+1], i)
+			if direction == "ingress" {
+				c.addPolicies(obj, c.options.Namespace, suffix, appliedTo, converted, convertedRules{})
+			} else {
+				c.addPolicies(obj, c.options.Namespace, suffix, appliedTo, convertedRules{}, converted)
+			}
+		}
+	}
+	return nil
+}
+
+// antreaRules converts the rules of the direction in order. The drop all rule is converted
+// into the default drop, the rules after it never match. The other drop rules could not be
+// expressed by the allow only rules.
+func (c *converter) antreaRules(source, direction string, rules []antreaRule, start int) convertedRules {
+	converted := convertedRules{present: len(rules) != 0}
+	for i, rule := range rules {
+		field := fmt.Sprintf("spec.%s[%d]", direction, start+i)
+		if converted.dropped {
+			c.result.Report.info(source, field, "shadowed by the drop all rule, not converted")
+			continue
+		}
+
+		switch rule.Action {
+		case "Allow":
+			if r, ok := c.antreaRule(source, field, direction, start+i, rule, converted.rules); ok {
+				converted.rules = append(converted.rules, *r)
+			}
+		case "Drop", "Reject":
+			if !isAntreaMatchAll(rule) {
+				c.result.Report.unsupported(source, field, "%s rule not converted, the traffic is allowed if matched by the allow rules", rule.Action)
+				continue
+			}
+			if rule.Action == "Reject" {
+				c.result.Report.approximated(source, field, "the rejected traffic is dropped without reply")
+			}
+			converted.dropped = true
+		case "Pass":
+			c.result.Report.unsupported(source, field, "Pass rule not converted, the traffic is not delegated to the lower tiers")
+		default:
+			c.result.Report.unsupported(source, field, "unknown action %q, not converted", rule.Action)
+		}
+	}
+	return converted
+}
+
+func isAntreaMatchAll(rule antreaRule) bool {
+	return len(rule.From) == 0 && len(rule.To) == 0 && len(rule.Ports) == 0 && len(rule.Protocols) == 0 &&
+		len(rule.ToServices) == 0 && len(rule.L7Protocols) == 0
+}
+
+// antreaRule converts the allow rule, returns false if the rule could not be converted, the
+// traffic it allows is denied.
+func (c *converter) antreaRule(source, field, direction string, index int, rule antreaRule, rules []securityv1alpha1.Rule) (*securityv1alpha1.Rule, bool) {
+	if len(rule.ToServices) != 0 || len(rule.L7Protocols) != 0 {
+		c.result.Report.unsupported(source, field, "toServices and l7Protocols not supported, the rule not converted")
+		return nil, false
+	}
+
+	r := &securityv1alpha1.Rule{Name: ruleName(rule.Name, direction, index, rules)}
+	ports, ok := c.antreaPorts(source, field, rule)
+	if !ok {
+		return nil, false
+	}
+	r.Ports = ports
+
+	peers, peerField := rule.From, field+".from"
+	if direction == "egress" {
+		peers, peerField = rule.To, field+".to"
+	}
+	var converted []securityv1alpha1.SecurityPolicyPeer
+	for i, peer := range peers {
+		converted = append(converted, c.antreaPeer(source, fmt.Sprintf("%s[%d]", peerField, i), peer)...)
+	}
+	if len(peers) != 0 && len(converted) == 0 {
+		c.result.Report.unsupported(source, field, "no peers converted, the rule not converted")
+		return nil, false
+	}
+	if direction == "egress" {
+		r.To = converted
+	} else {
+		r.From = converted
+	}
+	return r, true
+}
+
+func (c *converter) antreaPorts(source, field string, rule antreaRule) ([]securityv1alpha1.SecurityPolicyPort, bool) {
+	var ports []securityv1alpha1.SecurityPolicyPort
+	for i, port := range rule.Ports {
+		protocol := securityv1alpha1.Protocol(strings.ToUpper(port.Protocol))
+		if protocol == "" {
+			protocol = securityv1alpha1.ProtocolTCP
+		}
+		if protocol != securityv1alpha1.ProtocolTCP && protocol != securityv1alpha1.ProtocolUDP {
+			c.result.Report.unsupported(source, fmt.Sprintf("%s.ports[%d]", field, i), "protocol %s not supported, not converted", protocol)
+			continue
+		}
+
+		p := securityv1alpha1.SecurityPolicyPort{Protocol: protocol}
+		switch {
+		case port.Port == nil:
+		case port.Port.Type == intstr.String:
+			p.PortRange, p.Type = port.Port.StrVal, securityv1alpha1.PortTypeName
+		case port.EndPort != nil:
+			p.PortRange = fmt.Sprintf("%d-%d", port.Port.IntVal, *port.EndPort)
+		default:
+			p.PortRange = strconv.Itoa(int(port.Port.IntVal))
+		}
+		ports = append(ports, p)
+	}
+
+	for i, protocol := range rule.Protocols {
+		protocolField := fmt.Sprintf("%s.protocols[%d]", field, i)
+		if protocol.ICMP == nil {
+			c.result.Report.unsupported(source, protocolField, "only icmp supported, not converted")
+			continue
+		}
+		if protocol.ICMP.ICMPType == nil && protocol.ICMP.ICMPCode != nil {
+			c.result.Report.approximated(source, protocolField, "icmp code without type not supported, all icmp allowed")
+			ports = append(ports, securityv1alpha1.SecurityPolicyPort{Protocol: securityv1alpha1.ProtocolICMP})
+			continue
+		}
+		ports = append(ports, securityv1alpha1.SecurityPolicyPort{
+			Protocol: securityv1alpha1.ProtocolICMP,
+			ICMPType: protocol.ICMP.ICMPType,
+			ICMPCode: protocol.ICMP.ICMPCode,
+		})
+	}
+
+	if len(rule.Ports)+len(rule.Protocols) != 0 && len(ports) == 0 {
+		c.result.Report.unsupported(source, field, "no ports converted, the rule not converted")
+		return nil, false
+	}
+	return ports, true
+}
+
+// antreaPeer converts the peer, none if not supported.
+func (c *converter) antreaPeer(source, field string, peer antreaPeer) []securityv1alpha1.SecurityPolicyPeer {
+	switch {
+	case peer.Group != "":
+		return c.antreaGroupPeers(source, field, peer.Group, 0)
+	case peer.IPBlock != nil:
+		return []securityv1alpha1.SecurityPolicyPeer{{IPBlock: peer.IPBlock}}
+	case peer.FQDN != "":
+		c.result.Report.unsupported(source, field, "fqdn %s not supported, not converted", peer.FQDN)
+	case len(peer.Namespaces) != 0:
+		c.result.Report.unsupported(source, field, "namespaces match not supported, not converted")
+	case len(peer.ServiceAccount) != 0, peer.NodeSelector != nil, peer.ExternalEntitySelector != nil:
+		c.result.Report.unsupported(source, field, "serviceAccount, nodeSelector and externalEntitySelector not supported, not converted")
+	case peer.PodSelector != nil || peer.NamespaceSelector != nil:
+		return []securityv1alpha1.SecurityPolicyPeer{clusterSelectorPeer(peer.PodSelector, peer.NamespaceSelector)}
+	default:
+		c.result.Report.unsupported(source, field, "empty peer, not converted")
+	}
+	return nil
+}
+
+// clusterSelectorPeer returns the peer selecting the pods in the namespaces, in all the
+// namespaces if the namespace selector not set, as the source policy is cluster scoped.
+func clusterSelectorPeer(podSelector, namespaceSelector *metav1.LabelSelector) securityv1alpha1.SecurityPolicyPeer {
+	if namespaceSelector == nil {
+		namespaceSelector = &metav1.LabelSelector{}
+	}
+	return securityv1alpha1.SecurityPolicyPeer{
+		EndpointSelector:  labels.FromLabelSelector(podSelector),
+		NamespaceSelector: namespaceSelector,
+	}
+}
+
+// antreaGroupPeers inlines the ClusterGroup into the peers.
+func (c *converter) antreaGroupPeers(source, field, name string, depth int) []securityv1alpha1.SecurityPolicyPeer {
+	group, ok := c.antreaGroups[name]
+	if !ok {
+		c.result.Report.unsupported(source, field, "ClusterGroup %s not found, not converted", name)
+		return nil
+	}
+
+	spec := group.Spec
+	var peers []securityv1alpha1.SecurityPolicyPeer
+	switch {
+	case len(spec.ChildGroups) != 0 && depth < antreaMaxGroupDepth:
+		for _, child := range spec.ChildGroups {
+			peers = append(peers, c.antreaGroupPeers(source, field, child, depth+1)...)
+		}
+	case spec.IPBlock != nil || len(spec.IPBlocks) != 0:
+		ipBlocks := spec.IPBlocks
+		if spec.IPBlock != nil {
+			ipBlocks = append(ipBlocks, *spec.IPBlock)
+		}
+		for i := range ipBlocks {
+			peers = append(peers, securityv1alpha1.SecurityPolicyPeer{IPBlock: &ipBlocks[i]})
+		}
+	case spec.PodSelector != nil || spec.NamespaceSelector != nil:
+		peers = append(peers, clusterSelectorPeer(spec.PodSelector, spec.NamespaceSelector))
+	default:
+		c.result.Report.unsupported(source, field, "ClusterGroup %s with nested childGroups, serviceReference or externalEntitySelector not supported, not converted", name)
+	}
+	return peers
+}
+
+// antreaAppliedTo converts the appliedTo into the endpoint selectors in the namespace of the
+// options, returns false if none converted, the policy must not apply to all the endpoints.
+func (c *converter) antreaAppliedTo(source, field string, appliedTo []antreaAppliedTo) ([]securityv1alpha1.ApplyToPeer, bool) {
+	var peers []securityv1alpha1.ApplyToPeer
+	for i, item := range appliedTo {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		podSelector, namespaceSelector := item.PodSelector, item.NamespaceSelector
+		switch {
+		case item.Group != "":
+			group, ok := c.antreaGroups[item.Group]
+			if !ok || (group.Spec.PodSelector == nil && group.Spec.NamespaceSelector == nil) {
+				c.result.Report.unsupported(source, itemField, "ClusterGroup %s not found or not selecting pods, not converted", item.Group)
+				continue
+			}
+			podSelector, namespaceSelector = group.Spec.PodSelector, group.Spec.NamespaceSelector
+		case len(item.ServiceAccount) != 0, len(item.Service) != 0, item.NodeSelector != nil, item.ExternalEntitySelector != nil:
+			c.result.Report.unsupported(source, itemField, "serviceAccount, service, nodeSelector and externalEntitySelector not supported, not converted")
+			continue
+		case podSelector == nil && namespaceSelector == nil:
+			c.result.Report.unsupported(source, itemField, "empty appliedTo, not converted")
+			continue
+		}
+
+		if namespaceSelector != nil {
+			c.result.Report.approximated(source, itemField, "namespaceSelector not supported, applied to the endpoints in the namespace %s",
+				c.options.Namespace)
+		}
+		selector := labels.FromLabelSelector(podSelector)
+		if selector == nil {
+			selector = &labels.Selector{}
+		}
+		peers = append(peers, securityv1alpha1.ApplyToPeer{EndpointSelector: selector})
+	}
+
+	if len(peers) == 0 {
+		c.result.Report.unsupported(source, field, "no appliedTo converted, the policy not converted")
+		return nil, false
+	}
+	return peers, true
+}
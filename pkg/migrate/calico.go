@@ -0,0 +1,443 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+const (
+	calicoGroup    = "projectcalico.org"
+	calicoCRDGroup = "crd.projectcalico.org"
+	// calicoDefaultTier is the tier of the policy without tier specified
+	calicoDefaultTier = "default"
+	// calicoNamespaceNameLabel in the namespace selectors is the name of the namespace,
+	// it's the label kubernetes.io/metadata.name of the namespace in kubernetes.
+	calicoNamespaceNameLabel = "projectcalico.org/name"
+	namespaceNameLabel       = "kubernetes.io/metadata.name"
+)
+
+// The fields of the Calico NetworkPolicy and GlobalNetworkPolicy read by the conversion,
+// the unsupported constructs are kept raw to be reported.
+type calicoPolicy struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              calicoPolicySpec `json:"spec"`
+}
+
+type calicoPolicySpec struct {
+	Tier                   string       `json:"tier,omitempty"`
+	Selector               string       `json:"selector,omitempty"`
+	NamespaceSelector      string       `json:"namespaceSelector,omitempty"`
+	ServiceAccountSelector string       `json:"serviceAccountSelector,omitempty"`
+	Types                  []string     `json:"types,omitempty"`
+	Ingress                []calicoRule `json:"ingress,omitempty"`
+	Egress                 []calicoRule `json:"egress,omitempty"`
+	DoNotTrack             bool         `json:"doNotTrack,omitempty"`
+	PreDNAT                bool         `json:"preDNAT,omitempty"`
+	ApplyOnForward         bool         `json:"applyOnForward,omitempty"`
+}
+
+type calicoRule struct {
+	Action      string              `json:"action,omitempty"`
+	IPVersion   *int                `json:"ipVersion,omitempty"`
+	Protocol    *intstr.IntOrString `json:"protocol,omitempty"`
+	NotProtocol *intstr.IntOrString `json:"notProtocol,omitempty"`
+	ICMP        *calicoICMP         `json:"icmp,omitempty"`
+	NotICMP     *calicoICMP         `json:"notICMP,omitempty"`
+	Source      calicoEntityRule    `json:"source,omitempty"`
+	Destination calicoEntityRule    `json:"destination,omitempty"`
+	HTTP        json.RawMessage     `json:"http,omitempty"`
+}
+
+type calicoICMP struct {
+	Type *int `json:"type,omitempty"`
+	Code *int `json:"code,omitempty"`
+}
+
+type calicoEntityRule struct {
+	Nets              []string             `json:"nets,omitempty"`
+	Selector          string               `json:"selector,omitempty"`
+	NamespaceSelector string               `json:"namespaceSelector,omitempty"`
+	Ports             []intstr.IntOrString `json:"ports,omitempty"`
+	NotNets           []string             `json:"notNets,omitempty"`
+	NotSelector       string               `json:"notSelector,omitempty"`
+	NotPorts          []intstr.IntOrString `json:"notPorts,omitempty"`
+	ServiceAccounts   json.RawMessage      `json:"serviceAccounts,omitempty"`
+	Services          json.RawMessage      `json:"services,omitempty"`
+}
+
+func (e *calicoEntityRule) isEmpty() bool {
+	return len(e.Nets) == 0 && e.Selector == "" && e.NamespaceSelector == "" && len(e.Ports) == 0 &&
+		len(e.NotNets) == 0 && e.NotSelector == "" && len(e.NotPorts) == 0 && len(e.ServiceAccounts) == 0 && len(e.Services) == 0
+}
+
+// convertCalicoPolicy converts the NetworkPolicy into the policy in its namespace, or the
+// GlobalNetworkPolicy into the policy in the namespace of the options. The endpoints selected
+// by the calico policies deny the traffic not allowed, the policy types drop by default.
+func (c *converter) convertCalicoPolicy(obj *object) error {
+	policy := &calicoPolicy{}
+	if err := json.Unmarshal(obj.raw, policy); err != nil {
+		return err
+	}
+	source := obj.source()
+	global := obj.Kind == "GlobalNetworkPolicy"
+	namespace := policy.Namespace
+	if global || namespace == "" {
+		namespace = c.options.Namespace
+	}
+
+	if policy.Spec.DoNotTrack || policy.Spec.PreDNAT || policy.Spec.ApplyOnForward {
+		c.result.Report.unsupported(source, "spec", "doNotTrack, preDNAT and applyOnForward policies for the host endpoints not supported, the policy not converted")
+		return nil
+	}
+	if policy.Spec.ServiceAccountSelector != "" {
+		c.result.Report.unsupported(source, "spec.serviceAccountSelector", "not supported, the policy not converted")
+		return nil
+	}
+	if policy.Spec.Tier != "" && policy.Spec.Tier != calicoDefaultTier {
+		c.result.Report.approximated(source, "spec.tier", "tier %s and the order are flattened into the everoute tier %s",
+			policy.Spec.Tier, c.options.Tier)
+	}
+
+	selector, err := parseCalicoSelector(policy.Spec.Selector)
+	if err != nil {
+		c.result.Report.unsupported(source, "spec.selector", "%s, the policy not converted", err)
+		return nil
+	}
+	if global && policy.Spec.NamespaceSelector != "" {
+		c.result.Report.approximated(source, "spec.namespaceSelector", "not supported, applied to the endpoints in the namespace %s",
+			c.options.Namespace)
+	}
+	var appliedTo []securityv1alpha1.ApplyToPeer
+	if len(selector.MatchLabels) != 0 || len(selector.MatchExpressions) != 0 {
+		appliedTo = []securityv1alpha1.ApplyToPeer{{EndpointSelector: labels.FromLabelSelector(selector)}}
+	}
+
+	types := policy.Spec.Types
+	if len(types) == 0 {
+		types = []string{string(networkingv1.PolicyTypeIngress)}
+		if len(policy.Spec.Egress) != 0 {
+			types = append(types, string(networkingv1.PolicyTypeEgress))
+		}
+	}
+	var ingress, egress convertedRules
+	for _, policyType := range types {
+		switch networkingv1.PolicyType(policyType) {
+		case networkingv1.PolicyTypeIngress:
+			ingress = c.calicoRules(source, "ingress", policy.Spec.Ingress, global)
+		case networkingv1.PolicyTypeEgress:
+			egress = c.calicoRules(source, "egress", policy.Spec.Egress, global)
+		}
+	}
+	if !ingress.present && len(policy.Spec.Ingress) != 0 {
+		c.result.Report.info(source, "spec.ingress", "Ingress not in the types, not converted")
+	}
+	if !egress.present && len(policy.Spec.Egress) != 0 {
+		c.result.Report.info(source, "spec.egress", "Egress not in the types, not converted")
+	}
+
+	c.addPolicies(obj, namespace, "", appliedTo, ingress, egress)
+	return nil
+}
+
+// calicoRules converts the rules of the direction in order. The deny all rule is the same
+// as the default drop, the rules after it never match. The other deny rules could not be
+// expressed by the allow only rules.
+func (c *converter) calicoRules(source, direction string, rules []calicoRule, global bool) convertedRules {
+	converted := convertedRules{present: true, dropped: true}
+	var denied bool
+	for i, rule := range rules {
+		field := fmt.Sprintf("spec.%s[%d]", direction, i)
+		if denied {
+			c.result.Report.info(source, field, "shadowed by the deny all rule, not converted")
+			continue
+		}
+
+		switch rule.Action {
+		case "Allow":
+			if r, ok := c.calicoRule(source, field, direction, i, rule, converted.rules, global); ok {
+				converted.rules = append(converted.rules, *r)
+			}
+		case "Deny":
+			if !isCalicoMatchAll(rule) {
+				c.result.Report.unsupported(source, field, "Deny rule not converted, the traffic is allowed if matched by the allow rules")
+				continue
+			}
+			denied = true
+		case "Log":
+			c.result.Report.info(source, field, "Log rule not converted")
+		case "Pass":
+			c.result.Report.unsupported(source, field, "Pass rule not converted, the traffic is not delegated to the next tier")
+		default:
+			c.result.Report.unsupported(source, field, "unknown action %q, not converted", rule.Action)
+		}
+	}
+	return converted
+}
+
+func isCalicoMatchAll(rule calicoRule) bool {
+	return rule.Protocol == nil && rule.NotProtocol == nil && rule.ICMP == nil && rule.NotICMP == nil &&
+		rule.IPVersion == nil && len(rule.HTTP) == 0 && rule.Source.isEmpty() && rule.Destination.isEmpty()
+}
+
+// calicoRule converts the allow rule, returns false if the rule could not be converted, the
+// traffic it allows is denied. The rule with the negative matches is never converted, as
+// ignoring them allows more traffic.
+func (c *converter) calicoRule(source, field, direction string, index int, rule calicoRule, rules []securityv1alpha1.Rule, global bool) (*securityv1alpha1.Rule, bool) {
+	peer, peerField := rule.Source, field+".source"
+	local, localField := rule.Destination, field+".destination"
+	if direction == "egress" {
+		peer, peerField, local, localField = rule.Destination, field+".destination", rule.Source, field+".source"
+	}
+	ports := rule.Destination.Ports
+
+	unsupported := func(field, what string) (*securityv1alpha1.Rule, bool) {
+		c.result.Report.unsupported(source, field, "%s not supported, the rule not converted", what)
+		return nil, false
+	}
+	switch {
+	case rule.NotProtocol != nil || rule.NotICMP != nil:
+		return unsupported(field, "notProtocol and notICMP")
+	case len(rule.HTTP) != 0:
+		return unsupported(field+".http", "http match")
+	case len(rule.Source.Ports) != 0:
+		return unsupported(field+".source.ports", "source ports")
+	case len(local.Nets) != 0 || local.Selector != "" || local.NamespaceSelector != "":
+		return unsupported(localField, "match on the local endpoints")
+	case peer.NotSelector != "" || len(peer.NotPorts) != 0 ||
+		len(local.NotNets) != 0 || local.NotSelector != "" || len(local.NotPorts) != 0:
+		return unsupported(field, "notSelector, notPorts and notNets of the local endpoints")
+	case len(peer.ServiceAccounts) != 0 || len(peer.Services) != 0 || len(local.ServiceAccounts) != 0 || len(local.Services) != 0:
+		return unsupported(field, "serviceAccounts and services")
+	}
+	if rule.IPVersion != nil {
+		c.result.Report.approximated(source, field+".ipVersion", "not supported, both ip versions allowed")
+	}
+
+	r := &securityv1alpha1.Rule{Name: ruleName("", direction, index, rules)}
+	policyPorts, ok := c.calicoPorts(source, field, rule.Protocol, rule.ICMP, ports)
+	if !ok {
+		return nil, false
+	}
+	r.Ports = policyPorts
+
+	peers, ok := c.calicoPeers(source, peerField, peer, global)
+	if !ok {
+		return nil, false
+	}
+	if direction == "egress" {
+		r.To = peers
+	} else {
+		r.From = peers
+	}
+	return r, true
+}
+
+var calicoProtocols = map[string]securityv1alpha1.Protocol{
+	"TCP":    securityv1alpha1.ProtocolTCP,
+	"6":      securityv1alpha1.ProtocolTCP,
+	"UDP":    securityv1alpha1.ProtocolUDP,
+	"17":     securityv1alpha1.ProtocolUDP,
+	"ICMP":   securityv1alpha1.ProtocolICMP,
+	"1":      securityv1alpha1.ProtocolICMP,
+	"ICMPV6": securityv1alpha1.ProtocolICMPv6,
+	"58":     securityv1alpha1.ProtocolICMPv6,
+}
+
+func (c *converter) calicoPorts(source, field string, protocol *intstr.IntOrString, icmp *calicoICMP, ports []intstr.IntOrString) ([]securityv1alpha1.SecurityPolicyPort, bool) {
+	if protocol == nil {
+		if len(ports) != 0 {
+			c.result.Report.unsupported(source, field+".destination.ports", "ports without protocol, the rule not converted")
+			return nil, false
+		}
+		return nil, true
+	}
+	policyProtocol, ok := calicoProtocols[strings.ToUpper(protocol.String())]
+	if !ok {
+		c.result.Report.unsupported(source, field+".protocol", "protocol %s not supported, the rule not converted", protocol.String())
+		return nil, false
+	}
+	if icmp != nil {
+		return c.calicoICMPPorts(source, field, policyProtocol, icmp), true
+	}
+	if len(ports) == 0 {
+		return []securityv1alpha1.SecurityPolicyPort{{Protocol: policyProtocol}}, true
+	}
+
+	var policyPorts []securityv1alpha1.SecurityPolicyPort
+	for _, port := range ports {
+		policyPort := securityv1alpha1.SecurityPolicyPort{Protocol: policyProtocol}
+		switch {
+		case port.Type == intstr.Int:
+			policyPort.PortRange = strconv.Itoa(int(port.IntVal))
+		case strings.Contains(port.StrVal, ":"):
+			policyPort.PortRange = strings.Replace(port.StrVal, ":", "-", 1)
+		default:
+			policyPort.PortRange, policyPort.Type = port.StrVal, securityv1alpha1.PortTypeName
+		}
+		policyPorts = append(policyPorts, policyPort)
+	}
+	return policyPorts, true
+}
+
+// calicoICMPPorts converts the icmp type and code of the protocol, all the messages of the
+// protocol matched if the type not set.
+func (c *converter) calicoICMPPorts(source, field string, protocol securityv1alpha1.Protocol, icmp *calicoICMP) []securityv1alpha1.SecurityPolicyPort {
+	policyPort := securityv1alpha1.SecurityPolicyPort{Protocol: protocol}
+	switch {
+	case protocol != securityv1alpha1.ProtocolICMP && protocol != securityv1alpha1.ProtocolICMPv6:
+		c.result.Report.approximated(source, field+".icmp", "icmp with protocol %s not supported, ignored", protocol)
+	case icmp.Type == nil:
+		if icmp.Code != nil {
+			c.result.Report.approximated(source, field+".icmp", "icmp code without type not supported, all icmp allowed")
+		}
+	default:
+		icmpType := int32(*icmp.Type)
+		policyPort.ICMPType = &icmpType
+		if icmp.Code != nil {
+			icmpCode := int32(*icmp.Code)
+			policyPort.ICMPCode = &icmpCode
+		}
+	}
+	return []securityv1alpha1.SecurityPolicyPort{policyPort}
+}
+
+// calicoNetPeers converts the nets into the ipBlocks, the notNets within the net become the
+// except of the ipBlock, and the net within the notNets is dropped. The nets are any address of
+// the ip versions of the notNets if only the notNets set.
+func calicoNetPeers(nets, notNets []string) ([]securityv1alpha1.SecurityPolicyPeer, error) {
+	var notIPNets []*net.IPNet
+	for _, notNet := range notNets {
+		_, ipNet, err := net.ParseCIDR(notNet)
+		if err != nil {
+			return nil, fmt.Errorf("unvalid notNets %s", notNet)
+		}
+		notIPNets = append(notIPNets, ipNet)
+	}
+	if len(nets) == 0 {
+		anyNets := sets.NewString()
+		for _, notIPNet := range notIPNets {
+			if notIPNet.IP.To4() != nil {
+				anyNets.Insert("0.0.0.0/0")
+			} else {
+				anyNets.Insert("::/0")
+			}
+		}
+		nets = anyNets.List()
+	}
+
+	var peers []securityv1alpha1.SecurityPolicyPeer
+	for _, cidr := range nets {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("unvalid nets %s", cidr)
+		}
+		ipBlock := &networkingv1.IPBlock{CIDR: cidr}
+		excluded := false
+		for _, notIPNet := range notIPNets {
+			ones, _ := ipNet.Mask.Size()
+			notOnes, _ := notIPNet.Mask.Size()
+			switch {
+			case notIPNet.Contains(ipNet.IP) && notOnes <= ones:
+				excluded = true
+			case ipNet.Contains(notIPNet.IP):
+				ipBlock.Except = append(ipBlock.Except, notIPNet.String())
+			}
+		}
+		if !excluded {
+			peers = append(peers, securityv1alpha1.SecurityPolicyPeer{IPBlock: ipBlock})
+		}
+	}
+	return peers, nil
+}
+
+// calicoPeers converts the entity into the peers, none for any peer. The selector of the
+// GlobalNetworkPolicy selects the endpoints in all the namespaces.
+func (c *converter) calicoPeers(source, field string, entity calicoEntityRule, global bool) ([]securityv1alpha1.SecurityPolicyPeer, bool) {
+	hasSelector := entity.Selector != "" || entity.NamespaceSelector != ""
+	if len(entity.Nets) != 0 || len(entity.NotNets) != 0 {
+		if hasSelector {
+			c.result.Report.unsupported(source, field, "nets with selectors not supported, the rule not converted")
+			return nil, false
+		}
+		peers, err := calicoNetPeers(entity.Nets, entity.NotNets)
+		if err != nil {
+			c.result.Report.unsupported(source, field, "%s, the rule not converted", err)
+			return nil, false
+		}
+		if len(peers) == 0 {
+			c.result.Report.info(source, field, "nets all excluded by the notNets, the rule matches nothing and not converted")
+			return nil, false
+		}
+		return peers, true
+	}
+	if !hasSelector {
+		return nil, true
+	}
+
+	peer := securityv1alpha1.SecurityPolicyPeer{}
+	if entity.Selector != "" {
+		selector, err := parseCalicoSelector(entity.Selector)
+		if err != nil {
+			c.result.Report.unsupported(source, field+".selector", "%s, the rule not converted", err)
+			return nil, false
+		}
+		peer.EndpointSelector = labels.FromLabelSelector(selector)
+		if global {
+			peer.NamespaceSelector = &metav1.LabelSelector{}
+		}
+	}
+	if entity.NamespaceSelector != "" {
+		if strings.Contains(entity.NamespaceSelector, "global()") {
+			c.result.Report.unsupported(source, field+".namespaceSelector", "global() not supported, the rule not converted")
+			return nil, false
+		}
+		namespaceSelector, err := parseCalicoSelector(entity.NamespaceSelector)
+		if err != nil {
+			c.result.Report.unsupported(source, field+".namespaceSelector", "%s, the rule not converted", err)
+			return nil, false
+		}
+		peer.NamespaceSelector = renameNamespaceNameLabel(namespaceSelector)
+	}
+	return []securityv1alpha1.SecurityPolicyPeer{peer}, true
+}
+
+// renameNamespaceNameLabel replaces the calico namespace name label with the kubernetes one.
+func renameNamespaceNameLabel(selector *metav1.LabelSelector) *metav1.LabelSelector {
+	if value, ok := selector.MatchLabels[calicoNamespaceNameLabel]; ok {
+		delete(selector.MatchLabels, calicoNamespaceNameLabel)
+		selector.MatchLabels[namespaceNameLabel] = value
+	}
+	for i := range selector.MatchExpressions {
+		if selector.MatchExpressions[i].Key == calicoNamespaceNameLabel {
+			selector.MatchExpressions[i].Key = namespaceNameLabel
+		}
+	}
+	return selector
+}
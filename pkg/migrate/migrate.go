@@ -0,0 +1,325 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migrate converts the network policies of Antrea and Calico into the everoute
+// SecurityPolicies, and the SecurityPolicies into Kubernetes NetworkPolicies. The constructs
+// without equivalence, e.g. the deny rules in the middle of a policy, are reported instead
+// of converted.
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// SourceAnnotation on the converted policy is the kind and name of the source policy.
+const SourceAnnotation = "migrate.everoute.io/source"
+
+// Options of the conversion.
+type Options struct {
+	// Namespace of the policies converted from the cluster scoped sources, it should be
+	// the namespace of the everoute endpoints.
+	Namespace string
+	// Tier of the converted policies, tier2 if empty.
+	Tier string
+}
+
+// Result is the converted policies and the compatibility report.
+type Result struct {
+	Policies []securityv1alpha1.SecurityPolicy
+	Report   Report
+}
+
+// object is a decoded document with the type and metadata.
+type object struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Items             []json.RawMessage `json:"items,omitempty"`
+
+	raw []byte
+}
+
+func (o *object) source() string {
+	if o.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", o.Kind, o.Namespace, o.Name)
+	}
+	return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+}
+
+func (o *object) group() string {
+	if index := strings.Index(o.APIVersion, "/"); index >= 0 {
+		return o.APIVersion[:index]
+	}
+	return ""
+}
+
+// converter keeps the state among the documents.
+type converter struct {
+	options Options
+	result  *Result
+	// antreaGroups are the Antrea ClusterGroups keyed by name
+	antreaGroups map[string]*antreaClusterGroup
+}
+
+// Convert decodes the yaml or json documents, and converts the Antrea ClusterNetworkPolicies,
+// the Calico NetworkPolicies and GlobalNetworkPolicies into SecurityPolicies. SecurityPolicy
+// doesn't reference groups, the Antrea ClusterGroups are inlined into the peers.
+func Convert(data []byte, options Options) (*Result, error) {
+	if options.Namespace == "" {
+		options.Namespace = metav1.NamespaceDefault
+	}
+	if options.Tier == "" {
+		options.Tier = constants.Tier2
+	}
+
+	objects, err := decodeObjects(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{
+		options:      options,
+		result:       &Result{},
+		antreaGroups: make(map[string]*antreaClusterGroup),
+	}
+	// the groups are collected first, they may be defined after the policies
+	for _, obj := range objects {
+		if obj.group() == antreaGroup && obj.Kind == "ClusterGroup" {
+			group := &antreaClusterGroup{}
+			if err := json.Unmarshal(obj.raw, group); err != nil {
+				return nil, fmt.Errorf("decode %s: %s", obj.source(), err)
+			}
+			c.antreaGroups[group.Name] = group
+		}
+	}
+
+	for _, obj := range objects {
+		var err error
+		switch {
+		case obj.group() == antreaGroup && obj.Kind == "ClusterNetworkPolicy":
+			err = c.convertAntreaPolicy(obj)
+		case obj.group() == antreaGroup && obj.Kind == "ClusterGroup":
+		case (obj.group() == calicoGroup || obj.group() == calicoCRDGroup) &&
+			(obj.Kind == "NetworkPolicy" || obj.Kind == "GlobalNetworkPolicy"):
+			err = c.convertCalicoPolicy(obj)
+		default:
+			c.result.Report.unsupported(obj.source(), "", "%s is not supported, not converted", obj.APIVersion)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %s", obj.source(), err)
+		}
+	}
+
+	return c.result, nil
+}
+
+// decodeObjects decodes the documents, the items of the lists are flattened.
+func decodeObjects(data []byte) ([]*object, error) {
+	var objects []*object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		raw, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		items, err := decodeObject(raw)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, items...)
+	}
+}
+
+func decodeObject(raw []byte) ([]*object, error) {
+	obj := &object{raw: raw}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(obj.Kind, "List") {
+		return []*object{obj}, nil
+	}
+
+	var objects []*object
+	for _, item := range obj.Items {
+		items, err := decodeObject(item)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, items...)
+	}
+	return objects, nil
+}
+
+// policyDraft is a policy being converted, a source policy may be split into several
+// policies, as the default rule of SecurityPolicy applies to all its policy types.
+type policyDraft struct {
+	nameSuffix     string
+	appliedTo      []securityv1alpha1.ApplyToPeer
+	ingress        []securityv1alpha1.Rule
+	egress         []securityv1alpha1.Rule
+	policyTypes    []networkingv1.PolicyType
+	defaultDropped bool
+}
+
+func (c *converter) addPolicy(obj *object, namespace string, draft *policyDraft) {
+	policy := securityv1alpha1.SecurityPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: securityv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "SecurityPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        sanitizeName(obj.Name + draft.nameSuffix),
+			Namespace:   namespace,
+			Annotations: map[string]string{SourceAnnotation: obj.source()},
+		},
+		Spec: securityv1alpha1.SecurityPolicySpec{
+			Tier:         c.options.Tier,
+			AppliedTo:    draft.appliedTo,
+			IngressRules: draft.ingress,
+			EgressRules:  draft.egress,
+			DefaultRule:  securityv1alpha1.DefaultRuleNone,
+			PolicyTypes:  draft.policyTypes,
+		},
+	}
+	if draft.defaultDropped {
+		policy.Spec.DefaultRule = securityv1alpha1.DefaultRuleDrop
+	}
+	c.result.Policies = append(c.result.Policies, policy)
+}
+
+// convertedRules are the rules of a direction converted.
+type convertedRules struct {
+	// present is true if the source has the direction
+	present bool
+	rules   []securityv1alpha1.Rule
+	// dropped is true if the traffic not allowed by the rules is dropped
+	dropped bool
+}
+
+// addPolicies adds the policy with the rules of both directions, or one policy per direction
+// if only one direction drops the traffic not allowed.
+func (c *converter) addPolicies(obj *object, namespace, suffix string, appliedTo []securityv1alpha1.ApplyToPeer, ingress, egress convertedRules) {
+	if !ingress.present && !egress.present {
+		c.result.Report.info(obj.source(), "spec", "no rules, not converted")
+		return
+	}
+	if ingress.present && egress.present && ingress.dropped != egress.dropped {
+		c.addPolicies(obj, namespace, suffix+"-ingress", appliedTo, ingress, convertedRules{})
+		c.addPolicies(obj, namespace, suffix+"-egress", appliedTo, convertedRules{}, egress)
+		return
+	}
+	if len(ingress.rules) == 0 && len(egress.rules) == 0 && !ingress.dropped && !egress.dropped {
+		// the policy takes no effect, the rules not converted have been reported
+		return
+	}
+
+	draft := &policyDraft{
+		nameSuffix:     suffix,
+		appliedTo:      appliedTo,
+		ingress:        ingress.rules,
+		egress:         egress.rules,
+		defaultDropped: ingress.dropped || egress.dropped,
+	}
+	if ingress.present {
+		draft.policyTypes = append(draft.policyTypes, networkingv1.PolicyTypeIngress)
+	}
+	if egress.present {
+		draft.policyTypes = append(draft.policyTypes, networkingv1.PolicyTypeEgress)
+	}
+	c.addPolicy(obj, namespace, draft)
+}
+
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-z0-9.-]+`)
+	invalidLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// sanitizeName converts the name into a RFC 1123 subdomain.
+func sanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	return strings.Trim(name, "-.")
+}
+
+// ruleName returns the name of the rule conforms RFC 1123 label, unique in the rules.
+func ruleName(name, direction string, index int, rules []securityv1alpha1.Rule) string {
+	name = strings.Trim(invalidLabelChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	for _, rule := range rules {
+		if rule.Name == name {
+			name = ""
+		}
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%d", direction, index)
+	}
+	return name
+}
+
+// EncodePolicies writes the policies as yaml documents, without the empty status.
+func EncodePolicies(w io.Writer, policies []securityv1alpha1.SecurityPolicy) error {
+	for i := range policies {
+		if err := encodeObject(w, &policies[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeObject writes the object as a yaml document, without the status and the creationTimestamp.
+func encodeObject(w io.Writer, obj interface{}) error {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	var content map[string]interface{}
+	if err = json.Unmarshal(raw, &content); err != nil {
+		return err
+	}
+	delete(content, "status")
+	delete(content["metadata"].(map[string]interface{}), "creationTimestamp")
+
+	doc, err := yaml.Marshal(content)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "---\n%s", doc)
+	return err
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of migrate tests")
+
+// TestConvertGolden converts each testdata/*.yaml, and asserts the converted policies and
+// the report exactly the same as the golden file. Run with -update to regenerate the golden
+// files after intended changes.
+func TestConvertGolden(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil {
+		t.Fatalf("list sources: %s", err)
+	}
+
+	for _, source := range sources {
+		source := source
+		t.Run(strings.TrimSuffix(filepath.Base(source), ".yaml"), func(t *testing.T) {
+			RegisterTestingT(t)
+
+			data, err := ioutil.ReadFile(source)
+			Expect(err).ShouldNot(HaveOccurred())
+			result, err := Convert(data, Options{Namespace: "tenant"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var actual bytes.Buffer
+			Expect(EncodePolicies(&actual, result.Policies)).Should(Succeed())
+			actual.WriteString("# report\n")
+			for _, line := range strings.Split(strings.TrimSuffix(result.Report.String(), "\n"), "\n") {
+				actual.WriteString("# " + line + "\n")
+			}
+
+			golden := strings.TrimSuffix(source, ".yaml") + ".golden"
+			if *updateGolden {
+				Expect(ioutil.WriteFile(golden, actual.Bytes(), 0644)).Should(Succeed())
+			}
+			expect, err := ioutil.ReadFile(golden)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(actual.String()).Should(Equal(string(expect)))
+		})
+	}
+}
+
+// TestConvertToNetworkPoliciesGolden converts each testdata/networkpolicy/*.yaml into the
+// NetworkPolicies, and asserts them and the report exactly the same as the golden file.
+func TestConvertToNetworkPoliciesGolden(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "networkpolicy", "*.yaml"))
+	if err != nil {
+		t.Fatalf("list sources: %s", err)
+	}
+
+	for _, source := range sources {
+		source := source
+		t.Run(strings.TrimSuffix(filepath.Base(source), ".yaml"), func(t *testing.T) {
+			RegisterTestingT(t)
+
+			data, err := ioutil.ReadFile(source)
+			Expect(err).ShouldNot(HaveOccurred())
+			result, err := ConvertToNetworkPolicies(data)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var actual bytes.Buffer
+			Expect(EncodeNetworkPolicies(&actual, result.NetworkPolicies)).Should(Succeed())
+			actual.WriteString("# report\n")
+			for _, line := range strings.Split(strings.TrimSuffix(result.Report.String(), "\n"), "\n") {
+				actual.WriteString("# " + line + "\n")
+			}
+
+			golden := strings.TrimSuffix(source, ".yaml") + ".golden"
+			if *updateGolden {
+				Expect(ioutil.WriteFile(golden, actual.Bytes(), 0644)).Should(Succeed())
+			}
+			expect, err := ioutil.ReadFile(golden)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(actual.String()).Should(Equal(string(expect)))
+		})
+	}
+}
+
+func TestParseCalicoSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		expect   *metav1.LabelSelector
+		invalid  bool
+	}{
+		{selector: "", expect: &metav1.LabelSelector{}},
+		{selector: "all()", expect: &metav1.LabelSelector{}},
+		{
+			selector: `app == "web" && tier == 'fe'`,
+			expect:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", "tier": "fe"}},
+		},
+		{
+			selector: "has(a) && !has(b) && c != 'x' && d in {'1','2'} && e not in { '3' }",
+			expect: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "a", Operator: metav1.LabelSelectorOpExists},
+				{Key: "b", Operator: metav1.LabelSelectorOpDoesNotExist},
+				{Key: "c", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"x"}},
+				{Key: "d", Operator: metav1.LabelSelectorOpIn, Values: []string{"1", "2"}},
+				{Key: "e", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"3"}},
+			}},
+		},
+		{selector: "app == 'a' && app == 'b'", invalid: true},
+		{selector: "app == 'a' || app == 'b'", invalid: true},
+		{selector: "(app == 'a')", invalid: true},
+		{selector: "global()", invalid: true},
+		{selector: "app contains 'a'", invalid: true},
+		{selector: "app == 'a", invalid: true},
+		{selector: "app in {}", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			selector, err := parseCalicoSelector(tt.selector)
+			if tt.invalid {
+				Expect(err).Should(HaveOccurred())
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(selector).Should(Equal(tt.expect))
+		})
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/labels"
+)
+
+// maxExpandedPorts is the max ports of a port range expanded into the single ports, the
+// NetworkPolicy of the supported kubernetes versions has no endPort.
+const maxExpandedPorts = 32
+
+// NetworkPolicyResult is the NetworkPolicies converted from the SecurityPolicies and the
+// compatibility report.
+type NetworkPolicyResult struct {
+	NetworkPolicies []networkingv1.NetworkPolicy
+	Report          Report
+}
+
+// networkPolicyConverter converts the SecurityPolicies into the NetworkPolicies. The constructs
+// would allow more traffic after converted are never converted, they are reported instead.
+type networkPolicyConverter struct {
+	result *NetworkPolicyResult
+}
+
+// ConvertToNetworkPolicies decodes the yaml or json documents, and converts the SecurityPolicies
+// into Kubernetes NetworkPolicies, the other objects are reported as not supported.
+func ConvertToNetworkPolicies(data []byte) (*NetworkPolicyResult, error) {
+	objects, err := decodeObjects(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &networkPolicyConverter{result: &NetworkPolicyResult{}}
+	for _, obj := range objects {
+		if obj.group() != securityv1alpha1.SchemeGroupVersion.Group || obj.Kind != "SecurityPolicy" {
+			c.result.Report.unsupported(obj.source(), "", "%s %s is not supported, not converted", obj.APIVersion, obj.Kind)
+			continue
+		}
+		policy := &securityv1alpha1.SecurityPolicy{}
+		if err := json.Unmarshal(obj.raw, policy); err != nil {
+			return nil, fmt.Errorf("decode %s: %s", obj.source(), err)
+		}
+		c.convertPolicy(policy)
+	}
+	return c.result, nil
+}
+
+// ConvertSecurityPolicies converts the SecurityPolicies into Kubernetes NetworkPolicies. The
+// policies applied to several selectors are split into one NetworkPolicy per selector. The
+// peers, ports and rules could not be expressed are not converted, so the NetworkPolicies never
+// allow more traffic than the SecurityPolicies, except the symmetric rules and the tiers which
+// NetworkPolicy doesn't have, they are reported as approximated.
+func ConvertSecurityPolicies(policies []securityv1alpha1.SecurityPolicy) *NetworkPolicyResult {
+	c := &networkPolicyConverter{result: &NetworkPolicyResult{}}
+	for i := range policies {
+		c.convertPolicy(&policies[i])
+	}
+	return c.result
+}
+
+func (c *networkPolicyConverter) convertPolicy(policy *securityv1alpha1.SecurityPolicy) {
+	source := fmt.Sprintf("SecurityPolicy/%s/%s", policy.Namespace, policy.Name)
+	spec := &policy.Spec
+
+	if owner := metav1.GetControllerOf(policy); owner != nil && owner.Kind == "NetworkPolicy" {
+		c.result.Report.info(source, "metadata.ownerReferences", "converted from NetworkPolicy %s, not converted", owner.Name)
+		return
+	}
+	if spec.VRF != "" {
+		c.result.Report.unsupported(source, "spec.vrf", "vrf %s not supported, the policy not converted", spec.VRF)
+		return
+	}
+	if spec.Tier != "" && spec.Tier != constants.Tier2 {
+		c.result.Report.approximated(source, "spec.tier", "tier %s not supported, evaluated as the NetworkPolicies in %s", spec.Tier, constants.Tier2)
+	}
+	if spec.SecurityPolicyEnforcementMode == securityv1alpha1.MonitorMode {
+		c.result.Report.approximated(source, "spec.securityPolicyEnforcementMode", "monitor mode not supported, the traffic not allowed is dropped")
+	}
+	if spec.SymmetricMode {
+		c.result.Report.approximated(source, "spec.symmetricMode", "the symmetric rules of the peers not generated")
+	}
+
+	podSelectors := c.appliedTo(source, spec.AppliedTo)
+	if len(podSelectors) == 0 {
+		c.result.Report.unsupported(source, "spec.appliedTo", "no appliedTo converted, the policy not converted")
+		return
+	}
+
+	ingressEnabled, egressEnabled := policy.IsEnable()
+	var policyTypes []networkingv1.PolicyType
+	var ingress []networkingv1.NetworkPolicyIngressRule
+	var egress []networkingv1.NetworkPolicyEgressRule
+
+	if ingressEnabled {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeIngress)
+		for i := range spec.IngressRules {
+			field := fmt.Sprintf("spec.ingressRules[%d]", i)
+			if ports, peers, ok := c.rule(source, field, &spec.IngressRules[i], "from", spec.IngressRules[i].From); ok {
+				ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports, From: peers})
+			}
+		}
+	} else if len(spec.IngressRules) != 0 {
+		c.result.Report.info(source, "spec.ingressRules", "Ingress not in the policyTypes, not converted")
+	}
+	if egressEnabled {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
+		for i := range spec.EgressRules {
+			field := fmt.Sprintf("spec.egressRules[%d]", i)
+			if ports, peers, ok := c.rule(source, field, &spec.EgressRules[i], "to", spec.EgressRules[i].To); ok {
+				egress = append(egress, networkingv1.NetworkPolicyEgressRule{Ports: ports, To: peers})
+			}
+		}
+	} else if len(spec.EgressRules) != 0 {
+		c.result.Report.info(source, "spec.egressRules", "Egress not in the policyTypes, not converted")
+	}
+
+	switch spec.DefaultRule {
+	case securityv1alpha1.DefaultRuleAllow:
+		c.result.Report.info(source, "spec.defaultRule", "allow converted as the rules allow all the traffic")
+		if ingressEnabled {
+			ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{})
+		}
+		if egressEnabled {
+			egress = append(egress, networkingv1.NetworkPolicyEgressRule{})
+		}
+	case securityv1alpha1.DefaultRuleNone:
+		c.result.Report.approximated(source, "spec.defaultRule", "none not supported, the traffic not allowed is dropped instead of evaluated by the other policies")
+	}
+
+	for i, podSelector := range podSelectors {
+		name := policy.Name
+		if len(podSelectors) > 1 {
+			name = fmt.Sprintf("%s-%d", policy.Name, i)
+		}
+		c.result.NetworkPolicies = append(c.result.NetworkPolicies, networkingv1.NetworkPolicy{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: networkingv1.SchemeGroupVersion.String(),
+				Kind:       "NetworkPolicy",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   policy.Namespace,
+				Annotations: map[string]string{SourceAnnotation: source},
+			},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: podSelector,
+				Ingress:     ingress,
+				Egress:      egress,
+				PolicyTypes: policyTypes,
+			},
+		})
+	}
+}
+
+// appliedTo converts the appliedTo into the pod selectors, all the pods if empty.
+func (c *networkPolicyConverter) appliedTo(source string, appliedTo []securityv1alpha1.ApplyToPeer) []metav1.LabelSelector {
+	if len(appliedTo) == 0 {
+		return []metav1.LabelSelector{{}}
+	}
+
+	var podSelectors []metav1.LabelSelector
+	for i, item := range appliedTo {
+		field := fmt.Sprintf("spec.appliedTo[%d]", i)
+		switch {
+		case item.Endpoint != nil:
+			c.result.Report.unsupported(source, field, "endpoint %s not supported, not converted", *item.Endpoint)
+			continue
+		case item.EndpointSelector == nil:
+			c.result.Report.unsupported(source, field, "empty appliedTo, not converted")
+			continue
+		}
+		podSelector, err := toLabelSelector(item.EndpointSelector)
+		if err != nil {
+			c.result.Report.unsupported(source, field+".endpointSelector", "%s, not converted", err)
+			continue
+		}
+		if len(item.VLANs) != 0 {
+			c.result.Report.approximated(source, field+".vlans", "vlans not supported, applied to the pods of all the vlans")
+		}
+		podSelectors = append(podSelectors, *podSelector)
+	}
+	return podSelectors
+}
+
+// rule converts the ports and the peers of the rule, false if the rule not converted.
+func (c *networkPolicyConverter) rule(source, field string, rule *securityv1alpha1.Rule, peersField string,
+	peers []securityv1alpha1.SecurityPolicyPeer) ([]networkingv1.NetworkPolicyPort, []networkingv1.NetworkPolicyPeer, bool) {
+	if rule.Action == securityv1alpha1.RuleActionRedirect {
+		c.result.Report.unsupported(source, field, "Redirect rule not converted, the traffic is dropped instead of redirected")
+		return nil, nil, false
+	}
+	if rule.L7 != nil {
+		c.result.Report.unsupported(source, field+".l7", "l7 criteria not supported, the rule not converted")
+		return nil, nil, false
+	}
+
+	ports := c.ports(source, field, rule.Ports)
+	if len(rule.Ports) != 0 && len(ports) == 0 {
+		c.result.Report.unsupported(source, field, "no ports converted, the rule not converted")
+		return nil, nil, false
+	}
+
+	var converted []networkingv1.NetworkPolicyPeer
+	for i := range peers {
+		if peer, ok := c.peer(source, fmt.Sprintf("%s.%s[%d]", field, peersField, i), &peers[i]); ok {
+			converted = append(converted, peer)
+		}
+	}
+	if len(peers) != 0 && len(converted) == 0 {
+		c.result.Report.unsupported(source, field, "no peers converted, the rule not converted")
+		return nil, nil, false
+	}
+	return ports, converted, true
+}
+
+// ports converts the ports, the port ranges are expanded into the single ports.
+func (c *networkPolicyConverter) ports(source, field string, ports []securityv1alpha1.SecurityPolicyPort) []networkingv1.NetworkPolicyPort {
+	var converted []networkingv1.NetworkPolicyPort
+	for i, port := range ports {
+		portField := fmt.Sprintf("%s.ports[%d]", field, i)
+		if port.Protocol != securityv1alpha1.ProtocolTCP && port.Protocol != securityv1alpha1.ProtocolUDP {
+			c.result.Report.unsupported(source, portField, "protocol %s not supported, not converted", port.Protocol)
+			continue
+		}
+		protocol := corev1.Protocol(port.Protocol)
+
+		if port.PortRange == "" {
+			converted = append(converted, networkingv1.NetworkPolicyPort{Protocol: &protocol})
+			continue
+		}
+		for _, item := range strings.Split(port.PortRange, ",") {
+			if port.Type == securityv1alpha1.PortTypeName {
+				name := intstr.FromString(item)
+				converted = append(converted, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &name})
+				continue
+			}
+
+			begin, end, err := parsePortRange(item)
+			if err != nil {
+				c.result.Report.unsupported(source, portField, "%s, not converted", err)
+				continue
+			}
+			if end-begin+1 > maxExpandedPorts {
+				c.result.Report.unsupported(source, portField, "port range %s exceeds %d ports, not converted", item, maxExpandedPorts)
+				continue
+			}
+			for number := begin; number <= end; number++ {
+				portNumber := intstr.FromInt(number)
+				converted = append(converted, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &portNumber})
+			}
+		}
+	}
+	return converted
+}
+
+// parsePortRange parses the port like 80 or the port range like 80-90.
+func parsePortRange(portRange string) (int, int, error) {
+	items := strings.SplitN(portRange, "-", 2)
+	begin, err := strconv.Atoi(items[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", portRange)
+	}
+	if len(items) == 1 {
+		return begin, begin, nil
+	}
+	end, err := strconv.Atoi(items[1])
+	if err != nil || end < begin {
+		return 0, 0, fmt.Errorf("invalid port range %q", portRange)
+	}
+	return begin, end, nil
+}
+
+// peer converts the peer, false if not supported.
+func (c *networkPolicyConverter) peer(source, field string, peer *securityv1alpha1.SecurityPolicyPeer) (networkingv1.NetworkPolicyPeer, bool) {
+	switch {
+	case peer.Builtin != "":
+		c.result.Report.unsupported(source, field, "builtin peer %s not supported, not converted", peer.Builtin)
+	case peer.FQDN != "":
+		c.result.Report.unsupported(source, field, "fqdn %s not supported, not converted", peer.FQDN)
+	case peer.Endpoint != nil:
+		c.result.Report.unsupported(source, field, "endpoint %s not supported, not converted", peer.Endpoint)
+	case peer.ServiceAccount != "":
+		c.result.Report.unsupported(source, field, "serviceAccount %s not supported, not converted", peer.ServiceAccount)
+	case len(peer.VLANs) != 0:
+		c.result.Report.unsupported(source, field, "vlans not supported, not converted")
+	case peer.IPBlock != nil:
+		return networkingv1.NetworkPolicyPeer{IPBlock: peer.IPBlock.DeepCopy()}, true
+	case peer.EndpointSelector != nil || peer.NamespaceSelector != nil:
+		podSelector, err := toLabelSelector(peer.EndpointSelector)
+		if err != nil {
+			c.result.Report.unsupported(source, field+".endpointSelector", "%s, not converted", err)
+			break
+		}
+		return networkingv1.NetworkPolicyPeer{PodSelector: podSelector, NamespaceSelector: peer.NamespaceSelector.DeepCopy()}, true
+	default:
+		c.result.Report.unsupported(source, field, "empty peer, not converted")
+	}
+	return networkingv1.NetworkPolicyPeer{}, false
+}
+
+// toLabelSelector converts the everoute selector into the label selector, the pods have only
+// one value of a label, so the extendMatchLabels are supported only with one value.
+func toLabelSelector(selector *labels.Selector) (*metav1.LabelSelector, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	if selector.MatchNothing {
+		return nil, fmt.Errorf("matchNothing not supported")
+	}
+
+	labelSelector := selector.LabelSelector.DeepCopy()
+	for key, values := range selector.ExtendMatchLabels {
+		if value, ok := labelSelector.MatchLabels[key]; len(values) != 1 || (ok && value != values[0]) {
+			return nil, fmt.Errorf("extendMatchLabels %s with multiple values not supported", key)
+		}
+		if labelSelector.MatchLabels == nil {
+			labelSelector.MatchLabels = make(map[string]string)
+		}
+		labelSelector.MatchLabels[key] = values[0]
+	}
+	return labelSelector, nil
+}
+
+// EncodeNetworkPolicies writes the NetworkPolicies as yaml documents.
+func EncodeNetworkPolicies(w io.Writer, policies []networkingv1.NetworkPolicy) error {
+	for i := range policies {
+		if err := encodeObject(w, &policies[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity of the compatibility issue.
+type Severity string
+
+const (
+	// SeverityUnsupported the construct is not converted, the traffic it allows is denied,
+	// or the traffic it denies is allowed if matched by the other rules.
+	SeverityUnsupported Severity = "Unsupported"
+	// SeverityApproximated the construct is converted with different semantics.
+	SeverityApproximated Severity = "Approximated"
+	// SeverityInfo the construct has no effect after converted.
+	SeverityInfo Severity = "Info"
+)
+
+// Issue is a construct of the source policies could not be converted exactly.
+type Issue struct {
+	// Source is the kind, namespace and name of the source object.
+	Source string
+	// Field is the path of the construct in the source object.
+	Field    string
+	Severity Severity
+	Message  string
+}
+
+// Report is the compatibility report of the conversion.
+type Report struct {
+	Issues []Issue
+}
+
+func (r *Report) add(severity Severity, source, field, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{
+		Source:   source,
+		Field:    field,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *Report) unsupported(source, field, format string, args ...interface{}) {
+	r.add(SeverityUnsupported, source, field, format, args...)
+}
+
+func (r *Report) approximated(source, field, format string, args ...interface{}) {
+	r.add(SeverityApproximated, source, field, format, args...)
+}
+
+func (r *Report) info(source, field, format string, args ...interface{}) {
+	r.add(SeverityInfo, source, field, format, args...)
+}
+
+// Count returns the number of the issues with the severity.
+func (r *Report) Count(severity Severity) int {
+	var count int
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// String returns the issues one per line, followed by the summary.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, issue := range r.Issues {
+		source := issue.Source
+		if issue.Field != "" {
+			source += " " + issue.Field
+		}
+		fmt.Fprintf(&b, "%-12s %s: %s\n", issue.Severity, source, issue.Message)
+	}
+	fmt.Fprintf(&b, "%d unsupported, %d approximated, %d info\n",
+		r.Count(SeverityUnsupported), r.Count(SeverityApproximated), r.Count(SeverityInfo))
+	return b.String()
+}
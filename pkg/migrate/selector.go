@@ -0,0 +1,195 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// parseCalicoSelector converts the calico selector expression into the label selector. Only
+// the conjunctions of the label matches are supported: all(), has(k), !has(k), k == 'v',
+// k != 'v', k in {'v1', 'v2'} and k not in {'v1', 'v2'}, joined by &&.
+func parseCalicoSelector(selector string) (*metav1.LabelSelector, error) {
+	p := &selectorParser{input: selector}
+	labelSelector := &metav1.LabelSelector{}
+
+	p.skipSpaces()
+	if p.eof() {
+		return labelSelector, nil
+	}
+	for {
+		if err := p.parseTerm(labelSelector); err != nil {
+			return nil, fmt.Errorf("unsupported selector %q: %s", selector, err)
+		}
+		p.skipSpaces()
+		if p.eof() {
+			return labelSelector, nil
+		}
+		if !p.consume("&&") {
+			return nil, fmt.Errorf("unsupported selector %q: expect && at %d", selector, p.pos)
+		}
+	}
+}
+
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *selectorParser) skipSpaces() {
+	for !p.eof() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the spaces and the token if present.
+func (p *selectorParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *selectorParser) parseTerm(labelSelector *metav1.LabelSelector) error {
+	switch {
+	case p.consume("all()"):
+		return nil
+	case p.consume("!has("):
+		return p.parseHas(labelSelector, metav1.LabelSelectorOpDoesNotExist)
+	case p.consume("has("):
+		return p.parseHas(labelSelector, metav1.LabelSelectorOpExists)
+	}
+
+	key := p.parseKey()
+	if key == "" {
+		return fmt.Errorf("expect label key at %d", p.pos)
+	}
+	switch {
+	case p.consume("=="):
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		if labelSelector.MatchLabels == nil {
+			labelSelector.MatchLabels = make(map[string]string)
+		}
+		if origin, ok := labelSelector.MatchLabels[key]; ok && origin != value {
+			// k == v1 && k == v2 matches nothing, could not be expressed by match labels
+			return fmt.Errorf("conflict values of %s", key)
+		}
+		labelSelector.MatchLabels[key] = value
+	case p.consume("!="):
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		p.addExpression(labelSelector, key, metav1.LabelSelectorOpNotIn, []string{value})
+	case p.consume("not in"):
+		values, err := p.parseValues()
+		if err != nil {
+			return err
+		}
+		p.addExpression(labelSelector, key, metav1.LabelSelectorOpNotIn, values)
+	case p.consume("in"):
+		values, err := p.parseValues()
+		if err != nil {
+			return err
+		}
+		p.addExpression(labelSelector, key, metav1.LabelSelectorOpIn, values)
+	default:
+		return fmt.Errorf("unsupported operator at %d", p.pos)
+	}
+	return nil
+}
+
+func (p *selectorParser) parseHas(labelSelector *metav1.LabelSelector, operator metav1.LabelSelectorOperator) error {
+	key := p.parseKey()
+	if key == "" || !p.consume(")") {
+		return fmt.Errorf("expect has(<key>) at %d", p.pos)
+	}
+	p.addExpression(labelSelector, key, operator, nil)
+	return nil
+}
+
+func (p *selectorParser) addExpression(labelSelector *metav1.LabelSelector, key string, operator metav1.LabelSelectorOperator, values []string) {
+	labelSelector.MatchExpressions = append(labelSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      key,
+		Operator: operator,
+		Values:   values,
+	})
+}
+
+// parseKey parses the label key, the characters allowed in the label keys.
+func (p *selectorParser) parseKey() string {
+	p.skipSpaces()
+	start := p.pos
+	for !p.eof() {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("-_./", c) {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// parseValue parses the value quoted by ' or ".
+func (p *selectorParser) parseValue() (string, error) {
+	p.skipSpaces()
+	if p.eof() || (p.input[p.pos] != '\'' && p.input[p.pos] != '"') {
+		return "", fmt.Errorf("expect quoted value at %d", p.pos)
+	}
+	quote := p.input[p.pos]
+	end := strings.IndexByte(p.input[p.pos+1:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated value at %d", p.pos)
+	}
+	value := p.input[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return value, nil
+}
+
+// parseValues parses the values in the form {'v1', 'v2'}, at least one value.
+func (p *selectorParser) parseValues() ([]string, error) {
+	if !p.consume("{") {
+		return nil, fmt.Errorf("expect { at %d", p.pos)
+	}
+	var values []string
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.consume("}") {
+			return values, nil
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expect , or } at %d", p.pos)
+		}
+	}
+}
@@ -18,9 +18,12 @@ package monitor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,15 +32,22 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/lldp"
+	"github.com/everoute/everoute/pkg/agent/mirror"
 	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset"
 	client "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/typed/agent/v1alpha1"
 	informer "github.com/everoute/everoute/pkg/client/informers_generated/externalversions/agent/v1alpha1"
+	ererrors "github.com/everoute/everoute/pkg/errors"
 	"github.com/everoute/everoute/pkg/types"
 	"github.com/everoute/everoute/pkg/utils"
 )
@@ -48,9 +58,22 @@ const (
 	InterfaceDriver       = "driver_name"
 	InterfaceStatus       = "status"
 	AgentInfoSyncInterval = 60
+	IPAgingCheckInterval  = 10
+
+	// DefaultIPAgingTime is the time a learned ip kept without learned again, same as the
+	// timeout the controller cleans the ip of the endpoints.
+	DefaultIPAgingTime = 30 * time.Minute
+
+	// DefaultSyncDebounce and DefaultSyncMaxDelay coalesce the agentinfo syncs on the bursts
+	// of the port changes.
+	DefaultSyncDebounce = 500 * time.Millisecond
+	DefaultSyncMaxDelay = 5 * time.Second
 
 	VMNicDriver  = "tun"
 	PodNicDriver = "veth"
+
+	// AgentInfoFieldManager is the field manager of the agentinfo applied by the agent.
+	AgentInfoFieldManager = "everoute-agent"
 )
 
 // AgentMonitor monitor agent state, update agentinfo to apiserver.
@@ -60,13 +83,90 @@ type AgentMonitor struct {
 	ovsdbMonitor  *OVSDBMonitor             // ovsdbMonitor used to access ovsdb cache
 
 	// agentName is the name and uuid of this agent
-	agentName           string
-	ipCacheLock         sync.RWMutex
+	agentName   string
+	ipCacheLock sync.RWMutex
+	// ipCache is the learned ips keyed by the interface uuid, the ofport of an interface
+	// may be reassigned to another one after the interface re-created.
 	ipCache             map[string]map[types.IPAddress]metav1.Time
 	ofportIPMonitorChan chan map[string]net.IP
+	// ipLeases is the lease expiry of the ips learned from the dhcp acks keyed by the interface
+	// uuid, the leased ips expire at the end of the lease instead of the IPAgingTime.
+	ipLeases map[string]map[types.IPAddress]metav1.Time
+	// ipSources is the source of the ips in the ipCache keyed by the interface uuid, the one
+	// with the highest priority if learned from multiple sources.
+	ipSources map[string]map[types.IPAddress]agentv1alpha1.IPSource
+	// ipCacheRestored is true after the ips in the control plane loaded into the ipCache,
+	// only accessed with ipCacheLock held
+	ipCacheRestored bool
+
+	// IPAgingTime is the time a learned ip removed from the ipCache if not learned again,
+	// the ips of the interface are accumulated until aged out.
+	IPAgingTime time.Duration
+
+	// DHCPLeases receives the leases snooped by the datapath, the ips are learned with the
+	// lease expiry. Nothing is learned from dhcp if nil.
+	DHCPLeases <-chan datapath.DHCPLease
+
+	// IPSourcePolicy decides the source of the ips learned from multiple sources, and the
+	// ips of the interface reported in the agentinfo.
+	IPSourcePolicy *IPSourcePolicy
 
 	// syncQueue used to notify agentMonitor synchronize AgentInfo
 	syncQueue workqueue.RateLimitingInterface
+	// syncPendingSince and syncLastChange are the time of the first and the last change
+	// not synced yet, only accessed in the sync worker
+	syncPendingSince time.Time
+	syncLastChange   time.Time
+
+	// SyncDebounce and SyncMaxDelay coalesce the changes into one agentinfo sync, the sync
+	// waits for no changes in the SyncDebounce, but no longer than the SyncMaxDelay since
+	// the first change. The agentinfo is synced on each change if SyncDebounce is zero.
+	SyncDebounce time.Duration
+	SyncMaxDelay time.Duration
+
+	// flowUsage return the current flows and limit of each datapath, replaced in testing
+	flowUsage func() ([]datapathFlowUsage, error)
+	// fdbShow return the learned macs of the bridge, replaced in testing
+	fdbShow func(bridge string) ([]appctl.FDBEntry, error)
+	// lldpNeighbors return the lldp neighbors of the interfaces, replaced in testing
+	lldpNeighbors func() (map[string]agentv1alpha1.LLDPNeighbor, error)
+	// ovsdbProbe return error if the ovsdb-server unreachable, replaced in testing
+	ovsdbProbe func() error
+
+	// OpenflowConnected and FlowsSynced report the status of the datapath in the conditions
+	// OpenflowConnectionUp and PolicySynced, the conditions are not reported if nil.
+	OpenflowConnected func() bool
+	FlowsSynced       func() bool
+
+	// ConnectionStats return the connection stats of the ips polled from conntrack, the stats
+	// of the interfaces are not reported if nil.
+	ConnectionStats func() map[types.IPAddress]agentv1alpha1.ConnectionStats
+
+	// ConntrackZones return the policy conntrack zones keyed by the vds bridge name, the zones
+	// of the bridges are not reported if nil.
+	ConntrackZones func() map[string]uint16
+
+	// PolicyRuleStats return the counters of the policy rules, the counters are not reported
+	// if nil.
+	PolicyRuleStats func() []agentv1alpha1.PolicyRuleStats
+
+	// Features are the optional features enabled on the agent, reported in the agentinfo.
+	Features []agentv1alpha1.AgentFeature
+
+	// RuleFeatures are the features of the policy rules supported by the agent, reported in the
+	// agentinfo, the controller reports the policies with the rules the agent skips.
+	RuleFeatures []securityv1alpha1.RuleFeature
+
+	// Capacity is the limits of the agent, not reported if nil.
+	Capacity *agentv1alpha1.AgentCapacity
+
+	// VRFs return the vrfs keyed by the vds bridge name, the bridges not in it are reported in
+	// the default vrf.
+	VRFs func() map[string]string
+
+	// OVNInterop coexist with OVN on the host, the logical ports bound by ovn-controller
+	// are not reported, the controller would not claim them as endpoints.
+	OVNInterop bool
 }
 
 // NewAgentMonitor return a new agentMonitor with kubernetes client and ipMonitor.
@@ -77,9 +177,19 @@ func NewAgentMonitor(clientset clientset.Interface, ovsdbMonitor *OVSDBMonitor,
 		agentName:           utils.CurrentAgentName(),
 		ipCacheLock:         sync.RWMutex{},
 		ipCache:             make(map[string]map[types.IPAddress]metav1.Time),
+		ipLeases:            make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:           make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
 		ofportIPMonitorChan: ofportIPMonitorChan,
+		IPAgingTime:         DefaultIPAgingTime,
+		IPSourcePolicy:      DefaultIPSourcePolicy,
+		SyncDebounce:        DefaultSyncDebounce,
+		SyncMaxDelay:        DefaultSyncMaxDelay,
 		ovsdbMonitor:        ovsdbMonitor,
 		syncQueue:           ovsdbMonitor.GetSyncQueue(),
+		flowUsage:           getDatapathFlowUsage,
+		fdbShow:             appctl.ShowFDB,
+		lldpNeighbors:       lldp.Neighbors,
+		ovsdbProbe:          probeOvsdbServer,
 	}
 }
 
@@ -93,6 +203,7 @@ func (monitor *AgentMonitor) Run(stopChan <-chan struct{}) {
 	go monitor.handleOfPortIPAddressUpdate(monitor.ofportIPMonitorChan, stopChan)
 	go wait.Until(monitor.syncAgentInfoWorker, 0, stopChan)
 	go monitor.periodicallySyncAgentInfo(AgentInfoSyncInterval, stopChan)
+	go monitor.periodicallyAgingIPCache(IPAgingCheckInterval, stopChan)
 	<-stopChan
 }
 
@@ -101,6 +212,8 @@ func (monitor *AgentMonitor) handleOfPortIPAddressUpdate(ofPortIPAddressMonitorC
 		select {
 		case localEndpointInfo := <-ofPortIPAddressMonitorChan:
 			monitor.updateOfPortIPAddress(localEndpointInfo)
+		case lease := <-monitor.DHCPLeases:
+			monitor.updateDHCPLease(lease)
 		case <-stopChan:
 			return
 		}
@@ -111,22 +224,228 @@ func (monitor *AgentMonitor) updateOfPortIPAddress(localEndpointInfo map[string]
 	monitor.ipCacheLock.Lock()
 	defer monitor.ipCacheLock.Unlock()
 
-	for bridgePort, ip := range localEndpointInfo {
+	monitor.learnIPLocked(localEndpointInfo, agentv1alpha1.IPSourceARP, time.Now())
+
+	// only notify sync agentinfo on new address
+	if monitor.shouldSyncOnLearnIPLocked() {
+		monitor.syncQueue.Add(monitor.Name())
+	}
+}
+
+// updateDHCPLease learns the ip of the lease snooped by the datapath.
+func (monitor *AgentMonitor) updateDHCPLease(lease datapath.DHCPLease) {
+	monitor.ipCacheLock.Lock()
+	defer monitor.ipCacheLock.Unlock()
+
+	monitor.learnLeaseLocked(lease, time.Now())
+
+	// the lease expiry changes on each renewal, always notify sync agentinfo
+	monitor.syncQueue.Add(monitor.Name())
+}
+
+// learnLeaseLocked learns the ip of the lease, and expires it at the end of the lease. The ip
+// of the infinite lease ages out as the ips learned from arp.
+func (monitor *AgentMonitor) learnLeaseLocked(lease datapath.DHCPLease, now time.Time) {
+	monitor.learnIPLocked(map[string]net.IP{lease.InterfaceUUID: lease.IP}, agentv1alpha1.IPSourceDHCP, now)
+	ip := types.IPAddress(lease.IP.String())
+	if _, ok := monitor.ipCache[lease.InterfaceUUID][ip]; !ok {
+		return
+	}
+
+	delete(monitor.ipLeases[lease.InterfaceUUID], ip)
+	if lease.LeaseTime != 0 {
+		if _, ok := monitor.ipLeases[lease.InterfaceUUID]; !ok {
+			monitor.ipLeases[lease.InterfaceUUID] = make(map[types.IPAddress]metav1.Time)
+		}
+		monitor.ipLeases[lease.InterfaceUUID][ip] = metav1.NewTime(now.Add(lease.LeaseTime))
+	}
+	if len(monitor.ipLeases[lease.InterfaceUUID]) == 0 {
+		delete(monitor.ipLeases, lease.InterfaceUUID)
+	}
+}
+
+// learnIPLocked adds the ips into the ipCache of the interface, or refreshes the learned
+// time of them. An interface may have multiple ips, e.g. the vm with secondary ips.
+func (monitor *AgentMonitor) learnIPLocked(localEndpointInfo map[string]net.IP, source agentv1alpha1.IPSource, now time.Time) {
+	for key, ip := range localEndpointInfo {
 		if !ip.IsGlobalUnicast() {
 			continue
 		}
-		if _, ok := monitor.ipCache[bridgePort]; !ok {
-			monitor.ipCache[bridgePort] = make(map[types.IPAddress]metav1.Time)
+		ifaceUUID, ok := monitor.resolveIPCacheKey(key)
+		if !ok {
+			klog.V(2).Infof("drop ip %s learned on %s, no interface found on the ofport", ip, key)
+			continue
 		}
-		monitor.ipCache[bridgePort] = map[types.IPAddress]metav1.Time{
-			types.IPAddress(ip.String()): metav1.NewTime(time.Now()),
+		if _, ok := monitor.ipCache[ifaceUUID]; !ok {
+			monitor.ipCache[ifaceUUID] = make(map[types.IPAddress]metav1.Time)
 		}
+		monitor.ipCache[ifaceUUID][types.IPAddress(ip.String())] = metav1.NewTime(now)
+		monitor.setIPSourceLocked(ifaceUUID, types.IPAddress(ip.String()), source)
 	}
+	monitor.observeIPCacheLocked()
+}
 
-	// only notify sync agentinfo on new address
-	if monitor.shouldSyncOnLearnIPLocked() {
-		monitor.syncQueue.Add(monitor.Name())
+// setIPSourceLocked records the source of the ip, unless learned from a source with the
+// higher priority before.
+func (monitor *AgentMonitor) setIPSourceLocked(ifaceUUID string, ip types.IPAddress, source agentv1alpha1.IPSource) {
+	if !monitor.IPSourcePolicy.Prefer(source, monitor.ipSources[ifaceUUID][ip]) {
+		return
+	}
+	if _, ok := monitor.ipSources[ifaceUUID]; !ok {
+		monitor.ipSources[ifaceUUID] = make(map[types.IPAddress]agentv1alpha1.IPSource)
+	}
+	monitor.ipSources[ifaceUUID][ip] = source
+}
+
+// reportedIPSourcesLocked returns the sources of the ips of the interface reported in the
+// agentinfo, merged by the IPSourcePolicy.
+func (monitor *AgentMonitor) reportedIPSourcesLocked(ifaceUUID string) map[types.IPAddress]agentv1alpha1.IPSource {
+	sources := make(map[types.IPAddress]agentv1alpha1.IPSource, len(monitor.ipCache[ifaceUUID]))
+	for ip := range monitor.ipCache[ifaceUUID] {
+		source, ok := monitor.ipSources[ifaceUUID][ip]
+		if !ok {
+			// the ips learned before the sources recorded
+			source = agentv1alpha1.IPSourceARP
+		}
+		sources[ip] = source
+	}
+	return monitor.IPSourcePolicy.Merge(sources)
+}
+
+// resolveIPCacheKey returns the interface uuid of the learned ip key. The key in legacy
+// format "bridge-ofport" is migrated to the uuid of the interface on the ofport now,
+// return false if no such interface.
+func (monitor *AgentMonitor) resolveIPCacheKey(key string) (string, bool) {
+	bridgeName, ofport, legacy := parseLegacyIPCacheKey(key)
+	if !legacy {
+		return key, true
+	}
+	if monitor.ovsdbMonitor == nil {
+		return "", false
+	}
+
+	var ifaceUUID string
+	_ = monitor.ovsdbMonitor.LockedAccessCache(func(ovsdbCache OVSDBCache) error {
+		ifaceRefs := listInterfaceRefs(ovsdbCache)
+		if _, ok := ifaceRefs[key]; ok {
+			// the interface uuid looks like the legacy key
+			ifaceUUID = key
+			return nil
+		}
+		for uuid, ref := range ifaceRefs {
+			if ref.bridge == bridgeName && ref.ofport == ofport {
+				ifaceUUID = uuid
+				return nil
+			}
+		}
+		return nil
+	})
+	return ifaceUUID, ifaceUUID != ""
+}
+
+// parseLegacyIPCacheKey parses the key in format "bridge-ofport".
+func parseLegacyIPCacheKey(key string) (string, int32, bool) {
+	index := strings.LastIndex(key, "-")
+	if index <= 0 {
+		return "", 0, false
+	}
+	ofport, err := strconv.ParseInt(key[index+1:], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return key[:index], int32(ofport), true
+}
+
+// interfaceRef is the bridge, port, name, mac and ofport of an interface in the ovsdb.
+type interfaceRef struct {
+	bridge string
+	port   string
+	name   string
+	mac    string
+	ofport int32
+}
+
+// listInterfaceRefs returns the interfaces attached to the bridges, keyed by the interface uuid.
+func listInterfaceRefs(ovsdbCache OVSDBCache) map[string]interfaceRef {
+	ifaceRefs := make(map[string]interfaceRef)
+	for bridgeUUID, ovsBri := range ovsdbCache["Bridge"] {
+		bridgeReader := newRowReader(OvsDBBridgeTable, bridgeUUID, ovsBri)
+		bridgeName := bridgeReader.String("name")
+		for _, portUUID := range bridgeReader.UUIDs("ports") {
+			ovsPort, ok := ovsdbCache["Port"][portUUID.GoUuid]
+			if !ok {
+				continue
+			}
+			portReader := newRowReader(OvsDBPortTable, portUUID.GoUuid, ovsPort)
+			portName := portReader.String("name")
+			for _, ifaceUUID := range portReader.UUIDs("interfaces") {
+				ovsIface, ok := ovsdbCache["Interface"][ifaceUUID.GoUuid]
+				if !ok {
+					continue
+				}
+				ifaceReader := newRowReader(OvsDBInterfaceTable, ifaceUUID.GoUuid, ovsIface)
+				ref := interfaceRef{bridge: bridgeName, port: portName, name: ifaceReader.String("name"), ofport: -1}
+				if mac, ok := ifaceReader.StringMap("external_ids")[LocalEndpointIdentity]; ok {
+					ref.mac = mac
+				} else {
+					ref.mac = ifaceReader.String("mac_in_use")
+				}
+				if ofport, ok := ifaceReader.Float("ofport"); ok {
+					ref.ofport = int32(ofport)
+				}
+				ifaceRefs[ifaceUUID.GoUuid] = ref
+			}
+		}
+	}
+	return ifaceRefs
+}
+
+// agingIPCacheLocked removes the ips not learned in the IPAgingTime and the ips of the leases
+// expired from the ipCache, return true if any ip removed.
+func (monitor *AgentMonitor) agingIPCacheLocked(now time.Time) bool {
+	var agedOut bool
+	for ifaceUUID, ipMap := range monitor.ipCache {
+		for ip, learnTime := range ipMap {
+			if monitor.isIPExpiredLocked(ifaceUUID, ip, learnTime, now) {
+				klog.V(2).Infof("learned ip %s of interface %s aged out, last learned at %s", ip, ifaceUUID, learnTime)
+				delete(ipMap, ip)
+				delete(monitor.ipLeases[ifaceUUID], ip)
+				delete(monitor.ipSources[ifaceUUID], ip)
+				agedOut = true
+			}
+		}
+		if len(ipMap) == 0 {
+			delete(monitor.ipCache, ifaceUUID)
+		}
+		if len(monitor.ipLeases[ifaceUUID]) == 0 {
+			delete(monitor.ipLeases, ifaceUUID)
+		}
+		if len(monitor.ipSources[ifaceUUID]) == 0 {
+			delete(monitor.ipSources, ifaceUUID)
+		}
+	}
+	monitor.observeIPCacheLocked()
+	return agedOut
+}
+
+func (monitor *AgentMonitor) observeIPCacheLocked() {
+	var ipNum int
+	for _, ipMap := range monitor.ipCache {
+		ipNum += len(ipMap)
+	}
+	learnedIPs.Set(float64(ipNum))
+}
+
+func (monitor *AgentMonitor) isIPAgedOut(learnTime metav1.Time, now time.Time) bool {
+	return monitor.IPAgingTime > 0 && now.Sub(learnTime.Time) > monitor.IPAgingTime
+}
+
+// isIPExpiredLocked returns true if the lease of the ip expired, or the ip without lease aged out.
+func (monitor *AgentMonitor) isIPExpiredLocked(ifaceUUID string, ip types.IPAddress, learnTime metav1.Time, now time.Time) bool {
+	if expiry, ok := monitor.ipLeases[ifaceUUID][ip]; ok {
+		return now.After(expiry.Time)
 	}
+	return monitor.isIPAgedOut(learnTime, now)
 }
 
 func (monitor *AgentMonitor) shouldSyncOnLearnIPLocked() bool {
@@ -136,27 +455,46 @@ func (monitor *AgentMonitor) shouldSyncOnLearnIPLocked() bool {
 		return true
 	}
 
-	// stats agentinfo contains ipmap count in the monitor.ipCache
-	var agentInfoContainsIPMapCount int
-
+	type ifaceName struct{ bridge, name string }
+	agentInfoIPSources := make(map[ifaceName]map[types.IPAddress]agentv1alpha1.IPSource)
 	for _, bridge := range agentInfo.OVSInfo.Bridges {
 		for _, port := range bridge.Ports {
 			for _, iface := range port.Interfaces {
-				cacheIPMap, ok := monitor.ipCache[fmt.Sprintf("%s-%d", bridge.Name, iface.Ofport)]
-				if !ok {
-					continue
-				}
-				for ip := range cacheIPMap {
-					if _, ok = iface.IPMap[ip]; !ok {
-						return true
-					}
+				ipSources := make(map[types.IPAddress]agentv1alpha1.IPSource, len(iface.IPMap))
+				for ip := range iface.IPMap {
+					ipSources[ip] = iface.IPSourceMap[ip]
 				}
-				agentInfoContainsIPMapCount++
+				agentInfoIPSources[ifaceName{bridge.Name, iface.Name}] = ipSources
+			}
+		}
+	}
+
+	var ifaceRefs map[string]interfaceRef
+	_ = monitor.ovsdbMonitor.LockedAccessCache(func(ovsdbCache OVSDBCache) error {
+		ifaceRefs = listInterfaceRefs(ovsdbCache)
+		return nil
+	})
+
+	for ifaceUUID := range monitor.ipCache {
+		ref, ok := ifaceRefs[ifaceUUID]
+		if !ok {
+			// the interface has been removed, its ips would not be reported
+			continue
+		}
+		ipSources, ok := agentInfoIPSources[ifaceName{ref.bridge, ref.name}]
+		if !ok {
+			return true
+		}
+		// the ips not reported by the merge policy are ignored, the ips reported from
+		// another source are synced
+		for ip, source := range monitor.reportedIPSourcesLocked(ifaceUUID) {
+			if reported, ok := ipSources[ip]; !ok || reported != source {
+				return true
 			}
 		}
 	}
 
-	return agentInfoContainsIPMapCount != len(monitor.ipCache)
+	return false
 }
 
 func (monitor *AgentMonitor) periodicallySyncAgentInfo(cycle int, stopChan <-chan struct{}) {
@@ -171,6 +509,26 @@ func (monitor *AgentMonitor) periodicallySyncAgentInfo(cycle int, stopChan <-cha
 	}
 }
 
+// periodicallyAgingIPCache removes the aged out ips, and notify sync agentinfo to remove
+// them from the control plane without waiting for the next periodically sync.
+func (monitor *AgentMonitor) periodicallyAgingIPCache(cycle int, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(cycle) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			monitor.ipCacheLock.Lock()
+			agedOut := monitor.agingIPCacheLocked(time.Now())
+			monitor.ipCacheLock.Unlock()
+			if agedOut {
+				monitor.syncQueue.Add(monitor.Name())
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
+
 func (monitor *AgentMonitor) syncAgentInfoWorker() {
 	item, shutdown := monitor.syncQueue.Get()
 	if shutdown {
@@ -178,48 +536,112 @@ func (monitor *AgentMonitor) syncAgentInfoWorker() {
 	}
 	defer monitor.syncQueue.Done(item)
 
-	if err := monitor.syncAgentInfo(); err != nil {
+	delay, shouldSync := monitor.coalesceSync(item, time.Now())
+	if delay > 0 {
+		monitor.syncQueue.AddAfter(agentInfoSyncDeadline{}, delay)
+	}
+	if !shouldSync {
+		return
+	}
+
+	start := time.Now()
+	err := monitor.syncAgentInfo()
+	agentInfoSyncs.Inc()
+	agentInfoSyncDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
 		monitor.syncQueue.AddAfter(monitor.Name(), time.Second)
-		if errors.IsConflict(err) {
-			klog.V(4).Infof("conflict update agentinfo %s: %s", monitor.Name(), err)
-		} else {
-			klog.Errorf("sync agentinfo %s: %s", monitor.Name(), err)
+		agentInfoSyncErrors.WithLabelValues(syncErrorTypeError).Inc()
+		klog.Errorf("sync agentinfo %s: %s", monitor.Name(), err)
+	}
+}
+
+// agentInfoSyncDeadline is queued to sync the agentinfo at the end of the coalescing, it's
+// distinguished from the changes queued with the agent name.
+type agentInfoSyncDeadline struct{}
+
+// coalesceSync records the change of the queued item, returns whether to sync the agentinfo
+// now, or the delay before the next check. The deadline item is deduplicated in the queue
+// with the earliest delay, and queued again on fire if more changes come in.
+func (monitor *AgentMonitor) coalesceSync(item interface{}, now time.Time) (time.Duration, bool) {
+	if monitor.SyncDebounce <= 0 {
+		return 0, true
+	}
+
+	if _, ok := item.(agentInfoSyncDeadline); !ok {
+		if monitor.syncPendingSince.IsZero() {
+			monitor.syncPendingSince = now
 		}
+		monitor.syncLastChange = now
+	}
+	if monitor.syncPendingSince.IsZero() {
+		// the changes have been synced before the deadline
+		return 0, false
 	}
+
+	deadline := monitor.syncLastChange.Add(monitor.SyncDebounce)
+	if maxDeadline := monitor.syncPendingSince.Add(monitor.SyncMaxDelay); monitor.SyncMaxDelay > 0 && maxDeadline.Before(deadline) {
+		deadline = maxDeadline
+	}
+	if delay := deadline.Sub(now); delay > 0 {
+		return delay, false
+	}
+
+	monitor.syncPendingSince = time.Time{}
+	return 0, true
 }
 
+// syncAgentInfo applies the agentinfo with the server-side apply, the fields are owned by
+// the agent and replaced on each sync, never conflict with the updates from the controller.
 func (monitor *AgentMonitor) syncAgentInfo() error {
 	ctx := context.Background()
 	agentName := monitor.Name()
 
 	monitor.ipCacheLock.Lock()
 	defer monitor.ipCacheLock.Unlock()
-	agentInfo, err := monitor.getAgentInfo()
-	if err != nil {
-		return fmt.Errorf("couldn't get agentinfo: %s", err)
-	}
 
-	originAgentInfo, err := monitor.k8sClientGet(ctx, agentName, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		if _, err = monitor.k8sClient.Create(ctx, agentInfo, metav1.CreateOptions{}); err != nil {
-			return fmt.Errorf("couldn't create agent %s agentinfo: %s", agentName, err)
+	cpAgentInfo, err := monitor.k8sClientGet(ctx, agentName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("couldn't fetch agent %s agentinfo: %s", agentName, err)
+	}
+	if !monitor.ipCacheRestored {
+		if err = monitor.restoreIPCacheLocked(cpAgentInfo, time.Now()); err != nil {
+			return fmt.Errorf("couldn't restore learned ips: %s", err)
 		}
-		return nil
+		monitor.ipCacheRestored = true
 	}
 
+	monitor.agingIPCacheLocked(time.Now())
+	agentInfo, err := monitor.getAgentInfo()
 	if err != nil {
-		return fmt.Errorf("couldn't fetch agent %s agentinfo: %s", agentName, err)
+		return fmt.Errorf("couldn't get agentinfo: %s", err)
+	}
+	if cpAgentInfo != nil {
+		mergeConditionTransitionTimes(agentInfo.Conditions, cpAgentInfo.Conditions)
 	}
 
-	monitor.mergeAgentInfo(agentInfo, originAgentInfo)
-	agentInfo.ObjectMeta = originAgentInfo.ObjectMeta
-	_, err = monitor.k8sClient.Update(ctx, agentInfo, metav1.UpdateOptions{})
+	patch, err := createAgentInfoApplyPatch(agentInfo)
 	if err != nil {
-		return err
+		return fmt.Errorf("couldn't create agent %s agentinfo apply patch: %s", agentName, err)
 	}
-	monitor.ipCache = make(map[string]map[types.IPAddress]metav1.Time)
+	force := true
+	_, err = monitor.k8sClient.Patch(ctx, agentName, k8stypes.ApplyPatchType, patch, metav1.PatchOptions{
+		FieldManager: AgentInfoFieldManager,
+		Force:        &force,
+	})
+	return err
+}
 
-	return nil
+// createAgentInfoApplyPatch returns the apply patch of the agentinfo, the full object with
+// the apiVersion and kind, without the resourceVersion.
+func createAgentInfoApplyPatch(agentInfo *agentv1alpha1.AgentInfo) ([]byte, error) {
+	agentInfo = agentInfo.DeepCopy()
+	agentInfo.TypeMeta = metav1.TypeMeta{
+		APIVersion: agentv1alpha1.SchemeGroupVersion.String(),
+		Kind:       "AgentInfo",
+	}
+	agentInfo.ResourceVersion = ""
+	return json.Marshal(agentInfo)
 }
 
 func (monitor *AgentMonitor) k8sClientGet(ctx context.Context, name string, options metav1.GetOptions) (*agentv1alpha1.AgentInfo, error) {
@@ -236,25 +658,91 @@ func (monitor *AgentMonitor) k8sClientGet(ctx context.Context, name string, opti
 	return monitor.k8sClient.Get(ctx, name, options)
 }
 
-func (monitor *AgentMonitor) mergeAgentInfo(localAgentInfo, cpAgentInfo *agentv1alpha1.AgentInfo) {
-	for i, ovsBr := range localAgentInfo.OVSInfo.Bridges {
-		for j, port := range ovsBr.Ports {
-			for k, intf := range port.Interfaces {
-				matchIntf := getCpIntf(ovsBr.Name, intf, cpAgentInfo)
-				if matchIntf == nil {
+// restoreIPCacheLocked loads the ips in the control plane into the ipCache once the agent
+// started, e.g. learned before the agent restart, until they aged out. The interfaces are
+// matched by the bridge, port, interface name and mac, as the ofport could be duplicate
+// across bridges or reused by another interface. The ips learned locally take precedence.
+// Returns error if the ovsdb has not been cached, the ips would be lost on the next sync.
+func (monitor *AgentMonitor) restoreIPCacheLocked(cpAgentInfo *agentv1alpha1.AgentInfo, now time.Time) error {
+	ifaceUUIDs := make(map[interfaceKey]string)
+	err := monitor.ovsdbMonitor.LockedAccessCache(func(ovsdbCache OVSDBCache) error {
+		if len(ovsdbCache["Open_vSwitch"]) == 0 {
+			return ererrors.NewTransient("ovsdb has not been cached")
+		}
+		for uuid, ref := range listInterfaceRefs(ovsdbCache) {
+			ifaceUUIDs[interfaceKey{bridge: ref.bridge, port: ref.port, name: ref.name, mac: ref.mac}] = uuid
+		}
+		return nil
+	})
+	if err != nil || cpAgentInfo == nil {
+		return err
+	}
+
+	for _, ovsBr := range cpAgentInfo.OVSInfo.Bridges {
+		for _, port := range ovsBr.Ports {
+			for k := range port.Interfaces {
+				intf := &port.Interfaces[k]
+				ifaceUUID, ok := ifaceUUIDs[newInterfaceKey(ovsBr.Name, port.Name, intf)]
+				if !ok {
 					continue
 				}
-				for key, value := range matchIntf.IPMap {
-					if localAgentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPMap == nil {
-						localAgentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPMap = make(map[types.IPAddress]metav1.Time)
+				for ip, learnTime := range intf.IPMap {
+					expiry, leased := intf.IPLeaseMap[ip]
+					if (leased && now.After(expiry.Time)) || (!leased && monitor.isIPAgedOut(learnTime, now)) {
+						continue
 					}
-					if _, ok := intf.IPMap[key]; !ok {
-						localAgentInfo.OVSInfo.Bridges[i].Ports[j].Interfaces[k].IPMap[key] = value
+					if _, ok := monitor.ipCache[ifaceUUID][ip]; ok {
+						continue
+					}
+					if _, ok := monitor.ipCache[ifaceUUID]; !ok {
+						monitor.ipCache[ifaceUUID] = make(map[types.IPAddress]metav1.Time)
+					}
+					monitor.ipCache[ifaceUUID][ip] = learnTime
+					if leased {
+						if _, ok := monitor.ipLeases[ifaceUUID]; !ok {
+							monitor.ipLeases[ifaceUUID] = make(map[types.IPAddress]metav1.Time)
+						}
+						monitor.ipLeases[ifaceUUID][ip] = expiry
+					}
+					if source, ok := intf.IPSourceMap[ip]; ok {
+						monitor.setIPSourceLocked(ifaceUUID, ip, source)
+					} else if leased {
+						// the ips reported by the former agent without the sources
+						monitor.setIPSourceLocked(ifaceUUID, ip, agentv1alpha1.IPSourceDHCP)
 					}
 				}
 			}
 		}
 	}
+	monitor.observeIPCacheLocked()
+	return nil
+}
+
+// interfaceKey identifies an interface in the agentinfo.
+type interfaceKey struct {
+	bridge string
+	port   string
+	name   string
+	mac    string
+}
+
+func newInterfaceKey(bridgeName, portName string, intf *agentv1alpha1.OVSInterface) interfaceKey {
+	return interfaceKey{bridge: bridgeName, port: portName, name: intf.Name, mac: intf.Mac}
+}
+
+// sortAgentInfo sorts the bridges, ports and interfaces by name, they are fetched from
+// the ovsdb cache in random order.
+func sortAgentInfo(agentInfo *agentv1alpha1.AgentInfo) {
+	bridges := agentInfo.OVSInfo.Bridges
+	sort.Slice(bridges, func(i, j int) bool { return bridges[i].Name < bridges[j].Name })
+	for i := range bridges {
+		ports := bridges[i].Ports
+		sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+		for j := range ports {
+			interfaces := ports[j].Interfaces
+			sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+		}
+	}
 }
 
 func (monitor *AgentMonitor) getAgentInfo() (*agentv1alpha1.AgentInfo, error) {
@@ -283,22 +771,220 @@ func (monitor *AgentMonitor) getAgentInfo() (*agentv1alpha1.AgentInfo, error) {
 			}
 			agentInfo.OVSInfo.Bridges = append(agentInfo.OVSInfo.Bridges, *bridge)
 		}
+
+		if monitor.OVNInterop {
+			removeOVNInterfaces(agentInfo.OVSInfo.Bridges, fetchOVNBridgesLocked(ovsdbCache))
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	sortAgentInfo(agentInfo)
+
+	for i := range agentInfo.OVSInfo.Bridges {
+		// only uplink bridges learn remote macs by normal action
+		if strings.HasSuffix(agentInfo.OVSInfo.Bridges[i].Name, "-"+datapath.UPLINK_BRIDGE_KEYWORD) {
+			monitor.fillLearnedMACs(&agentInfo.OVSInfo.Bridges[i])
+		}
+	}
+	monitor.fillLLDPNeighbors(agentInfo)
+	monitor.fillConnectionStats(agentInfo)
+	monitor.fillConntrackZones(agentInfo)
+	monitor.fillVRFs(agentInfo)
+	fillVLANs(agentInfo)
+	if monitor.PolicyRuleStats != nil {
+		agentInfo.PolicyRuleStats = monitor.PolicyRuleStats()
+	}
+	agentInfo.Features = monitor.Features
+	agentInfo.RuleFeatures = monitor.RuleFeatures
+	agentInfo.Capacity = monitor.Capacity
+
+	// the hardware vtep gateways are only reported when the database monitored
+	_ = monitor.ovsdbMonitor.LockedAccessDatabaseCache(DatabaseHardwareVTEP, func(vtepCache OVSDBCache) error {
+		agentInfo.VTEPInfo = fetchVTEPInfo(vtepCache)
+		return nil
+	})
 
+	now := metav1.NewTime(time.Now())
 	agentHealthCondition := agentv1alpha1.AgentCondition{
-		Type:              agentv1alpha1.AgentHealthy,
-		Status:            corev1.ConditionTrue,
-		LastHeartbeatTime: metav1.NewTime(time.Now()),
+		Type:               agentv1alpha1.AgentHealthy,
+		Status:             corev1.ConditionTrue,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
 	}
 	agentInfo.Conditions = []agentv1alpha1.AgentCondition{agentHealthCondition}
 
+	if flowTableCondition, err := monitor.getFlowTableCondition(); err != nil {
+		klog.V(4).Infof("unable get datapath flow usage: %s", err)
+	} else {
+		agentInfo.Conditions = append(agentInfo.Conditions, *flowTableCondition)
+	}
+	agentInfo.Conditions = append(agentInfo.Conditions, monitor.getComponentConditions(now)...)
+
 	return agentInfo, nil
 }
 
+// fillLearnedMACs fill the learned macs of the bridge into its ports.
+func (monitor *AgentMonitor) fillLearnedMACs(bridge *agentv1alpha1.OVSBridge) {
+	entries, err := monitor.fdbShow(bridge.Name)
+	if err != nil {
+		klog.V(4).Infof("unable get fdb of bridge %s: %s", bridge.Name, err)
+		return
+	}
+
+	// fdb/show identify the ports by ofport, or LOCAL for the bridge internal port
+	portIndex := make(map[string]int)
+	for i, port := range bridge.Ports {
+		if port.Name == bridge.Name {
+			portIndex["LOCAL"] = i
+		}
+		for _, iface := range port.Interfaces {
+			portIndex[strconv.Itoa(int(iface.Ofport))] = i
+		}
+	}
+
+	for _, entry := range entries {
+		i, ok := portIndex[entry.Port]
+		if !ok {
+			continue
+		}
+		port := &bridge.Ports[i]
+		port.LearnedMACCount++
+		if len(port.LearnedMACs) < agentv1alpha1.MaxReportedLearnedMACs {
+			port.LearnedMACs = append(port.LearnedMACs, agentv1alpha1.LearnedMAC{MAC: entry.MAC, VLAN: int32(entry.VLAN)})
+		}
+	}
+}
+
+// fillLLDPNeighbors fill the lldp neighbors into the interfaces, nothing filled if lldpd not running.
+func (monitor *AgentMonitor) fillLLDPNeighbors(agentInfo *agentv1alpha1.AgentInfo) {
+	neighbors, err := monitor.lldpNeighbors()
+	if err != nil {
+		klog.V(4).Infof("unable get lldp neighbors: %s", err)
+		return
+	}
+
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		for _, port := range bridge.Ports {
+			for i := range port.Interfaces {
+				if neighbor, ok := neighbors[port.Interfaces[i].Name]; ok {
+					port.Interfaces[i].LLDPNeighbor = neighbor.DeepCopy()
+				}
+			}
+		}
+	}
+}
+
+// fillConnectionStats sums the connection stats of the ips learned on the interfaces.
+func (monitor *AgentMonitor) fillConnectionStats(agentInfo *agentv1alpha1.AgentInfo) {
+	if monitor.ConnectionStats == nil {
+		return
+	}
+	stats := monitor.ConnectionStats()
+
+	for _, bridge := range agentInfo.OVSInfo.Bridges {
+		for _, port := range bridge.Ports {
+			for i := range port.Interfaces {
+				iface := &port.Interfaces[i]
+				for ip := range iface.IPMap {
+					stat, ok := stats[ip]
+					if !ok {
+						continue
+					}
+					if iface.ConnectionStats == nil {
+						iface.ConnectionStats = &agentv1alpha1.ConnectionStats{}
+					}
+					iface.ConnectionStats.ActiveConnections += stat.ActiveConnections
+					iface.ConnectionStats.Packets += stat.Packets
+					iface.ConnectionStats.Bytes += stat.Bytes
+				}
+			}
+		}
+	}
+}
+
+// fillConntrackZones fill the policy conntrack zones into the vds bridges.
+func (monitor *AgentMonitor) fillConntrackZones(agentInfo *agentv1alpha1.AgentInfo) {
+	if monitor.ConntrackZones == nil {
+		return
+	}
+	zones := monitor.ConntrackZones()
+
+	for i := range agentInfo.OVSInfo.Bridges {
+		if zone, ok := zones[agentInfo.OVSInfo.Bridges[i].Name]; ok {
+			conntrackZone := int32(zone)
+			agentInfo.OVSInfo.Bridges[i].ConntrackZone = &conntrackZone
+		}
+	}
+}
+
+// fillVRFs fill the vrfs into the vds bridges.
+func (monitor *AgentMonitor) fillVRFs(agentInfo *agentv1alpha1.AgentInfo) {
+	if monitor.VRFs == nil {
+		return
+	}
+	vrfs := monitor.VRFs()
+
+	for i := range agentInfo.OVSInfo.Bridges {
+		agentInfo.OVSInfo.Bridges[i].VRF = vrfs[agentInfo.OVSInfo.Bridges[i].Name]
+	}
+}
+
+// fillVLANs fill the vlans into the bridges from the vlan configs of the ports, the ports
+// should have been sorted.
+func fillVLANs(agentInfo *agentv1alpha1.AgentInfo) {
+	for i := range agentInfo.OVSInfo.Bridges {
+		bridge := &agentInfo.OVSInfo.Bridges[i]
+		vlans := make(map[int32]*agentv1alpha1.VLANInfo)
+		getVLAN := func(id int32) *agentv1alpha1.VLANInfo {
+			if _, ok := vlans[id]; !ok {
+				vlans[id] = &agentv1alpha1.VLANInfo{ID: id}
+			}
+			return vlans[id]
+		}
+
+		for _, port := range bridge.Ports {
+			if vlan := port.VlanConfig.AccessVLAN(); vlan != 0 {
+				getVLAN(vlan).AccessPorts = append(getVLAN(vlan).AccessPorts, port.Name)
+			}
+			for _, vlan := range listTrunkVLANs(port.VlanConfig) {
+				getVLAN(vlan).TrunkPorts = append(getVLAN(vlan).TrunkPorts, port.Name)
+			}
+		}
+
+		bridge.VLANs = nil
+		for _, vlan := range vlans {
+			bridge.VLANs = append(bridge.VLANs, *vlan)
+		}
+		sort.Slice(bridge.VLANs, func(i, j int) bool { return bridge.VLANs[i].ID < bridge.VLANs[j].ID })
+	}
+}
+
+func (monitor *AgentMonitor) getFlowTableCondition() (*agentv1alpha1.AgentCondition, error) {
+	usages, err := monitor.flowUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	now := metav1.NewTime(time.Now())
+	condition := &agentv1alpha1.AgentCondition{
+		Type:               agentv1alpha1.FlowTableFull,
+		Status:             corev1.ConditionFalse,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+	for _, usage := range usages {
+		if usage.limit > 0 && usage.current >= usage.limit {
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = "FlowLimitReached"
+			condition.Message = fmt.Sprintf("datapath %s flows %d reached limit %d", usage.datapath, usage.current, usage.limit)
+			break
+		}
+	}
+	return condition, nil
+}
+
 func (monitor *AgentMonitor) Name() string {
 	return monitor.agentName
 }
@@ -306,170 +992,284 @@ func (monitor *AgentMonitor) Name() string {
 func (monitor *AgentMonitor) fetchOvsVersionLocked(ovsdbCache OVSDBCache) (string, error) {
 	tableOvs := ovsdbCache["Open_vSwitch"]
 	if len(tableOvs) == 0 {
-		return "", fmt.Errorf("couldn't find table %s, agentMonitor may haven't start", "Open_vSwitch")
+		return "", ererrors.NewTransient("couldn't find table %s, agentMonitor may haven't start", "Open_vSwitch")
 	}
 
-	for _, raw := range tableOvs {
-		return raw.Fields["ovs_version"].(string), nil
+	for uuid, raw := range tableOvs {
+		reader := newRowReader("Open_vSwitch", uuid, raw)
+		version := reader.String("ovs_version")
+		if err := reader.Err(); err != nil {
+			klog.Errorf("unable read ovs version: %s", err)
+			return "", err
+		}
+		return version, nil
 	}
 
 	return "", nil
 }
 
-func (monitor *AgentMonitor) fetchPortLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID, bridgeName string) (*agentv1alpha1.OVSPort, error) {
+func (monitor *AgentMonitor) fetchPortLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID) (*agentv1alpha1.OVSPort, error) {
 	ovsPort, ok := ovsdbCache["Port"][uuid.GoUuid]
 	if !ok {
-		return nil, fmt.Errorf("ovs port %s not found in cache", uuid)
+		return nil, ererrors.NewNotFound("ovs port %s not found in cache", uuid)
 	}
 
+	reader := newRowReader(OvsDBPortTable, uuid.GoUuid, ovsPort)
 	port := &agentv1alpha1.OVSPort{
-		Name:        ovsPort.Fields["name"].(string),
-		ExternalIDs: make(map[string]string),
-	}
-
-	externalIDs := ovsPort.Fields["external_ids"].(ovsdb.OvsMap).GoMap
-	for name, value := range externalIDs {
-		port.ExternalIDs[name.(string)] = value.(string)
+		Name:        reader.String("name"),
+		ExternalIDs: reader.StringMap("external_ids"),
 	}
 
-	// we use _ receive the second return, because field type is ovsdb.OvsSet when field empty
-	ovsVlanMode, _ := ovsPort.Fields["vlan_mode"].(string)
-	ovsBondMode, _ := ovsPort.Fields["bond_mode"].(string)
-
-	// json number type is always float64
-	ovsTag, _ := ovsPort.Fields["tag"].(float64)
-	var ovsTrunks []float64
-	trunks, ok := ovsPort.Fields["trunks"].(ovsdb.OvsSet)
-	if ok {
-		for _, item := range trunks.GoSet {
-			ovsTrunks = append(ovsTrunks, item.(float64))
-		}
+	ovsVlanMode := reader.String("vlan_mode")
+	ovsBondMode := reader.String("bond_mode")
+	ovsTag, _ := reader.Float("tag")
+	var ovsTrunks types.Trunks
+	for _, trunk := range reader.Floats("trunks") {
+		ovsTrunks = append(ovsTrunks, int32(trunk))
 	}
-	trunkString := strings.Trim(strings.Join(strings.Split(fmt.Sprintf("%v", ovsTrunks), " "), ","), "[]")
+	ovsTrunks = ovsTrunks.Normalize()
 
 	port.VlanConfig = &agentv1alpha1.VlanConfig{
 		VlanMode: vlanModeMap[ovsVlanMode],
 		Tag:      int32(ovsTag),
-		Trunk:    trunkString,
+		Trunk:    ovsTrunks.String(),
+		Trunks:   ovsTrunks,
 	}
 
 	port.BondConfig = &agentv1alpha1.BondConfig{
 		BondMode: bondModeMap[ovsBondMode],
 	}
 
-	for _, uuid := range listUUID(ovsPort.Fields["interfaces"]) {
-		iface := monitor.fetchInterfaceLocked(ovsdbCache, uuid, bridgeName)
+	for _, uuid := range reader.UUIDs("interfaces") {
+		iface := monitor.fetchInterfaceLocked(ovsdbCache, uuid)
 		if iface != nil {
 			port.Interfaces = append(port.Interfaces, *iface)
 		}
 	}
 
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of port %s: %s", port.Name, err)
+	}
 	return port, nil
 }
 
-func (monitor *AgentMonitor) fetchInterfaceLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID, bridgeName string) *agentv1alpha1.OVSInterface {
+func (monitor *AgentMonitor) fetchInterfaceLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID) *agentv1alpha1.OVSInterface {
 	ovsIface, ok := ovsdbCache["Interface"][uuid.GoUuid]
 	if !ok {
 		klog.V(4).Infof("could not find interface %+v in cache", ovsIface)
 		return nil
 	}
+	reader := newRowReader(OvsDBInterfaceTable, uuid.GoUuid, ovsIface)
 	// ignore interface will errors
-	if ifHasError(ovsIface.Fields["error"]) {
+	if reader.String("error") != "" {
 		klog.V(4).Infof("errors occur in interface %+v", ovsIface)
 		return nil
 	}
 
 	iface := agentv1alpha1.OVSInterface{
-		Name:        ovsIface.Fields["name"].(string),
-		Type:        ovsIface.Fields["type"].(string),
-		ExternalIDs: make(map[string]string),
-	}
-
-	externalIDs := ovsIface.Fields["external_ids"].(ovsdb.OvsMap).GoMap
-	for name, value := range externalIDs {
-		iface.ExternalIDs[name.(string)] = value.(string)
+		Name:        reader.String("name"),
+		Type:        reader.String("type"),
+		ExternalIDs: reader.StringMap("external_ids"),
 	}
 
 	if mac, ok := iface.ExternalIDs[LocalEndpointIdentity]; ok {
 		// if attached-mac found, use attached-mac as endpoint mac
 		iface.Mac = mac
 	} else {
-		// field type is ovsdb.OvsSet instead of string when field empty
-		iface.Mac, _ = ovsIface.Fields["mac_in_use"].(string)
+		iface.Mac = reader.String("mac_in_use")
 	}
 
-	ofport, ok := ovsIface.Fields["ofport"].(float64)
+	iface.LinkState = reader.String("link_state")
+	iface.BFDStatus = getBFDStatus(reader)
+
+	ofport, ok := reader.Float("ofport")
 	if ok && ofport >= 0 {
 		iface.Ofport = int32(ofport)
-		iface.IPMap = monitor.ipCache[fmt.Sprintf("%s-%d", bridgeName, iface.Ofport)]
+		// copy the ips reported, the ipCache is reused among the syncs
+		for ip, source := range monitor.reportedIPSourcesLocked(uuid.GoUuid) {
+			if iface.IPMap == nil {
+				iface.IPMap = make(map[types.IPAddress]metav1.Time)
+				iface.IPSourceMap = make(map[types.IPAddress]agentv1alpha1.IPSource)
+			}
+			iface.IPMap[ip] = monitor.ipCache[uuid.GoUuid][ip]
+			iface.IPSourceMap[ip] = source
+			if expiry, ok := monitor.ipLeases[uuid.GoUuid][ip]; ok {
+				if iface.IPLeaseMap == nil {
+					iface.IPLeaseMap = make(map[types.IPAddress]metav1.Time)
+				}
+				iface.IPLeaseMap[ip] = expiry
+			}
+		}
 	}
 
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of interface %s: %s", iface.Name, err)
+	}
 	return &iface
 }
 
 func (monitor *AgentMonitor) fetchBridgeLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID) (*agentv1alpha1.OVSBridge, error) {
 	ovsBri, ok := ovsdbCache["Bridge"][uuid.GoUuid]
 	if !ok {
-		return nil, fmt.Errorf("ovs bridge %s not found in cache", uuid)
+		return nil, ererrors.NewNotFound("ovs bridge %s not found in cache", uuid)
 	}
 
+	reader := newRowReader(OvsDBBridgeTable, uuid.GoUuid, ovsBri)
 	bridge := &agentv1alpha1.OVSBridge{
-		Name: ovsBri.Fields["name"].(string),
+		Name: reader.String("name"),
+	}
+	// the bridge name is the identity of the bridge, could not report without it
+	if bridge.Name == "" {
+		return nil, fmt.Errorf("ovs bridge %s has no valid name: %v", uuid, reader.Err())
 	}
 
-	for _, uuid := range listUUID(ovsBri.Fields["ports"]) {
-		port, err := monitor.fetchPortLocked(ovsdbCache, uuid, bridge.Name)
+	// rstp takes precedence over stp when both enabled
+	if reader.Bool("rstp_enable") {
+		bridge.SpanningTree = agentv1alpha1.SpanningTreeRSTP
+	} else if reader.Bool("stp_enable") {
+		bridge.SpanningTree = agentv1alpha1.SpanningTreeSTP
+	}
+
+	for _, uuid := range reader.UUIDs("ports") {
+		port, err := monitor.fetchPortLocked(ovsdbCache, uuid)
 		if err != nil {
 			return nil, err
 		}
+		if bridge.SpanningTree != "" {
+			portReader := newRowReader(OvsDBPortTable, uuid.GoUuid, ovsdbCache["Port"][uuid.GoUuid])
+			port.SpanningTreeStatus = getSpanningTreeStatus(portReader, bridge.SpanningTree)
+			if err := portReader.Err(); err != nil {
+				klog.Errorf("ignore unexpected spanning tree status of port %s: %s", port.Name, err)
+			}
+		}
 		bridge.Ports = append(bridge.Ports, *port)
 	}
 
+	for _, uuid := range reader.UUIDs("sflow") {
+		bridge.SFlow = fetchSFlowLocked(ovsdbCache, uuid)
+	}
+	for _, uuid := range reader.UUIDs("mirrors") {
+		if mirror := fetchMirrorLocked(ovsdbCache, uuid); mirror != nil {
+			bridge.Mirrors = append(bridge.Mirrors, *mirror)
+		}
+	}
+	sort.Slice(bridge.Mirrors, func(i, j int) bool { return bridge.Mirrors[i].Name < bridge.Mirrors[j].Name })
+
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of bridge %s: %s", bridge.Name, err)
+	}
 	return bridge, nil
 }
 
-func ifHasError(ovsIf interface{}) bool {
-	value, ok := ovsIf.(string)
+// fetchSFlowLocked read the sflow sampling of the bridge, nil if not found in cache.
+func fetchSFlowLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID) *agentv1alpha1.SFlowStatus {
+	row, ok := ovsdbCache["sFlow"][uuid.GoUuid]
 	if !ok {
-		return false
+		return nil
 	}
-	if ok && value == "" {
-		return false
+	reader := newRowReader("sFlow", uuid.GoUuid, row)
+	sflow := &agentv1alpha1.SFlowStatus{
+		Agent:   reader.String("agent"),
+		Targets: reader.Strings("targets"),
+		Managed: reader.StringMap("external_ids")[mirror.ManagedKey] == "true",
 	}
-	return true
+	for column, value := range map[string]*int32{"sampling": &sflow.Sampling, "polling": &sflow.Polling, "header": &sflow.Header} {
+		if f, ok := reader.Float(column); ok {
+			*value = int32(f)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of sflow %s: %s", uuid.GoUuid, err)
+	}
+	return sflow
 }
 
-func listUUID(uuidList interface{}) []ovsdb.UUID {
-	var idList []ovsdb.UUID
-
-	switch uuidList.(type) {
-	case ovsdb.UUID:
-		return []ovsdb.UUID{uuidList.(ovsdb.UUID)}
-	case ovsdb.OvsSet:
-		uuidSet := uuidList.(ovsdb.OvsSet).GoSet
-		for item := range uuidSet {
-			idList = append(idList, listUUID(uuidSet[item])...)
+// fetchMirrorLocked read the port mirror of the bridge, the ports are reported by name, nil if not
+// found in cache.
+func fetchMirrorLocked(ovsdbCache OVSDBCache, uuid ovsdb.UUID) *agentv1alpha1.MirrorStatus {
+	row, ok := ovsdbCache["Mirror"][uuid.GoUuid]
+	if !ok {
+		return nil
+	}
+	portNames := func(uuids []ovsdb.UUID) []string {
+		var names []string
+		for _, uuid := range uuids {
+			if port, ok := ovsdbCache["Port"][uuid.GoUuid]; ok {
+				names = append(names, newRowReader(OvsDBPortTable, uuid.GoUuid, port).String("name"))
+			}
 		}
+		sort.Strings(names)
+		return names
 	}
 
-	return idList
+	reader := newRowReader("Mirror", uuid.GoUuid, row)
+	status := &agentv1alpha1.MirrorStatus{
+		Name:           reader.String("name"),
+		SelectAll:      reader.Bool("select_all"),
+		SelectSrcPorts: portNames(reader.UUIDs("select_src_port")),
+		SelectDstPorts: portNames(reader.UUIDs("select_dst_port")),
+		Managed:        reader.StringMap("external_ids")[mirror.ManagedKey] == "true",
+	}
+	for _, vlan := range reader.Floats("select_vlan") {
+		status.SelectVLANs = append(status.SelectVLANs, int32(vlan))
+	}
+	if outputPort := portNames(reader.UUIDs("output_port")); len(outputPort) != 0 {
+		status.OutputPort = outputPort[0]
+	}
+	if vlan, ok := reader.Float("output_vlan"); ok {
+		status.OutputVLAN = int32(vlan)
+	}
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of mirror %s: %s", uuid.GoUuid, err)
+	}
+	return status
 }
 
-func getCpIntf(bridgeName string, newInterface agentv1alpha1.OVSInterface, cpAgentInfo *agentv1alpha1.AgentInfo) *agentv1alpha1.OVSInterface {
-	var matchInterface agentv1alpha1.OVSInterface
-	for _, ovsBr := range cpAgentInfo.OVSInfo.Bridges {
-		if ovsBr.Name != bridgeName {
-			continue
-		}
-		for _, port := range ovsBr.Ports {
-			for _, intf := range port.Interfaces {
-				if intf.Ofport == newInterface.Ofport {
-					intf.DeepCopyInto(&matchInterface)
-					return &matchInterface
-				}
+// getSpanningTreeStatus read the port state and role from the port status columns,
+// return nil if the port doesn't participate in the spanning tree.
+func getSpanningTreeStatus(ovsPort *rowReader, mode agentv1alpha1.SpanningTreeMode) *agentv1alpha1.SpanningTreeStatus {
+	statusColumn, stateKey, roleKey := "status", "stp_state", "stp_role"
+	if mode == agentv1alpha1.SpanningTreeRSTP {
+		statusColumn, stateKey, roleKey = "rstp_status", "rstp_port_state", "rstp_port_role"
+	}
+
+	status := ovsPort.StringMap(statusColumn)
+	state, role := status[stateKey], status[roleKey]
+	if state == "" && role == "" {
+		return nil
+	}
+
+	return &agentv1alpha1.SpanningTreeStatus{State: state, Role: role}
+}
+
+// getBFDStatus read the bfd session from the bfd_status column, return nil if bfd not enabled.
+func getBFDStatus(ovsIface *rowReader) *agentv1alpha1.BFDStatus {
+	status := ovsIface.StringMap("bfd_status")
+	if status["state"] == "" {
+		return nil
+	}
+
+	return &agentv1alpha1.BFDStatus{
+		State:       status["state"],
+		Forwarding:  status["forwarding"] == "true",
+		RemoteState: status["remote_state"],
+		Diagnostic:  status["diagnostic"],
+	}
+}
+
+func listUUID(uuidList interface{}) []ovsdb.UUID {
+	var idList []ovsdb.UUID
+
+	switch value := uuidList.(type) {
+	case ovsdb.UUID:
+		return []ovsdb.UUID{value}
+	case ovsdb.OvsSet:
+		for _, item := range value.GoSet {
+			if uuid, ok := item.(ovsdb.UUID); ok {
+				idList = append(idList, uuid)
 			}
 		}
 	}
 
-	return nil
+	return idList
 }
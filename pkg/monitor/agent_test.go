@@ -20,11 +20,19 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
+	ovsdb "github.com/contiv/libovsdb"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 
+	"github.com/everoute/everoute/pkg/agent/appctl"
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/agent/mirror"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	ererrors "github.com/everoute/everoute/pkg/errors"
 	"github.com/everoute/everoute/pkg/types"
 )
 
@@ -114,7 +122,7 @@ func TestAgentMonitor(t *testing.T) {
 	t.Run("monitor should delete port", func(t *testing.T) {
 		Eventually(func() bool {
 			_, err := getPort(k8sClient, brName, portName)
-			return isNotFoundError(err)
+			return ererrors.IsNotFound(err)
 		}, timeout, interval).Should(BeTrue())
 	})
 
@@ -124,7 +132,7 @@ func TestAgentMonitor(t *testing.T) {
 	t.Run("monitor should delete bridge", func(t *testing.T) {
 		Eventually(func() bool {
 			_, err := getBridge(k8sClient, brName)
-			return isNotFoundError(err)
+			return ererrors.IsNotFound(err)
 		}, timeout, interval).Should(BeTrue())
 	})
 }
@@ -164,12 +172,622 @@ func TestAgentMonitorIpAddressLearning(t *testing.T) {
 	t.Logf("Add another ovsPort related IpAddress %v.", ipAddr2)
 	Expect(updateIPAddress(brName, ofPort1, ipAddr2, ofPortIPAddressMonitorChan)).Should(Succeed())
 
-	t.Run("Monitor should update learned OfPort to IpAddress mapping.", func(t *testing.T) {
+	t.Run("Monitor should accumulate learned OfPort to IpAddress mapping.", func(t *testing.T) {
 		Eventually(func() bool {
 			iface, err := getIface(k8sClient, brName, portName, iface.IfaceName)
 			Expect(err).ShouldNot(HaveOccurred())
-			hasIPAddr := iface.IPMap != nil && iface.IPMap[types.IPAddress(ipAddr2.String())] != metav1.Time{}
+			hasIPAddr := iface.IPMap != nil && iface.IPMap[types.IPAddress(ipAddr1.String())] != metav1.Time{} &&
+				iface.IPMap[types.IPAddress(ipAddr2.String())] != metav1.Time{}
 			return hasIPAddr && iface.Ofport == int32(ofPort1)
 		}, timeout, interval).Should(BeTrue())
 	})
 }
+
+func TestLearnIPWithAging(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentMonitor := &AgentMonitor{
+		ipCache:        make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:      make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
+		IPAgingTime:    time.Minute,
+		IPSourcePolicy: DefaultIPSourcePolicy,
+	}
+	now := time.Now()
+
+	ifaceUUID1, ifaceUUID2 := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0002"
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID1: net.ParseIP("10.10.10.1")}, agentv1alpha1.IPSourceARP, now.Add(-2*time.Minute))
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID1: net.ParseIP("10.10.10.2")}, agentv1alpha1.IPSourceARP, now)
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID2: net.ParseIP("fe80::1")}, agentv1alpha1.IPSourceARP, now)
+	Expect(agentMonitor.ipCache).Should(HaveLen(1))
+	Expect(agentMonitor.ipCache[ifaceUUID1]).Should(HaveLen(2))
+
+	Expect(agentMonitor.agingIPCacheLocked(now)).Should(BeTrue())
+	Expect(agentMonitor.ipCache[ifaceUUID1]).Should(HaveKey(types.IPAddress("10.10.10.2")))
+	Expect(agentMonitor.ipCache[ifaceUUID1]).ShouldNot(HaveKey(types.IPAddress("10.10.10.1")))
+
+	Expect(agentMonitor.agingIPCacheLocked(now)).Should(BeFalse())
+
+	Expect(agentMonitor.agingIPCacheLocked(now.Add(2 * time.Minute))).Should(BeTrue())
+	Expect(agentMonitor.ipCache).Should(BeEmpty())
+}
+
+func TestLearnIPWithDHCPLease(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentMonitor := &AgentMonitor{
+		ipCache:        make(map[string]map[types.IPAddress]metav1.Time),
+		ipLeases:       make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:      make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
+		IPAgingTime:    time.Minute,
+		IPSourcePolicy: DefaultIPSourcePolicy,
+	}
+	now := time.Now()
+
+	ifaceUUID := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001"
+	shortLease := datapath.DHCPLease{InterfaceUUID: ifaceUUID, IP: net.ParseIP("10.10.10.1"), LeaseTime: 30 * time.Second}
+	longLease := datapath.DHCPLease{InterfaceUUID: ifaceUUID, IP: net.ParseIP("10.10.10.2"), LeaseTime: time.Hour}
+	infiniteLease := datapath.DHCPLease{InterfaceUUID: ifaceUUID, IP: net.ParseIP("10.10.10.3")}
+	agentMonitor.learnLeaseLocked(shortLease, now)
+	agentMonitor.learnLeaseLocked(longLease, now)
+	agentMonitor.learnLeaseLocked(infiniteLease, now)
+	Expect(agentMonitor.ipCache[ifaceUUID]).Should(HaveLen(3))
+	Expect(agentMonitor.ipLeases[ifaceUUID]).Should(HaveLen(2))
+	Expect(agentMonitor.ipSources[ifaceUUID]).Should(HaveKeyWithValue(types.IPAddress("10.10.10.1"), agentv1alpha1.IPSourceDHCP))
+
+	// the ip expires at the end of the lease, even if learned again from arp
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID: shortLease.IP}, agentv1alpha1.IPSourceARP, now.Add(20*time.Second))
+	Expect(agentMonitor.agingIPCacheLocked(now.Add(40 * time.Second))).Should(BeTrue())
+	Expect(agentMonitor.ipCache[ifaceUUID]).ShouldNot(HaveKey(types.IPAddress("10.10.10.1")))
+	Expect(agentMonitor.ipLeases[ifaceUUID]).ShouldNot(HaveKey(types.IPAddress("10.10.10.1")))
+	Expect(agentMonitor.ipSources[ifaceUUID]).ShouldNot(HaveKey(types.IPAddress("10.10.10.1")))
+
+	// the ip of the infinite lease ages out, the ip of the long lease kept until the lease end
+	Expect(agentMonitor.agingIPCacheLocked(now.Add(2 * time.Minute))).Should(BeTrue())
+	Expect(agentMonitor.ipCache[ifaceUUID]).Should(HaveLen(1))
+	Expect(agentMonitor.ipCache[ifaceUUID]).Should(HaveKey(types.IPAddress("10.10.10.2")))
+
+	// the renewed lease extends the expiry
+	agentMonitor.learnLeaseLocked(longLease, now.Add(30*time.Minute))
+	Expect(agentMonitor.agingIPCacheLocked(now.Add(time.Hour + time.Minute))).Should(BeFalse())
+	Expect(agentMonitor.agingIPCacheLocked(now.Add(2 * time.Hour))).Should(BeTrue())
+	Expect(agentMonitor.ipCache).Should(BeEmpty())
+	Expect(agentMonitor.ipLeases).Should(BeEmpty())
+	Expect(agentMonitor.ipSources).Should(BeEmpty())
+}
+
+func TestLearnIPWithSourcePolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	bridgeUUID, portUUID, ifaceUUID := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0002", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0003"
+	ovsdbCache := OVSDBCache{
+		"Bridge": {bridgeUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":  "ovsbr0",
+			"ports": ovsdb.UUID{GoUuid: portUUID},
+		}}},
+		"Port": {portUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":       "port01",
+			"interfaces": ovsdb.UUID{GoUuid: ifaceUUID},
+		}}},
+		"Interface": {ifaceUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":   "iface01",
+			"ofport": float64(1),
+		}}},
+	}
+	agentMonitor := &AgentMonitor{
+		ipCache:        make(map[string]map[types.IPAddress]metav1.Time),
+		ipLeases:       make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:      make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
+		IPAgingTime:    time.Minute,
+		IPSourcePolicy: DefaultIPSourcePolicy,
+	}
+	now := time.Now()
+
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID: net.ParseIP("10.10.10.1")}, agentv1alpha1.IPSourceARP, now)
+	agentMonitor.learnLeaseLocked(datapath.DHCPLease{InterfaceUUID: ifaceUUID, IP: net.ParseIP("10.10.10.2"), LeaseTime: time.Hour}, now)
+	// the ip leased is still from dhcp after learned from arp
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID: net.ParseIP("10.10.10.2")}, agentv1alpha1.IPSourceARP, now)
+
+	iface := agentMonitor.fetchInterfaceLocked(ovsdbCache, ovsdb.UUID{GoUuid: ifaceUUID})
+	Expect(iface.IPMap).Should(HaveLen(2))
+	Expect(iface.IPSourceMap).Should(Equal(map[types.IPAddress]agentv1alpha1.IPSource{
+		"10.10.10.1": agentv1alpha1.IPSourceARP,
+		"10.10.10.2": agentv1alpha1.IPSourceDHCP,
+	}))
+	Expect(iface.IPLeaseMap).Should(HaveKey(types.IPAddress("10.10.10.2")))
+
+	// only the ips from dhcp reported, the ip from arp kept in the cache
+	policy, err := NewIPSourcePolicy(IPSourceConfig{MergePolicy: string(IPMergeHighestPriority)})
+	Expect(err).ShouldNot(HaveOccurred())
+	agentMonitor.IPSourcePolicy = policy
+	iface = agentMonitor.fetchInterfaceLocked(ovsdbCache, ovsdb.UUID{GoUuid: ifaceUUID})
+	Expect(iface.IPMap).Should(HaveLen(1))
+	Expect(iface.IPSourceMap).Should(Equal(map[types.IPAddress]agentv1alpha1.IPSource{"10.10.10.2": agentv1alpha1.IPSourceDHCP}))
+	Expect(agentMonitor.ipCache[ifaceUUID]).Should(HaveLen(2))
+
+	// the ips from arp reported when prior to dhcp
+	policy, err = NewIPSourcePolicy(IPSourceConfig{Priority: []string{"ARP"}, MergePolicy: string(IPMergeHighestPriority)})
+	Expect(err).ShouldNot(HaveOccurred())
+	agentMonitor.IPSourcePolicy = policy
+	agentMonitor.ipSources = make(map[string]map[types.IPAddress]agentv1alpha1.IPSource)
+	agentMonitor.learnIPLocked(map[string]net.IP{ifaceUUID: net.ParseIP("10.10.10.1")}, agentv1alpha1.IPSourceARP, now)
+	agentMonitor.learnLeaseLocked(datapath.DHCPLease{InterfaceUUID: ifaceUUID, IP: net.ParseIP("10.10.10.2"), LeaseTime: time.Hour}, now)
+	iface = agentMonitor.fetchInterfaceLocked(ovsdbCache, ovsdb.UUID{GoUuid: ifaceUUID})
+	Expect(iface.IPSourceMap).Should(Equal(map[types.IPAddress]agentv1alpha1.IPSource{"10.10.10.1": agentv1alpha1.IPSourceARP}))
+	Expect(iface.IPLeaseMap).Should(BeEmpty())
+}
+
+func TestLearnIPMigrateLegacyKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	bridgeUUID, portUUID := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0002"
+	oldIfaceUUID, newIfaceUUID := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0003", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0004"
+	ovsdbMonitor := &OVSDBMonitor{ovsdbCache: OVSDBCache{
+		"Bridge": {bridgeUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":  "ovsbr0",
+			"ports": ovsdb.UUID{GoUuid: portUUID},
+		}}},
+		"Port": {portUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":       "port01",
+			"interfaces": ovsdb.UUID{GoUuid: newIfaceUUID},
+		}}},
+		"Interface": {newIfaceUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":   "iface01",
+			"ofport": float64(1),
+		}}},
+	}}
+	agentMonitor := &AgentMonitor{
+		ovsdbMonitor:   ovsdbMonitor,
+		ipCache:        make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:      make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
+		IPSourcePolicy: DefaultIPSourcePolicy,
+	}
+	now := time.Now()
+
+	// the ip learned on the removed interface must not be attributed to the new one
+	agentMonitor.learnIPLocked(map[string]net.IP{oldIfaceUUID: net.ParseIP("10.10.10.1")}, agentv1alpha1.IPSourceARP, now)
+	// the legacy key is migrated to the interface on the ofport now
+	agentMonitor.learnIPLocked(map[string]net.IP{"ovsbr0-1": net.ParseIP("10.10.10.2")}, agentv1alpha1.IPSourceARP, now)
+	// the legacy key without interface on the ofport is dropped
+	agentMonitor.learnIPLocked(map[string]net.IP{"ovsbr0-2": net.ParseIP("10.10.10.3")}, agentv1alpha1.IPSourceARP, now)
+
+	Expect(agentMonitor.ipCache).Should(HaveLen(2))
+	Expect(agentMonitor.ipCache[oldIfaceUUID]).Should(HaveKey(types.IPAddress("10.10.10.1")))
+	Expect(agentMonitor.ipCache[newIfaceUUID]).Should(HaveLen(1))
+	Expect(agentMonitor.ipCache[newIfaceUUID]).Should(HaveKey(types.IPAddress("10.10.10.2")))
+
+	iface := agentMonitor.fetchInterfaceLocked(ovsdbMonitor.ovsdbCache, ovsdb.UUID{GoUuid: newIfaceUUID})
+	Expect(iface).ShouldNot(BeNil())
+	Expect(iface.IPMap).Should(HaveLen(1))
+	Expect(iface.IPMap).Should(HaveKey(types.IPAddress("10.10.10.2")))
+}
+
+func TestParseLegacyIPCacheKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	bridgeName, ofport, ok := parseLegacyIPCacheKey("ovsbr0-uplink-10")
+	Expect(ok).Should(BeTrue())
+	Expect(bridgeName).Should(Equal("ovsbr0-uplink"))
+	Expect(ofport).Should(Equal(int32(10)))
+
+	_, _, ok = parseLegacyIPCacheKey("a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001")
+	Expect(ok).Should(BeFalse())
+	_, _, ok = parseLegacyIPCacheKey("-1")
+	Expect(ok).Should(BeFalse())
+}
+
+func TestRestoreIPCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	agedOut := metav1.NewTime(now.Add(-2 * time.Hour))
+
+	ovsdbCache := OVSDBCache{"Open_vSwitch": {"ovs": ovsdb.Row{Fields: map[string]interface{}{}}}}
+	addInterface := func(bridge, uuid, name, mac string, ofport int) {
+		if ovsdbCache["Bridge"] == nil {
+			ovsdbCache["Bridge"], ovsdbCache["Port"], ovsdbCache["Interface"] = map[string]ovsdb.Row{}, map[string]ovsdb.Row{}, map[string]ovsdb.Row{}
+		}
+		bridgeRow, ok := ovsdbCache["Bridge"][bridge]
+		if !ok {
+			bridgeRow = ovsdb.Row{Fields: map[string]interface{}{"name": bridge, "ports": ovsdb.OvsSet{}}}
+		}
+		ports := bridgeRow.Fields["ports"].(ovsdb.OvsSet)
+		ports.GoSet = append(ports.GoSet, ovsdb.UUID{GoUuid: "port-" + uuid})
+		bridgeRow.Fields["ports"] = ports
+		ovsdbCache["Bridge"][bridge] = bridgeRow
+		ovsdbCache["Port"]["port-"+uuid] = ovsdb.Row{Fields: map[string]interface{}{"name": name, "interfaces": ovsdb.UUID{GoUuid: uuid}}}
+		ovsdbCache["Interface"][uuid] = ovsdb.Row{Fields: map[string]interface{}{"name": name, "mac_in_use": mac, "ofport": float64(ofport)}}
+	}
+	addInterface("br0", "iface0", "vnet0", "52:54:00:00:00:01", 1)
+	// ofport changed after the interface recreated
+	addInterface("br0", "iface1", "vnet1", "52:54:00:00:00:02", 12)
+	// ofport 3 reused by another interface
+	addInterface("br0", "iface4", "vnet4", "52:54:00:00:00:05", 3)
+	// duplicate ofport on another bridge
+	addInterface("br1", "iface5", "vnet5", "52:54:00:00:00:06", 1)
+
+	newIntf := func(name, mac string, ofport int32, ipMap map[types.IPAddress]metav1.Time) agentv1alpha1.OVSPort {
+		return agentv1alpha1.OVSPort{Name: name, Interfaces: []agentv1alpha1.OVSInterface{{Name: name, Mac: mac, Ofport: ofport, IPMap: ipMap}}}
+	}
+	cpAgentInfo := &agentv1alpha1.AgentInfo{OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{
+		{Name: "br0", Ports: []agentv1alpha1.OVSPort{
+			newIntf("vnet0", "52:54:00:00:00:01", 1, map[types.IPAddress]metav1.Time{"10.0.0.1": earlier, "10.0.0.9": agedOut}),
+			newIntf("vnet1", "52:54:00:00:00:02", 2, map[types.IPAddress]metav1.Time{"10.0.0.2": earlier}),
+			newIntf("vnet2", "52:54:00:00:00:03", 3, map[types.IPAddress]metav1.Time{"10.0.0.3": earlier}),
+		}},
+		{Name: "br1", Ports: []agentv1alpha1.OVSPort{
+			newIntf("vnet3", "52:54:00:00:00:04", 1, map[types.IPAddress]metav1.Time{"10.0.1.1": earlier}),
+		}},
+	}}}
+
+	agentMonitor := &AgentMonitor{
+		ovsdbMonitor:   &OVSDBMonitor{ovsdbCache: OVSDBCache{}},
+		ipCache:        make(map[string]map[types.IPAddress]metav1.Time),
+		ipSources:      make(map[string]map[types.IPAddress]agentv1alpha1.IPSource),
+		IPAgingTime:    time.Hour,
+		IPSourcePolicy: DefaultIPSourcePolicy,
+	}
+	// the ips must not be dropped before the ovsdb cached
+	Expect(agentMonitor.restoreIPCacheLocked(cpAgentInfo, now.Time)).ShouldNot(Succeed())
+
+	agentMonitor.ovsdbMonitor.ovsdbCache = ovsdbCache
+	// learned the same ip again
+	agentMonitor.ipCache["iface0"] = map[types.IPAddress]metav1.Time{"10.0.0.1": now}
+	Expect(agentMonitor.restoreIPCacheLocked(cpAgentInfo, now.Time)).Should(Succeed())
+	Expect(agentMonitor.ipCache).Should(Equal(map[string]map[types.IPAddress]metav1.Time{
+		"iface0": {"10.0.0.1": now},
+		"iface1": {"10.0.0.2": earlier},
+	}))
+
+	// nothing to restore for the new agent
+	Expect(agentMonitor.restoreIPCacheLocked(nil, now.Time)).Should(Succeed())
+}
+
+func TestSortAgentInfo(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentInfo := &agentv1alpha1.AgentInfo{OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{
+		{Name: "br1"},
+		{Name: "br0", Ports: []agentv1alpha1.OVSPort{
+			{Name: "bond0", Interfaces: []agentv1alpha1.OVSInterface{{Name: "eth1"}, {Name: "eth0"}}},
+			{Name: "br0"},
+		}},
+	}}}
+
+	sortAgentInfo(agentInfo)
+	Expect(agentInfo.OVSInfo.Bridges[0].Name).Should(Equal("br0"))
+	Expect(agentInfo.OVSInfo.Bridges[0].Ports[0].Name).Should(Equal("bond0"))
+	Expect(agentInfo.OVSInfo.Bridges[0].Ports[0].Interfaces[0].Name).Should(Equal("eth0"))
+}
+
+func TestGetComponentConditions(t *testing.T) {
+	RegisterTestingT(t)
+
+	ofportIPMonitorChan := make(chan map[string]net.IP, 1)
+	agentMonitor := &AgentMonitor{
+		ipCache:             map[string]map[types.IPAddress]metav1.Time{"a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001": {"10.10.10.1": metav1.Now()}},
+		ofportIPMonitorChan: ofportIPMonitorChan,
+		ovsdbProbe:          func() error { return fmt.Errorf("connection refused") },
+		OpenflowConnected:   func() bool { return true },
+		FlowsSynced:         func() bool { return false },
+	}
+	now := metav1.Now()
+
+	conditions := agentMonitor.getComponentConditions(now)
+	Expect(conditions).Should(HaveLen(4))
+	statuses := make(map[agentv1alpha1.AgentConditionType]corev1.ConditionStatus)
+	for _, condition := range conditions {
+		statuses[condition.Type] = condition.Status
+		Expect(condition.Reason).ShouldNot(BeEmpty())
+		Expect(condition.LastTransitionTime).Should(Equal(now))
+	}
+	Expect(statuses).Should(Equal(map[agentv1alpha1.AgentConditionType]corev1.ConditionStatus{
+		agentv1alpha1.OVSDBConnectionUp:    corev1.ConditionFalse,
+		agentv1alpha1.OpenflowConnectionUp: corev1.ConditionTrue,
+		agentv1alpha1.PolicySynced:         corev1.ConditionFalse,
+		agentv1alpha1.IPLearnerActive:      corev1.ConditionTrue,
+	}))
+
+	ofportIPMonitorChan <- map[string]net.IP{}
+	Expect(agentMonitor.getIPLearnerConditionLocked(now).Status).Should(Equal(corev1.ConditionFalse))
+}
+
+func TestMergeConditionTransitionTimes(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := metav1.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	cpConditions := []agentv1alpha1.AgentCondition{
+		newAgentCondition(agentv1alpha1.OpenflowConnectionUp, earlier, true, "Connected", ""),
+		newAgentCondition(agentv1alpha1.PolicySynced, earlier, false, "FlowsSyncing", ""),
+	}
+	localConditions := []agentv1alpha1.AgentCondition{
+		newAgentCondition(agentv1alpha1.OpenflowConnectionUp, now, true, "Connected", ""),
+		newAgentCondition(agentv1alpha1.PolicySynced, now, true, "FlowsSynced", ""),
+		newAgentCondition(agentv1alpha1.IPLearnerActive, now, true, "Learning", ""),
+	}
+
+	mergeConditionTransitionTimes(localConditions, cpConditions)
+	Expect(localConditions[0].LastTransitionTime).Should(Equal(earlier))
+	Expect(localConditions[0].LastHeartbeatTime).Should(Equal(now))
+	Expect(localConditions[1].LastTransitionTime).Should(Equal(now))
+	Expect(localConditions[2].LastTransitionTime).Should(Equal(now))
+}
+
+func TestCoalesceSync(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentMonitor := &AgentMonitor{SyncDebounce: time.Second, SyncMaxDelay: 3 * time.Second}
+	now := time.Now()
+
+	// sync after no changes in the debounce
+	delay, shouldSync := agentMonitor.coalesceSync("agent", now)
+	Expect(shouldSync).Should(BeFalse())
+	Expect(delay).Should(Equal(time.Second))
+	delay, shouldSync = agentMonitor.coalesceSync("agent", now.Add(500*time.Millisecond))
+	Expect(shouldSync).Should(BeFalse())
+	Expect(delay).Should(Equal(time.Second))
+	delay, shouldSync = agentMonitor.coalesceSync(agentInfoSyncDeadline{}, now.Add(time.Second))
+	Expect(shouldSync).Should(BeFalse())
+	Expect(delay).Should(Equal(500 * time.Millisecond))
+	_, shouldSync = agentMonitor.coalesceSync(agentInfoSyncDeadline{}, now.Add(1500*time.Millisecond))
+	Expect(shouldSync).Should(BeTrue())
+
+	// the deadline fires after synced
+	_, shouldSync = agentMonitor.coalesceSync(agentInfoSyncDeadline{}, now.Add(2*time.Second))
+	Expect(shouldSync).Should(BeFalse())
+
+	// sync no later than the max delay under continuous changes
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 6; i++ {
+		_, shouldSync = agentMonitor.coalesceSync("agent", now.Add(time.Duration(i)*500*time.Millisecond))
+		Expect(shouldSync).Should(BeFalse())
+	}
+	delay, shouldSync = agentMonitor.coalesceSync("agent", now.Add(2900*time.Millisecond))
+	Expect(shouldSync).Should(BeFalse())
+	Expect(delay).Should(Equal(100 * time.Millisecond))
+	_, shouldSync = agentMonitor.coalesceSync(agentInfoSyncDeadline{}, now.Add(3*time.Second))
+	Expect(shouldSync).Should(BeTrue())
+
+	// sync on each change without debounce
+	_, shouldSync = (&AgentMonitor{}).coalesceSync("agent", now)
+	Expect(shouldSync).Should(BeTrue())
+}
+
+func TestCreateAgentInfoApplyPatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentInfo := &agentv1alpha1.AgentInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", ResourceVersion: "10"},
+		Hostname:   "node01",
+		OVSInfo:    agentv1alpha1.OVSInfo{Version: "2.14.0"},
+	}
+
+	patch, err := createAgentInfoApplyPatch(agentInfo)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(string(patch)).Should(MatchJSON(`{"apiVersion":"agent.everoute.io/v1alpha1","kind":"AgentInfo",` +
+		`"metadata":{"name":"agent","creationTimestamp":null},"hostname":"node01","ovsInfo":{"version":"2.14.0"}}`))
+	Expect(agentInfo.ResourceVersion).Should(Equal("10"))
+}
+
+func TestFillLearnedMACs(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentMonitor := &AgentMonitor{
+		fdbShow: func(bridge string) ([]appctl.FDBEntry, error) {
+			entries := []appctl.FDBEntry{
+				{Port: "LOCAL", VLAN: 0, MAC: "52:54:00:00:00:01", Age: "1"},
+				{Port: "3", VLAN: 0, MAC: "52:54:00:00:00:02", Age: "1"},
+			}
+			for i := 0; i < agentv1alpha1.MaxReportedLearnedMACs+1; i++ {
+				entries = append(entries, appctl.FDBEntry{Port: "1", VLAN: 10, MAC: fmt.Sprintf("52:54:00:00:01:%02x", i), Age: "1"})
+			}
+			return entries, nil
+		},
+	}
+	bridge := agentv1alpha1.OVSBridge{
+		Name: "ovsbr0-uplink",
+		Ports: []agentv1alpha1.OVSPort{
+			{Name: "ovsbr0-uplink", Interfaces: []agentv1alpha1.OVSInterface{{Name: "ovsbr0-uplink", Type: "internal"}}},
+			{Name: "bond0", Interfaces: []agentv1alpha1.OVSInterface{{Name: "ens192", Ofport: 1}, {Name: "ens224", Ofport: 2}}},
+		},
+	}
+
+	agentMonitor.fillLearnedMACs(&bridge)
+	Expect(bridge.Ports[0].LearnedMACCount).Should(Equal(int32(1)))
+	Expect(bridge.Ports[0].LearnedMACs).Should(Equal([]agentv1alpha1.LearnedMAC{{MAC: "52:54:00:00:00:01"}}))
+	Expect(bridge.Ports[1].LearnedMACCount).Should(Equal(int32(agentv1alpha1.MaxReportedLearnedMACs + 1)))
+	Expect(bridge.Ports[1].LearnedMACs).Should(HaveLen(agentv1alpha1.MaxReportedLearnedMACs))
+	Expect(bridge.Ports[1].LearnedMACs[0]).Should(Equal(agentv1alpha1.LearnedMAC{MAC: "52:54:00:00:01:00", VLAN: 10}))
+}
+
+func TestFillVLANs(t *testing.T) {
+	RegisterTestingT(t)
+
+	agentInfo := &agentv1alpha1.AgentInfo{OVSInfo: agentv1alpha1.OVSInfo{Bridges: []agentv1alpha1.OVSBridge{{
+		Name: "ovsbr0",
+		Ports: []agentv1alpha1.OVSPort{
+			{Name: "bond0", VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeTrunk, Trunk: "200,100"}},
+			{Name: "ovsbr0"},
+			{Name: "tap0", VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeAccess, Tag: 100}},
+			{Name: "tap1", VlanConfig: &agentv1alpha1.VlanConfig{Tag: 100}},
+			{Name: "tap2", VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeNativeUntagged, Tag: 300, Trunk: "200"}},
+			{Name: "tap3", VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeTrunk, Trunks: types.Trunks{300}}},
+			{Name: "uplink", VlanConfig: &agentv1alpha1.VlanConfig{VlanMode: agentv1alpha1.VlanModeTrunk}},
+		},
+	}}}}
+
+	fillVLANs(agentInfo)
+	Expect(agentInfo.OVSInfo.Bridges[0].VLANs).Should(Equal([]agentv1alpha1.VLANInfo{
+		{ID: 100, AccessPorts: []string{"tap0", "tap1"}, TrunkPorts: []string{"bond0"}},
+		{ID: 200, TrunkPorts: []string{"bond0", "tap2"}},
+		{ID: 300, AccessPorts: []string{"tap2"}, TrunkPorts: []string{"tap3"}},
+	}))
+}
+
+func TestListVlanTrunks(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(listVlanTrunks(float64(100))).Should(Equal(types.Trunks{100}))
+	Expect(listVlanTrunks(ovsdb.OvsSet{GoSet: []interface{}{float64(200), float64(100), float64(200)}})).Should(Equal(types.Trunks{100, 200}))
+	Expect(listVlanTrunks(ovsdb.OvsSet{})).Should(BeNil())
+}
+
+func TestGetSpanningTreeStatus(t *testing.T) {
+	RegisterTestingT(t)
+
+	newPort := func(column string, status map[interface{}]interface{}) *rowReader {
+		return newRowReader(OvsDBPortTable, "port", ovsdb.Row{Fields: map[string]interface{}{column: ovsdb.OvsMap{GoMap: status}}})
+	}
+
+	stpPort := newPort("status", map[interface{}]interface{}{"stp_state": "blocking", "stp_role": "alternate"})
+	Expect(getSpanningTreeStatus(stpPort, agentv1alpha1.SpanningTreeSTP)).Should(Equal(&agentv1alpha1.SpanningTreeStatus{State: "blocking", Role: "alternate"}))
+	Expect(getSpanningTreeStatus(stpPort, agentv1alpha1.SpanningTreeRSTP)).Should(BeNil())
+
+	rstpPort := newPort("rstp_status", map[interface{}]interface{}{"rstp_port_state": "Forwarding", "rstp_port_role": "Root"})
+	Expect(getSpanningTreeStatus(rstpPort, agentv1alpha1.SpanningTreeRSTP)).Should(Equal(&agentv1alpha1.SpanningTreeStatus{State: "Forwarding", Role: "Root"}))
+	Expect(getSpanningTreeStatus(newPort("status", map[interface{}]interface{}{}), agentv1alpha1.SpanningTreeSTP)).Should(BeNil())
+}
+
+func TestGetBFDStatus(t *testing.T) {
+	RegisterTestingT(t)
+
+	newIface := func(status map[interface{}]interface{}) *rowReader {
+		return newRowReader(OvsDBInterfaceTable, "iface", ovsdb.Row{Fields: map[string]interface{}{"bfd_status": ovsdb.OvsMap{GoMap: status}}})
+	}
+
+	Expect(getBFDStatus(newIface(map[interface{}]interface{}{
+		"state":        "down",
+		"forwarding":   "false",
+		"remote_state": "up",
+		"diagnostic":   "Control Detection Time Expired",
+	}))).Should(Equal(&agentv1alpha1.BFDStatus{State: "down", RemoteState: "up", Diagnostic: "Control Detection Time Expired"}))
+	Expect(getBFDStatus(newIface(map[interface{}]interface{}{"state": "up", "forwarding": "true"}))).Should(Equal(&agentv1alpha1.BFDStatus{State: "up", Forwarding: true}))
+	Expect(getBFDStatus(newIface(map[interface{}]interface{}{}))).Should(BeNil())
+}
+
+func TestFetchPortMalformedRows(t *testing.T) {
+	RegisterTestingT(t)
+
+	portUUID, ifaceUUID := "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001", "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0002"
+	ovsdbCache := OVSDBCache{
+		"Port": {portUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":         "port01",
+			"external_ids": ovsdb.OvsMap{GoMap: map[interface{}]interface{}{"key": "value", "number": float64(1), true: "bool"}},
+			"trunks":       float64(100),
+			"interfaces":   ovsdb.UUID{GoUuid: ifaceUUID},
+		}}},
+		"Interface": {ifaceUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":         float64(1),
+			"type":         ovsdb.OvsSet{},
+			"external_ids": ovsdb.OvsSet{},
+		}}},
+	}
+
+	var port *agentv1alpha1.OVSPort
+	var err error
+	Expect(func() {
+		port, err = (&AgentMonitor{}).fetchPortLocked(ovsdbCache, ovsdb.UUID{GoUuid: portUUID})
+	}).ShouldNot(Panic())
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(port.Name).Should(Equal("port01"))
+	Expect(port.ExternalIDs).Should(Equal(map[string]string{"key": "value"}))
+	Expect(port.VlanConfig.Trunk).Should(Equal("100"))
+	Expect(port.VlanConfig.Trunks).Should(Equal(types.Trunks{100}))
+	Expect(port.Interfaces).Should(HaveLen(1))
+	Expect(port.Interfaces[0].Name).Should(BeEmpty())
+	Expect(port.Interfaces[0].ExternalIDs).Should(BeEmpty())
+}
+
+func TestFetchBridgeMirrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	bridgeUUID, sflowUUID, mirrorUUID := "b0000000-0000-0000-0000-000000000001", "b0000000-0000-0000-0000-000000000002", "b0000000-0000-0000-0000-000000000003"
+	vnetUUID, tapUUID := "b0000000-0000-0000-0000-000000000004", "b0000000-0000-0000-0000-000000000005"
+	managed := ovsdb.OvsMap{GoMap: map[interface{}]interface{}{mirror.ManagedKey: "true", mirror.BridgeKey: "ovsbr0"}}
+	ovsdbCache := OVSDBCache{
+		"Bridge": {bridgeUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":    "ovsbr0",
+			"ports":   ovsdb.OvsSet{GoSet: []interface{}{}},
+			"sflow":   ovsdb.UUID{GoUuid: sflowUUID},
+			"mirrors": ovsdb.OvsSet{GoSet: []interface{}{ovsdb.UUID{GoUuid: mirrorUUID}}},
+		}}},
+		"Port": {
+			vnetUUID: ovsdb.Row{Fields: map[string]interface{}{"name": "vnet0"}},
+			tapUUID:  ovsdb.Row{Fields: map[string]interface{}{"name": "tap0"}},
+		},
+		"sFlow": {sflowUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"agent":        ovsdb.OvsSet{GoSet: []interface{}{}},
+			"targets":      "10.0.0.1:6343",
+			"sampling":     float64(64),
+			"polling":      float64(10),
+			"header":       ovsdb.OvsSet{GoSet: []interface{}{}},
+			"external_ids": managed,
+		}}},
+		"Mirror": {mirrorUUID: ovsdb.Row{Fields: map[string]interface{}{
+			"name":            "span",
+			"select_all":      false,
+			"select_src_port": ovsdb.UUID{GoUuid: vnetUUID},
+			"select_dst_port": ovsdb.OvsSet{GoSet: []interface{}{ovsdb.UUID{GoUuid: vnetUUID}}},
+			"select_vlan":     ovsdb.OvsSet{GoSet: []interface{}{float64(10), float64(20)}},
+			"output_port":     ovsdb.UUID{GoUuid: tapUUID},
+			"output_vlan":     ovsdb.OvsSet{GoSet: []interface{}{}},
+			"external_ids":    managed,
+		}}},
+	}
+
+	bridge, err := (&AgentMonitor{}).fetchBridgeLocked(ovsdbCache, ovsdb.UUID{GoUuid: bridgeUUID})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(bridge.SFlow).Should(Equal(&agentv1alpha1.SFlowStatus{
+		Targets: []string{"10.0.0.1:6343"}, Sampling: 64, Polling: 10, Managed: true,
+	}))
+	Expect(bridge.Mirrors).Should(Equal([]agentv1alpha1.MirrorStatus{{
+		Name:           "span",
+		SelectSrcPorts: []string{"vnet0"},
+		SelectDstPorts: []string{"vnet0"},
+		SelectVLANs:    []int32{10, 20},
+		OutputPort:     "tap0",
+		Managed:        true,
+	}}))
+}
+
+func TestGetMacStrFromMalformedInterface(t *testing.T) {
+	RegisterTestingT(t)
+
+	row := ovsdb.Row{Fields: map[string]interface{}{
+		InterfaceStatus: ovsdb.OvsMap{GoMap: map[interface{}]interface{}{InterfaceDriver: float64(1)}},
+	}}
+	_, err := getMacStrFromInterface(row, DefaultEndpointClassifier)
+	Expect(err).Should(HaveOccurred())
+
+	row.Fields[InterfaceStatus] = ovsdb.OvsMap{GoMap: map[interface{}]interface{}{InterfaceDriver: VMNicDriver}}
+	row.Fields["external_ids"] = ovsdb.OvsMap{GoMap: map[interface{}]interface{}{LocalEndpointIdentity: float64(1)}}
+	row.Fields["mac_in_use"] = ovsdb.OvsSet{}
+	_, err = getMacStrFromInterface(row, DefaultEndpointClassifier)
+	Expect(err).Should(HaveOccurred())
+
+	Expect(getIPv4Addr(map[interface{}]interface{}{LocalEndpointIPv4: float64(1)})).Should(BeNil())
+}
+
+func TestRowReader(t *testing.T) {
+	RegisterTestingT(t)
+
+	reader := newRowReader(OvsDBInterfaceTable, "iface", ovsdb.Row{Fields: map[string]interface{}{
+		"name":       "iface01",
+		"type":       ovsdb.OvsSet{},
+		"ofport":     "1",
+		"link_state": float64(1),
+		"interfaces": ovsdb.OvsSet{GoSet: []interface{}{ovsdb.UUID{GoUuid: "uuid01"}, "uuid02"}},
+	}})
+
+	Expect(reader.String("name")).Should(Equal("iface01"))
+	Expect(reader.String("type")).Should(BeEmpty())
+	Expect(reader.Err()).ShouldNot(HaveOccurred())
+
+	_, ok := reader.Float("ofport")
+	Expect(ok).Should(BeFalse())
+	Expect(reader.String("link_state")).Should(BeEmpty())
+	Expect(reader.UUIDs("interfaces")).Should(Equal([]ovsdb.UUID{{GoUuid: "uuid01"}}))
+	Expect(reader.Err()).Should(HaveOccurred())
+	Expect(reader.errs).Should(HaveLen(3))
+}
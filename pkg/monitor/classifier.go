@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+
+	ovsdb "github.com/contiv/libovsdb"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ClassifierConfig configures the rules classifying the interfaces as the endpoints.
+type ClassifierConfig struct {
+	// Drivers are the driver_name in the interface status, default tun and veth if empty.
+	Drivers []string
+	// ExternalIDs match the interfaces with all the external_ids, an empty value matches
+	// any value of the key, e.g. iface-id set by the cloud platform.
+	ExternalIDs map[string]string
+	// NamePatterns are the regular expressions of the interface names, e.g. ^vhu for the
+	// DPDK vhost-user ports.
+	NamePatterns []string
+}
+
+// EndpointClassifier classifies the ovs interfaces as the endpoints, whose mac is the
+// attached-mac in the external_ids instead of the mac_in_use. An interface is an endpoint
+// if it matches any of the rules.
+type EndpointClassifier struct {
+	drivers      sets.String
+	externalIDs  map[string]string
+	namePatterns []*regexp.Regexp
+}
+
+// DefaultEndpointClassifier classifies the vm nics and the pod nics by the drivers.
+var DefaultEndpointClassifier = &EndpointClassifier{drivers: sets.NewString(VMNicDriver, PodNicDriver)}
+
+func NewEndpointClassifier(config ClassifierConfig) (*EndpointClassifier, error) {
+	classifier := &EndpointClassifier{
+		drivers:     sets.NewString(config.Drivers...),
+		externalIDs: config.ExternalIDs,
+	}
+	if classifier.drivers.Len() == 0 {
+		classifier.drivers = DefaultEndpointClassifier.drivers
+	}
+	for _, pattern := range config.NamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interface name pattern %s: %s", pattern, err)
+		}
+		classifier.namePatterns = append(classifier.namePatterns, re)
+	}
+	return classifier, nil
+}
+
+// IsEndpoint returns true if the interface row matches any of the rules.
+func (c *EndpointClassifier) IsEndpoint(row ovsdb.Row) bool {
+	if c.drivers.Has(getDriverNameFromInterface(row)) {
+		return true
+	}
+	if len(c.externalIDs) != 0 && matchExternalIDs(getExternalIDs(row), c.externalIDs) {
+		return true
+	}
+	if name, ok := row.Fields["name"].(string); ok {
+		for _, re := range c.namePatterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchExternalIDs(externalIDs, selector map[string]string) bool {
+	for key, value := range selector {
+		actual, ok := externalIDs[key]
+		if !ok || value != "" && actual != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+
+	ovsdb "github.com/contiv/libovsdb"
+	. "github.com/onsi/gomega"
+)
+
+func newTestInterfaceRow(name, driver string, externalIDs map[string]string) ovsdb.Row {
+	ids := make(map[interface{}]interface{})
+	for key, value := range externalIDs {
+		ids[key] = value
+	}
+	return ovsdb.Row{Fields: map[string]interface{}{
+		"name":          name,
+		"mac_in_use":    "00:00:00:00:00:01",
+		"external_ids":  ovsdb.OvsMap{GoMap: ids},
+		InterfaceStatus: ovsdb.OvsMap{GoMap: map[interface{}]interface{}{InterfaceDriver: driver}},
+	}}
+}
+
+func TestEndpointClassifier(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := NewEndpointClassifier(ClassifierConfig{NamePatterns: []string{"("}})
+	Expect(err).Should(HaveOccurred())
+
+	classifier, err := NewEndpointClassifier(ClassifierConfig{
+		ExternalIDs:  map[string]string{"iface-id": "", "iface-status": "active"},
+		NamePatterns: []string{"^vhu"},
+	})
+	Expect(err).ShouldNot(HaveOccurred())
+
+	tests := []struct {
+		name   string
+		row    ovsdb.Row
+		expect bool
+	}{
+		{name: "default driver", row: newTestInterfaceRow("tap0", VMNicDriver, nil), expect: true},
+		{name: "other driver", row: newTestInterfaceRow("eth0", "ixgbe", nil), expect: false},
+		{
+			name:   "external ids matched",
+			row:    newTestInterfaceRow("vf0", "mlx5e_rep", map[string]string{"iface-id": "abc", "iface-status": "active"}),
+			expect: true,
+		},
+		{
+			name:   "external ids value unmatched",
+			row:    newTestInterfaceRow("vf0", "mlx5e_rep", map[string]string{"iface-id": "abc", "iface-status": "inactive"}),
+			expect: false,
+		},
+		{name: "name matched", row: newTestInterfaceRow("vhu12345", "", nil), expect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+			Expect(classifier.IsEndpoint(tt.row)).Should(Equal(tt.expect))
+		})
+	}
+
+	classifier, err = NewEndpointClassifier(ClassifierConfig{Drivers: []string{"macvlan"}})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(classifier.IsEndpoint(newTestInterfaceRow("mv0", "macvlan", nil))).Should(BeTrue())
+	Expect(classifier.IsEndpoint(newTestInterfaceRow("tap0", VMNicDriver, nil))).Should(BeFalse())
+}
+
+func TestGetMacStrFromClassifiedInterface(t *testing.T) {
+	RegisterTestingT(t)
+
+	classifier, err := NewEndpointClassifier(ClassifierConfig{NamePatterns: []string{"^vhu"}})
+	Expect(err).ShouldNot(HaveOccurred())
+	attached := map[string]string{LocalEndpointIdentity: "00:00:00:00:00:02"}
+
+	// the vhost-user ports without driver use the attached-mac
+	mac, err := getMacStrFromInterface(newTestInterfaceRow("vhu0", "", attached), classifier)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(mac).Should(Equal("00:00:00:00:00:02"))
+	_, err = getMacStrFromInterface(newTestInterfaceRow("vhu0", "", attached), DefaultEndpointClassifier)
+	Expect(err).Should(HaveOccurred())
+
+	// the interfaces not classified use the mac_in_use
+	mac, err = getMacStrFromInterface(newTestInterfaceRow("eth0", "ixgbe", attached), classifier)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(mac).Should(Equal("00:00:00:00:00:01"))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	ovsdb "github.com/contiv/libovsdb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+// ovsdbProbeTimeout is the timeout of probing the ovsdb-server socket.
+const ovsdbProbeTimeout = time.Second
+
+// probeOvsdbServer returns error if the ovsdb-server socket could not be connected, the
+// ovsdb client reconnects in a second once the ovsdb-server is reachable again.
+func probeOvsdbServer() error {
+	conn, err := net.DialTimeout("unix", ovsdb.DEFAULT_SOCK, ovsdbProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// getComponentConditions returns the conditions of the agent components, the conditions
+// could not be probed are not reported.
+func (monitor *AgentMonitor) getComponentConditions(now metav1.Time) []agentv1alpha1.AgentCondition {
+	var conditions []agentv1alpha1.AgentCondition
+
+	if monitor.ovsdbProbe != nil {
+		condition := newAgentCondition(agentv1alpha1.OVSDBConnectionUp, now, true, "Connected", "")
+		if err := monitor.ovsdbProbe(); err != nil {
+			condition = newAgentCondition(agentv1alpha1.OVSDBConnectionUp, now, false, "OVSDBServerUnreachable", err.Error())
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if monitor.OpenflowConnected != nil {
+		condition := newAgentCondition(agentv1alpha1.OpenflowConnectionUp, now, true, "Connected", "")
+		if !monitor.OpenflowConnected() {
+			condition = newAgentCondition(agentv1alpha1.OpenflowConnectionUp, now, false, "BridgesDisconnected",
+				"not all the bridges connected to the openflow controller")
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if monitor.FlowsSynced != nil {
+		condition := newAgentCondition(agentv1alpha1.PolicySynced, now, true, "FlowsSynced", "")
+		if !monitor.FlowsSynced() {
+			condition = newAgentCondition(agentv1alpha1.PolicySynced, now, false, "FlowsSyncing",
+				"the flows have not been synced since the agent start")
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if monitor.ofportIPMonitorChan != nil {
+		conditions = append(conditions, monitor.getIPLearnerConditionLocked(now))
+	}
+
+	return conditions
+}
+
+// getIPLearnerConditionLocked returns the IPLearnerActive condition, the ip learner falls
+// behind if the learned ips are backlogged in the channel.
+func (monitor *AgentMonitor) getIPLearnerConditionLocked(now metav1.Time) agentv1alpha1.AgentCondition {
+	var ipNum int
+	for _, ipMap := range monitor.ipCache {
+		ipNum += len(ipMap)
+	}
+
+	backlog := len(monitor.ofportIPMonitorChan)
+	if backlog >= cap(monitor.ofportIPMonitorChan) {
+		return newAgentCondition(agentv1alpha1.IPLearnerActive, now, false, "LearnerBacklogged",
+			fmt.Sprintf("%d learned ips backlogged, %d ips in cache", backlog, ipNum))
+	}
+	return newAgentCondition(agentv1alpha1.IPLearnerActive, now, true, "Learning", fmt.Sprintf("%d ips in cache", ipNum))
+}
+
+func newAgentCondition(conditionType agentv1alpha1.AgentConditionType, now metav1.Time, ok bool, reason, message string) agentv1alpha1.AgentCondition {
+	status := corev1.ConditionTrue
+	if !ok {
+		status = corev1.ConditionFalse
+	}
+	return agentv1alpha1.AgentCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// mergeConditionTransitionTimes keeps the transition time of the conditions in the control
+// plane, if the status of the condition unchanged.
+func mergeConditionTransitionTimes(localConditions, cpConditions []agentv1alpha1.AgentCondition) {
+	for i := range localConditions {
+		for _, cpCondition := range cpConditions {
+			if cpCondition.Type == localConditions[i].Type && cpCondition.Status == localConditions[i].Status &&
+				!cpCondition.LastTransitionTime.IsZero() {
+				localConditions[i].LastTransitionTime = cpCondition.LastTransitionTime
+			}
+		}
+	}
+}
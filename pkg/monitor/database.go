@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cenkalti/backoff"
+	ovsdb "github.com/contiv/libovsdb"
+	"k8s.io/klog"
+
+	ererrors "github.com/everoute/everoute/pkg/errors"
+)
+
+// OVSDBDatabase is a database monitored in addition to Open_vSwitch.
+type OVSDBDatabase struct {
+	// Name is the name of the database, e.g. hardware_vtep.
+	Name string
+	// Address is the ovsdb-server serving the database, unix:<path> or tcp:<ip>:<port>,
+	// the local ovsdb-server if empty.
+	Address string
+}
+
+// supportedDatabases are the tables and columns monitored in the databases.
+var supportedDatabases = map[string]map[string]ovsdb.MonitorRequest{
+	DatabaseHardwareVTEP: hardwareVTEPMonitorRequests,
+}
+
+// LockedAccessDatabaseCache reads the cache of the database monitored in addition to
+// Open_vSwitch, returns NotFound if the database has not been monitored.
+func (monitor *OVSDBMonitor) LockedAccessDatabaseCache(database string, readFunc func(OVSDBCache) error) error {
+	monitor.cacheLock.RLock()
+	defer monitor.cacheLock.RUnlock()
+
+	databaseCache, ok := monitor.databaseCaches[database]
+	if !ok {
+		return ererrors.NewNotFound("ovsdb database %s not monitored", database)
+	}
+	return readFunc(databaseCache)
+}
+
+// runDatabaseMonitor monitors the database and caches the updates, the connection retries
+// until stopped, or the database not supported. The ovsdb client re-monitors the database
+// after reconnected, and sends the rows deleted meanwhile before the full dump.
+func (monitor *OVSDBMonitor) runDatabaseMonitor(database OVSDBDatabase, stopChan <-chan struct{}) {
+	requests, ok := supportedDatabases[database.Name]
+	if !ok {
+		klog.Errorf("unable monitor ovsdb database %s: database not supported", database.Name)
+		return
+	}
+
+	var ovsClient *ovsdb.OvsdbClient
+	connectBackOff := backoff.NewExponentialBackOff()
+	connectBackOff.MaxElapsedTime = 0
+
+	err := backoff.Retry(func() error {
+		select {
+		case <-stopChan:
+			return backoff.Permanent(fmt.Errorf("monitor stopped"))
+		default:
+		}
+
+		client, err := connectOvsdb(database.Address)
+		if err != nil {
+			klog.Errorf("unable connect to ovsdb database %s, will retry: %s", database.Name, err)
+			return err
+		}
+		if _, ok := client.Schema[database.Name]; !ok {
+			client.Disconnect()
+			return backoff.Permanent(fmt.Errorf("database not served by the ovsdb-server"))
+		}
+
+		client.Register(ovsUpdateHandlerFunc(func(_ interface{}, updates ovsdb.TableUpdates) {
+			monitor.handleDatabaseUpdates(database.Name, updates)
+		}))
+		if err = client.Monitor(database.Name, nil, requests); err != nil {
+			client.Disconnect()
+			klog.Errorf("unable monitor ovsdb database %s, will retry: %s", database.Name, err)
+			return err
+		}
+		ovsClient = client
+		return nil
+	}, connectBackOff)
+	if err != nil {
+		klog.Errorf("unable monitor ovsdb database %s: %s", database.Name, err)
+		return
+	}
+
+	klog.Infof("start monitor ovsdb %s", database.Name)
+	<-stopChan
+	ovsClient.Disconnect()
+}
+
+// handleDatabaseUpdates caches the updates of the database, and notifies the agentinfo sync.
+func (monitor *OVSDBMonitor) handleDatabaseUpdates(database string, updates ovsdb.TableUpdates) {
+	monitor.cacheLock.Lock()
+	if monitor.databaseCaches == nil {
+		monitor.databaseCaches = make(map[string]OVSDBCache)
+	}
+	databaseCache, ok := monitor.databaseCaches[database]
+	if !ok {
+		databaseCache = make(OVSDBCache)
+		monitor.databaseCaches[database] = databaseCache
+	}
+	for table, tableUpdate := range updates.Updates {
+		if _, ok := databaseCache[table]; !ok {
+			databaseCache[table] = make(map[string]ovsdb.Row)
+		}
+		for uuid, row := range tableUpdate.Rows {
+			if !reflect.DeepEqual(row.New, ovsdb.Row{}) {
+				databaseCache[table][uuid] = row.New
+			} else {
+				delete(databaseCache[table], uuid)
+			}
+		}
+	}
+	monitor.cacheLock.Unlock()
+
+	monitor.syncQueue.Add("ovsdb-event")
+}
+
+// connectOvsdb connects to the ovsdb-server on the address, unix:<path> or tcp:<ip>:<port>,
+// or the local ovsdb-server if empty.
+func connectOvsdb(address string) (*ovsdb.OvsdbClient, error) {
+	switch {
+	case address == "":
+		return ovsdb.ConnectUnix(ovsdb.DEFAULT_SOCK)
+	case strings.HasPrefix(address, "unix:"):
+		return ovsdb.ConnectUnix(strings.TrimPrefix(address, "unix:"))
+	case strings.HasPrefix(address, "tcp:"):
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(address, "tcp:"))
+		if err != nil {
+			return nil, err
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port of %s: %s", address, err)
+		}
+		return ovsdb.Connect(host, portNumber)
+	default:
+		return nil, fmt.Errorf("unsupported ovsdb address %s, expect unix:<path> or tcp:<ip>:<port>", address)
+	}
+}
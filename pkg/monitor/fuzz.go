@@ -0,0 +1,101 @@
+//go:build gofuzz
+// +build gofuzz
+
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ovsdb "github.com/contiv/libovsdb"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+// The go-fuzz targets of the ovsdb rows parsing, the rows are written by other tools
+// and may have unexpected values, they must never panic the agent. Build them with
+// go-fuzz-build or hack/oss-fuzz-build.sh in oss-fuzz.
+
+const (
+	fuzzPortUUID      = "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0001"
+	fuzzInterfaceUUID = "a8f6b1f0-5d4c-4c5e-9d0b-3c2c8f6a0002"
+)
+
+// FuzzFetchPort parse a port and its interface, the data is the json of the rows
+// in ovsdb notation, e.g. {"port": {"name": "p1"}, "interface": {"name": "p1"}}.
+func FuzzFetchPort(data []byte) int {
+	var rows struct {
+		Port      ovsdb.Row `json:"port"`
+		Interface ovsdb.Row `json:"interface"`
+	}
+	if err := unmarshalRows(data, &rows); err != nil || rows.Port.Fields == nil || rows.Interface.Fields == nil {
+		return 0
+	}
+
+	// the port always reference the interface, otherwise the interface never parsed
+	rows.Port.Fields["interfaces"] = ovsdb.UUID{GoUuid: fuzzInterfaceUUID}
+	ovsdbCache := OVSDBCache{
+		"Port":      {fuzzPortUUID: rows.Port},
+		"Interface": {fuzzInterfaceUUID: rows.Interface},
+	}
+
+	monitor := &AgentMonitor{}
+	if _, err := monitor.fetchPortLocked(ovsdbCache, ovsdb.UUID{GoUuid: fuzzPortUUID}); err != nil {
+		return 0
+	}
+	_ = getSpanningTreeStatus(newRowReader(OvsDBPortTable, fuzzPortUUID, rows.Port), agentv1alpha1.SpanningTreeSTP)
+	_ = getSpanningTreeStatus(newRowReader(OvsDBPortTable, fuzzPortUUID, rows.Port), agentv1alpha1.SpanningTreeRSTP)
+	return 1
+}
+
+// FuzzInterfaceRow parse the external_ids and status of an interface, as what the
+// ovsdb monitor does on the interface updates.
+func FuzzInterfaceRow(data []byte) int {
+	var row ovsdb.Row
+	if err := unmarshalRows(data, &row); err != nil {
+		return 0
+	}
+
+	reader := newRowReader(OvsDBInterfaceTable, fuzzInterfaceUUID, row)
+	_ = reader.StringMap("external_ids")
+	_ = reader.String("error")
+	_ = reader.UUIDs("interfaces")
+	_ = getBFDStatus(reader)
+	_ = getExternalIDs(row)
+	_ = listUUID(row.Fields["interfaces"])
+	if externalIDs, ok := row.Fields["external_ids"].(ovsdb.OvsMap); ok {
+		_ = getIPv4Addr(externalIDs.GoMap)
+	}
+	if _, err := getMacStrFromInterface(row, DefaultEndpointClassifier); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// unmarshalRows decode the rows in ovsdb notation. The libovsdb decoder panics on
+// invalid notation, e.g. an empty array, which ovsdb-server never sends, so these
+// inputs are considered invalid rather than crashes of the rows parsing.
+func unmarshalRows(data []byte, rows interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid ovsdb notation: %v", r)
+		}
+	}()
+	return json.Unmarshal(data, rows)
+}
@@ -19,10 +19,16 @@ package monitor
 import (
 	"fmt"
 	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 
 	ovsdb "github.com/contiv/libovsdb"
+	"k8s.io/klog"
 
 	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/types"
 )
 
 var (
@@ -42,11 +48,49 @@ var (
 	}
 )
 
+// upcallFlowsRegexp match the flows line in the output of ovs-appctl upcall/show, e.g.
+// "flows         : (current 12) (avg 11) (max 45) (limit 200000)"
+var upcallFlowsRegexp = regexp.MustCompile(`flows\s*:\s*\(current (\d+)\).*\(limit (\d+)\)`)
+
+// datapathFlowUsage is the number of flows and the flow limit of a datapath
+type datapathFlowUsage struct {
+	datapath string
+	current  int
+	limit    int
+}
+
+func getDatapathFlowUsage() ([]datapathFlowUsage, error) {
+	out, err := exec.Command("ovs-appctl", "upcall/show").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-appctl upcall/show: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return parseUpcallShow(string(out)), nil
+}
+
+func parseUpcallShow(output string) []datapathFlowUsage {
+	var usages []datapathFlowUsage
+	var datapath string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasSuffix(line, ":") && !strings.HasPrefix(line, " ") {
+			datapath = strings.TrimSuffix(line, ":")
+			continue
+		}
+		match := upcallFlowsRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		current, _ := strconv.Atoi(match[1])
+		limit, _ := strconv.Atoi(match[2])
+		usages = append(usages, datapathFlowUsage{datapath: datapath, current: current, limit: limit})
+	}
+	return usages
+}
+
 // ovsUpdateHandlerFunc implements ovsdb.NotificationHandler
-type ovsUpdateHandlerFunc func(tableUpdates ovsdb.TableUpdates)
+type ovsUpdateHandlerFunc func(context interface{}, tableUpdates ovsdb.TableUpdates)
 
 func (fn ovsUpdateHandlerFunc) Update(context interface{}, tableUpdates ovsdb.TableUpdates) {
-	fn(tableUpdates)
+	fn(context, tableUpdates)
 }
 
 func (fn ovsUpdateHandlerFunc) Locked([]interface{}) {
@@ -58,24 +102,69 @@ func (fn ovsUpdateHandlerFunc) Stolen([]interface{}) {
 func (fn ovsUpdateHandlerFunc) Echo([]interface{}) {
 }
 
-func listVlanTrunks(trunk interface{}) []float64 {
-	var trunkList []float64
+// listVlanTrunks returns the normalized trunks of the trunks column, the column is a set or a
+// number if only one vlan trunked.
+func listVlanTrunks(trunk interface{}) types.Trunks {
+	var trunks types.Trunks
 	switch t := trunk.(type) {
 	case float64:
-		return []float64{t}
+		trunks = types.Trunks{int32(t)}
 	case ovsdb.OvsSet:
-		trunkSet := trunk.(ovsdb.OvsSet).GoSet
-		for item := range trunkSet {
-			trunkList = append(trunkList, listVlanTrunks(trunkSet[item])...)
+		for _, item := range t.GoSet {
+			trunks = append(trunks, listVlanTrunks(item)...)
+		}
+	}
+	return trunks.Normalize()
+}
+
+// listTrunkVLANs returns the vlans trunked on the port, nil if the port is an access port or
+// trunks all the vlans.
+func listTrunkVLANs(vlanConfig *agentv1alpha1.VlanConfig) []int32 {
+	if vlanConfig == nil || (len(vlanConfig.Trunks) == 0 && vlanConfig.Trunk == "") {
+		return nil
+	}
+	switch vlanConfig.VlanMode {
+	case agentv1alpha1.VlanModeAccess, agentv1alpha1.VlanModeDot1qTunnel:
+		return nil
+	case "":
+		// the port without vlan_mode is an access port if tagged
+		if vlanConfig.Tag != 0 {
+			return nil
 		}
 	}
 
-	return trunkList
+	if len(vlanConfig.Trunks) != 0 {
+		return vlanConfig.Trunks
+	}
+	// the agentinfo reported by the former version
+	trunks, err := types.ParseTrunks(vlanConfig.Trunk)
+	if err != nil {
+		klog.Errorf("ignore unexpected trunks %s: %s", vlanConfig.Trunk, err)
+	}
+	return trunks
+}
+
+// getExternalIDs return the external_ids of the row, the keys or values not string
+// are ignored, as the column could be written by other tools with weird values.
+func getExternalIDs(row ovsdb.Row) map[string]string {
+	externalIDs := make(map[string]string)
+	ovsExternalIDs, ok := row.Fields["external_ids"].(ovsdb.OvsMap)
+	if !ok {
+		return externalIDs
+	}
+	for key, value := range ovsExternalIDs.GoMap {
+		keyString, keyOK := key.(string)
+		valueString, valueOK := value.(string)
+		if keyOK && valueOK {
+			externalIDs[keyString] = valueString
+		}
+	}
+	return externalIDs
 }
 
 func getIPv4Addr(externalIDs map[interface{}]interface{}) net.IP {
-	if ip, ok := externalIDs[LocalEndpointIPv4]; ok {
-		return net.ParseIP(ip.(string)).To4()
+	if ip, ok := externalIDs[LocalEndpointIPv4].(string); ok {
+		return net.ParseIP(ip).To4()
 	}
 
 	return nil
@@ -83,26 +172,28 @@ func getIPv4Addr(externalIDs map[interface{}]interface{}) net.IP {
 
 func getDriverNameFromInterface(row ovsdb.Row) string {
 	if status, ok := row.Fields[InterfaceStatus].(ovsdb.OvsMap); ok {
-		if driver, ok := status.GoMap[InterfaceDriver]; ok {
-			return driver.(string)
-		}
+		driver, _ := status.GoMap[InterfaceDriver].(string)
+		return driver
 	}
 
 	return ""
 }
 
-func getMacStrFromInterface(row ovsdb.Row) (string, error) {
+// getMacStrFromInterface returns the attached-mac of the endpoints classified, or the mac_in_use.
+// The interfaces neither classified nor with the driver are not ready.
+func getMacStrFromInterface(row ovsdb.Row, classifier *EndpointClassifier) (string, error) {
 	var macStr string
-	driver := getDriverNameFromInterface(row)
-	if driver == "" {
+	isEndpoint := classifier.IsEndpoint(row)
+	if !isEndpoint && getDriverNameFromInterface(row) == "" {
 		return "", fmt.Errorf("get interface driver failed, interface row: %+v", row)
 	}
 
-	isErEp, mac := isErEndpointIntface(row, driver)
+	isErEp, mac := isErEndpointIntface(row, isEndpoint)
 	if isErEp {
 		macStr = mac
 	} else {
-		macStr = row.Fields["mac_in_use"].(string)
+		// field type is ovsdb.OvsSet instead of string when field empty
+		macStr, _ = row.Fields["mac_in_use"].(string)
 	}
 
 	if _, err := net.ParseMAC(macStr); err != nil {
@@ -112,11 +203,11 @@ func getMacStrFromInterface(row ovsdb.Row) (string, error) {
 	return macStr, nil
 }
 
-func isErEndpointIntface(row ovsdb.Row, driver string) (bool, string) {
-	if driver == VMNicDriver || driver == PodNicDriver {
+func isErEndpointIntface(row ovsdb.Row, isEndpoint bool) (bool, string) {
+	if isEndpoint {
 		if externalIds, ok := row.Fields["external_ids"].(ovsdb.OvsMap); ok {
-			if mac, ok := externalIds.GoMap[LocalEndpointIdentity]; ok {
-				return true, mac.(string)
+			if mac, ok := externalIds.GoMap[LocalEndpointIdentity].(string); ok {
+				return true, mac
 			}
 		}
 	}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"fmt"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+// IPMergePolicy decides the ips of an interface reported when learned from multiple sources.
+type IPMergePolicy string
+
+const (
+	// IPMergeUnion reports the ips learned from all the sources.
+	IPMergeUnion IPMergePolicy = "Union"
+	// IPMergeHighestPriority reports only the ips learned from the source with the highest
+	// priority of the interface, e.g. the ips announced in the arp are not reported once the
+	// interface has a dhcp lease, an endpoint could not claim the ips of the others by arp.
+	IPMergeHighestPriority IPMergePolicy = "HighestPriority"
+)
+
+// DefaultIPSourcePriority is the priority of the sources from the highest to the lowest.
+var DefaultIPSourcePriority = []agentv1alpha1.IPSource{
+	agentv1alpha1.IPSourceDeclared,
+	agentv1alpha1.IPSourceDHCP,
+	agentv1alpha1.IPSourceARP,
+}
+
+// IPSourceConfig configures the priority of the ip learning sources and how they merge.
+type IPSourceConfig struct {
+	// Priority are the sources from the highest priority to the lowest, the sources not in
+	// it are lower than all of them in the order of DefaultIPSourcePriority. Default
+	// DefaultIPSourcePriority if empty.
+	Priority []string
+	// MergePolicy is Union or HighestPriority, default Union if empty.
+	MergePolicy string
+}
+
+// IPSourcePolicy merges the ips of an interface learned from the sources, the source of an
+// ip is the one with the highest priority learned it.
+type IPSourcePolicy struct {
+	ranks       map[agentv1alpha1.IPSource]int
+	mergePolicy IPMergePolicy
+}
+
+// DefaultIPSourcePolicy reports the ips learned from all the sources.
+var DefaultIPSourcePolicy, _ = NewIPSourcePolicy(IPSourceConfig{})
+
+func NewIPSourcePolicy(config IPSourceConfig) (*IPSourcePolicy, error) {
+	policy := &IPSourcePolicy{
+		ranks:       make(map[agentv1alpha1.IPSource]int, len(DefaultIPSourcePriority)),
+		mergePolicy: IPMergePolicy(config.MergePolicy),
+	}
+
+	switch policy.mergePolicy {
+	case "":
+		policy.mergePolicy = IPMergeUnion
+	case IPMergeUnion, IPMergeHighestPriority:
+	default:
+		return nil, fmt.Errorf("unknown ip merge policy %s", config.MergePolicy)
+	}
+
+	known := make(map[agentv1alpha1.IPSource]bool, len(DefaultIPSourcePriority))
+	for _, source := range DefaultIPSourcePriority {
+		known[source] = true
+	}
+	for _, item := range config.Priority {
+		source := agentv1alpha1.IPSource(item)
+		if !known[source] {
+			return nil, fmt.Errorf("unknown ip source %s", item)
+		}
+		if _, ok := policy.ranks[source]; ok {
+			return nil, fmt.Errorf("duplicate ip source %s", item)
+		}
+		policy.ranks[source] = len(policy.ranks)
+	}
+	for _, source := range DefaultIPSourcePriority {
+		if _, ok := policy.ranks[source]; !ok {
+			policy.ranks[source] = len(policy.ranks)
+		}
+	}
+	return policy, nil
+}
+
+// Prefer returns true if the source has the higher priority than the current one, any
+// source is preferred to an empty current.
+func (p *IPSourcePolicy) Prefer(source, current agentv1alpha1.IPSource) bool {
+	if current == "" {
+		return true
+	}
+	return p.ranks[source] < p.ranks[current]
+}
+
+// Merge returns the sources of the ips reported out of the ips learned of an interface.
+func (p *IPSourcePolicy) Merge(sources map[types.IPAddress]agentv1alpha1.IPSource) map[types.IPAddress]agentv1alpha1.IPSource {
+	if len(sources) == 0 {
+		return nil
+	}
+	if p.mergePolicy == IPMergeUnion {
+		return sources
+	}
+
+	var highest agentv1alpha1.IPSource
+	for _, source := range sources {
+		if p.Prefer(source, highest) {
+			highest = source
+		}
+	}
+	merged := make(map[types.IPAddress]agentv1alpha1.IPSource, len(sources))
+	for ip, source := range sources {
+		if source == highest {
+			merged[ip] = source
+		}
+	}
+	return merged
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	"github.com/everoute/everoute/pkg/types"
+)
+
+func TestNewIPSourcePolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, config := range []IPSourceConfig{
+		{Priority: []string{"eBPF"}},
+		{Priority: []string{"ARP", "ARP"}},
+		{MergePolicy: "Intersection"},
+	} {
+		_, err := NewIPSourcePolicy(config)
+		Expect(err).Should(HaveOccurred())
+	}
+
+	policy, err := NewIPSourcePolicy(IPSourceConfig{Priority: []string{"ARP"}})
+	Expect(err).ShouldNot(HaveOccurred())
+	// the sources not configured are lower in the default order
+	Expect(policy.Prefer(agentv1alpha1.IPSourceARP, agentv1alpha1.IPSourceDeclared)).Should(BeTrue())
+	Expect(policy.Prefer(agentv1alpha1.IPSourceDeclared, agentv1alpha1.IPSourceDHCP)).Should(BeTrue())
+	Expect(policy.Prefer(agentv1alpha1.IPSourceDHCP, "")).Should(BeTrue())
+
+	Expect(DefaultIPSourcePolicy.Prefer(agentv1alpha1.IPSourceDHCP, agentv1alpha1.IPSourceARP)).Should(BeTrue())
+	Expect(DefaultIPSourcePolicy.Prefer(agentv1alpha1.IPSourceARP, agentv1alpha1.IPSourceDHCP)).Should(BeFalse())
+	Expect(DefaultIPSourcePolicy.Prefer(agentv1alpha1.IPSourceDHCP, agentv1alpha1.IPSourceDHCP)).Should(BeFalse())
+}
+
+func TestMergeIPSources(t *testing.T) {
+	RegisterTestingT(t)
+
+	sources := map[types.IPAddress]agentv1alpha1.IPSource{
+		"10.0.0.1": agentv1alpha1.IPSourceARP,
+		"10.0.0.2": agentv1alpha1.IPSourceDHCP,
+		"10.0.0.3": agentv1alpha1.IPSourceDHCP,
+	}
+	Expect(DefaultIPSourcePolicy.Merge(sources)).Should(Equal(sources))
+	Expect(DefaultIPSourcePolicy.Merge(nil)).Should(BeEmpty())
+
+	policy, err := NewIPSourcePolicy(IPSourceConfig{MergePolicy: "HighestPriority"})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(policy.Merge(sources)).Should(Equal(map[types.IPAddress]agentv1alpha1.IPSource{
+		"10.0.0.2": agentv1alpha1.IPSourceDHCP,
+		"10.0.0.3": agentv1alpha1.IPSourceDHCP,
+	}))
+}
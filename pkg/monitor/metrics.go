@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/metrics"
+)
+
+const (
+	// syncErrorTypeError is the agentinfo sync failed, it's retried soon.
+	syncErrorTypeError = "error"
+
+	endpointEventAdd    = "add"
+	endpointEventDelete = "delete"
+	endpointEventUpdate = "update"
+
+	bridgeEventAdd      = "bridge_add"
+	bridgeEventDelete   = "bridge_delete"
+	portVlanEventUpdate = "port_vlan_update"
+)
+
+var (
+	agentInfoSyncs = metrics.NewCounter(prometheus.CounterOpts{
+		Subsystem: "monitor",
+		Name:      "agentinfo_syncs_total",
+		Help:      "Number of attempts syncing the agentinfo to the control plane.",
+	})
+
+	agentInfoSyncErrors = metrics.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "monitor",
+		Name:      "agentinfo_sync_errors_total",
+		Help:      "Number of failed agentinfo syncs.",
+	}, []string{metrics.LabelType})
+
+	agentInfoSyncDuration = metrics.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "monitor",
+		Name:      "agentinfo_sync_duration_seconds",
+		Help:      "Duration of syncing the agentinfo to the control plane.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	ovsdbCacheRows = metrics.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_cache_rows",
+		Help:      "Number of rows of the table in the ovsdb cache.",
+	}, []string{metrics.LabelTable})
+
+	learnedIPs = metrics.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "monitor",
+		Name:      "learned_ips",
+		Help:      "Number of the ips learned on the local ofports not aged out.",
+	})
+
+	endpointEvents = metrics.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "monitor",
+		Name:      "endpoint_events_total",
+		Help:      "Number of the local endpoint events emitted from ovsdb, by add, delete or update.",
+	}, []string{metrics.LabelType})
+
+	ovsdbUpdateRows = metrics.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_update_rows_total",
+		Help:      "Number of the rows inserted, modified or deleted in the ovsdb updates received.",
+	}, []string{metrics.LabelTable})
+
+	ovsdbPendingUpdates = metrics.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_pending_updates",
+		Help:      "Number of the ovsdb updates received but the events not handled yet.",
+	})
+
+	ovsdbLastUpdateAge = metrics.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_last_update_age_seconds",
+		Help:      "Age of the last ovsdb update processed, from received to the events handled.",
+	})
+
+	ovsdbUpdateLag = metrics.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_update_lag_seconds",
+		Help:      "Duration from the ovsdb updates received to the events handled, including the time queued.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	})
+
+	ovsdbEventHandlerDuration = metrics.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_event_handler_duration_seconds",
+		Help:      "Duration of the handlers handling the events emitted from ovsdb, by event type.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{metrics.LabelType})
+)
+
+func init() {
+	metrics.MustRegister(agentInfoSyncs, agentInfoSyncErrors, agentInfoSyncDuration, ovsdbCacheRows, learnedIPs, endpointEvents,
+		ovsdbUpdateRows, ovsdbPendingUpdates, ovsdbLastUpdateAge, ovsdbUpdateLag, ovsdbEventHandlerDuration)
+}
+
+// instrumentedEventHandler counts the local endpoint events, and times the handlers of all
+// the events in the metrics.
+type instrumentedEventHandler struct {
+	ovsdbEventHandler
+}
+
+func (handler instrumentedEventHandler) AddLocalEndpoint(endpoint *datapath.Endpoint) {
+	defer observeEventHandler(endpointEventAdd, time.Now())
+	endpointEvents.WithLabelValues(endpointEventAdd).Inc()
+	handler.ovsdbEventHandler.AddLocalEndpoint(endpoint)
+}
+
+func (handler instrumentedEventHandler) DeleteLocalEndpoint(endpoint *datapath.Endpoint) {
+	defer observeEventHandler(endpointEventDelete, time.Now())
+	endpointEvents.WithLabelValues(endpointEventDelete).Inc()
+	handler.ovsdbEventHandler.DeleteLocalEndpoint(endpoint)
+}
+
+func (handler instrumentedEventHandler) UpdateLocalEndpoint(newEndpoint *datapath.Endpoint, oldEndpoint *datapath.Endpoint) {
+	defer observeEventHandler(endpointEventUpdate, time.Now())
+	endpointEvents.WithLabelValues(endpointEventUpdate).Inc()
+	handler.ovsdbEventHandler.UpdateLocalEndpoint(newEndpoint, oldEndpoint)
+}
+
+func (handler instrumentedEventHandler) AddBridge(bridgeName string) {
+	defer observeEventHandler(bridgeEventAdd, time.Now())
+	handler.ovsdbEventHandler.AddBridge(bridgeName)
+}
+
+func (handler instrumentedEventHandler) DeleteBridge(bridgeName string) {
+	defer observeEventHandler(bridgeEventDelete, time.Now())
+	handler.ovsdbEventHandler.DeleteBridge(bridgeName)
+}
+
+func (handler instrumentedEventHandler) UpdatePortVlan(newVlan *PortVlan, oldVlan *PortVlan) {
+	defer observeEventHandler(portVlanEventUpdate, time.Now())
+	handler.ovsdbEventHandler.UpdatePortVlan(newVlan, oldVlan)
+}
+
+func observeEventHandler(eventType string, start time.Time) {
+	ovsdbEventHandlerDuration.WithLabelValues(eventType).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+// fetchOVNBridgesLocked returns the bridges owned by OVN, the integration bridge first,
+// none if OVN not configured on the host.
+func fetchOVNBridgesLocked(ovsdbCache OVSDBCache) []string {
+	for uuid, raw := range ovsdbCache["Open_vSwitch"] {
+		reader := newRowReader("Open_vSwitch", uuid, raw)
+		externalIDs := reader.StringMap("external_ids")
+		if err := reader.Err(); err != nil {
+			klog.Errorf("ignore unexpected external_ids of Open_vSwitch: %s", err)
+		}
+		return datapath.ParseOVNBridges(externalIDs)
+	}
+	return nil
+}
+
+// removeOVNInterfaces removes the logical ports bound by ovn-controller from the bridges,
+// the ports are owned by OVN and must not be claimed as endpoints. The ports left without
+// interfaces are removed.
+func removeOVNInterfaces(bridges []agentv1alpha1.OVSBridge, ovnBridges []string) {
+	for i := range bridges {
+		var ports []agentv1alpha1.OVSPort
+		for _, port := range bridges[i].Ports {
+			var ifaces []agentv1alpha1.OVSInterface
+			for _, iface := range port.Interfaces {
+				if !datapath.IsOVNInterface(bridges[i].Name, iface.ExternalIDs, ovnBridges) {
+					ifaces = append(ifaces, iface)
+				}
+			}
+			if len(port.Interfaces) != 0 && len(ifaces) == 0 {
+				continue
+			}
+			port.Interfaces = ifaces
+			ports = append(ports, port)
+		}
+		bridges[i].Ports = ports
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+
+	ovsdb "github.com/contiv/libovsdb"
+	. "github.com/onsi/gomega"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+func TestFetchOVNBridges(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(fetchOVNBridgesLocked(OVSDBCache{})).Should(BeEmpty())
+	Expect(fetchOVNBridgesLocked(OVSDBCache{"Open_vSwitch": {"ovs": ovsdb.Row{Fields: map[string]interface{}{
+		"external_ids": ovsdb.OvsMap{GoMap: map[interface{}]interface{}{
+			"ovn-remote":          "tcp:10.0.0.1:6642",
+			"ovn-bridge-mappings": "physnet1:br-ex",
+		}},
+	}}}})).Should(Equal([]string{"br-int", "br-ex"}))
+}
+
+func TestRemoveOVNInterfaces(t *testing.T) {
+	RegisterTestingT(t)
+
+	newPort := func(name string, externalIDs map[string]string) agentv1alpha1.OVSPort {
+		return agentv1alpha1.OVSPort{Name: name, Interfaces: []agentv1alpha1.OVSInterface{{Name: name, ExternalIDs: externalIDs}}}
+	}
+	bridges := []agentv1alpha1.OVSBridge{
+		{Name: "br-int", Ports: []agentv1alpha1.OVSPort{
+			{Name: "br-int"},
+			newPort("lsp1", map[string]string{"iface-id": "lsp1"}),
+			newPort("patch-br-int-to-br-ex", nil),
+		}},
+		{Name: "ovsbr0", Ports: []agentv1alpha1.OVSPort{
+			newPort("vnet0", map[string]string{"iface-id": "vm1"}),
+			newPort("vnet1", map[string]string{"iface-id": "vm2", "ovn-installed": "true"}),
+		}},
+	}
+
+	removeOVNInterfaces(bridges, []string{"br-int", "br-ex"})
+	Expect(bridges[0].Ports).Should(Equal([]agentv1alpha1.OVSPort{
+		{Name: "br-int"},
+		newPort("patch-br-int-to-br-ex", nil),
+	}))
+	Expect(bridges[1].Ports).Should(Equal([]agentv1alpha1.OVSPort{
+		newPort("vnet0", map[string]string{"iface-id": "vm1"}),
+	}))
+}
@@ -20,15 +20,18 @@ import (
 	"fmt"
 	"net"
 	"reflect"
-	"strings"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/cenkalti/backoff"
 	ovsdb "github.com/contiv/libovsdb"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 
 	"github.com/everoute/everoute/pkg/agent/datapath"
+	"github.com/everoute/everoute/pkg/types"
 	"github.com/everoute/everoute/pkg/utils"
 )
 
@@ -38,18 +41,39 @@ const (
 	OvsDBInterfaceTable = "Interface"
 
 	OvsdbUpdatesChanSize = 100
+
+	// OvsdbConnectMaxElapsedTime is the max time retry connecting to ovsdb on start, with
+	// exponential backoff, e.g. the ovsdb-server is restarting with the agent.
+	OvsdbConnectMaxElapsedTime = 5 * time.Minute
 )
 
 type ovsdbEventHandler interface {
 	AddLocalEndpoint(endpoint *datapath.Endpoint)
 	DeleteLocalEndpoint(endpoint *datapath.Endpoint)
 	UpdateLocalEndpoint(newEndpoint *datapath.Endpoint, oldEndpoint *datapath.Endpoint)
+	AddBridge(bridgeName string)
+	DeleteBridge(bridgeName string)
+	UpdatePortVlan(newVlan *PortVlan, oldVlan *PortVlan)
+}
+
+// PortVlan is the vlan config of an ovs port, bond ports included.
+type PortVlan struct {
+	PortName   string
+	BridgeName string
+	// VlanMode is the vlan_mode in ovsdb, empty if not set
+	VlanMode string
+	// Tag is the access vlan, 0 if not set
+	Tag    uint16
+	Trunks []uint16
 }
 
 type OvsdbEventHandlerFuncs struct {
 	LocalEndpointAddFunc    func(endpoint *datapath.Endpoint)
 	LocalEndpointDeleteFunc func(endpoint *datapath.Endpoint)
 	LocalEndpointUpdateFunc func(newEndpoint *datapath.Endpoint, oldEndpoint *datapath.Endpoint)
+	BridgeAddFunc           func(bridgeName string)
+	BridgeDeleteFunc        func(bridgeName string)
+	PortVlanChangeFunc      func(newVlan *PortVlan, oldVlan *PortVlan)
 }
 
 func (handler OvsdbEventHandlerFuncs) AddLocalEndpoint(endpoint *datapath.Endpoint) {
@@ -70,8 +94,82 @@ func (handler OvsdbEventHandlerFuncs) UpdateLocalEndpoint(newEndpoint *datapath.
 	}
 }
 
+func (handler OvsdbEventHandlerFuncs) AddBridge(bridgeName string) {
+	if handler.BridgeAddFunc != nil {
+		handler.BridgeAddFunc(bridgeName)
+	}
+}
+
+func (handler OvsdbEventHandlerFuncs) DeleteBridge(bridgeName string) {
+	if handler.BridgeDeleteFunc != nil {
+		handler.BridgeDeleteFunc(bridgeName)
+	}
+}
+
+func (handler OvsdbEventHandlerFuncs) UpdatePortVlan(newVlan *PortVlan, oldVlan *PortVlan) {
+	if handler.PortVlanChangeFunc != nil {
+		handler.PortVlanChangeFunc(newVlan, oldVlan)
+	}
+}
+
+// ovsdbEventHandlers dispatches the events to the handlers in the order registered.
+type ovsdbEventHandlers []ovsdbEventHandler
+
+func (handlers ovsdbEventHandlers) AddLocalEndpoint(endpoint *datapath.Endpoint) {
+	for _, handler := range handlers {
+		handler.AddLocalEndpoint(endpoint)
+	}
+}
+
+func (handlers ovsdbEventHandlers) DeleteLocalEndpoint(endpoint *datapath.Endpoint) {
+	for _, handler := range handlers {
+		handler.DeleteLocalEndpoint(endpoint)
+	}
+}
+
+func (handlers ovsdbEventHandlers) UpdateLocalEndpoint(newEndpoint *datapath.Endpoint, oldEndpoint *datapath.Endpoint) {
+	for _, handler := range handlers {
+		handler.UpdateLocalEndpoint(newEndpoint, oldEndpoint)
+	}
+}
+
+func (handlers ovsdbEventHandlers) AddBridge(bridgeName string) {
+	for _, handler := range handlers {
+		handler.AddBridge(bridgeName)
+	}
+}
+
+func (handlers ovsdbEventHandlers) DeleteBridge(bridgeName string) {
+	for _, handler := range handlers {
+		handler.DeleteBridge(bridgeName)
+	}
+}
+
+func (handlers ovsdbEventHandlers) UpdatePortVlan(newVlan *PortVlan, oldVlan *PortVlan) {
+	for _, handler := range handlers {
+		handler.UpdatePortVlan(newVlan, oldVlan)
+	}
+}
+
 type OVSDBCache map[string]map[string]ovsdb.Row
 
+// selectAll monitors the initial rows and all the changes of the table
+var selectAll = ovsdb.MonitorSelect{
+	Initial: true,
+	Insert:  true,
+	Delete:  true,
+	Modify:  true,
+}
+
+// ovsdbUpdates is the table updates from ovsdb, resync means the updates is a full dump
+// of ovsdb sent when the monitor reset after reconnected to ovsdb.
+type ovsdbUpdates struct {
+	ovsdb.TableUpdates
+	resync bool
+	// received is the time the updates received from ovsdb, the lag of the events counts from it
+	received time.Time
+}
+
 // OVSDBMonitor monitor and cache ovsdb, the syncQueue are queued on cache updates
 type OVSDBMonitor struct {
 	// ovsClient used to monitor ovsdb table port/bridge/interface
@@ -80,46 +178,85 @@ type OVSDBMonitor struct {
 	// cacheLock is a read/write lock for accessing the cache
 	cacheLock  sync.RWMutex
 	ovsdbCache OVSDBCache
-
-	ovsdbEventHandler ovsdbEventHandler
+	// databaseCaches are the caches of the ExtraDatabases, keyed by the database name
+	databaseCaches map[string]OVSDBCache
+
+	// ExtraDatabases are the databases monitored in addition to Open_vSwitch, e.g. the
+	// hardware_vtep, each with its own connection, set before Run.
+	ExtraDatabases []OVSDBDatabase
+	// EndpointClassifier classifies the interfaces as the endpoints, DefaultEndpointClassifier
+	// if nil, set before Run.
+	EndpointClassifier *EndpointClassifier
+
+	ovsdbEventHandler  ovsdbEventHandler
+	ovsdbEventHandlers ovsdbEventHandlers
 	// map interface uuid
-	endpointMap      map[string]*datapath.Endpoint
-	bridgeMap        map[string]sets.String
-	ovsdbUpdatesChan chan ovsdb.TableUpdates
+	endpointMap map[string]*datapath.Endpoint
+	// detachedEndpoints are the endpoints lost the ofport only, e.g. the ofport transitions through
+	// -1 while ovs reassigning it, keyed by the interface uuid. The handlers keep them with the last
+	// ofport until they reattached or deleted.
+	detachedEndpoints map[string]*datapath.Endpoint
+	bridgeMap         map[string]sets.String
+	// portVlanMap are the vlan configs of the ports, keyed by the port uuid
+	portVlanMap      map[string]*PortVlan
+	ovsdbUpdatesChan chan ovsdbUpdates
+	// initialDumped is true after the initial dump of ovsdb received, the full dumps
+	// received later are sent on reconnect, only accessed in the ovsdb update handler
+	initialDumped bool
 
 	// syncQueue used to notify ovsdb update
 	syncQueue workqueue.RateLimitingInterface
+
+	// initialSynced closed after the initial dump of ovsdb has been handled
+	initialSynced     chan struct{}
+	initialSyncedOnce sync.Once
 }
 
 // NewOVSDBMonitor create a new instance of OVSDBMonitor
 func NewOVSDBMonitor() (*OVSDBMonitor, error) {
-	ovsClient, err := ovsdb.ConnectUnix(ovsdb.DEFAULT_SOCK)
+	var ovsClient *ovsdb.OvsdbClient
+	connectBackOff := backoff.NewExponentialBackOff()
+	connectBackOff.MaxElapsedTime = OvsdbConnectMaxElapsedTime
+
+	err := backoff.Retry(func() error {
+		var err error
+		ovsClient, err = ovsdb.ConnectUnix(ovsdb.DEFAULT_SOCK)
+		if err != nil {
+			klog.Errorf("unable connect to ovsdb, will retry: %s", err)
+		}
+		return err
+	}, connectBackOff)
 	if err != nil {
 		return nil, err
 	}
 
 	monitor := &OVSDBMonitor{
-		ovsClient:        ovsClient,
-		cacheLock:        sync.RWMutex{},
-		endpointMap:      make(map[string]*datapath.Endpoint),
-		ovsdbCache:       make(map[string]map[string]ovsdb.Row),
-		syncQueue:        workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
-		bridgeMap:        make(map[string]sets.String),
-		ovsdbUpdatesChan: make(chan ovsdb.TableUpdates, OvsdbUpdatesChanSize),
+		ovsClient:         ovsClient,
+		cacheLock:         sync.RWMutex{},
+		endpointMap:       make(map[string]*datapath.Endpoint),
+		detachedEndpoints: make(map[string]*datapath.Endpoint),
+		ovsdbCache:        make(map[string]map[string]ovsdb.Row),
+		databaseCaches:    make(map[string]OVSDBCache),
+		syncQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+		bridgeMap:         make(map[string]sets.String),
+		portVlanMap:       make(map[string]*PortVlan),
+		ovsdbUpdatesChan:  make(chan ovsdbUpdates, OvsdbUpdatesChanSize),
+		initialSynced:     make(chan struct{}),
 	}
 
 	return monitor, nil
 }
 
+// RegisterOvsdbEventHandler registers the handler of the local endpoint, bridge and port vlan
+// events, should be called before Run. The subsystems could register their own handlers, the
+// events are dispatched to the handlers in the order registered.
 func (monitor *OVSDBMonitor) RegisterOvsdbEventHandler(ovsdbEventHandler ovsdbEventHandler) {
 	if ovsdbEventHandler == nil {
 		klog.Fatalf("Failed to register ovsdbEventHandler: register nil ovsdbEventHandler not allow")
 	}
-	if monitor.ovsdbEventHandler != nil {
-		klog.Fatalf("Failed to register ovsdbEventHandler: monitor ovsdbEventHandler already register")
-	}
 
-	monitor.ovsdbEventHandler = ovsdbEventHandler
+	monitor.ovsdbEventHandlers = append(monitor.ovsdbEventHandlers, ovsdbEventHandler)
+	monitor.ovsdbEventHandler = instrumentedEventHandler{monitor.ovsdbEventHandlers}
 }
 
 func (monitor *OVSDBMonitor) LockedAccessCache(readFunc func(OVSDBCache) error) error {
@@ -132,6 +269,17 @@ func (monitor *OVSDBMonitor) GetSyncQueue() workqueue.RateLimitingInterface {
 	return monitor.syncQueue
 }
 
+// WaitForInitialSync waits until the local endpoints in the initial dump of ovsdb
+// have been handled, returns false if stopped before that.
+func (monitor *OVSDBMonitor) WaitForInitialSync(stopChan <-chan struct{}) bool {
+	select {
+	case <-monitor.initialSynced:
+		return true
+	case <-stopChan:
+		return false
+	}
+}
+
 func (monitor *OVSDBMonitor) Run(stopChan <-chan struct{}) {
 	defer monitor.ovsClient.Disconnect()
 
@@ -143,6 +291,9 @@ func (monitor *OVSDBMonitor) Run(stopChan <-chan struct{}) {
 		klog.Fatalf("unable start ovsdb monitor: %s", err)
 	}
 	go monitor.handleOvsEvents(stopChan)
+	for _, database := range monitor.ExtraDatabases {
+		go monitor.runDatabaseMonitor(database, stopChan)
+	}
 
 	<-stopChan
 }
@@ -151,17 +302,14 @@ func (monitor *OVSDBMonitor) startOvsdbMonitor() error {
 	klog.Infof("start monitor ovsdb %s", "Open_vSwitch")
 	monitor.ovsClient.Register(ovsUpdateHandlerFunc(monitor.handleOvsUpdates))
 
-	selectAll := ovsdb.MonitorSelect{
-		Initial: true,
-		Insert:  true,
-		Delete:  true,
-		Modify:  true,
-	}
 	requests := map[string]ovsdb.MonitorRequest{
-		"Port":         {Select: selectAll, Columns: []string{"name", "interfaces", "external_ids", "bond_mode", "vlan_mode", "tag", "trunks"}},
-		"Interface":    {Select: selectAll, Columns: []string{"name", "mac_in_use", "ofport", "type", "external_ids", "error", "status"}},
-		"Bridge":       {Select: selectAll, Columns: []string{"name", "ports"}},
-		"Open_vSwitch": {Select: selectAll, Columns: []string{"ovs_version"}},
+		"Port":         {Select: selectAll, Columns: []string{"name", "interfaces", "external_ids", "bond_mode", "vlan_mode", "tag", "trunks", "status", "rstp_status"}},
+		"Interface":    {Select: selectAll, Columns: []string{"name", "mac_in_use", "ofport", "type", "external_ids", "error", "status", "link_state", "bfd_status"}},
+		"Bridge":       {Select: selectAll, Columns: []string{"name", "ports", "stp_enable", "rstp_enable", "sflow", "mirrors"}},
+		"Open_vSwitch": {Select: selectAll, Columns: []string{"ovs_version", "external_ids"}},
+		"sFlow":        {Select: selectAll, Columns: []string{"agent", "targets", "sampling", "polling", "header", "external_ids"}},
+		"Mirror": {Select: selectAll, Columns: []string{"name", "select_all", "select_src_port", "select_dst_port",
+			"select_vlan", "output_port", "output_vlan", "external_ids"}},
 	}
 
 	err := monitor.ovsClient.Monitor("Open_vSwitch", nil, requests)
@@ -175,7 +323,7 @@ func (monitor *OVSDBMonitor) startOvsdbMonitor() error {
 func (monitor *OVSDBMonitor) filterPortVlanModeUpdate(rowupdate ovsdb.RowUpdate, ifaceUUID string) (*datapath.Endpoint, *datapath.Endpoint) {
 	var newEndpoint, oldEndpoint *datapath.Endpoint
 	var oldTag, newTag *float64
-	var oldTrunk, newTrunk []float64
+	var oldTrunk, newTrunk types.Trunks
 	var ok bool
 
 	oldEndpoint, ok = monitor.endpointMap[ifaceUUID]
@@ -203,14 +351,13 @@ func (monitor *OVSDBMonitor) filterPortVlanModeUpdate(rowupdate ovsdb.RowUpdate,
 
 	// access to trunk
 	if newTag == nil && oldTag != nil && len(newTrunk) != 0 && len(oldTrunk) == 0 {
-		trunkString := strings.Trim(strings.Join(strings.Split(fmt.Sprintf("%v", newTrunk), " "), ","), "[]")
 		newEndpoint = &datapath.Endpoint{
 			InterfaceName: oldEndpoint.InterfaceName,
 			InterfaceUUID: oldEndpoint.InterfaceUUID,
 			MacAddrStr:    oldEndpoint.MacAddrStr,
 			PortNo:        oldEndpoint.PortNo,
 			BridgeName:    oldEndpoint.BridgeName,
-			Trunk:         trunkString,
+			Trunk:         newTrunk.String(),
 			VlanID:        0,
 		}
 	}
@@ -262,7 +409,7 @@ func (monitor *OVSDBMonitor) filterPortVlanTagUpdate(rowupdate ovsdb.RowUpdate,
 
 func (monitor *OVSDBMonitor) filterPortVlanTrunkUpdate(rowupdate ovsdb.RowUpdate, ifaceUUID string) (*datapath.Endpoint, *datapath.Endpoint) {
 	var newEndpoint, oldEndpoint *datapath.Endpoint
-	var oldTrunk, newTrunk []float64
+	var oldTrunk, newTrunk types.Trunks
 
 	if _, ok := monitor.endpointMap[ifaceUUID]; !ok {
 		return nil, nil
@@ -285,7 +432,7 @@ func (monitor *OVSDBMonitor) filterPortVlanTrunkUpdate(rowupdate ovsdb.RowUpdate
 		MacAddrStr:    oldEndpoint.MacAddrStr,
 		PortNo:        oldEndpoint.PortNo,
 		BridgeName:    oldEndpoint.BridgeName,
-		Trunk:         strings.Trim(strings.Join(strings.Split(fmt.Sprintf("%v", newTrunk), " "), ","), "[]"),
+		Trunk:         newTrunk.String(),
 	}
 
 	return newEndpoint, oldEndpoint
@@ -299,11 +446,51 @@ func (monitor *OVSDBMonitor) processOvsBridgeAdd(row ovsdb.RowUpdate) {
 		portUUIDs.Insert(port.GoUuid)
 	}
 	monitor.bridgeMap[bridgeName] = portUUIDs
+	monitor.ovsdbEventHandler.AddBridge(bridgeName)
 }
 
 func (monitor *OVSDBMonitor) processOvsBridgeDelete(row ovsdb.RowUpdate) {
 	bridgeName := row.Old.Fields["name"].(string)
 	delete(monitor.bridgeMap, bridgeName)
+	monitor.ovsdbEventHandler.DeleteBridge(bridgeName)
+}
+
+// newPortVlan returns the vlan config of the port row, the trunks are sorted.
+func (monitor *OVSDBMonitor) newPortVlan(uuid string, row ovsdb.Row) *PortVlan {
+	reader := newRowReader(OvsDBPortTable, uuid, row)
+	tag, _ := reader.Float("tag")
+	portVlan := &PortVlan{
+		PortName:   reader.String("name"),
+		BridgeName: monitor.getPortBridgeName(uuid),
+		VlanMode:   reader.String("vlan_mode"),
+		Tag:        uint16(tag),
+	}
+	for _, trunk := range reader.Floats("trunks") {
+		portVlan.Trunks = append(portVlan.Trunks, uint16(trunk))
+	}
+	sort.Slice(portVlan.Trunks, func(i, j int) bool { return portVlan.Trunks[i] < portVlan.Trunks[j] })
+
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected vlan values of port %s: %s", portVlan.PortName, err)
+	}
+	return portVlan
+}
+
+func (monitor *OVSDBMonitor) processPortVlanAdd(uuid string, rowupdate ovsdb.RowUpdate) {
+	monitor.portVlanMap[uuid] = monitor.newPortVlan(uuid, rowupdate.New)
+}
+
+func (monitor *OVSDBMonitor) processPortVlanUpdate(uuid string, rowupdate ovsdb.RowUpdate) {
+	newVlan := monitor.newPortVlan(uuid, rowupdate.New)
+	oldVlan, ok := monitor.portVlanMap[uuid]
+	monitor.portVlanMap[uuid] = newVlan
+	if ok && !reflect.DeepEqual(newVlan, oldVlan) {
+		monitor.ovsdbEventHandler.UpdatePortVlan(newVlan, oldVlan)
+	}
+}
+
+func (monitor *OVSDBMonitor) processPortVlanDelete(uuid string) {
+	delete(monitor.portVlanMap, uuid)
 }
 
 func (monitor *OVSDBMonitor) processOvsBridgeUpdate(row ovsdb.RowUpdate) {
@@ -346,7 +533,7 @@ func (monitor *OVSDBMonitor) processOvsPortAdd(uuid string, rowupdate ovsdb.RowU
 		monitor.endpointMap[newIfaceUUID] = &datapath.Endpoint{}
 	}
 
-	var newTrunk []float64
+	var newTrunk types.Trunks
 	var newTag float64
 	if rowupdate.New.Fields["trunks"] != nil {
 		newTrunk = listVlanTrunks(rowupdate.New.Fields["trunks"])
@@ -358,7 +545,7 @@ func (monitor *OVSDBMonitor) processOvsPortAdd(uuid string, rowupdate ovsdb.RowU
 		}
 	}
 	if len(newTrunk) != 0 {
-		monitor.endpointMap[newIfaceUUID].Trunk = strings.Trim(strings.Join(strings.Split(fmt.Sprintf("%v", newTrunk), " "), ","), "[]")
+		monitor.endpointMap[newIfaceUUID].Trunk = newTrunk.String()
 	} else {
 		monitor.endpointMap[newIfaceUUID].VlanID = uint16(newTag)
 	}
@@ -385,7 +572,7 @@ func (monitor *OVSDBMonitor) processOvsInterfaceAdd(uuid string, rowupdate ovsdb
 		monitor.endpointMap[uuid].PortNo = uint32(ofPort)
 	}
 
-	macStr, err := getMacStrFromInterface(rowupdate.New)
+	macStr, err := getMacStrFromInterface(rowupdate.New, monitor.endpointClassifier())
 	if err != nil {
 		klog.Errorf("Failed to get interface %+v mac, err: %s", rowupdate, err)
 	}
@@ -485,7 +672,7 @@ func (monitor *OVSDBMonitor) processOvsInterfaceUpdate(uuid string, rowupdate ov
 		newOfPort = uint32(ofPort)
 	}
 
-	newMacStr, err := getMacStrFromInterface(rowupdate.New)
+	newMacStr, err := getMacStrFromInterface(rowupdate.New, monitor.endpointClassifier())
 	if err != nil {
 		klog.Errorf("Failed to get interface %+v mac, err: %s", rowupdate, err)
 	}
@@ -548,6 +735,7 @@ func (monitor *OVSDBMonitor) processOvsPortDelete(uuid string, rowupdate ovsdb.R
 	if monitor.isEndpointReady(oldEndpoint) {
 		monitor.ovsdbEventHandler.DeleteLocalEndpoint(monitor.endpointMap[oldIfaceUUID])
 	}
+	monitor.deleteDetachedEndpoint(oldIfaceUUID)
 	delete(monitor.endpointMap, uuid)
 }
 
@@ -562,6 +750,7 @@ func (monitor *OVSDBMonitor) processOvsInterfaceDelete(uuid string, rowupdate ov
 	if monitor.isEndpointReady(oldEndpoint) {
 		monitor.ovsdbEventHandler.DeleteLocalEndpoint(monitor.endpointMap[uuid])
 	}
+	monitor.deleteDetachedEndpoint(uuid)
 	delete(monitor.endpointMap, uuid)
 }
 
@@ -577,34 +766,84 @@ func (monitor *OVSDBMonitor) getPortBridgeName(portUUID string) string {
 	return bridgeName
 }
 
+// updateEndpoint emits the events of the endpoint changed. The endpoint lost the ofport only is
+// detached instead of deleted, and updated to the new ofport once reattached, so the flows are
+// updated in place and the ips learned on it are kept.
 func (monitor *OVSDBMonitor) updateEndpoint(newEndpoint, oldEndpoint *datapath.Endpoint, ifaceUUID string) {
-	if monitor.isEndpointReady(oldEndpoint) && monitor.isEndpointReady(newEndpoint) {
+	detachedEndpoint, detached := monitor.detachedEndpoints[ifaceUUID]
+	oldReady, newReady := monitor.isEndpointReady(oldEndpoint), monitor.isEndpointReady(newEndpoint)
+
+	switch {
+	case detached && newReady:
+		klog.Infof("endpoint %s reattached from ofport %d to %d", ifaceUUID, detachedEndpoint.PortNo, newEndpoint.PortNo)
+		delete(monitor.detachedEndpoints, ifaceUUID)
+		monitor.ovsdbEventHandler.UpdateLocalEndpoint(newEndpoint, detachedEndpoint)
+	case detached && monitor.isEndpointDetached(newEndpoint):
+		// keep detached until the ofport reassigned
+	case detached:
+		delete(monitor.detachedEndpoints, ifaceUUID)
+		monitor.ovsdbEventHandler.DeleteLocalEndpoint(detachedEndpoint)
+	case oldReady && newReady:
 		monitor.ovsdbEventHandler.UpdateLocalEndpoint(newEndpoint, oldEndpoint)
-		delete(monitor.endpointMap, ifaceUUID)
-		monitor.endpointMap[ifaceUUID] = newEndpoint
-	}
-	if monitor.isEndpointReady(newEndpoint) && !monitor.isEndpointReady(oldEndpoint) {
+	case oldReady && monitor.isEndpointDetached(newEndpoint):
+		klog.Infof("endpoint %s detached from ofport %d", ifaceUUID, oldEndpoint.PortNo)
+		monitor.detachedEndpoints[ifaceUUID] = oldEndpoint
+	case oldReady:
+		monitor.ovsdbEventHandler.DeleteLocalEndpoint(oldEndpoint)
+	case newReady:
 		monitor.ovsdbEventHandler.AddLocalEndpoint(newEndpoint)
-		delete(monitor.endpointMap, ifaceUUID)
-		monitor.endpointMap[ifaceUUID] = newEndpoint
 	}
-	if !monitor.isEndpointReady(newEndpoint) && monitor.isEndpointReady(oldEndpoint) {
-		monitor.ovsdbEventHandler.DeleteLocalEndpoint(oldEndpoint)
-		delete(monitor.endpointMap, ifaceUUID)
+	monitor.endpointMap[ifaceUUID] = newEndpoint
+}
+
+// deleteDetachedEndpoint deletes the endpoint detached when its interface or port deleted.
+func (monitor *OVSDBMonitor) deleteDetachedEndpoint(ifaceUUID string) {
+	if detachedEndpoint, ok := monitor.detachedEndpoints[ifaceUUID]; ok {
+		delete(monitor.detachedEndpoints, ifaceUUID)
+		monitor.ovsdbEventHandler.DeleteLocalEndpoint(detachedEndpoint)
 	}
 }
 
+func (monitor *OVSDBMonitor) endpointClassifier() *EndpointClassifier {
+	if monitor.EndpointClassifier == nil {
+		return DefaultEndpointClassifier
+	}
+	return monitor.EndpointClassifier
+}
+
 func (monitor *OVSDBMonitor) isEndpointReady(endpoint *datapath.Endpoint) bool {
 	return endpoint.BridgeName != "" && endpoint.InterfaceUUID != "" &&
 		endpoint.InterfaceName != "" && endpoint.MacAddrStr != "" && endpoint.PortNo != 0
 }
 
-func (monitor *OVSDBMonitor) handleOvsUpdates(updates ovsdb.TableUpdates) {
+// isEndpointDetached returns true if the endpoint is ready except the ofport.
+func (monitor *OVSDBMonitor) isEndpointDetached(endpoint *datapath.Endpoint) bool {
+	return endpoint.BridgeName != "" && endpoint.InterfaceUUID != "" &&
+		endpoint.InterfaceName != "" && endpoint.MacAddrStr != "" && endpoint.PortNo == 0
+}
+
+// handleOvsUpdates caches the updates and queues them for the ovsdb event handler. The ovsdb
+// client reconnects when the ovsdb-server restarted, then resets the monitor, which sends the
+// updates deleting the rows gone and a full dump of ovsdb. The cache is re-populated from the
+// full dump, and the endpoints are resynced from it.
+func (monitor *OVSDBMonitor) handleOvsUpdates(context interface{}, updates ovsdb.TableUpdates) {
+	// the full dumps are sent with no context, the updates notified have context
+	var resync bool
+	if context == nil && isFullDump(updates) {
+		resync = monitor.initialDumped
+		monitor.initialDumped = true
+	}
+
 	monitor.cacheLock.Lock()
+	if resync {
+		klog.Infof("ovsdb monitor reset after reconnected, rebuild ovsdb cache")
+		monitor.ovsdbCache = make(OVSDBCache)
+	}
 	for table, tableUpdate := range updates.Updates {
 		if _, ok := monitor.ovsdbCache[table]; !ok {
 			monitor.ovsdbCache[table] = make(map[string]ovsdb.Row)
 		}
+		ovsdbUpdateRows.WithLabelValues(table).Add(float64(len(tableUpdate.Rows)))
 		for uuid, row := range tableUpdate.Rows {
 			empty := ovsdb.Row{}
 			if !reflect.DeepEqual(row.New, empty) {
@@ -614,24 +853,49 @@ func (monitor *OVSDBMonitor) handleOvsUpdates(updates ovsdb.TableUpdates) {
 			}
 		}
 	}
+	for table, rows := range monitor.ovsdbCache {
+		ovsdbCacheRows.WithLabelValues(table).Set(float64(len(rows)))
+	}
 	monitor.cacheLock.Unlock()
 
 	monitor.syncQueue.Add("ovsdb-event")
-	monitor.ovsdbUpdatesChan <- updates
+	ovsdbPendingUpdates.Inc()
+	monitor.ovsdbUpdatesChan <- ovsdbUpdates{TableUpdates: updates, resync: resync, received: time.Now()}
 }
 
 func (monitor *OVSDBMonitor) handleOvsEvents(stopChan <-chan struct{}) {
 	for {
 		select {
 		case updates := <-monitor.ovsdbUpdatesChan:
-			monitor.ovsdbEventFilter(updates)
+			monitor.processOvsUpdates(updates)
+			// the initial dump is always the first updates, sent when start monitor
+			monitor.initialSyncedOnce.Do(func() { close(monitor.initialSynced) })
 		case <-stopChan:
 			return
 		}
 	}
 }
 
+// processOvsUpdates emits the events of the updates queued, the lag from the updates received
+// to the events handled is recorded, it grows when the handlers fall behind the ovsdb changes,
+// e.g. lots of vms booted at the same time.
+func (monitor *OVSDBMonitor) processOvsUpdates(updates ovsdbUpdates) {
+	if updates.resync {
+		monitor.resyncEndpoints(updates.TableUpdates)
+	} else {
+		monitor.ovsdbEventFilter(updates.TableUpdates)
+	}
+
+	lag := time.Since(updates.received).Seconds()
+	ovsdbUpdateLag.Observe(lag)
+	ovsdbLastUpdateAge.Set(lag)
+	ovsdbPendingUpdates.Dec()
+}
+
+// ovsdbEventFilter emits the events of the updates. The bridges added are handled before the
+// ports, and the bridges deleted after, so the handlers see the bridges of the endpoints.
 func (monitor *OVSDBMonitor) ovsdbEventFilter(updates ovsdb.TableUpdates) {
+	var deletedBridges []ovsdb.RowUpdate
 	bridgeUpdate, ok := updates.Updates[OvsDBBridgeTable]
 	empty := ovsdb.Row{}
 	if ok {
@@ -642,7 +906,7 @@ func (monitor *OVSDBMonitor) ovsdbEventFilter(updates ovsdb.TableUpdates) {
 			case !reflect.DeepEqual(row.New, empty) && !reflect.DeepEqual(row.Old, empty):
 				monitor.processOvsBridgeUpdate(row)
 			case reflect.DeepEqual(row.New, empty) && !reflect.DeepEqual(row.Old, empty):
-				monitor.processOvsBridgeDelete(row)
+				deletedBridges = append(deletedBridges, row)
 			}
 		}
 	}
@@ -654,6 +918,7 @@ func (monitor *OVSDBMonitor) ovsdbEventFilter(updates ovsdb.TableUpdates) {
 					monitor.processOvsInterfaceAdd(uuid, row)
 				}
 				if table == OvsDBPortTable {
+					monitor.processPortVlanAdd(uuid, row)
 					monitor.processOvsPortAdd(uuid, row)
 				}
 			case !reflect.DeepEqual(row.New, empty) && !reflect.DeepEqual(row.Old, empty):
@@ -661,6 +926,7 @@ func (monitor *OVSDBMonitor) ovsdbEventFilter(updates ovsdb.TableUpdates) {
 					monitor.processOvsInterfaceUpdate(uuid, row)
 				}
 				if table == OvsDBPortTable {
+					monitor.processPortVlanUpdate(uuid, row)
 					monitor.processOvsPortUpdate(uuid, row)
 				}
 			case reflect.DeepEqual(row.New, empty) && !reflect.DeepEqual(row.Old, empty):
@@ -668,9 +934,98 @@ func (monitor *OVSDBMonitor) ovsdbEventFilter(updates ovsdb.TableUpdates) {
 					monitor.processOvsInterfaceDelete(uuid, row)
 				}
 				if table == OvsDBPortTable {
+					monitor.processPortVlanDelete(uuid)
 					monitor.processOvsPortDelete(uuid, row)
 				}
 			}
 		}
 	}
+	for _, row := range deletedBridges {
+		monitor.processOvsBridgeDelete(row)
+	}
+}
+
+// resyncEndpoints rebuilds the bridges and the endpoints from the full dump of ovsdb, then
+// emits the events against the endpoints before, so the handlers converge: the endpoints gone
+// are deleted, the endpoints changed are updated, and all the others are added again. The
+// bridges and the port vlans only emit the differences.
+func (monitor *OVSDBMonitor) resyncEndpoints(dump ovsdb.TableUpdates) {
+	oldEndpointMap := monitor.endpointMap
+	oldBridgeMap := monitor.bridgeMap
+	oldPortVlanMap := monitor.portVlanMap
+	// the handlers keep the detached endpoints with the last ofport
+	for ifaceUUID, detachedEndpoint := range monitor.detachedEndpoints {
+		oldEndpointMap[ifaceUUID] = detachedEndpoint
+	}
+	monitor.detachedEndpoints = make(map[string]*datapath.Endpoint)
+	monitor.endpointMap = make(map[string]*datapath.Endpoint)
+	monitor.bridgeMap = make(map[string]sets.String)
+	monitor.portVlanMap = make(map[string]*PortVlan)
+
+	// rebuild without emitting events, the endpoints may not complete until the whole dump handled
+	eventHandler := monitor.ovsdbEventHandler
+	monitor.ovsdbEventHandler = OvsdbEventHandlerFuncs{}
+	monitor.ovsdbEventFilter(dump)
+	monitor.ovsdbEventHandler = eventHandler
+
+	for ifaceUUID, oldEndpoint := range oldEndpointMap {
+		newEndpoint, ok := monitor.endpointMap[ifaceUUID]
+		if monitor.isEndpointReady(oldEndpoint) && (!ok || !monitor.isEndpointReady(newEndpoint)) {
+			monitor.ovsdbEventHandler.DeleteLocalEndpoint(oldEndpoint)
+		}
+	}
+	for bridgeName := range oldBridgeMap {
+		if _, ok := monitor.bridgeMap[bridgeName]; !ok {
+			monitor.ovsdbEventHandler.DeleteBridge(bridgeName)
+		}
+	}
+	for bridgeName := range monitor.bridgeMap {
+		if _, ok := oldBridgeMap[bridgeName]; !ok {
+			monitor.ovsdbEventHandler.AddBridge(bridgeName)
+		}
+	}
+	for portUUID, newVlan := range monitor.portVlanMap {
+		if oldVlan, ok := oldPortVlanMap[portUUID]; ok && !reflect.DeepEqual(newVlan, oldVlan) {
+			monitor.ovsdbEventHandler.UpdatePortVlan(newVlan, oldVlan)
+		}
+	}
+	for ifaceUUID, newEndpoint := range monitor.endpointMap {
+		if !monitor.isEndpointReady(newEndpoint) {
+			continue
+		}
+		oldEndpoint, ok := oldEndpointMap[ifaceUUID]
+		if ok && monitor.isEndpointReady(oldEndpoint) && isEndpointChanged(oldEndpoint, newEndpoint) {
+			monitor.ovsdbEventHandler.UpdateLocalEndpoint(newEndpoint, oldEndpoint)
+			continue
+		}
+		monitor.ovsdbEventHandler.AddLocalEndpoint(newEndpoint)
+	}
+	klog.Infof("resync %d endpoints from ovsdb after reconnected", len(monitor.endpointMap))
+}
+
+// isEndpointChanged compares the fields of the endpoints from ovsdb, the ip address is not
+// compared as it may be learned and written by the datapath.
+func isEndpointChanged(oldEndpoint, newEndpoint *datapath.Endpoint) bool {
+	return oldEndpoint.InterfaceName != newEndpoint.InterfaceName ||
+		oldEndpoint.BridgeName != newEndpoint.BridgeName ||
+		oldEndpoint.MacAddrStr != newEndpoint.MacAddrStr ||
+		oldEndpoint.PortNo != newEndpoint.PortNo ||
+		oldEndpoint.VlanID != newEndpoint.VlanID ||
+		oldEndpoint.Trunk != newEndpoint.Trunk
+}
+
+// isFullDump returns true if all the rows of the updates are inserted, e.g. the updates
+// deleting rows sent before the full dump on monitor reset are not.
+func isFullDump(updates ovsdb.TableUpdates) bool {
+	var rowNum int
+	empty := ovsdb.Row{}
+	for _, tableUpdate := range updates.Updates {
+		for _, row := range tableUpdate.Rows {
+			if !reflect.DeepEqual(row.Old, empty) {
+				return false
+			}
+			rowNum++
+		}
+	}
+	return rowNum != 0
 }
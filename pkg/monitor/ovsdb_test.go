@@ -17,11 +17,21 @@ limitations under the License.
 package monitor
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	ovsdb "github.com/contiv/libovsdb"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
+
+	"github.com/everoute/everoute/pkg/agent/datapath"
+	ererrors "github.com/everoute/everoute/pkg/errors"
 )
 
 // nolint: funlen
@@ -162,7 +172,7 @@ func TestOvsDbEventHandler(t *testing.T) {
 	t.Run("monitor update veth interface test", func(t *testing.T) {
 		Eventually(func() bool {
 			iface, err := getIface(k8sClient, bridgeName, vethPortName, vethIfaceName)
-			if isNotFoundError(err) {
+			if ererrors.IsNotFound(err) {
 				return false
 			}
 			localEndpointLock.Lock()
@@ -178,7 +188,7 @@ func TestOvsDbEventHandler(t *testing.T) {
 	t.Run("monitor delete veth port", func(t *testing.T) {
 		Eventually(func() bool {
 			_, err := getPort(k8sClient, bridgeName, vethPortName)
-			return isNotFoundError(err)
+			return ererrors.IsNotFound(err)
 		}, timeout, interval).Should(BeTrue())
 	})
 
@@ -196,7 +206,7 @@ func TestOvsDbEventHandler(t *testing.T) {
 	t.Run("Add internal endpoint", func(t *testing.T) {
 		Eventually(func() bool {
 			iface, err := getIface(k8sClient, bridgeName, internalPortName, internalPortName)
-			if isNotFoundError(err) {
+			if ererrors.IsNotFound(err) {
 				return false
 			}
 			localEndpointLock.Lock()
@@ -211,7 +221,271 @@ func TestOvsDbEventHandler(t *testing.T) {
 	t.Run("Delete internal endpoint", func(t *testing.T) {
 		Eventually(func() bool {
 			_, err := getPort(k8sClient, bridgeName, internalPortName)
-			return isNotFoundError(err)
+			return ererrors.IsNotFound(err)
 		}, timeout, interval).Should(BeTrue())
 	})
 }
+
+func TestResyncEndpoints(t *testing.T) {
+	RegisterTestingT(t)
+
+	var added, deleted, updated []string
+	m := &OVSDBMonitor{
+		ovsdbCache:        make(OVSDBCache),
+		endpointMap:       make(map[string]*datapath.Endpoint),
+		detachedEndpoints: make(map[string]*datapath.Endpoint),
+		bridgeMap:         make(map[string]sets.String),
+		portVlanMap:       make(map[string]*PortVlan),
+		ovsdbUpdatesChan:  make(chan ovsdbUpdates, OvsdbUpdatesChanSize),
+		syncQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+		ovsdbEventHandler: OvsdbEventHandlerFuncs{
+			LocalEndpointAddFunc: func(endpoint *datapath.Endpoint) {
+				added = append(added, endpoint.InterfaceName)
+			},
+			LocalEndpointDeleteFunc: func(endpoint *datapath.Endpoint) {
+				deleted = append(deleted, endpoint.InterfaceName)
+			},
+			LocalEndpointUpdateFunc: func(newEndpoint, oldEndpoint *datapath.Endpoint) {
+				updated = append(updated, newEndpoint.InterfaceName)
+			},
+		},
+	}
+	handle := func(updates ovsdb.TableUpdates) {
+		m.handleOvsUpdates(nil, updates)
+		m.processOvsUpdates(<-m.ovsdbUpdatesChan)
+	}
+
+	handle(newTestDump(map[string]uint32{"ep1": 1, "ep2": 2, "ep3": 3}))
+	Expect(added).Should(ConsistOf("ep1", "ep2", "ep3"))
+
+	// ovsdb-server restarted: ep3 removed, ep2 ofport changed
+	added = nil
+	handle(newTestDump(map[string]uint32{"ep1": 1, "ep2": 20}))
+	Expect(added).Should(ConsistOf("ep1"))
+	Expect(updated).Should(ConsistOf("ep2"))
+	Expect(deleted).Should(ConsistOf("ep3"))
+	Expect(m.endpointMap["iface-ep2"].PortNo).Should(Equal(uint32(20)))
+
+	Expect(m.ovsdbCache[OvsDBInterfaceTable]).Should(HaveLen(2))
+	Expect(m.ovsdbCache[OvsDBPortTable]).Should(HaveLen(2))
+}
+
+func TestBridgeAndPortVlanEvents(t *testing.T) {
+	RegisterTestingT(t)
+
+	var events, endpointEvents []string
+	m := &OVSDBMonitor{
+		ovsdbCache:        make(OVSDBCache),
+		endpointMap:       make(map[string]*datapath.Endpoint),
+		detachedEndpoints: make(map[string]*datapath.Endpoint),
+		bridgeMap:         make(map[string]sets.String),
+		portVlanMap:       make(map[string]*PortVlan),
+		ovsdbUpdatesChan:  make(chan ovsdbUpdates, OvsdbUpdatesChanSize),
+		syncQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+	}
+	m.RegisterOvsdbEventHandler(OvsdbEventHandlerFuncs{
+		LocalEndpointDeleteFunc: func(endpoint *datapath.Endpoint) {
+			events = append(events, "delete endpoint "+endpoint.InterfaceName)
+		},
+		BridgeAddFunc: func(bridgeName string) {
+			events = append(events, "add bridge "+bridgeName)
+		},
+		BridgeDeleteFunc: func(bridgeName string) {
+			events = append(events, "delete bridge "+bridgeName)
+		},
+		PortVlanChangeFunc: func(newVlan, oldVlan *PortVlan) {
+			events = append(events, fmt.Sprintf("update port %s/%s vlan %d%v to %d%v",
+				newVlan.BridgeName, newVlan.PortName, oldVlan.Tag, oldVlan.Trunks, newVlan.Tag, newVlan.Trunks))
+		},
+	})
+	// the other subsystems could register their handlers
+	m.RegisterOvsdbEventHandler(OvsdbEventHandlerFuncs{
+		LocalEndpointAddFunc: func(endpoint *datapath.Endpoint) {
+			endpointEvents = append(endpointEvents, "add endpoint "+endpoint.InterfaceName)
+		},
+	})
+	handle := func(updates ovsdb.TableUpdates) {
+		m.handleOvsUpdates(nil, updates)
+		m.processOvsUpdates(<-m.ovsdbUpdatesChan)
+	}
+	portRow := func(tag interface{}, trunks interface{}) ovsdb.Row {
+		return ovsdb.Row{Fields: map[string]interface{}{
+			"name":       "ep1",
+			"interfaces": ovsdb.UUID{GoUuid: "iface-ep1"},
+			"tag":        tag,
+			"trunks":     trunks,
+		}}
+	}
+	emptySet := ovsdb.OvsSet{GoSet: []interface{}{}}
+
+	handle(newTestDump(map[string]uint32{"ep1": 1}))
+	Expect(events).Should(Equal([]string{"add bridge bridge"}))
+	Expect(endpointEvents).Should(Equal([]string{"add endpoint ep1"}))
+
+	events = nil
+	handle(ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		OvsDBPortTable: {Rows: map[string]ovsdb.RowUpdate{"port-ep1": {
+			Old: portRow(emptySet, emptySet),
+			New: portRow(float64(10), emptySet),
+		}}},
+	}})
+	handle(ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		OvsDBPortTable: {Rows: map[string]ovsdb.RowUpdate{"port-ep1": {
+			Old: portRow(float64(10), emptySet),
+			New: portRow(emptySet, ovsdb.OvsSet{GoSet: []interface{}{float64(30), float64(20)}}),
+		}}},
+	}})
+	Expect(events).Should(Equal([]string{
+		"update port bridge/ep1 vlan 0[] to 10[]",
+		"update port bridge/ep1 vlan 10[] to 0[20 30]",
+	}))
+
+	// ovsdb-server restarted, only the differences emitted
+	events = nil
+	handle(newTestDump(map[string]uint32{"ep1": 1}))
+	Expect(events).Should(Equal([]string{"update port bridge/ep1 vlan 0[20 30] to 0[]"}))
+
+	// the bridge deleted after its endpoints
+	events = nil
+	dump := newTestDump(map[string]uint32{"ep1": 1})
+	deletes := ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{}}
+	for table, tableUpdate := range dump.Updates {
+		rows := map[string]ovsdb.RowUpdate{}
+		for uuid, row := range tableUpdate.Rows {
+			rows[uuid] = ovsdb.RowUpdate{Old: row.New}
+		}
+		deletes.Updates[table] = ovsdb.TableUpdate{Rows: rows}
+	}
+	handle(deletes)
+	Expect(events).Should(ContainElement("delete endpoint ep1"))
+	Expect(events[len(events)-1]).Should(Equal("delete bridge bridge"))
+	Expect(m.portVlanMap).Should(BeEmpty())
+}
+
+func TestEndpointOfportReassigned(t *testing.T) {
+	RegisterTestingT(t)
+
+	var events []string
+	m := &OVSDBMonitor{
+		ovsdbCache:        make(OVSDBCache),
+		endpointMap:       make(map[string]*datapath.Endpoint),
+		detachedEndpoints: make(map[string]*datapath.Endpoint),
+		bridgeMap:         make(map[string]sets.String),
+		portVlanMap:       make(map[string]*PortVlan),
+		ovsdbUpdatesChan:  make(chan ovsdbUpdates, OvsdbUpdatesChanSize),
+		syncQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+	}
+	m.RegisterOvsdbEventHandler(OvsdbEventHandlerFuncs{
+		LocalEndpointAddFunc: func(endpoint *datapath.Endpoint) {
+			events = append(events, fmt.Sprintf("add %s/%d", endpoint.InterfaceName, endpoint.PortNo))
+		},
+		LocalEndpointDeleteFunc: func(endpoint *datapath.Endpoint) {
+			events = append(events, fmt.Sprintf("delete %s/%d", endpoint.InterfaceName, endpoint.PortNo))
+		},
+		LocalEndpointUpdateFunc: func(newEndpoint, oldEndpoint *datapath.Endpoint) {
+			events = append(events, fmt.Sprintf("update %s/%d to %d", newEndpoint.InterfaceName, oldEndpoint.PortNo, newEndpoint.PortNo))
+		},
+	})
+	handle := func(context interface{}, updates ovsdb.TableUpdates) {
+		m.handleOvsUpdates(context, updates)
+		m.processOvsUpdates(<-m.ovsdbUpdatesChan)
+	}
+	ifaceRow := func(ofport float64) ovsdb.Row {
+		row := newTestDump(map[string]uint32{"ep1": 1}).Updates[OvsDBInterfaceTable].Rows["iface-ep1"].New
+		row.Fields["ofport"] = ofport
+		return row
+	}
+	updateOfport := func(oldOfport, newOfport float64) {
+		handle("update", ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+			OvsDBInterfaceTable: {Rows: map[string]ovsdb.RowUpdate{"iface-ep1": {Old: ifaceRow(oldOfport), New: ifaceRow(newOfport)}}},
+		}})
+	}
+
+	handle(nil, newTestDump(map[string]uint32{"ep1": 1}))
+	Expect(events).Should(Equal([]string{"add ep1/1"}))
+
+	// the ofport transitions through -1 while ovs reassigning it
+	events = nil
+	updateOfport(1, -1)
+	Expect(events).Should(BeEmpty())
+	updateOfport(-1, 5)
+	Expect(events).Should(Equal([]string{"update ep1/1 to 5"}))
+	Expect(m.endpointMap["iface-ep1"].PortNo).Should(Equal(uint32(5)))
+
+	// the endpoint deleted while detached
+	events = nil
+	updateOfport(5, -1)
+	handle("update", ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		OvsDBInterfaceTable: {Rows: map[string]ovsdb.RowUpdate{"iface-ep1": {Old: ifaceRow(-1)}}},
+	}})
+	Expect(events).Should(Equal([]string{"delete ep1/5"}))
+	Expect(m.detachedEndpoints).Should(BeEmpty())
+}
+
+func TestOvsdbUpdateMetrics(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := &OVSDBMonitor{
+		ovsdbCache:        make(OVSDBCache),
+		endpointMap:       make(map[string]*datapath.Endpoint),
+		detachedEndpoints: make(map[string]*datapath.Endpoint),
+		bridgeMap:         make(map[string]sets.String),
+		portVlanMap:       make(map[string]*PortVlan),
+		ovsdbUpdatesChan:  make(chan ovsdbUpdates, OvsdbUpdatesChanSize),
+		syncQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+	}
+	m.RegisterOvsdbEventHandler(OvsdbEventHandlerFuncs{
+		LocalEndpointAddFunc: func(endpoint *datapath.Endpoint) { time.Sleep(10 * time.Millisecond) },
+	})
+	readMetric := func(metric interface{}) *dto.Metric {
+		var m dto.Metric
+		Expect(metric.(prometheus.Metric).Write(&m)).Should(Succeed())
+		return &m
+	}
+	interfaceRows := readMetric(ovsdbUpdateRows.WithLabelValues(OvsDBInterfaceTable)).GetCounter().GetValue()
+	handlerCount := readMetric(ovsdbEventHandlerDuration.WithLabelValues(endpointEventAdd)).GetHistogram().GetSampleCount()
+
+	m.handleOvsUpdates(nil, newTestDump(map[string]uint32{"ep1": 1, "ep2": 2}))
+	Expect(readMetric(ovsdbPendingUpdates).GetGauge().GetValue()).Should(Equal(1.0))
+	m.processOvsUpdates(<-m.ovsdbUpdatesChan)
+
+	Expect(readMetric(ovsdbPendingUpdates).GetGauge().GetValue()).Should(Equal(0.0))
+	Expect(readMetric(ovsdbUpdateRows.WithLabelValues(OvsDBInterfaceTable)).GetCounter().GetValue()).Should(Equal(interfaceRows + 2))
+	Expect(readMetric(ovsdbEventHandlerDuration.WithLabelValues(endpointEventAdd)).GetHistogram().GetSampleCount()).Should(Equal(handlerCount + 2))
+	// the update waits for the handlers of both endpoints
+	Expect(readMetric(ovsdbLastUpdateAge).GetGauge().GetValue()).Should(BeNumerically(">=", 0.02))
+}
+
+// newTestDump returns a full dump of ovsdb with a bridge and the interfaces of the ofports.
+func newTestDump(ofports map[string]uint32) ovsdb.TableUpdates {
+	bridgeRows := map[string]ovsdb.RowUpdate{}
+	portRows := map[string]ovsdb.RowUpdate{}
+	ifaceRows := map[string]ovsdb.RowUpdate{}
+
+	var ports []interface{}
+	for name, ofport := range ofports {
+		ports = append(ports, ovsdb.UUID{GoUuid: "port-" + name})
+		portRows["port-"+name] = ovsdb.RowUpdate{New: ovsdb.Row{Fields: map[string]interface{}{
+			"name":       name,
+			"interfaces": ovsdb.UUID{GoUuid: "iface-" + name},
+		}}}
+		ifaceRows["iface-"+name] = ovsdb.RowUpdate{New: ovsdb.Row{Fields: map[string]interface{}{
+			"name":       name,
+			"ofport":     float64(ofport),
+			"mac_in_use": fmt.Sprintf("00:00:00:00:00:%02x", ofport),
+			InterfaceStatus: ovsdb.OvsMap{GoMap: map[interface{}]interface{}{
+				InterfaceDriver: "tun",
+			}},
+		}}}
+	}
+	bridgeRows["bridge"] = ovsdb.RowUpdate{New: ovsdb.Row{Fields: map[string]interface{}{
+		"name":  "bridge",
+		"ports": ovsdb.OvsSet{GoSet: ports},
+	}}}
+
+	return ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		OvsDBBridgeTable:    {Rows: bridgeRows},
+		OvsDBPortTable:      {Rows: portRows},
+		OvsDBInterfaceTable: {Rows: ifaceRows},
+	}}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"fmt"
+
+	ovsdb "github.com/contiv/libovsdb"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/everoute/everoute/pkg/metrics"
+)
+
+var (
+	ovsdbParseErrors = metrics.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "monitor",
+		Name:      "ovsdb_parse_errors_total",
+		Help:      "Number of ovsdb column values with unexpected type.",
+	}, []string{metrics.LabelTable, metrics.LabelColumn})
+)
+
+func init() {
+	metrics.MustRegister(ovsdbParseErrors)
+}
+
+// rowReader reads the columns of an ovsdb row with checked type assertions. The
+// columns may be written by other tools, a value with unexpected type is read as
+// the zero value, counted in the parse error metric, and reported by Err().
+type rowReader struct {
+	table string
+	uuid  string
+	row   ovsdb.Row
+	errs  []error
+}
+
+func newRowReader(table, uuid string, row ovsdb.Row) *rowReader {
+	return &rowReader{table: table, uuid: uuid, row: row}
+}
+
+// Err returns the parse errors of the columns read so far, with the row context.
+func (r *rowReader) Err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("parse %s row %s: %s", r.table, r.uuid, errors.NewAggregate(r.errs))
+}
+
+func (r *rowReader) fail(column string, value interface{}, expect string) {
+	ovsdbParseErrors.WithLabelValues(r.table, column).Inc()
+	r.errs = append(r.errs, fmt.Errorf("column %s expect %s, got %T %v", column, expect, value, value))
+}
+
+// isUnset returns true if the optional column not set, ovsdb sends an empty set for it.
+func isUnset(value interface{}) bool {
+	set, ok := value.(ovsdb.OvsSet)
+	return value == nil || ok && len(set.GoSet) == 0
+}
+
+func (r *rowReader) String(column string) string {
+	value := r.row.Fields[column]
+	if isUnset(value) {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		r.fail(column, value, "string")
+	}
+	return s
+}
+
+// Float returns the number value of the column, and whether the column is set.
+func (r *rowReader) Float(column string) (float64, bool) {
+	value := r.row.Fields[column]
+	if isUnset(value) {
+		return 0, false
+	}
+	// json number type is always float64
+	f, ok := value.(float64)
+	if !ok {
+		r.fail(column, value, "number")
+	}
+	return f, ok
+}
+
+func (r *rowReader) Bool(column string) bool {
+	value := r.row.Fields[column]
+	if isUnset(value) {
+		return false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		r.fail(column, value, "bool")
+	}
+	return b
+}
+
+// StringMap returns the string map of the column, the entries not string are ignored.
+func (r *rowReader) StringMap(column string) map[string]string {
+	m := make(map[string]string)
+	value := r.row.Fields[column]
+	if isUnset(value) {
+		return m
+	}
+	ovsMap, ok := value.(ovsdb.OvsMap)
+	if !ok {
+		r.fail(column, value, "map")
+		return m
+	}
+	for key, item := range ovsMap.GoMap {
+		keyString, keyOK := key.(string)
+		itemString, itemOK := item.(string)
+		if !keyOK || !itemOK {
+			r.fail(column, ovsMap.GoMap, "string map")
+			continue
+		}
+		m[keyString] = itemString
+	}
+	return m
+}
+
+// UUIDs returns the uuid set of the column, the elements not uuid are ignored.
+func (r *rowReader) UUIDs(column string) []ovsdb.UUID {
+	var uuids []ovsdb.UUID
+	for _, item := range r.set(column) {
+		uuid, ok := item.(ovsdb.UUID)
+		if !ok {
+			r.fail(column, item, "uuid")
+			continue
+		}
+		uuids = append(uuids, uuid)
+	}
+	return uuids
+}
+
+// Strings returns the string set of the column, the elements not string are ignored.
+func (r *rowReader) Strings(column string) []string {
+	var strs []string
+	for _, item := range r.set(column) {
+		str, ok := item.(string)
+		if !ok {
+			r.fail(column, item, "string")
+			continue
+		}
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// NumberUUIDMap returns the map from number to uuid of the column, the entries of other
+// types are ignored. The values of a map are not decoded, the uuid is sent as ["uuid", <id>].
+func (r *rowReader) NumberUUIDMap(column string) map[int64]ovsdb.UUID {
+	m := make(map[int64]ovsdb.UUID)
+	value := r.row.Fields[column]
+	if isUnset(value) {
+		return m
+	}
+	ovsMap, ok := value.(ovsdb.OvsMap)
+	if !ok {
+		r.fail(column, value, "map")
+		return m
+	}
+	for key, item := range ovsMap.GoMap {
+		number, keyOK := key.(float64)
+		uuid, itemOK := decodeUUID(item)
+		if !keyOK || !itemOK {
+			r.fail(column, ovsMap.GoMap, "number uuid map")
+			continue
+		}
+		m[int64(number)] = uuid
+	}
+	return m
+}
+
+func decodeUUID(value interface{}) (ovsdb.UUID, bool) {
+	if uuid, ok := value.(ovsdb.UUID); ok {
+		return uuid, true
+	}
+	pair, ok := value.([]interface{})
+	if !ok || len(pair) != 2 || pair[0] != "uuid" {
+		return ovsdb.UUID{}, false
+	}
+	id, ok := pair[1].(string)
+	return ovsdb.UUID{GoUuid: id}, ok
+}
+
+// Floats returns the number set of the column, the elements not number are ignored.
+func (r *rowReader) Floats(column string) []float64 {
+	var floats []float64
+	for _, item := range r.set(column) {
+		f, ok := item.(float64)
+		if !ok {
+			r.fail(column, item, "number")
+			continue
+		}
+		floats = append(floats, f)
+	}
+	return floats
+}
+
+// set returns the elements of the set column, a set with single element is
+// sent as the element itself.
+func (r *rowReader) set(column string) []interface{} {
+	value := r.row.Fields[column]
+	if value == nil {
+		return nil
+	}
+	if set, ok := value.(ovsdb.OvsSet); ok {
+		return set.GoSet
+	}
+	return []interface{}{value}
+}
@@ -18,6 +18,7 @@ package monitor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
@@ -28,13 +29,18 @@ import (
 
 	ovsdb "github.com/contiv/libovsdb"
 	"github.com/vishvananda/netlink"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/klog"
 
 	"github.com/everoute/everoute/pkg/agent/datapath"
 	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
 	"github.com/everoute/everoute/pkg/client/clientset_generated/clientset/fake"
 	clientset "github.com/everoute/everoute/pkg/client/clientset_generated/clientset/typed/agent/v1alpha1"
+	ererrors "github.com/everoute/everoute/pkg/errors"
 )
 
 const (
@@ -74,6 +80,7 @@ var (
 
 func TestMain(m *testing.M) {
 	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("patch", "agentinfos", applyAgentInfoReactor(clientset.Tracker()))
 	k8sClient = clientset.AgentV1alpha1().AgentInfos()
 
 	var err error
@@ -130,6 +137,27 @@ func TestMain(m *testing.M) {
 	os.Exit(exitCode)
 }
 
+// applyAgentInfoReactor handles the server-side apply of the agentinfo, which is not supported
+// by the fake object tracker, the applied agentinfo replaces the existing one.
+func applyAgentInfoReactor(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != k8stypes.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		agentInfo := &agentv1alpha1.AgentInfo{}
+		if err := json.Unmarshal(patchAction.GetPatch(), agentInfo); err != nil {
+			return true, nil, err
+		}
+		err := tracker.Create(patchAction.GetResource(), agentInfo, "")
+		if errors.IsAlreadyExists(err) {
+			err = tracker.Update(patchAction.GetResource(), agentInfo, "")
+		}
+		return true, agentInfo, err
+	}
+}
+
 func createVethPair(vethName, peerName string) error {
 	veth := &netlink.Veth{
 		LinkAttrs: netlink.LinkAttrs{Name: vethName, TxQLen: 0},
@@ -496,7 +524,7 @@ func getBridge(client clientset.AgentInfoInterface, brName string) (*agentv1alph
 		}
 	}
 
-	return nil, notFoundError(fmt.Errorf("bridge %s not found in agentInfo", brName))
+	return nil, ererrors.NewNotFound("bridge %s not found in agentInfo", brName)
 }
 
 func getPort(client clientset.AgentInfoInterface, brName, portName string) (*agentv1alpha1.OVSPort, error) {
@@ -511,7 +539,7 @@ func getPort(client clientset.AgentInfoInterface, brName, portName string) (*age
 		}
 	}
 
-	return nil, notFoundError(fmt.Errorf("port %s not found in agentInfo", portName))
+	return nil, ererrors.NewNotFound("port %s not found in agentInfo", portName)
 }
 
 func getIface(client clientset.AgentInfoInterface, brName, portName, ifaceName string) (*agentv1alpha1.OVSInterface, error) {
@@ -526,16 +554,5 @@ func getIface(client clientset.AgentInfoInterface, brName, portName, ifaceName s
 		}
 	}
 
-	return nil, notFoundError(fmt.Errorf("port %s not found in agentInfo", ifaceName))
-}
-
-type notFoundError error
-
-func isNotFoundError(err error) bool {
-	switch err.(type) {
-	case notFoundError:
-		return true
-	default:
-		return false
-	}
+	return nil, ererrors.NewNotFound("port %s not found in agentInfo", ifaceName)
 }
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"sort"
+
+	ovsdb "github.com/contiv/libovsdb"
+	"k8s.io/klog"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+)
+
+const (
+	// DatabaseHardwareVTEP is the database of the hardware vtep gateways, see vtep(5).
+	DatabaseHardwareVTEP = "hardware_vtep"
+
+	VTEPPhysicalSwitchTable = "Physical_Switch"
+	VTEPPhysicalPortTable   = "Physical_Port"
+	VTEPLogicalSwitchTable  = "Logical_Switch"
+	VTEPUcastMacsLocalTable = "Ucast_Macs_Local"
+)
+
+var hardwareVTEPMonitorRequests = map[string]ovsdb.MonitorRequest{
+	VTEPPhysicalSwitchTable: {Select: selectAll, Columns: []string{"name", "ports", "tunnel_ips"}},
+	VTEPPhysicalPortTable:   {Select: selectAll, Columns: []string{"name", "vlan_bindings"}},
+	VTEPLogicalSwitchTable:  {Select: selectAll, Columns: []string{"name", "tunnel_key"}},
+	VTEPUcastMacsLocalTable: {Select: selectAll, Columns: []string{"MAC", "logical_switch", "ipaddr"}},
+}
+
+// fetchVTEPInfo returns the physical switches and the logical switches in the hardware_vtep
+// cache, the vlan bindings refer to the logical switches by name.
+func fetchVTEPInfo(vtepCache OVSDBCache) *agentv1alpha1.VTEPInfo {
+	vtepInfo := &agentv1alpha1.VTEPInfo{}
+
+	logicalSwitches := make(map[string]*agentv1alpha1.VTEPLogicalSwitch)
+	for uuid, row := range vtepCache[VTEPLogicalSwitchTable] {
+		reader := newRowReader(VTEPLogicalSwitchTable, uuid, row)
+		logicalSwitch := &agentv1alpha1.VTEPLogicalSwitch{Name: reader.String("name")}
+		if tunnelKey, ok := reader.Float("tunnel_key"); ok {
+			logicalSwitch.TunnelKey = int32(tunnelKey)
+		}
+		if err := reader.Err(); err != nil {
+			klog.Errorf("ignore unexpected values of logical switch %s: %s", logicalSwitch.Name, err)
+		}
+		logicalSwitches[uuid] = logicalSwitch
+	}
+
+	for uuid, row := range vtepCache[VTEPUcastMacsLocalTable] {
+		reader := newRowReader(VTEPUcastMacsLocalTable, uuid, row)
+		mac := agentv1alpha1.VTEPMAC{MAC: reader.String("MAC"), IP: reader.String("ipaddr")}
+		for _, lsUUID := range reader.UUIDs("logical_switch") {
+			logicalSwitch, ok := logicalSwitches[lsUUID.GoUuid]
+			if !ok {
+				continue
+			}
+			logicalSwitch.LocalMACs = append(logicalSwitch.LocalMACs, mac)
+		}
+		if err := reader.Err(); err != nil {
+			klog.Errorf("ignore unexpected values of local mac %s: %s", mac.MAC, err)
+		}
+	}
+
+	for uuid, row := range vtepCache[VTEPPhysicalSwitchTable] {
+		reader := newRowReader(VTEPPhysicalSwitchTable, uuid, row)
+		physicalSwitch := agentv1alpha1.VTEPPhysicalSwitch{
+			Name:      reader.String("name"),
+			TunnelIPs: reader.Strings("tunnel_ips"),
+		}
+		for _, portUUID := range reader.UUIDs("ports") {
+			if port := fetchVTEPPhysicalPort(vtepCache, portUUID, logicalSwitches); port != nil {
+				physicalSwitch.Ports = append(physicalSwitch.Ports, *port)
+			}
+		}
+		if err := reader.Err(); err != nil {
+			klog.Errorf("ignore unexpected values of physical switch %s: %s", physicalSwitch.Name, err)
+		}
+		sort.Strings(physicalSwitch.TunnelIPs)
+		sort.Slice(physicalSwitch.Ports, func(i, j int) bool { return physicalSwitch.Ports[i].Name < physicalSwitch.Ports[j].Name })
+		vtepInfo.PhysicalSwitches = append(vtepInfo.PhysicalSwitches, physicalSwitch)
+	}
+
+	for _, logicalSwitch := range logicalSwitches {
+		// sort before truncated, the same macs are reported among the syncs
+		sort.Slice(logicalSwitch.LocalMACs, func(i, j int) bool { return logicalSwitch.LocalMACs[i].MAC < logicalSwitch.LocalMACs[j].MAC })
+		logicalSwitch.LocalMACCount = int32(len(logicalSwitch.LocalMACs))
+		if len(logicalSwitch.LocalMACs) > agentv1alpha1.MaxReportedVTEPLocalMACs {
+			logicalSwitch.LocalMACs = logicalSwitch.LocalMACs[:agentv1alpha1.MaxReportedVTEPLocalMACs]
+		}
+		vtepInfo.LogicalSwitches = append(vtepInfo.LogicalSwitches, *logicalSwitch)
+	}
+
+	sort.Slice(vtepInfo.PhysicalSwitches, func(i, j int) bool {
+		return vtepInfo.PhysicalSwitches[i].Name < vtepInfo.PhysicalSwitches[j].Name
+	})
+	sort.Slice(vtepInfo.LogicalSwitches, func(i, j int) bool {
+		return vtepInfo.LogicalSwitches[i].Name < vtepInfo.LogicalSwitches[j].Name
+	})
+	return vtepInfo
+}
+
+func fetchVTEPPhysicalPort(vtepCache OVSDBCache, uuid ovsdb.UUID, logicalSwitches map[string]*agentv1alpha1.VTEPLogicalSwitch) *agentv1alpha1.VTEPPhysicalPort {
+	row, ok := vtepCache[VTEPPhysicalPortTable][uuid.GoUuid]
+	if !ok {
+		klog.V(4).Infof("could not find physical port %s in cache", uuid.GoUuid)
+		return nil
+	}
+
+	reader := newRowReader(VTEPPhysicalPortTable, uuid.GoUuid, row)
+	port := &agentv1alpha1.VTEPPhysicalPort{Name: reader.String("name")}
+	for vlan, lsUUID := range reader.NumberUUIDMap("vlan_bindings") {
+		logicalSwitch, ok := logicalSwitches[lsUUID.GoUuid]
+		if !ok {
+			continue
+		}
+		port.VlanBindings = append(port.VlanBindings, agentv1alpha1.VTEPVlanBinding{
+			VLAN:          int32(vlan),
+			LogicalSwitch: logicalSwitch.Name,
+		})
+	}
+	if err := reader.Err(); err != nil {
+		klog.Errorf("ignore unexpected values of physical port %s: %s", port.Name, err)
+	}
+
+	sort.Slice(port.VlanBindings, func(i, j int) bool { return port.VlanBindings[i].VLAN < port.VlanBindings[j].VLAN })
+	return port
+}
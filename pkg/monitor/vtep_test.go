@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+
+	ovsdb "github.com/contiv/libovsdb"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+
+	agentv1alpha1 "github.com/everoute/everoute/pkg/apis/agent/v1alpha1"
+	ererrors "github.com/everoute/everoute/pkg/errors"
+)
+
+func newVTEPDump() ovsdb.TableUpdates {
+	newRow := func(fields map[string]interface{}) ovsdb.RowUpdate {
+		return ovsdb.RowUpdate{New: ovsdb.Row{Fields: fields}}
+	}
+	return ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		VTEPPhysicalSwitchTable: {Rows: map[string]ovsdb.RowUpdate{
+			"ps1": newRow(map[string]interface{}{
+				"name":       "tor01",
+				"ports":      ovsdb.OvsSet{GoSet: []interface{}{ovsdb.UUID{GoUuid: "pp2"}, ovsdb.UUID{GoUuid: "pp1"}}},
+				"tunnel_ips": "10.0.0.10",
+			}),
+		}},
+		VTEPPhysicalPortTable: {Rows: map[string]ovsdb.RowUpdate{
+			"pp1": newRow(map[string]interface{}{
+				"name": "eth1",
+				"vlan_bindings": ovsdb.OvsMap{GoMap: map[interface{}]interface{}{
+					float64(200): []interface{}{"uuid", "ls2"},
+					float64(100): []interface{}{"uuid", "ls1"},
+				}},
+			}),
+			"pp2": newRow(map[string]interface{}{
+				"name":          "eth2",
+				"vlan_bindings": ovsdb.OvsMap{GoMap: map[interface{}]interface{}{}},
+			}),
+		}},
+		VTEPLogicalSwitchTable: {Rows: map[string]ovsdb.RowUpdate{
+			"ls1": newRow(map[string]interface{}{"name": "ls-a", "tunnel_key": float64(5001)}),
+			"ls2": newRow(map[string]interface{}{"name": "ls-b", "tunnel_key": ovsdb.OvsSet{}}),
+		}},
+		VTEPUcastMacsLocalTable: {Rows: map[string]ovsdb.RowUpdate{
+			"mac2": newRow(map[string]interface{}{"MAC": "00:00:00:00:00:02", "logical_switch": ovsdb.UUID{GoUuid: "ls1"}, "ipaddr": ""}),
+			"mac1": newRow(map[string]interface{}{"MAC": "00:00:00:00:00:01", "logical_switch": ovsdb.UUID{GoUuid: "ls1"}, "ipaddr": "192.168.1.1"}),
+		}},
+	}}
+}
+
+func TestFetchVTEPInfo(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := &OVSDBMonitor{syncQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter())}
+	err := m.LockedAccessDatabaseCache(DatabaseHardwareVTEP, func(OVSDBCache) error { return nil })
+	Expect(ererrors.IsNotFound(err)).Should(BeTrue())
+
+	m.handleDatabaseUpdates(DatabaseHardwareVTEP, newVTEPDump())
+	Expect(m.syncQueue.Len()).Should(Equal(1))
+
+	var vtepInfo *agentv1alpha1.VTEPInfo
+	Expect(m.LockedAccessDatabaseCache(DatabaseHardwareVTEP, func(vtepCache OVSDBCache) error {
+		vtepInfo = fetchVTEPInfo(vtepCache)
+		return nil
+	})).Should(Succeed())
+
+	Expect(vtepInfo).Should(Equal(&agentv1alpha1.VTEPInfo{
+		PhysicalSwitches: []agentv1alpha1.VTEPPhysicalSwitch{{
+			Name:      "tor01",
+			TunnelIPs: []string{"10.0.0.10"},
+			Ports: []agentv1alpha1.VTEPPhysicalPort{
+				{Name: "eth1", VlanBindings: []agentv1alpha1.VTEPVlanBinding{
+					{VLAN: 100, LogicalSwitch: "ls-a"},
+					{VLAN: 200, LogicalSwitch: "ls-b"},
+				}},
+				{Name: "eth2"},
+			},
+		}},
+		LogicalSwitches: []agentv1alpha1.VTEPLogicalSwitch{
+			{Name: "ls-a", TunnelKey: 5001, LocalMACCount: 2, LocalMACs: []agentv1alpha1.VTEPMAC{
+				{MAC: "00:00:00:00:00:01", IP: "192.168.1.1"},
+				{MAC: "00:00:00:00:00:02"},
+			}},
+			{Name: "ls-b"},
+		},
+	}))
+
+	// the logical switch deleted, its bindings and macs are not reported
+	m.handleDatabaseUpdates(DatabaseHardwareVTEP, ovsdb.TableUpdates{Updates: map[string]ovsdb.TableUpdate{
+		VTEPLogicalSwitchTable: {Rows: map[string]ovsdb.RowUpdate{"ls1": {Old: ovsdb.Row{Fields: map[string]interface{}{"name": "ls-a"}}}}},
+	}})
+	Expect(m.LockedAccessDatabaseCache(DatabaseHardwareVTEP, func(vtepCache OVSDBCache) error {
+		vtepInfo = fetchVTEPInfo(vtepCache)
+		return nil
+	})).Should(Succeed())
+	Expect(vtepInfo.LogicalSwitches).Should(Equal([]agentv1alpha1.VTEPLogicalSwitch{{Name: "ls-b"}}))
+	Expect(vtepInfo.PhysicalSwitches[0].Ports[0].VlanBindings).Should(Equal([]agentv1alpha1.VTEPVlanBinding{{VLAN: 200, LogicalSwitch: "ls-b"}}))
+}
+
+func TestConnectOvsdbInvalidAddress(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := connectOvsdb("ssl:10.0.0.1:6640")
+	Expect(err).Should(HaveOccurred())
+	_, err = connectOvsdb("tcp:10.0.0.1")
+	Expect(err).Should(HaveOccurred())
+	_, err = connectOvsdb("tcp:10.0.0.1:port")
+	Expect(err).Should(HaveOccurred())
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxVLAN is the max vlan id could be trunked, 4095 is reserved.
+const MaxVLAN = 4094
+
+// Trunks are the vlans trunked on a port, sorted without duplicates. On input the ranges like
+// "100-110" are accepted besides the vlan ids, e.g. [1, "100-110"] or "1,100-110", they are
+// expanded when decoded.
+type Trunks []int32
+
+// ParseTrunks parses the trunks in format of the vlan ids and ranges separated by comma, e.g.
+// "1,2,100-110", the result is normalized.
+func ParseTrunks(trunks string) (Trunks, error) {
+	var result Trunks
+	for _, item := range strings.Split(trunks, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		vlans, err := parseTrunkItem(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, vlans...)
+	}
+	return result.Normalize(), nil
+}
+
+func parseTrunkItem(item string) (Trunks, error) {
+	bounds := strings.SplitN(item, "-", 2)
+	start, err := parseVLAN(bounds[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(bounds) == 1 {
+		return Trunks{start}, nil
+	}
+
+	end, err := parseVLAN(bounds[1])
+	if err != nil {
+		return nil, err
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid vlan range %s", item)
+	}
+	vlans := make(Trunks, 0, end-start+1)
+	for vlan := start; vlan <= end; vlan++ {
+		vlans = append(vlans, vlan)
+	}
+	return vlans, nil
+}
+
+func parseVLAN(vlan string) (int32, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(vlan), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid vlan %s", vlan)
+	}
+	if id < 0 || id > MaxVLAN {
+		return 0, fmt.Errorf("vlan %d out of range", id)
+	}
+	return int32(id), nil
+}
+
+// Normalize returns the trunks sorted without duplicates.
+func (t Trunks) Normalize() Trunks {
+	if len(t) == 0 {
+		return nil
+	}
+	sorted := append(Trunks(nil), t...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result := sorted[:1]
+	for _, vlan := range sorted[1:] {
+		if vlan != result[len(result)-1] {
+			result = append(result, vlan)
+		}
+	}
+	return result
+}
+
+// Validate returns error if any of the vlans out of range.
+func (t Trunks) Validate() error {
+	for _, vlan := range t {
+		if vlan < 0 || vlan > MaxVLAN {
+			return fmt.Errorf("vlan %d out of range", vlan)
+		}
+	}
+	return nil
+}
+
+// Has returns true if the vlan is trunked.
+func (t Trunks) Has(vlan int32) bool {
+	for _, item := range t {
+		if item == vlan {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the vlan ids separated by comma, e.g. "1,2,100,101", which is the format of
+// the trunks in the datapath.
+func (t Trunks) String() string {
+	items := make([]string, 0, len(t))
+	for _, vlan := range t {
+		items = append(items, strconv.Itoa(int(vlan)))
+	}
+	return strings.Join(items, ",")
+}
+
+// UnmarshalJSON decodes the trunks from a list of the vlan ids and ranges, or a string of them
+// separated by comma.
+func (t *Trunks) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		trunks, err := ParseTrunks(str)
+		if err != nil {
+			return err
+		}
+		*t = trunks
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("trunks must be a list or a string: %s", err)
+	}
+	var trunks Trunks
+	for _, item := range items {
+		var vlan int32
+		if err := json.Unmarshal(item, &vlan); err == nil {
+			trunks = append(trunks, vlan)
+			continue
+		}
+		if err := json.Unmarshal(item, &str); err != nil {
+			return fmt.Errorf("invalid vlan %s", item)
+		}
+		vlans, err := parseTrunkItem(str)
+		if err != nil {
+			return err
+		}
+		trunks = append(trunks, vlans...)
+	}
+	*t = trunks.Normalize()
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseTrunks(t *testing.T) {
+	RegisterTestingT(t)
+
+	trunks, err := ParseTrunks("200, 1,100-103,2,101")
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(trunks).Should(Equal(Trunks{1, 2, 100, 101, 102, 103, 200}))
+	Expect(trunks.String()).Should(Equal("1,2,100,101,102,103,200"))
+
+	trunks, err = ParseTrunks("")
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(trunks).Should(BeNil())
+
+	for _, invalid := range []string{"a", "110-100", "100-", "1-4095", "-1"} {
+		_, err = ParseTrunks(invalid)
+		Expect(err).Should(HaveOccurred(), invalid)
+	}
+}
+
+func TestTrunksJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	var spec struct {
+		Trunks Trunks `json:"trunks,omitempty"`
+	}
+	Expect(json.Unmarshal([]byte(`{"trunks": [300, "100-102", "5", 100]}`), &spec)).Should(Succeed())
+	Expect(spec.Trunks).Should(Equal(Trunks{5, 100, 101, 102, 300}))
+
+	Expect(json.Unmarshal([]byte(`{"trunks": "7,1-2"}`), &spec)).Should(Succeed())
+	Expect(spec.Trunks).Should(Equal(Trunks{1, 2, 7}))
+
+	raw, err := json.Marshal(spec)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(string(raw)).Should(Equal(`{"trunks":[1,2,7]}`))
+
+	Expect(json.Unmarshal([]byte(`{"trunks": ["100-a"]}`), &spec)).ShouldNot(Succeed())
+	Expect(json.Unmarshal([]byte(`{"trunks": {}}`), &spec)).ShouldNot(Succeed())
+}
+
+func TestTrunksValidate(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(Trunks{0, 4094}.Validate()).Should(Succeed())
+	Expect(Trunks{4095}.Validate()).ShouldNot(Succeed())
+	Expect(Trunks{-1}.Validate()).ShouldNot(Succeed())
+	Expect(Trunks{3, 1, 3}.Normalize()).Should(Equal(Trunks{1, 3}))
+	Expect(Trunks{1, 3}.Has(3)).Should(BeTrue())
+	Expect(Trunks{1, 3}.Has(2)).Should(BeFalse())
+}
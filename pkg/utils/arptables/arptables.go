@@ -24,6 +24,8 @@ import (
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	ererrors "github.com/everoute/everoute/pkg/errors"
 )
 
 type ArpRule struct {
@@ -248,7 +250,7 @@ func AppendUnique(chain, table string, rule *ArpRule) error {
 	if err != nil {
 		return fmt.Errorf("check rule failed")
 	} else if ok {
-		return fmt.Errorf("rule existed")
+		return ererrors.NewConflict("rule existed")
 	}
 
 	cmd := append([]string{"-A", chain, "-t", table}, rule.ToSpec()...)
@@ -265,7 +267,7 @@ func InsertUnique(chain, table string, pos int, rule *ArpRule) error {
 	if err != nil {
 		return fmt.Errorf("check rule failed")
 	} else if ok {
-		return fmt.Errorf("rule existed")
+		return ererrors.NewConflict("rule existed")
 	}
 
 	cmd := append([]string{"-I", chain, strconv.Itoa(pos), "-t", table}, rule.ToSpec()...)
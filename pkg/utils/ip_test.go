@@ -67,6 +67,27 @@ func TestParseIPBlock(t *testing.T) {
 				mustParseCIDR("192.168.0.0/24"),
 			},
 		},
+		{
+			name: "should merge the overlapping excepts",
+			args: args{ipBlock: &networkingv1.IPBlock{
+				CIDR:   "192.168.0.0/24",
+				Except: []string{"192.168.0.128/26", "192.168.0.128/25", "192.168.0.130/32"},
+			}},
+			want: []*net.IPNet{
+				mustParseCIDR("192.168.0.0/25"),
+			},
+		},
+		{
+			name: "should exclude ipv6 cidr in the except",
+			args: args{ipBlock: &networkingv1.IPBlock{
+				CIDR:   "fd00::/126",
+				Except: []string{"fd00::1/128"},
+			}},
+			want: []*net.IPNet{
+				mustParseCIDR("fd00::/128"),
+				mustParseCIDR("fd00::2/127"),
+			},
+		},
 		{
 			name: "should error when input wrong cidr format",
 			args: args{ipBlock: &networkingv1.IPBlock{
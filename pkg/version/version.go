@@ -0,0 +1,22 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version is the version of everoute binaries, set by the linker flags on build:
+// -X github.com/everoute/everoute/pkg/version.Version=<version>
+package version
+
+// Version is the git describe of the source built from, "unknown" if not set on build.
+var Version = "unknown"
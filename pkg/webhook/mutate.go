@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+
+	admv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/everoute/everoute/pkg/webhook/mutates"
+)
+
+// MutateHandle defines capability about set the defaults of the object in AdmissionReview.
+type MutateHandle interface {
+	Mutate(ar *admv1.AdmissionReview) *admv1.AdmissionResponse
+}
+
+// MutateWebhook register webhook for set the defaults of everoute objects.
+type MutateWebhook struct {
+	Scheme *runtime.Scheme
+}
+
+// SetupWithManager create and add a MutateWebhook to the manager.
+func (m *MutateWebhook) SetupWithManager(mgr ctrl.Manager) error {
+	crdMutate := mutates.NewCRDMutate(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register("/mutate/crds", m.Handler(crdMutate))
+	return nil
+}
+
+// Handler handle mutate admission http request.
+func (m *MutateWebhook) Handler(handle MutateHandle) http.HandlerFunc {
+	return admissionHandler(handle.Mutate)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	admv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// CRDMutate maintains list of mutator for set the defaults of everoute objects.
+type CRDMutate struct {
+	scheme *runtime.Scheme
+	mutate map[metav1.GroupVersionKind]mutator
+}
+
+// NewCRDMutate return a new *CRDMutate and register mutators.
+func NewCRDMutate(scheme *runtime.Scheme) *CRDMutate {
+	m := &CRDMutate{
+		scheme: scheme,
+		mutate: make(map[metav1.GroupVersionKind]mutator),
+	}
+
+	// security.everoute.io/v1alpha1 securitypolicy mutator
+	m.mutate[metav1.GroupVersionKind{
+		Group:   "security.everoute.io",
+		Version: "v1alpha1",
+		Kind:    "SecurityPolicy",
+	}] = securityPolicyMutator{}
+
+	// security.everoute.io/v1alpha1 policyplan mutator
+	m.mutate[metav1.GroupVersionKind{
+		Group:   "security.everoute.io",
+		Version: "v1alpha1",
+		Kind:    "PolicyPlan",
+	}] = policyPlanMutator{}
+
+	return m
+}
+
+// mutator sets the defaults of the object in place, returns the json patch replace
+// the fields changed, empty if nothing changed. The oldObj is nil on create.
+type mutator interface {
+	mutate(obj, oldObj runtime.Object, userInfo authv1.UserInfo) ([]patchOperation, error)
+}
+
+// patchOperation is an operation of the RFC 6902 json patch.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Mutate read AdmissionReview request, return AdmissionResponse with the json patch
+// set the defaults. The objects without mutator or unchanged are allowed as they are.
+func (m *CRDMutate) Mutate(ar *admv1.AdmissionReview) *admv1.AdmissionResponse {
+	gvk := ar.Request.Kind
+	raw := ar.Request.Object.Raw
+
+	mutator, ok := m.mutate[gvk]
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return &admv1.AdmissionResponse{Allowed: true}
+	}
+	if ar.Request.Operation != admv1.Create && ar.Request.Operation != admv1.Update {
+		return &admv1.AdmissionResponse{Allowed: true}
+	}
+
+	obj, err := m.unmarshal(raw, gvk)
+	var oldObj runtime.Object
+	if oldRaw := ar.Request.OldObject.Raw; err == nil && len(oldRaw) != 0 && string(oldRaw) != "null" {
+		oldObj, err = m.unmarshal(oldRaw, gvk)
+	}
+	var patch []byte
+	if err == nil {
+		var operations []patchOperation
+		operations, err = mutator.mutate(obj, oldObj, ar.Request.UserInfo)
+		if err == nil && len(operations) != 0 {
+			patch, err = json.Marshal(operations)
+		}
+	}
+	if err != nil {
+		return &admv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Message: err.Error(),
+			},
+		}
+	}
+
+	if len(patch) == 0 {
+		return &admv1.AdmissionResponse{Allowed: true}
+	}
+	patchType := admv1.PatchTypeJSONPatch
+	return &admv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+func (m *CRDMutate) unmarshal(raw []byte, gvk metav1.GroupVersionKind) (runtime.Object, error) {
+	obj, err := m.scheme.New(schema.GroupVersionKind{
+		Group:   gvk.Group,
+		Version: gvk.Version,
+		Kind:    gvk.Kind,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj, json.Unmarshal(raw, obj)
+}
+
+type securityPolicyMutator struct{}
+
+func (securityPolicyMutator) mutate(obj, _ runtime.Object, _ authv1.UserInfo) ([]patchOperation, error) {
+	policy, ok := obj.(*securityv1alpha1.SecurityPolicy)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	if !SetSecurityPolicyDefaults(&policy.Spec) {
+		return nil, nil
+	}
+	return []patchOperation{{Op: "add", Path: "/spec", Value: policy.Spec}}, nil
+}
+
+// policyPlanMutator records the user requesting as the author when the changes proposed, and
+// as the approver when the plan approved. The validate webhook rejects the users set otherwise.
+type policyPlanMutator struct{}
+
+func (policyPlanMutator) mutate(obj, oldObj runtime.Object, userInfo authv1.UserInfo) ([]patchOperation, error) {
+	plan, ok := obj.(*securityv1alpha1.PolicyPlan)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	oldPlan, _ := oldObj.(*securityv1alpha1.PolicyPlan)
+
+	var operations []patchOperation
+	if oldPlan == nil || plan.Spec.ProposalChanged(&oldPlan.Spec) {
+		operations = append(operations, patchOperation{Op: "add", Path: "/spec/author", Value: userInfo})
+	}
+	if plan.Spec.Approved && (oldPlan == nil || !oldPlan.Spec.Approved) {
+		operations = append(operations, patchOperation{Op: "add", Path: "/spec/approver", Value: userInfo})
+	}
+	return operations, nil
+}
+
+// SetSecurityPolicyDefaults sets the defaults of the SecurityPolicy spec in place, returns
+// true if the spec changed. The policies accepted before work the same with the defaults:
+//   - the empty tier is set as tier2.
+//   - the empty policyTypes are set as the types inferred by IsEnable, ingress always and
+//     egress if the policy has egress rules.
+//   - the cidrs of the ipBlocks are set as the network address, e.g. 10.0.0.1/24 as 10.0.0.0/24.
+//   - the spaces in the portRanges are removed, e.g. "22, 80 - 90" as "22,80-90".
+func SetSecurityPolicyDefaults(spec *securityv1alpha1.SecurityPolicySpec) bool {
+	var changed bool
+
+	if spec.Tier == "" {
+		spec.Tier = constants.Tier2
+		changed = true
+	}
+
+	if len(spec.PolicyTypes) == 0 {
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+		if len(spec.EgressRules) != 0 {
+			spec.PolicyTypes = append(spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+		}
+		changed = true
+	}
+
+	for _, rules := range [][]securityv1alpha1.Rule{spec.IngressRules, spec.EgressRules} {
+		for i := range rules {
+			for j := range rules[i].Ports {
+				changed = normalizePortRange(&rules[i].Ports[j].PortRange) || changed
+			}
+			for _, peers := range [][]securityv1alpha1.SecurityPolicyPeer{rules[i].From, rules[i].To} {
+				for k := range peers {
+					if peers[k].IPBlock == nil {
+						continue
+					}
+					changed = normalizeCIDR(&peers[k].IPBlock.CIDR) || changed
+					for l := range peers[k].IPBlock.Except {
+						changed = normalizeCIDR(&peers[k].IPBlock.Except[l]) || changed
+					}
+				}
+			}
+		}
+	}
+
+	return changed
+}
+
+// normalizeCIDR sets the cidr as the network address, the invalid cidr is left for the
+// validate webhook to reject.
+func normalizeCIDR(cidr *string) bool {
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(*cidr))
+	if err != nil || ipNet.String() == *cidr {
+		return false
+	}
+	*cidr = ipNet.String()
+	return true
+}
+
+func normalizePortRange(portRange *string) bool {
+	normalized := strings.Join(strings.Fields(*portRange), "")
+	if normalized == *portRange {
+		return false
+	}
+	*portRange = normalized
+	return true
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutates
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+func TestSetSecurityPolicyDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    securityv1alpha1.SecurityPolicySpec
+		expect  securityv1alpha1.SecurityPolicySpec
+		changed bool
+	}{
+		{
+			name: "should set tier and ingress policy type",
+			spec: securityv1alpha1.SecurityPolicySpec{},
+			expect: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier2",
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+			changed: true,
+		},
+		{
+			name: "should set egress policy type with egress rules",
+			spec: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier1",
+				EgressRules: []securityv1alpha1.Rule{{Name: "rule1"}},
+			},
+			expect: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier1",
+				EgressRules: []securityv1alpha1.Rule{{Name: "rule1"}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			},
+			changed: true,
+		},
+		{
+			name: "should normalize cidrs and port ranges",
+			spec: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier2",
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				IngressRules: []securityv1alpha1.Rule{{
+					Name:  "rule1",
+					Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: " 22, 80 - 90 "}},
+					From: []securityv1alpha1.SecurityPolicyPeer{{
+						IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.1/16", Except: []string{"10.0.1.1/24"}},
+					}},
+				}},
+			},
+			expect: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier2",
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				IngressRules: []securityv1alpha1.Rule{{
+					Name:  "rule1",
+					Ports: []securityv1alpha1.SecurityPolicyPort{{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "22,80-90"}},
+					From: []securityv1alpha1.SecurityPolicyPeer{{
+						IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+					}},
+				}},
+			},
+			changed: true,
+		},
+		{
+			name: "should keep the invalid cidr and the spec with defaults",
+			spec: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier0",
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				EgressRules: []securityv1alpha1.Rule{{
+					Name: "rule1",
+					To:   []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.300/24"}}},
+				}},
+			},
+			expect: securityv1alpha1.SecurityPolicySpec{
+				Tier:        "tier0",
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				EgressRules: []securityv1alpha1.Rule{{
+					Name: "rule1",
+					To:   []securityv1alpha1.SecurityPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.300/24"}}},
+				}},
+			},
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := SetSecurityPolicyDefaults(&tt.spec)
+			if changed != tt.changed || !reflect.DeepEqual(tt.spec, tt.expect) {
+				t.Errorf("expect changed %v spec %+v, got changed %v spec %+v", tt.changed, tt.expect, changed, tt.spec)
+			}
+		})
+	}
+}
+
+func TestMutate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = securityv1alpha1.AddToScheme(scheme)
+	mutate := NewCRDMutate(scheme)
+
+	policyGVK := metav1.GroupVersionKind{Group: "security.everoute.io", Version: "v1alpha1", Kind: "SecurityPolicy"}
+	newReview := func(gvk metav1.GroupVersionKind, operation admv1.Operation, spec securityv1alpha1.SecurityPolicySpec) *admv1.AdmissionReview {
+		raw, _ := json.Marshal(&securityv1alpha1.SecurityPolicy{Spec: spec})
+		return &admv1.AdmissionReview{Request: &admv1.AdmissionRequest{
+			Kind:      gvk,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	t.Run("should patch the spec with defaults", func(t *testing.T) {
+		resp := mutate.Mutate(newReview(policyGVK, admv1.Create, securityv1alpha1.SecurityPolicySpec{}))
+		if !resp.Allowed || resp.PatchType == nil || *resp.PatchType != admv1.PatchTypeJSONPatch {
+			t.Fatalf("expect allowed with json patch, got %+v", resp)
+		}
+		var operations []struct {
+			Op    string                              `json:"op"`
+			Path  string                              `json:"path"`
+			Value securityv1alpha1.SecurityPolicySpec `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Patch, &operations); err != nil {
+			t.Fatalf("unexpect patch %s: %s", resp.Patch, err)
+		}
+		if len(operations) != 1 || operations[0].Path != "/spec" || operations[0].Value.Tier != "tier2" {
+			t.Errorf("unexpect patch %s", resp.Patch)
+		}
+	})
+
+	t.Run("should not patch the spec without change", func(t *testing.T) {
+		resp := mutate.Mutate(newReview(policyGVK, admv1.Update, securityv1alpha1.SecurityPolicySpec{
+			Tier:        "tier2",
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}))
+		if !resp.Allowed || len(resp.Patch) != 0 {
+			t.Errorf("expect allowed without patch, got %+v", resp)
+		}
+	})
+
+	t.Run("should not patch the objects without mutator", func(t *testing.T) {
+		gvk := metav1.GroupVersionKind{Group: "security.everoute.io", Version: "v1alpha1", Kind: "GlobalPolicy"}
+		resp := mutate.Mutate(newReview(gvk, admv1.Create, securityv1alpha1.SecurityPolicySpec{}))
+		if !resp.Allowed || len(resp.Patch) != 0 {
+			t.Errorf("expect allowed without patch, got %+v", resp)
+		}
+	})
+}
+
+func TestMutatePolicyPlan(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = securityv1alpha1.AddToScheme(scheme)
+	mutate := NewCRDMutate(scheme)
+
+	planGVK := metav1.GroupVersionKind{Group: "security.everoute.io", Version: "v1alpha1", Kind: "PolicyPlan"}
+	changes := []securityv1alpha1.PolicyChange{{
+		Operation: securityv1alpha1.PolicyChangeDelete,
+		Namespace: "ns1",
+		Name:      "policy1",
+	}}
+	mutatePaths := func(plan, oldPlan *securityv1alpha1.PolicyPlan, username string) map[string]string {
+		operation, oldRaw := admv1.Create, []byte(nil)
+		if oldPlan != nil {
+			operation = admv1.Update
+			oldRaw, _ = json.Marshal(oldPlan)
+		}
+		raw, _ := json.Marshal(plan)
+		resp := mutate.Mutate(&admv1.AdmissionReview{Request: &admv1.AdmissionRequest{
+			Kind:      planGVK,
+			Operation: operation,
+			UserInfo:  authv1.UserInfo{Username: username},
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}})
+		if !resp.Allowed {
+			t.Fatalf("expect allowed, got %+v", resp)
+		}
+		var operations []struct {
+			Path  string          `json:"path"`
+			Value authv1.UserInfo `json:"value"`
+		}
+		if len(resp.Patch) != 0 {
+			if err := json.Unmarshal(resp.Patch, &operations); err != nil {
+				t.Fatalf("unexpect patch %s: %s", resp.Patch, err)
+			}
+		}
+		paths := make(map[string]string, len(operations))
+		for _, operation := range operations {
+			paths[operation.Path] = operation.Value.Username
+		}
+		return paths
+	}
+
+	t.Run("should record the author on create", func(t *testing.T) {
+		plan := &securityv1alpha1.PolicyPlan{Spec: securityv1alpha1.PolicyPlanSpec{
+			Changes: changes,
+			Author:  &authv1.UserInfo{Username: "user2"},
+		}}
+		paths := mutatePaths(plan, nil, "user1")
+		if !reflect.DeepEqual(paths, map[string]string{"/spec/author": "user1"}) {
+			t.Errorf("unexpect patch paths %v", paths)
+		}
+	})
+
+	t.Run("should record the author when the changes modified", func(t *testing.T) {
+		oldPlan := &securityv1alpha1.PolicyPlan{Spec: securityv1alpha1.PolicyPlanSpec{
+			Author: &authv1.UserInfo{Username: "user1"},
+		}}
+		plan := oldPlan.DeepCopy()
+		plan.Spec.Changes = changes
+		paths := mutatePaths(plan, oldPlan, "user2")
+		if !reflect.DeepEqual(paths, map[string]string{"/spec/author": "user2"}) {
+			t.Errorf("unexpect patch paths %v", paths)
+		}
+	})
+
+	t.Run("should record the approver only when approved", func(t *testing.T) {
+		oldPlan := &securityv1alpha1.PolicyPlan{Spec: securityv1alpha1.PolicyPlanSpec{
+			Changes: changes,
+			Author:  &authv1.UserInfo{Username: "user1"},
+		}}
+		plan := oldPlan.DeepCopy()
+		plan.Spec.Approved = true
+		paths := mutatePaths(plan, oldPlan, "user2")
+		if !reflect.DeepEqual(paths, map[string]string{"/spec/approver": "user2"}) {
+			t.Errorf("unexpect patch paths %v", paths)
+		}
+		if paths := mutatePaths(plan, plan, "user3"); len(paths) != 0 {
+			t.Errorf("unexpect patch paths %v", paths)
+		}
+	})
+}
@@ -38,11 +38,13 @@ type ValidateHandle interface {
 // ValidateWebhook register webhook for validate everoute objects.
 type ValidateWebhook struct {
 	Scheme *runtime.Scheme
+	// PolicyDelegation restricts the cross-namespace peers of the SecurityPolicies, optional.
+	PolicyDelegation *validates.PolicyDelegation
 }
 
 // SetupWithManager create and add a ValidateWebhook to the manager.
 func (v *ValidateWebhook) SetupWithManager(mgr ctrl.Manager) error {
-	crdValidate := validates.NewCRDValidate(mgr.GetClient(), mgr.GetScheme())
+	crdValidate := validates.NewCRDValidate(mgr.GetClient(), mgr.GetScheme(), v.PolicyDelegation)
 
 	mgr.GetWebhookServer().Register("/validate/crds", v.Handler(crdValidate))
 	return nil
@@ -50,6 +52,12 @@ func (v *ValidateWebhook) SetupWithManager(mgr ctrl.Manager) error {
 
 // Handler handle validate admission http request.
 func (v *ValidateWebhook) Handler(handle ValidateHandle) http.HandlerFunc {
+	return admissionHandler(handle.Validate)
+}
+
+// admissionHandler decodes the AdmissionReview of the http request, and writes back the
+// AdmissionReview with the response of the review.
+func admissionHandler(review func(ar *admv1.AdmissionReview) *admv1.AdmissionResponse) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var reqBody []byte
 		if r.Body != nil {
@@ -79,7 +87,7 @@ func (v *ValidateWebhook) Handler(handle ValidateHandle) http.HandlerFunc {
 				},
 			}
 		} else {
-			admissionResponse = handle.Validate(&ar)
+			admissionResponse = review(&ar)
 		}
 
 		aReview := admv1.AdmissionReview{}
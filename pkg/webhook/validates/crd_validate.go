@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	admv1 "k8s.io/api/admission/v1"
 	authv1 "k8s.io/api/authentication/v1"
@@ -41,8 +42,10 @@ import (
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
+	"github.com/everoute/everoute/pkg/controller/bypass"
 	ctrltypes "github.com/everoute/everoute/pkg/controller/types"
 	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
 )
 
 // CRDValidate maintains list of validator for validate everoute objects.
@@ -52,8 +55,9 @@ type CRDValidate struct {
 	validate map[metav1.GroupVersionKind][]validator
 }
 
-// NewCRDValidate return a new *CRDValidate and register validators.
-func NewCRDValidate(client client.Client, scheme *runtime.Scheme) *CRDValidate {
+// NewCRDValidate return a new *CRDValidate and register validators. The cross-namespace
+// peers of the SecurityPolicies are not restricted if the delegation is nil.
+func NewCRDValidate(client client.Client, scheme *runtime.Scheme, delegation *PolicyDelegation) *CRDValidate {
 	v := &CRDValidate{
 		client:   client,
 		scheme:   scheme,
@@ -79,7 +83,7 @@ func NewCRDValidate(client client.Client, scheme *runtime.Scheme) *CRDValidate {
 		Group:   "security.everoute.io",
 		Version: "v1alpha1",
 		Kind:    "SecurityPolicy",
-	}, &securityPolicyValidator{v.client})
+	}, &securityPolicyValidator{Client: v.client, delegation: delegation})
 
 	// security.everoute.io/v1alpha1 globalpolicy validator
 	v.register(metav1.GroupVersionKind{
@@ -88,6 +92,20 @@ func NewCRDValidate(client client.Client, scheme *runtime.Scheme) *CRDValidate {
 		Kind:    "GlobalPolicy",
 	}, &globalPolicyValidator{v.client})
 
+	// security.everoute.io/v1alpha1 policyplan validator
+	v.register(metav1.GroupVersionKind{
+		Group:   "security.everoute.io",
+		Version: "v1alpha1",
+		Kind:    "PolicyPlan",
+	}, &policyPlanValidator{policy: &securityPolicyValidator{Client: v.client, delegation: delegation}})
+
+	// security.everoute.io/v1alpha1 tier validator
+	v.register(metav1.GroupVersionKind{
+		Group:   "security.everoute.io",
+		Version: "v1alpha1",
+		Kind:    "Tier",
+	}, &tierValidator{v.client})
+
 	return v
 }
 
@@ -210,6 +228,9 @@ func (v endpointValidator) createValidate(curObj runtime.Object, userInfo authv1
 	if err != nil {
 		return err.Error(), false
 	}
+	if err = v.validateBypass(nil, curObj.(*securityv1alpha1.Endpoint)); err != nil {
+		return err.Error(), false
+	}
 	return "", true
 }
 
@@ -224,6 +245,9 @@ func (v endpointValidator) updateValidate(oldObj, curObj runtime.Object, userInf
 	if err != nil {
 		return err.Error(), false
 	}
+	if err = v.validateBypass(oldEndpoint, curEndpoint); err != nil {
+		return err.Error(), false
+	}
 	return "", true
 }
 
@@ -241,10 +265,37 @@ func (v *endpointValidator) validateEndpoint(endpoint *securityv1alpha1.Endpoint
 	if strings.ContainsRune(endpoint.Spec.Reference.ExternalIDValue, ctrltypes.Separator) {
 		return fmt.Errorf("externalIDValue contains rune / not allow")
 	}
+	if err := validateVRF(endpoint.Spec.VRF); err != nil {
+		return err
+	}
+	if err := endpoint.Spec.Trunks.Validate(); err != nil {
+		return fmt.Errorf("invalid trunks: %s", err)
+	}
 	_, err := labels.AsSet(endpoint.Labels, endpoint.Spec.ExtendLabels)
 	return err
 }
 
+// validateBypass validates the expire time of the enforcement bypass when it changed, it must
+// be in the future and no later than bypass.MaxTTL, so the enforcement never bypassed forever.
+func (v *endpointValidator) validateBypass(oldEndpoint, curEndpoint *securityv1alpha1.Endpoint) error {
+	expireTimeStr, ok := curEndpoint.GetAnnotations()[constants.EnforcementBypassExpireTimeAnnotation]
+	if !ok || (oldEndpoint != nil && oldEndpoint.GetAnnotations()[constants.EnforcementBypassExpireTimeAnnotation] == expireTimeStr) {
+		return nil
+	}
+	expireTime, err := bypass.ExpireTime(curEndpoint)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !expireTime.After(now) {
+		return fmt.Errorf("enforcement bypass expire time %s must be in the future", expireTimeStr)
+	}
+	if expireTime.After(now.Add(bypass.MaxTTL)) {
+		return fmt.Errorf("enforcement bypass expire time %s exceeds the max ttl %s", expireTimeStr, bypass.MaxTTL)
+	}
+	return nil
+}
+
 type endpointGroupValidator resourceValidator
 
 func (v endpointGroupValidator) createValidate(curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
@@ -271,6 +322,11 @@ func (v endpointGroupValidator) updateValidate(oldObj, curObj runtime.Object, us
 	return "", true
 }
 
+// ValidateEndpointGroupSpec validates the group spec as the webhook does.
+func ValidateEndpointGroupSpec(spec *groupv1alpha1.EndpointGroupSpec) error {
+	return endpointGroupValidator{}.validateGroupSpec(spec)
+}
+
 func (v endpointGroupValidator) validateGroupSpec(spec *groupv1alpha1.EndpointGroupSpec) error {
 	var allErrs field.ErrorList
 
@@ -278,11 +334,24 @@ func (v endpointGroupValidator) validateGroupSpec(spec *groupv1alpha1.EndpointGr
 		return fmt.Errorf("NamespaceSelector and Namespace cannot be set at the same time")
 	}
 
+	// the selectors are ignored when the endpoint set
+	if spec.Endpoint != nil && (spec.EndpointSelector != nil || spec.NamespaceSelector != nil) {
+		return fmt.Errorf("Endpoint is set then neither of EndpointSelector or NamespaceSelector can be")
+	}
+
 	valid, message := spec.EndpointSelector.IsValid()
 	if !valid {
 		allErrs = append(allErrs, &field.Error{Type: field.ErrorTypeInvalid, Detail: message})
 	}
 
+	if err := validateVRF(spec.VRF); err != nil {
+		allErrs = append(allErrs, &field.Error{Type: field.ErrorTypeInvalid, Field: "VRF", Detail: err.Error()})
+	}
+
+	if err := validateVLANs(spec.VLANs); err != nil {
+		allErrs = append(allErrs, &field.Error{Type: field.ErrorTypeInvalid, Field: "VLANs", Detail: err.Error()})
+	}
+
 	errs := metav1validation.ValidateLabelSelector(spec.NamespaceSelector, field.NewPath("NamespaceSelector"))
 	allErrs = append(allErrs, errs...)
 
@@ -293,10 +362,17 @@ func (v endpointGroupValidator) deleteValidate(oldObj runtime.Object, userInfo a
 	return "", true
 }
 
-type securityPolicyValidator resourceValidator
+type securityPolicyValidator struct {
+	client.Client
+	// delegation restricts the cross-namespace peers, nil means not restricted
+	delegation *PolicyDelegation
+}
 
 func (v securityPolicyValidator) createValidate(curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
 	err := v.validatePolicy(curObj.(*securityv1alpha1.SecurityPolicy))
+	if err == nil {
+		err = v.validateDelegation(curObj.(*securityv1alpha1.SecurityPolicy), userInfo)
+	}
 	if err != nil {
 		return err.Error(), false
 	}
@@ -305,6 +381,9 @@ func (v securityPolicyValidator) createValidate(curObj runtime.Object, userInfo
 
 func (v securityPolicyValidator) updateValidate(oldObj, curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
 	err := v.validatePolicy(curObj.(*securityv1alpha1.SecurityPolicy))
+	if err == nil {
+		err = v.validateDelegation(curObj.(*securityv1alpha1.SecurityPolicy), userInfo)
+	}
 	if err != nil {
 		return err.Error(), false
 	}
@@ -315,6 +394,12 @@ func (v securityPolicyValidator) deleteValidate(oldObj runtime.Object, userInfo
 	return "", true
 }
 
+// ValidateSecurityPolicy validates the policy as the webhook does, except the delegation which
+// depends on the user requesting.
+func ValidateSecurityPolicy(policy *securityv1alpha1.SecurityPolicy) error {
+	return (&securityPolicyValidator{}).validatePolicy(policy)
+}
+
 func (v *securityPolicyValidator) validatePolicy(policy *securityv1alpha1.SecurityPolicy) error {
 	// check attached tier exist
 	switch policy.Spec.Tier {
@@ -324,7 +409,13 @@ func (v *securityPolicyValidator) validatePolicy(policy *securityv1alpha1.Securi
 			return fmt.Errorf("monitor mode doesn't support tier %s", policy.Spec.Tier)
 		}
 	default:
-		return fmt.Errorf("tier %s not in: %s, %s, %s, %s", policy.Spec.Tier, constants.Tier0, constants.Tier1, constants.Tier2, constants.TierECP)
+		if err := v.validateTier(policy.Spec.Tier); err != nil {
+			return err
+		}
+	}
+
+	if err := validateVRF(policy.Spec.VRF); err != nil {
+		return err
 	}
 
 	// check validate of spec.appliedTo
@@ -339,6 +430,64 @@ func (v *securityPolicyValidator) validatePolicy(policy *securityv1alpha1.Securi
 		return fmt.Errorf("error format of policy rules: %s", err)
 	}
 
+	if err = validatePolicyL7(policy); err != nil {
+		return fmt.Errorf("error format of policy rules: %s", err)
+	}
+
+	if err = v.validateRuleSemantics(policy); err != nil {
+		return fmt.Errorf("policy rules would not work as written: %s", err)
+	}
+
+	return nil
+}
+
+// validateRuleSemantics rejects the rules well-formed but silently ignored or misprogrammed
+// in the datapath, with the hints to fix them.
+func (v *securityPolicyValidator) validateRuleSemantics(policy *securityv1alpha1.SecurityPolicy) error {
+	var errList []error
+	for _, rule := range policy.Spec.IngressRules {
+		if len(rule.To) != 0 {
+			errList = append(errList, fmt.Errorf("ingress rule %s sets to, which only works in the egress rules, select the sources in from instead", rule.Name))
+		}
+		// the named ports of the ingress rules are resolved on the endpoints applied to
+		if len(policy.Spec.AppliedTo) == 0 && isNamedPortExists(rule.Ports) {
+			errList = append(errList, fmt.Errorf("ingress rule %s uses the named ports without appliedTo, select the endpoints in appliedTo or use the port numbers instead", rule.Name))
+		}
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		if len(rule.From) != 0 {
+			errList = append(errList, fmt.Errorf("egress rule %s sets from, which only works in the ingress rules, select the destinations in to instead", rule.Name))
+		}
+	}
+	return errors.NewAggregate(errList)
+}
+
+func isNamedPortExists(ports []securityv1alpha1.SecurityPolicyPort) bool {
+	for _, port := range ports {
+		if port.Type == securityv1alpha1.PortTypeName {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTier checks the Tier named exists, only the name is checked without the client.
+func (v *securityPolicyValidator) validateTier(name string) error {
+	notFound := fmt.Errorf("tier %s not in: %s, %s, %s, %s or the Tiers", name, constants.Tier0, constants.Tier1, constants.Tier2, constants.TierECP)
+	if len(validation.IsDNS1123Subdomain(name)) != 0 {
+		return notFound
+	}
+	if v.Client == nil {
+		return nil
+	}
+
+	err := v.Get(context.Background(), client.ObjectKey{Name: name}, &securityv1alpha1.Tier{})
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err != nil {
+		return notFound
+	}
 	return nil
 }
 
@@ -350,6 +499,12 @@ func (v *securityPolicyValidator) validateAppliedTo(appliedTo []securityv1alpha1
 		if peer.Endpoint != nil && peer.EndpointSelector != nil {
 			return fmt.Errorf("cannot both set Endpoint and EndpointSelector")
 		}
+		if peer.Endpoint != nil && len(peer.VLANs) != 0 {
+			return fmt.Errorf("cannot both set Endpoint and VLANs")
+		}
+		if err := validateVLANs(peer.VLANs); err != nil {
+			return err
+		}
 		if peer.Endpoint != nil {
 			errs := validation.IsDNS1123Subdomain(*peer.Endpoint)
 			if len(errs) != 0 {
@@ -409,15 +564,115 @@ func (v *securityPolicyValidator) validateRule(rule *securityv1alpha1.Rule) erro
 		}
 	}
 
+	if err := v.validateRuleAction(rule); err != nil {
+		ruleErrList = append(ruleErrList, err)
+	}
+
+	if err := v.validateRuleL7(rule); err != nil {
+		ruleErrList = append(ruleErrList, err)
+	}
+
 	if len(ruleErrList)+len(portErrList) != 0 {
 		return errors.NewAggregate(append(ruleErrList, portErrList...))
 	}
 	return nil
 }
 
+func (v *securityPolicyValidator) validateRuleAction(rule *securityv1alpha1.Rule) error {
+	if rule.Action != securityv1alpha1.RuleActionRedirect {
+		if rule.RedirectTo != nil {
+			return fmt.Errorf("redirectTo only works with action %s", securityv1alpha1.RuleActionRedirect)
+		}
+		return nil
+	}
+
+	if rule.RedirectTo == nil {
+		return fmt.Errorf("redirectTo must set when action is %s", securityv1alpha1.RuleActionRedirect)
+	}
+	if ip := net.ParseIP(rule.RedirectTo.IP); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("redirect ip %s must be an available ipv4 address", rule.RedirectTo.IP)
+	}
+	if rule.RedirectTo.Port < 0 || rule.RedirectTo.Port > 65535 {
+		return fmt.Errorf("redirect port %d out of range", rule.RedirectTo.Port)
+	}
+	return nil
+}
+
+// validatePolicyL7 allows the l7 criteria only in the egress rules of the policy not in symmetric
+// mode. The l7 proxy dials the upstream from the agent of the client, the server would not see
+// the client address, so the proxy never works on the agent of the server.
+func validatePolicyL7(policy *securityv1alpha1.SecurityPolicy) error {
+	for _, rule := range policy.Spec.IngressRules {
+		if rule.L7 != nil {
+			return fmt.Errorf("l7 of rule %s only works in egress rules", rule.Name)
+		}
+	}
+	for _, rule := range policy.Spec.EgressRules {
+		if rule.L7 != nil && policy.Spec.SymmetricMode {
+			return fmt.Errorf("l7 of rule %s not works in symmetric mode", rule.Name)
+		}
+	}
+	return nil
+}
+
+// validateRuleL7 validates the l7 criteria, which only works on the allowed tcp traffic.
+func (v *securityPolicyValidator) validateRuleL7(rule *securityv1alpha1.Rule) error {
+	if rule.L7 == nil {
+		return nil
+	}
+
+	if rule.Action != "" && rule.Action != securityv1alpha1.RuleActionAllow {
+		return fmt.Errorf("l7 only works with action %s", securityv1alpha1.RuleActionAllow)
+	}
+	if len(rule.Ports) == 0 {
+		return fmt.Errorf("l7 must set with tcp ports")
+	}
+	for _, port := range rule.Ports {
+		if port.Protocol != securityv1alpha1.ProtocolTCP {
+			return fmt.Errorf("l7 only works with protocol %s, got %s", securityv1alpha1.ProtocolTCP, port.Protocol)
+		}
+	}
+	if len(rule.L7.HTTP) == 0 && len(rule.L7.SNI) == 0 {
+		return fmt.Errorf("at least one of http and sni should be set in l7")
+	}
+
+	httpMethod := regexp.MustCompile(`^[A-Z]+$`)
+	for _, match := range rule.L7.HTTP {
+		if match.Method != "" && !httpMethod.MatchString(match.Method) {
+			return fmt.Errorf("http method %s must be uppercase letters", match.Method)
+		}
+		if _, err := regexp.Compile(match.Path); err != nil {
+			return fmt.Errorf("http path %s not a available regular expression: %s", match.Path, err)
+		}
+	}
+	for _, sni := range rule.L7.SNI {
+		if err := validateFQDN(sni); err != nil {
+			return fmt.Errorf("error format of sni %s: %s", sni, err)
+		}
+	}
+	return nil
+}
+
 func (v *securityPolicyValidator) validateRulePeer(peer *securityv1alpha1.SecurityPolicyPeer) error {
+	if peer.Builtin != "" {
+		if peer.IPBlock != nil || peer.FQDN != "" || peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil || peer.ServiceAccount != "" || len(peer.VLANs) != 0 {
+			return fmt.Errorf("builtin is set then neither of the other fields can be")
+		}
+		if peer.Builtin != securityv1alpha1.BuiltinPeerClusterInternal && peer.Builtin != securityv1alpha1.BuiltinPeerClusterExternal {
+			return fmt.Errorf("unknown builtin peer %s", peer.Builtin)
+		}
+		return nil
+	}
+
+	if peer.FQDN != "" {
+		if peer.IPBlock != nil || peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil || peer.ServiceAccount != "" || len(peer.VLANs) != 0 {
+			return fmt.Errorf("fqdn is set then neither of the other fields can be")
+		}
+		return validateFQDN(peer.FQDN)
+	}
+
 	if peer.IPBlock != nil {
-		if peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil {
+		if peer.Endpoint != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil || peer.ServiceAccount != "" || len(peer.VLANs) != 0 {
 			return fmt.Errorf("ipBlock is set then neither of the other fields can be")
 		}
 		if err := validateIPBlock(*peer.IPBlock); err != nil {
@@ -427,7 +682,7 @@ func (v *securityPolicyValidator) validateRulePeer(peer *securityv1alpha1.Securi
 	}
 
 	if peer.Endpoint != nil {
-		if peer.IPBlock != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil {
+		if peer.IPBlock != nil || peer.EndpointSelector != nil || peer.NamespaceSelector != nil || peer.ServiceAccount != "" || len(peer.VLANs) != 0 {
 			return fmt.Errorf("endpoint is set then neither of the other fields can be")
 		}
 		es1 := validation.IsDNS1123Subdomain(peer.Endpoint.Name)
@@ -438,10 +693,22 @@ func (v *securityPolicyValidator) validateRulePeer(peer *securityv1alpha1.Securi
 		return nil
 	}
 
-	if peer.EndpointSelector == nil && peer.NamespaceSelector == nil {
+	if peer.EndpointSelector == nil && peer.NamespaceSelector == nil && peer.ServiceAccount == "" {
 		return fmt.Errorf("at least one field should be set in SecurityPolicyPeer")
 	}
 
+	if peer.ServiceAccount != "" {
+		// the service account is matched as the label value on the endpoints of the pods
+		errs := append(validation.IsDNS1123Subdomain(peer.ServiceAccount), validation.IsValidLabelValue(peer.ServiceAccount)...)
+		if len(errs) != 0 {
+			return fmt.Errorf("%s not a available service account: %s", peer.ServiceAccount, strings.Join(errs, ", "))
+		}
+	}
+
+	if err := validateVLANs(peer.VLANs); err != nil {
+		return err
+	}
+
 	valid, message := peer.EndpointSelector.IsValid()
 	if !valid {
 		return fmt.Errorf("%+v not a available selector: %s", peer.EndpointSelector, message)
@@ -458,6 +725,13 @@ func (v *securityPolicyValidator) validateRulePeer(peer *securityv1alpha1.Securi
 }
 
 func (v *securityPolicyValidator) validatePort(port *securityv1alpha1.SecurityPolicyPort) error {
+	if err := v.validateICMP(port); err != nil {
+		return err
+	}
+	// the ports of the other protocols are ignored in the datapath
+	if port.PortRange != "" && port.Protocol != securityv1alpha1.ProtocolTCP && port.Protocol != securityv1alpha1.ProtocolUDP {
+		return fmt.Errorf("portrange %s only works with protocol TCP or UDP, remove it to match all the %s traffic", port.PortRange, port.Protocol)
+	}
 	// Only validate PortRange, port.Protocol and port.Type validate by crd
 	if port.Type != securityv1alpha1.PortTypeName {
 		return v.validatePortRange(port.PortRange)
@@ -465,6 +739,24 @@ func (v *securityPolicyValidator) validatePort(port *securityv1alpha1.SecurityPo
 	return nil
 }
 
+// validateICMP validates the ICMPType and ICMPCode only set with ICMP or ICMPv6, the range of
+// them validated by crd.
+func (v *securityPolicyValidator) validateICMP(port *securityv1alpha1.SecurityPolicyPort) error {
+	if port.ICMPType == nil && port.ICMPCode == nil {
+		return nil
+	}
+	if port.Protocol != securityv1alpha1.ProtocolICMP && port.Protocol != securityv1alpha1.ProtocolICMPv6 {
+		return fmt.Errorf("icmp type and code only valid when protocol is ICMP or ICMPv6")
+	}
+	if port.ICMPType == nil {
+		return fmt.Errorf("icmp code couldn't be set without icmp type")
+	}
+	if port.PortRange != "" {
+		return fmt.Errorf("portrange couldn't be set with icmp type")
+	}
+	return nil
+}
+
 func (v *securityPolicyValidator) validatePortRange(portRange string) error {
 	const (
 		emptyPort    = `^$`
@@ -532,6 +824,10 @@ func (v globalPolicyValidator) createValidate(curObj runtime.Object, userInfo au
 	policy := curObj.(*securityv1alpha1.GlobalPolicy)
 	policyList := securityv1alpha1.GlobalPolicyList{}
 
+	if err := v.validateExceptions(policy.Spec.Exceptions); err != nil {
+		return err.Error(), false
+	}
+
 	if err := v.List(context.Background(), &policyList); err != nil {
 		return err.Error(), false
 	}
@@ -555,6 +851,9 @@ func (v globalPolicyValidator) createValidate(curObj runtime.Object, userInfo au
 }
 
 func (v globalPolicyValidator) updateValidate(oldObj, curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	if err := v.validateExceptions(curObj.(*securityv1alpha1.GlobalPolicy).Spec.Exceptions); err != nil {
+		return err.Error(), false
+	}
 	return "", true
 }
 
@@ -562,6 +861,61 @@ func (v globalPolicyValidator) deleteValidate(oldObj runtime.Object, userInfo au
 	return "", true
 }
 
+// validateExceptions validates the cidrs and the ports of the exceptions, the named ports are not
+// allowed, because the exceptions are not applied to any endpoints.
+func (v globalPolicyValidator) validateExceptions(exceptions []securityv1alpha1.GlobalPolicyException) error {
+	for index, exception := range exceptions {
+		if len(exception.CIDRs) == 0 {
+			return fmt.Errorf("exception %d must have at least one cidr", index)
+		}
+		for _, cidr := range exception.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("exception %d unvalid cidr %s: %s", index, cidr, err)
+			}
+		}
+		for i := range exception.Ports {
+			port := &exception.Ports[i]
+			if port.Type == securityv1alpha1.PortTypeName {
+				return fmt.Errorf("exception %d couldn't use named port %s", index, port.PortRange)
+			}
+			if err := (&securityPolicyValidator{}).validatePort(port); err != nil {
+				return fmt.Errorf("exception %d unvalid port: %s", index, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVRF checks the vrf is empty or a dns label, it's used in the names of the groups.
+func validateVRF(vrf string) error {
+	if vrf == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(vrf); len(errs) != 0 {
+		return fmt.Errorf("%s not a available vrf: %s", vrf, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateVLANs checks the vlans are in range, vlan 0 selects the untagged endpoints.
+func validateVLANs(vlans []int32) error {
+	for _, vlan := range vlans {
+		if vlan < 0 || vlan > types.MaxVLAN {
+			return fmt.Errorf("vlan %d out of range", vlan)
+		}
+	}
+	return nil
+}
+
+// validateFQDN validates the dns name, the leftmost label could be the wildcard *.
+func validateFQDN(fqdn string) error {
+	name := strings.TrimPrefix(fqdn, "*.")
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(name)); len(errs) != 0 {
+		return fmt.Errorf("unvalid fqdn %s: %s", fqdn, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
 func validateIPBlock(ipBlock networkingv1.IPBlock) error {
 	_, cidrIPNet, err := net.ParseCIDR(ipBlock.CIDR)
 	if err != nil {
@@ -18,22 +18,29 @@ package validates_test
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	admv1 "k8s.io/api/admission/v1"
 	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	groupv1alpha1 "github.com/everoute/everoute/pkg/apis/group/v1alpha1"
 	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
 	"github.com/everoute/everoute/pkg/constants"
 	"github.com/everoute/everoute/pkg/labels"
+	"github.com/everoute/everoute/pkg/types"
+	"github.com/everoute/everoute/pkg/webhook/validates"
 )
 
 func init() {
@@ -294,6 +301,11 @@ var _ = Describe("CRD Validate", func() {
 			endpointGroupA.Spec.NamespaceSelector = &metav1.LabelSelector{}
 			Expect(validate.Validate(fakeAdmissionReview(endpointGroupA, nil, "")).Allowed).Should(BeFalse())
 		})
+		It("Create EndpointGroup with both Endpoint and EndpointSelector set should not allowed", func() {
+			endpointGroup := endpointGroupA.DeepCopy()
+			endpointGroup.Spec.Endpoint = &securityv1alpha1.NamespacedName{Name: "endpoint", Namespace: metav1.NamespaceDefault}
+			Expect(validate.Validate(fakeAdmissionReview(endpointGroup, nil, "")).Allowed).Should(BeFalse())
+		})
 		It("Update EndpointGroup with wrong selector should not allowed", func() {
 			endpointGroup := endpointGroupA.DeepCopy()
 			endpointGroup.Name = "endpointgroup"
@@ -328,6 +340,30 @@ var _ = Describe("CRD Validate", func() {
 			endpointB.Name = "endpointB"
 			Expect(validate.Validate(fakeAdmissionReview(endpointB, nil, "")).Allowed).Should(BeTrue())
 		})
+		It("Create endpoint with vrf should allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Name = "endpointB"
+			endpointB.Spec.VRF = "tenant-a"
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, nil, "")).Allowed).Should(BeTrue())
+		})
+		It("Create endpoint with error format of vrf should not allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Name = "endpointB"
+			endpointB.Spec.VRF = "Tenant/A"
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, nil, "")).Allowed).Should(BeFalse())
+		})
+		It("Create endpoint with trunks should allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Name = "endpointB"
+			endpointB.Spec.Trunks = types.Trunks{0, 100, 4094}
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, nil, "")).Allowed).Should(BeTrue())
+		})
+		It("Create endpoint with trunks out of range should not allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Name = "endpointB"
+			endpointB.Spec.Trunks = types.Trunks{100, 4095}
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, nil, "")).Allowed).Should(BeFalse())
+		})
 		It("Update endpoint id should not allowed", func() {
 			endpointB := endpointA.DeepCopy()
 			endpointB.Spec.Reference.ExternalIDValue = "update-id-value"
@@ -339,6 +375,34 @@ var _ = Describe("CRD Validate", func() {
 			endpointB.Spec.ExtendLabels = map[string][]string{"foo": {"bar", "baz"}}
 			Expect(validate.Validate(fakeAdmissionReview(endpointB, endpointA, "")).Allowed).Should(BeFalse())
 		})
+		It("Update endpoint with enforcement bypass should allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Annotations = map[string]string{
+				constants.EnforcementBypassExpireTimeAnnotation: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			}
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, endpointA, "")).Allowed).Should(BeTrue())
+		})
+		It("Update endpoint with enforcement bypass exceeds the max ttl should not allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Annotations = map[string]string{
+				constants.EnforcementBypassExpireTimeAnnotation: time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339),
+			}
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, endpointA, "")).Allowed).Should(BeFalse())
+		})
+		It("Update endpoint with enforcement bypass without expire time should not allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Annotations = map[string]string{constants.EnforcementBypassExpireTimeAnnotation: "forever"}
+			Expect(validate.Validate(fakeAdmissionReview(endpointB, endpointA, "")).Allowed).Should(BeFalse())
+		})
+		It("Update endpoint with expired enforcement bypass unchanged should allowed", func() {
+			endpointB := endpointA.DeepCopy()
+			endpointB.Annotations = map[string]string{
+				constants.EnforcementBypassExpireTimeAnnotation: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			}
+			endpointC := endpointB.DeepCopy()
+			endpointC.Spec.VRF = "tenant-a"
+			Expect(validate.Validate(fakeAdmissionReview(endpointC, endpointB, "")).Allowed).Should(BeTrue())
+		})
 		It("Delete endpoint should always allowed", func() {
 			Expect(validate.Validate(fakeAdmissionReview(nil, endpointA, "")).Allowed).Should(BeTrue())
 		})
@@ -363,11 +427,46 @@ var _ = Describe("CRD Validate", func() {
 			policy.Spec.SecurityPolicyEnforcementMode = securityv1alpha1.MonitorMode
 			Expect(validate.Validate(fakeAdmissionReview(policy, securityPolicyIngress, "")).Allowed).Should(BeFalse())
 		})
+		It("Create policy with error format of vrf should not allowed", func() {
+			policy := securityPolicyIngress.DeepCopy()
+			policy.Name = "new-policy"
+			policy.Spec.VRF = "Tenant_A"
+			Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+		})
 		It("Delete policy should always allowed", func() {
 			Expect(validate.Validate(fakeAdmissionReview(nil, securityPolicyEgress, "")).Allowed).Should(BeTrue())
 			Expect(validate.Validate(fakeAdmissionReview(nil, securityPolicyIngress, "")).Allowed).Should(BeTrue())
 		})
 
+		Context("Validate On ICMP", func() {
+			var policy *securityv1alpha1.SecurityPolicy
+			var icmpType, icmpCode int32 = 3, 1
+			BeforeEach(func() {
+				policy = securityPolicyIngress.DeepCopy()
+				policy.Name = "new-policy"
+			})
+
+			It("Create policy with icmp type and code should allowed", func() {
+				policy.Spec.IngressRules[0].Ports[0] = securityv1alpha1.SecurityPolicyPort{
+					Protocol: securityv1alpha1.ProtocolICMPv6,
+					ICMPType: &icmpType,
+					ICMPCode: &icmpCode,
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with icmp type of tcp should not allowed", func() {
+				policy.Spec.IngressRules[0].Ports[0].ICMPType = &icmpType
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with icmp code without type should not allowed", func() {
+				policy.Spec.IngressRules[0].Ports[0] = securityv1alpha1.SecurityPolicyPort{
+					Protocol: securityv1alpha1.ProtocolICMP,
+					ICMPCode: &icmpCode,
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+		})
+
 		Context("Validate On AppliedTo", func() {
 			var policy *securityv1alpha1.SecurityPolicy
 			BeforeEach(func() {
@@ -400,6 +499,19 @@ var _ = Describe("CRD Validate", func() {
 					EndpointSelector: &labels.Selector{},
 				}
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+
+				policy.Spec.AppliedTo[0] = securityv1alpha1.ApplyToPeer{
+					EndpointSelector: &labels.Selector{},
+					VLANs:            []int32{0, 100},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with vlans of applied to endpoint should not allowed", func() {
+				policy.Spec.AppliedTo[0] = securityv1alpha1.ApplyToPeer{
+					Endpoint: &endpointA.Name,
+					VLANs:    []int32{100},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
 			})
 		})
 
@@ -424,6 +536,124 @@ var _ = Describe("CRD Validate", func() {
 				policy.Spec.IngressRules[0].Ports[0].PortRange = "22,80,"
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
 			})
+			It("Create policy with portRange out of range should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				policy.Spec.IngressRules[0].Ports[0].PortRange = "30000-65536"
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with portRange begin bigger than end should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				policy.Spec.IngressRules[0].Ports[0].PortRange = "32767-30000"
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with portRange of the portless protocol should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				for _, protocol := range []securityv1alpha1.Protocol{securityv1alpha1.ProtocolICMP, securityv1alpha1.ProtocolIPIP, securityv1alpha1.ProtocolVRRP} {
+					policy.Spec.IngressRules[0].Ports[0].Protocol = protocol
+					Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				}
+				policy.Spec.IngressRules[0].Ports[0].PortRange = ""
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with peers in the other direction should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				policy.Spec.IngressRules[0].To = policy.Spec.IngressRules[0].From
+				response := validate.Validate(fakeAdmissionReview(policy, nil, ""))
+				Expect(response.Allowed).Should(BeFalse())
+				Expect(response.Result.Message).Should(ContainSubstring("select the sources in from instead"))
+
+				policy = securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].From = policy.Spec.EgressRules[0].To
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with named ports in ingress rules without applied to should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				policy.Spec.IngressRules[0].Ports[0] = securityv1alpha1.SecurityPolicyPort{
+					Protocol:  securityv1alpha1.ProtocolTCP,
+					PortRange: "http",
+					Type:      securityv1alpha1.PortTypeName,
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+				policy.Spec.AppliedTo = nil
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with available redirect target should allowed", func() {
+				policy := securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].Action = securityv1alpha1.RuleActionRedirect
+				policy.Spec.EgressRules[0].RedirectTo = &securityv1alpha1.RedirectTarget{IP: "10.0.0.100", Port: 2222}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with redirect action without target should not allowed", func() {
+				policy := securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].Action = securityv1alpha1.RuleActionRedirect
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with error format of redirect ip should not allowed", func() {
+				policy := securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].Action = securityv1alpha1.RuleActionRedirect
+				policy.Spec.EgressRules[0].RedirectTo = &securityv1alpha1.RedirectTarget{IP: "10.0.0.300"}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with redirect target but allow action should not allowed", func() {
+				policy := securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].RedirectTo = &securityv1alpha1.RedirectTarget{IP: "10.0.0.100"}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			l7Policy := func() *securityv1alpha1.SecurityPolicy {
+				policy := securityPolicyEgress.DeepCopy()
+				policy.Spec.EgressRules[0].Ports[0].Protocol = securityv1alpha1.ProtocolTCP
+				return policy
+			}
+			It("Create policy with available l7 criteria should allowed", func() {
+				policy := l7Policy()
+				policy.Spec.EgressRules[0].L7 = &securityv1alpha1.L7Match{
+					HTTP: []securityv1alpha1.HTTPMatch{{Method: "GET", Path: "/api/v1/.*"}, {}},
+					SNI:  []string{"*.example.com"},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with empty l7 criteria should not allowed", func() {
+				policy := l7Policy()
+				policy.Spec.EgressRules[0].L7 = &securityv1alpha1.L7Match{}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with l7 criteria on non-tcp ports should not allowed", func() {
+				policy := l7Policy()
+				policy.Spec.EgressRules[0].L7 = &securityv1alpha1.L7Match{SNI: []string{"www.example.com"}}
+				policy.Spec.EgressRules[0].Ports[0].Protocol = securityv1alpha1.ProtocolUDP
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				policy.Spec.EgressRules[0].Ports = nil
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with l7 criteria and redirect action should not allowed", func() {
+				policy := l7Policy()
+				policy.Spec.EgressRules[0].L7 = &securityv1alpha1.L7Match{SNI: []string{"www.example.com"}}
+				policy.Spec.EgressRules[0].Action = securityv1alpha1.RuleActionRedirect
+				policy.Spec.EgressRules[0].RedirectTo = &securityv1alpha1.RedirectTarget{IP: "10.0.0.100"}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with error format of l7 criteria should not allowed", func() {
+				policy := l7Policy()
+				for _, l7 := range []securityv1alpha1.L7Match{
+					{HTTP: []securityv1alpha1.HTTPMatch{{Method: "get"}}},
+					{HTTP: []securityv1alpha1.HTTPMatch{{Path: "/api/(v1"}}},
+					{SNI: []string{"www.*.com"}},
+				} {
+					policy.Spec.EgressRules[0].L7 = l7.DeepCopy()
+					Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				}
+			})
+			It("Create policy with l7 criteria in ingress rules should not allowed", func() {
+				policy := securityPolicyIngress.DeepCopy()
+				policy.Spec.IngressRules[0].L7 = &securityv1alpha1.L7Match{SNI: []string{"www.example.com"}}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with l7 criteria in symmetric mode should not allowed", func() {
+				policy := l7Policy()
+				policy.Spec.SymmetricMode = true
+				policy.Spec.EgressRules[0].L7 = &securityv1alpha1.L7Match{SNI: []string{"www.example.com"}}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
 		})
 
 		Context("Validate On SecurityPolicyPeer", func() {
@@ -452,6 +682,55 @@ var _ = Describe("CRD Validate", func() {
 				}
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
 			})
+			It("Create policy with vlans out of range in SecurityPolicyPeer should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					EndpointSelector: &labels.Selector{},
+					VLANs:            []int32{4095},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"},
+					VLANs:   []int32{100},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with error fields set with builtin SecurityPolicyPeer should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Builtin: securityv1alpha1.BuiltinPeerClusterExternal,
+					IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Builtin: "ClusterUnknown",
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+			})
+			It("Create policy with error fqdn SecurityPolicyPeer should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					FQDN:    "www.example.com",
+					IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+
+				for _, fqdn := range []string{"www.*.com", "*", "-www.example.com", "www.example.com."} {
+					policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{FQDN: fqdn}
+					Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				}
+			})
+			It("Create policy with error serviceAccount SecurityPolicyPeer should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					ServiceAccount: "default",
+					IPBlock:        &networkingv1.IPBlock{CIDR: "10.0.0.0/8"},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+
+				for _, serviceAccount := range []string{"Default", "-default", strings.Repeat("a", 64)} {
+					policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{ServiceAccount: serviceAccount}
+					Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				}
+			})
 			It("Create policy with nil SecurityPolicyPeer should allowed", func() {
 				policy.Spec.IngressRules[0].From = nil
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
@@ -485,6 +764,28 @@ var _ = Describe("CRD Validate", func() {
 					NamespaceSelector: &metav1.LabelSelector{},
 				}
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{},
+					VLANs:             []int32{100, 200},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Builtin: securityv1alpha1.BuiltinPeerClusterExternal,
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+
+				for _, fqdn := range []string{"www.example.com", "*.Example.com"} {
+					policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{FQDN: fqdn}
+					Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+				}
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					ServiceAccount:    "default",
+					NamespaceSelector: &metav1.LabelSelector{},
+				}
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
 			})
 		})
 
@@ -530,6 +831,71 @@ var _ = Describe("CRD Validate", func() {
 				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
 			})
 		})
+
+		Context("Validate On PolicyDelegation", func() {
+			var policy *securityv1alpha1.SecurityPolicy
+			var delegationValidate *validates.CRDValidate
+			var delegated, undelegated *corev1.Namespace
+
+			BeforeEach(func() {
+				policy = securityPolicyIngress.DeepCopy()
+				delegationValidate = validates.NewCRDValidate(k8sClient, scheme.Scheme, &validates.PolicyDelegation{
+					PrivilegedUsers: []string{"admin"},
+				})
+
+				delegated = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "delegated-" + rand.String(6),
+					Labels: map[string]string{constants.DelegationLabelPrefix + policy.Namespace: constants.DelegationAllow},
+				}}
+				undelegated = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "undelegated-" + rand.String(6),
+					Labels: map[string]string{constants.DelegationLabelPrefix + "other": constants.DelegationAllow},
+				}}
+				createAndWait(k8sClient, delegated)
+				createAndWait(k8sClient, undelegated)
+			})
+			AfterEach(func() {
+				Expect(k8sClient.Delete(context.Background(), delegated)).Should(Succeed())
+				Expect(k8sClient.Delete(context.Background(), undelegated)).Should(Succeed())
+			})
+
+			It("Create policy with peers in the policy namespace should allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Endpoint: &securityv1alpha1.NamespacedName{Name: "ep", Namespace: policy.Namespace},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with endpoint peer in the delegated namespace should allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Endpoint: &securityv1alpha1.NamespacedName{Name: "ep", Namespace: delegated.Name},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with endpoint peer in the undelegated namespace should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Endpoint: &securityv1alpha1.NamespacedName{Name: "ep", Namespace: undelegated.Name},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+				Expect(validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy with namespace selector matches the undelegated namespace should not allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: undelegated.Labels},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeFalse())
+
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: delegated.Labels},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed).Should(BeTrue())
+			})
+			It("Create policy by the privileged user should allowed", func() {
+				policy.Spec.IngressRules[0].From[0] = securityv1alpha1.SecurityPolicyPeer{
+					Endpoint: &securityv1alpha1.NamespacedName{Name: "ep", Namespace: undelegated.Name},
+				}
+				Expect(delegationValidate.Validate(fakeAdmissionReview(policy, nil, "admin")).Allowed).Should(BeTrue())
+			})
+		})
 	})
 
 	Context("Validate On GlobalPolicy", func() {
@@ -550,5 +916,227 @@ var _ = Describe("CRD Validate", func() {
 		It("Delete GlobalPolicy should always allowed", func() {
 			Expect(validate.Validate(fakeAdmissionReview(nil, globalPolicy, "")).Allowed).Should(BeTrue())
 		})
+		It("Update GlobalPolicy with available exceptions should allowed", func() {
+			policy := globalPolicy.DeepCopy()
+			policy.Spec.Exceptions = []securityv1alpha1.GlobalPolicyException{
+				{Type: securityv1alpha1.GlobalPolicyExceptionKubeAPIServer, CIDRs: []string{"10.0.0.1/32"}},
+				{Type: securityv1alpha1.GlobalPolicyExceptionCustom, CIDRs: []string{"192.168.0.0/24", "fe80::/64"}, Ports: []securityv1alpha1.SecurityPolicyPort{
+					{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "10250-10256"},
+				}},
+			}
+			Expect(validate.Validate(fakeAdmissionReview(policy, globalPolicy, "")).Allowed).Should(BeTrue())
+		})
+		It("Update GlobalPolicy with unavailable exception cidr should not allowed", func() {
+			policy := globalPolicy.DeepCopy()
+			policy.Spec.Exceptions = []securityv1alpha1.GlobalPolicyException{
+				{Type: securityv1alpha1.GlobalPolicyExceptionDNS, CIDRs: []string{"10.0.0.300/32"}},
+			}
+			Expect(validate.Validate(fakeAdmissionReview(policy, globalPolicy, "")).Allowed).Should(BeFalse())
+		})
+		It("Update GlobalPolicy with exception named port should not allowed", func() {
+			policy := globalPolicy.DeepCopy()
+			policy.Spec.Exceptions = []securityv1alpha1.GlobalPolicyException{
+				{CIDRs: []string{"10.0.0.0/24"}, Ports: []securityv1alpha1.SecurityPolicyPort{
+					{Protocol: securityv1alpha1.ProtocolTCP, PortRange: "http", Type: securityv1alpha1.PortTypeName},
+				}},
+			}
+			Expect(validate.Validate(fakeAdmissionReview(policy, globalPolicy, "")).Allowed).Should(BeFalse())
+		})
+	})
+
+	Context("Validate On Tier", func() {
+		var tier *securityv1alpha1.Tier
+		BeforeEach(func() {
+			tier = &securityv1alpha1.Tier{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Tier",
+					APIVersion: "security.everoute.io/v1alpha1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "security-ops",
+				},
+				Spec: securityv1alpha1.TierSpec{Priority: 50},
+			}
+			createAndWait(k8sClient, tier)
+		})
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(context.Background(), &securityv1alpha1.Tier{})).Should(Succeed())
+		})
+
+		It("Create Tier with unique priority should allowed", func() {
+			newTier := tier.DeepCopy()
+			newTier.Name = "emergency"
+			newTier.Spec.Priority = 10
+			Expect(validate.Validate(fakeAdmissionReview(newTier, nil, "")).Allowed).Should(BeTrue())
+		})
+		It("Create Tier with duplicate priority should not allowed", func() {
+			newTier := tier.DeepCopy()
+			newTier.Name = "emergency"
+			Expect(validate.Validate(fakeAdmissionReview(newTier, nil, "")).Allowed).Should(BeFalse())
+		})
+		It("Create Tier named as builtin tier should not allowed", func() {
+			newTier := tier.DeepCopy()
+			newTier.Name = constants.Tier2
+			newTier.Spec.Priority = 10
+			Expect(validate.Validate(fakeAdmissionReview(newTier, nil, "")).Allowed).Should(BeFalse())
+		})
+		It("Update Tier priority should allowed", func() {
+			newTier := tier.DeepCopy()
+			newTier.Spec.Priority = 60
+			Expect(validate.Validate(fakeAdmissionReview(newTier, tier, "")).Allowed).Should(BeTrue())
+		})
+		It("Create policy with existing Tier should allowed", func() {
+			policy := securityPolicyIngress.DeepCopy()
+			policy.Name = "new-policy"
+			policy.Spec.Tier = tier.Name
+			Eventually(func() bool {
+				return validate.Validate(fakeAdmissionReview(policy, nil, "")).Allowed
+			}, timeout, interval).Should(BeTrue())
+		})
+		It("Delete Tier used by policies should not allowed", func() {
+			policy := securityPolicyIngress.DeepCopy()
+			policy.Name = "tier-policy"
+			policy.Spec.Tier = tier.Name
+			createAndWait(k8sClient, policy)
+			Eventually(func() bool {
+				return validate.Validate(fakeAdmissionReview(nil, tier, "")).Allowed
+			}, timeout, interval).Should(BeFalse())
+		})
+		It("Delete Tier not used should allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(nil, tier, "")).Allowed).Should(BeTrue())
+		})
+	})
+
+	Context("Validate On PolicyPlan", func() {
+		var plan *securityv1alpha1.PolicyPlan
+		var validate *validates.CRDValidate
+		var approve = func(plan *securityv1alpha1.PolicyPlan, approver string) *securityv1alpha1.PolicyPlan {
+			approved := plan.DeepCopy()
+			approved.Spec.Approved = true
+			approved.Spec.Approver = &authv1.UserInfo{Username: approver}
+			return approved
+		}
+		BeforeEach(func() {
+			// the users of the plans are allowed unless denied, even on the existing cluster
+			validate = validates.NewCRDValidate(&reviewClient{Client: k8sClient}, scheme.Scheme, nil)
+			plan = &securityv1alpha1.PolicyPlan{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "PolicyPlan",
+					APIVersion: "security.everoute.io/v1alpha1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "policy-plan",
+					Generation: 1,
+				},
+				Spec: securityv1alpha1.PolicyPlanSpec{
+					Changes: []securityv1alpha1.PolicyChange{{
+						Operation: securityv1alpha1.PolicyChangeApply,
+						Namespace: securityPolicyIngress.Namespace,
+						Name:      securityPolicyIngress.Name,
+						Spec:      securityPolicyIngress.Spec.DeepCopy(),
+					}},
+					Author: &authv1.UserInfo{Username: "user1"},
+				},
+			}
+		})
+
+		It("Create available PolicyPlan should allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeTrue())
+		})
+		It("Create approved PolicyPlan should not allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user1"), nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Create PolicyPlan with the author not the user requesting should not allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user2")).Allowed).Should(BeFalse())
+			plan.Spec.Author = nil
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Create PolicyPlan with invalid policy should not allowed", func() {
+			plan.Spec.Changes[0].Spec.Tier = "UNExist-Tier-endpointName"
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Create PolicyPlan with duplicate changes should not allowed", func() {
+			plan.Spec.Changes = append(plan.Spec.Changes, securityv1alpha1.PolicyChange{
+				Operation: securityv1alpha1.PolicyChangeDelete,
+				Namespace: securityPolicyIngress.Namespace,
+				Name:      securityPolicyIngress.Name,
+			})
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Create PolicyPlan with changes the author not allowed should not allowed", func() {
+			validate := validates.NewCRDValidate(&reviewClient{Client: k8sClient, denied: "user1", verb: "create"}, scheme.Scheme, nil)
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Create PolicyPlan with delete changes the author not allowed should not allowed", func() {
+			plan.Spec.Changes[0] = securityv1alpha1.PolicyChange{
+				Operation: securityv1alpha1.PolicyChangeDelete,
+				Namespace: securityPolicyIngress.Namespace,
+				Name:      securityPolicyIngress.Name,
+			}
+			validate := validates.NewCRDValidate(&reviewClient{Client: k8sClient, denied: "user1", verb: "delete"}, scheme.Scheme, nil)
+			Expect(validate.Validate(fakeAdmissionReview(plan, nil, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Update PolicyPlan by the user not the author should not allowed", func() {
+			updated := plan.DeepCopy()
+			updated.Spec.Changes[0].Spec.Tier = constants.Tier2
+			Expect(validate.Validate(fakeAdmissionReview(updated, plan, "user2")).Allowed).Should(BeFalse())
+			updated.Spec.Author = &authv1.UserInfo{Username: "user2"}
+			Expect(validate.Validate(fakeAdmissionReview(updated, plan, "user2")).Allowed).Should(BeTrue())
+		})
+		It("Update PolicyPlan with approver set without the approval should not allowed", func() {
+			updated := plan.DeepCopy()
+			updated.Spec.Approver = &authv1.UserInfo{Username: "user1"}
+			Expect(validate.Validate(fakeAdmissionReview(updated, plan, "user1")).Allowed).Should(BeFalse())
+		})
+		It("Approve planned PolicyPlan should allowed", func() {
+			plan.Status.Phase = securityv1alpha1.PolicyPlanPlanned
+			plan.Status.ObservedGeneration = plan.Generation
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user2"), plan, "user2")).Allowed).Should(BeTrue())
+		})
+		It("Approve PolicyPlan not planned should not allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user2"), plan, "user2")).Allowed).Should(BeFalse())
+		})
+		It("Approve PolicyPlan along with changes modified should not allowed", func() {
+			plan.Status.Phase = securityv1alpha1.PolicyPlanPlanned
+			plan.Status.ObservedGeneration = plan.Generation
+			approved := approve(plan, "user2")
+			approved.Spec.Changes[0].Spec.Tier = constants.Tier2
+			Expect(validate.Validate(fakeAdmissionReview(approved, plan, "user2")).Allowed).Should(BeFalse())
+		})
+		It("Approve PolicyPlan with the approver not the user requesting should not allowed", func() {
+			plan.Status.Phase = securityv1alpha1.PolicyPlanPlanned
+			plan.Status.ObservedGeneration = plan.Generation
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user1"), plan, "user2")).Allowed).Should(BeFalse())
+		})
+		It("Approve PolicyPlan by the user not allowed to approve should not allowed", func() {
+			plan.Status.Phase = securityv1alpha1.PolicyPlanPlanned
+			plan.Status.ObservedGeneration = plan.Generation
+			validate := validates.NewCRDValidate(&reviewClient{Client: k8sClient, denied: "user2", verb: validates.VerbApprove}, scheme.Scheme, nil)
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user2"), plan, "user2")).Allowed).Should(BeFalse())
+		})
+		It("Approve PolicyPlan by the user not allowed to change the policies should not allowed", func() {
+			plan.Status.Phase = securityv1alpha1.PolicyPlanPlanned
+			plan.Status.ObservedGeneration = plan.Generation
+			validate := validates.NewCRDValidate(&reviewClient{Client: k8sClient, denied: "user2", verb: "update"}, scheme.Scheme, nil)
+			Expect(validate.Validate(fakeAdmissionReview(approve(plan, "user2"), plan, "user2")).Allowed).Should(BeFalse())
+		})
+		It("Delete PolicyPlan should always allowed", func() {
+			Expect(validate.Validate(fakeAdmissionReview(nil, plan, "")).Allowed).Should(BeTrue())
+		})
 	})
 })
+
+// reviewClient denies the verb of the user denied in the SubjectAccessReview, and allows the others.
+type reviewClient struct {
+	client.Client
+	denied string
+	verb   string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		review.Status.Allowed = review.Spec.User != c.denied || review.Spec.ResourceAttributes.Verb != c.verb
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validates
+
+import (
+	"context"
+	"fmt"
+
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// PolicyDelegation restricts the SecurityPolicies to reference the peers in other namespaces,
+// only if the namespaces delegated to the policy namespace by the label
+// delegation.everoute.io/<policy namespace>=allow. The labels of the namespaces are managed
+// by the cluster admins, so the tenants could write the policies by themselves.
+//
+// The namespaces are checked when the policy created or updated, the namespaces selected by
+// the namespaceSelector later are not checked until the policy updated.
+type PolicyDelegation struct {
+	// PrivilegedUsers and PrivilegedGroups are not restricted, e.g. the cluster admins and
+	// the controller creates policies from the kubernetes NetworkPolicies.
+	PrivilegedUsers  []string
+	PrivilegedGroups []string
+}
+
+func (d *PolicyDelegation) isPrivileged(userInfo authv1.UserInfo) bool {
+	return sets.NewString(d.PrivilegedUsers...).Has(userInfo.Username) ||
+		sets.NewString(d.PrivilegedGroups...).HasAny(userInfo.Groups...)
+}
+
+// validateDelegation validates the namespaces of the policy peers delegated to the policy.
+func (v *securityPolicyValidator) validateDelegation(policy *securityv1alpha1.SecurityPolicy, userInfo authv1.UserInfo) error {
+	if v.delegation == nil || v.delegation.isPrivileged(userInfo) {
+		return nil
+	}
+
+	peerNamespaces := sets.NewString()
+	for _, rule := range append(policy.Spec.IngressRules, policy.Spec.EgressRules...) {
+		peers := append(rule.From, rule.To...)
+		for item := range peers {
+			namespaces, err := v.peerNamespaces(&peers[item])
+			if err != nil {
+				return err
+			}
+			peerNamespaces.Insert(namespaces...)
+		}
+	}
+	peerNamespaces.Delete(policy.GetNamespace())
+
+	var errList []error
+	for _, namespace := range peerNamespaces.List() {
+		if err := v.validateNamespaceDelegated(namespace, policy.GetNamespace()); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return errors.NewAggregate(errList)
+}
+
+// peerNamespaces returns the namespaces the peer referenced, other than the policy namespace.
+func (v *securityPolicyValidator) peerNamespaces(peer *securityv1alpha1.SecurityPolicyPeer) ([]string, error) {
+	switch {
+	case peer.Endpoint != nil:
+		return []string{peer.Endpoint.Namespace}, nil
+	case peer.NamespaceSelector != nil:
+		selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("%+v not a available selector: %s", peer.NamespaceSelector, err)
+		}
+		namespaceList := corev1.NamespaceList{}
+		if err = v.List(context.Background(), &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("list namespaces: %s", err)
+		}
+		var namespaces []string
+		for _, namespace := range namespaceList.Items {
+			namespaces = append(namespaces, namespace.GetName())
+		}
+		return namespaces, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (v *securityPolicyValidator) validateNamespaceDelegated(namespace, delegateTo string) error {
+	ns := corev1.Namespace{}
+	err := v.Get(context.Background(), types.NamespacedName{Name: namespace}, &ns)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("namespace %s not found, could not delegate to namespace %s", namespace, delegateTo)
+	}
+	if err != nil {
+		return fmt.Errorf("get namespace %s: %s", namespace, err)
+	}
+
+	if ns.Labels[constants.DelegationLabelPrefix+delegateTo] != constants.DelegationAllow {
+		return fmt.Errorf("namespace %s not delegate to namespace %s, require label %s%s=%s",
+			namespace, delegateTo, constants.DelegationLabelPrefix, delegateTo, constants.DelegationAllow)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validates
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+)
+
+// policyPlanValidator validates the policies of the changes as the SecurityPolicies created by
+// the user requesting, because the changes are applied by the controller. The approval must
+// be a separate update after the plan planned, so the approver reviews the impact computed.
+// Both the author and the approver must be allowed to change the policies, and the approver
+// must be allowed to approve the plan. The users are recorded by the mutate webhook.
+type policyPlanValidator struct {
+	policy *securityPolicyValidator
+}
+
+func (v policyPlanValidator) createValidate(curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	plan := curObj.(*securityv1alpha1.PolicyPlan)
+	if plan.Spec.Approved || plan.Spec.Approver != nil {
+		return "policy plan couldn't be approved before planned", false
+	}
+	if !sameUser(plan.Spec.Author, userInfo) {
+		return "author of policy plan must be the user requesting", false
+	}
+	if err := v.validateChanges(plan.Spec.Changes, userInfo); err != nil {
+		return err.Error(), false
+	}
+	return "", true
+}
+
+func (v policyPlanValidator) updateValidate(oldObj, curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	oldPlan := oldObj.(*securityv1alpha1.PolicyPlan)
+	plan := curObj.(*securityv1alpha1.PolicyPlan)
+	if reflect.DeepEqual(oldPlan.Spec, plan.Spec) {
+		return "", true
+	}
+	if oldPlan.Spec.Approved {
+		return "policy plan approved couldn't be modified", false
+	}
+
+	if plan.Spec.Approved {
+		expectSpec := oldPlan.Spec.DeepCopy()
+		expectSpec.Approved = true
+		expectSpec.Approver = plan.Spec.Approver
+		if !reflect.DeepEqual(*expectSpec, plan.Spec) {
+			return "policy plan couldn't be modified along with the approval", false
+		}
+		if !sameUser(plan.Spec.Approver, userInfo) {
+			return "approver of policy plan must be the user requesting", false
+		}
+		if oldPlan.Status.Phase != securityv1alpha1.PolicyPlanPlanned || oldPlan.Status.ObservedGeneration != oldPlan.Generation {
+			return "policy plan couldn't be approved before planned", false
+		}
+		ctx := context.Background()
+		err := ReviewPolicyPlanApproval(ctx, v.policy.Client, plan.Spec.Approver, plan.Name)
+		if err == nil {
+			err = ReviewPolicyChanges(ctx, v.policy.Client, plan.Spec.Approver, plan.Spec.Changes)
+		}
+		if err != nil {
+			return err.Error(), false
+		}
+		return "", true
+	}
+
+	if plan.Spec.Approver != nil {
+		return "approver of policy plan couldn't be set without the approval", false
+	}
+	if !sameUser(plan.Spec.Author, userInfo) {
+		return "author of policy plan must be the user requesting", false
+	}
+	if err := v.validateChanges(plan.Spec.Changes, userInfo); err != nil {
+		return err.Error(), false
+	}
+	return "", true
+}
+
+func (v policyPlanValidator) deleteValidate(oldObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	return "", true
+}
+
+func (v policyPlanValidator) validateChanges(changes []securityv1alpha1.PolicyChange, userInfo authv1.UserInfo) error {
+	policies := sets.NewString()
+	for _, change := range changes {
+		key := change.Namespace + "/" + change.Name
+		if change.Namespace == "" || change.Name == "" {
+			return fmt.Errorf("policy %s of the change must have namespace and name", key)
+		}
+		if policies.Has(key) {
+			return fmt.Errorf("policy %s changed more than once", key)
+		}
+		policies.Insert(key)
+
+		if change.Operation == securityv1alpha1.PolicyChangeDelete {
+			if change.Spec != nil {
+				return fmt.Errorf("spec of policy %s couldn't be set when delete", key)
+			}
+			continue
+		}
+		if change.Spec == nil {
+			return fmt.Errorf("spec of policy %s must be set when apply", key)
+		}
+
+		policy := &securityv1alpha1.SecurityPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: change.Namespace, Name: change.Name},
+			Spec:       *change.Spec,
+		}
+		err := v.policy.validatePolicy(policy)
+		if err == nil {
+			err = v.policy.validateDelegation(policy, userInfo)
+		}
+		if err != nil {
+			return fmt.Errorf("policy %s: %s", key, err)
+		}
+	}
+	// the delete changes are reviewed too
+	return ReviewPolicyChanges(context.Background(), v.policy.Client, &userInfo, changes)
+}
+
+// sameUser returns true if the user recorded is the user requesting.
+func sameUser(user *authv1.UserInfo, userInfo authv1.UserInfo) bool {
+	return user != nil && equality.Semantic.DeepEqual(*user, userInfo)
+}
+
+// VerbApprove is the verb on the policyplans required to approve a plan.
+const VerbApprove = "approve"
+
+// ReviewPolicyChanges returns error if the user is not allowed to change the SecurityPolicies of the
+// changes. The Apply changes require the verbs create and update, and the Delete changes require
+// the verb delete on the securitypolicies in the namespace of the change.
+func ReviewPolicyChanges(ctx context.Context, c client.Client, user *authv1.UserInfo, changes []securityv1alpha1.PolicyChange) error {
+	for _, change := range changes {
+		verbs := []string{"create", "update"}
+		if change.Operation == securityv1alpha1.PolicyChangeDelete {
+			verbs = []string{"delete"}
+		}
+		for _, verb := range verbs {
+			err := reviewAccess(ctx, c, user, &authorizationv1.ResourceAttributes{
+				Namespace: change.Namespace,
+				Verb:      verb,
+				Group:     securityv1alpha1.SchemeGroupVersion.Group,
+				Resource:  "securitypolicies",
+				Name:      change.Name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReviewPolicyPlanApproval returns error if the user is not allowed to approve the plan.
+func ReviewPolicyPlanApproval(ctx context.Context, c client.Client, user *authv1.UserInfo, planName string) error {
+	return reviewAccess(ctx, c, user, &authorizationv1.ResourceAttributes{
+		Verb:     VerbApprove,
+		Group:    securityv1alpha1.SchemeGroupVersion.Group,
+		Resource: "policyplans",
+		Name:     planName,
+	})
+}
+
+func reviewAccess(ctx context.Context, c client.Client, user *authv1.UserInfo, attributes *authorizationv1.ResourceAttributes) error {
+	if user == nil {
+		return fmt.Errorf("user unknown couldn't %s %s", attributes.Verb, attributes.Resource)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              make(map[string]authorizationv1.ExtraValue, len(user.Extra)),
+			ResourceAttributes: attributes,
+		},
+	}
+	for key, value := range user.Extra {
+		accessReview.Spec.Extra[key] = authorizationv1.ExtraValue(value)
+	}
+	if err := c.Create(ctx, accessReview); err != nil {
+		return fmt.Errorf("review access of user %s: %s", user.Username, err)
+	}
+	if !accessReview.Status.Allowed {
+		if attributes.Namespace != "" {
+			return fmt.Errorf("user %s cannot %s %s in namespace %s", user.Username, attributes.Verb, attributes.Resource, attributes.Namespace)
+		}
+		return fmt.Errorf("user %s cannot %s %s %s", user.Username, attributes.Verb, attributes.Resource, attributes.Name)
+	}
+	return nil
+}
@@ -118,7 +118,7 @@ var _ = BeforeSuite(func() {
 	Expect(k8sManager).ToNot(BeNil())
 
 	Expect(err).ToNot(HaveOccurred())
-	validate = validates.NewCRDValidate(k8sManager.GetClient(), k8sManager.GetScheme())
+	validate = validates.NewCRDValidate(k8sManager.GetClient(), k8sManager.GetScheme(), nil)
 	Expect(validate).ToNot(BeNil())
 
 	go func() {
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Everoute Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validates
+
+import (
+	"context"
+	"fmt"
+
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	securityv1alpha1 "github.com/everoute/everoute/pkg/apis/security/v1alpha1"
+	"github.com/everoute/everoute/pkg/constants"
+)
+
+// builtinTiers are the tiers without Tier objects, no Tier could be named as them.
+var builtinTiers = sets.NewString(constants.Tier0, constants.Tier1, constants.Tier2, constants.TierECP)
+
+// tierValidator keeps the strict order of the Tiers, the priorities of the Tiers must be unique.
+// A Tier couldn't be deleted when any policy belongs to it.
+type tierValidator resourceValidator
+
+func (v tierValidator) createValidate(curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	if err := v.validateTier(curObj.(*securityv1alpha1.Tier)); err != nil {
+		return err.Error(), false
+	}
+	return "", true
+}
+
+func (v tierValidator) updateValidate(oldObj, curObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	if err := v.validateTier(curObj.(*securityv1alpha1.Tier)); err != nil {
+		return err.Error(), false
+	}
+	return "", true
+}
+
+func (v tierValidator) deleteValidate(oldObj runtime.Object, userInfo authv1.UserInfo) (string, bool) {
+	tier := oldObj.(*securityv1alpha1.Tier)
+	policyList := securityv1alpha1.SecurityPolicyList{}
+	if err := v.List(context.Background(), &policyList); err != nil {
+		return err.Error(), false
+	}
+	for _, policy := range policyList.Items {
+		if policy.Spec.Tier == tier.Name {
+			return fmt.Sprintf("tier %s is used by policy %s/%s", tier.Name, policy.Namespace, policy.Name), false
+		}
+	}
+	return "", true
+}
+
+func (v tierValidator) validateTier(tier *securityv1alpha1.Tier) error {
+	if builtinTiers.Has(tier.Name) {
+		return fmt.Errorf("tier %s is builtin", tier.Name)
+	}
+	if tier.Spec.Priority < 1 || tier.Spec.Priority > constants.MaxTierPriority {
+		return fmt.Errorf("tier priority %d out of range [1, %d]", tier.Spec.Priority, constants.MaxTierPriority)
+	}
+
+	tierList := securityv1alpha1.TierList{}
+	if err := v.List(context.Background(), &tierList); err != nil {
+		return err
+	}
+	for _, item := range tierList.Items {
+		if item.Name != tier.Name && item.Spec.Priority == tier.Spec.Priority {
+			return fmt.Errorf("tier priority %d conflicts with tier %s", tier.Spec.Priority, item.Name)
+		}
+	}
+	return nil
+}
@@ -180,8 +180,10 @@ func (c *Controller) reconcileGlobalPolicy(name string) error {
 		globalPolicy = obj.(*v1alpha1.GlobalPolicy).DeepCopy()
 	}
 
-	// get current global policy spec
+	// get current global policy spec, the exceptions are configured in the cluster, keep them
+	exceptions := globalPolicy.Spec.Exceptions
 	globalPolicy.Spec = c.getCurrentGlobalPolicySpec()
+	globalPolicy.Spec.Exceptions = exceptions
 
 	klog.Infof("update global policy to %+v", globalPolicy)
 	_, err = c.crdClient.SecurityV1alpha1().GlobalPolicies().Update(context.Background(), globalPolicy, metav1.UpdateOptions{})
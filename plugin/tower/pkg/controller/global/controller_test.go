@@ -69,6 +69,33 @@ var _ = Describe("GlobalPolicyController", func() {
 				assertMatchDefaultAction(ctx, v1alpha1.GlobalDefaultActionDrop)
 			})
 		})
+
+		When("update default global policy with exceptions", func() {
+			var exceptions []v1alpha1.GlobalPolicyException
+
+			BeforeEach(func() {
+				assertMatchDefaultAction(ctx, v1alpha1.GlobalDefaultActionAllow)
+				exceptions = []v1alpha1.GlobalPolicyException{
+					{Type: v1alpha1.GlobalPolicyExceptionKubeAPIServer, CIDRs: []string{"10.0.0.1/32"}},
+				}
+				globalPolicy, err := crdClient.SecurityV1alpha1().GlobalPolicies().Get(ctx, controller.DefaultGlobalPolicyName, metav1.GetOptions{})
+				Expect(err).Should(Succeed())
+				globalPolicy.Spec.Exceptions = exceptions
+				By(fmt.Sprintf("update default global policy with exceptions %+v", exceptions))
+				_, err = crdClient.SecurityV1alpha1().GlobalPolicies().Update(ctx, globalPolicy, metav1.UpdateOptions{})
+				Expect(err).Should(Succeed())
+
+				erCluster.GlobalDefaultAction = schema.GlobalPolicyActionDrop
+				By(fmt.Sprintf("update everoute cluster %s to default drop", erCluster.ID))
+				server.TrackerFactory().EverouteCluster().CreateOrUpdate(erCluster)
+			})
+			It("should keep the exceptions of default global policy", func() {
+				assertMatchDefaultAction(ctx, v1alpha1.GlobalDefaultActionDrop)
+				globalPolicy, err := crdClient.SecurityV1alpha1().GlobalPolicies().Get(ctx, controller.DefaultGlobalPolicyName, metav1.GetOptions{})
+				Expect(err).Should(Succeed())
+				Expect(globalPolicy.Spec.Exceptions).Should(Equal(exceptions))
+			})
+		})
 	})
 
 	Context("create everoute cluster with default drop", func() {